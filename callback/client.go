@@ -0,0 +1,111 @@
+package callback
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// TransportOptions tunes the HTTP transport used for callback delivery
+// Zero values keep the net/http defaults
+type TransportOptions struct {
+	MaxIdleConnsPerHost int         // Idle keep-alive connections kept per receiver host
+	TLSConfig           *tls.Config // e.g. client certificates for mTLS receivers
+	ProxyURL            string      // Egress proxy; empty uses the HTTP_PROXY/HTTPS_PROXY environment
+	DisableKeepAlives   bool
+	URLPolicy           *URLPolicy // Refuses connections to denied addresses; see URLPolicy.DialContext
+}
+
+// Validate checks the transport options without building a client
+func (o TransportOptions) Validate() error {
+	if o.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("max idle connections per host cannot be negative")
+	}
+	if o.ProxyURL != "" {
+		if _, err := parseProxyURL(o.ProxyURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewHTTPClient builds a callback HTTP client with the given overall timeout and transport options
+func NewHTTPClient(timeout time.Duration, opts TransportOptions) (*http.Client, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+		if transport.MaxIdleConns < opts.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = opts.MaxIdleConnsPerHost
+		}
+	}
+	if opts.TLSConfig != nil {
+		transport.TLSClientConfig = opts.TLSConfig.Clone()
+	}
+	if opts.ProxyURL != "" {
+		proxyURL, _ := parseProxyURL(opts.ProxyURL)
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+	if opts.URLPolicy != nil {
+		transport = policyTransport(transport, opts.URLPolicy)
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// policyTransport makes transport dial through the policy. Connections to its proxies are
+// exempt, since they may well be on a private network; behind a proxy, the proxy resolves and
+// connects to receivers, so only Check and its own rules apply
+func policyTransport(transport *http.Transport, policy *URLPolicy) *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	dial := policy.DialContext(dialer)
+	proxies := proxyAddrs(transport)
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if proxies[address] {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return dial(ctx, network, address)
+	}
+	return transport
+}
+
+// proxyAddrs returns the host:port of the proxies the transport sends requests through
+func proxyAddrs(transport *http.Transport) map[string]bool {
+	addrs := map[string]bool{}
+	if transport.Proxy == nil {
+		return addrs
+	}
+	defaultPorts := map[string]string{"http": "80", "https": "443", "socks5": "1080"}
+	for _, scheme := range []string{"http", "https"} {
+		proxyURL, err := transport.Proxy(&http.Request{URL: &url.URL{Scheme: scheme, Host: "callback.invalid"}})
+		if err != nil || proxyURL == nil {
+			continue
+		}
+		port := proxyURL.Port()
+		if port == "" {
+			port = defaultPorts[proxyURL.Scheme]
+		}
+		addrs[net.JoinHostPort(proxyURL.Hostname(), port)] = true
+	}
+	return addrs
+}
+
+// parseProxyURL requires an absolute proxy URL such as http://proxy:3128
+func parseProxyURL(raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL: %s", raw)
+	}
+	return proxyURL, nil
+}
@@ -0,0 +1,124 @@
+package callback
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// newClientCertificate creates a self-signed client certificate for mTLS tests
+func newClientCertificate(t *testing.T) (tls.Certificate, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "later-callback"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, leaf
+}
+
+func TestNewHTTPClientMutualTLS(t *testing.T) {
+	clientCert, clientLeaf := newClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientLeaf)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	serverCAs := x509.NewCertPool()
+	serverCAs.AddCert(server.Certificate())
+
+	t.Run("Client certificate is presented", func(t *testing.T) {
+		client, err := NewHTTPClient(time.Second, TransportOptions{
+			TLSConfig: &tls.Config{RootCAs: serverCAs, Certificates: []tls.Certificate{clientCert}},
+		})
+		require.NoError(t, err)
+
+		svc := NewService(client, nil, "", 0, zap.NewNop())
+		task := &entity.Task{ID: "mtls", CallbackURL: server.URL}
+		assert.NoError(t, svc.DeliverCallback(context.Background(), task))
+		assert.Equal(t, entity.TaskStatusCompleted, task.Status)
+	})
+
+	t.Run("Missing client certificate is rejected", func(t *testing.T) {
+		client, err := NewHTTPClient(time.Second, TransportOptions{
+			TLSConfig: &tls.Config{RootCAs: serverCAs},
+		})
+		require.NoError(t, err)
+
+		svc := NewService(client, nil, "", 0, zap.NewNop())
+		task := &entity.Task{ID: "no-cert", CallbackURL: server.URL}
+		assert.Error(t, svc.DeliverCallback(context.Background(), task))
+	})
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient(time.Second, TransportOptions{ProxyURL: proxy.URL, DisableKeepAlives: true})
+	require.NoError(t, err)
+
+	svc := NewService(client, nil, "", 0, zap.NewNop())
+	task := &entity.Task{ID: "proxied", CallbackURL: "http://receiver.internal/hook"}
+	require.NoError(t, svc.DeliverCallback(context.Background(), task))
+	assert.Equal(t, "http://receiver.internal/hook", proxied)
+}
+
+func TestTransportOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    TransportOptions
+		wantErr bool
+	}{
+		{name: "Zero value", opts: TransportOptions{}},
+		{name: "Valid proxy", opts: TransportOptions{ProxyURL: "http://proxy:3128"}},
+		{name: "Relative proxy", opts: TransportOptions{ProxyURL: "proxy:3128"}, wantErr: true},
+		{name: "Negative idle conns", opts: TransportOptions{MaxIdleConnsPerHost: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			assert.Equal(t, tt.wantErr, err != nil, "Validate() error = %v", err)
+		})
+	}
+}
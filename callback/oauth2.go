@@ -0,0 +1,139 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// tokenRefreshMargin is how long before expiry a cached token is refreshed
+const tokenRefreshMargin = 30 * time.Second
+
+// tokenCache fetches OAuth2 client-credentials tokens and caches them until shortly before expiry
+// It is safe for concurrent workers; concurrent requests for the same credentials share one fetch
+type tokenCache struct {
+	client *http.Client
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cachedToken
+}
+
+// cachedToken holds one credential's token; its mutex serializes fetches for that credential
+type cachedToken struct {
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time // Zero means the token doesn't expire
+}
+
+// tokenResponse is the token endpoint's JSON response (RFC 6749 section 5.1)
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func newTokenCache(client *http.Client) *tokenCache {
+	return &tokenCache{
+		client:  client,
+		now:     time.Now,
+		entries: make(map[string]*cachedToken),
+	}
+}
+
+// Token returns a valid access token for the credentials, fetching one if needed
+func (c *tokenCache) Token(ctx context.Context, cfg *entity.OAuth2Config) (string, error) {
+	entry := c.entry(cfg)
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.accessToken != "" && (entry.expiry.IsZero() || c.now().Add(tokenRefreshMargin).Before(entry.expiry)) {
+		return entry.accessToken, nil
+	}
+
+	token, err := c.fetch(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	entry.accessToken = token.AccessToken
+	entry.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		entry.expiry = c.now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return entry.accessToken, nil
+}
+
+// Invalidate drops the cached token, e.g. after the receiver rejected it
+func (c *tokenCache) Invalidate(cfg *entity.OAuth2Config) {
+	entry := c.entry(cfg)
+
+	entry.mu.Lock()
+	entry.accessToken = ""
+	entry.mu.Unlock()
+}
+
+// entry returns the cache entry for the credentials, creating it if needed
+func (c *tokenCache) entry(cfg *entity.OAuth2Config) *cachedToken {
+	key := strings.Join([]string{cfg.TokenURL, cfg.ClientID, cfg.ClientSecret, strings.Join(cfg.Scopes, " ")}, "\x00")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &cachedToken{}
+		c.entries[key] = entry
+	}
+	return entry
+}
+
+// fetch requests a token using the client-credentials grant with HTTP Basic client authentication
+func (c *tokenCache) fetch(ctx context.Context, cfg *entity.OAuth2Config) (*tokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return nil, fmt.Errorf("unsupported token type %q", token.TokenType)
+	}
+	return &token, nil
+}
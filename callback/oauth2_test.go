@@ -0,0 +1,137 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// newTokenServer issues sequential tokens ("token-1", "token-2", ...) to client "later"
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var issued atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "later" || secret != "s3cret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := issued.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, n, expiresIn)
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestTokenCacheRefreshesBeforeExpiry(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 300)
+	cfg := &entity.OAuth2Config{TokenURL: tokenServer.URL, ClientID: "later", ClientSecret: "s3cret", Scopes: []string{"webhooks"}}
+
+	now := time.Now()
+	cache := newTokenCache(http.DefaultClient)
+	cache.now = func() time.Time { return now }
+
+	token, err := cache.Token(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	now = now.Add(4 * time.Minute)
+	token, err = cache.Token(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token, "still valid beyond the refresh margin")
+
+	now = now.Add(40 * time.Second)
+	token, err = cache.Token(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token, "refreshed within the refresh margin")
+	assert.Equal(t, int32(2), issued.Load())
+}
+
+func TestTokenCacheConcurrentFetch(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 3600)
+	cfg := &entity.OAuth2Config{TokenURL: tokenServer.URL, ClientID: "later", ClientSecret: "s3cret"}
+	cache := newTokenCache(http.DefaultClient)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := cache.Token(context.Background(), cfg)
+			assert.NoError(t, err)
+			assert.Equal(t, "token-1", token)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), issued.Load())
+}
+
+func TestDeliverCallbackWithOAuth2(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 3600)
+
+	var mu sync.Mutex
+	var authorizations []string
+	rejectNext := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		if rejectNext {
+			rejectNext = false
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	svc := NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop(), WithOAuth2(entity.OAuth2Config{
+		TokenURL: tokenServer.URL, ClientID: "later", ClientSecret: "s3cret",
+	}))
+
+	t.Run("Bearer token is attached and cached", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			task := &entity.Task{ID: "oauth2", CallbackURL: receiver.URL}
+			require.NoError(t, svc.DeliverCallback(context.Background(), task))
+		}
+		assert.Equal(t, []string{"Bearer token-1", "Bearer token-1"}, authorizations)
+		assert.Equal(t, int32(1), issued.Load())
+	})
+
+	t.Run("Rejected token is refreshed on the next attempt", func(t *testing.T) {
+		rejectNext = true
+		task := &entity.Task{ID: "revoked", CallbackURL: receiver.URL}
+		err := svc.DeliverCallback(context.Background(), task)
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, ErrPermanent), "401 is retriable with OAuth2")
+
+		require.NoError(t, svc.DeliverCallback(context.Background(), task))
+		assert.Equal(t, "Bearer token-2", authorizations[len(authorizations)-1])
+	})
+
+	t.Run("Token fetch failure is retriable", func(t *testing.T) {
+		task := &entity.Task{
+			ID:             "bad-credentials",
+			CallbackURL:    receiver.URL,
+			CallbackOAuth2: &entity.OAuth2Config{TokenURL: tokenServer.URL, ClientID: "later", ClientSecret: "wrong"},
+		}
+		err := svc.DeliverCallback(context.Background(), task)
+		assert.ErrorContains(t, err, "failed to fetch OAuth2 token")
+		assert.False(t, errors.Is(err, ErrPermanent))
+		assert.Equal(t, 1, task.CallbackAttempts)
+	})
+}
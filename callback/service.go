@@ -3,70 +3,291 @@ package callback
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/usual2970/later/domain/entity"
 	"github.com/usual2970/later/infrastructure/circuitbreaker"
+	"github.com/usual2970/later/infrastructure/clock"
+	"github.com/usual2970/later/infrastructure/logger"
 
 	"go.uber.org/zap"
 )
 
+// DefaultResponseBodyLimit is how much of a callback response body is read before the rest is abandoned
+const DefaultResponseBodyLimit int64 = 64 << 10
+
+// responseSnippetLimit is how much of the response body is kept on the task
+const responseSnippetLimit = 1024
+
+// ErrPermanent marks callback failures that must not be retried, such as non-retryable status codes
+var ErrPermanent = errors.New("permanent callback failure")
+
+// MaxRedirects is the most redirects a callback follows, like net/http's default client
+const MaxRedirects = 10
+
+// timeoutUnit is the unit of task.CallbackTimeoutSecs; overridden in tests
+var timeoutUnit = time.Second
+
 // Service handles HTTP callback delivery
 type Service struct {
-	client         *http.Client
-	circuitBreaker *circuitbreaker.CircuitBreaker
-	signingSecret  string
-	logger         *zap.Logger
+	client            *http.Client
+	callbackClient    *http.Client // client without automatic redirects; see send
+	maxRedirects      int          // Followed for every task; zero fails deliveries that redirect
+	circuitBreaker    *circuitbreaker.CircuitBreaker
+	signingSecret     string
+	previousSecret    string // Also signs deliveries while receivers move to signingSecret
+	responseBodyLimit int64
+	urlPolicy         *URLPolicy           // nil allows any URL
+	oauth2            *entity.OAuth2Config // nil sends no Authorization header unless the task sets one
+	tokens            *tokenCache
+	retryableCodes    []int // nil retries 5xx and 429
+	clock             clock.Clock
+	logger            *zap.Logger
 }
 
-// NewService creates a new callback service
+// ServiceOption configures optional Service behaviour
+type ServiceOption func(*Service)
+
+// WithURLPolicy checks callback URLs, redirects included, against the policy before each delivery
+// Denied addresses are refused when dialled by clients without a transport, which get one, and
+// by clients from NewHTTPClient with TransportOptions.URLPolicy; other transports must do it
+func WithURLPolicy(policy *URLPolicy) ServiceOption {
+	return func(s *Service) {
+		s.urlPolicy = policy
+	}
+}
+
+// WithPreviousSigningSecret additionally signs deliveries with a retired secret in the
+// X-Signature-Previous header, so receivers can switch to the new secret at their own pace
+// Remove it once every receiver verifies X-Signature with the new secret
+func WithPreviousSigningSecret(secret string) ServiceOption {
+	return func(s *Service) {
+		s.previousSecret = secret
+	}
+}
+
+// WithOAuth2 authenticates deliveries with an OAuth2 client-credentials bearer token
+// Tasks with their own CallbackOAuth2 settings use those instead
+func WithOAuth2(cfg entity.OAuth2Config) ServiceOption {
+	return func(s *Service) {
+		s.oauth2 = &cfg
+	}
+}
+
+// WithRetryableStatusCodes sets the response status codes that are retried; all other
+// non-2xx codes fail permanently. Tasks can override the set with RetryableStatusCodes
+func WithRetryableStatusCodes(codes []int) ServiceOption {
+	return func(s *Service) {
+		s.retryableCodes = codes
+	}
+}
+
+// WithMaxRedirects lets every callback follow up to n redirects, at most MaxRedirects; by
+// default a redirect fails the delivery like any other non-2xx response. Tasks with
+// FollowRedirects set follow up to MaxRedirects regardless
+func WithMaxRedirects(n int) ServiceOption {
+	return func(s *Service) {
+		s.maxRedirects = min(max(n, 0), MaxRedirects)
+	}
+}
+
+// WithClock sets the clock that timestamps callback attempts and OAuth2 token expiry
+func WithClock(c clock.Clock) ServiceOption {
+	return func(s *Service) {
+		s.clock = c
+	}
+}
+
+// NewService creates a new callback service delivering through the given client
+// A nil client uses a default client with no timeout; see NewHTTPClient
+// A responseBodyLimit of zero or less uses DefaultResponseBodyLimit
 func NewService(
-	timeout time.Duration,
+	client *http.Client,
 	circuitBreaker *circuitbreaker.CircuitBreaker,
 	signingSecret string,
+	responseBodyLimit int64,
 	logger *zap.Logger,
+	opts ...ServiceOption,
 ) *Service {
-	return &Service{
-		client:         &http.Client{Timeout: timeout},
-		circuitBreaker: circuitBreaker,
-		signingSecret:  signingSecret,
-		logger:         logger,
+	if responseBodyLimit <= 0 {
+		responseBodyLimit = DefaultResponseBodyLimit
+	}
+	if client == nil {
+		client = &http.Client{}
 	}
+	s := &Service{
+		client:            client,
+		circuitBreaker:    circuitBreaker,
+		signingSecret:     signingSecret,
+		responseBodyLimit: responseBodyLimit,
+		clock:             clock.Real,
+		logger:            logger,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.urlPolicy != nil {
+		// Copy the client so a caller-provided one isn't modified
+		checked := *s.client
+		checked.CheckRedirect = s.checkRedirect
+		if checked.Transport == nil {
+			checked.Transport = policyTransport(http.DefaultTransport.(*http.Transport).Clone(), s.urlPolicy)
+		}
+		s.client = &checked
+	}
+	s.tokens = newTokenCache(s.client)
+	s.tokens.now = s.clock.Now
+
+	// net/http turns a redirected POST into a GET without the body for some statuses, so
+	// callbacks follow redirects themselves
+	callbackClient := *s.client
+	callbackClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	s.callbackClient = &callbackClient
+	return s
+}
+
+// checkRedirect applies the URL policy to redirect targets, keeping net/http's limit of 10 redirects
+func (s *Service) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return s.urlPolicy.checkStatic(req.URL.String())
+}
+
+// Attempt describes the HTTP exchange of a callback delivery
+type Attempt struct {
+	StatusCode int           // Zero when no response was received
+	Duration   time.Duration // Time until the response arrived; zero when no request was sent
+	FinalURL   string        // URL of the last request sent, after any redirects followed
 }
 
 // DeliverCallback delivers a callback to the task's callback URL
 func (s *Service) DeliverCallback(ctx context.Context, task *entity.Task) error {
+	_, err := s.Deliver(ctx, task)
+	return err
+}
+
+// Deliver delivers a callback like DeliverCallback, also reporting the HTTP exchange
+func (s *Service) Deliver(ctx context.Context, task *entity.Task) (Attempt, error) {
+	var attempt Attempt
+
+	// Re-check the URL policy, which may have changed since the task was created
+	if err := s.checkURLs(task); err != nil {
+		return attempt, s.handleFailure(task, err)
+	}
+
 	// Check circuit breaker
 	if s.circuitBreaker != nil && s.circuitBreaker.IsOpen(task.CallbackURL) {
-		return fmt.Errorf("circuit breaker is open for URL: %s", task.CallbackURL)
+		return attempt, fmt.Errorf("circuit breaker is open for URL: %s", task.CallbackURL)
 	}
 
 	// Execute callback via circuit breaker
 	if s.circuitBreaker != nil {
-		return s.circuitBreaker.Execute(task.CallbackURL, func() error {
-			return s.deliverHTTPCallback(ctx, task)
+		err := s.circuitBreaker.Execute(task.CallbackURL, func() error {
+			return s.deliverHTTPCallback(ctx, task, &attempt)
 		})
+		return attempt, err
 	}
 
-	return s.deliverHTTPCallback(ctx, task)
+	return attempt, s.deliverHTTPCallback(ctx, task, &attempt)
+}
+
+// checkURLs applies the URL policy to the callback URL and the OAuth2 token URL, if any
+// Their addresses aren't resolved here: the client's dialer refuses denied ones when it
+// connects, see URLPolicy.DialContext
+func (s *Service) checkURLs(task *entity.Task) error {
+	if s.urlPolicy == nil {
+		return nil
+	}
+	if err := s.urlPolicy.checkStatic(task.CallbackURL); err != nil {
+		return err
+	}
+	if task.CallbackOAuth2 != nil {
+		if err := s.urlPolicy.checkStatic(task.CallbackOAuth2.TokenURL); err != nil {
+			return fmt.Errorf("oauth2 token_url: %w", err)
+		}
+	}
+	return nil
 }
 
-// deliverHTTPCallback performs the actual HTTP POST
-func (s *Service) deliverHTTPCallback(ctx context.Context, task *entity.Task) error {
+// Outcomes of a delivery, as a worker acts on them
+const (
+	OutcomeSuccess = "success" // The task completes
+	OutcomeRetry   = "retry"   // The task is retried while it has retries left
+	OutcomeFailure = "failure" // The task is dead-lettered at once
+)
+
+// Outcome classifies an error returned by Deliver
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, ErrPermanent):
+		return OutcomeFailure
+	default:
+		return OutcomeRetry
+	}
+}
+
+// TestResult is the outcome of a test delivery
+type TestResult struct {
+	Attempt
+	Outcome  string  // How a worker would act on the delivery; see Outcome
+	Response *string // Start of the response body, if any
+	Signed   bool    // Whether the request carried a signature
+	Err      error   // nil when the receiver accepted the callback
+}
+
+// TestDelivery delivers the task's callback once, right away, so receivers can check how they
+// handle it, e.g. their signature verification, before going live. The request is signed and
+// authenticated like a real delivery, but the circuit breaker is bypassed: an open circuit
+// doesn't refuse it and its outcome isn't counted. The task is only read, never stored
+// Returns an error wrapping ErrURLNotAllowed, without delivering, if the URL policy denies a URL
+func (s *Service) TestDelivery(ctx context.Context, task *entity.Task) (*TestResult, error) {
+	if err := s.checkURLs(task); err != nil {
+		return nil, err
+	}
+
+	result := &TestResult{Signed: s.signingSecret != ""}
+	result.Err = s.deliverHTTPCallback(ctx, task, &result.Attempt)
+	result.Outcome = Outcome(result.Err)
+	result.Response = task.LastCallbackResponse
+	return result, nil
+}
+
+// deliverHTTPCallback performs the actual HTTP POST, recording the exchange in attempt
+// The task's callback timeout applies per request; the client timeout remains an upper bound
+func (s *Service) deliverHTTPCallback(ctx context.Context, task *entity.Task, attempt *Attempt) error {
+	if task.CallbackTimeoutSecs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(task.CallbackTimeoutSecs)*timeoutUnit)
+		defer cancel()
+	}
+
+	// Render the body; a template that fails now will fail on every retry too
+	body, err := renderBody(task)
+	if err != nil {
+		return s.handleFailure(task, err)
+	}
+
 	// Create request
 	req, err := http.NewRequestWithContext(
 		ctx,
 		"POST",
 		task.CallbackURL,
-		bytes.NewReader(task.Payload),
+		bytes.NewReader(body),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return s.handleFailure(task, fmt.Errorf("failed to create request: %w", err))
 	}
 
 	// Set headers
@@ -74,55 +295,187 @@ func (s *Service) deliverHTTPCallback(ctx context.Context, task *entity.Task) er
 	req.Header.Set("X-Task-ID", task.ID)
 	req.Header.Set("X-Task-Name", task.Name)
 	req.Header.Set("X-Retry-Count", fmt.Sprintf("%d", task.RetryCount))
+	if task.RequestID != nil {
+		req.Header.Set("X-Origin-Request-ID", *task.RequestID)
+	}
 
-	// Add signature if secret is configured
+	// Add signatures if secrets are configured
 	if s.signingSecret != "" {
-		signature := s.generateSignature(task.Payload)
-		req.Header.Set("X-Signature", signature)
+		req.Header.Set(SignatureHeader, sign(s.signingSecret, body))
+	}
+	if s.previousSecret != "" {
+		req.Header.Set(PreviousSignatureHeader, sign(s.previousSecret, body))
+	}
+
+	// Add OAuth2 bearer token; failing to get one is retriable like a receiver outage
+	oauth2 := s.oauth2Config(task)
+	if oauth2 != nil {
+		token, err := s.tokens.Token(ctx, oauth2)
+		if err != nil {
+			return s.handleRetry(task, fmt.Errorf("failed to fetch OAuth2 token: %w", err))
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 
 	// Execute request
 	startTime := time.Now()
-	resp, err := s.client.Do(req)
+	resp, err := s.send(task, req, body, attempt)
+	attempt.Duration = time.Since(startTime)
+	durationMs := attempt.Duration.Milliseconds()
+	task.CallbackDurationMs = &durationMs
+	if errors.Is(err, ErrURLNotAllowed) {
+		// Redirected to a denied URL, or the host resolved to a denied address when dialled
+		return s.handleFailure(task, err)
+	}
 	if err != nil {
-		return fmt.Errorf("HTTP request failed: %w", err)
+		// A request cancelled with a cause, such as an operator abort, reports the cause
+		if cause := context.Cause(ctx); cause != nil && cause != ctx.Err() {
+			err = cause
+		}
+		// Network-level errors (connection refused, timeouts, resets) are always retriable
+		return s.handleRetry(task, fmt.Errorf("HTTP request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
+	attempt.StatusCode = resp.StatusCode
+	task.LastCallbackResponse = s.readResponseBody(resp.Body)
 	duration := time.Since(startTime)
 
 	// Log callback attempt
-	s.logger.Info("Callback delivered",
+	fields := []zap.Field{
 		zap.String("task_id", task.ID),
+		logger.RequestID(task.RequestID),
 		zap.String("callback_url", task.CallbackURL),
 		zap.Int("status_code", resp.StatusCode),
 		zap.Duration("duration", duration),
-	)
+	}
+	if attempt.FinalURL != task.CallbackURL {
+		fields = append(fields, zap.String("final_url", attempt.FinalURL))
+	}
+	s.logger.Info("Callback delivered", fields...)
 
 	// Classify response
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		// Success
 		return s.handleSuccess(task)
-	} else if resp.StatusCode >= 500 || resp.StatusCode == 429 {
-		// Server error or rate limit - retry
+	} else if resp.StatusCode == http.StatusUnauthorized && oauth2 != nil {
+		// The token may have been revoked early; fetch a fresh one next attempt
+		s.tokens.Invalidate(oauth2)
 		return s.handleRetry(task, fmt.Errorf("callback returned status %d", resp.StatusCode))
+	} else if s.retryableStatus(task, resp.StatusCode) {
+		return s.handleRetry(task, statusError(resp))
 	} else {
-		// Client error - don't retry
-		return s.handleFailure(task, fmt.Errorf("callback returned status %d", resp.StatusCode))
+		return s.handleFailure(task, statusError(resp))
+	}
+}
+
+// send sends the callback request, following redirects up to the task's limit. A redirect is
+// followed with the same POST, body and headers whatever its status, so the payload is never
+// dropped; the Authorization header is only kept on the same host. Redirect targets are checked
+// against the URL policy. The URL of the last request is recorded in attempt
+func (s *Service) send(task *entity.Task, req *http.Request, body []byte, attempt *Attempt) (*http.Response, error) {
+	limit := s.maxRedirects
+	if task.FollowRedirects {
+		limit = MaxRedirects
+	}
+
+	for redirects := 0; ; redirects++ {
+		attempt.FinalURL = req.URL.String()
+		resp, err := s.callbackClient.Do(req)
+		if err != nil || redirects >= limit || !isRedirect(resp.StatusCode) {
+			return resp, err
+		}
+		location, err := resp.Location()
+		if err != nil {
+			// Classified like any other response with its status
+			return resp, nil
+		}
+		s.readResponseBody(resp.Body)
+		resp.Body.Close()
+
+		if s.urlPolicy != nil {
+			if err := s.urlPolicy.checkStatic(location.String()); err != nil {
+				return nil, err
+			}
+		}
+		next, err := http.NewRequestWithContext(req.Context(), req.Method, location.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		next.Header = req.Header.Clone()
+		if !strings.EqualFold(next.URL.Hostname(), req.URL.Hostname()) {
+			next.Header.Del("Authorization")
+		}
+		req = next
+	}
+}
+
+// isRedirect reports whether net/http would follow a response with the status
+func isRedirect(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// statusError describes a non-2xx response, with the target of a redirect that wasn't followed
+func statusError(resp *http.Response) error {
+	if location := resp.Header.Get("Location"); isRedirect(resp.StatusCode) && location != "" {
+		return fmt.Errorf("callback returned status %d redirecting to %s, which wasn't followed", resp.StatusCode, location)
+	}
+	return fmt.Errorf("callback returned status %d", resp.StatusCode)
+}
+
+// retryableStatus reports whether a non-2xx status should be retried
+// The task's codes take precedence over the service's; by default 5xx and 429 are retried
+func (s *Service) retryableStatus(task *entity.Task, code int) bool {
+	codes := task.RetryableStatusCodes
+	if codes == nil {
+		codes = s.retryableCodes
+	}
+	if codes == nil {
+		return code >= 500 || code == http.StatusTooManyRequests
+	}
+	return slices.Contains(codes, code)
+}
+
+// oauth2Config returns the task's OAuth2 settings, falling back to the service's
+func (s *Service) oauth2Config(task *entity.Task) *entity.OAuth2Config {
+	if task.CallbackOAuth2 != nil {
+		return task.CallbackOAuth2
 	}
+	return s.oauth2
+}
+
+// readResponseBody keeps a snippet of the body and drains the rest up to the limit
+// Draining lets the transport reuse the keep-alive connection; larger bodies are abandoned
+func (s *Service) readResponseBody(body io.Reader) *string {
+	limited := io.LimitReader(body, s.responseBodyLimit)
+	snippet, _ := io.ReadAll(io.LimitReader(limited, responseSnippetLimit))
+	io.Copy(io.Discard, limited)
+
+	if len(snippet) == 0 {
+		return nil
+	}
+	// The snippet may end mid-character
+	text := strings.ToValidUTF8(string(snippet), "")
+	return &text
 }
 
 // handleSuccess marks task as completed
 func (s *Service) handleSuccess(task *entity.Task) error {
-	task.MarkAsCompleted()
+	task.MarkAsCompleted(s.clock.Now())
 	task.CallbackAttempts++
 	status := 200
 	task.LastCallbackStatus = &status
-	now := time.Now()
+	now := s.clock.Now()
 	task.LastCallbackAt = &now
 
 	s.logger.Info("Task completed successfully",
 		zap.String("task_id", task.ID),
+		logger.RequestID(task.RequestID),
 		zap.Int("callback_attempts", task.CallbackAttempts))
 
 	return nil
@@ -133,41 +486,36 @@ func (s *Service) handleRetry(task *entity.Task, err error) error {
 	task.CallbackAttempts++
 	status := 500
 	task.LastCallbackStatus = &status
-	now := time.Now()
+	now := s.clock.Now()
 	task.LastCallbackAt = &now
 	errMsg := err.Error()
 	task.LastCallbackError = &errMsg
 
 	s.logger.Warn("Task callback failed, will retry",
 		zap.String("task_id", task.ID),
+		logger.RequestID(task.RequestID),
 		zap.Int("callback_attempts", task.CallbackAttempts),
 		zap.Error(err))
 
 	return err
 }
 
-// handleFailure marks task as permanently failed
+// handleFailure records a failure that must not be retried; the returned error wraps ErrPermanent
 func (s *Service) handleFailure(task *entity.Task, err error) error {
-	task.MarkAsFailed(err)
+	err = fmt.Errorf("%w: %w", ErrPermanent, err)
 	task.CallbackAttempts++
 	status := 400
 	task.LastCallbackStatus = &status
-	now := time.Now()
+	now := s.clock.Now()
 	task.LastCallbackAt = &now
 	errMsg := err.Error()
 	task.LastCallbackError = &errMsg
 
 	s.logger.Error("Task failed permanently",
 		zap.String("task_id", task.ID),
+		logger.RequestID(task.RequestID),
 		zap.Int("callback_attempts", task.CallbackAttempts),
 		zap.Error(err))
 
 	return err
 }
-
-// generateSignature creates an HMAC signature for the payload
-func (s *Service) generateSignature(payload []byte) string {
-	h := hmac.New(sha256.New, []byte(s.signingSecret))
-	h.Write(payload)
-	return "sha256=" + hex.EncodeToString(h.Sum(nil))
-}
@@ -0,0 +1,271 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/circuitbreaker"
+)
+
+func TestDeliverCallbackHonorsTaskTimeout(t *testing.T) {
+	// Scale task timeouts down so "5s" and "60s" become 5ms and 60ms
+	timeoutUnit = time.Millisecond
+	defer func() { timeoutUnit = time.Second }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(30 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	svc := NewService(&http.Client{Timeout: time.Minute}, nil, "", 0, zap.NewNop())
+
+	t.Run("Short task timeout expires", func(t *testing.T) {
+		task := &entity.Task{ID: "short", CallbackURL: server.URL, CallbackTimeoutSecs: 5}
+		err := svc.DeliverCallback(context.Background(), task)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.NotEqual(t, entity.TaskStatusCompleted, task.Status)
+	})
+
+	t.Run("Long task timeout succeeds", func(t *testing.T) {
+		task := &entity.Task{ID: "long", CallbackURL: server.URL, CallbackTimeoutSecs: 60}
+		err := svc.DeliverCallback(context.Background(), task)
+		assert.NoError(t, err)
+		assert.Equal(t, entity.TaskStatusCompleted, task.Status)
+	})
+
+	t.Run("Client timeout is an upper bound", func(t *testing.T) {
+		bounded := NewService(&http.Client{Timeout: 5 * time.Millisecond}, nil, "", 0, zap.NewNop())
+		task := &entity.Task{ID: "bounded", CallbackURL: server.URL, CallbackTimeoutSecs: 60}
+		assert.Error(t, bounded.DeliverCallback(context.Background(), task))
+	})
+}
+
+func TestDeliverCallbackCapturesResponseSnippet(t *testing.T) {
+	body := strings.Repeat("x", responseSnippetLimit) + "truncated"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	svc := NewService(&http.Client{Timeout: time.Minute}, nil, "", 0, zap.NewNop())
+	task := &entity.Task{ID: "snippet", CallbackURL: server.URL}
+	assert.Error(t, svc.DeliverCallback(context.Background(), task))
+
+	if assert.NotNil(t, task.LastCallbackResponse) {
+		assert.Equal(t, body[:responseSnippetLimit], *task.LastCallbackResponse)
+	}
+}
+
+func TestDeliverCallbackRecordsDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	svc := NewService(&http.Client{Timeout: time.Minute}, nil, "", 0, zap.NewNop())
+	task := &entity.Task{ID: "duration", CallbackURL: server.URL}
+	assert.NoError(t, svc.DeliverCallback(context.Background(), task))
+
+	if assert.NotNil(t, task.CallbackDurationMs) {
+		assert.GreaterOrEqual(t, *task.CallbackDurationMs, int64(20))
+	}
+}
+
+func TestDeliverCallbackOriginRequestID(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("X-Origin-Request-ID"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	svc := NewService(&http.Client{Timeout: time.Minute}, nil, "", 0, zap.NewNop())
+	requestID := "req-123"
+	assert.NoError(t, svc.DeliverCallback(context.Background(), &entity.Task{ID: "api", CallbackURL: server.URL, RequestID: &requestID}))
+	assert.NoError(t, svc.DeliverCallback(context.Background(), &entity.Task{ID: "sdk", CallbackURL: server.URL}))
+
+	assert.Equal(t, []string{"req-123", ""}, got)
+}
+
+func TestDeliverCallbackRedirects(t *testing.T) {
+	type received struct {
+		method, body, signature string
+	}
+	var got []received
+	mux := http.NewServeMux()
+	mux.HandleFunc("/signed", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, received{r.Method, string(body), r.Header.Get(SignatureHeader)})
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		http.Redirect(w, r, "/signed", status)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("Not followed by default", func(t *testing.T) {
+		got = nil
+		svc := NewService(&http.Client{Timeout: time.Minute}, nil, "secret", 0, zap.NewNop())
+		task := &entity.Task{ID: "default", CallbackURL: server.URL + "/hook?status=302", Payload: []byte(`{"a":1}`)}
+		attempt, err := svc.Deliver(context.Background(), task)
+		assert.True(t, errors.Is(err, ErrPermanent), "got %v", err)
+		assert.Contains(t, err.Error(), "status 302 redirecting to /signed")
+		assert.Equal(t, http.StatusFound, attempt.StatusCode)
+		assert.Equal(t, task.CallbackURL, attempt.FinalURL)
+		assert.Empty(t, got)
+	})
+
+	for _, status := range []int{http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect} {
+		t.Run(fmt.Sprintf("Followed with %d", status), func(t *testing.T) {
+			got = nil
+			svc := NewService(&http.Client{Timeout: time.Minute}, nil, "secret", 0, zap.NewNop(), WithMaxRedirects(1))
+			task := &entity.Task{ID: "followed", CallbackURL: fmt.Sprintf("%s/hook?status=%d", server.URL, status), Payload: []byte(`{"a":1}`)}
+			attempt, err := svc.Deliver(context.Background(), task)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, attempt.StatusCode)
+			assert.Equal(t, server.URL+"/signed", attempt.FinalURL)
+			assert.Equal(t, []received{{http.MethodPost, `{"a":1}`, sign("secret", []byte(`{"a":1}`))}}, got,
+				"the redirect is followed with the same signed POST")
+		})
+	}
+
+	t.Run("Followed for the task", func(t *testing.T) {
+		got = nil
+		svc := NewService(&http.Client{Timeout: time.Minute}, nil, "", 0, zap.NewNop())
+		task := &entity.Task{ID: "task", CallbackURL: server.URL + "/hook?status=307", Payload: []byte(`{}`), FollowRedirects: true}
+		require.NoError(t, svc.DeliverCallback(context.Background(), task))
+		assert.Len(t, got, 1)
+	})
+}
+
+func TestDeliverCallbackClassification(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(code)
+	}))
+	defer server.Close()
+
+	// Nothing listens on this address once the listener is closed
+	closed := httptest.NewServer(http.NotFoundHandler())
+	refusedURL := closed.URL
+	closed.Close()
+
+	const (
+		success = "success"
+		retry   = "retry"
+		fail    = "permanent"
+	)
+	tests := []struct {
+		name         string
+		serviceCodes []int
+		taskCodes    []int
+		status       int
+		url          string
+		want         string
+	}{
+		{name: "2xx succeeds", status: 204, want: success},
+		{name: "5xx retries by default", status: 503, want: retry},
+		{name: "429 retries by default", status: 429, want: retry},
+		{name: "409 fails by default", status: 409, want: fail},
+		{name: "422 fails by default", status: 422, want: fail},
+		{name: "Configured 409 retries", serviceCodes: []int{409, 503}, status: 409, want: retry},
+		{name: "Configured set replaces 5xx default", serviceCodes: []int{409, 503}, status: 500, want: fail},
+		{name: "Configured 422 fails", serviceCodes: []int{409, 503}, status: 422, want: fail},
+		{name: "Task override wins", serviceCodes: []int{409}, taskCodes: []int{422}, status: 422, want: retry},
+		{name: "Task override replaces service codes", serviceCodes: []int{409}, taskCodes: []int{422}, status: 409, want: fail},
+		{name: "Empty task override retries nothing", taskCodes: []int{}, status: 503, want: fail},
+		{name: "Connection refused retries", serviceCodes: []int{}, url: refusedURL, want: retry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []ServiceOption
+			if tt.serviceCodes != nil {
+				opts = append(opts, WithRetryableStatusCodes(tt.serviceCodes))
+			}
+			svc := NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop(), opts...)
+
+			url := tt.url
+			if url == "" {
+				url = fmt.Sprintf("%s/?status=%d", server.URL, tt.status)
+			}
+			task := &entity.Task{ID: "classify", CallbackURL: url, RetryableStatusCodes: tt.taskCodes}
+			err := svc.DeliverCallback(context.Background(), task)
+
+			switch tt.want {
+			case success:
+				assert.NoError(t, err)
+			case retry:
+				assert.Error(t, err)
+				assert.False(t, errors.Is(err, ErrPermanent), "error = %v", err)
+			case fail:
+				assert.True(t, errors.Is(err, ErrPermanent), "error = %v", err)
+			}
+			if err != nil {
+				assert.Equal(t, 1, task.CallbackAttempts)
+				assert.NotNil(t, task.LastCallbackError)
+			}
+		})
+	}
+}
+
+func TestTestDelivery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !VerifySignature(r.Header, body, "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "status %d", status)
+	}))
+	defer server.Close()
+
+	// An open circuit neither refuses test deliveries nor counts them
+	breaker := circuitbreaker.NewCircuitBreaker(1, time.Hour)
+	svc := NewService(&http.Client{Timeout: time.Second}, breaker, "secret", 0, zap.NewNop())
+
+	for status, outcome := range map[int]string{200: OutcomeSuccess, 503: OutcomeRetry, 422: OutcomeFailure} {
+		url := fmt.Sprintf("%s/?status=%d", server.URL, status)
+		breaker.RecordFailure(url)
+		task := &entity.Task{ID: "test", CallbackURL: url, Payload: []byte(`{}`)}
+
+		result, err := svc.TestDelivery(context.Background(), task)
+		require.NoError(t, err)
+		assert.Equal(t, outcome, result.Outcome, "status %d", status)
+		assert.Equal(t, status, result.StatusCode)
+		assert.True(t, result.Signed)
+		if assert.NotNil(t, result.Response) {
+			assert.Equal(t, fmt.Sprintf("status %d", status), *result.Response)
+		}
+		assert.Equal(t, 1, breaker.GetFailureCount(url), "status %d", status)
+	}
+
+	policy, err := NewURLPolicy(URLPolicyConfig{Denylist: []string{"127.0.0.0/8"}})
+	require.NoError(t, err)
+	denied := NewService(&http.Client{Timeout: time.Second}, nil, "secret", 0, zap.NewNop(), WithURLPolicy(policy))
+	_, err = denied.TestDelivery(context.Background(), &entity.Task{ID: "test", CallbackURL: server.URL})
+	assert.True(t, errors.Is(err, ErrURLNotAllowed))
+}
@@ -0,0 +1,41 @@
+package callback
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of the callback body made with the current secret
+	SignatureHeader = "X-Signature"
+
+	// PreviousSignatureHeader carries the signature made with the previous secret during a rotation
+	PreviousSignatureHeader = "X-Signature-Previous"
+)
+
+// sign creates an HMAC signature for the payload
+func sign(secret string, payload []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(payload)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifySignature reports whether a callback body was signed with any of the secrets
+// Both the X-Signature and X-Signature-Previous headers are checked, so a receiver keeps
+// accepting deliveries across a rotation whether it holds the new secret, the old one or both
+func VerifySignature(header http.Header, body []byte, secrets ...string) bool {
+	for _, name := range []string{SignatureHeader, PreviousSignatureHeader} {
+		signature := header.Get(name)
+		if signature == "" {
+			continue
+		}
+		for _, secret := range secrets {
+			if secret != "" && hmac.Equal([]byte(signature), []byte(sign(secret, body))) {
+				return true
+			}
+		}
+	}
+	return false
+}
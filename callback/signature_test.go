@@ -0,0 +1,68 @@
+package callback
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+func TestSignatureRotation(t *testing.T) {
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	var got delivery
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = delivery{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	deliver := func(t *testing.T, secret string, opts ...ServiceOption) delivery {
+		svc := NewService(&http.Client{Timeout: time.Minute}, nil, secret, 0, zap.NewNop(), opts...)
+		require.NoError(t, svc.DeliverCallback(context.Background(), &entity.Task{ID: "signed", CallbackURL: server.URL}))
+		return got
+	}
+
+	t.Run("Before rotation", func(t *testing.T) {
+		d := deliver(t, "old")
+		assert.Empty(t, d.header.Get(PreviousSignatureHeader))
+		assert.True(t, VerifySignature(d.header, d.body, "old"))
+		assert.False(t, VerifySignature(d.header, d.body, "new"))
+	})
+
+	t.Run("During rotation", func(t *testing.T) {
+		d := deliver(t, "new", WithPreviousSigningSecret("old"))
+		assert.NotEqual(t, d.header.Get(SignatureHeader), d.header.Get(PreviousSignatureHeader))
+
+		// Receivers that haven't updated, have updated, or accept both all verify
+		assert.True(t, VerifySignature(d.header, d.body, "old"))
+		assert.True(t, VerifySignature(d.header, d.body, "new"))
+		assert.True(t, VerifySignature(d.header, d.body, "new", "old"))
+		assert.False(t, VerifySignature(d.header, d.body, "other"))
+		assert.False(t, VerifySignature(d.header, append(d.body, ' '), "new", "old"), "tampered body")
+	})
+
+	t.Run("After rotation", func(t *testing.T) {
+		d := deliver(t, "new")
+		assert.Empty(t, d.header.Get(PreviousSignatureHeader))
+		assert.True(t, VerifySignature(d.header, d.body, "new", "old"))
+		assert.False(t, VerifySignature(d.header, d.body, "old"))
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		d := deliver(t, "")
+		assert.Empty(t, d.header.Get(SignatureHeader))
+		assert.False(t, VerifySignature(d.header, d.body, "", "old"))
+	})
+}
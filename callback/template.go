@@ -0,0 +1,84 @@
+package callback
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// TemplateData is what a callback body template can reference, e.g.
+//
+//	{"event": {{.Payload}}, "source": "later", "task_id": {{json .ID}}}
+//
+// Payload is the raw stored payload; use the json function to quote other fields
+type TemplateData struct {
+	ID          string
+	Name        string
+	Payload     string
+	Priority    int
+	Tags        []string
+	TenantID    string
+	RetryCount  int
+	CreatedAt   time.Time
+	ScheduledAt time.Time
+}
+
+// templateFuncs are available to every body template
+var templateFuncs = template.FuncMap{
+	// json encodes a value, e.g. {{json .Name}} renders a quoted, escaped string
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// ParseBodyTemplate parses a callback body template; references to unknown fields are errors
+func ParseBodyTemplate(text string) (*template.Template, error) {
+	return template.New("callback_body").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// ValidateBodyTemplate parses the task's body template and renders it once against the task
+// so broken templates and oversized output are rejected at creation
+func ValidateBodyTemplate(task *entity.Task) error {
+	if task.CallbackBodyTemplate == nil {
+		return nil
+	}
+	_, err := renderBody(task)
+	return err
+}
+
+// renderBody returns the callback body: the rendered template, or the payload if the task has none
+func renderBody(task *entity.Task) ([]byte, error) {
+	if task.CallbackBodyTemplate == nil {
+		return task.Payload, nil
+	}
+
+	tmpl, err := ParseBodyTemplate(*task.CallbackBodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid callback body template: %w", err)
+	}
+
+	var body bytes.Buffer
+	err = tmpl.Execute(&body, TemplateData{
+		ID:          task.ID,
+		Name:        task.Name,
+		Payload:     string(task.Payload),
+		Priority:    task.Priority,
+		Tags:        task.Tags,
+		TenantID:    task.TenantID,
+		RetryCount:  task.RetryCount,
+		CreatedAt:   task.CreatedAt,
+		ScheduledAt: task.ScheduledAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render callback body template: %w", err)
+	}
+	if body.Len() > entity.MaxPayloadSize {
+		return nil, fmt.Errorf("rendered callback body exceeds %d bytes", entity.MaxPayloadSize)
+	}
+	return body.Bytes(), nil
+}
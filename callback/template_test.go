@@ -0,0 +1,78 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+func TestValidateBodyTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		payload  string
+		wantErr  bool
+	}{
+		{name: "Wrapper template", template: `{"event": {{.Payload}}, "task_id": {{json .ID}}}`, payload: `{"a":1}`},
+		{name: "Parse error", template: `{{.Payload`, wantErr: true},
+		{name: "Unknown field", template: `{{.Secret}}`, wantErr: true},
+		{name: "Output over size limit", template: `{{.Payload}}{{.Payload}}`, payload: strings.Repeat("x", entity.MaxPayloadSize/2+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &entity.Task{ID: "t1", Payload: entity.JSONBytes(tt.payload), CallbackBodyTemplate: &tt.template}
+			err := ValidateBodyTemplate(task)
+			assert.Equal(t, tt.wantErr, err != nil, "ValidateBodyTemplate() error = %v", err)
+		})
+	}
+}
+
+func TestDeliverCallbackRendersBodyTemplate(t *testing.T) {
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	svc := NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop())
+
+	t.Run("Body is rendered", func(t *testing.T) {
+		tmpl := `{"event": {{.Payload}}, "source": "later", "task_id": {{json .ID}}, "name": {{json .Name}}}`
+		task := &entity.Task{
+			ID:                   "task-1",
+			Name:                 `say "hi"`,
+			CallbackURL:          server.URL,
+			Payload:              entity.JSONBytes(`{"order":42}`),
+			CallbackBodyTemplate: &tmpl,
+		}
+		require.NoError(t, svc.DeliverCallback(context.Background(), task))
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(received, &body))
+		assert.Equal(t, map[string]any{"order": float64(42)}, body["event"])
+		assert.Equal(t, "later", body["source"])
+		assert.Equal(t, "task-1", body["task_id"])
+		assert.Equal(t, `say "hi"`, body["name"])
+	})
+
+	t.Run("Execution error is permanent", func(t *testing.T) {
+		tmpl := `{{index .Tags 3}}`
+		task := &entity.Task{ID: "task-2", CallbackURL: server.URL, CallbackBodyTemplate: &tmpl}
+		err := svc.DeliverCallback(context.Background(), task)
+		assert.True(t, errors.Is(err, ErrPermanent), "error = %v", err)
+	})
+}
@@ -0,0 +1,220 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// ErrURLNotAllowed is returned when a callback URL violates the URL policy
+var ErrURLNotAllowed = errors.New("callback URL not allowed")
+
+// DefaultDeniedNetworks are the link-local and private ranges denied when no denylist is configured
+// Loopback is not included so local development keeps working; add 127.0.0.0/8 and ::1/128 to deny it
+var DefaultDeniedNetworks = []string{
+	"169.254.0.0/16", // IPv4 link-local, including cloud metadata endpoints
+	"fe80::/10",      // IPv6 link-local
+	"10.0.0.0/8",     // RFC1918
+	"172.16.0.0/12",  // RFC1918
+	"192.168.0.0/16", // RFC1918
+	"fc00::/7",       // IPv6 unique local
+}
+
+// Resolver looks up the addresses of callback hosts; *net.Resolver implements it
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// URLPolicyConfig configures which callback URLs may be used
+type URLPolicyConfig struct {
+	HTTPSOnly bool
+	Denylist  []string // CIDRs, IPs or hostnames ("*.example.com" matches subdomains); nil uses DefaultDeniedNetworks
+	Allowlist []string // Hostnames ("*.example.com" matches subdomains); empty allows any host not denied
+	Resolver  Resolver // nil uses net.DefaultResolver
+}
+
+// URLPolicy restricts callback URLs to protect against server-side request forgery
+type URLPolicy struct {
+	httpsOnly    bool
+	deniedNets   []*net.IPNet
+	deniedHosts  []string
+	allowedHosts []string
+	resolver     Resolver
+}
+
+// NewURLPolicy builds a URL policy from its configuration
+func NewURLPolicy(cfg URLPolicyConfig) (*URLPolicy, error) {
+	denylist := cfg.Denylist
+	if denylist == nil {
+		denylist = DefaultDeniedNetworks
+	}
+
+	p := &URLPolicy{httpsOnly: cfg.HTTPSOnly, resolver: cfg.Resolver}
+	if p.resolver == nil {
+		p.resolver = net.DefaultResolver
+	}
+	for _, entry := range denylist {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid denylist CIDR %q: %w", entry, err)
+			}
+			p.deniedNets = append(p.deniedNets, ipNet)
+		} else if ip := net.ParseIP(entry); ip != nil {
+			p.deniedNets = append(p.deniedNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+		} else {
+			p.deniedHosts = append(p.deniedHosts, strings.ToLower(entry))
+		}
+	}
+	for _, entry := range cfg.Allowlist {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			p.allowedHosts = append(p.allowedHosts, strings.ToLower(entry))
+		}
+	}
+	return p, nil
+}
+
+// Check validates the URL and every address its host resolves to, so tasks with denied
+// callback URLs are refused when created. DNS can change before delivery, so deliveries only
+// repeat the checks that don't need it and leave addresses to DialContext
+// Lookup failures are not policy violations; the request itself will fail instead
+func (p *URLPolicy) Check(ctx context.Context, rawURL string) error {
+	u, err := p.parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	host := u.Hostname()
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	addrs, err := p.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if p.deniedIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to denied address %s", ErrURLNotAllowed, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// DialContext wraps dialer for an http.Transport so that it only connects to addresses the
+// policy allows. The host is resolved once, and the connection goes to an address that was
+// checked, so a name rebound to a denied address after Check is still refused; the dialer's
+// Control checks the connected address again, covering literal IPs too
+func (p *URLPolicy) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	checked := *dialer
+	checked.Control = p.dialControl
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return checked.DialContext(ctx, network, address)
+		}
+
+		addrs, err := p.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			if p.deniedIP(addr.IP) {
+				return nil, fmt.Errorf("%w: %s resolves to denied address %s", ErrURLNotAllowed, host, addr.IP)
+			}
+		}
+		dialErr := error(&net.DNSError{Err: "no such host", Name: host, IsNotFound: true})
+		for i, addr := range addrs {
+			conn, err := checked.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			if i == 0 {
+				dialErr = err
+			}
+		}
+		return nil, dialErr
+	}
+}
+
+// dialControl refuses connections to denied addresses right before they are made
+func (p *URLPolicy) dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip != nil && p.deniedIP(ip) {
+		return fmt.Errorf("%w: address %s is denied", ErrURLNotAllowed, ip)
+	}
+	return nil
+}
+
+// checkStatic applies the checks that don't need DNS, as deliveries do
+func (p *URLPolicy) checkStatic(rawURL string) error {
+	_, err := p.parse(rawURL)
+	return err
+}
+
+// parse applies the checks that don't need DNS
+func (p *URLPolicy) parse(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("%w: invalid URL %q", ErrURLNotAllowed, rawURL)
+	}
+
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if p.httpsOnly {
+			return nil, fmt.Errorf("%w: only https callback URLs are accepted", ErrURLNotAllowed)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrURLNotAllowed, u.Scheme)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if len(p.allowedHosts) > 0 && !matchHost(p.allowedHosts, host) {
+		return nil, fmt.Errorf("%w: host %s is not in the allowlist", ErrURLNotAllowed, host)
+	}
+	if matchHost(p.deniedHosts, host) {
+		return nil, fmt.Errorf("%w: host %s is denied", ErrURLNotAllowed, host)
+	}
+	if ip := net.ParseIP(host); ip != nil && p.deniedIP(ip) {
+		return nil, fmt.Errorf("%w: address %s is denied", ErrURLNotAllowed, ip)
+	}
+	return u, nil
+}
+
+// deniedIP reports whether the address falls in a denied network
+func (p *URLPolicy) deniedIP(ip net.IP) bool {
+	for _, ipNet := range p.deniedNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost reports whether host equals a pattern or, for "*.example.com", is a subdomain of it
+func matchHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
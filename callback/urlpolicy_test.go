@@ -0,0 +1,160 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+func TestURLPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     URLPolicyConfig
+		url     string
+		allowed bool
+	}{
+		{name: "Public address", url: "https://203.0.113.10/hook", allowed: true},
+		{name: "Metadata endpoint", url: "http://169.254.169.254/latest/meta-data"},
+		{name: "RFC1918 address", url: "http://10.1.2.3:8080/admin"},
+		{name: "IPv6 link-local", url: "http://[fe80::1]/"},
+		{name: "Loopback allowed by default", url: "http://127.0.0.1:9000/hook", allowed: true},
+		{name: "Unsupported scheme", url: "file:///etc/passwd"},
+		{name: "Missing host", url: "http:///hook"},
+		{name: "HTTPS only", cfg: URLPolicyConfig{HTTPSOnly: true}, url: "http://203.0.113.10/hook"},
+		{name: "Empty denylist", cfg: URLPolicyConfig{Denylist: []string{}}, url: "http://10.1.2.3/", allowed: true},
+		{name: "Denied hostname", cfg: URLPolicyConfig{Denylist: []string{"*.internal"}}, url: "https://admin.internal/"},
+		{name: "Denied single IP", cfg: URLPolicyConfig{Denylist: []string{"203.0.113.10"}}, url: "https://203.0.113.10/hook"},
+		{name: "Host not in allowlist", cfg: URLPolicyConfig{Allowlist: []string{"*.example.com"}}, url: "https://203.0.113.10/"},
+		{name: "Allowlisted host", cfg: URLPolicyConfig{Allowlist: []string{"*.example.com"}}, url: "https://hooks.example.com/", allowed: true},
+		{name: "Resolved loopback denied", cfg: URLPolicyConfig{Denylist: []string{"127.0.0.0/8", "::1/128"}}, url: "http://localhost:9000/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewURLPolicy(tt.cfg)
+			require.NoError(t, err)
+
+			err = policy.Check(context.Background(), tt.url)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrURLNotAllowed), "Check() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestNewURLPolicyInvalidCIDR(t *testing.T) {
+	_, err := NewURLPolicy(URLPolicyConfig{Denylist: []string{"10.0.0.0/33"}})
+	assert.Error(t, err)
+}
+
+func TestDeliverCallbackEnforcesURLPolicy(t *testing.T) {
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	t.Run("Denied at delivery time", func(t *testing.T) {
+		policy, err := NewURLPolicy(URLPolicyConfig{Denylist: []string{"127.0.0.0/8"}})
+		require.NoError(t, err)
+
+		svc := NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop(), WithURLPolicy(policy))
+		task := &entity.Task{ID: "denied", CallbackURL: receiver.URL}
+		err = svc.DeliverCallback(context.Background(), task)
+		assert.True(t, errors.Is(err, ErrURLNotAllowed))
+		assert.True(t, errors.Is(err, ErrPermanent))
+		assert.Zero(t, hits.Load())
+	})
+
+	t.Run("Redirect to denied host", func(t *testing.T) {
+		redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+		}))
+		defer redirector.Close()
+
+		policy, err := NewURLPolicy(URLPolicyConfig{Denylist: []string{"localhost"}})
+		require.NoError(t, err)
+
+		svc := NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop(), WithURLPolicy(policy))
+		task := &entity.Task{ID: "redirected", CallbackURL: redirector.URL, FollowRedirects: true}
+		assert.True(t, errors.Is(svc.DeliverCallback(context.Background(), task), ErrURLNotAllowed))
+		assert.Zero(t, hits.Load())
+	})
+}
+
+// rebindingResolver answers with each of its addresses in turn, like DNS with a short TTL
+// rebound between lookups
+type rebindingResolver struct {
+	addrs   []string
+	lookups atomic.Int32
+}
+
+func (r *rebindingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	i := int(r.lookups.Add(1)) - 1
+	return []net.IPAddr{{IP: net.ParseIP(r.addrs[min(i, len(r.addrs)-1)])}}, nil
+}
+
+func TestDialRefusesReboundAddresses(t *testing.T) {
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+	callbackURL := strings.Replace(receiver.URL, "127.0.0.1", "hooks.example.com", 1)
+
+	deliver := func(t *testing.T, resolver *rebindingResolver) error {
+		policy, err := NewURLPolicy(URLPolicyConfig{Denylist: []string{"127.0.0.0/8"}, Resolver: resolver})
+		require.NoError(t, err)
+
+		// Created while the name resolves to a public address
+		require.NoError(t, policy.Check(context.Background(), callbackURL))
+
+		client, err := NewHTTPClient(time.Second, TransportOptions{URLPolicy: policy})
+		require.NoError(t, err)
+		svc := NewService(client, nil, "", 0, zap.NewNop(), WithURLPolicy(policy))
+		return svc.DeliverCallback(context.Background(), &entity.Task{ID: "rebound", CallbackURL: callbackURL})
+	}
+
+	t.Run("Rebound to a denied address", func(t *testing.T) {
+		err := deliver(t, &rebindingResolver{addrs: []string{"203.0.113.10", "127.0.0.1"}})
+		assert.True(t, errors.Is(err, ErrURLNotAllowed), "DeliverCallback() error = %v", err)
+		assert.True(t, errors.Is(err, ErrPermanent))
+		assert.Zero(t, hits.Load())
+	})
+
+	t.Run("Literal address checked when connecting", func(t *testing.T) {
+		policy, err := NewURLPolicy(URLPolicyConfig{Denylist: []string{"127.0.0.0/8"}})
+		require.NoError(t, err)
+		dial := policy.DialContext(&net.Dialer{Timeout: time.Second})
+		_, err = dial(context.Background(), "tcp", receiver.Listener.Addr().String())
+		assert.True(t, errors.Is(err, ErrURLNotAllowed), "dial error = %v", err)
+	})
+
+	t.Run("Resolved address dialled", func(t *testing.T) {
+		// A public address at creation and a permitted one at delivery, where the receiver listens
+		policy, err := NewURLPolicy(URLPolicyConfig{Denylist: []string{"10.0.0.0/8"}, Resolver: &rebindingResolver{addrs: []string{"203.0.113.10", "127.0.0.1"}}})
+		require.NoError(t, err)
+		require.NoError(t, policy.Check(context.Background(), callbackURL))
+		client, err := NewHTTPClient(time.Second, TransportOptions{URLPolicy: policy})
+		require.NoError(t, err)
+		svc := NewService(client, nil, "", 0, zap.NewNop(), WithURLPolicy(policy))
+		assert.NoError(t, svc.DeliverCallback(context.Background(), &entity.Task{ID: "resolved", CallbackURL: callbackURL}))
+		assert.Equal(t, int32(1), hits.Load())
+	})
+}
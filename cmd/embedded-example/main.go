@@ -41,8 +41,9 @@ func main() {
 	laterSDK, err := later.New(
 		later.WithSeparateDB(dsn),
 		later.WithRoutePrefix("/internal/tasks"),
-		later.WithWorkerPoolSize(10),   // Smaller pool for demo
-		later.WithAutoMigration(false), // Automatically run migrations
+		later.WithWorkerPoolSize(10),    // Smaller pool for demo
+		later.WithAutoMigration(false),  // Automatically run migrations
+		later.WithWebSocketEvents(true), // Stream task events on /internal/tasks/tasks/stream
 	)
 	if err != nil {
 		log.Fatalf("Failed to initialize Later: %v", err)
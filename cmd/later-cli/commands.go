@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/domain/entity"
+)
+
+func (c *cli) taskCreate(ctx context.Context, args []string) error {
+	var (
+		req          dto.CreateTaskRequest
+		payload      string
+		scheduledFor string
+		tags         string
+		maxRetries   int
+		timeout      int
+		dependsOn    string
+	)
+	fs := c.flags("task create")
+	fs.StringVar(&req.Name, "name", "", "task name")
+	fs.StringVar(&req.CallbackURL, "callback-url", "", "URL the callback is delivered to")
+	fs.StringVar(&payload, "payload", "", "JSON payload, or @file to read it from a file")
+	fs.StringVar(&scheduledFor, "scheduled-for", "", "RFC 3339 execution time (default now)")
+	fs.IntVar(&req.Priority, "priority", 0, "priority, 0 to 10")
+	fs.StringVar(&tags, "tags", "", "comma-separated tags")
+	fs.IntVar(&maxRetries, "max-retries", -1, "callback retries (server default when unset)")
+	fs.IntVar(&timeout, "timeout-seconds", 0, "callback timeout in seconds (server default when unset)")
+	fs.StringVar(&dependsOn, "depends-on", "", "ID of the task this one waits for")
+	if _, err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if req.Name == "" || req.CallbackURL == "" || payload == "" {
+		return c.usageError("task create needs --name, --callback-url and --payload")
+	}
+
+	if strings.HasPrefix(payload, "@") {
+		data, err := os.ReadFile(payload[1:])
+		if err != nil {
+			return err
+		}
+		payload = string(data)
+	}
+	if !json.Valid([]byte(payload)) {
+		return fmt.Errorf("--payload is not valid JSON")
+	}
+	if scheduledFor != "" {
+		t, err := time.Parse(time.RFC3339, scheduledFor)
+		if err != nil {
+			return fmt.Errorf("--scheduled-for: %w", err)
+		}
+		req.ScheduledFor = &dto.CustomTime{Time: t}
+	}
+	if tags != "" {
+		req.Tags = strings.Split(tags, ",")
+	}
+	if maxRetries >= 0 {
+		req.MaxRetries = &maxRetries
+	}
+	if timeout > 0 {
+		req.TimeoutSeconds = &timeout
+	}
+	if dependsOn != "" {
+		req.DependsOn = &dependsOn
+	}
+	req.Payload = entity.JSONBytes(payload)
+
+	task, err := c.client.CreateTask(ctx, &req)
+	if err != nil {
+		return err
+	}
+	return c.printTask(task)
+}
+
+func (c *cli) taskGet(ctx context.Context, args []string) error {
+	var (
+		watch    bool
+		interval time.Duration
+	)
+	fs := c.flags("task get")
+	fs.BoolVar(&watch, "watch", false, "poll until the task completes or is dead-lettered")
+	fs.DurationVar(&interval, "interval", 2*time.Second, "polling interval for --watch")
+	id, err := c.taskID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	task, err := c.client.GetTask(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !watch {
+		return c.printTask(task)
+	}
+
+	// Report each change in table mode; JSON output is the final state only
+	for {
+		if c.output == "table" {
+			fmt.Fprintf(c.stdout, "%s  %-13s  attempts=%d retries=%d\n",
+				time.Now().Format(time.TimeOnly), task.Status, task.CallbackAttempts, task.RetryCount)
+		}
+		if terminal(task.Status) {
+			return c.printTask(task)
+		}
+
+		status, attempts := task.Status, task.CallbackAttempts
+		for task.Status == status && task.CallbackAttempts == attempts {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+			if task, err = c.client.GetTask(ctx, id); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// terminal reports whether a task's status is final
+func terminal(status entity.TaskStatus) bool {
+	return status == entity.TaskStatusCompleted || status == entity.TaskStatusDeadLettered ||
+		status == entity.TaskStatusExpired
+}
+
+// taskList lists tasks; status fixes the status filter when set
+func (c *cli) taskList(ctx context.Context, args []string, status string) error {
+	query := dto.ListTasksQuery{Page: 1, Limit: 20}
+	var (
+		filterStatus string
+		priority     int
+	)
+	fs := c.flags("task list")
+	if status == "" {
+		fs.StringVar(&filterStatus, "status", "", "status filter")
+	}
+	fs.StringVar(&query.Name, "name", "", "exact name filter")
+	fs.StringVar(&query.NamePrefix, "name-prefix", "", "name prefix filter")
+	fs.StringVar(&query.Tags, "tags", "", "comma-separated tags filter")
+	fs.IntVar(&priority, "priority", -1, "minimum priority")
+	fs.IntVar(&query.Page, "page", query.Page, "page number")
+	fs.IntVar(&query.Limit, "limit", query.Limit, "tasks per page, at most 100")
+	fs.StringVar(&query.SortBy, "sort-by", "", "created_at, scheduled_at or priority")
+	fs.StringVar(&query.SortOrder, "sort-order", "", "asc or desc")
+	if _, err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if status != "" {
+		filterStatus = status
+	}
+	if filterStatus != "" {
+		s := entity.TaskStatus(filterStatus)
+		query.Status = &s
+	}
+	if priority >= 0 {
+		query.Priority = &priority
+	}
+	list, err := c.client.ListTasks(ctx, &query)
+	if err != nil {
+		return err
+	}
+	return c.printTaskList(list)
+}
+
+// taskAction posts a task action, "retry" or "resurrect", and prints the updated task
+func (c *cli) taskAction(ctx context.Context, args []string, action string) error {
+	id, err := c.taskID(c.flags("task "+action), args)
+	if err != nil {
+		return err
+	}
+	var task *dto.TaskResponse
+	if action == "retry" {
+		task, err = c.client.RetryTask(ctx, id)
+	} else {
+		task, err = c.client.ResurrectTask(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	return c.printTask(task)
+}
+
+func (c *cli) taskDelete(ctx context.Context, args []string) error {
+	id, err := c.taskID(c.flags("task delete"), args)
+	if err != nil {
+		return err
+	}
+	if err := c.client.DeleteTask(ctx, id); err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return c.printJSON(map[string]any{"id": id, "deleted": true})
+	}
+	fmt.Fprintf(c.stdout, "Deleted task %s\n", id)
+	return nil
+}
+
+func (c *cli) stats(ctx context.Context, args []string) error {
+	var window string
+	fs := c.flags("stats")
+	fs.StringVar(&window, "window", "", "activity window: 1h, 24h or 7d (server default when unset)")
+	if _, err := c.parse(fs, args); err != nil {
+		return err
+	}
+	stats, err := c.client.Stats(ctx, window)
+	if err != nil {
+		return err
+	}
+	return c.printStats(stats)
+}
+
+// taskID parses a command taking a single task ID
+func (c *cli) taskID(fs *flag.FlagSet, args []string) (string, error) {
+	positional, err := c.parse(fs, args)
+	if err != nil {
+		return "", err
+	}
+	if len(positional) != 1 {
+		return "", c.usageError("%s needs a task ID", fs.Name())
+	}
+	return positional[0], nil
+}
@@ -0,0 +1,167 @@
+// Command later-cli administers a Later server through its HTTP API
+//
+// Usage:
+//
+//	later-cli [--url URL] [--api-key KEY] [--output table|json] <command> [flags] [args]
+//
+// The URL and API key default to the LATER_URL and LATER_API_KEY environment variables
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/usual2970/later/pkg/client"
+)
+
+const usage = `Usage: later-cli [global flags] <command> [flags] [args]
+
+Commands:
+  task create --name NAME --callback-url URL --payload JSON [flags]
+  task get ID [--watch] [--interval 2s]
+  task list [--status S] [--name N] [--name-prefix P] [--tags a,b] [--page N] [--limit N]
+  task retry ID
+  task resurrect ID
+  task delete ID
+  stats [--window 1h|24h|7d]
+  dead-letter list [--page N] [--limit N]
+
+Global flags (accepted anywhere on the command line):
+  --url URL                 API base URL (env LATER_URL, default http://localhost:8080)
+  --api-key KEY             API key (env LATER_API_KEY)
+  --output, -o table|json   Output format (default table)
+  --timeout DURATION        Per-request timeout (default 30s)
+`
+
+// errUsage reports a malformed command line; usage has been printed
+var errUsage = errors.New("invalid usage")
+
+// cli holds the global options shared by every command
+type cli struct {
+	baseURL string
+	apiKey  string
+	timeout time.Duration
+	output  string
+	stdout  io.Writer
+	stderr  io.Writer
+
+	// client is built from the global options once a command's flags are parsed
+	client *client.Client
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// run executes a command line and returns the process exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+	err := c.dispatch(ctx, args, getenv)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "later-cli: %v\n", err)
+		return 1
+	}
+}
+
+func (c *cli) dispatch(ctx context.Context, args []string, getenv func(string) string) error {
+	c.baseURL = getenv("LATER_URL")
+	if c.baseURL == "" {
+		c.baseURL = "http://localhost:8080"
+	}
+	c.apiKey = getenv("LATER_API_KEY")
+	c.timeout = client.DefaultTimeout
+	c.output = "table"
+
+	if len(args) == 0 {
+		fmt.Fprint(c.stderr, usage)
+		return errUsage
+	}
+
+	switch command := args[0]; command {
+	case "task":
+		if len(args) < 2 {
+			return c.usageError("task needs a subcommand")
+		}
+		switch args[1] {
+		case "create":
+			return c.taskCreate(ctx, args[2:])
+		case "get":
+			return c.taskGet(ctx, args[2:])
+		case "list":
+			return c.taskList(ctx, args[2:], "")
+		case "retry":
+			return c.taskAction(ctx, args[2:], "retry")
+		case "resurrect":
+			return c.taskAction(ctx, args[2:], "resurrect")
+		case "delete":
+			return c.taskDelete(ctx, args[2:])
+		default:
+			return c.usageError("unknown task subcommand %q", args[1])
+		}
+	case "stats":
+		return c.stats(ctx, args[1:])
+	case "dead-letter":
+		if len(args) < 2 || args[1] != "list" {
+			return c.usageError("dead-letter supports list")
+		}
+		return c.taskList(ctx, args[2:], "dead_lettered")
+	case "help", "-h", "--help":
+		fmt.Fprint(c.stdout, usage)
+		return nil
+	default:
+		return c.usageError("unknown command %q", command)
+	}
+}
+
+// flags returns a flag set for a command with the global flags registered
+func (c *cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() { fmt.Fprint(c.stderr, usage) }
+	fs.StringVar(&c.baseURL, "url", c.baseURL, "API base URL")
+	fs.StringVar(&c.apiKey, "api-key", c.apiKey, "API key")
+	fs.StringVar(&c.output, "output", c.output, "output format: table or json")
+	fs.StringVar(&c.output, "o", c.output, "output format: table or json")
+	fs.DurationVar(&c.timeout, "timeout", c.timeout, "per-request timeout")
+	return fs
+}
+
+// parse parses flags interleaved with positional arguments, returning the positionals
+func (c *cli) parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if c.output != "table" && c.output != "json" {
+		return nil, c.usageError("--output must be table or json")
+	}
+	c.client = client.New(c.baseURL, client.WithAPIKey(c.apiKey), client.WithHTTPClient(&http.Client{Timeout: c.timeout}))
+	return positional, nil
+}
+
+func (c *cli) usageError(format string, args ...any) error {
+	fmt.Fprintf(c.stderr, "later-cli: "+format+"\n\n", args...)
+	fmt.Fprint(c.stderr, usage)
+	return errUsage
+}
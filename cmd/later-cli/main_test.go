@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/domain/entity"
+)
+
+const taskID = "00000000-0000-0000-0000-000000000001"
+
+// runCLI runs a command line against the server and returns its exit code and output
+func runCLI(t *testing.T, server *httptest.Server, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	env := map[string]string{"LATER_URL": server.URL, "LATER_API_KEY": "secret"}
+	code := run(context.Background(), args, &stdout, &stderr, func(key string) string { return env[key] })
+	return code, stdout.String(), stderr.String()
+}
+
+func TestTaskCreate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+		assert.Equal(t, "/api/v1/tasks", r.URL.Path)
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]any{"order_id": float64(42)}, body["payload"], "the payload is sent as a document")
+		assert.Equal(t, []any{"billing", "eu"}, body["tags"])
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(dto.TaskResponse{ID: taskID, Name: "charge", Status: entity.TaskStatusPending, EstimatedExecution: "immediate"})
+	}))
+	defer server.Close()
+
+	code, stdout, stderr := runCLI(t, server, "task", "create", "--name", "charge",
+		"--callback-url", "https://example.com/callback", "--payload", `{"order_id":42}`, "--tags", "billing,eu", "-o", "json")
+	require.Equal(t, 0, code, stderr)
+	var task dto.TaskResponse
+	require.NoError(t, json.Unmarshal([]byte(stdout), &task))
+	assert.Equal(t, taskID, task.ID)
+
+	// Requests the server would reject are caught before sending
+	code, _, stderr = runCLI(t, server, "task", "create", "--name", "charge",
+		"--callback-url", "https://example.com/callback", "--payload", `{}`, "--priority", "11")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "priority must be between 0 and 10")
+}
+
+func TestTaskGetWatch(t *testing.T) {
+	statuses := []entity.TaskStatus{entity.TaskStatusPending, entity.TaskStatusProcessing, entity.TaskStatusProcessing, entity.TaskStatusCompleted}
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/tasks/"+taskID, r.URL.Path)
+		status := statuses[min(int(calls.Add(1))-1, len(statuses)-1)]
+		json.NewEncoder(w).Encode(dto.TaskResponse{ID: taskID, Name: "charge", Status: status})
+	}))
+	defer server.Close()
+
+	code, stdout, stderr := runCLI(t, server, "task", "get", taskID, "--watch", "--interval", "1ms")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, int32(4), calls.Load(), "polls until the task is terminal")
+
+	// One line per change, then the final task
+	lines := strings.Split(stdout, "\n")
+	assert.Contains(t, lines[0], "pending")
+	assert.Contains(t, lines[1], "processing")
+	assert.Contains(t, lines[2], "completed")
+	assert.Regexp(t, `Status:\s+completed`, stdout)
+}
+
+func TestDeadLetterList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "dead_lettered", r.URL.Query().Get("status"))
+		assert.Equal(t, "2", r.URL.Query().Get("page"))
+		errMsg := "callback returned 500"
+		json.NewEncoder(w).Encode(dto.TaskListResponse{
+			Tasks:      []*dto.TaskResponse{{ID: taskID, Name: "charge", Status: entity.TaskStatusDeadLettered, CallbackAttempts: 6, ErrorMessage: &errMsg}},
+			Pagination: dto.PaginationInfo{Page: 2, Limit: 20, Total: 21, TotalPages: 2},
+		})
+	}))
+	defer server.Close()
+
+	code, stdout, stderr := runCLI(t, server, "dead-letter", "list", "--page", "2")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "ID")
+	assert.Contains(t, stdout, taskID)
+	assert.Contains(t, stdout, "callback returned 500")
+	assert.Contains(t, stdout, "Page 2 of 2 (21 tasks)")
+}
+
+func TestErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"task_not_found","message":"Task not found"}`))
+	}))
+	defer server.Close()
+
+	code, _, stderr := runCLI(t, server, "task", "retry", taskID)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "task_not_found: Task not found (404)")
+
+	code, _, stderr = runCLI(t, server, "task", "get")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "task get needs a task ID")
+
+	code, _, _ = runCLI(t, server, "stats", "--output", "yaml")
+	assert.Equal(t, 2, code)
+}
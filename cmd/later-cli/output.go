@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/domain/entity"
+)
+
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// table returns a writer aligning tab-separated columns; flush it when done
+func (c *cli) table() *tabwriter.Writer {
+	return tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+}
+
+func (c *cli) printTask(task *dto.TaskResponse) error {
+	if c.output == "json" {
+		return c.printJSON(task)
+	}
+
+	w := c.table()
+	row := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", key, value)
+		}
+	}
+	row("ID", task.ID)
+	row("Name", task.Name)
+	row("Status", string(task.Status))
+	row("Priority", fmt.Sprint(task.Priority))
+	row("Tags", strings.Join(task.Tags, ", "))
+	row("Callback URL", task.CallbackURL)
+	row("Created", formatTime(&task.CreatedAt))
+	row("Scheduled", formatTime(&task.ScheduledFor))
+	row("Started", formatTime(task.StartedAt))
+	row("Completed", formatTime(task.CompletedAt))
+	row("Attempts", fmt.Sprintf("%d (retries %d of %d)", task.CallbackAttempts, task.RetryCount, task.MaxRetries))
+	if task.DependsOn != nil {
+		row("Depends on", fmt.Sprintf("%s (%s)", *task.DependsOn, task.DependencyFailurePolicy))
+	}
+	row("Tenant", task.TenantID)
+	if task.ErrorMessage != nil {
+		row("Error", *task.ErrorMessage)
+	}
+	row("Execution", task.EstimatedExecution)
+	if task.PayloadRedacted {
+		row("Payload", "(redacted)")
+	} else {
+		row("Payload", task.Payload)
+	}
+	for _, child := range task.Children {
+		row("Child", fmt.Sprintf("%s %s (%s)", child.ID, child.Name, child.Status))
+	}
+	return w.Flush()
+}
+
+func (c *cli) printTaskList(list *dto.TaskListResponse) error {
+	if c.output == "json" {
+		return c.printJSON(list)
+	}
+
+	w := c.table()
+	fmt.Fprintln(w, "ID\tNAME\tSTATUS\tPRIORITY\tATTEMPTS\tSCHEDULED\tERROR")
+	for _, task := range list.Tasks {
+		var errMsg string
+		if task.ErrorMessage != nil {
+			errMsg = truncate(*task.ErrorMessage, 60)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n", task.ID, task.Name, task.Status,
+			task.Priority, task.CallbackAttempts, formatTime(&task.ScheduledFor), errMsg)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	p := list.Pagination
+	fmt.Fprintf(c.stdout, "\nPage %d of %d (%d tasks)\n", p.Page, max(p.TotalPages, 1), p.Total)
+	return nil
+}
+
+func (c *cli) printStats(stats *dto.StatsResponse) error {
+	if c.output == "json" {
+		return c.printJSON(stats)
+	}
+
+	w := c.table()
+	fmt.Fprintf(w, "Total tasks:\t%d\n", stats.Total)
+	statuses := make([]entity.TaskStatus, 0, len(stats.ByStatus))
+	for status := range stats.ByStatus {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(w, "  %s:\t%d\n", status, stats.ByStatus[status])
+	}
+
+	recent := stats.Recent
+	fmt.Fprintf(w, "Last %s:\t\n", stats.Window)
+	fmt.Fprintf(w, "  submitted:\t%d\n", recent.Submitted)
+	fmt.Fprintf(w, "  completed:\t%d\n", recent.Completed)
+	fmt.Fprintf(w, "  failed:\t%d\n", recent.Failed)
+	fmt.Fprintf(w, "  dead lettered:\t%d\n", recent.DeadLettered)
+	fmt.Fprintf(w, "  avg callback attempts:\t%.2f\n", recent.AvgCallbackAttempts)
+	fmt.Fprintf(w, "  completion latency p50/p95:\t%.0fms / %.0fms\n", recent.P50CompletionLatencyMs, recent.P95CompletionLatencyMs)
+	fmt.Fprintf(w, "  dispatch latency p50/p95:\t%.0fms / %.0fms\n", recent.P50DispatchLatencyMs, recent.P95DispatchLatencyMs)
+	fmt.Fprintf(w, "  callback duration p50/p95:\t%.0fms / %.0fms\n", recent.P50CallbackDurationMs, recent.P95CallbackDurationMs)
+	fmt.Fprintf(w, "Callback success rate:\t%.1f%%\n", stats.CallbackSuccessRate*100)
+	return w.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
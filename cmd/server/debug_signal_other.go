@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "go.uber.org/zap"
+
+// toggleDebugOnSignal does nothing where SIGUSR1 doesn't exist; use PUT /api/v1/admin/log-level
+func toggleDebugOnSignal(log *zap.Logger) {}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"go.uber.org/zap"
+)
+
+// toggleDebugOnSignal switches the log level to debug on SIGUSR1, and back on the next one,
+// so a misbehaving server can be inspected without restarting it
+func toggleDebugOnSignal(log *zap.Logger) {
+	restore := logger.Level()
+	if restore == "debug" {
+		restore = "info"
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			next := "debug"
+			if logger.Level() == "debug" {
+				next = restore
+			} else {
+				// Return to the level in force now, which the admin API may have changed
+				restore = logger.Level()
+			}
+			if err := logger.SetLevel(next); err != nil {
+				log.Error("Failed to toggle log level", zap.Error(err))
+				continue
+			}
+			log.Warn("Log level toggled by SIGUSR1", zap.String("level", next))
+		}
+	}()
+}
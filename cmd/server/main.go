@@ -11,7 +11,12 @@ import (
 	"github.com/usual2970/later/callback"
 	"github.com/usual2970/later/configs"
 	"github.com/usual2970/later/delivery/rest"
+	"github.com/usual2970/later/delivery/websocket"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/alert"
+	"github.com/usual2970/later/infrastructure/archive"
 	"github.com/usual2970/later/infrastructure/circuitbreaker"
+	"github.com/usual2970/later/infrastructure/eventbus"
 	"github.com/usual2970/later/infrastructure/logger"
 	"github.com/usual2970/later/infrastructure/worker"
 	"github.com/usual2970/later/repository/mysql"
@@ -29,6 +34,7 @@ func main() {
 	defer logger.Sync()
 
 	log := logger.Named("main")
+	toggleDebugOnSignal(log)
 
 	// Load configuration
 	cfg, err := configs.LoadConfig("")
@@ -43,13 +49,20 @@ func main() {
 	}
 	defer mysql.Close(db)
 
+	dbOpts := []mysql.Option{
+		mysql.WithLogger(logger.Named("db")),
+		mysql.WithSlowQueryLog(cfg.Database.SlowQueryThreshold),
+	}
+
 	// Run migrations
-	if err := mysql.RunMigrations(db, "migrations"); err != nil {
+	if err := mysql.RunMigrations(db, dbOpts...); err != nil {
 		log.Fatal("Failed to run migrations", zap.Error(err))
 	}
 
 	// Initialize repositories
-	taskRepo := mysql.NewTaskRepository(db)
+	taskRepo := mysql.NewTaskRepository(db, dbOpts...)
+	taskEventRepo := mysql.NewTaskEventRepository(db, "", dbOpts...)
+	hostPauseRepo := mysql.NewHostPauseRepository(db, "", dbOpts...)
 
 	// Initialize circuit breaker
 	cb := circuitbreaker.NewCircuitBreaker(
@@ -58,38 +71,176 @@ func main() {
 	)
 
 	// Initialize callback service
+	var callbackOpts []callback.ServiceOption
+	var taskOpts []task.ServiceOption
+	var urlPolicy *callback.URLPolicy
+	if cfg.Callback.URLPolicy.Enabled {
+		urlPolicy, err = callback.NewURLPolicy(callback.URLPolicyConfig{
+			HTTPSOnly: cfg.Callback.URLPolicy.HTTPSOnly,
+			Denylist:  append([]string{}, cfg.Callback.URLPolicy.Denylist...), // An empty list denies nothing
+			Allowlist: cfg.Callback.URLPolicy.Allowlist,
+		})
+		if err != nil {
+			log.Fatal("Invalid callback URL policy", zap.Error(err))
+		}
+		callbackOpts = append(callbackOpts, callback.WithURLPolicy(urlPolicy))
+		taskOpts = append(taskOpts, task.WithCallbackURLPolicy(urlPolicy))
+	}
+	callbackClient, err := callback.NewHTTPClient(cfg.Callback.DefaultTimeout, callback.TransportOptions{
+		MaxIdleConnsPerHost: cfg.Callback.MaxIdleConnsPerHost,
+		ProxyURL:            cfg.Callback.ProxyURL,
+		DisableKeepAlives:   cfg.Callback.DisableKeepAlives,
+		URLPolicy:           urlPolicy,
+	})
+	if err != nil {
+		log.Fatal("Invalid callback transport configuration", zap.Error(err))
+	}
+	if cfg.Callback.MaxRedirects > 0 {
+		callbackOpts = append(callbackOpts, callback.WithMaxRedirects(cfg.Callback.MaxRedirects))
+	}
+	if len(cfg.Callback.RetryableStatusCodes) > 0 {
+		callbackOpts = append(callbackOpts, callback.WithRetryableStatusCodes(cfg.Callback.RetryableStatusCodes))
+	}
+	if cfg.Callback.OAuth2.TokenURL != "" {
+		oauth2 := entity.OAuth2Config{
+			TokenURL:     cfg.Callback.OAuth2.TokenURL,
+			ClientID:     cfg.Callback.OAuth2.ClientID,
+			ClientSecret: cfg.Callback.OAuth2.ClientSecret,
+			Scopes:       cfg.Callback.OAuth2.Scopes,
+		}
+		if err := oauth2.Validate(); err != nil {
+			log.Fatal("Invalid callback OAuth2 configuration", zap.Error(err))
+		}
+		callbackOpts = append(callbackOpts, callback.WithOAuth2(oauth2))
+	}
+	if cfg.Callback.PreviousSecret != "" {
+		callbackOpts = append(callbackOpts, callback.WithPreviousSigningSecret(cfg.Callback.PreviousSecret))
+	}
 	callbackService := callback.NewService(
-		cfg.Callback.DefaultTimeout,
+		callbackClient,
 		cb,
 		cfg.Callback.Secret,
+		cfg.Callback.ResponseBodyLimit,
 		logger.Named("callback"),
+		callbackOpts...,
 	)
 
 	// Initialize task service
-	taskService := task.NewService(taskRepo)
+	taskOpts = append(taskOpts,
+		task.WithMaxPayloadSize(cfg.Task.MaxPayloadSize),
+		task.WithPayloadCompression(cfg.Task.PayloadCompressionMinSize),
+		task.WithDefaultTaskTTL(cfg.Task.DefaultTTL),
+		task.WithBacklogLimit(cfg.Task.MaxPendingTasks, cfg.Task.BacklogBypassPriority),
+		task.WithTaskIDFormat(cfg.Task.IDFormat),
+		task.WithEventHistory(taskEventRepo),
+		task.WithRetryPolicy(cfg.Callback.RetryPolicy()),
+		task.WithHostPauses(hostPauseRepo),
+	)
+	var payloadCipher *task.PayloadCipher
+	if cfg.Task.PayloadEncryption.Key != "" {
+		key, decryptionKeys, err := cfg.Task.PayloadEncryption.DecodeKeys()
+		if err != nil {
+			log.Fatal("Invalid payload encryption configuration", zap.Error(err))
+		}
+		payloadCipher, err = task.NewPayloadCipher(key, decryptionKeys...)
+		if err != nil {
+			log.Fatal("Invalid payload encryption configuration", zap.Error(err))
+		}
+		taskOpts = append(taskOpts, task.WithPayloadCipher(payloadCipher))
+	}
+	if cfg.Task.PayloadSchemaDir != "" {
+		schemas, err := task.LoadPayloadSchemas(cfg.Task.PayloadSchemaDir)
+		if err != nil {
+			log.Fatal("Invalid payload schemas", zap.Error(err))
+		}
+		log.Info("Loaded payload schemas", zap.Int("count", len(schemas)))
+		taskOpts = append(taskOpts, task.WithPayloadSchemas(schemas))
+	}
+	taskService := task.NewService(taskRepo, taskOpts...)
 
-	// Initialize worker pool
+	// Initialize WebSocket hub for real-time task events, recording them for replay and timelines
+	// and fanning them out to the other instances over the event bus
+	bus, err := eventbus.New(cfg.Server.WebSocket.EventBus.BusConfig(), taskEventRepo, logger.Named("eventbus"))
+	if err != nil {
+		log.Fatal("Invalid event bus configuration", zap.Error(err))
+	}
+	hub := websocket.NewHub(logger.Named("websocket"), cfg.Server.WebSocket.HubConfig(),
+		websocket.WithEventStore(taskEventRepo), websocket.WithEventBus(bus),
+		websocket.WithPayloadRedaction(cfg.Server.WebSocket.Redaction()))
+	go hub.Run()
+
+	// Initialize worker pool, capping tasks in flight per concurrency key
+	// Callbacks in flight are tracked so operators can abort them
+	limiter, err := worker.NewConcurrencyLimiter(cfg.Worker.ConcurrencyLimits, 0)
+	if err != nil {
+		log.Fatal("Invalid concurrency limits", zap.Error(err))
+	}
+	inFlight := worker.NewInFlight()
 	workerPool := worker.NewWorkerPool(
 		cfg.Worker.PoolSize,
 		taskService,
 		callbackService,
+		hub,
 		logger.Named("worker"),
+		worker.WithConcurrencyLimiter(limiter),
+		worker.WithQueueBuffer(cfg.Worker.QueueBuffer),
+		worker.WithInFlight(inFlight),
 	)
 	workerPool.Start(cfg.Worker.PoolSize)
 
+	// Operators can run single tasks inline, bypassing the worker queue
+	executor := worker.NewExecutor(taskService, callbackService, hub, inFlight, logger.Named("worker"))
+
 	// Convert configs.Scheduler to task.SchedulerConfig
-	schedulerCfg := task.SchedulerConfig{
-		HighPriorityInterval:   cfg.Scheduler.HighPriorityInterval,
-		NormalPriorityInterval: cfg.Scheduler.NormalPriorityInterval,
-		CleanupInterval:        cfg.Scheduler.CleanupInterval,
+	schedulerCfg := cfg.Scheduler.TaskConfig().WithBatchDefaults(cfg.Worker.QueueCapacity())
+	schedulerCfg.PayloadCipher = payloadCipher
+	schedulerCfg.TaskEvents = taskEventRepo
+	schedulerCfg.Logger = logger.Named("scheduler")
+
+	// Expired tasks are written to object storage before the cleanup deletes them
+	if cfg.Scheduler.ArchiveStorage.Enabled() {
+		sink, err := archive.NewS3(cfg.Scheduler.ArchiveStorage.S3Config(), nil)
+		if err != nil {
+			log.Fatal("Invalid archive storage configuration", zap.Error(err))
+		}
+		schedulerCfg.ArchiveSink = sink
+		log.Info("Archiving expired tasks",
+			zap.String("bucket", cfg.Scheduler.ArchiveStorage.Bucket),
+			zap.String("prefix", cfg.Scheduler.ArchiveStorage.Prefix))
+	}
+
+	// Dead letter alerts are posted to a webhook, or only logged without one
+	if cfg.Scheduler.DeadLetterAlert.WebhookURL != "" {
+		webhook, err := alert.NewWebhook(cfg.Scheduler.DeadLetterAlert.WebhookURL, nil)
+		if err != nil {
+			log.Fatal("Invalid dead letter alert configuration", zap.Error(err))
+		}
+		schedulerCfg.DeadLetterAlert.Alerter = webhook
+	}
+
+	// Only the replica holding the scheduler lease polls and cleans up
+	var elector *task.LeaderElector
+	if cfg.Scheduler.LeaderElection.Enabled {
+		elector = task.NewLeaderElector(mysql.NewLeaseRepository(db, "", dbOpts...), cfg.Scheduler.LeaderElection.TaskConfig())
+		schedulerCfg.Leader = elector
+		go elector.Run()
+		log.Info("Leader election enabled", zap.String("holder", elector.Holder()))
+	}
+	if cfg.Scheduler.Notify.Enabled {
+		schedulerCfg.Notifier = task.NewInsertWatcher(taskRepo, cfg.Scheduler.Notify.MinInterval, cfg.Scheduler.Notify.MaxInterval)
 	}
 	scheduler := task.NewScheduler(taskRepo, workerPool, schedulerCfg)
 
 	// Initialize HTTP handler
-	h := rest.NewHandler(taskService, scheduler)
+	h := rest.NewHandler(taskService, scheduler, executor, hub)
+	admin := rest.NewAdminHandler(limiter, scheduler)
 
-	// Start HTTP server
-	srv := server.NewServer(cfg.Server, h)
+	// Start HTTP server; readiness also requires a reachable database
+	srv := server.NewServer(cfg.Server, cfg.Auth, h, admin, hub)
+	srv.SetReadinessCheck(db.PingContext)
+	if cfg.Server.TLS.Enabled() {
+		reloadTLSOnSignal(srv, log)
+	}
 
 	// Start scheduler in background
 	go scheduler.Start()
@@ -105,27 +256,61 @@ func main() {
 		}
 	}()
 
+	srv.SetReady(true)
 	log.Info("Server started",
 		zap.String("address", cfg.Server.Address()),
+		zap.Bool("tls", cfg.Server.TLS.Enabled()),
 		zap.Int("workers", cfg.Worker.PoolSize),
 	)
 
-	// Graceful shutdown
+	// Graceful shutdown: stop taking on work, drain the workers, then close the HTTP server,
+	// which keeps serving reads and task creation until then
 	<-ctx.Done()
-	log.Info("Shutting down server...")
+	log.Info("Shutting down server...",
+		zap.Duration("shutdown_timeout", cfg.Server.ShutdownTimeout),
+		zap.Duration("drain_timeout", cfg.Worker.DrainTimeout),
+	)
+	srv.SetReady(false)
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Fatal("Server shutdown failed", zap.Error(err))
+	// Stop scheduler; due tasks it hasn't dispatched stay pending in the database
+	scheduler.Stop()
+
+	// Hand the scheduler lease to another replica
+	if elector != nil {
+		elector.Stop()
 	}
+	log.Info("Shutdown: scheduler stopped")
 
-	// Stop scheduler
-	scheduler.Stop()
+	// Stop worker pool, waiting for in-flight tasks within the drain deadline
+	drainCtx := shutdownCtx
+	if cfg.Worker.DrainTimeout > 0 {
+		var cancelDrain context.CancelFunc
+		drainCtx, cancelDrain = context.WithTimeout(shutdownCtx, cfg.Worker.DrainTimeout)
+		defer cancelDrain()
+	}
+	drainStart := time.Now()
+	abandoned := workerPool.Stop(drainCtx)
+	if abandoned > 0 {
+		log.Warn("Shutdown: tasks abandoned while draining workers; they will be retried on next start",
+			zap.Int("abandoned", abandoned),
+			zap.Duration("elapsed", time.Since(drainStart)),
+		)
+	} else {
+		log.Info("Shutdown: workers drained", zap.Duration("elapsed", time.Since(drainStart)))
+	}
+
+	// Close the HTTP server with the time left
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Warn("Shutdown: HTTP server closed before requests finished", zap.Error(err))
+	} else {
+		log.Info("Shutdown: HTTP server closed")
+	}
 
-	// Stop worker pool
-	workerPool.Stop()
+	// Stop WebSocket hub
+	hub.Stop()
 
-	log.Info("Server stopped")
+	log.Info("Server stopped", zap.Int("abandoned", abandoned))
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/usual2970/later/server"
+
+	"go.uber.org/zap"
+)
+
+// reloadTLSOnSignal reloads the server's TLS certificate on SIGHUP, for rotations that
+// shouldn't wait for the file watcher; where SIGHUP doesn't exist the watcher alone applies
+func reloadTLSOnSignal(srv *server.Server, log *zap.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			if err := srv.ReloadTLS(); err != nil {
+				log.Error("Failed to reload TLS certificate", zap.Error(err))
+				continue
+			}
+			log.Info("TLS certificate reloaded on SIGHUP")
+		}
+	}()
+}
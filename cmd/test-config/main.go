@@ -19,6 +19,7 @@ func main() {
 	fmt.Printf("  Host: %s\n", cfg.Server.Host)
 	fmt.Printf("  Port: %d\n", cfg.Server.Port)
 	fmt.Printf("  Address: %s\n", cfg.Server.Address())
+	fmt.Printf("  Swagger UI: %v\n", cfg.Server.SwaggerUI)
 
 	fmt.Printf("\nDatabase:\n")
 	fmt.Printf("  URL: %s\n", cfg.Database.URL)
@@ -27,10 +28,19 @@ func main() {
 	fmt.Printf("\nScheduler:\n")
 	fmt.Printf("  High Priority Interval: %v\n", cfg.Scheduler.HighPriorityInterval)
 	fmt.Printf("  Normal Priority Interval: %v\n", cfg.Scheduler.NormalPriorityInterval)
+	fmt.Printf("  Retry Interval: %v\n", cfg.Scheduler.RetryInterval)
 	fmt.Printf("  Cleanup Interval: %v\n", cfg.Scheduler.CleanupInterval)
+	fmt.Printf("  Leader Election: %v (lease %v, renew every %v)\n", cfg.Scheduler.LeaderElection.Enabled,
+		cfg.Scheduler.LeaderElection.LeaseTimeout, cfg.Scheduler.LeaderElection.RenewInterval)
+	fmt.Printf("  Insert Notifications: %v (poll every %v to %v)\n", cfg.Scheduler.Notify.Enabled,
+		cfg.Scheduler.Notify.MinInterval, cfg.Scheduler.Notify.MaxInterval)
+	fmt.Printf("  Delay Queue: %v (size %d)\n", cfg.Scheduler.DelayQueue, cfg.Scheduler.DelayQueueSize)
+	fmt.Printf("  Immediate Submit Wait: %v\n", cfg.Scheduler.ImmediateSubmitWait)
 
 	fmt.Printf("\nWorker:\n")
 	fmt.Printf("  Pool Size: %d\n", cfg.Worker.PoolSize)
+	fmt.Printf("  Queue Buffer: %d\n", cfg.Worker.QueueCapacity())
+	fmt.Printf("  Concurrency Limits: %v\n", cfg.Worker.ConcurrencyLimits)
 
 	fmt.Printf("\nCallback:\n")
 	fmt.Printf("  Secret: %s\n", maskSecret(cfg.Callback.Secret))
@@ -0,0 +1,351 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// envOnly runs the test from an empty directory outside the module, so no config file is found
+func envOnly(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("LATER_CONFIG_FILE", "")
+}
+
+func TestLoadConfigWithoutFile(t *testing.T) {
+	envOnly(t)
+
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Server.Port)
+	assert.Equal(t, 2*time.Second, cfg.Scheduler.HighPriorityInterval)
+	assert.Equal(t, 30*time.Second, cfg.Callback.DefaultTimeout)
+	assert.Equal(t, 20, cfg.Worker.PoolSize)
+	assert.Equal(t, "info", cfg.Log.Level)
+	assert.Equal(t, 30*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Zero(t, cfg.Worker.DrainTimeout)
+	assert.Zero(t, cfg.Scheduler.InitialPollDelay)
+	assert.Equal(t, time.Second, cfg.Scheduler.PollJitter)
+}
+
+func TestLoadConfigFromEnvOnly(t *testing.T) {
+	envOnly(t)
+
+	env := map[string]string{
+		"LATER_SERVER_HOST":                              "127.0.0.1",
+		"LATER_SERVER_PORT":                              "9090",
+		"LATER_SERVER_SWAGGER_UI":                        "true",
+		"LATER_SERVER_CREATE_RATE_LIMIT_RPS":             "2.5",
+		"LATER_SERVER_SHUTDOWN_TIMEOUT":                  "2m",
+		"LATER_SERVER_WEBSOCKET_MAX_CLIENTS":             "500",
+		"LATER_SERVER_WEBSOCKET_PING_INTERVAL":           "25s",
+		"LATER_SERVER_WEBSOCKET_BACKFILL_LIMIT":          "50",
+		"LATER_SERVER_WEBSOCKET_ALLOWED_ORIGINS":         "https://dash.example.com,https://*.example.com",
+		"LATER_DATABASE_URL":                             "mysql://app:secret@db:3306/later?parseTime=true",
+		"LATER_DATABASE_MAX_CONNECTIONS":                 "40",
+		"LATER_DATABASE_CONN_MAX_LIFETIME":               "30m",
+		"LATER_DATABASE_CONN_MAX_IDLE_TIME":              "90s",
+		"LATER_SCHEDULER_HIGH_PRIORITY_INTERVAL":         "500ms",
+		"LATER_SCHEDULER_NORMAL_PRIORITY_INTERVAL":       "1s",
+		"LATER_SCHEDULER_RETRY_INTERVAL":                 "10s",
+		"LATER_SCHEDULER_CLEANUP_INTERVAL":               "1m",
+		"LATER_SCHEDULER_COMPLETED_RETENTION":            "48h",
+		"LATER_SCHEDULER_DEAD_LETTERED_RETENTION":        "0s",
+		"LATER_SCHEDULER_EVENT_RETENTION":                "72h",
+		"LATER_SCHEDULER_LEADER_ELECTION_ENABLED":        "true",
+		"LATER_SCHEDULER_LEADER_ELECTION_LEASE_TIMEOUT":  "20s",
+		"LATER_SCHEDULER_LEADER_ELECTION_RENEW_INTERVAL": "4s",
+		"LATER_SCHEDULER_NOTIFY_ENABLED":                 "true",
+		"LATER_SCHEDULER_NOTIFY_MIN_INTERVAL":            "50ms",
+		"LATER_SCHEDULER_NOTIFY_MAX_INTERVAL":            "2s",
+		"LATER_SCHEDULER_IMMEDIATE_SUBMIT_WAIT":          "25ms",
+		"LATER_SCHEDULER_INITIAL_POLL_DELAY":             "3s",
+		"LATER_SCHEDULER_POLL_JITTER":                    "750ms",
+		"LATER_WORKER_POOL_SIZE":                         "8",
+		"LATER_WORKER_QUEUE_BUFFER":                      "64",
+		"LATER_WORKER_DRAIN_TIMEOUT":                     "90s",
+		"LATER_TASK_MAX_PAYLOAD_SIZE":                    "2048",
+		"LATER_TASK_DEFAULT_TTL":                         "6h",
+		"LATER_TASK_MAX_PENDING_TASKS":                   "1000",
+		"LATER_TASK_ID_FORMAT":                           "opaque",
+		"LATER_CALLBACK_SECRET":                          "env-secret",
+		"LATER_CALLBACK_DEFAULT_TIMEOUT":                 "10s",
+		"LATER_CALLBACK_DEFAULT_MAX_RETRIES":             "3",
+		"LATER_CALLBACK_RETRYABLE_STATUS_CODES":          "409,503",
+		"LATER_CALLBACK_URL_POLICY_HTTPS_ONLY":           "true",
+		"LATER_CALLBACK_URL_POLICY_DENYLIST":             "10.0.0.0/8,*.internal",
+		"LATER_CALLBACK_OAUTH2_SCOPES":                   "webhooks.write",
+		"LATER_AUTH_API_KEYS":                            "key1,key2",
+		"LATER_AUTH_ADMIN_KEYS":                          "admin1",
+		"LATER_AUTH_TENANT_HEADER":                       "X-Tenant-ID",
+		"LATER_LOG_LEVEL":                                "debug",
+		"LATER_LOG_FORMAT":                               "text",
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+
+	assert.Equal(t, "127.0.0.1:9090", cfg.Server.Address())
+	assert.True(t, cfg.Server.SwaggerUI)
+	assert.Equal(t, 2.5, cfg.Server.CreateRateLimit.RPS)
+	assert.Equal(t, 2*time.Minute, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, 500, cfg.Server.WebSocket.MaxClients)
+	assert.Equal(t, 25*time.Second, cfg.Server.WebSocket.PingInterval)
+	assert.Equal(t, 10*time.Second, cfg.Server.WebSocket.WriteTimeout)
+	assert.Equal(t, 50, cfg.Server.WebSocket.BackfillLimit)
+	assert.Equal(t, []string{"https://dash.example.com", "https://*.example.com"}, cfg.Server.WebSocket.AllowedOrigins)
+
+	assert.Equal(t, "mysql://app:secret@db:3306/later?parseTime=true", cfg.Database.URL)
+	assert.Equal(t, 40, cfg.Database.MaxConnections)
+	assert.Equal(t, 30*time.Minute, cfg.Database.ConnMaxLifetime)
+	assert.Equal(t, 90*time.Second, cfg.Database.ConnMaxIdleTime)
+
+	assert.Equal(t, 500*time.Millisecond, cfg.Scheduler.HighPriorityInterval)
+	assert.Equal(t, time.Second, cfg.Scheduler.NormalPriorityInterval)
+	assert.Equal(t, 10*time.Second, cfg.Scheduler.RetryInterval)
+	assert.Equal(t, time.Minute, cfg.Scheduler.CleanupInterval)
+	assert.Equal(t, 48*time.Hour, cfg.Scheduler.CompletedRetention)
+	assert.Zero(t, cfg.Scheduler.DeadLetteredRetention)
+	assert.Equal(t, 72*time.Hour, cfg.Scheduler.EventRetention)
+	assert.True(t, cfg.Scheduler.LeaderElection.Enabled)
+	assert.Equal(t, 20*time.Second, cfg.Scheduler.LeaderElection.LeaseTimeout)
+	assert.Equal(t, 4*time.Second, cfg.Scheduler.LeaderElection.RenewInterval)
+	assert.True(t, cfg.Scheduler.Notify.Enabled)
+	assert.Equal(t, 50*time.Millisecond, cfg.Scheduler.Notify.MinInterval)
+	assert.Equal(t, 2*time.Second, cfg.Scheduler.Notify.MaxInterval)
+	assert.Equal(t, 25*time.Millisecond, cfg.Scheduler.ImmediateSubmitWait)
+	assert.Equal(t, 3*time.Second, cfg.Scheduler.InitialPollDelay)
+	assert.Equal(t, 750*time.Millisecond, cfg.Scheduler.PollJitter)
+
+	assert.Equal(t, 8, cfg.Worker.PoolSize)
+	assert.Equal(t, 64, cfg.Worker.QueueCapacity())
+	assert.Equal(t, 90*time.Second, cfg.Worker.DrainTimeout)
+
+	assert.Equal(t, 2048, cfg.Task.MaxPayloadSize)
+	assert.Equal(t, 6*time.Hour, cfg.Task.DefaultTTL)
+	assert.Equal(t, int64(1000), cfg.Task.MaxPendingTasks)
+	assert.Equal(t, "opaque", cfg.Task.IDFormat)
+
+	assert.Equal(t, "env-secret", cfg.Callback.Secret)
+	assert.Equal(t, 10*time.Second, cfg.Callback.DefaultTimeout)
+	assert.Equal(t, 3, cfg.Callback.DefaultMaxRetries)
+	assert.Equal(t, []int{409, 503}, cfg.Callback.RetryableStatusCodes)
+	assert.True(t, cfg.Callback.URLPolicy.HTTPSOnly)
+	assert.Equal(t, []string{"10.0.0.0/8", "*.internal"}, cfg.Callback.URLPolicy.Denylist)
+	assert.Equal(t, []string{"webhooks.write"}, cfg.Callback.OAuth2.Scopes)
+
+	assert.Equal(t, []string{"key1", "key2"}, cfg.Auth.APIKeys)
+	assert.Equal(t, []string{"admin1"}, cfg.Auth.AdminKeys)
+	assert.Equal(t, "X-Tenant-ID", cfg.Auth.TenantHeader)
+
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, "text", cfg.Log.Format)
+}
+
+func TestLoadConfigInvalidEnvDuration(t *testing.T) {
+	envOnly(t)
+
+	for _, key := range []string{
+		"LATER_SCHEDULER_RETRY_INTERVAL",
+		"LATER_CALLBACK_DEFAULT_TIMEOUT",
+		"LATER_DATABASE_CONN_MAX_LIFETIME",
+		"LATER_SERVER_SHUTDOWN_TIMEOUT",
+		"LATER_WORKER_DRAIN_TIMEOUT",
+		"LATER_TASK_DEFAULT_TTL",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, "soon")
+			_, err := LoadConfig("")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	envOnly(t)
+
+	path := filepath.Join(t.TempDir(), "later.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  port: 7070\nworker:\n  pool_size: 4\n"), 0o600))
+
+	t.Run("LATER_CONFIG_FILE is read, and env overrides it", func(t *testing.T) {
+		t.Setenv("LATER_CONFIG_FILE", path)
+		t.Setenv("LATER_WORKER_POOL_SIZE", "6")
+
+		cfg, err := LoadConfig("")
+		require.NoError(t, err)
+		assert.Equal(t, 7070, cfg.Server.Port)
+		assert.Equal(t, 6, cfg.Worker.PoolSize)
+	})
+
+	t.Run("Missing LATER_CONFIG_FILE is an error", func(t *testing.T) {
+		t.Setenv("LATER_CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+
+		_, err := LoadConfig("")
+		assert.ErrorContains(t, err, "LATER_CONFIG_FILE")
+	})
+
+	t.Run("Missing explicit path is an error", func(t *testing.T) {
+		_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadConfigDatabaseURL(t *testing.T) {
+	envOnly(t)
+
+	for _, url := range []string{
+		"mysql://later:later@db:3306/later?parseTime=true",
+		"MYSQL://later@db/later",
+		"later:later@tcp(db:3306)/later?parseTime=true",
+	} {
+		t.Run(url, func(t *testing.T) {
+			t.Setenv("LATER_DATABASE_URL", url)
+			_, err := LoadConfig("")
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("Postgres URL fails fast", func(t *testing.T) {
+		t.Setenv("LATER_DATABASE_URL", "postgres://later:later@db:5432/later?sslmode=disable")
+		_, err := LoadConfig("")
+		assert.ErrorContains(t, err, `database.url scheme "postgres" is not supported`)
+	})
+
+	t.Run("Empty URL", func(t *testing.T) {
+		t.Setenv("LATER_DATABASE_URL", " ")
+		_, err := LoadConfig("")
+		assert.ErrorContains(t, err, "database.url is required")
+	})
+}
+
+func TestLoadConfigTLS(t *testing.T) {
+	envOnly(t)
+
+	t.Run("Certificate and key", func(t *testing.T) {
+		t.Setenv("LATER_SERVER_TLS_CERT_FILE", "/etc/later/tls.crt")
+		t.Setenv("LATER_SERVER_TLS_KEY_FILE", "/etc/later/tls.key")
+		t.Setenv("LATER_SERVER_TLS_CLIENT_CA_FILE", "/etc/later/ca.crt")
+
+		cfg, err := LoadConfig("")
+		require.NoError(t, err)
+		assert.True(t, cfg.Server.TLS.Enabled())
+		assert.Equal(t, "/etc/later/ca.crt", cfg.Server.TLS.ClientCAFile)
+	})
+
+	t.Run("Certificate without key", func(t *testing.T) {
+		t.Setenv("LATER_SERVER_TLS_CERT_FILE", "/etc/later/tls.crt")
+		_, err := LoadConfig("")
+		assert.ErrorContains(t, err, "must be set together")
+	})
+
+	t.Run("Client CA without certificate", func(t *testing.T) {
+		t.Setenv("LATER_SERVER_TLS_CLIENT_CA_FILE", "/etc/later/ca.crt")
+		_, err := LoadConfig("")
+		assert.ErrorContains(t, err, "client_ca_file requires")
+	})
+}
+
+func TestLoadConfigDrainTimeout(t *testing.T) {
+	envOnly(t)
+	t.Setenv("LATER_SERVER_SHUTDOWN_TIMEOUT", "10s")
+	t.Setenv("LATER_WORKER_DRAIN_TIMEOUT", "20s")
+
+	_, err := LoadConfig("")
+	assert.ErrorContains(t, err, "worker.drain_timeout must be between 0 and server.shutdown_timeout")
+}
+
+func TestLoadConfigWebSocketAllowedOrigins(t *testing.T) {
+	envOnly(t)
+	t.Setenv("LATER_SERVER_WEBSOCKET_ALLOWED_ORIGINS", "dash.example.com")
+
+	_, err := LoadConfig("")
+	assert.ErrorContains(t, err, "server.websocket")
+}
+
+func TestLoadConfigWebSocketEventBus(t *testing.T) {
+	envOnly(t)
+	t.Setenv("LATER_SERVER_WEBSOCKET_EVENT_BUS_DRIVER", "redis")
+	t.Setenv("LATER_SERVER_WEBSOCKET_EVENT_BUS_REDIS_URL", "redis://:secret@cache:6379")
+
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+	bus := cfg.Server.WebSocket.EventBus.BusConfig()
+	assert.Equal(t, "redis", bus.Driver)
+	assert.Equal(t, "redis://:secret@cache:6379", bus.RedisURL)
+	assert.Equal(t, "later:task_events", bus.RedisChannel)
+	assert.Equal(t, time.Second, bus.PollInterval)
+
+	t.Setenv("LATER_SERVER_WEBSOCKET_EVENT_BUS_DRIVER", "kafka")
+	_, err = LoadConfig("")
+	assert.ErrorContains(t, err, "server.websocket.event_bus")
+}
+
+func TestLoadConfigWebSocketPayloadRedaction(t *testing.T) {
+	envOnly(t)
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "omit", cfg.Server.WebSocket.Redaction().Mode)
+
+	t.Setenv("LATER_SERVER_WEBSOCKET_PAYLOAD_REDACTION", "fields")
+	t.Setenv("LATER_SERVER_WEBSOCKET_REDACTED_FIELDS", "email,ssn")
+	cfg, err = LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"email", "ssn"}, cfg.Server.WebSocket.Redaction().Fields)
+
+	t.Setenv("LATER_SERVER_WEBSOCKET_REDACTED_FIELDS", "")
+	_, err = LoadConfig("")
+	assert.ErrorContains(t, err, "server.websocket.payload_redaction")
+}
+
+func TestLoadConfigArchiveStorage(t *testing.T) {
+	envOnly(t)
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+	assert.False(t, cfg.Scheduler.ArchiveStorage.Enabled(), "archiving is off without a bucket")
+
+	t.Setenv("LATER_SCHEDULER_ARCHIVE_STORAGE_ENDPOINT", "http://minio:9000")
+	t.Setenv("LATER_SCHEDULER_ARCHIVE_STORAGE_BUCKET", "compliance")
+	t.Setenv("LATER_SCHEDULER_ARCHIVE_STORAGE_PREFIX", "later/tasks")
+	t.Setenv("LATER_SCHEDULER_ARCHIVE_STORAGE_ACCESS_KEY_ID", "AKID")
+	t.Setenv("LATER_SCHEDULER_ARCHIVE_STORAGE_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("LATER_SCHEDULER_ARCHIVE_STORAGE_PATH_STYLE", "true")
+
+	cfg, err = LoadConfig("")
+	require.NoError(t, err)
+	s3 := cfg.Scheduler.ArchiveStorage.S3Config()
+	assert.Equal(t, "http://minio:9000", s3.Endpoint)
+	assert.Equal(t, "compliance", s3.Bucket)
+	assert.Equal(t, "later/tasks", s3.Prefix)
+	assert.Equal(t, "AKID", s3.AccessKeyID)
+	assert.True(t, s3.PathStyle)
+
+	t.Setenv("LATER_SCHEDULER_ARCHIVE_STORAGE_SECRET_ACCESS_KEY", "")
+	_, err = LoadConfig("")
+	assert.ErrorContains(t, err, "scheduler.archive_storage")
+}
+
+func TestLoadConfigRetryPolicy(t *testing.T) {
+	envOnly(t)
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, entity.DefaultRetryPolicy, cfg.Callback.RetryPolicy())
+
+	t.Setenv("LATER_CALLBACK_RETRY_JITTER_PERCENT", "10")
+	t.Setenv("LATER_CALLBACK_MAX_RETRY_BACKOFF", "2h")
+	cfg, err = LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, entity.RetryPolicy{JitterPercent: 10, MaxBackoff: 2 * time.Hour}, cfg.Callback.RetryPolicy())
+
+	t.Setenv("LATER_CALLBACK_RETRY_JITTER_PERCENT", "100")
+	_, err = LoadConfig("")
+	assert.ErrorContains(t, err, "retry jitter")
+}
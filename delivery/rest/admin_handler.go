@@ -0,0 +1,158 @@
+package rest
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/logger"
+	"github.com/usual2970/later/infrastructure/worker"
+	tasksvc "github.com/usual2970/later/task"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler handles HTTP requests that change server-wide settings at runtime
+type AdminHandler struct {
+	limiter   *worker.ConcurrencyLimiter
+	scheduler *tasksvc.Scheduler
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(limiter *worker.ConcurrencyLimiter, scheduler *tasksvc.Scheduler) *AdminHandler {
+	return &AdminHandler{limiter: limiter, scheduler: scheduler}
+}
+
+// ListConcurrencyLimits handles GET /api/v1/admin/concurrency-limits
+func (h *AdminHandler) ListConcurrencyLimits(c *gin.Context) {
+	limits := h.limiter.Limits()
+
+	keys := make([]string, 0, len(limits))
+	for key := range limits {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	resp := make([]dto.ConcurrencyLimitResponse, 0, len(keys))
+	for _, key := range keys {
+		resp = append(resp, h.limitResponse(key, limits[key]))
+	}
+	response.Success(c, gin.H{"limits": resp})
+}
+
+// SetConcurrencyLimit handles PUT /api/v1/admin/concurrency-limits/:key
+// Raising a limit starts waiting tasks at once; lowering it lets running tasks finish
+func (h *AdminHandler) SetConcurrencyLimit(c *gin.Context) {
+	key := c.Param("key")
+
+	var req dto.SetConcurrencyLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if err := h.limiter.SetLimit(key, req.MaxInFlight); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	logger.Info("Concurrency limit updated",
+		logger.String("key", key),
+		logger.Int("max_in_flight", req.MaxInFlight),
+	)
+
+	response.Success(c, h.limitResponse(key, req.MaxInFlight))
+}
+
+// DeleteConcurrencyLimit handles DELETE /api/v1/admin/concurrency-limits/:key
+// The key becomes unbounded and its waiting tasks start
+func (h *AdminHandler) DeleteConcurrencyLimit(c *gin.Context) {
+	key := c.Param("key")
+
+	if _, ok := h.limiter.Limits()[key]; !ok {
+		response.ErrorWithMessage(c, http.StatusNotFound, "limit_not_found", "No concurrency limit for key")
+		return
+	}
+	h.limiter.RemoveLimit(key)
+	logger.Info("Concurrency limit removed", logger.String("key", key))
+
+	response.NoContent(c)
+}
+
+// SetLogLevel handles PUT /api/v1/admin/log-level
+// The level applies at once to every logger derived from the global logger, until the next
+// change or restart
+func (h *AdminHandler) SetLogLevel(c *gin.Context) {
+	var req dto.SetLogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	previous := logger.Level()
+	if err := logger.SetLevel(req.Level); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	// Warn, so the change is recorded whatever the new level
+	logger.Warn("Log level changed",
+		logger.String("from", previous),
+		logger.String("to", logger.Level()),
+	)
+
+	response.Success(c, dto.LogLevelResponse{Level: logger.Level()})
+}
+
+// RunCleanup handles POST /api/v1/admin/cleanup
+// The cleanup waits for a scheduled one in progress, then deletes (and archives, as configured)
+// the selected tasks; a dry run only counts them
+func (h *AdminHandler) RunCleanup(c *gin.Context) {
+	var body dto.CleanupRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	req, err := body.ToCleanupRequest()
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	result, err := h.scheduler.Cleanup(c.Request.Context(), req)
+	if err != nil {
+		logger.Error("Failed to run cleanup",
+			logger.String("handler", "RunCleanup"),
+			logger.Any("error", err),
+		)
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to run cleanup")
+		return
+	}
+
+	resp := dto.CleanupResponse{
+		ByStatus: make(map[entity.TaskStatus]int64, len(req.Statuses)),
+		Archived: result.Archived,
+		DryRun:   req.DryRun,
+	}
+	for _, status := range req.Statuses {
+		resp.ByStatus[status] = result.ByStatus[status]
+		resp.Total += result.ByStatus[status]
+	}
+	logger.Info("Cleanup run on demand",
+		logger.Any("statuses", req.Statuses),
+		logger.String("older_than", req.OlderThan.String()),
+		logger.Bool("dry_run", req.DryRun),
+		logger.Int64("total", resp.Total),
+	)
+
+	response.Success(c, resp)
+}
+
+func (h *AdminHandler) limitResponse(key string, limit int) dto.ConcurrencyLimitResponse {
+	return dto.ConcurrencyLimitResponse{
+		Key:         key,
+		MaxInFlight: limit,
+		InFlight:    h.limiter.InFlight(key),
+		Waiting:     h.limiter.Waiting(key),
+	}
+}
@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/delivery/websocket"
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/logger"
+	tasksvc "github.com/usual2970/later/task"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BulkDeleteTasks handles POST /api/v1/tasks/bulk/delete
+func (h *Handler) BulkDeleteTasks(c *gin.Context) {
+	deletedBy := "system"
+	if userID := c.GetHeader("X-User-ID"); userID != "" {
+		deletedBy = userID
+	}
+
+	h.bulk(c, "BulkDeleteTasks", func(sel tasksvc.BulkSelector) (*tasksvc.BulkResult, error) {
+		result, err := h.taskService.BulkDelete(c.Request.Context(), sel, deletedBy)
+		if err == nil {
+			for _, id := range result.IDs {
+				h.scheduler.ForgetTask(id)
+			}
+			h.broadcastBulk(c, websocket.EventTasksBulkDeleted, result, "")
+		}
+		return result, err
+	})
+}
+
+// BulkRetryTasks handles POST /api/v1/tasks/bulk/retry
+func (h *Handler) BulkRetryTasks(c *gin.Context) {
+	h.bulk(c, "BulkRetryTasks", func(sel tasksvc.BulkSelector) (*tasksvc.BulkResult, error) {
+		result, err := h.taskService.BulkRetry(c.Request.Context(), sel)
+		if err == nil && !result.DryRun {
+			// Retried tasks are due now; poll for them instead of waiting for the next tick
+			h.scheduler.Wake()
+			h.broadcastBulk(c, websocket.EventTasksBulkRetried, result, entity.TaskStatusPending)
+		}
+		return result, err
+	})
+}
+
+// bulk binds a bulk request, runs the operation and writes its result
+func (h *Handler) bulk(c *gin.Context, handler string, run func(tasksvc.BulkSelector) (*tasksvc.BulkResult, error)) {
+	var req dto.BulkTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	result, err := run(req.ToSelector())
+	if err != nil {
+		if status, _ := response.StatusFor(err); status == http.StatusInternalServerError {
+			logger.Error("Bulk operation failed",
+				logger.String("handler", handler),
+				logger.Any("error", err),
+			)
+		}
+		response.DomainError(c, err, "Failed to update tasks")
+		return
+	}
+
+	response.Success(c, dto.BulkTaskResponse{Count: result.Count, DryRun: result.DryRun})
+}
+
+// broadcastBulk publishes one summary event for a bulk operation that changed tasks
+func (h *Handler) broadcastBulk(c *gin.Context, eventType string, result *tasksvc.BulkResult, status entity.TaskStatus) {
+	if h.hub == nil || result.DryRun || result.Count == 0 {
+		return
+	}
+	tenantID, _ := domain.TenantFromContext(c.Request.Context())
+	h.hub.Broadcast(websocket.NewBulkEvent(eventType, result.Count, status, tenantID))
+}
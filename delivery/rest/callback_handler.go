@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestCallback handles POST /api/v1/callbacks/test
+// It delivers a sample callback synchronously, signed like a real one, so receivers can check
+// their handling before going live; a failed delivery is still a 200, reporting the outcome
+func (h *Handler) TestCallback(c *gin.Context) {
+	var req dto.TestCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	task := req.ToModel()
+	result, err := h.executor.TestCallback(c.Request.Context(), task)
+	if errors.Is(err, callback.ErrURLNotAllowed) {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	if err != nil {
+		response.DomainError(c, err, "Failed to test callback")
+		return
+	}
+
+	logger.Info("Test callback delivered",
+		logger.String("handler", "TestCallback"),
+		logger.String("callback_url", task.CallbackURL),
+		logger.String("outcome", result.Outcome),
+	)
+	response.Success(c, dto.NewTestCallbackResponse(task, result))
+}
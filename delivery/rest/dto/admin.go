@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// SetConcurrencyLimitRequest sets the maximum tasks in flight for a concurrency key
+type SetConcurrencyLimitRequest struct {
+	MaxInFlight int `json:"max_in_flight" binding:"required,min=1"`
+}
+
+// ConcurrencyLimitResponse describes a concurrency key's limit and current load
+type ConcurrencyLimitResponse struct {
+	Key         string `json:"key"`
+	MaxInFlight int    `json:"max_in_flight"`
+	InFlight    int    `json:"in_flight"`
+	Waiting     int    `json:"waiting"`
+}
+
+// SetLogLevelRequest changes the server's log level
+type SetLogLevelRequest struct {
+	Level string `json:"level" binding:"required"` // debug, info, warn or error
+}
+
+// LogLevelResponse reports the server's log level
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// CleanupRequest runs the cleanup job now for finished tasks of the given statuses
+// With DryRun set nothing is deleted and the response reports what would be
+type CleanupRequest struct {
+	OlderThan string              `json:"older_than" binding:"required"` // A duration such as 720h
+	Statuses  []entity.TaskStatus `json:"statuses" binding:"required,min=1"`
+	DryRun    bool                `json:"dry_run"`
+}
+
+// ToCleanupRequest converts the request to the scheduler's cleanup request
+func (r *CleanupRequest) ToCleanupRequest() (tasksvc.CleanupRequest, error) {
+	olderThan, err := time.ParseDuration(r.OlderThan)
+	if err != nil {
+		return tasksvc.CleanupRequest{}, fmt.Errorf("invalid older_than %q: %w", r.OlderThan, err)
+	}
+	req := tasksvc.CleanupRequest{Statuses: r.Statuses, OlderThan: olderThan, DryRun: r.DryRun}
+	return req, req.Validate()
+}
+
+// CleanupResponse reports the tasks a cleanup deleted per status, or would delete on a dry run
+type CleanupResponse struct {
+	ByStatus map[entity.TaskStatus]int64 `json:"by_status"` // Every requested status, even without tasks
+	Total    int64                       `json:"total"`
+	Archived int64                       `json:"archived"` // Copied to the archive table before deletion
+	DryRun   bool                        `json:"dry_run"`
+}
@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// BulkTaskRequest selects the tasks of a bulk delete or retry, either by ID or by filter
+// Limit caps the tasks affected; with DryRun set nothing changes and the response
+// reports how many tasks would be affected
+type BulkTaskRequest struct {
+	IDs      []string           `json:"ids"`
+	Status   *entity.TaskStatus `json:"status"`
+	Tag      string             `json:"tag"`
+	DateFrom *time.Time         `json:"date_from"`
+	DateTo   *time.Time         `json:"date_to"`
+	Limit    int                `json:"limit" binding:"required,min=1"`
+	DryRun   bool               `json:"dry_run"`
+}
+
+// ToSelector converts the request to the task service's selector
+func (r *BulkTaskRequest) ToSelector() tasksvc.BulkSelector {
+	return tasksvc.BulkSelector{
+		IDs:      r.IDs,
+		Status:   r.Status,
+		Tag:      r.Tag,
+		DateFrom: r.DateFrom,
+		DateTo:   r.DateTo,
+		Limit:    r.Limit,
+		DryRun:   r.DryRun,
+	}
+}
+
+// BulkTaskResponse reports how many tasks a bulk operation affected, or would affect on a dry run
+type BulkTaskResponse struct {
+	Count  int64 `json:"count"`
+	DryRun bool  `json:"dry_run"`
+}
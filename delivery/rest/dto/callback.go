@@ -0,0 +1,80 @@
+package dto
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/domain/entity"
+)
+
+// DefaultTestCallbackName is the X-Task-Name of test deliveries that don't name one
+const DefaultTestCallbackName = "callback_test"
+
+// TestCallbackRequest describes a sample delivery to a callback URL
+type TestCallbackRequest struct {
+	CallbackURL     string           `json:"callback_url" binding:"required,url"`
+	Payload         entity.JSONBytes `json:"payload"`          // Defaults to {}
+	Name            string           `json:"name"`             // Sent as X-Task-Name; defaults to DefaultTestCallbackName
+	TimeoutSeconds  *int             `json:"timeout_seconds"`  // Defaults to the task default
+	FollowRedirects bool             `json:"follow_redirects"` // As a task created with it would
+}
+
+// Validate validates the request and returns an error if invalid
+func (r *TestCallbackRequest) Validate() error {
+	if r.TimeoutSeconds != nil && (*r.TimeoutSeconds < entity.MinCallbackTimeoutSecs || *r.TimeoutSeconds > entity.MaxCallbackTimeoutSecs) {
+		return fmt.Errorf("timeout_seconds must be between %d and %d seconds", entity.MinCallbackTimeoutSecs, entity.MaxCallbackTimeoutSecs)
+	}
+	return nil
+}
+
+// ToModel converts the request to the unsaved task whose callback is delivered
+func (r *TestCallbackRequest) ToModel() *entity.Task {
+	name := r.Name
+	if name == "" {
+		name = DefaultTestCallbackName
+	}
+	payload := r.Payload
+	if len(payload) == 0 {
+		payload = entity.JSONBytes("{}")
+	}
+	task := entity.NewTask(name, payload, r.CallbackURL, time.Now(), 0)
+	if r.TimeoutSeconds != nil {
+		task.CallbackTimeoutSecs = *r.TimeoutSeconds
+	}
+	task.FollowRedirects = r.FollowRedirects
+	return task
+}
+
+// TestCallbackResponse reports how the receiver handled a test delivery
+type TestCallbackResponse struct {
+	TaskID       string  `json:"task_id"` // Sent as X-Task-ID; no task is stored under it
+	CallbackURL  string  `json:"callback_url"`
+	FinalURL     string  `json:"final_url,omitempty"`   // Where redirects led; omitted without any
+	Outcome      string  `json:"outcome"`               // success, retry or failure, as a real task would be treated
+	StatusCode   int     `json:"status_code,omitempty"` // Omitted when the receiver didn't respond
+	LatencyMs    int64   `json:"latency_ms"`
+	ResponseBody *string `json:"response_body,omitempty"` // Start of the body
+	Signed       bool    `json:"signed"`                  // Whether the request carried X-Signature
+	Error        string  `json:"error,omitempty"`
+}
+
+// NewTestCallbackResponse converts the result of a test delivery
+func NewTestCallbackResponse(task *entity.Task, result *callback.TestResult) TestCallbackResponse {
+	resp := TestCallbackResponse{
+		TaskID:       task.ID,
+		CallbackURL:  task.CallbackURL,
+		Outcome:      result.Outcome,
+		StatusCode:   result.StatusCode,
+		LatencyMs:    result.Duration.Milliseconds(),
+		ResponseBody: result.Response,
+		Signed:       result.Signed,
+	}
+	if result.FinalURL != task.CallbackURL {
+		resp.FinalURL = result.FinalURL
+	}
+	if result.Err != nil {
+		resp.Error = result.Err.Error()
+	}
+	return resp
+}
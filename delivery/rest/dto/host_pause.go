@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"time"
+
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// PauseHostRequest pauses the callbacks to a host; without Until the pause lasts until the host
+// is resumed
+type PauseHostRequest struct {
+	Until *CustomTime `json:"until"`
+}
+
+// UntilTime returns the end of the pause, nil for none
+func (r *PauseHostRequest) UntilTime() *time.Time {
+	if r.Until == nil || r.Until.IsZero() {
+		return nil
+	}
+	until := r.Until.Time
+	return &until
+}
+
+// HostPauseResponse describes a paused host
+type HostPauseResponse struct {
+	Host      string     `json:"host"`
+	Until     *time.Time `json:"until"` // Null until the host is resumed
+	CreatedAt time.Time  `json:"created_at"`
+	HeldTasks *int64     `json:"held_tasks,omitempty"` // Pending and failed tasks for the host, when listed
+}
+
+// NewHostPauseResponses converts the paused hosts with their held tasks
+func NewHostPauseResponses(paused []*tasksvc.HostPauseStatus) []HostPauseResponse {
+	resp := make([]HostPauseResponse, 0, len(paused))
+	for _, status := range paused {
+		held := status.HeldTasks
+		resp = append(resp, HostPauseResponse{
+			Host:      status.Host,
+			Until:     status.Until,
+			CreatedAt: status.CreatedAt,
+			HeldTasks: &held,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,68 @@
+package dto
+
+import (
+	"encoding/json"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// Duplicate ID handling for imports
+const (
+	ImportRejectDuplicates = "reject"
+	ImportRemapDuplicates  = "remap"
+)
+
+// ImportTasksQuery represents query parameters for importing tasks
+type ImportTasksQuery struct {
+	// OnDuplicate decides what happens to a task whose ID is taken: "reject" (default) or
+	// "remap" to store it under a new ID
+	OnDuplicate string `form:"on_duplicate" binding:"omitempty,oneof=reject remap"`
+}
+
+// ImportTaskRequest is one line of an NDJSON import: a task definition in the export's shape
+// It is validated like CreateTaskRequest, whose ID is kept unless taken; fields the export
+// carries that creation doesn't take, such as status and retry_count, are ignored
+type ImportTaskRequest struct {
+	CreateTaskRequest
+}
+
+// UnmarshalJSON also accepts the payload as exported, a string holding the JSON document
+func (r *ImportTaskRequest) UnmarshalJSON(b []byte) error {
+	type plain ImportTaskRequest
+	var aux struct {
+		plain
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	*r = ImportTaskRequest(aux.plain)
+
+	var exported string
+	if err := json.Unmarshal(aux.Payload, &exported); err == nil && (exported == "" || json.Valid([]byte(exported))) {
+		// An export without payloads leaves them empty, which fails the required check
+		if exported != "" {
+			r.Payload = entity.JSONBytes(exported)
+		}
+		return nil
+	}
+	if len(aux.Payload) > 0 && string(aux.Payload) != "null" {
+		return r.Payload.UnmarshalJSON(aux.Payload)
+	}
+	return nil
+}
+
+// ImportTasksResponse reports the outcome of every line of an import
+type ImportTasksResponse struct {
+	Imported int                `json:"imported"`
+	Failed   int                `json:"failed"`
+	Results  []ImportLineResult `json:"results"`
+}
+
+// ImportLineResult reports the outcome of one line of an import
+type ImportLineResult struct {
+	Line       int    `json:"line"`
+	ID         string `json:"id,omitempty"`
+	OriginalID string `json:"original_id,omitempty"` // Set when the task was stored under a new ID
+	Error      string `json:"error,omitempty"`
+}
@@ -0,0 +1,206 @@
+package dto
+
+import (
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// taskSizeHint is roughly the encoded size of a task response without its variable-length text
+const taskSizeHint = 512
+
+// sizeHint estimates the encoded size of the response, so it is written without regrowing
+func (tr *TaskResponse) sizeHint() int {
+	size := taskSizeHint + len(tr.Payload) + len(tr.CallbackURL)
+	if tr.LastCallbackResponse != nil {
+		size += len(*tr.LastCallbackResponse)
+	}
+	if tr.ErrorMessage != nil {
+		size += len(*tr.ErrorMessage)
+	}
+	return size
+}
+
+// appendJSON appends the response as a JSON object, with the fields and omissions its struct
+// tags declare
+func (tr *TaskResponse) appendJSON(b []byte) []byte {
+	b = append(b, `{"id":`...)
+	b = appendString(b, tr.ID)
+	b = append(b, `,"name":`...)
+	b = appendString(b, tr.Name)
+	b = append(b, `,"payload":`...)
+	b = appendString(b, tr.Payload)
+	if tr.PayloadRedacted {
+		b = append(b, `,"payload_redacted":true`...)
+	}
+	b = append(b, `,"callback_url":`...)
+	b = appendString(b, tr.CallbackURL)
+	b = append(b, `,"status":`...)
+	b = appendString(b, string(tr.Status))
+	b = append(b, `,"created_at":`...)
+	b = appendTime(b, tr.CreatedAt)
+	b = append(b, `,"scheduled_at":`...)
+	b = appendTime(b, tr.ScheduledFor)
+	b = appendOptionalTime(b, `,"started_at":`, tr.StartedAt)
+	b = appendOptionalTime(b, `,"completed_at":`, tr.CompletedAt)
+	b = appendOptionalTime(b, `,"expires_at":`, tr.ExpiresAt)
+	b = appendOptionalInt(b, `,"dispatch_latency_ms":`, tr.DispatchLatencyMs)
+	b = appendOptionalInt(b, `,"callback_duration_ms":`, tr.CallbackDurationMs)
+	b = append(b, `,"max_retries":`...)
+	b = strconv.AppendInt(b, int64(tr.MaxRetries), 10)
+	b = append(b, `,"retry_count":`...)
+	b = strconv.AppendInt(b, int64(tr.RetryCount), 10)
+	b = append(b, `,"callback_attempts":`...)
+	b = strconv.AppendInt(b, int64(tr.CallbackAttempts), 10)
+	b = append(b, `,"priority":`...)
+	b = strconv.AppendInt(b, int64(tr.Priority), 10)
+
+	if len(tr.Tags) > 0 {
+		b = append(b, `,"tags":[`...)
+		for i, tag := range tr.Tags {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendString(b, tag)
+		}
+		b = append(b, ']')
+	}
+	b = appendOptionalString(b, `,"concurrency_key":`, tr.ConcurrencyKey)
+	b = appendOptionalString(b, `,"depends_on":`, tr.DependsOn)
+	if tr.DependencyFailurePolicy != "" {
+		b = append(b, `,"dependency_failure_policy":`...)
+		b = appendString(b, string(tr.DependencyFailurePolicy))
+	}
+	b = appendOptionalString(b, `,"request_id":`, tr.RequestID)
+
+	if len(tr.Children) > 0 {
+		b = append(b, `,"children":[`...)
+		for i, child := range tr.Children {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, `{"id":`...)
+			b = appendString(b, child.ID)
+			b = append(b, `,"name":`...)
+			b = appendString(b, child.Name)
+			b = append(b, `,"status":`...)
+			b = appendString(b, string(child.Status))
+			b = append(b, '}')
+		}
+		b = append(b, ']')
+	}
+	if tr.TenantID != "" {
+		b = append(b, `,"tenant_id":`...)
+		b = appendString(b, tr.TenantID)
+	}
+	b = appendOptionalString(b, `,"error_message":`, tr.ErrorMessage)
+
+	// Error records keep their own time format, as time.Time marshals it
+	if len(tr.ErrorHistory) > 0 {
+		b = append(b, `,"error_history":[`...)
+		for i, record := range tr.ErrorHistory {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, `{"at":"`...)
+			b = record.At.AppendFormat(b, time.RFC3339Nano)
+			b = append(b, `","retry_count":`...)
+			b = strconv.AppendInt(b, int64(record.RetryCount), 10)
+			b = append(b, `,"error":`...)
+			b = appendString(b, record.Error)
+			b = append(b, '}')
+		}
+		b = append(b, ']')
+	}
+	b = appendOptionalString(b, `,"last_callback_response":`, tr.LastCallbackResponse)
+	if tr.EstimatedExecution != "" {
+		b = append(b, `,"estimated_execution":`...)
+		b = appendString(b, tr.EstimatedExecution)
+	}
+	return append(b, '}')
+}
+
+// appendTime appends t as a JSON string in UTC, to the second
+func appendTime(b []byte, t time.Time) []byte {
+	b = append(b, '"')
+	b = t.UTC().AppendFormat(b, time.RFC3339)
+	return append(b, '"')
+}
+
+// appendOptionalTime appends the key and time, or nothing if t is nil
+func appendOptionalTime(b []byte, key string, t *time.Time) []byte {
+	if t == nil {
+		return b
+	}
+	return appendTime(append(b, key...), *t)
+}
+
+// appendOptionalInt appends the key and number, or nothing if n is nil
+func appendOptionalInt(b []byte, key string, n *int64) []byte {
+	if n == nil {
+		return b
+	}
+	return strconv.AppendInt(append(b, key...), *n, 10)
+}
+
+// appendOptionalString appends the key and string, or nothing if s is nil
+func appendOptionalString(b []byte, key string, s *string) []byte {
+	if s == nil {
+		return b
+	}
+	return appendString(append(b, key...), *s)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendString appends s as a JSON string, escaped like encoding/json escapes it: HTML
+// characters and U+2028/U+2029 as \u escapes, and invalid UTF-8 replaced with U+FFFD
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b = append(b, s[start:i]...)
+			b = append(b, "\uFFFD"...)
+		case r == '\u2028' || r == '\u2029':
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
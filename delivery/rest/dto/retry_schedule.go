@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// RetryScheduleResponse projects when a task's remaining retries run if its attempts keep failing
+type RetryScheduleResponse struct {
+	TaskID              string                   `json:"task_id"`
+	Status              entity.TaskStatus        `json:"status"`
+	RetryCount          int                      `json:"retry_count"`
+	MaxRetries          int                      `json:"max_retries"`
+	RetryBackoffSeconds int                      `json:"retry_backoff_seconds"`
+	JitterPercent       int                      `json:"jitter_percent"`
+	MaxBackoffSeconds   float64                  `json:"max_backoff_seconds"`
+	Retries             []ProjectedRetryResponse `json:"retries"`
+}
+
+// ProjectedRetryResponse is when one remaining retry runs, not counting time spent on callbacks
+type ProjectedRetryResponse struct {
+	Retry          int       `json:"retry"`
+	BackoffSeconds float64   `json:"backoff_seconds"` // After the previous attempt, before jitter
+	Capped         bool      `json:"capped"`          // Shortened to the max backoff
+	At             time.Time `json:"at"`              // Without jitter
+	Earliest       time.Time `json:"earliest"`
+	Latest         time.Time `json:"latest"`
+}
+
+// NewRetryScheduleResponse converts a task's projected retries
+func NewRetryScheduleResponse(schedule *tasksvc.RetrySchedule) RetryScheduleResponse {
+	task := schedule.Task
+	resp := RetryScheduleResponse{
+		TaskID:              task.ID,
+		Status:              task.Status,
+		RetryCount:          task.RetryCount,
+		MaxRetries:          task.MaxRetries,
+		RetryBackoffSeconds: task.RetryBackoffSeconds,
+		JitterPercent:       schedule.Policy.JitterPercent,
+		MaxBackoffSeconds:   schedule.Policy.MaxBackoff.Seconds(),
+		Retries:             make([]ProjectedRetryResponse, 0, len(schedule.Retries)),
+	}
+	for _, retry := range schedule.Retries {
+		resp.Retries = append(resp.Retries, ProjectedRetryResponse{
+			Retry:          retry.Retry,
+			BackoffSeconds: retry.Backoff.Seconds(),
+			Capped:         retry.Capped,
+			At:             retry.At.UTC(),
+			Earliest:       retry.Earliest.UTC(),
+			Latest:         retry.Latest.UTC(),
+		})
+	}
+	return resp
+}
@@ -0,0 +1,26 @@
+package dto
+
+// SetTaskTagsRequest replaces a task's tags; an empty list removes them all
+type SetTaskTagsRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+// EditTaskTagsRequest adds and removes some of a task's tags; a tag in both lists is removed
+type EditTaskTagsRequest struct {
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+// TaskTagsResponse lists a task's tags after a change
+type TaskTagsResponse struct {
+	ID   string   `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+// NewTaskTagsResponse creates the response for a task's tags, with an empty list for none
+func NewTaskTagsResponse(id string, tags []string) TaskTagsResponse {
+	if tags == nil {
+		tags = []string{}
+	}
+	return TaskTagsResponse{ID: id, Tags: tags}
+}
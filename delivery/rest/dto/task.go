@@ -3,35 +3,77 @@ package dto
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/usual2970/later/domain/entity"
 	"github.com/usual2970/later/domain/repository"
+	tasksvc "github.com/usual2970/later/task"
 )
 
 // CreateTaskRequest represents a request to create a new task
+// Both the REST server and the embedded pkg/later routes bind and validate it, so a request
+// is accepted or rejected the same way by either
 type CreateTaskRequest struct {
+	// ID is assigned when empty; otherwise it must be a UUID, or an opaque ID if the server
+	// allows them, that isn't taken yet
+	ID string `json:"id,omitempty"`
+
 	Name           string           `json:"name" binding:"required"`
 	Payload        entity.JSONBytes `json:"payload" binding:"required"`
 	CallbackURL    string           `json:"callback_url" binding:"required,url"`
 	ScheduledFor   *CustomTime      `json:"scheduled_for"`
+	ScheduledAt    *CustomTime      `json:"scheduled_at"` // Alias of scheduled_for, which takes precedence
 	TimeoutSeconds *int             `json:"timeout_seconds"`
 	MaxRetries     *int             `json:"max_retries"`
 	Priority       int              `json:"priority"`
 	Tags           []string         `json:"tags"`
+
+	// RetryBackoffSeconds delays the first retry; the delay doubles with each retry (default 60)
+	RetryBackoffSeconds *int `json:"retry_backoff_seconds"`
+
+	// RetryableStatusCodes overrides the server's retryable response codes for this task
+	RetryableStatusCodes []int `json:"retryable_status_codes"`
+
+	// FollowRedirects lets the callback follow up to 10 redirects, resending the same POST, even
+	// if the server doesn't follow them for other tasks
+	FollowRedirects bool `json:"follow_redirects"`
+
+	// CallbackBodyTemplate renders the callback body with text/template, e.g.
+	// {"event": {{.Payload}}, "task_id": {{json .ID}}}; see callback.TemplateData
+	CallbackBodyTemplate *string `json:"callback_body_template"`
+
+	// CallbackOAuth2 overrides the server's OAuth2 client-credentials settings for this task
+	CallbackOAuth2 *entity.OAuth2Config `json:"callback_oauth2"`
+
+	// ConcurrencyKey selects the concurrency limit applied to the callback; defaults to the first tag
+	ConcurrencyKey *string `json:"concurrency_key"`
+
+	// DependsOn holds the task back in the waiting status until the parent task completes
+	DependsOn *string `json:"depends_on"`
+
+	// DependencyFailurePolicy decides what happens if the parent is dead-lettered, expired or
+	// cancelled: "dead_letter" (default) or "run_anyway"
+	DependencyFailurePolicy entity.DependencyFailurePolicy `json:"dependency_failure_policy"`
+
+	// ExpiresAt is when the task goes stale: picked up any later, it is expired instead of delivered
+	// Defaults to the scheduled time plus the server's default task TTL, if one is configured
+	ExpiresAt *CustomTime `json:"expires_at"`
 }
 
 // Validate validates the request and returns an error if invalid
 func (r *CreateTaskRequest) Validate() error {
-	// Validate payload size (max 1MB)
-	if len(r.Payload) > 1024*1024 {
-		return fmt.Errorf("payload size exceeds 1MB limit")
-	}
+	// Payload size is enforced by the task service, whose limit is configurable
 
 	// Validate timeout_seconds (5-300 range)
-	if r.TimeoutSeconds != nil && (*r.TimeoutSeconds < 5 || *r.TimeoutSeconds > 300) {
-		return fmt.Errorf("timeout_seconds must be between 5 and 300 seconds")
+	if r.TimeoutSeconds != nil && (*r.TimeoutSeconds < entity.MinCallbackTimeoutSecs || *r.TimeoutSeconds > entity.MaxCallbackTimeoutSecs) {
+		return fmt.Errorf("timeout_seconds must be between %d and %d seconds", entity.MinCallbackTimeoutSecs, entity.MaxCallbackTimeoutSecs)
+	}
+
+	// Validate retry_backoff_seconds (1 second to 1 day)
+	if r.RetryBackoffSeconds != nil && (*r.RetryBackoffSeconds < entity.MinRetryBackoffSecs || *r.RetryBackoffSeconds > entity.MaxRetryBackoffSecs) {
+		return fmt.Errorf("retry_backoff_seconds must be between %d and %d seconds", entity.MinRetryBackoffSecs, entity.MaxRetryBackoffSecs)
 	}
 
 	// Validate max_retries (0-20 range)
@@ -45,9 +87,9 @@ func (r *CreateTaskRequest) Validate() error {
 	}
 
 	// Validate scheduled_for (must be future or within 1 year)
-	if r.ScheduledFor != nil && !r.ScheduledFor.IsZero() {
+	if scheduledFor := r.scheduledFor(); scheduledFor != nil {
 		now := time.Now()
-		scheduledTime := r.ScheduledFor.Time
+		scheduledTime := scheduledFor.Time
 		if scheduledTime.Before(now.AddDate(0, 0, -1)) {
 			// Allow tasks scheduled in the past - they'll execute immediately
 			return nil
@@ -60,55 +102,129 @@ func (r *CreateTaskRequest) Validate() error {
 	return nil
 }
 
+// scheduledFor returns the requested execution time, or nil to execute immediately
+func (r *CreateTaskRequest) scheduledFor() *CustomTime {
+	for _, t := range []*CustomTime{r.ScheduledFor, r.ScheduledAt} {
+		if t != nil && !t.IsZero() {
+			return t
+		}
+	}
+	return nil
+}
+
+// RetryTaskRequest represents the optional body of a retry request
+type RetryTaskRequest struct {
+	ScheduledFor *CustomTime `json:"scheduled_for"` // Delays the restart
+	MaxRetries   *int        `json:"max_retries"`   // Replaces the task's retry budget
+}
+
+// Validate validates the request and returns an error if invalid
+func (r *RetryTaskRequest) Validate() error {
+	if r.MaxRetries != nil && (*r.MaxRetries < 0 || *r.MaxRetries > 20) {
+		return fmt.Errorf("max_retries must be between 0 and 20")
+	}
+	if r.ScheduledFor != nil && r.ScheduledFor.After(time.Now().AddDate(1, 0, 0)) {
+		return fmt.Errorf("scheduled_for must be within 1 year from now")
+	}
+	return nil
+}
+
+// ToOptions converts the request to task retry options
+func (r *RetryTaskRequest) ToOptions() tasksvc.RetryOptions {
+	opts := tasksvc.RetryOptions{MaxRetries: r.MaxRetries}
+	if r.ScheduledFor != nil {
+		opts.ScheduledFor = r.ScheduledFor.ToTime()
+	}
+	return opts
+}
+
 // TaskResponse represents a task response
 type TaskResponse struct {
-	ID                 string            `json:"id"`
-	Name               string            `json:"name"`
-	Payload            string            `json:"payload"` // Changed from json.RawMessage
-	CallbackURL        string            `json:"callback_url"`
-	Status             entity.TaskStatus `json:"status"`
-	CreatedAt          time.Time         `json:"created_at"`
-	ScheduledFor       time.Time         `json:"scheduled_at"`
-	StartedAt          *time.Time        `json:"started_at,omitempty"`
-	CompletedAt        *time.Time        `json:"completed_at,omitempty"`
-	MaxRetries         int               `json:"max_retries"`
-	RetryCount         int               `json:"retry_count"`
-	CallbackAttempts   int               `json:"callback_attempts"`
-	Priority           int               `json:"priority"`
-	Tags               []string          `json:"tags,omitempty"`
-	ErrorMessage       *string           `json:"error_message,omitempty"`
-	EstimatedExecution string            `json:"estimated_execution,omitempty"`
-}
-
-// MarshalJSON implements json.Marshaler to ensure all times are in UTC
-// TEMPORARILY DISABLED TO DEBUG PERFORMANCE ISSUE
+	ID                      string                         `json:"id"`
+	Name                    string                         `json:"name"`
+	Payload                 string                         `json:"payload"` // Changed from json.RawMessage
+	PayloadRedacted         bool                           `json:"payload_redacted,omitempty"`
+	CallbackURL             string                         `json:"callback_url"`
+	Status                  entity.TaskStatus              `json:"status"`
+	CreatedAt               time.Time                      `json:"created_at"`
+	ScheduledFor            time.Time                      `json:"scheduled_at"`
+	StartedAt               *time.Time                     `json:"started_at,omitempty"`
+	CompletedAt             *time.Time                     `json:"completed_at,omitempty"`
+	ExpiresAt               *time.Time                     `json:"expires_at,omitempty"`
+	DispatchLatencyMs       *int64                         `json:"dispatch_latency_ms,omitempty"`  // How late the last run started past its due time
+	CallbackDurationMs      *int64                         `json:"callback_duration_ms,omitempty"` // How long the last callback took
+	MaxRetries              int                            `json:"max_retries"`
+	RetryCount              int                            `json:"retry_count"`
+	CallbackAttempts        int                            `json:"callback_attempts"`
+	Priority                int                            `json:"priority"`
+	Tags                    []string                       `json:"tags,omitempty"`
+	ConcurrencyKey          *string                        `json:"concurrency_key,omitempty"`
+	DependsOn               *string                        `json:"depends_on,omitempty"`
+	DependencyFailurePolicy entity.DependencyFailurePolicy `json:"dependency_failure_policy,omitempty"`
+	RequestID               *string                        `json:"request_id,omitempty"`
+	Children                []ChildTask                    `json:"children,omitempty"`
+	TenantID                string                         `json:"tenant_id,omitempty"`
+	ErrorMessage            *string                        `json:"error_message,omitempty"`
+	ErrorHistory            []entity.ErrorRecord           `json:"error_history,omitempty"`
+	LastCallbackResponse    *string                        `json:"last_callback_response,omitempty"`
+	EstimatedExecution      string                         `json:"estimated_execution,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, formatting all times in UTC to the second
+// Responses are written directly rather than through reflection, as list pages marshal many
 func (tr TaskResponse) MarshalJSON() ([]byte, error) {
-	// Create a new type to avoid infinite recursion
-	type Alias TaskResponse
+	return tr.appendJSON(make([]byte, 0, tr.sizeHint())), nil
+}
 
-	aux := &struct {
-		Alias
-		CreatedAt    string  `json:"created_at"`
-		ScheduledFor string  `json:"scheduled_at"`
-		StartedAt    *string `json:"started_at,omitempty"`
-		CompletedAt  *string `json:"completed_at,omitempty"`
-	}{
-		Alias:        (Alias)(tr),
-		CreatedAt:    tr.CreatedAt.UTC().Format(time.RFC3339),
-		ScheduledFor: tr.ScheduledFor.UTC().Format(time.RFC3339),
-	}
+// ChildTask summarizes a task that depends on another
+type ChildTask struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Status entity.TaskStatus `json:"status"`
+}
 
-	if tr.StartedAt != nil {
-		s := tr.StartedAt.UTC().Format(time.RFC3339)
-		aux.StartedAt = &s
+// NewChildTasks summarizes the tasks depending on a task
+func NewChildTasks(children []*entity.Task) []ChildTask {
+	summaries := make([]ChildTask, 0, len(children))
+	for _, child := range children {
+		summaries = append(summaries, ChildTask{ID: child.ID, Name: child.Name, Status: child.Status})
 	}
+	return summaries
+}
 
-	if tr.CompletedAt != nil {
-		s := tr.CompletedAt.UTC().Format(time.RFC3339)
-		aux.CompletedAt = &s
+// NewCreatedTaskResponse converts a newly created task, with how it will be dispatched
+func NewCreatedTaskResponse(task *entity.Task, dispatch tasksvc.Dispatch) TaskResponse {
+	// Convert JSONBytes to string for JSON response
+	var payloadStr string
+	if len(task.Payload) > 0 && json.Valid(task.Payload) {
+		payloadStr = string(task.Payload)
 	}
 
-	return json.Marshal(aux)
+	resp := TaskResponse{
+		ID:                 task.ID,
+		Name:               task.Name,
+		Payload:            payloadStr,
+		CallbackURL:        task.CallbackURL,
+		Status:             task.Status,
+		CreatedAt:          task.CreatedAt,
+		ScheduledFor:       task.ScheduledAt,
+		ExpiresAt:          task.ExpiresAt,
+		DispatchLatencyMs:  task.DispatchLatencyMs,
+		CallbackDurationMs: task.CallbackDurationMs,
+		MaxRetries:         task.MaxRetries,
+		RetryCount:         task.RetryCount,
+		CallbackAttempts:   task.CallbackAttempts,
+		Priority:           task.Priority,
+		Tags:               task.Tags,
+		RequestID:          task.RequestID,
+		TenantID:           task.TenantID,
+		EstimatedExecution: string(dispatch),
+	}
+	if task.DependsOn != nil {
+		resp.DependsOn = task.DependsOn
+		resp.DependencyFailurePolicy = task.DependencyFailurePolicy
+	}
+	return resp
 }
 
 // ToModel converts CreateTaskRequest to a Task entity
@@ -116,8 +232,8 @@ func (r *CreateTaskRequest) ToModel() *entity.Task {
 	now := time.Now()
 	scheduledAt := now
 
-	if r.ScheduledFor != nil && !r.ScheduledFor.IsZero() {
-		scheduledAt = r.ScheduledFor.Time
+	if scheduledFor := r.scheduledFor(); scheduledFor != nil {
+		scheduledAt = scheduledFor.Time
 	}
 
 	// Set defaults
@@ -126,7 +242,7 @@ func (r *CreateTaskRequest) ToModel() *entity.Task {
 		maxRetries = *r.MaxRetries
 	}
 
-	timeoutSeconds := 30
+	timeoutSeconds := entity.DefaultCallbackTimeoutSecs
 	if r.TimeoutSeconds != nil {
 		timeoutSeconds = *r.TimeoutSeconds
 	}
@@ -137,26 +253,48 @@ func (r *CreateTaskRequest) ToModel() *entity.Task {
 	}
 
 	task := entity.NewTask(r.Name, r.Payload, r.CallbackURL, scheduledAt, priority)
+	if r.ID != "" {
+		task.ID = r.ID
+	}
 
 	// Override defaults with request values
 	task.MaxRetries = maxRetries
 	task.CallbackTimeoutSecs = timeoutSeconds
+	if r.RetryBackoffSeconds != nil {
+		task.RetryBackoffSeconds = *r.RetryBackoffSeconds
+	}
 	task.Tags = r.Tags
+	task.RetryableStatusCodes = r.RetryableStatusCodes
+	task.FollowRedirects = r.FollowRedirects
+	task.CallbackBodyTemplate = r.CallbackBodyTemplate
+	task.CallbackOAuth2 = r.CallbackOAuth2
+	task.ConcurrencyKey = r.ConcurrencyKey
+	task.DependsOn = r.DependsOn
+	task.DependencyFailurePolicy = r.DependencyFailurePolicy
+	if r.ExpiresAt != nil && !r.ExpiresAt.IsZero() {
+		expiresAt := r.ExpiresAt.Time
+		task.ExpiresAt = &expiresAt
+	}
 
 	return task
 }
 
 // ListTasksQuery represents query parameters for listing tasks
 type ListTasksQuery struct {
-	Status    *entity.TaskStatus `form:"status"`
-	Priority  *int               `form:"priority"`
-	Tags      string             `form:"tags"` // comma-separated
-	DateFrom  *string            `form:"date_from"`
-	DateTo    *string            `form:"date_to"`
-	Page      int                `form:"page" binding:"required,min=1"`
-	Limit     int                `form:"limit" binding:"required,min=1,max=100"`
-	SortBy    string             `form:"sort_by"`
-	SortOrder string             `form:"sort_order"`
+	TenantID      *string            `form:"tenant_id"` // Only meaningful for admin (unscoped) callers
+	Status        *entity.TaskStatus `form:"status"`
+	Priority      *int               `form:"priority"`
+	Tags          string             `form:"tags"` // comma-separated
+	Name          string             `form:"name"`
+	NamePrefix    string             `form:"name_prefix"`
+	DateFrom      *string            `form:"date_from"`
+	DateTo        *string            `form:"date_to"`
+	ScheduledFrom *string            `form:"scheduled_from"`
+	ScheduledTo   *string            `form:"scheduled_to"`
+	Page          int                `form:"page" binding:"required,min=1"`
+	Limit         int                `form:"limit" binding:"required,min=1,max=100"`
+	SortBy        string             `form:"sort_by"`
+	SortOrder     string             `form:"sort_order"`
 }
 
 // Validate validates and normalizes the query parameters
@@ -190,12 +328,15 @@ func (q *ListTasksQuery) Validate() error {
 // ToRepositoryFilter converts ListTasksQuery to repository filter
 func (q *ListTasksQuery) ToRepositoryFilter() (*repository.TaskFilter, error) {
 	filter := &repository.TaskFilter{
-		Status:    q.Status,
-		Priority:  q.Priority,
-		Page:      q.Page,
-		Limit:     q.Limit,
-		SortBy:    q.SortBy,
-		SortOrder: q.SortOrder,
+		TenantID:   q.TenantID,
+		Status:     q.Status,
+		Priority:   q.Priority,
+		Name:       q.Name,
+		NamePrefix: q.NamePrefix,
+		Page:       q.Page,
+		Limit:      q.Limit,
+		SortBy:     q.SortBy,
+		SortOrder:  q.SortOrder,
 	}
 
 	// Parse tags
@@ -220,15 +361,201 @@ func (q *ListTasksQuery) ToRepositoryFilter() (*repository.TaskFilter, error) {
 		filter.DateTo = &dateTo
 	}
 
+	if q.ScheduledFrom != nil {
+		scheduledFrom, err := time.Parse(time.RFC3339, *q.ScheduledFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scheduled_from format: %w", err)
+		}
+		filter.ScheduledFrom = &scheduledFrom
+	}
+
+	if q.ScheduledTo != nil {
+		scheduledTo, err := time.Parse(time.RFC3339, *q.ScheduledTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scheduled_to format: %w", err)
+		}
+		filter.ScheduledTo = &scheduledTo
+	}
+
 	return filter, nil
 }
 
+// Upcoming window bounds
+const (
+	DefaultUpcomingWithin = time.Hour
+	MaxUpcomingWithin     = 7 * 24 * time.Hour
+)
+
+// UpcomingTasksQuery represents query parameters for listing tasks about to execute
+type UpcomingTasksQuery struct {
+	Within string `form:"within"` // Go duration, e.g. "1h"; defaults to DefaultUpcomingWithin
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+// ToRepositoryFilter converts UpcomingTasksQuery to a filter for the pending tasks due by now+within,
+// soonest first; overdue tasks are included as they are next to run
+func (q *UpcomingTasksQuery) ToRepositoryFilter(now time.Time) (*repository.TaskFilter, error) {
+	within := DefaultUpcomingWithin
+	if q.Within != "" {
+		d, err := time.ParseDuration(q.Within)
+		if err != nil {
+			return nil, fmt.Errorf("invalid within format: %w", err)
+		}
+		within = d
+	}
+	if within <= 0 || within > MaxUpcomingWithin {
+		return nil, fmt.Errorf("within must be positive and at most %s", MaxUpcomingWithin)
+	}
+
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.Limit <= 0 || q.Limit > 100 {
+		q.Limit = 50
+	}
+
+	status := entity.TaskStatusPending
+	scheduledTo := now.Add(within)
+	return &repository.TaskFilter{
+		Status:      &status,
+		ScheduledTo: &scheduledTo,
+		Page:        q.Page,
+		Limit:       q.Limit,
+		SortBy:      "scheduled_at",
+		SortOrder:   "asc",
+	}, nil
+}
+
+// MaxExportRows caps the rows a single export returns; narrow the filters to export more
+const MaxExportRows = 100000
+
+// Export formats
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// ExportTasksQuery represents query parameters for exporting tasks
+// It takes the ListTasksQuery filters; every matching task is exported instead of a page
+type ExportTasksQuery struct {
+	TenantID       *string            `form:"tenant_id"` // Only meaningful for admin (unscoped) callers
+	Status         *entity.TaskStatus `form:"status"`
+	Priority       *int               `form:"priority"`
+	Tags           string             `form:"tags"` // comma-separated
+	Name           string             `form:"name"`
+	NamePrefix     string             `form:"name_prefix"`
+	DateFrom       *string            `form:"date_from"`
+	DateTo         *string            `form:"date_to"`
+	ScheduledFrom  *string            `form:"scheduled_from"`
+	ScheduledTo    *string            `form:"scheduled_to"`
+	SortBy         string             `form:"sort_by"`
+	SortOrder      string             `form:"sort_order"`
+	Format         string             `form:"format" binding:"required,oneof=csv ndjson"`
+	IncludePayload bool               `form:"include_payload"`
+}
+
+// ToRepositoryFilter converts ExportTasksQuery to a repository filter capped at MaxExportRows
+func (q *ExportTasksQuery) ToRepositoryFilter() (*repository.TaskFilter, error) {
+	list := ListTasksQuery{
+		TenantID:      q.TenantID,
+		Status:        q.Status,
+		Priority:      q.Priority,
+		Tags:          q.Tags,
+		Name:          q.Name,
+		NamePrefix:    q.NamePrefix,
+		DateFrom:      q.DateFrom,
+		DateTo:        q.DateTo,
+		ScheduledFrom: q.ScheduledFrom,
+		ScheduledTo:   q.ScheduledTo,
+		SortBy:        q.SortBy,
+		SortOrder:     q.SortOrder,
+	}
+	if err := list.Validate(); err != nil {
+		return nil, err
+	}
+	filter, err := list.ToRepositoryFilter()
+	if err != nil {
+		return nil, err
+	}
+	filter.Page = 0
+	filter.Limit = MaxExportRows
+	return filter, nil
+}
+
+// ExportCSVHeader is the header row of a CSV export; payload is only present when requested
+var ExportCSVHeader = []string{
+	"id", "name", "status", "callback_url", "priority", "tags", "tenant_id",
+	"created_at", "scheduled_at", "started_at", "completed_at",
+	"max_retries", "retry_count", "callback_attempts", "error_message",
+}
+
+// ExportCSVRecord converts a task to a CSV export row matching ExportCSVHeader
+// Times are RFC 3339 in UTC and tags are joined with "|"
+func ExportCSVRecord(task *entity.Task) []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	var errorMessage string
+	if task.ErrorMessage != nil {
+		errorMessage = *task.ErrorMessage
+	}
+	return []string{
+		task.ID, task.Name, string(task.Status), task.CallbackURL,
+		strconv.Itoa(task.Priority), strings.Join(task.Tags, "|"), task.TenantID,
+		formatTime(&task.CreatedAt), formatTime(&task.ScheduledAt), formatTime(task.StartedAt), formatTime(task.CompletedAt),
+		strconv.Itoa(task.MaxRetries), strconv.Itoa(task.RetryCount), strconv.Itoa(task.CallbackAttempts), errorMessage,
+	}
+}
+
 // TaskListResponse represents a paginated list of tasks
 type TaskListResponse struct {
 	Tasks      []*TaskResponse `json:"tasks"`
 	Pagination PaginationInfo  `json:"pagination"`
 }
 
+// MarshalJSON implements json.Marshaler, writing the whole page into one buffer
+func (r TaskListResponse) MarshalJSON() ([]byte, error) {
+	size := 128
+	for _, task := range r.Tasks {
+		if task != nil {
+			size += task.sizeHint()
+		}
+	}
+	b := make([]byte, 0, size)
+
+	b = append(b, `{"tasks":`...)
+	if r.Tasks == nil {
+		b = append(b, "null"...)
+	} else {
+		b = append(b, '[')
+		for i, task := range r.Tasks {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if task == nil {
+				b = append(b, "null"...)
+			} else {
+				b = task.appendJSON(b)
+			}
+		}
+		b = append(b, ']')
+	}
+
+	b = append(b, `,"pagination":{"page":`...)
+	b = strconv.AppendInt(b, int64(r.Pagination.Page), 10)
+	b = append(b, `,"limit":`...)
+	b = strconv.AppendInt(b, int64(r.Pagination.Limit), 10)
+	b = append(b, `,"total":`...)
+	b = strconv.AppendInt(b, r.Pagination.Total, 10)
+	b = append(b, `,"total_pages":`...)
+	b = strconv.AppendInt(b, int64(r.Pagination.TotalPages), 10)
+	return append(b, "}}"...), nil
+}
+
 // PaginationInfo represents pagination metadata
 type PaginationInfo struct {
 	Page       int   `json:"page"`
@@ -237,19 +564,85 @@ type PaginationInfo struct {
 	TotalPages int   `json:"total_pages"`
 }
 
+// ExecuteTaskResponse reports the outcome of running a task inline
+type ExecuteTaskResponse struct {
+	Task       *TaskResponse `json:"task"`
+	Delivered  bool          `json:"delivered"`
+	StatusCode int           `json:"status_code,omitempty"` // Omitted when the receiver didn't respond
+	FinalURL   string        `json:"final_url,omitempty"`   // Where redirects led; omitted without any
+	LatencyMs  int64         `json:"latency_ms"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// AbortTaskRequest represents the optional body of an abort request
+type AbortTaskRequest struct {
+	Terminal bool `json:"terminal"` // Dead-letter the task instead of failing it for a retry
+}
+
+// AbortTaskResponse acknowledges an abort; the task's new status is persisted by its worker
+type AbortTaskResponse struct {
+	ID       string `json:"id"`
+	Terminal bool   `json:"terminal"`
+}
+
 // StatsResponse represents statistics about tasks
 type StatsResponse struct {
 	Total               int64                       `json:"total"`
 	ByStatus            map[entity.TaskStatus]int64 `json:"by_status"`
 	Last24h             Last24hStats                `json:"last_24h"`
-	CallbackSuccessRate float64                     `json:"callback_success_rate"`
+	Window              string                      `json:"window"`
+	Recent              Last24hStats                `json:"recent"`                // Activity within Window
+	CallbackSuccessRate float64                     `json:"callback_success_rate"` // Completed share of the tasks finished within Window
 }
 
-// Last24hStats represents statistics for the last 24 hours
+// Last24hStats represents task activity within a time window (the last 24 hours unless noted)
 type Last24hStats struct {
-	Submitted int64 `json:"submitted"`
-	Completed int64 `json:"completed"`
-	Failed    int64 `json:"failed"`
+	Submitted              int64   `json:"submitted"`
+	Completed              int64   `json:"completed"`
+	Failed                 int64   `json:"failed"` // Awaiting retry
+	DeadLettered           int64   `json:"dead_lettered"`
+	Expired                int64   `json:"expired"`               // Picked up past their expiry, never delivered
+	AvgCallbackAttempts    float64 `json:"avg_callback_attempts"` // Of the tasks completed or dead-lettered
+	P50CompletionLatencyMs float64 `json:"p50_completion_latency_ms"`
+	P95CompletionLatencyMs float64 `json:"p95_completion_latency_ms"`
+	P50DispatchLatencyMs   float64 `json:"p50_dispatch_latency_ms"` // Of the tasks started, past their due time
+	P95DispatchLatencyMs   float64 `json:"p95_dispatch_latency_ms"`
+	P50CallbackDurationMs  float64 `json:"p50_callback_duration_ms"`
+	P95CallbackDurationMs  float64 `json:"p95_callback_duration_ms"`
+}
+
+// TimeSeriesResponse represents task activity bucketed over a window
+type TimeSeriesResponse struct {
+	Window  string             `json:"window"`
+	Bucket  string             `json:"bucket"`
+	Buckets []TimeSeriesBucket `json:"buckets"`
+}
+
+// TimeSeriesBucket represents task activity within one bucket
+type TimeSeriesBucket struct {
+	Start                time.Time `json:"start"`
+	Created              int64     `json:"created"`
+	Completed            int64     `json:"completed"`
+	Failed               int64     `json:"failed"`
+	DeadLettered         int64     `json:"dead_lettered"`
+	AvgCallbackLatencyMs float64   `json:"avg_callback_latency_ms"`
+}
+
+// GroupedStatsResponse represents task counts per task name or tag over a window
+type GroupedStatsResponse struct {
+	Window    string       `json:"window"`
+	GroupBy   string       `json:"group_by"`
+	Groups    []GroupStats `json:"groups"`    // Largest first
+	Truncated bool         `json:"truncated"` // Only the largest groups were returned
+}
+
+// GroupStats represents the tasks of one name or tag created within the window
+type GroupStats struct {
+	Key                 string                      `json:"key"`
+	Total               int64                       `json:"total"`
+	ByStatus            map[entity.TaskStatus]int64 `json:"by_status"`
+	AvgCallbackAttempts float64                     `json:"avg_callback_attempts"`
+	SuccessRate         float64                     `json:"success_rate"` // Completed share of the tasks finished
 }
 
 // ErrorResponse represents an error response
@@ -257,3 +650,35 @@ type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message,omitempty"`
 }
+
+// TaskTimelineResponse lists a task's recorded events, oldest first
+type TaskTimelineResponse struct {
+	TaskID string              `json:"task_id"`
+	Events []TaskEventResponse `json:"events"`
+}
+
+// TaskEventResponse is one event in a task's timeline
+type TaskEventResponse struct {
+	ID         int64             `json:"id"` // As sent to stream clients, for resuming with ?since
+	Type       string            `json:"type"`
+	FromStatus entity.TaskStatus `json:"from_status,omitempty"` // Empty for the task's first recorded event
+	ToStatus   entity.TaskStatus `json:"to_status,omitempty"`
+	Metadata   json.RawMessage   `json:"metadata,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// NewTaskTimelineResponse converts a task's recorded events
+func NewTaskTimelineResponse(taskID string, events []*entity.TaskEvent) TaskTimelineResponse {
+	resp := TaskTimelineResponse{TaskID: taskID, Events: make([]TaskEventResponse, 0, len(events))}
+	for _, event := range events {
+		resp.Events = append(resp.Events, TaskEventResponse{
+			ID:         event.ID,
+			Type:       event.Type,
+			FromStatus: event.FromStatus,
+			ToStatus:   event.ToStatus,
+			Metadata:   event.Metadata,
+			CreatedAt:  event.CreatedAt.UTC(),
+		})
+	}
+	return resp
+}
@@ -0,0 +1,172 @@
+package dto
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// reflectedJSON encodes a response the way encoding/json would without its MarshalJSON method:
+// with reflection, and its five timestamps in UTC to the second
+func reflectedJSON(t *testing.T, tr TaskResponse) string {
+	type plain TaskResponse
+	utc := func(at *time.Time) *string {
+		if at == nil {
+			return nil
+		}
+		s := at.UTC().Format(time.RFC3339)
+		return &s
+	}
+	b, err := json.Marshal(struct {
+		plain
+		CreatedAt    string  `json:"created_at"`
+		ScheduledFor string  `json:"scheduled_at"`
+		StartedAt    *string `json:"started_at,omitempty"`
+		CompletedAt  *string `json:"completed_at,omitempty"`
+		ExpiresAt    *string `json:"expires_at,omitempty"`
+	}{
+		plain:        plain(tr),
+		CreatedAt:    *utc(&tr.CreatedAt),
+		ScheduledFor: *utc(&tr.ScheduledFor),
+		StartedAt:    utc(tr.StartedAt),
+		CompletedAt:  utc(tr.CompletedAt),
+		ExpiresAt:    utc(tr.ExpiresAt),
+	})
+	require.NoError(t, err)
+	return string(b)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestTaskResponseMarshalJSON(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	at := time.Date(2024, 3, 1, 9, 30, 15, 123456789, tokyo)
+	tests := []struct {
+		name string
+		task TaskResponse
+	}{
+		{"Minimal", TaskResponse{ID: "a", CreatedAt: at, ScheduledFor: at}},
+		{"Every field", TaskResponse{
+			ID:                      "00000000-0000-0000-0000-000000000001",
+			Name:                    "send_email",
+			Payload:                 `{"to":"user@example.com","html":"<p>Hi & bye</p>"}`,
+			PayloadRedacted:         true,
+			CallbackURL:             "https://example.com/callback?a=1&b=2",
+			Status:                  entity.TaskStatusFailed,
+			CreatedAt:               at,
+			ScheduledFor:            at.Add(time.Hour),
+			StartedAt:               ptr(at.Add(2 * time.Hour)),
+			CompletedAt:             ptr(at.Add(3 * time.Hour)),
+			ExpiresAt:               ptr(at.Add(4 * time.Hour)),
+			DispatchLatencyMs:       ptr(int64(-1)),
+			CallbackDurationMs:      ptr(int64(1500)),
+			MaxRetries:              5,
+			RetryCount:              2,
+			CallbackAttempts:        3,
+			Priority:                10,
+			Tags:                    []string{"email", "eu"},
+			ConcurrencyKey:          ptr("email"),
+			DependsOn:               ptr("parent"),
+			DependencyFailurePolicy: entity.DependencyFailureRunAnyway,
+			RequestID:               ptr("req-1"),
+			Children:                []ChildTask{{ID: "child", Name: "notify", Status: entity.TaskStatusWaiting}},
+			TenantID:                "acme",
+			ErrorMessage:            ptr("callback returned 503"),
+			ErrorHistory:            []entity.ErrorRecord{{At: at, RetryCount: 1, Error: "connection refused"}},
+			LastCallbackResponse:    ptr("\"unavailable\"\n"),
+			EstimatedExecution:      "immediate",
+		}},
+		{"Empty collections are omitted", TaskResponse{ID: "a", Tags: []string{}, Children: []ChildTask{}, ErrorHistory: []entity.ErrorRecord{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.task)
+			require.NoError(t, err)
+			assert.JSONEq(t, reflectedJSON(t, tt.task), string(got))
+		})
+	}
+}
+
+// TestAppendString tests that strings are escaped exactly as encoding/json escapes them
+func TestAppendString(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"plain",
+		`quote " and backslash \\`,
+		"controls \x00 \x01 \b \f \n \r \t \x1f \x7f",
+		"<script>alert('x & y')</script>",
+		"unicode: héllo, 日本語, emoji 🎉",
+		"separators \u2028 and \u2029",
+		"invalid \xff\xfe utf-8 \xe2\x82",
+	} {
+		want, err := json.Marshal(s)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(appendString(nil, s)), "%q", s)
+	}
+}
+
+func TestTaskListResponseMarshalJSON(t *testing.T) {
+	page := listPage(3)
+	page.Tasks[1] = nil
+	got, err := json.Marshal(page)
+	require.NoError(t, err)
+
+	want := `{"tasks":[` + reflectedJSON(t, *page.Tasks[0]) + `,null,` + reflectedJSON(t, *page.Tasks[2]) +
+		`],"pagination":{"page":1,"limit":3,"total":3,"total_pages":1}}`
+	assert.JSONEq(t, want, string(got))
+
+	got, err = json.Marshal(TaskListResponse{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"tasks":null,"pagination":{"page":0,"limit":0,"total":0,"total_pages":0}}`, string(got))
+}
+
+// listPage returns a list response of n tasks filled in like a typical page of the task list
+func listPage(n int) TaskListResponse {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tasks := make([]*TaskResponse, n)
+	for i := range tasks {
+		started, completed := created.Add(time.Minute), created.Add(2*time.Minute)
+		latency, duration := int64(120), int64(35)
+		tasks[i] = &TaskResponse{
+			ID:                 fmt.Sprintf("00000000-0000-0000-0000-%012d", i),
+			Name:               "send_email",
+			Payload:            `{"to":"user@example.com","subject":"Your order has shipped","order_id":12345}`,
+			CallbackURL:        "https://example.com/webhooks/later",
+			Status:             entity.TaskStatusCompleted,
+			CreatedAt:          created,
+			ScheduledFor:       created,
+			StartedAt:          &started,
+			CompletedAt:        &completed,
+			DispatchLatencyMs:  &latency,
+			CallbackDurationMs: &duration,
+			MaxRetries:         5,
+			CallbackAttempts:   1,
+			Priority:           5,
+			Tags:               []string{"email", "orders"},
+			TenantID:           "acme",
+		}
+	}
+	return TaskListResponse{Tasks: tasks, Pagination: PaginationInfo{Page: 1, Limit: n, Total: int64(n), TotalPages: 1}}
+}
+
+func BenchmarkTaskListResponseJSON(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		page := listPage(n)
+		b.Run(fmt.Sprintf("tasks=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := json.Marshal(page); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
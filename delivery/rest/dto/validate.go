@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"github.com/usual2970/later/domain/entity"
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// ValidatedTaskResponse is the task a create request would make, with the defaults it would get
+type ValidatedTaskResponse struct {
+	Task                TaskResponse `json:"task"`
+	TimeoutSeconds      int          `json:"timeout_seconds"`
+	RetryBackoffSeconds int          `json:"retry_backoff_seconds"`
+}
+
+// NewValidatedTaskResponse converts a validated task, with how it would be dispatched
+func NewValidatedTaskResponse(task *entity.Task, dispatch tasksvc.Dispatch) ValidatedTaskResponse {
+	return ValidatedTaskResponse{
+		Task:                NewCreatedTaskResponse(task, dispatch),
+		TimeoutSeconds:      task.CallbackTimeoutSecs,
+		RetryBackoffSeconds: task.RetryBackoffSeconds,
+	}
+}
@@ -0,0 +1,108 @@
+package rest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/middleware"
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/logger"
+	"github.com/usual2970/later/infrastructure/worker"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExecuteTask handles POST /api/v1/tasks/:id/execute
+// It runs a pending or failed task's callback inline and responds with the delivery outcome;
+// a failed delivery is still a 200, with the task persisted as failed or dead-lettered
+func (h *Handler) ExecuteTask(c *gin.Context) {
+	id := c.Param("id")
+
+	execution, err := h.executor.Execute(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, worker.ErrNotExecutable):
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_status", "Can only execute pending or failed tasks")
+		return
+	case errors.Is(err, worker.ErrAlreadyClaimed):
+		response.ErrorWithMessage(c, http.StatusConflict, "task_processing", "Task is already being processed")
+		return
+	case err != nil:
+		if status, _ := response.StatusFor(err); status == http.StatusInternalServerError {
+			logger.Error("Failed to execute task",
+				logger.String("handler", "ExecuteTask"),
+				logger.String("task_id", id),
+				logger.Any("error", err),
+			)
+		}
+		response.DomainError(c, err, "Failed to execute task")
+		return
+	}
+
+	// A scheduled task no longer needs its delay queue entry
+	h.scheduler.ForgetTask(id)
+
+	// Callers without payload access get the task without its payload, as in listings
+	redacted := middleware.PayloadsRedacted(c)
+	resp := dto.ExecuteTaskResponse{
+		Task:       detailedTaskResponse(execution.Task, !redacted, redacted),
+		Delivered:  execution.Err == nil,
+		StatusCode: execution.Attempt.StatusCode,
+		LatencyMs:  execution.Attempt.Duration.Milliseconds(),
+	}
+	if execution.Attempt.FinalURL != execution.Task.CallbackURL {
+		resp.FinalURL = execution.Attempt.FinalURL
+	}
+	if execution.Err != nil {
+		resp.Error = execution.Err.Error()
+	}
+	logger.Info("Task executed inline",
+		logger.String("handler", "ExecuteTask"),
+		logger.String("task_id", id),
+		logger.RequestID(execution.Task.RequestID),
+		logger.Any("delivered", resp.Delivered),
+	)
+	response.Success(c, resp)
+}
+
+// AbortTask handles POST /api/v1/tasks/:id/abort
+// It cancels the in-flight callback of a task processed by this instance, so a request stuck on
+// an unresponsive host fails at once; the task is then failed for a retry as usual, or
+// dead-lettered when the body sets "terminal": true
+func (h *Handler) AbortTask(c *gin.Context) {
+	id := c.Param("id")
+
+	// The body is optional, so an empty one aborts for a retry
+	var req dto.AbortTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	// Looked up first so tasks of other tenants can't be aborted
+	task, err := h.taskService.GetTask(c.Request.Context(), id)
+	if err != nil {
+		response.DomainError(c, err, "Failed to get task")
+		return
+	}
+	if task.Status != entity.TaskStatusProcessing {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_status", "Can only abort processing tasks")
+		return
+	}
+
+	// Another replica may be processing it, or its callback has just returned
+	if err := h.executor.Abort(id, req.Terminal); err != nil {
+		response.ErrorWithMessage(c, http.StatusConflict, "not_in_flight", "Task callback is not in flight on this instance")
+		return
+	}
+
+	logger.Info("Task aborted",
+		logger.String("handler", "AbortTask"),
+		logger.String("task_id", id),
+		logger.RequestID(task.RequestID),
+		logger.Any("terminal", req.Terminal),
+	)
+	response.Accepted(c, dto.AbortTaskResponse{ID: id, Terminal: req.Terminal})
+}
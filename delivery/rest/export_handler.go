@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/middleware"
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportTasks handles GET /api/v1/tasks/export
+// Rows are streamed as they are read, up to dto.MaxExportRows, so the export is never held in memory
+func (h *Handler) ExportTasks(c *gin.Context) {
+	var query dto.ExportTasksQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	filter, err := query.ToRepositoryFilter()
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_filter", err.Error())
+		return
+	}
+
+	// Payloads are opt-in, and never exported to callers without payload access
+	redacted := query.IncludePayload && middleware.PayloadsRedacted(c)
+	includePayload := query.IncludePayload && !redacted
+
+	contentType := "text/csv; charset=utf-8"
+	if query.Format == dto.ExportFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	filename := fmt.Sprintf("tasks-%s.%s", time.Now().UTC().Format("20060102T150405Z"), query.Format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("X-Export-Limit", fmt.Sprint(dto.MaxExportRows))
+	c.Status(http.StatusOK)
+
+	// Output is buffered, so an error before the first flush can still be reported as JSON
+	buf := bufio.NewWriter(c.Writer)
+	var write func(*entity.Task) error
+	flush := buf.Flush
+	switch query.Format {
+	case dto.ExportFormatCSV:
+		w := csv.NewWriter(buf)
+		header := dto.ExportCSVHeader
+		if includePayload {
+			header = append(header[:len(header):len(header)], "payload")
+		}
+		if err := w.Write(header); err != nil {
+			return
+		}
+		write = func(task *entity.Task) error {
+			record := dto.ExportCSVRecord(task)
+			if includePayload {
+				record = append(record, string(task.Payload))
+			}
+			return w.Write(record)
+		}
+		flush = func() error {
+			w.Flush()
+			if err := w.Error(); err != nil {
+				return err
+			}
+			return buf.Flush()
+		}
+	default:
+		enc := json.NewEncoder(buf)
+		write = func(task *entity.Task) error {
+			return enc.Encode(detailedTaskResponse(task, includePayload, redacted))
+		}
+	}
+
+	rows := 0
+	err = h.taskService.ListStream(c.Request.Context(), filter, func(task *entity.Task) error {
+		rows++
+		return write(task)
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		logger.Error("Failed to export tasks",
+			logger.String("handler", "ExportTasks"),
+			logger.Int("rows", rows),
+			logger.Any("error", err),
+		)
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			c.Writer.Header().Del("Content-Type")
+			response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to export tasks")
+		}
+		return
+	}
+
+	logger.Info("Exported tasks",
+		logger.String("handler", "ExportTasks"),
+		logger.String("format", query.Format),
+		logger.Int("rows", rows),
+	)
+}
+
+// detailedTaskResponse converts a task to a response carrying all its fields, as in NDJSON exports
+func detailedTaskResponse(task *entity.Task, includePayload, redacted bool) *dto.TaskResponse {
+	var payloadStr string
+	if includePayload && len(task.Payload) > 0 && json.Valid(task.Payload) {
+		payloadStr = string(task.Payload)
+	}
+	resp := &dto.TaskResponse{
+		ID:                 task.ID,
+		Name:               task.Name,
+		Payload:            payloadStr,
+		PayloadRedacted:    redacted,
+		CallbackURL:        task.CallbackURL,
+		Status:             task.Status,
+		CreatedAt:          task.CreatedAt,
+		ScheduledFor:       task.ScheduledAt,
+		StartedAt:          task.StartedAt,
+		CompletedAt:        task.CompletedAt,
+		ExpiresAt:          task.ExpiresAt,
+		DispatchLatencyMs:  task.DispatchLatencyMs,
+		CallbackDurationMs: task.CallbackDurationMs,
+		MaxRetries:         task.MaxRetries,
+		RetryCount:         task.RetryCount,
+		CallbackAttempts:   task.CallbackAttempts,
+		Priority:           task.Priority,
+		Tags:               task.Tags,
+		ConcurrencyKey:     task.ConcurrencyKey,
+		RequestID:          task.RequestID,
+		TenantID:           task.TenantID,
+		ErrorMessage:       task.ErrorMessage,
+	}
+	// The policy only means something with a parent, and re-importing it without one is rejected
+	if task.DependsOn != nil {
+		resp.DependsOn = task.DependsOn
+		resp.DependencyFailurePolicy = task.DependencyFailurePolicy
+	}
+	return resp
+}
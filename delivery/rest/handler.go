@@ -1,15 +1,20 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 
 	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/middleware"
 	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/delivery/websocket"
 	"github.com/usual2970/later/domain"
 	"github.com/usual2970/later/domain/entity"
 	"github.com/usual2970/later/infrastructure/logger"
+	"github.com/usual2970/later/infrastructure/worker"
 	tasksvc "github.com/usual2970/later/task"
 
 	"github.com/gin-gonic/gin"
@@ -19,80 +24,113 @@ import (
 type Handler struct {
 	taskService *tasksvc.Service
 	scheduler   *tasksvc.Scheduler
+	executor    *worker.Executor
+	hub         *websocket.Hub
 }
 
 // NewHandler creates a new HTTP handler
-func NewHandler(taskService *tasksvc.Service, scheduler *tasksvc.Scheduler) *Handler {
+func NewHandler(taskService *tasksvc.Service, scheduler *tasksvc.Scheduler, executor *worker.Executor, hub *websocket.Hub) *Handler {
 	return &Handler{
 		taskService: taskService,
 		scheduler:   scheduler,
+		executor:    executor,
+		hub:         hub,
+	}
+}
+
+// broadcast publishes a task event to WebSocket subscribers if a hub is configured
+func (h *Handler) broadcast(eventType string, task *entity.Task) {
+	if h.hub != nil {
+		h.hub.Broadcast(websocket.NewTaskEvent(eventType, task))
 	}
 }
 
 // CreateTask handles POST /api/v1/tasks
 func (h *Handler) CreateTask(c *gin.Context) {
-	var req dto.CreateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+	task, ok := h.bindNewTask(c)
+	if !ok {
 		return
 	}
 
-	// Validate request
-	if err := req.Validate(); err != nil {
-		response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error", err.Error())
+	// Save to database
+	ctx := c.Request.Context()
+	if err := h.taskService.CreateTask(ctx, task); err != nil {
+		respondCreateError(c, err, task, "Failed to create task")
 		return
 	}
+	h.broadcast(websocket.EventTaskCreated, task)
+	logger.Info("Task created",
+		logger.String("handler", "CreateTask"),
+		logger.String("task_id", task.ID),
+		logger.RequestID(task.RequestID),
+	)
 
-	// Convert to domain model
-	task := req.ToModel()
+	// Submit now if due, or hold in the delay queue if due shortly
+	dispatch := h.scheduler.ScheduleTask(task)
 
-	// Save to database
-	ctx := c.Request.Context()
-	if err := h.taskService.CreateTask(ctx, task); err != nil {
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to create task")
+	response.Accepted(c, dto.NewCreatedTaskResponse(task, dispatch))
+}
+
+// ValidateTask handles POST /api/v1/tasks/validate
+// It runs a create request through the same checks as CreateTask and returns the task it would
+// make, defaults applied, without storing or scheduling it
+func (h *Handler) ValidateTask(c *gin.Context) {
+	task, ok := h.bindNewTask(c)
+	if !ok {
 		return
 	}
 
-	// If immediate execution, submit directly to worker pool
-	if task.ShouldExecuteNow() {
-		h.scheduler.SubmitTaskImmediately(task)
+	if err := h.taskService.ValidateTask(c.Request.Context(), task); err != nil {
+		respondCreateError(c, err, task, "Failed to validate task")
+		return
 	}
 
-	// Build response
-	estimatedExec := "scheduled"
-	if task.ShouldExecuteNow() {
-		estimatedExec = "immediate"
+	response.Success(c, dto.NewValidatedTaskResponse(task, h.scheduler.EstimateDispatch(task)))
+}
+
+// bindNewTask binds and validates a create request and converts it to a task, responding with
+// the error if the request is invalid
+func (h *Handler) bindNewTask(c *gin.Context) (*entity.Task, bool) {
+	var req dto.CreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return nil, false
 	}
 
-	// Convert JSONBytes to json.RawMessage for response
-	// Convert JSONBytes to string for JSON response
-	var payloadStr string
-	if len(task.Payload) > 0 && json.Valid(task.Payload) {
-		payloadStr = string(task.Payload)
+	// Validate request
+	if err := req.Validate(); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error", err.Error())
+		return nil, false
 	}
 
-	taskResponse := dto.TaskResponse{
-		ID:                 task.ID,
-		Name:               task.Name,
-		Payload:            payloadStr,
-		CallbackURL:        task.CallbackURL,
-		Status:             task.Status,
-		CreatedAt:          task.CreatedAt,
-		ScheduledFor:       task.ScheduledAt,
-		MaxRetries:         task.MaxRetries,
-		RetryCount:         task.RetryCount,
-		CallbackAttempts:   task.CallbackAttempts,
-		Priority:           task.Priority,
-		Tags:               task.Tags,
-		EstimatedExecution: estimatedExec,
+	if req.ID != "" {
+		if err := h.taskService.ValidateID(req.ID); err != nil {
+			response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error", err.Error())
+			return nil, false
+		}
 	}
 
-	response.Accepted(c, taskResponse)
+	// Convert to domain model
+	return req.ToModel(), true
+}
+
+// respondCreateError responds to a task that failed the service's create checks
+func respondCreateError(c *gin.Context, err error, task *entity.Task, message string) {
+	if errors.Is(err, domain.ErrConflict) {
+		response.Conflict(c, "A task with this ID already exists", task.ID)
+		return
+	}
+	response.DomainError(c, err, message)
 }
 
 // ListTasks handles GET /api/v1/tasks
 func (h *Handler) ListTasks(c *gin.Context) {
-	var query dto.ListTasksQuery
+	h.ListTasksFrom(c, dto.ListTasksQuery{})
+}
+
+// ListTasksFrom lists tasks like ListTasks, binding the request's query over the given one, so
+// that fields set there are defaults: a query with Page and Limit set makes them optional
+func (h *Handler) ListTasksFrom(c *gin.Context, query dto.ListTasksQuery) {
 	if err := c.ShouldBindQuery(&query); err != nil {
 		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_query", err.Error())
 		return
@@ -111,7 +149,7 @@ func (h *Handler) ListTasks(c *gin.Context) {
 		return
 	}
 
-	logger.Info("Fetching tasks with filter",
+	logger.Debug("Fetching tasks with filter",
 		logger.Int("page", query.Page),
 		logger.Int("limit", query.Limit),
 		logger.Any("status", query.Status),
@@ -131,65 +169,66 @@ func (h *Handler) ListTasks(c *gin.Context) {
 		return
 	}
 
-	logger.Info("Successfully fetched tasks",
+	logger.Debug("Successfully fetched tasks",
 		logger.String("handler", "ListTasks"),
 		logger.Int("count", len(tasks)),
 		logger.Int64("total", total),
 	)
 
-	// Convert to response format
+	response.Success(c, taskListResponse(c, tasks, total, query.Page, query.Limit))
+}
+
+// taskListResponse converts a page of tasks to the list response, redacting payloads if required
+func taskListResponse(c *gin.Context, tasks []*entity.Task, total int64, page, limit int) dto.TaskListResponse {
+	redacted := middleware.PayloadsRedacted(c)
 	taskResponses := make([]*dto.TaskResponse, len(tasks))
 	for i, task := range tasks {
 		// Convert JSONBytes to string for JSON response
 		// Don't use json.RawMessage as it can have invalid characters causing marshal errors
 		var payloadStr string
-		if len(task.Payload) > 0 && json.Valid(task.Payload) {
+		if !redacted && len(task.Payload) > 0 && json.Valid(task.Payload) {
 			payloadStr = string(task.Payload)
 		}
 
 		taskResponses[i] = &dto.TaskResponse{
-			ID:               task.ID,
-			Name:             task.Name,
-			Payload:          payloadStr,
-			CallbackURL:      task.CallbackURL,
-			Status:           task.Status,
-			CreatedAt:        task.CreatedAt,
-			ScheduledFor:     task.ScheduledAt,
-			StartedAt:        task.StartedAt,
-			CompletedAt:      task.CompletedAt,
-			MaxRetries:       task.MaxRetries,
-			RetryCount:       task.RetryCount,
-			CallbackAttempts: task.CallbackAttempts,
-			Priority:         task.Priority,
-			Tags:             task.Tags,
-			ErrorMessage:     task.ErrorMessage,
+			ID:                 task.ID,
+			Name:               task.Name,
+			Payload:            payloadStr,
+			PayloadRedacted:    redacted,
+			CallbackURL:        task.CallbackURL,
+			Status:             task.Status,
+			CreatedAt:          task.CreatedAt,
+			ScheduledFor:       task.ScheduledAt,
+			StartedAt:          task.StartedAt,
+			CompletedAt:        task.CompletedAt,
+			ExpiresAt:          task.ExpiresAt,
+			DispatchLatencyMs:  task.DispatchLatencyMs,
+			CallbackDurationMs: task.CallbackDurationMs,
+			MaxRetries:         task.MaxRetries,
+			RetryCount:         task.RetryCount,
+			CallbackAttempts:   task.CallbackAttempts,
+			Priority:           task.Priority,
+			Tags:               task.Tags,
+			TenantID:           task.TenantID,
+			ErrorMessage:       task.ErrorMessage,
 		}
 	}
 
 	// Calculate pagination
-	totalPages := int(total) / query.Limit
-	if int(total)%query.Limit != 0 {
+	totalPages := int(total) / limit
+	if int(total)%limit != 0 {
 		totalPages++
 	}
 
-	listResponse := dto.TaskListResponse{
+	return dto.TaskListResponse{
 		Tasks: taskResponses,
 		Pagination: dto.PaginationInfo{
-			Page:       query.Page,
-			Limit:      query.Limit,
+			Page:       page,
+			Limit:      limit,
 			Total:      total,
 			TotalPages: totalPages,
 		},
 	}
-
-	logger.Debug("Sending response",
-		logger.String("handler", "ListTasks"),
-		logger.Int("task_count", len(listResponse.Tasks)),
-		logger.Int("page", listResponse.Pagination.Page),
-		logger.Int64("total", listResponse.Pagination.Total),
-	)
-
-	response.Success(c, listResponse)
 }
 
 // GetTask handles GET /api/v1/tasks/:id
@@ -199,37 +238,55 @@ func (h *Handler) GetTask(c *gin.Context) {
 	ctx := c.Request.Context()
 	task, err := h.taskService.GetTask(ctx, id)
 	if err != nil {
-		if errors.Is(err, domain.ErrNotFound) {
-			response.ErrorWithMessage(c, http.StatusNotFound, "task_not_found", "Task not found")
-			return
-		}
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to get task")
+		response.DomainError(c, err, "Failed to get task")
 		return
 	}
 
-	// Convert JSONBytes to json.RawMessage for response
 	// Convert JSONBytes to string for JSON response
+	redacted := middleware.PayloadsRedacted(c)
 	var payloadStr string
-	if len(task.Payload) > 0 && json.Valid(task.Payload) {
+	if !redacted && len(task.Payload) > 0 && json.Valid(task.Payload) {
 		payloadStr = string(task.Payload)
 	}
 
 	taskResponse := dto.TaskResponse{
-		ID:               task.ID,
-		Name:             task.Name,
-		Payload:          payloadStr,
-		CallbackURL:      task.CallbackURL,
-		Status:           task.Status,
-		CreatedAt:        task.CreatedAt,
-		ScheduledFor:     task.ScheduledAt,
-		StartedAt:        task.StartedAt,
-		CompletedAt:      task.CompletedAt,
-		MaxRetries:       task.MaxRetries,
-		RetryCount:       task.RetryCount,
-		CallbackAttempts: task.CallbackAttempts,
-		Priority:         task.Priority,
-		Tags:             task.Tags,
-		ErrorMessage:     task.ErrorMessage,
+		ID:                   task.ID,
+		Name:                 task.Name,
+		Payload:              payloadStr,
+		PayloadRedacted:      redacted,
+		CallbackURL:          task.CallbackURL,
+		Status:               task.Status,
+		CreatedAt:            task.CreatedAt,
+		ScheduledFor:         task.ScheduledAt,
+		StartedAt:            task.StartedAt,
+		CompletedAt:          task.CompletedAt,
+		ExpiresAt:            task.ExpiresAt,
+		DispatchLatencyMs:    task.DispatchLatencyMs,
+		CallbackDurationMs:   task.CallbackDurationMs,
+		MaxRetries:           task.MaxRetries,
+		RetryCount:           task.RetryCount,
+		CallbackAttempts:     task.CallbackAttempts,
+		Priority:             task.Priority,
+		Tags:                 task.Tags,
+		ConcurrencyKey:       task.ConcurrencyKey,
+		RequestID:            task.RequestID,
+		TenantID:             task.TenantID,
+		ErrorMessage:         task.ErrorMessage,
+		ErrorHistory:         task.ErrorHistory,
+		LastCallbackResponse: task.LastCallbackResponse,
+	}
+	if task.DependsOn != nil {
+		taskResponse.DependsOn = task.DependsOn
+		taskResponse.DependencyFailurePolicy = task.DependencyFailurePolicy
+	}
+
+	children, err := h.taskService.GetChildren(ctx, task.ID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to get task children")
+		return
+	}
+	if len(children) > 0 {
+		taskResponse.Children = dto.NewChildTasks(children)
 	}
 
 	response.Success(c, taskResponse)
@@ -242,11 +299,7 @@ func (h *Handler) CancelTask(c *gin.Context) {
 	ctx := c.Request.Context()
 	task, err := h.taskService.GetTask(ctx, id)
 	if err != nil {
-		if errors.Is(err, domain.ErrNotFound) {
-			response.ErrorWithMessage(c, http.StatusNotFound, "task_not_found", "Task not found")
-			return
-		}
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to get task")
+		response.DomainError(c, err, "Failed to get task")
 		return
 	}
 
@@ -268,64 +321,55 @@ func (h *Handler) CancelTask(c *gin.Context) {
 		logger.Error("Failed to delete task",
 			logger.String("handler", "CancelTask"),
 			logger.String("task_id", id),
+			logger.RequestID(task.RequestID),
 			logger.Any("error", err),
 		)
-		if err.Error() == "task cannot be deleted: invalid status or already deleted" {
-			response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_status", err.Error())
-			return
-		}
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to delete task")
+		response.DomainError(c, err, "Failed to delete task")
 		return
 	}
+	h.scheduler.ForgetTask(id)
 
 	response.NoContent(c)
 }
 
 // RetryTask handles POST /api/v1/tasks/:id/retry
+// Failed and dead-lettered tasks are reset to pending; the optional body can delay the restart
+// with scheduled_for and replace the retry budget with max_retries
 func (h *Handler) RetryTask(c *gin.Context) {
 	id := c.Param("id")
 
-	ctx := c.Request.Context()
-	task, err := h.taskService.GetTask(ctx, id)
-	if err != nil {
-		if errors.Is(err, domain.ErrNotFound) {
-			response.ErrorWithMessage(c, http.StatusNotFound, "task_not_found", "Task not found")
-			return
-		}
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to get task")
+	// The body is optional, so an empty one retries with the task's own settings
+	var req dto.RetryTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
 		return
 	}
-
-	// Can only retry failed tasks
-	if task.Status != entity.TaskStatusFailed {
-		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_status", "Can only retry failed tasks")
+	if err := req.Validate(); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error", err.Error())
 		return
 	}
 
-	// Reset task for retry
-	task.Status = entity.TaskStatusPending
-	task.RetryCount = 0
-	task.NextRetryAt = nil
-
-	if err := h.taskService.UpdateTask(ctx, task); err != nil {
-		logger.Error("Failed to retry task",
-			logger.String("handler", "RetryTask"),
-			logger.String("task_id", id),
-			logger.Any("error", err),
-		)
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to retry task")
+	task, err := h.taskService.RetryTask(c.Request.Context(), id, req.ToOptions())
+	if err != nil {
+		if status, _ := response.StatusFor(err); status == http.StatusInternalServerError {
+			logger.Error("Failed to retry task",
+				logger.String("handler", "RetryTask"),
+				logger.String("task_id", id),
+				logger.Any("error", err),
+			)
+		}
+		response.DomainError(c, err, "Failed to retry task")
 		return
 	}
+	h.broadcast(websocket.EventTaskUpdated, task)
 
-	// If immediate execution, submit to worker pool
-	if task.ShouldExecuteNow() {
-		h.scheduler.SubmitTaskImmediately(task)
-	}
+	// Submit now if due, or hold in the delay queue if due shortly
+	dispatch := h.scheduler.ScheduleTask(task)
 
-	// Convert JSONBytes to json.RawMessage for response
 	// Convert JSONBytes to string for JSON response
+	redacted := middleware.PayloadsRedacted(c)
 	var payloadStr string
-	if len(task.Payload) > 0 && json.Valid(task.Payload) {
+	if !redacted && len(task.Payload) > 0 && json.Valid(task.Payload) {
 		payloadStr = string(task.Payload)
 	}
 
@@ -333,6 +377,7 @@ func (h *Handler) RetryTask(c *gin.Context) {
 		ID:                 task.ID,
 		Name:               task.Name,
 		Payload:            payloadStr,
+		PayloadRedacted:    redacted,
 		CallbackURL:        task.CallbackURL,
 		Status:             task.Status,
 		CreatedAt:          task.CreatedAt,
@@ -342,17 +387,24 @@ func (h *Handler) RetryTask(c *gin.Context) {
 		CallbackAttempts:   task.CallbackAttempts,
 		Priority:           task.Priority,
 		Tags:               task.Tags,
-		EstimatedExecution: "immediate",
+		TenantID:           task.TenantID,
+		EstimatedExecution: string(dispatch),
 	}
 
 	response.Accepted(c, taskResp)
 }
 
 // GetStats handles GET /api/v1/tasks/stats
+// The optional window query parameter (1h, 24h, 7d) selects the activity window
 func (h *Handler) GetStats(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	stats, err := h.taskService.GetStats(ctx)
+	window := c.DefaultQuery("window", tasksvc.DefaultStatsWindow)
+	stats, err := h.taskService.GetStatsForWindow(ctx, window)
+	if errors.Is(err, domain.ErrBadParamInput) {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_query", "window must be one of 1h, 24h, 7d")
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to get statistics",
 			logger.String("handler", "GetStats"),
@@ -362,91 +414,126 @@ func (h *Handler) GetStats(c *gin.Context) {
 		return
 	}
 
-	// Convert tasksvc.Last24hStats to dto.Last24hStats
-	last24h := dto.Last24hStats{
-		Submitted: stats.Last24h.Submitted,
-		Completed: stats.Last24h.Completed,
-		Failed:    stats.Last24h.Failed,
-	}
-
 	statsResponse := dto.StatsResponse{
 		Total:               stats.Total,
 		ByStatus:            stats.ByStatus,
-		Last24h:             last24h,
+		Last24h:             toWindowStatsDTO(stats.Last24h),
+		Window:              stats.Window,
+		Recent:              toWindowStatsDTO(stats.Recent),
 		CallbackSuccessRate: stats.CallbackSuccessRate,
 	}
 
 	response.Success(c, statsResponse)
 }
 
-// ResurrectTask handles POST /api/v1/tasks/:id/resurrect
-func (h *Handler) ResurrectTask(c *gin.Context) {
-	id := c.Param("id")
+// GetStatsTimeSeries handles GET /api/v1/tasks/stats/timeseries
+// Query parameters: window (1h, 24h, 7d; default 24h) and bucket (Go duration; default 1h)
+func (h *Handler) GetStatsTimeSeries(c *gin.Context) {
+	window := c.DefaultQuery("window", tasksvc.DefaultStatsWindow)
+	bucket := c.DefaultQuery("bucket", "1h")
 
-	ctx := c.Request.Context()
-	task, err := h.taskService.GetTask(ctx, id)
+	series, err := h.taskService.GetTimeSeries(c.Request.Context(), window, bucket)
+	if errors.Is(err, domain.ErrBadParamInput) {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
 	if err != nil {
-		if errors.Is(err, domain.ErrNotFound) {
-			response.ErrorWithMessage(c, http.StatusNotFound, "task_not_found", "Task not found")
-			return
-		}
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to get task")
+		logger.Error("Failed to get time series",
+			logger.String("handler", "GetStatsTimeSeries"),
+			logger.Any("error", err),
+		)
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to get time series")
 		return
 	}
 
-	// Can only resurrect dead_lettered tasks
-	if task.Status != entity.TaskStatusDeadLettered {
-		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_status", "Can only resurrect dead_lettered tasks")
-		return
+	buckets := make([]dto.TimeSeriesBucket, len(series.Buckets))
+	for i, point := range series.Buckets {
+		buckets[i] = dto.TimeSeriesBucket{
+			Start:                point.Start,
+			Created:              point.Created,
+			Completed:            point.Completed,
+			Failed:               point.Failed,
+			DeadLettered:         point.DeadLettered,
+			AvgCallbackLatencyMs: point.AvgCallbackLatencyMs,
+		}
 	}
 
-	// Reset task for resurrection
-	task.Status = entity.TaskStatusPending
-	task.RetryCount = 0
-	task.NextRetryAt = nil
-	task.ErrorMessage = nil
-	task.StartedAt = nil
-	task.CompletedAt = nil
+	response.Success(c, dto.TimeSeriesResponse{
+		Window:  series.Window,
+		Bucket:  series.Bucket,
+		Buckets: buckets,
+	})
+}
 
-	if err := h.taskService.UpdateTask(ctx, task); err != nil {
-		logger.Error("Failed to resurrect task",
-			logger.String("handler", "ResurrectTask"),
-			logger.String("task_id", id),
+// GetStatsByName handles GET /api/v1/tasks/stats/by-name
+// The optional window query parameter (1h, 24h, 7d) selects the tasks created within it
+func (h *Handler) GetStatsByName(c *gin.Context) {
+	h.getGroupedStats(c, "GetStatsByName", h.taskService.GetStatsByName)
+}
+
+// GetStatsByTag handles GET /api/v1/tasks/stats/by-tag
+// The optional window query parameter (1h, 24h, 7d) selects the tasks created within it
+func (h *Handler) GetStatsByTag(c *gin.Context) {
+	h.getGroupedStats(c, "GetStatsByTag", h.taskService.GetStatsByTag)
+}
+
+// getGroupedStats responds with the grouped statistics get returns for the requested window
+func (h *Handler) getGroupedStats(c *gin.Context, handler string,
+	get func(ctx context.Context, window string) (*tasksvc.GroupedStats, error)) {
+	window := c.DefaultQuery("window", tasksvc.DefaultStatsWindow)
+	stats, err := get(c.Request.Context(), window)
+	if errors.Is(err, domain.ErrBadParamInput) {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_query", "window must be one of 1h, 24h, 7d")
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get grouped statistics",
+			logger.String("handler", handler),
 			logger.Any("error", err),
 		)
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to resurrect task")
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to get statistics")
 		return
 	}
 
-	// If immediate execution, submit to worker pool
-	if task.ShouldExecuteNow() {
-		h.scheduler.SubmitTaskImmediately(task)
+	groups := make([]dto.GroupStats, len(stats.Groups))
+	for i, group := range stats.Groups {
+		groups[i] = dto.GroupStats{
+			Key:                 group.Key,
+			Total:               group.Total,
+			ByStatus:            group.ByStatus,
+			AvgCallbackAttempts: group.AvgCallbackAttempts,
+			SuccessRate:         group.SuccessRate,
+		}
 	}
 
-	// Convert JSONBytes to json.RawMessage for response
-	// Convert JSONBytes to string for JSON response
-	var payloadStr string
-	if len(task.Payload) > 0 && json.Valid(task.Payload) {
-		payloadStr = string(task.Payload)
-	}
+	response.Success(c, dto.GroupedStatsResponse{
+		Window:    stats.Window,
+		GroupBy:   stats.GroupBy,
+		Groups:    groups,
+		Truncated: stats.Truncated,
+	})
+}
 
-	taskResp := dto.TaskResponse{
-		ID:                 task.ID,
-		Name:               task.Name,
-		Payload:            payloadStr,
-		CallbackURL:        task.CallbackURL,
-		Status:             task.Status,
-		CreatedAt:          task.CreatedAt,
-		ScheduledFor:       task.ScheduledAt,
-		MaxRetries:         task.MaxRetries,
-		RetryCount:         task.RetryCount,
-		CallbackAttempts:   task.CallbackAttempts,
-		Priority:           task.Priority,
-		Tags:               task.Tags,
-		EstimatedExecution: "immediate",
+// toWindowStatsDTO converts tasksvc.WindowStats to dto.Last24hStats
+func toWindowStatsDTO(stats tasksvc.WindowStats) dto.Last24hStats {
+	return dto.Last24hStats{
+		Submitted:              stats.Submitted,
+		Completed:              stats.Completed,
+		Failed:                 stats.Failed,
+		DeadLettered:           stats.DeadLettered,
+		Expired:                stats.Expired,
+		AvgCallbackAttempts:    stats.AvgCallbackAttempts,
+		P50CompletionLatencyMs: stats.P50CompletionLatencyMs,
+		P95CompletionLatencyMs: stats.P95CompletionLatencyMs,
+		P50DispatchLatencyMs:   stats.P50DispatchLatencyMs,
+		P95DispatchLatencyMs:   stats.P95DispatchLatencyMs,
+		P50CallbackDurationMs:  stats.P50CallbackDurationMs,
+		P95CallbackDurationMs:  stats.P95CallbackDurationMs,
 	}
-
-	response.Accepted(c, taskResp)
 }
 
-// getStatusCode maps domain errors to HTTP status codes
+// ResurrectTask handles POST /api/v1/tasks/:id/resurrect
+// It is kept as an alias of RetryTask, which accepts dead-lettered tasks too
+func (h *Handler) ResurrectTask(c *gin.Context) {
+	h.RetryTask(c)
+}
@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PauseHost handles POST /api/v1/admin/hosts/:host/pause
+// Tasks whose callback URL is on the host are held, their scheduled time moved to the end of
+// the pause; without until the pause lasts until the host is resumed
+func (h *Handler) PauseHost(c *gin.Context) {
+	var req dto.PauseHostRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	pause, err := h.taskService.PauseHost(c.Request.Context(), c.Param("host"), req.UntilTime())
+	if err != nil {
+		if status, _ := response.StatusFor(err); status == http.StatusInternalServerError {
+			logger.Error("Failed to pause host",
+				logger.String("handler", "PauseHost"),
+				logger.String("host", c.Param("host")),
+				logger.Any("error", err),
+			)
+		}
+		response.DomainError(c, err, "Failed to pause host")
+		return
+	}
+	// Warn, so incident actions stand out in the logs
+	logger.Warn("Callback host paused",
+		logger.String("host", pause.Host),
+		logger.Any("until", pause.Until),
+	)
+
+	response.Success(c, dto.HostPauseResponse{Host: pause.Host, Until: pause.Until, CreatedAt: pause.CreatedAt})
+}
+
+// ResumeHost handles DELETE /api/v1/admin/hosts/:host/pause
+// The host's held tasks run once their scheduled time comes
+func (h *Handler) ResumeHost(c *gin.Context) {
+	err := h.taskService.ResumeHost(c.Request.Context(), c.Param("host"))
+	if errors.Is(err, domain.ErrNotFound) {
+		response.ErrorWithMessage(c, http.StatusNotFound, "host_not_paused", "Host is not paused")
+		return
+	}
+	if err != nil {
+		if status, _ := response.StatusFor(err); status == http.StatusInternalServerError {
+			logger.Error("Failed to resume host",
+				logger.String("handler", "ResumeHost"),
+				logger.String("host", c.Param("host")),
+				logger.Any("error", err),
+			)
+		}
+		response.DomainError(c, err, "Failed to resume host")
+		return
+	}
+	logger.Warn("Callback host resumed", logger.String("host", c.Param("host")))
+
+	response.NoContent(c)
+}
+
+// ListPausedHosts handles GET /api/v1/admin/hosts
+// It lists the hosts paused now with the count of tasks each pause holds
+func (h *Handler) ListPausedHosts(c *gin.Context) {
+	paused, err := h.taskService.PausedHosts(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to list paused hosts",
+			logger.String("handler", "ListPausedHosts"),
+			logger.Any("error", err),
+		)
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to list paused hosts")
+		return
+	}
+
+	response.Success(c, gin.H{"hosts": dto.NewHostPauseResponses(paused)})
+}
@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/logger"
+	tasksvc "github.com/usual2970/later/task"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxImportLineSize bounds one NDJSON line, leaving room for an escaped payload at the default limit
+const maxImportLineSize = 4 * entity.MaxPayloadSize
+
+// ImportTasks handles POST /api/v1/tasks/import
+// The body is NDJSON, one task per line in the shape GET /api/v1/tasks/export writes; every
+// line is reported on, so a bad line doesn't stop the rest from being imported
+func (h *Handler) ImportTasks(c *gin.Context) {
+	var query dto.ImportTasksQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	var (
+		report dto.ImportTasksResponse
+		tasks  []*entity.Task
+		lines  []int // Source line of each task
+	)
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(tasks)+len(report.Results) == tasksvc.MaxImportTasks {
+			response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error",
+				fmt.Sprintf("at most %d tasks can be imported at once", tasksvc.MaxImportTasks))
+			return
+		}
+
+		var req dto.ImportTaskRequest
+		err := json.Unmarshal(text, &req)
+		if err == nil {
+			err = binding.Validator.ValidateStruct(&req)
+		}
+		if err == nil {
+			err = req.Validate()
+		}
+		if err != nil {
+			report.Results = append(report.Results, dto.ImportLineResult{Line: line, Error: err.Error()})
+			continue
+		}
+		tasks = append(tasks, req.ToModel())
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if len(tasks)+len(report.Results) == 0 {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", "import contains no tasks")
+		return
+	}
+
+	results, err := h.taskService.ImportTasks(c.Request.Context(), tasks, query.OnDuplicate == dto.ImportRemapDuplicates)
+	if err != nil {
+		if errors.Is(err, domain.ErrBadParamInput) {
+			response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
+		logger.Error("Failed to import tasks",
+			logger.String("handler", "ImportTasks"),
+			logger.Any("error", err),
+		)
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to import tasks")
+		return
+	}
+
+	report.Failed = len(report.Results)
+	for i, result := range results {
+		lineResult := dto.ImportLineResult{Line: lines[i], ID: result.ID, OriginalID: result.OriginalID}
+		if result.Err != nil {
+			lineResult.Error = result.Err.Error()
+			report.Failed++
+		} else {
+			report.Imported++
+		}
+		report.Results = append(report.Results, lineResult)
+	}
+	sort.Slice(report.Results, func(i, j int) bool { return report.Results[i].Line < report.Results[j].Line })
+
+	logger.Info("Imported tasks",
+		logger.String("handler", "ImportTasks"),
+		logger.Int("imported", report.Imported),
+		logger.Int("failed", report.Failed),
+	)
+	response.Success(c, report)
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminOnly is a middleware that restricts routes changing server-wide settings
+// It must run after APIKeyAuth and TenantScope. Admin keys are always allowed; when none are
+// configured, any request not scoped to a tenant is, so single-tenant deployments keep access
+func AdminOnly(adminKeys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetString(ContextKeyAPIKey)
+		if key != "" && validAPIKey(adminKeys, key) {
+			c.Next()
+			return
+		}
+
+		_, scoped := domain.TenantFromContext(c.Request.Context())
+		if len(adminKeys) > 0 || scoped {
+			response.ErrorWithMessage(c, http.StatusForbidden, "forbidden", "Admin API key required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		adminKeys  []string
+		keyTenants map[string]string
+		key        string
+		expectCode int
+	}{
+		{"Admin key is allowed", []string{"admin-key"}, nil, "admin-key", http.StatusOK},
+		{"Other key is forbidden when admin keys are set", []string{"admin-key"}, nil, "plain-key", http.StatusForbidden},
+		{"Any key is allowed without admin keys", nil, nil, "plain-key", http.StatusOK},
+		{"Tenant key is forbidden without admin keys", nil, map[string]string{"plain-key": "acme"}, "plain-key", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.PUT("/admin",
+				APIKeyAuth([]string{"admin-key", "plain-key"}),
+				TenantScope(TenantConfig{KeyTenants: tt.keyTenants, AdminKeys: tt.adminKeys}),
+				AdminOnly(tt.adminKeys),
+				func(c *gin.Context) { c.Status(http.StatusOK) },
+			)
+
+			req, _ := http.NewRequest("PUT", "/admin", nil)
+			req.Header.Set("X-API-Key", tt.key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+		})
+	}
+}
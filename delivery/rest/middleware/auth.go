@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/usual2970/later/delivery/rest/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyAPIKey is the gin context key holding the authenticated API key
+const ContextKeyAPIKey = "api_key"
+
+// APIKeyQueryParam is accepted for clients that cannot set headers (e.g. browser WebSockets)
+const APIKeyQueryParam = "api_key"
+
+// APIKeySubprotocolPrefix marks the API key among a WebSocket's offered subprotocols, for
+// browsers that would rather not put it in the URL: Sec-WebSocket-Protocol: api-key.<key>
+const APIKeySubprotocolPrefix = "api-key."
+
+// APIKeyAuth is a middleware that requires one of the given API keys
+// The key is read from "Authorization: Bearer <key>", "X-API-Key", the api_key query parameter,
+// or an api-key.<key> WebSocket subprotocol
+// If keys is empty, all requests are allowed
+func APIKeyAuth(keys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(keys) == 0 {
+			c.Next()
+			return
+		}
+
+		provided := extractAPIKey(c)
+		if provided == "" {
+			response.ErrorWithMessage(c, http.StatusUnauthorized, "unauthorized", "API key required")
+			c.Abort()
+			return
+		}
+
+		if !validAPIKey(keys, provided) {
+			response.ErrorWithMessage(c, http.StatusUnauthorized, "unauthorized", "Invalid API key")
+			c.Abort()
+			return
+		}
+
+		c.Set(ContextKeyAPIKey, provided)
+		c.Next()
+	}
+}
+
+// extractAPIKey returns the API key supplied with the request, if any
+func extractAPIKey(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+
+	if key := c.Query(APIKeyQueryParam); key != "" {
+		return key
+	}
+
+	for _, header := range c.Request.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if key, ok := strings.CutPrefix(strings.TrimSpace(protocol), APIKeySubprotocolPrefix); ok {
+				return key
+			}
+		}
+	}
+	return ""
+}
+
+// validAPIKey compares the provided key against every configured key in constant time
+func validAPIKey(keys []string, provided string) bool {
+	match := 0
+	for _, key := range keys {
+		match |= subtle.ConstantTimeCompare([]byte(key), []byte(provided))
+	}
+	return match == 1
+}
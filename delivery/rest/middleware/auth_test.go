@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		keys       []string
+		path       string
+		headers    map[string]string
+		expectCode int
+	}{
+		{"No keys configured allows all", nil, "/tasks", nil, http.StatusOK},
+		{"Missing key", []string{"secret"}, "/tasks", nil, http.StatusUnauthorized},
+		{"Bearer token", []string{"secret"}, "/tasks", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+		{"X-API-Key header", []string{"other", "secret"}, "/tasks", map[string]string{"X-API-Key": "secret"}, http.StatusOK},
+		{"Query parameter", []string{"secret"}, "/tasks?api_key=secret", nil, http.StatusOK},
+		{"WebSocket subprotocol", []string{"secret"}, "/tasks", map[string]string{"Sec-WebSocket-Protocol": "later, api-key.secret"}, http.StatusOK},
+		{"WebSocket subprotocol without key", []string{"secret"}, "/tasks", map[string]string{"Sec-WebSocket-Protocol": "later"}, http.StatusUnauthorized},
+		{"Wrong key", []string{"secret"}, "/tasks", map[string]string{"X-API-Key": "secret2"}, http.StatusUnauthorized},
+		{"Non-bearer authorization", []string{"secret"}, "/tasks", map[string]string{"Authorization": "Basic secret"}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/tasks", APIKeyAuth(tt.keys), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			if tt.expectCode == http.StatusUnauthorized {
+				var body map[string]string
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, "unauthorized", body["error"])
+				assert.NotEmpty(t, body["message"])
+			}
+		})
+	}
+}
@@ -1,15 +1,61 @@
 package middleware
 
 import (
-	"log"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/usual2970/later/infrastructure/logger"
 )
 
-// Logger is a middleware that logs HTTP requests
+// RequestLogMode selects which HTTP requests are logged
+type RequestLogMode string
+
+const (
+	RequestLogAll    RequestLogMode = "all"    // Every request; fast successful ones at Debug (default)
+	RequestLogErrors RequestLogMode = "errors" // Only error responses and requests with handler errors
+	RequestLogOff    RequestLogMode = "off"    // No request logs
+)
+
+// Valid returns true if the mode is known; empty selects the default
+func (m RequestLogMode) Valid() bool {
+	return m == "" || m == RequestLogAll || m == RequestLogErrors || m == RequestLogOff
+}
+
+// DefaultSlowRequestThreshold is how long a request may take before it is logged at Info
+const DefaultSlowRequestThreshold = time.Second
+
+// RequestLogConfig configures RequestLogger
+type RequestLogConfig struct {
+	Mode RequestLogMode // Empty uses RequestLogAll
+
+	// SlowThreshold raises successful requests taking longer to Info; zero uses
+	// DefaultSlowRequestThreshold and a negative value never raises them
+	SlowThreshold time.Duration
+}
+
+// Logger is a middleware that logs HTTP requests through the global logger with the default config
 func Logger() gin.HandlerFunc {
+	return RequestLogger(logger.Named("http"), RequestLogConfig{})
+}
+
+// RequestLogger is a middleware that logs HTTP requests at a level matching their outcome, so
+// health probes and polling clients don't flood production logs: server errors and handler
+// errors at Warn, client errors and slow requests at Info, everything else at Debug
+func RequestLogger(log *zap.Logger, cfg RequestLogConfig) gin.HandlerFunc {
+	if cfg.SlowThreshold == 0 {
+		cfg.SlowThreshold = DefaultSlowRequestThreshold
+	}
+
 	return func(c *gin.Context) {
+		if cfg.Mode == RequestLogOff {
+			c.Next()
+			return
+		}
+
 		start := time.Now()
 		path := c.Request.URL.Path
 		query := c.Request.URL.RawQuery
@@ -17,20 +63,43 @@ func Logger() gin.HandlerFunc {
 		// Process request
 		c.Next()
 
-		// Log after processing
 		latency := time.Since(start)
 		status := c.Writer.Status()
-		method := c.Request.Method
-		clientIP := c.ClientIP()
-
-		log.Printf("[%s] %s %s %s | status=%d | latency=%v | client=%s",
-			time.Now().Format("2006-01-02 15:04:05"),
-			method,
-			path,
-			query,
-			status,
-			latency,
-			clientIP,
-		)
+		level, ok := requestLogLevel(cfg, status, latency, len(c.Errors) > 0)
+		if !ok {
+			return
+		}
+
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", path),
+			zap.String("query", query),
+			zap.Int("status", status),
+			zap.Duration("latency", latency),
+			zap.String("client_ip", c.ClientIP()),
+			zap.Int("response_size", c.Writer.Size()),
+			zap.String("request_id", c.GetString(ContextKeyRequestID)),
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.Strings("errors", c.Errors.Errors()))
+		}
+		log.Log(level, "HTTP request", fields...)
+	}
+}
+
+// requestLogLevel returns the level a finished request is logged at, or false if it isn't logged
+func requestLogLevel(cfg RequestLogConfig, status int, latency time.Duration, handlerErrors bool) (zapcore.Level, bool) {
+	failed := status >= http.StatusBadRequest || handlerErrors
+	switch {
+	case cfg.Mode == RequestLogOff, cfg.Mode == RequestLogErrors && !failed:
+		return 0, false
+	case status >= http.StatusInternalServerError || handlerErrors:
+		return zapcore.WarnLevel, true
+	case status >= http.StatusBadRequest:
+		return zapcore.InfoLevel, true
+	case cfg.SlowThreshold > 0 && latency > cfg.SlowThreshold:
+		return zapcore.InfoLevel, true
+	default:
+		return zapcore.DebugLevel, true
 	}
 }
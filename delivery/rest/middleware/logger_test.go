@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Each case serves a fast 200, a slow 200, a 404 and a 500, in that order
+	tests := []struct {
+		name   string
+		cfg    RequestLogConfig
+		levels []zapcore.Level
+	}{
+		{"All logs successes at Debug", RequestLogConfig{SlowThreshold: 20 * time.Millisecond},
+			[]zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.InfoLevel, zapcore.WarnLevel}},
+		{"Negative threshold never raises slow requests", RequestLogConfig{SlowThreshold: -1},
+			[]zapcore.Level{zapcore.DebugLevel, zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel}},
+		{"Errors skips successes", RequestLogConfig{Mode: RequestLogErrors, SlowThreshold: 20 * time.Millisecond},
+			[]zapcore.Level{zapcore.InfoLevel, zapcore.WarnLevel}},
+		{"Off logs nothing", RequestLogConfig{Mode: RequestLogOff}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			router := gin.New()
+			router.Use(RequestLogger(zap.New(core), tt.cfg))
+			router.GET("/fast", func(c *gin.Context) { c.Status(http.StatusOK) })
+			router.GET("/slow", func(c *gin.Context) {
+				time.Sleep(30 * time.Millisecond)
+				c.Status(http.StatusOK)
+			})
+			router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+			for _, path := range []string{"/fast", "/slow", "/missing", "/fail"} {
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+			}
+
+			var levels []zapcore.Level
+			for _, entry := range logs.All() {
+				levels = append(levels, entry.Level)
+			}
+			assert.Equal(t, tt.levels, levels)
+		})
+	}
+}
+
+// TestRequestLoggerQuietWhenIdle tests that health probes don't reach Info-level logs
+func TestRequestLoggerQuietWhenIdle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	router := gin.New()
+	router.Use(RequestLogger(zap.New(core), RequestLogConfig{}))
+	router.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for range 100 {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	}
+	assert.Zero(t, logs.Len())
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyRedactPayloads is the gin context key set when task listings must omit payloads
+const ContextKeyRedactPayloads = "redact_payloads"
+
+// PayloadAccess is a middleware that limits which API keys see payloads in task responses
+// It must run after APIKeyAuth. Requests without one of the given keys are marked for
+// redaction; if keys is empty, every request sees payloads
+func PayloadAccess(keys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(keys) > 0 && !validAPIKey(keys, c.GetString(ContextKeyAPIKey)) {
+			c.Set(ContextKeyRedactPayloads, true)
+		}
+		c.Next()
+	}
+}
+
+// PayloadsRedacted returns true if task responses must omit payloads for this request
+func PayloadsRedacted(c *gin.Context) bool {
+	return c.GetBool(ContextKeyRedactPayloads)
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayloadAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		payloadKeys  []string
+		key          string
+		expectRedact bool
+	}{
+		{"No payload keys shows payloads", nil, "plain-key", false},
+		{"Payload key sees payloads", []string{"audit-key"}, "audit-key", false},
+		{"Other key is redacted", []string{"audit-key"}, "plain-key", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var redacted bool
+
+			router := gin.New()
+			router.GET("/tasks",
+				APIKeyAuth([]string{"audit-key", "plain-key"}),
+				PayloadAccess(tt.payloadKeys),
+				func(c *gin.Context) {
+					redacted = PayloadsRedacted(c)
+					c.Status(http.StatusOK)
+				},
+			)
+
+			req, _ := http.NewRequest("GET", "/tasks", nil)
+			req.Header.Set("X-API-Key", tt.key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectRedact, redacted)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter keeps a token bucket per client, so one misbehaving client can't starve the others
+// It is safe for concurrent use
+type RateLimiter struct {
+	rate  float64 // Tokens added per second
+	burst float64 // Bucket capacity
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+
+	rejected atomic.Int64
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	limited bool // The last request was rejected; logged once per streak
+}
+
+// NewRateLimiter allows each client rps requests per second on average and up to burst at once
+// A burst below one is raised to rps rounded up, and at least one
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = max(1, int(math.Ceil(rps)))
+	}
+	return &RateLimiter{
+		rate:    rps,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Allow takes a token from the client's bucket
+// When the bucket is empty it returns false and how long until a token is available
+func (l *RateLimiter) Allow(client string) (allowed bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[client] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.limited = false
+		return true, 0
+	}
+
+	l.rejected.Add(1)
+	if !b.limited {
+		b.limited = true
+		logger.Warn("Client exceeded the task creation rate limit",
+			logger.String("client", client),
+			logger.Any("rps", l.rate),
+			logger.Int("burst", int(l.burst)),
+		)
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep drops buckets that have refilled completely, as they behave like new ones
+// It runs at most once per refill period so busy limiters don't rescan on every request
+func (l *RateLimiter) sweep(now time.Time) {
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.lastSweep) < refill {
+		return
+	}
+	for client, b := range l.buckets {
+		if now.Sub(b.updated) >= refill {
+			delete(l.buckets, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Rejected returns how many requests have been refused
+func (l *RateLimiter) Rejected() int64 {
+	return l.rejected.Load()
+}
+
+// RateLimit is a middleware that rejects requests with 429 and a Retry-After header once the
+// client's bucket is empty. Clients are identified by API key, or by IP without one, so it
+// must run after APIKeyAuth. A nil limiter allows every request
+func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		// API keys are hashed, as the client is logged when it is first limited
+		client := "ip:" + c.ClientIP()
+		if key := c.GetString(ContextKeyAPIKey); key != "" {
+			sum := sha256.Sum256([]byte(key))
+			client = "key:" + hex.EncodeToString(sum[:8])
+		}
+
+		allowed, retryAfter := limiter.Allow(client)
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			response.ErrorWithMessage(c, http.StatusTooManyRequests, "rate_limited", "Too many requests, retry later")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
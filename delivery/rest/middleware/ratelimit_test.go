@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewRateLimiter(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	// The burst is available at once, then tokens refill at the rate
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.Allow("a")
+		assert.True(t, allowed, "request %d", i)
+	}
+	allowed, retryAfter := limiter.Allow("a")
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// Other clients have their own bucket
+	allowed, _ = limiter.Allow("b")
+	assert.True(t, allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	allowed, _ = limiter.Allow("a")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("a")
+	assert.False(t, allowed)
+	assert.Equal(t, int64(2), limiter.Rejected())
+
+	// Idle buckets refill completely and are dropped
+	now = now.Add(2 * time.Second)
+	limiter.Allow("c")
+	assert.Len(t, limiter.buckets, 1)
+}
+
+func TestRateLimiterConcurrent(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 50)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := limiter.Allow("client"); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 50, allowed)
+	assert.Equal(t, int64(150), limiter.Rejected())
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		limiter *RateLimiter
+		codes   []int
+	}{
+		{"Disabled", nil, []int{http.StatusCreated, http.StatusCreated, http.StatusCreated}},
+		{"Limited per API key", NewRateLimiter(0.1, 2), []int{http.StatusCreated, http.StatusCreated, http.StatusTooManyRequests}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/tasks",
+				APIKeyAuth([]string{"key-a", "key-b"}),
+				RateLimit(tt.limiter),
+				func(c *gin.Context) { c.Status(http.StatusCreated) },
+			)
+			send := func(key string) *httptest.ResponseRecorder {
+				req, _ := http.NewRequest("POST", "/tasks", nil)
+				req.Header.Set("X-API-Key", key)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				return w
+			}
+
+			for i, code := range tt.codes {
+				w := send("key-a")
+				assert.Equal(t, code, w.Code, "request %d", i)
+				if code == http.StatusTooManyRequests {
+					assert.Equal(t, "10", w.Header().Get("Retry-After"))
+					assert.Contains(t, w.Body.String(), "rate_limited")
+				}
+			}
+
+			// Another key still has its full burst
+			assert.Equal(t, http.StatusCreated, send("key-b").Code)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"github.com/usual2970/later/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID on requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength matches the request_id column
+const maxRequestIDLength = 128
+
+// ContextKeyRequestID is the gin context key holding the request ID
+const ContextKeyRequestID = "request_id"
+
+// RequestID is a middleware that tags each request with an ID, taken from the X-Request-ID
+// header or generated, and echoes it in the response. The ID travels in the request context
+// so tasks created by the request record it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(ContextKeyRequestID, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(domain.WithRequestID(c.Request.Context(), requestID))
+		c.Next()
+	}
+}
+
+// validRequestID accepts client IDs that fit the column and are safe to log and echo in headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
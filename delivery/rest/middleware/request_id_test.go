@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/usual2970/later/domain"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		header   string
+		expectID string // Empty expects a generated UUID
+	}{
+		{"Client ID is kept", "req-123", "req-123"},
+		{"Missing ID is generated", "", ""},
+		{"Overlong ID is replaced", strings.Repeat("a", 129), ""},
+		{"ID with spaces is replaced", "req 123", ""},
+		{"ID with control characters is replaced", "req\x01", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxID string
+			var ok bool
+
+			router := gin.New()
+			router.GET("/tasks", RequestID(), func(c *gin.Context) {
+				ctxID, ok = domain.RequestIDFromContext(c.Request.Context())
+				assert.Equal(t, ctxID, c.GetString(ContextKeyRequestID))
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.True(t, ok)
+			assert.Equal(t, ctxID, w.Header().Get(RequestIDHeader))
+			if tt.expectID != "" {
+				assert.Equal(t, tt.expectID, ctxID)
+			} else {
+				_, err := uuid.Parse(ctxID)
+				assert.NoError(t, err, "expected a generated UUID, got %q", ctxID)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantConfig configures how requests are mapped to tenants
+type TenantConfig struct {
+	Header     string            // Trusted header carrying the tenant ID (e.g. X-Tenant-ID)
+	KeyTenants map[string]string // API key -> tenant ID
+	AdminKeys  []string          // API keys with an unscoped, global view
+}
+
+// Enabled returns true if tenant scoping is configured
+func (c TenantConfig) Enabled() bool {
+	return c.Header != "" || len(c.KeyTenants) > 0
+}
+
+// TenantScope is a middleware that scopes the request context to a tenant
+// It must run after APIKeyAuth. Admin keys keep a global view; other requests
+// are resolved from the API key mapping, then the tenant header, and rejected
+// with 403 if no tenant can be determined.
+func TenantScope(cfg TenantConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enabled() {
+			c.Next()
+			return
+		}
+
+		key := c.GetString(ContextKeyAPIKey)
+		if key != "" && validAPIKey(cfg.AdminKeys, key) {
+			c.Next()
+			return
+		}
+
+		var tenantID string
+		if key != "" {
+			tenantID = cfg.KeyTenants[key]
+		}
+		if tenantID == "" && cfg.Header != "" {
+			tenantID = c.GetHeader(cfg.Header)
+		}
+
+		if tenantID == "" {
+			response.ErrorWithMessage(c, http.StatusForbidden, "tenant_required", "Request is not associated with a tenant")
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(domain.WithTenant(c.Request.Context(), tenantID))
+		c.Next()
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/usual2970/later/domain"
+)
+
+func TestTenantScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := TenantConfig{
+		Header:     "X-Tenant-ID",
+		KeyTenants: map[string]string{"acme-key": "acme"},
+		AdminKeys:  []string{"admin-key"},
+	}
+
+	tests := []struct {
+		name         string
+		cfg          TenantConfig
+		headers      map[string]string
+		expectCode   int
+		expectTenant string
+	}{
+		{"Disabled leaves request unscoped", TenantConfig{}, nil, http.StatusOK, ""},
+		{"Admin key is unscoped", cfg, map[string]string{"X-API-Key": "admin-key", "X-Tenant-ID": "acme"}, http.StatusOK, ""},
+		{"Mapped key", cfg, map[string]string{"X-API-Key": "acme-key"}, http.StatusOK, "acme"},
+		{"Mapped key ignores header", cfg, map[string]string{"X-API-Key": "acme-key", "X-Tenant-ID": "globex"}, http.StatusOK, "acme"},
+		{"Tenant header", cfg, map[string]string{"X-API-Key": "plain-key", "X-Tenant-ID": "globex"}, http.StatusOK, "globex"},
+		{"No tenant", cfg, map[string]string{"X-API-Key": "plain-key"}, http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tenantID string
+			var scoped bool
+
+			router := gin.New()
+			router.GET("/tasks",
+				APIKeyAuth([]string{"admin-key", "acme-key", "plain-key"}),
+				TenantScope(tt.cfg),
+				func(c *gin.Context) {
+					tenantID, scoped = domain.TenantFromContext(c.Request.Context())
+					c.Status(http.StatusOK)
+				},
+			)
+
+			req, _ := http.NewRequest("GET", "/tasks", nil)
+			req.Header.Set("X-API-Key", "plain-key")
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Equal(t, tt.expectTenant, tenantID)
+			assert.Equal(t, tt.expectTenant != "", scoped)
+		})
+	}
+}
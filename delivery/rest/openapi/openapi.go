@@ -0,0 +1,29 @@
+// Package openapi serves the API's OpenAPI document and an optional Swagger UI
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Spec is the OpenAPI 3 document describing the REST API
+// It is maintained by hand; server tests check handler responses against it
+//
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed swagger.html
+var swaggerUI []byte
+
+// ServeSpec handles GET /openapi.json
+func ServeSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", Spec)
+}
+
+// ServeUI handles GET /docs with a Swagger UI page for the spec
+// The page loads Swagger UI's assets from a CDN
+func ServeUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", swaggerUI)
+}
@@ -0,0 +1,44 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/usual2970/later/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusFor maps an error from the task service to the HTTP status and error code returned for
+// it by both the REST server and the embedded pkg/later routes
+// Errors that don't wrap a domain error are internal errors
+func StatusFor(err error) (status int, code string) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound, "task_not_found"
+	case errors.Is(err, domain.ErrTaskCannotDelete), errors.Is(err, domain.ErrTaskCannotRetry):
+		return http.StatusBadRequest, "invalid_status"
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict, "conflict"
+	case errors.Is(err, domain.ErrBadParamInput):
+		return http.StatusBadRequest, "validation_error"
+	case errors.Is(err, domain.ErrQueueFull):
+		return http.StatusTooManyRequests, "queue_full"
+	}
+	return http.StatusInternalServerError, "internal_error"
+}
+
+// DomainError sends the error response StatusFor maps err to
+// Client errors carry err's message; internal errors carry message instead, so connection
+// details and the like stay in the logs
+func DomainError(c *gin.Context, err error, message string) {
+	status, code := StatusFor(err)
+	switch status {
+	case http.StatusInternalServerError:
+	case http.StatusNotFound:
+		message = "Task not found"
+	default:
+		message = err.Error()
+	}
+	ErrorWithMessage(c, status, code, message)
+}
@@ -87,6 +87,17 @@ func ErrorWithMessage(c *gin.Context, httpStatus int, code string, message strin
 	})
 }
 
+// Conflict sends a 409 response naming the task whose ID is already taken
+func Conflict(c *gin.Context, message string, taskID string) {
+	log.Printf("[ERROR] conflict: %s - %s", message, c.Request.URL.Path)
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "conflict",
+		"message": message,
+		"task_id": taskID,
+	})
+}
+
 // NoContent sends a 204 No Content response
 func NoContent(c *gin.Context) {
 	c.AbortWithStatus(http.StatusNoContent)
@@ -0,0 +1,21 @@
+package rest
+
+import (
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRetrySchedule handles GET /api/v1/tasks/:id/retry-schedule
+// It projects when the task's remaining retries run under the configured backoff, jitter and cap
+// if every attempt fails; finished tasks have none left
+func (h *Handler) GetRetrySchedule(c *gin.Context) {
+	schedule, err := h.taskService.GetRetrySchedule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		response.DomainError(c, err, "Failed to get retry schedule")
+		return
+	}
+
+	response.Success(c, dto.NewRetryScheduleResponse(schedule))
+}
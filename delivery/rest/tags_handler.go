@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/delivery/websocket"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetTaskTags handles PUT /api/v1/tasks/:id/tags
+// It replaces the task's tags, whatever its status
+func (h *Handler) SetTaskTags(c *gin.Context) {
+	var req dto.SetTaskTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	h.updateTags(c, "SetTaskTags", func(id string) (*entity.Task, error) {
+		return h.taskService.SetTags(c.Request.Context(), id, req.Tags)
+	})
+}
+
+// EditTaskTags handles PATCH /api/v1/tasks/:id/tags
+// It adds and removes tags, keeping the task's others
+func (h *Handler) EditTaskTags(c *gin.Context) {
+	var req dto.EditTaskTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "validation_error", "add or remove at least one tag")
+		return
+	}
+
+	h.updateTags(c, "EditTaskTags", func(id string) (*entity.Task, error) {
+		return h.taskService.EditTags(c.Request.Context(), id, req.Add, req.Remove)
+	})
+}
+
+// updateTags runs a tag change, broadcasts the updated task and writes its tags
+func (h *Handler) updateTags(c *gin.Context, handler string, update func(id string) (*entity.Task, error)) {
+	id := c.Param("id")
+
+	task, err := update(id)
+	if err != nil {
+		if status, _ := response.StatusFor(err); status == http.StatusInternalServerError {
+			logger.Error("Failed to update task tags",
+				logger.String("handler", handler),
+				logger.String("task_id", id),
+				logger.Any("error", err),
+			)
+		}
+		response.DomainError(c, err, "Failed to update task tags")
+		return
+	}
+	h.broadcast(websocket.EventTaskUpdated, task)
+
+	response.Success(c, dto.NewTaskTagsResponse(task.ID, task.Tags))
+}
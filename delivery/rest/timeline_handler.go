@@ -0,0 +1,22 @@
+package rest
+
+import (
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTaskTimeline handles GET /api/v1/tasks/:id/timeline
+// It lists the task's recorded events, oldest first, with the status each moved it from and to
+func (h *Handler) GetTaskTimeline(c *gin.Context) {
+	id := c.Param("id")
+
+	events, err := h.taskService.GetTaskTimeline(c.Request.Context(), id)
+	if err != nil {
+		response.DomainError(c, err, "Failed to get task timeline")
+		return
+	}
+
+	response.Success(c, dto.NewTaskTimelineResponse(id, events))
+}
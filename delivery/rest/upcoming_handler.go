@@ -0,0 +1,40 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpcomingTasks handles GET /api/v1/tasks/upcoming
+// It lists the pending tasks due within the window (?within=1h by default), soonest first
+func (h *Handler) UpcomingTasks(c *gin.Context) {
+	var query dto.UpcomingTasksQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	filter, err := query.ToRepositoryFilter(time.Now())
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_filter", err.Error())
+		return
+	}
+
+	tasks, total, err := h.taskService.List(c.Request.Context(), filter)
+	if err != nil {
+		logger.Error("Failed to list upcoming tasks",
+			logger.String("handler", "UpcomingTasks"),
+			logger.Any("error", err),
+		)
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error", "Failed to list upcoming tasks")
+		return
+	}
+
+	response.Success(c, taskListResponse(c, tasks, total, query.Page, query.Limit))
+}
@@ -0,0 +1,181 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"go.uber.org/zap"
+)
+
+// sendBuffer is how many new events a client may fall behind by before it is dropped; its
+// buffer also has room for the welcome and a full backfill
+const sendBuffer = 256
+
+// Client is a single WebSocket connection registered with the hub
+type Client struct {
+	id       string
+	hub      *Hub
+	conn     *websocket.Conn
+	send     chan []byte
+	tenantID string // Empty for unscoped clients, which see every tenant
+	filter   *SubscriptionFilter
+	mu       sync.RWMutex
+	admitted bool // Holds one of the hub's MaxClients slots
+
+	payloadRedacted bool // Events are sent without payloads
+}
+
+// NewClient creates a client for an upgraded connection, identified by a random UUID
+// A non-empty tenantID restricts the client to that tenant's events
+func NewClient(hub *Hub, conn *websocket.Conn, tenantID string) *Client {
+	return &Client{
+		id:       uuid.New().String(),
+		hub:      hub,
+		conn:     conn,
+		send:     make(chan []byte, sendBuffer+hub.cfg.BackfillLimit),
+		tenantID: tenantID,
+	}
+}
+
+// ID returns the client's identifier
+func (c *Client) ID() string {
+	return c.id
+}
+
+// welcome queues the connected message and the missed events to replay ahead of any new event
+// Call it before registering the client, while nothing else writes to its send buffer
+func (c *Client) welcome(backfill []*Event, truncated bool) error {
+	msg := connectedMessage{Type: EventConnected}
+	msg.Data.ClientID = c.id
+	msg.Data.Backfilled = len(backfill)
+	msg.Data.BackfillTruncated = truncated
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.send <- data
+
+	for _, event := range backfill {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		c.send <- data
+	}
+	return nil
+}
+
+// Filter returns the client's current subscription filter (nil means all events)
+func (c *Client) Filter() *SubscriptionFilter {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.filter
+}
+
+// accepts returns true if the event belongs to the client's tenant and passes its filter
+func (c *Client) accepts(event *Event) bool {
+	if c.tenantID != "" && event.Data.TenantID != c.tenantID {
+		return false
+	}
+	return c.Filter().Matches(event)
+}
+
+// SetFilter replaces the client's subscription filter
+func (c *Client) SetFilter(filter *SubscriptionFilter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filter = filter
+}
+
+// handleMessage applies a subscription message sent by the client
+// subscribe replaces the current filter; unsubscribe resets to all events
+func (c *Client) handleMessage(data []byte) error {
+	var msg SubscriptionMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("invalid subscription message: %w", err)
+	}
+
+	switch msg.Action {
+	case ActionSubscribe:
+		c.SetFilter(NewSubscriptionFilter(&msg))
+	case ActionUnsubscribe:
+		c.SetFilter(nil)
+	default:
+		return fmt.Errorf("unknown action: %q", msg.Action)
+	}
+
+	return nil
+}
+
+// readPump reads subscription messages until the connection closes
+func (c *Client) readPump() {
+	defer func() {
+		c.hub.unregisterClient(c)
+		c.conn.Close()
+	}()
+
+	// Clients that stop answering pings hit the read deadline and are closed
+	pongWait := c.hub.cfg.pongWait()
+	c.conn.SetReadLimit(c.hub.cfg.ReadLimit)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				c.hub.logger.Warn("WebSocket read error",
+					zap.String("client_id", c.id),
+					zap.Error(err))
+			}
+			return
+		}
+
+		if err := c.handleMessage(data); err != nil {
+			c.hub.logger.Warn("Ignoring WebSocket client message",
+				zap.String("client_id", c.id),
+				zap.Error(err))
+		}
+	}
+}
+
+// writePump writes queued events and keepalive pings to the connection
+func (c *Client) writePump() {
+	ticker := time.NewTicker(c.hub.cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+		c.hub.pumps.Done()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.cfg.WriteTimeout))
+			if !ok {
+				// Hub closed the channel
+				c.conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+				return
+			}
+
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.cfg.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
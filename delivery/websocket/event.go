@@ -0,0 +1,156 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// Event types sent to WebSocket clients
+const (
+	EventTaskCreated = "task_created"
+	EventTaskUpdated = "task_updated"
+
+	// Worker outcomes carry the details of the transition instead of a bare task_updated
+	EventTaskRetryScheduled = "task_retry_scheduled"
+	EventTaskDeadLettered   = "task_dead_lettered"
+	EventTaskCompleted      = "task_completed"
+
+	// Bulk operations send a single summary event instead of one event per task
+	EventTasksBulkDeleted = "tasks_bulk_deleted"
+	EventTasksBulkRetried = "tasks_bulk_retried"
+)
+
+// EventConnected is sent to each client once, as it connects, with the ID the server logs it under
+const EventConnected = "connected"
+
+// connectedMessage welcomes a newly connected client, and says how many missed events follow
+type connectedMessage struct {
+	Type string `json:"type"`
+	Data struct {
+		ClientID          string `json:"client_id"`
+		Backfilled        int    `json:"backfilled,omitempty"`         // Recorded events sent next, oldest first
+		BackfillTruncated bool   `json:"backfill_truncated,omitempty"` // More were missed than sent
+	} `json:"data"`
+}
+
+// Event is a message broadcast to connected clients
+type Event struct {
+	ID   int64     `json:"id,omitempty"` // Set once the event is recorded, for replaying missed events
+	Type string    `json:"type"`
+	Data EventData `json:"data"`
+
+	payload []byte // The task's payload, carried in Data.Payload as the hub's PayloadRedaction allows
+}
+
+// EventData carries the task fields a client needs to react to an event
+type EventData struct {
+	TaskID    string            `json:"task_id"`
+	Name      string            `json:"name,omitempty"`
+	Status    entity.TaskStatus `json:"status"`
+	Tags      []string          `json:"tags,omitempty"`
+	TenantID  string            `json:"tenant_id,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Count     int64             `json:"count,omitempty"` // Tasks affected by a bulk operation
+
+	// The task's payload, as the hub's PayloadRedaction allows: none by default
+	// Payloads are sent live only, never recorded, so replayed events and those from other
+	// instances don't carry them
+	Payload json.RawMessage `json:"payload,omitempty"`
+
+	// Set on task_retry_scheduled: when the next attempt is due, and the failed attempts so far
+	// out of the retries allowed
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	RetryCount  int        `json:"retry_count,omitempty"`
+	MaxRetries  int        `json:"max_retries,omitempty"`
+
+	// Set on task_retry_scheduled and task_dead_lettered: the error that failed the attempt,
+	// or that dead-lettered the task
+	Error string `json:"error,omitempty"`
+
+	// Set on task_completed: how long the callback took to respond, and how late after its
+	// scheduled time the task was dispatched
+	CallbackDurationMs *int64 `json:"callback_duration_ms,omitempty"`
+	DispatchLatencyMs  *int64 `json:"dispatch_latency_ms,omitempty"`
+}
+
+// IsBulk reports whether the event summarizes a bulk operation rather than describing one task
+func (e *Event) IsBulk() bool {
+	return e.Type == EventTasksBulkDeleted || e.Type == EventTasksBulkRetried
+}
+
+// NewTaskEvent builds an event of the given type from a task snapshot
+func NewTaskEvent(eventType string, task *entity.Task) *Event {
+	return &Event{
+		Type: eventType,
+		Data: EventData{
+			TaskID:    task.ID,
+			Name:      task.Name,
+			Status:    task.Status,
+			Tags:      task.Tags,
+			TenantID:  task.TenantID,
+			UpdatedAt: time.Now().UTC(),
+		},
+		payload: task.Payload,
+	}
+}
+
+// withoutPayload returns the event as sent to clients without payload access
+func (e *Event) withoutPayload() *Event {
+	if e.Data.Payload == nil {
+		return e
+	}
+	stripped := *e
+	stripped.Data.Payload = nil
+	return &stripped
+}
+
+// NewTaskUpdateEvent builds the event for a task a worker has just persisted: a
+// task_retry_scheduled, task_dead_lettered or task_completed event with the transition's
+// details, or a task_updated event for any other status
+func NewTaskUpdateEvent(task *entity.Task) *Event {
+	var event *Event
+	switch task.Status {
+	case entity.TaskStatusFailed:
+		event = NewTaskEvent(EventTaskRetryScheduled, task)
+		event.Data.NextRetryAt = task.NextRetryAt
+		event.Data.RetryCount = task.RetryCount
+		event.Data.MaxRetries = task.MaxRetries
+		event.Data.Error = taskError(task)
+	case entity.TaskStatusDeadLettered:
+		event = NewTaskEvent(EventTaskDeadLettered, task)
+		event.Data.RetryCount = task.RetryCount
+		event.Data.MaxRetries = task.MaxRetries
+		event.Data.Error = taskError(task)
+	case entity.TaskStatusCompleted:
+		event = NewTaskEvent(EventTaskCompleted, task)
+		event.Data.CallbackDurationMs = task.CallbackDurationMs
+		event.Data.DispatchLatencyMs = task.DispatchLatencyMs
+	default:
+		event = NewTaskEvent(EventTaskUpdated, task)
+	}
+	return event
+}
+
+// taskError returns the task's latest error message, or "" if it has none
+func taskError(task *entity.Task) string {
+	if task.ErrorMessage == nil {
+		return ""
+	}
+	return *task.ErrorMessage
+}
+
+// NewBulkEvent builds a summary event for a bulk operation that affected count tasks
+// status is the affected tasks' new status, if the operation sets one
+func NewBulkEvent(eventType string, count int64, status entity.TaskStatus, tenantID string) *Event {
+	return &Event{
+		Type: eventType,
+		Data: EventData{
+			Status:    status,
+			TenantID:  tenantID,
+			UpdatedAt: time.Now().UTC(),
+			Count:     count,
+		},
+	}
+}
@@ -0,0 +1,82 @@
+package websocket
+
+import "github.com/usual2970/later/domain/entity"
+
+// Subscription actions accepted from clients
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+)
+
+// SubscriptionMessage is sent by a client to narrow the events it receives
+// Example: {"action":"subscribe","task_ids":["..."],"tags":["billing"],"statuses":["failed"]}
+type SubscriptionMessage struct {
+	Action   string              `json:"action"`
+	TaskIDs  []string            `json:"task_ids"`
+	Tags     []string            `json:"tags"`
+	Statuses []entity.TaskStatus `json:"statuses"`
+}
+
+// SubscriptionFilter restricts which events are delivered to a client
+// Each non-empty dimension must match; empty dimensions match everything
+type SubscriptionFilter struct {
+	taskIDs  map[string]bool
+	tags     map[string]bool
+	statuses map[entity.TaskStatus]bool
+}
+
+// NewSubscriptionFilter builds a filter from a subscription message
+// Returns nil if the message carries no criteria, meaning "all events"
+func NewSubscriptionFilter(msg *SubscriptionMessage) *SubscriptionFilter {
+	if len(msg.TaskIDs) == 0 && len(msg.Tags) == 0 && len(msg.Statuses) == 0 {
+		return nil
+	}
+
+	f := &SubscriptionFilter{
+		taskIDs:  make(map[string]bool, len(msg.TaskIDs)),
+		tags:     make(map[string]bool, len(msg.Tags)),
+		statuses: make(map[entity.TaskStatus]bool, len(msg.Statuses)),
+	}
+	for _, id := range msg.TaskIDs {
+		f.taskIDs[id] = true
+	}
+	for _, tag := range msg.Tags {
+		f.tags[tag] = true
+	}
+	for _, status := range msg.Statuses {
+		f.statuses[status] = true
+	}
+	return f
+}
+
+// Matches returns true if the event passes the filter
+// A nil filter matches every event
+func (f *SubscriptionFilter) Matches(event *Event) bool {
+	if f == nil {
+		return true
+	}
+
+	// A bulk summary may concern any of the tasks a client watches by tag or status
+	if event.IsBulk() {
+		return len(f.taskIDs) == 0
+	}
+
+	if len(f.taskIDs) > 0 && !f.taskIDs[event.Data.TaskID] {
+		return false
+	}
+
+	if len(f.statuses) > 0 && !f.statuses[event.Data.Status] {
+		return false
+	}
+
+	if len(f.tags) > 0 {
+		for _, tag := range event.Data.Tags {
+			if f.tags[tag] {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
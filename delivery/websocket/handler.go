@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/usual2970/later/delivery/rest/middleware"
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/domain"
+
+	"go.uber.org/zap"
+)
+
+// Subprotocol is selected for browsers that send their API key as a subprotocol, which
+// must offer it alongside: new WebSocket(url, ["later", "api-key." + key])
+const Subprotocol = "later"
+
+// ServeWS upgrades the request to a WebSocket and registers the client with the hub
+// Clients connecting with a tenant-scoped request context only receive that tenant's events
+// Browser pages from origins the hub doesn't allow are refused with 403, and connections beyond
+// its MaxClients with 503, before upgrading; API keys are checked by the route's middleware
+// Clients whose API key lacks payload access are sent events without payloads
+// A client reconnecting with ?since=<event ID> is first sent the recorded events it missed,
+// up to the hub's BackfillLimit
+func ServeWS(hub *Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hub.upgrader.CheckOrigin(c.Request) {
+			hub.logger.Warn("WebSocket origin not allowed, refusing connection",
+				zap.String("origin", c.GetHeader("Origin")))
+			response.ErrorWithMessage(c, http.StatusForbidden, "origin_not_allowed",
+				"Origin not allowed")
+			return
+		}
+		var since int64 = -1
+		if raw := c.Query("since"); raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || id < 0 {
+				response.ErrorWithMessage(c, http.StatusBadRequest, "invalid_query",
+					"since must be an event ID")
+				return
+			}
+			since = id
+		}
+		if !hub.admit() {
+			hub.logger.Warn("WebSocket client limit reached, refusing connection",
+				zap.Int("max_clients", hub.cfg.MaxClients))
+			response.ErrorWithMessage(c, http.StatusServiceUnavailable, "too_many_connections",
+				"Too many WebSocket connections")
+			return
+		}
+
+		// Missed events are loaded before upgrading, so a failure can still be reported
+		// An event recorded after loading them but delivered before the client registers is
+		// lost, a window of a few milliseconds
+		tenantID, _ := domain.TenantFromContext(c.Request.Context())
+		var backfill []*Event
+		var truncated bool
+		if since >= 0 && hub.events != nil {
+			var err error
+			backfill, truncated, err = hub.backfill(c.Request.Context(), since, tenantID)
+			if err != nil {
+				hub.admitted.Add(-1)
+				hub.logger.Error("Failed to load missed task events", zap.Error(err))
+				response.ErrorWithMessage(c, http.StatusInternalServerError, "internal_error",
+					"Failed to load missed events")
+				return
+			}
+		}
+
+		conn, err := hub.upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			hub.admitted.Add(-1)
+			hub.logger.Warn("WebSocket upgrade failed", zap.Error(err))
+			return
+		}
+
+		client := NewClient(hub, conn, tenantID)
+		client.admitted = true
+		client.payloadRedacted = middleware.PayloadsRedacted(c)
+		if err := client.welcome(backfill, truncated); err != nil {
+			hub.release(client)
+			hub.logger.Error("Failed to welcome WebSocket client", zap.Error(err))
+			conn.Close()
+			return
+		}
+		hub.pumps.Add(1)
+		if !hub.registerClient(client) {
+			hub.release(client)
+			hub.pumps.Done()
+			conn.Close()
+			return
+		}
+
+		go client.writePump()
+		go client.readPump()
+	}
+}
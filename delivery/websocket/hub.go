@@ -0,0 +1,502 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/eventbus"
+
+	"go.uber.org/zap"
+)
+
+// Keepalive and size defaults for HubConfig fields left at zero
+const (
+	DefaultPingInterval  = 54 * time.Second
+	DefaultWriteTimeout  = 10 * time.Second
+	DefaultReadLimit     = 4096
+	DefaultBackfillLimit = 200
+)
+
+// recordTimeout bounds recording an event or publishing it on the bus, so a slow database
+// or bus delays broadcasts only so long
+const recordTimeout = 5 * time.Second
+
+// outboxSize is how many broadcast events may wait to be recorded before they are delivered
+// unrecorded, and how many recorded events may wait to be published on the bus before they are
+// dropped from it
+const outboxSize = 1024
+
+// HubConfig limits the hub's clients and tunes their keepalive
+// Zero values keep the defaults: unlimited clients, same-origin browser pages only, and the
+// Default* constants
+type HubConfig struct {
+	MaxClients   int           // Connections beyond this many are refused with 503
+	PingInterval time.Duration // Clients not answering a ping within 10/9 of this are closed
+	WriteTimeout time.Duration // Bounds each write to a client
+	ReadLimit    int64         // Largest subscription message accepted, in bytes
+
+	// AllowedOrigins lists the other origins browser pages may connect from, such as
+	// "https://dash.example.com" or "https://*.example.com" for its subdomains; "*" allows any
+	AllowedOrigins []string
+
+	// BackfillLimit caps the recorded events sent to a client reconnecting with ?since
+	BackfillLimit int
+}
+
+// Validate checks the configuration without building a hub
+func (c HubConfig) Validate() error {
+	if c.MaxClients < 0 {
+		return fmt.Errorf("max WebSocket clients cannot be negative")
+	}
+	if c.PingInterval < 0 || c.WriteTimeout < 0 {
+		return fmt.Errorf("WebSocket ping interval and write timeout cannot be negative")
+	}
+	if c.ReadLimit < 0 {
+		return fmt.Errorf("WebSocket read limit cannot be negative")
+	}
+	if c.BackfillLimit < 0 {
+		return fmt.Errorf("WebSocket backfill limit cannot be negative")
+	}
+	for _, origin := range c.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withDefaults fills in the fields left at zero
+func (c HubConfig) withDefaults() HubConfig {
+	if c.PingInterval == 0 {
+		c.PingInterval = DefaultPingInterval
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = DefaultWriteTimeout
+	}
+	if c.ReadLimit == 0 {
+		c.ReadLimit = DefaultReadLimit
+	}
+	if c.BackfillLimit == 0 {
+		c.BackfillLimit = DefaultBackfillLimit
+	}
+	return c
+}
+
+// pongWait is how long a client has to answer a ping before it is considered gone
+func (c HubConfig) pongWait() time.Duration {
+	return c.PingInterval * 10 / 9
+}
+
+// Hub maintains the set of connected clients and fans out task events
+type Hub struct {
+	cfg        HubConfig
+	upgrader   websocket.Upgrader
+	clients    map[*Client]bool
+	register   chan *Client
+	unregister chan *Client
+	broadcast  chan *Event
+	outbox     chan *Event            // Broadcast events waiting to be recorded
+	published  chan *entity.TaskEvent // Recorded events waiting to be published on the bus
+	quit       chan struct{}
+	stopOnce   sync.Once
+	pumps      sync.WaitGroup
+	background sync.WaitGroup // The recorder and publisher goroutines
+	mu         sync.RWMutex
+	logger     *zap.Logger
+	events     repository.TaskEventRepository // nil when events aren't recorded
+	bus        eventbus.Bus                   // Fans events out to the hubs of other instances
+	origin     string                         // Marks this hub's events on the bus
+	redaction  PayloadRedaction
+
+	admitted atomic.Int64 // Clients connected or connecting through ServeWS
+	rejected atomic.Int64 // Connections refused over MaxClients
+}
+
+// HubOption configures optional Hub behaviour
+type HubOption func(*Hub)
+
+// WithEventStore records every broadcast event, numbering it, so clients reconnecting with
+// ?since=<id> are sent the events they missed
+func WithEventStore(events repository.TaskEventRepository) HubOption {
+	return func(h *Hub) {
+		h.events = events
+	}
+}
+
+// WithEventBus publishes every broadcast event on bus and broadcasts the events other
+// instances publish there, so clients see events wherever they were produced
+// The hub runs the bus while it runs
+func WithEventBus(bus eventbus.Bus) HubOption {
+	return func(h *Hub) {
+		h.bus = bus
+	}
+}
+
+// NewHub creates a new WebSocket hub; cfg must be valid
+func NewHub(logger *zap.Logger, cfg HubConfig, opts ...HubOption) *Hub {
+	cfg = cfg.withDefaults()
+	h := &Hub{
+		cfg: cfg,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			Subprotocols:    []string{Subprotocol},
+			CheckOrigin: func(r *http.Request) bool {
+				return originAllowed(cfg.AllowedOrigins, r)
+			},
+		},
+		clients:    make(map[*Client]bool),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		broadcast:  make(chan *Event, 256),
+		outbox:     make(chan *Event, outboxSize),
+		published:  make(chan *entity.TaskEvent, outboxSize),
+		quit:       make(chan struct{}),
+		logger:     logger,
+		bus:        eventbus.NewNop(),
+		origin:     uuid.New().String(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Run processes registrations and broadcasts, and receives events from other instances,
+// until Stop is called
+func (h *Hub) Run() {
+	go h.bus.Run(h.receive)
+	h.background.Add(2)
+	go h.runRecorder()
+	go h.runPublisher()
+
+	for {
+		select {
+		case client := <-h.register:
+			h.mu.Lock()
+			h.clients[client] = true
+			h.mu.Unlock()
+			h.logger.Debug("WebSocket client connected", zap.String("client_id", client.id))
+
+		case client := <-h.unregister:
+			h.removeClient(client)
+
+		case event := <-h.broadcast:
+			h.deliver(event)
+
+		case <-h.quit:
+			h.bus.Stop()
+			h.closeAllClients()
+			return
+		}
+	}
+}
+
+// Stop stops the hub's run loop, closes every client connection and waits
+// for their write loops to send a close frame and for queued events to be recorded
+func (h *Hub) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.quit)
+	})
+	h.pumps.Wait()
+	h.background.Wait()
+}
+
+// Broadcast queues the event to be recorded, if the hub has an event store, published to
+// other instances and delivered to all matching clients
+// It never waits on the store or the bus: when the outbox is full the event is delivered
+// unrecorded
+func (h *Hub) Broadcast(event *Event) {
+	event.Data.Payload = h.redaction.apply(event.payload)
+	select {
+	case h.outbox <- event:
+	default:
+		h.logger.Warn("WebSocket event outbox full, broadcasting event unrecorded",
+			zap.String("type", event.Type),
+			zap.String("task_id", event.Data.TaskID))
+		h.enqueue(event)
+	}
+}
+
+// runRecorder records and queues for delivery the broadcast events, in order, until the hub
+// stops; the events still waiting then are recorded for clients to replay
+func (h *Hub) runRecorder() {
+	defer h.background.Done()
+	for {
+		select {
+		case event := <-h.outbox:
+			h.record(event)
+			h.enqueue(event)
+		case <-h.quit:
+			h.drainOutbox()
+			return
+		}
+	}
+}
+
+// drainOutbox records the events left in the outbox, giving up after recordTimeout
+func (h *Hub) drainOutbox() {
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+	for {
+		select {
+		case event := <-h.outbox:
+			h.store(ctx, event)
+		default:
+			return
+		}
+	}
+}
+
+// enqueue queues an event for delivery; events are dropped if the broadcast buffer is full
+func (h *Hub) enqueue(event *Event) {
+	select {
+	case h.broadcast <- event:
+	default:
+		h.logger.Warn("WebSocket broadcast buffer full, dropping event",
+			zap.String("type", event.Type),
+			zap.String("task_id", event.Data.TaskID))
+	}
+}
+
+// BroadcastTaskUpdate broadcasts the event for a task a worker has persisted, detailing retries,
+// dead-lettering and completion
+func (h *Hub) BroadcastTaskUpdate(task *entity.Task) {
+	h.Broadcast(NewTaskUpdateEvent(task))
+}
+
+// record stores the event and sets its ID, then queues it to be published on the bus; events
+// that fail to record are still broadcast, without an ID
+func (h *Hub) record(event *Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+	record := h.store(ctx, event)
+	if record == nil {
+		return
+	}
+	select {
+	case h.published <- record:
+	default:
+		h.logger.Warn("Event bus backlog full, not publishing event",
+			zap.String("type", event.Type),
+			zap.String("task_id", event.Data.TaskID))
+	}
+}
+
+// runPublisher publishes the recorded events on the bus, in order, until the hub stops, so a
+// slow bus delays only other instances' clients; stopping abandons the event being published
+func (h *Hub) runPublisher() {
+	defer h.background.Done()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-h.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		select {
+		case record := <-h.published:
+			h.publish(ctx, record)
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// publish publishes a recorded event on the bus
+func (h *Hub) publish(ctx context.Context, record *entity.TaskEvent) {
+	ctx, cancel := context.WithTimeout(ctx, recordTimeout)
+	defer cancel()
+	if err := h.bus.Publish(ctx, record); err != nil {
+		h.logger.Warn("Failed to publish task event",
+			zap.String("type", record.Type),
+			zap.String("task_id", record.TaskID),
+			zap.Error(err))
+	}
+}
+
+// store records the event, if the hub has an event store, and sets its ID, returning the
+// record to publish or nil if the event cannot be encoded
+func (h *Hub) store(ctx context.Context, event *Event) *entity.TaskEvent {
+	record, err := event.taskEvent()
+	if err != nil {
+		h.logger.Error("Failed to encode task event", zap.String("type", event.Type), zap.Error(err))
+		return nil
+	}
+	record.Origin = h.origin
+
+	if h.events != nil {
+		if err := h.events.Append(ctx, record); err != nil {
+			h.logger.Warn("Failed to record task event",
+				zap.String("type", event.Type),
+				zap.String("task_id", event.Data.TaskID),
+				zap.Error(err))
+		} else {
+			event.ID = record.ID
+		}
+	}
+	return record
+}
+
+// receive broadcasts an event another instance published, skipping this hub's own
+func (h *Hub) receive(record *entity.TaskEvent) {
+	if record.Origin == h.origin {
+		return
+	}
+	event, err := replayedEvent(record)
+	if err != nil {
+		h.logger.Warn("Ignoring malformed task event from the event bus", zap.Error(err))
+		return
+	}
+	h.enqueue(event)
+}
+
+// backfill returns the recorded events after since that a client of the tenant missed, up to
+// the backfill limit, and whether there were more
+func (h *Hub) backfill(ctx context.Context, since int64, tenantID string) ([]*Event, bool, error) {
+	records, err := h.events.ListSince(ctx, since, tenantID, h.cfg.BackfillLimit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	truncated := len(records) > h.cfg.BackfillLimit
+	if truncated {
+		records = records[:h.cfg.BackfillLimit]
+	}
+
+	events := make([]*Event, 0, len(records))
+	for _, record := range records {
+		event, err := replayedEvent(record)
+		if err != nil {
+			return nil, false, err
+		}
+		events = append(events, event)
+	}
+	return events, truncated, nil
+}
+
+// ClientCount returns the number of connected clients
+func (h *Hub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// RejectedCount returns the number of connections refused for exceeding MaxClients
+func (h *Hub) RejectedCount() int64 {
+	return h.rejected.Load()
+}
+
+// admit reserves a slot for a new connection, or counts it as rejected if the hub is full
+// The slot is freed with release once the client is removed or fails to connect
+func (h *Hub) admit() bool {
+	for {
+		n := h.admitted.Load()
+		if h.cfg.MaxClients > 0 && n >= int64(h.cfg.MaxClients) {
+			h.rejected.Add(1)
+			return false
+		}
+		if h.admitted.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// release frees the slot of a client admitted through ServeWS
+func (h *Hub) release(client *Client) {
+	if client.admitted {
+		h.admitted.Add(-1)
+	}
+}
+
+// deliver sends an event to every client whose filter matches
+func (h *Hub) deliver(event *Event) {
+	message, err := json.Marshal(event)
+	if err != nil {
+		h.logger.Error("Failed to marshal WebSocket event", zap.Error(err))
+		return
+	}
+	var redacted []byte // The event without its payload, marshaled for the first client needing it
+
+	h.mu.RLock()
+	var slow []*Client
+	for client := range h.clients {
+		if !client.accepts(event) {
+			continue
+		}
+		data := message
+		if client.payloadRedacted && event.Data.Payload != nil {
+			if redacted == nil {
+				// It can't fail where marshaling the whole event didn't
+				redacted, _ = json.Marshal(event.withoutPayload())
+			}
+			data = redacted
+		}
+		select {
+		case client.send <- data:
+		default:
+			slow = append(slow, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	// Drop clients that cannot keep up
+	for _, client := range slow {
+		h.removeClient(client)
+	}
+}
+
+// registerClient adds a client unless the hub is stopping
+func (h *Hub) registerClient(client *Client) bool {
+	select {
+	case h.register <- client:
+		return true
+	case <-h.quit:
+		return false
+	}
+}
+
+// unregisterClient removes a client unless the hub is already stopping
+func (h *Hub) unregisterClient(client *Client) {
+	select {
+	case h.unregister <- client:
+	case <-h.quit:
+	}
+}
+
+// closeAllClients closes every client's send channel during shutdown
+func (h *Hub) closeAllClients() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.clients {
+		delete(h.clients, client)
+		close(client.send)
+		h.release(client)
+	}
+}
+
+// removeClient unregisters a client and closes its send channel
+func (h *Hub) removeClient(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.send)
+		h.release(client)
+		h.logger.Debug("WebSocket client disconnected", zap.String("client_id", client.id))
+	}
+}
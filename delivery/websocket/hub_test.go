@@ -0,0 +1,707 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/eventbus"
+	"github.com/usual2970/later/repository/memory"
+)
+
+// newTestClient returns a client without a network connection
+func newTestClient(hub *Hub) *Client {
+	return &Client{
+		id:   "test-client",
+		hub:  hub,
+		send: make(chan []byte, 16),
+	}
+}
+
+// receive waits for the next event delivered to the client
+func receive(t *testing.T, client *Client) (*Event, bool) {
+	t.Helper()
+	select {
+	case data := <-client.send:
+		var event Event
+		require.NoError(t, json.Unmarshal(data, &event))
+		return &event, true
+	case <-time.After(100 * time.Millisecond):
+		return nil, false
+	}
+}
+
+func TestSubscriptionFilterMatches(t *testing.T) {
+	failedBilling := &Event{Data: EventData{TaskID: "a", Status: entity.TaskStatusFailed, Tags: []string{"billing"}}}
+	completedOps := &Event{Data: EventData{TaskID: "b", Status: entity.TaskStatusCompleted, Tags: []string{"ops"}}}
+	bulkRetried := NewBulkEvent(EventTasksBulkRetried, 40000, entity.TaskStatusPending, "")
+
+	tests := []struct {
+		name     string
+		msg      SubscriptionMessage
+		event    *Event
+		expected bool
+	}{
+		{"Empty filter matches everything", SubscriptionMessage{}, completedOps, true},
+		{"Task ID match", SubscriptionMessage{TaskIDs: []string{"a"}}, failedBilling, true},
+		{"Task ID mismatch", SubscriptionMessage{TaskIDs: []string{"a"}}, completedOps, false},
+		{"Tag match", SubscriptionMessage{Tags: []string{"billing"}}, failedBilling, true},
+		{"Tag mismatch", SubscriptionMessage{Tags: []string{"billing"}}, completedOps, false},
+		{"Status match", SubscriptionMessage{Statuses: []entity.TaskStatus{entity.TaskStatusFailed}}, failedBilling, true},
+		{"Status mismatch", SubscriptionMessage{Statuses: []entity.TaskStatus{entity.TaskStatusFailed}}, completedOps, false},
+		{"Bulk summary matches tag filters", SubscriptionMessage{Tags: []string{"billing"}}, bulkRetried, true},
+		{"Bulk summary skips task ID filters", SubscriptionMessage{TaskIDs: []string{"a"}}, bulkRetried, false},
+		{
+			"All dimensions must match",
+			SubscriptionMessage{Tags: []string{"billing"}, Statuses: []entity.TaskStatus{entity.TaskStatusCompleted}},
+			failedBilling,
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewSubscriptionFilter(&tt.msg)
+			assert.Equal(t, tt.expected, filter.Matches(tt.event))
+		})
+	}
+}
+
+func TestBroadcastRespectsClientFilter(t *testing.T) {
+	hub := NewHub(zap.NewNop(), HubConfig{})
+	go hub.Run()
+	defer hub.Stop()
+
+	filtered := newTestClient(hub)
+	unfiltered := newTestClient(hub)
+	hub.register <- filtered
+	hub.register <- unfiltered
+
+	require.NoError(t, filtered.handleMessage([]byte(`{"action":"subscribe","statuses":["failed"]}`)))
+
+	hub.BroadcastTaskUpdate(&entity.Task{ID: "t1", Status: entity.TaskStatusCompleted})
+	hub.BroadcastTaskUpdate(&entity.Task{ID: "t2", Status: entity.TaskStatusFailed})
+
+	event, ok := receive(t, filtered)
+	require.True(t, ok)
+	assert.Equal(t, "t2", event.Data.TaskID)
+	_, ok = receive(t, filtered)
+	assert.False(t, ok)
+
+	event, ok = receive(t, unfiltered)
+	require.True(t, ok)
+	assert.Equal(t, "t1", event.Data.TaskID)
+	event, ok = receive(t, unfiltered)
+	require.True(t, ok)
+	assert.Equal(t, "t2", event.Data.TaskID)
+}
+
+func TestPayloadRedaction(t *testing.T) {
+	payload := `{"user":{"email":"jane@example.com","ssn":"123-45-6789","plan":"pro"},"items":[{"ssn":"987-65-4321","qty":2}],"amount":12.50}`
+
+	for _, tt := range []struct {
+		name      string
+		redaction PayloadRedaction
+		want      string // Empty when events carry no payload
+	}{
+		{"Default", PayloadRedaction{}, ""},
+		{"Omit", PayloadRedaction{Mode: RedactionOmit}, ""},
+		{"None", PayloadRedaction{Mode: RedactionNone}, payload},
+		{"Fields", PayloadRedaction{Mode: RedactionFields, Fields: []string{"email", "ssn"}},
+			`{"user":{"email":"[REDACTED]","ssn":"[REDACTED]","plan":"pro"},"items":[{"ssn":"[REDACTED]","qty":2}],"amount":12.50}`},
+		{"Masked object", PayloadRedaction{Mode: RedactionFields, Fields: []string{"user"}},
+			`{"user":"[REDACTED]","items":[{"ssn":"987-65-4321","qty":2}],"amount":12.50}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(zap.NewNop(), HubConfig{}, WithPayloadRedaction(tt.redaction))
+			go hub.Run()
+			defer hub.Stop()
+
+			client := newTestClient(hub)
+			hub.register <- client
+
+			task := entity.NewTask("signup", []byte(payload), "https://example.com/callback", time.Now(), 0)
+			hub.Broadcast(NewTaskEvent(EventTaskCreated, task))
+			hub.BroadcastTaskUpdate(task)
+
+			for i := 0; i < 2; i++ {
+				event, ok := receive(t, client)
+				require.True(t, ok, "event not delivered")
+				if tt.want == "" {
+					assert.Empty(t, event.Data.Payload)
+				} else {
+					assert.JSONEq(t, tt.want, string(event.Data.Payload))
+				}
+			}
+		})
+	}
+}
+
+func TestPayloadsAreNotRecorded(t *testing.T) {
+	store := memory.NewTaskEventRepository()
+	hub := NewHub(zap.NewNop(), HubConfig{}, WithEventStore(store),
+		WithPayloadRedaction(PayloadRedaction{Mode: RedactionNone}))
+	go hub.Run()
+	defer hub.Stop()
+
+	task := entity.NewTask("signup", []byte(`{"ssn":"123-45-6789"}`), "https://example.com/callback", time.Now(), 0)
+	hub.Broadcast(NewTaskEvent(EventTaskCreated, task))
+
+	var records []*entity.TaskEvent
+	require.Eventually(t, func() bool {
+		var err error
+		records, err = store.ListSince(context.Background(), 0, "", 10)
+		require.NoError(t, err)
+		return len(records) == 1
+	}, time.Second, 5*time.Millisecond, "the event is recorded")
+	assert.NotContains(t, string(records[0].Metadata), "123-45-6789", "timelines and replays carry no payload")
+}
+
+func TestPayloadRedactionValidate(t *testing.T) {
+	assert.NoError(t, PayloadRedaction{}.Validate())
+	assert.NoError(t, PayloadRedaction{Mode: RedactionNone}.Validate())
+	assert.NoError(t, PayloadRedaction{Mode: RedactionFields, Fields: []string{"ssn"}}.Validate())
+	assert.Error(t, PayloadRedaction{Mode: RedactionFields}.Validate(), "fields needs the keys to mask")
+	assert.Error(t, PayloadRedaction{Mode: "mask"}.Validate())
+}
+
+func TestClientsWithoutPayloadAccessGetNoPayload(t *testing.T) {
+	hub := NewHub(zap.NewNop(), HubConfig{}, WithPayloadRedaction(PayloadRedaction{Mode: RedactionNone}))
+	go hub.Run()
+	defer hub.Stop()
+
+	full := newTestClient(hub)
+	redacted := newTestClient(hub)
+	redacted.payloadRedacted = true
+	hub.register <- full
+	hub.register <- redacted
+
+	task := entity.NewTask("signup", []byte(`{"ssn":"123-45-6789"}`), "https://example.com/callback", time.Now(), 0)
+	hub.Broadcast(NewTaskEvent(EventTaskCreated, task))
+
+	event, ok := receive(t, full)
+	require.True(t, ok, "event not delivered")
+	assert.JSONEq(t, `{"ssn":"123-45-6789"}`, string(event.Data.Payload))
+
+	event, ok = receive(t, redacted)
+	require.True(t, ok, "event not delivered")
+	assert.Empty(t, event.Data.Payload)
+	assert.Equal(t, task.ID, event.Data.TaskID)
+}
+
+func TestTaskUpdateEvents(t *testing.T) {
+	now := time.Date(2026, 3, 1, 14, 30, 0, 0, time.UTC)
+	newTask := func() *entity.Task {
+		task := entity.NewTask("invoice", []byte(`{}`), "https://example.com/callback", now, 5)
+		task.MarkAsProcessing("worker-1", now)
+		return task
+	}
+
+	retrying := newTask()
+	retrying.RetryCount = 2
+	retrying.MarkAsFailed(errors.New("callback returned 503"), now)
+	event := NewTaskUpdateEvent(retrying)
+	assert.Equal(t, EventTaskRetryScheduled, event.Type)
+	require.NotNil(t, event.Data.NextRetryAt)
+	assert.Equal(t, *retrying.NextRetryAt, *event.Data.NextRetryAt)
+	assert.Equal(t, 3, event.Data.RetryCount)
+	assert.Equal(t, 5, event.Data.MaxRetries)
+	assert.Equal(t, "callback returned 503", event.Data.Error)
+
+	deadLettered := newTask()
+	deadLettered.MarkAsDeadLettered()
+	msg := "Max retries (5) exceeded: callback returned 503"
+	deadLettered.ErrorMessage = &msg
+	event = NewTaskUpdateEvent(deadLettered)
+	assert.Equal(t, EventTaskDeadLettered, event.Type)
+	assert.Equal(t, msg, event.Data.Error)
+	assert.Nil(t, event.Data.NextRetryAt)
+
+	completed := newTask()
+	duration := int64(120)
+	completed.CallbackDurationMs = &duration
+	completed.MarkAsCompleted(now)
+	event = NewTaskUpdateEvent(completed)
+	assert.Equal(t, EventTaskCompleted, event.Type)
+	require.NotNil(t, event.Data.CallbackDurationMs)
+	assert.Equal(t, int64(120), *event.Data.CallbackDurationMs)
+	assert.Empty(t, event.Data.Error)
+
+	event = NewTaskUpdateEvent(newTask())
+	assert.Equal(t, EventTaskUpdated, event.Type, "other statuses stay task_updated")
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "next_retry_at")
+	assert.NotContains(t, string(data), "callback_duration_ms")
+}
+
+func TestBroadcastRespectsClientTenant(t *testing.T) {
+	hub := NewHub(zap.NewNop(), HubConfig{})
+	go hub.Run()
+	defer hub.Stop()
+
+	scoped := newTestClient(hub)
+	scoped.tenantID = "acme"
+	unscoped := newTestClient(hub)
+	hub.register <- scoped
+	hub.register <- unscoped
+
+	hub.BroadcastTaskUpdate(&entity.Task{ID: "t1", TenantID: "globex"})
+	hub.BroadcastTaskUpdate(&entity.Task{ID: "t2", TenantID: "acme"})
+
+	event, ok := receive(t, scoped)
+	require.True(t, ok)
+	assert.Equal(t, "t2", event.Data.TaskID)
+	_, ok = receive(t, scoped)
+	assert.False(t, ok)
+
+	event, ok = receive(t, unscoped)
+	require.True(t, ok)
+	assert.Equal(t, "t1", event.Data.TaskID)
+}
+
+func TestClientFilterUpdates(t *testing.T) {
+	hub := NewHub(zap.NewNop(), HubConfig{})
+	client := newTestClient(hub)
+	event := &Event{Data: EventData{TaskID: "t1", Tags: []string{"ops"}}}
+
+	t.Run("Subscribe replaces previous filter", func(t *testing.T) {
+		require.NoError(t, client.handleMessage([]byte(`{"action":"subscribe","tags":["billing"]}`)))
+		assert.False(t, client.Filter().Matches(event))
+
+		require.NoError(t, client.handleMessage([]byte(`{"action":"subscribe","task_ids":["t1"]}`)))
+		assert.True(t, client.Filter().Matches(event))
+	})
+
+	t.Run("Unsubscribe resets to all events", func(t *testing.T) {
+		require.NoError(t, client.handleMessage([]byte(`{"action":"subscribe","task_ids":["other"]}`)))
+		assert.False(t, client.Filter().Matches(event))
+
+		require.NoError(t, client.handleMessage([]byte(`{"action":"unsubscribe"}`)))
+		assert.Nil(t, client.Filter())
+		assert.True(t, client.Filter().Matches(event))
+	})
+
+	t.Run("Invalid messages are rejected", func(t *testing.T) {
+		assert.Error(t, client.handleMessage([]byte(`not json`)))
+		assert.Error(t, client.handleMessage([]byte(`{"action":"shout"}`)))
+	})
+}
+
+func TestStopClosesClients(t *testing.T) {
+	hub := NewHub(zap.NewNop(), HubConfig{})
+	go hub.Run()
+
+	client := newTestClient(hub)
+	require.True(t, hub.registerClient(client))
+	assert.Equal(t, 1, hub.ClientCount())
+
+	hub.Stop()
+
+	select {
+	case _, ok := <-client.send:
+		assert.False(t, ok, "send channel should be closed")
+	case <-time.After(time.Second):
+		t.Fatal("client was not closed on Stop")
+	}
+	assert.Equal(t, 0, hub.ClientCount())
+	assert.False(t, hub.registerClient(newTestClient(hub)))
+}
+
+func TestClientIDsAreUnique(t *testing.T) {
+	hub := NewHub(zap.NewNop(), HubConfig{})
+	seen := make(map[string]bool, 10000)
+	for i := 0; i < 10000; i++ {
+		id := NewClient(hub, nil, "").ID()
+		require.False(t, seen[id], "duplicate client ID %q", id)
+		seen[id] = true
+	}
+}
+
+// serveHub runs the hub behind a test server, returning the stream URL
+func serveHub(t *testing.T, hub *Hub) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	go hub.Run()
+	t.Cleanup(hub.Stop)
+
+	router := gin.New()
+	router.GET("/stream", ServeWS(hub))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/stream"
+}
+
+func TestServeWSWelcomesClient(t *testing.T) {
+	hub := NewHub(zap.NewNop(), HubConfig{})
+	url := serveHub(t, hub)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	var msg connectedMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, EventConnected, msg.Type)
+
+	require.Eventually(t, func() bool { return hub.ClientCount() == 1 }, time.Second, 10*time.Millisecond)
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	require.Len(t, hub.clients, 1)
+	for client := range hub.clients {
+		assert.Equal(t, client.ID(), msg.Data.ClientID)
+	}
+}
+
+func TestServeWSRefusesClientsOverLimit(t *testing.T) {
+	hub := NewHub(zap.NewNop(), HubConfig{MaxClients: 1})
+	url := serveHub(t, hub)
+
+	first, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int64(1), hub.RejectedCount())
+
+	// A disconnected client frees its slot
+	first.Close()
+	require.Eventually(t, func() bool {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServeWSClosesClientsThatNeverPong(t *testing.T) {
+	hub := NewHub(zap.NewNop(), HubConfig{PingInterval: 45 * time.Millisecond})
+	url := serveHub(t, hub)
+
+	// Pongs are only sent while reading, so this client never answers a ping
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Eventually(t, func() bool { return hub.ClientCount() == 1 }, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return hub.ClientCount() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func TestHubConfigValidate(t *testing.T) {
+	assert.NoError(t, HubConfig{}.Validate())
+	assert.NoError(t, HubConfig{MaxClients: 100, PingInterval: time.Second, ReadLimit: 512}.Validate())
+	assert.Error(t, HubConfig{MaxClients: -1}.Validate())
+	assert.Error(t, HubConfig{PingInterval: -time.Second}.Validate())
+	assert.Error(t, HubConfig{ReadLimit: -1}.Validate())
+	assert.Error(t, HubConfig{BackfillLimit: -1}.Validate())
+}
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://dash.example.com", "https://*.ops.example.com"}
+
+	tests := []struct {
+		name     string
+		origin   string
+		allowed  []string
+		expected bool
+	}{
+		{"No Origin header", "", allowed, true},
+		{"Same host", "http://later.internal:8080", nil, true},
+		{"Other origin by default", "https://evil.example.net", nil, false},
+		{"Exact match", "https://dash.example.com", allowed, true},
+		{"Exact match ignores case", "https://Dash.Example.com", allowed, true},
+		{"Scheme must match", "http://dash.example.com", allowed, false},
+		{"Port must match", "https://dash.example.com:8443", allowed, false},
+		{"Subdomain wildcard", "https://eu.ops.example.com", allowed, true},
+		{"Nested subdomain wildcard", "https://a.eu.ops.example.com", allowed, true},
+		{"Wildcard excludes the bare domain", "https://ops.example.com", allowed, false},
+		{"Wildcard needs a dot boundary", "https://evilops.example.com", allowed, false},
+		{"Any origin", "https://evil.example.net", []string{"*"}, true},
+		{"Null origin", "null", allowed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://later.internal:8080/stream", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			assert.Equal(t, tt.expected, originAllowed(tt.allowed, req))
+		})
+	}
+
+	assert.Error(t, HubConfig{AllowedOrigins: []string{"dash.example.com"}}.Validate())
+	assert.Error(t, HubConfig{AllowedOrigins: []string{"https://dash.*.com"}}.Validate())
+	assert.Error(t, HubConfig{AllowedOrigins: []string{"https://dash.example.com/app"}}.Validate())
+	assert.NoError(t, HubConfig{AllowedOrigins: allowed}.Validate())
+}
+
+func TestServeWSChecksOrigin(t *testing.T) {
+	hub := NewHub(zap.NewNop(), HubConfig{AllowedOrigins: []string{"https://dash.example.com"}})
+	url := serveHub(t, hub)
+
+	header := http.Header{"Origin": {"https://evil.example.net"}}
+	_, resp, err := websocket.DefaultDialer.Dial(url, header)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	header = http.Header{"Origin": {"https://dash.example.com"}, "Sec-WebSocket-Protocol": {"later, api-key.secret"}}
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, Subprotocol, resp.Header.Get("Sec-WebSocket-Protocol"), "the key is never echoed back")
+}
+
+func TestServeWSReplaysMissedEvents(t *testing.T) {
+	store := memory.NewTaskEventRepository()
+	hub := NewHub(zap.NewNop(), HubConfig{BackfillLimit: 2}, WithEventStore(store))
+	url := serveHub(t, hub)
+
+	for _, status := range []entity.TaskStatus{entity.TaskStatusPending, entity.TaskStatusProcessing, entity.TaskStatusCompleted} {
+		task := entity.NewTask("replayed", []byte(`{}`), "https://example.com/callback", time.Now(), 0)
+		task.Status = status
+		hub.Broadcast(NewTaskUpdateEvent(task))
+	}
+	var ids []int64
+	require.Eventually(t, func() bool {
+		records, err := store.ListSince(context.Background(), 0, "", 10)
+		require.NoError(t, err)
+		ids = ids[:0]
+		for _, record := range records {
+			ids = append(ids, record.ID)
+		}
+		return len(ids) == 3
+	}, time.Second, 5*time.Millisecond, "broadcast events are recorded")
+
+	dial := func(query string) (connectedMessage, []Event) {
+		conn, _, err := websocket.DefaultDialer.Dial(url+query, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+		var msg connectedMessage
+		require.NoError(t, conn.ReadJSON(&msg))
+		events := make([]Event, msg.Data.Backfilled)
+		for i := range events {
+			require.NoError(t, conn.ReadJSON(&events[i]))
+		}
+		return msg, events
+	}
+
+	msg, events := dial("?since=" + strconv.FormatInt(ids[0], 10))
+	assert.False(t, msg.Data.BackfillTruncated)
+	require.Len(t, events, 2)
+	assert.Equal(t, ids[1], events[0].ID)
+	assert.Equal(t, entity.TaskStatusProcessing, events[0].Data.Status)
+	assert.Equal(t, ids[2], events[1].ID)
+	assert.Equal(t, EventTaskCompleted, events[1].Type)
+
+	msg, events = dial("?since=0")
+	assert.True(t, msg.Data.BackfillTruncated, "only BackfillLimit events are replayed")
+	require.Len(t, events, 2)
+	assert.Equal(t, ids[0], events[0].ID)
+
+	msg, events = dial("")
+	assert.Zero(t, msg.Data.Backfilled, "nothing is replayed without since")
+	assert.Empty(t, events)
+
+	_, resp, err := websocket.DefaultDialer.Dial(url+"?since=latest", nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// slowEventStore holds every Append until release is closed, signalling entered as each starts
+type slowEventStore struct {
+	repository.TaskEventRepository
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newSlowEventStore() *slowEventStore {
+	return &slowEventStore{
+		TaskEventRepository: memory.NewTaskEventRepository(),
+		entered:             make(chan struct{}, 16),
+		release:             make(chan struct{}),
+	}
+}
+
+func (s *slowEventStore) Append(ctx context.Context, event *entity.TaskEvent) error {
+	select {
+	case s.entered <- struct{}{}:
+	default:
+	}
+	select {
+	case <-s.release:
+		return s.TaskEventRepository.Append(ctx, event)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestBroadcastDoesNotWaitOnEventStore(t *testing.T) {
+	store := newSlowEventStore()
+	hub := NewHub(zap.NewNop(), HubConfig{}, WithEventStore(store))
+	go hub.Run()
+	defer hub.Stop()
+
+	client := newTestClient(hub)
+	hub.register <- client
+
+	hub.BroadcastTaskUpdate(&entity.Task{ID: "t0", Status: entity.TaskStatusCompleted})
+	<-store.entered // The store now holds the first event until released
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i < 3; i++ {
+			hub.BroadcastTaskUpdate(&entity.Task{ID: "t" + strconv.Itoa(i), Status: entity.TaskStatusCompleted})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Broadcast waited for the store to record an event")
+	}
+
+	select {
+	case <-client.send:
+		t.Fatal("events are delivered once recorded")
+	default:
+	}
+	close(store.release)
+	for i := 0; i < 3; i++ {
+		event, ok := receive(t, client)
+		require.True(t, ok)
+		assert.Equal(t, "t"+strconv.Itoa(i), event.Data.TaskID, "events keep their order")
+		assert.NotZero(t, event.ID)
+	}
+}
+
+// stalledBus never finishes publishing an event
+type stalledBus struct {
+	eventbus.Bus
+	published chan *entity.TaskEvent
+}
+
+func (b *stalledBus) Publish(ctx context.Context, event *entity.TaskEvent) error {
+	b.published <- event
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBroadcastDoesNotWaitOnEventBus(t *testing.T) {
+	bus := &stalledBus{Bus: eventbus.NewNop(), published: make(chan *entity.TaskEvent, 3)}
+	store := memory.NewTaskEventRepository()
+	hub := NewHub(zap.NewNop(), HubConfig{}, WithEventStore(store), WithEventBus(bus))
+	go hub.Run()
+	defer hub.Stop()
+
+	client := newTestClient(hub)
+	hub.register <- client
+
+	for i := 0; i < 3; i++ {
+		hub.BroadcastTaskUpdate(&entity.Task{ID: "t" + strconv.Itoa(i), Status: entity.TaskStatusCompleted})
+	}
+	for i := 0; i < 3; i++ {
+		event, ok := receive(t, client)
+		require.True(t, ok, "local clients don't wait for the bus")
+		assert.Equal(t, "t"+strconv.Itoa(i), event.Data.TaskID)
+		assert.NotZero(t, event.ID)
+	}
+
+	record := <-bus.published
+	assert.Equal(t, "t0", record.TaskID, "the recorded event is handed to the bus")
+}
+
+func TestStopRecordsQueuedEvents(t *testing.T) {
+	store := newSlowEventStore()
+	hub := NewHub(zap.NewNop(), HubConfig{}, WithEventStore(store))
+	go hub.Run()
+
+	client := newTestClient(hub)
+	hub.register <- client
+
+	hub.BroadcastTaskUpdate(&entity.Task{ID: "t1", Status: entity.TaskStatusCompleted})
+	<-store.entered
+	hub.BroadcastTaskUpdate(&entity.Task{ID: "t2", Status: entity.TaskStatusCompleted})
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		hub.Stop()
+	}()
+	// Stopping closes the clients; nothing was delivered, since nothing was recorded yet
+	for range client.send {
+		t.Fatal("events are delivered once recorded")
+	}
+	close(store.release)
+	<-stopped
+
+	records, err := store.ListSince(context.Background(), 0, "", 10)
+	require.NoError(t, err)
+	require.Len(t, records, 2, "the event still queued when the hub stopped is recorded")
+	assert.Equal(t, "t1", records[0].TaskID)
+	assert.Equal(t, "t2", records[1].TaskID)
+}
+
+func TestHubsShareEventsOverBus(t *testing.T) {
+	events := memory.NewTaskEventRepository()
+	newHub := func() (*Hub, string) {
+		hub := NewHub(zap.NewNop(), HubConfig{}, WithEventStore(events),
+			WithEventBus(eventbus.NewDatabase(events, 10*time.Millisecond, zap.NewNop())))
+		return hub, serveHub(t, hub)
+	}
+	hubA, urlA := newHub()
+	_, urlB := newHub()
+
+	dial := func(url string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		var msg connectedMessage
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		require.NoError(t, conn.ReadJSON(&msg))
+		return conn
+	}
+	connA, connB := dial(urlA), dial(urlB)
+	require.Eventually(t, func() bool { return hubA.ClientCount() == 1 }, time.Second, 5*time.Millisecond)
+	// Let both buses read the newest event ID before anything is broadcast
+	time.Sleep(30 * time.Millisecond)
+
+	task := entity.NewTask("shared", []byte(`{}`), "https://example.com/callback", time.Now(), 0)
+	hubA.Broadcast(NewTaskEvent(EventTaskCreated, task))
+
+	for name, conn := range map[string]*websocket.Conn{"local": connA, "remote": connB} {
+		var event Event
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		require.NoError(t, conn.ReadJSON(&event), name)
+		assert.Equal(t, task.ID, event.Data.TaskID, name)
+		assert.NotZero(t, event.ID, name)
+	}
+
+	// The hub that broadcast the event skips it when it comes back over the bus
+	require.NoError(t, connA.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, _, err := connA.ReadMessage()
+	assert.Error(t, err, "the event is delivered once")
+}
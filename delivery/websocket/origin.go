@@ -0,0 +1,62 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// validateOrigin checks an allowed origin: "*", or a scheme and host such as
+// "https://dash.example.com", where the host may start with "*." to allow any subdomain
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return fmt.Errorf("invalid WebSocket allowed origin %q: want scheme://host[:port]", origin)
+	}
+	if host := strings.TrimPrefix(u.Host, "*."); strings.Contains(host, "*") {
+		return fmt.Errorf("invalid WebSocket allowed origin %q: only a leading *. wildcard is supported", origin)
+	}
+	return nil
+}
+
+// originAllowed reports whether a browser on the request's Origin may open a socket
+// Requests without an Origin header don't come from a browser page and are allowed, as are
+// pages served from the same host; other origins must match one in allowed
+func originAllowed(allowed []string, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, pattern := range allowed {
+		if pattern == "*" {
+			return true
+		}
+		p, err := url.Parse(pattern)
+		if err != nil || !strings.EqualFold(p.Scheme, u.Scheme) {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(p.Host, "*"); ok {
+			// "*.example.com" matches subdomains at any depth, but not example.com itself
+			if len(u.Host) > len(suffix) && strings.HasSuffix(strings.ToLower(u.Host), strings.ToLower(suffix)) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(p.Host, u.Host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Payload redaction modes: how much of a task's payload its events carry
+const (
+	RedactionNone   = "none"   // The whole payload
+	RedactionOmit   = "omit"   // No payload; the default
+	RedactionFields = "fields" // The payload with the values of the listed keys masked
+)
+
+// RedactedValue replaces the values of the keys masked by RedactionFields
+const RedactedValue = "[REDACTED]"
+
+// PayloadRedaction selects how much of a task's payload its events carry, recorded and replayed
+// events included; clients without payload access never receive payloads
+type PayloadRedaction struct {
+	Mode   string   // RedactionNone, RedactionOmit or RedactionFields; empty means RedactionOmit
+	Fields []string // JSON keys whose values are masked at any depth, for RedactionFields
+}
+
+// Validate checks the mode, and that RedactionFields lists the keys to mask
+func (r PayloadRedaction) Validate() error {
+	switch r.Mode {
+	case "", RedactionNone, RedactionOmit:
+		return nil
+	case RedactionFields:
+		if len(r.Fields) == 0 {
+			return fmt.Errorf("payload redaction %q needs the keys to mask", RedactionFields)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown payload redaction %q: use %s, %s or %s",
+			r.Mode, RedactionNone, RedactionOmit, RedactionFields)
+	}
+}
+
+// WithPayloadRedaction sets how much of a task's payload its events carry; r must be valid
+// Defaults to RedactionOmit
+func WithPayloadRedaction(r PayloadRedaction) HubOption {
+	return func(h *Hub) {
+		h.redaction = r
+	}
+}
+
+// apply returns the part of payload an event may carry, or nil for none
+// Payloads that aren't valid JSON are never included
+func (r PayloadRedaction) apply(payload []byte) json.RawMessage {
+	if len(payload) == 0 || !json.Valid(payload) {
+		return nil
+	}
+	switch r.Mode {
+	case RedactionNone:
+		return json.RawMessage(payload)
+	case RedactionFields:
+		decoder := json.NewDecoder(bytes.NewReader(payload))
+		decoder.UseNumber() // Keep numbers as written
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil
+		}
+		masked, err := json.Marshal(r.mask(value))
+		if err != nil {
+			return nil
+		}
+		return masked
+	default:
+		return nil
+	}
+}
+
+// mask replaces the values of the redacted keys in value and in every object nested in it
+func (r PayloadRedaction) mask(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if slices.Contains(r.Fields, key) {
+				v[key] = RedactedValue
+			} else {
+				v[key] = r.mask(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.mask(item)
+		}
+	}
+	return value
+}
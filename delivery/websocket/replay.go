@@ -0,0 +1,77 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// eventMetadata holds the event fields recorded as a TaskEvent's metadata, besides the task,
+// tenant, status and time it has columns for
+type eventMetadata struct {
+	Name               string     `json:"name,omitempty"`
+	Tags               []string   `json:"tags,omitempty"`
+	Count              int64      `json:"count,omitempty"`
+	NextRetryAt        *time.Time `json:"next_retry_at,omitempty"`
+	RetryCount         int        `json:"retry_count,omitempty"`
+	MaxRetries         int        `json:"max_retries,omitempty"`
+	Error              string     `json:"error,omitempty"`
+	CallbackDurationMs *int64     `json:"callback_duration_ms,omitempty"`
+	DispatchLatencyMs  *int64     `json:"dispatch_latency_ms,omitempty"`
+}
+
+// taskEvent converts the event into the record kept for replay
+func (e *Event) taskEvent() (*entity.TaskEvent, error) {
+	metadata, err := json.Marshal(eventMetadata{
+		Name:               e.Data.Name,
+		Tags:               e.Data.Tags,
+		Count:              e.Data.Count,
+		NextRetryAt:        e.Data.NextRetryAt,
+		RetryCount:         e.Data.RetryCount,
+		MaxRetries:         e.Data.MaxRetries,
+		Error:              e.Data.Error,
+		CallbackDurationMs: e.Data.CallbackDurationMs,
+		DispatchLatencyMs:  e.Data.DispatchLatencyMs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entity.TaskEvent{
+		TaskID:    e.Data.TaskID,
+		TenantID:  e.Data.TenantID,
+		Type:      e.Type,
+		ToStatus:  e.Data.Status,
+		Metadata:  metadata,
+		CreatedAt: e.Data.UpdatedAt,
+	}, nil
+}
+
+// replayedEvent rebuilds the event a client missed from its record
+func replayedEvent(record *entity.TaskEvent) (*Event, error) {
+	var metadata eventMetadata
+	if len(record.Metadata) > 0 {
+		if err := json.Unmarshal(record.Metadata, &metadata); err != nil {
+			return nil, err
+		}
+	}
+	return &Event{
+		ID:   record.ID,
+		Type: record.Type,
+		Data: EventData{
+			TaskID:             record.TaskID,
+			Name:               metadata.Name,
+			Status:             record.ToStatus,
+			Tags:               metadata.Tags,
+			TenantID:           record.TenantID,
+			UpdatedAt:          record.CreatedAt.UTC(),
+			Count:              metadata.Count,
+			NextRetryAt:        metadata.NextRetryAt,
+			RetryCount:         metadata.RetryCount,
+			MaxRetries:         metadata.MaxRetries,
+			Error:              metadata.Error,
+			CallbackDurationMs: metadata.CallbackDurationMs,
+			DispatchLatencyMs:  metadata.DispatchLatencyMs,
+		},
+	}, nil
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HostPause holds the callbacks of every task whose callback URL is on Host, e.g. during a
+// receiver's maintenance window
+type HostPause struct {
+	Host      string     `json:"host" db:"host"`                    // Lowercase hostname, without port
+	Until     *time.Time `json:"until,omitempty" db:"paused_until"` // nil holds callbacks until the host is resumed
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Active reports whether the pause still holds callbacks at now
+func (p *HostPause) Active(now time.Time) bool {
+	return p.Until == nil || now.Before(*p.Until)
+}
+
+// CallbackHost returns the host a callback URL is delivered to, as HostPause.Host names it,
+// or an empty string if the URL can't be parsed
+func CallbackHost(callbackURL string) string {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"errors"
+	"net/url"
+)
+
+// OAuth2Config holds OAuth2 client-credentials settings for callback delivery
+type OAuth2Config struct {
+	TokenURL     string   `json:"token_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// Validate checks that the token URL is absolute and a client ID is set
+func (c *OAuth2Config) Validate() error {
+	u, err := url.Parse(c.TokenURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("oauth2 token_url must be an absolute http(s) URL")
+	}
+	if c.ClientID == "" {
+		return errors.New("oauth2 client_id is required")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Payload encodings, recorded in payload_encoding so stored payloads can be decoded
+const (
+	PayloadEncodingJSON = "json" // Stored as is
+	PayloadEncodingGzip = "gzip" // Gzip-compressed, stored as a base64 JSON string since the column is JSON
+)
+
+// EncodePayload returns the payload as stored with the given encoding
+func EncodePayload(payload JSONBytes, encoding string) (JSONBytes, error) {
+	switch encoding {
+	case "", PayloadEncodingJSON:
+		return payload, nil
+	case PayloadEncodingGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return json.Marshal(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	default:
+		return nil, fmt.Errorf("unsupported payload encoding %q", encoding)
+	}
+}
+
+// DecodePayload reverses EncodePayload
+func DecodePayload(stored JSONBytes, encoding string) (JSONBytes, error) {
+	switch encoding {
+	case "", PayloadEncodingJSON:
+		return stored, nil
+	case PayloadEncodingGzip:
+		var encoded string
+		if err := json.Unmarshal(stored, &encoded); err != nil {
+			return nil, fmt.Errorf("invalid gzip payload: %w", err)
+		}
+		compressed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip payload: %w", err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip payload: %w", err)
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	default:
+		return nil, fmt.Errorf("unsupported payload encoding %q", encoding)
+	}
+}
@@ -0,0 +1,105 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// samplePayloads are typical task payloads of increasing size
+func samplePayloads() map[string]JSONBytes {
+	type item struct {
+		SKU      string  `json:"sku"`
+		Name     string  `json:"name"`
+		Quantity int     `json:"quantity"`
+		Price    float64 `json:"price"`
+	}
+	order := func(items int) JSONBytes {
+		o := map[string]any{
+			"order_id":    12345,
+			"customer_id": "abc-123",
+			"email":       "customer@example.com",
+			"currency":    "USD",
+			"status":      "paid",
+		}
+		lines := make([]item, items)
+		for i := range lines {
+			lines[i] = item{SKU: fmt.Sprintf("SKU-%06d", i), Name: fmt.Sprintf("Product %d", i), Quantity: i%5 + 1, Price: float64(i%100) + 0.99}
+		}
+		o["items"] = lines
+		b, _ := json.Marshal(o)
+		return b
+	}
+
+	return map[string]JSONBytes{
+		"small":  JSONBytes(`{"order_id":12345,"customer_id":"abc-123"}`),
+		"medium": order(20),
+		"large":  order(1000),
+	}
+}
+
+func TestPayloadEncodingRoundTrip(t *testing.T) {
+	for name, payload := range samplePayloads() {
+		for _, encoding := range []string{"", PayloadEncodingJSON, PayloadEncodingGzip} {
+			stored, err := EncodePayload(payload, encoding)
+			if err != nil {
+				t.Fatalf("%s/%q: encode: %v", name, encoding, err)
+			}
+			if !json.Valid(stored) {
+				t.Errorf("%s/%q: stored payload must be valid JSON for the payload column", name, encoding)
+			}
+
+			decoded, err := DecodePayload(stored, encoding)
+			if err != nil {
+				t.Fatalf("%s/%q: decode: %v", name, encoding, err)
+			}
+			if !bytes.Equal(decoded, payload) {
+				t.Errorf("%s/%q: round trip changed the payload", name, encoding)
+			}
+		}
+	}
+
+	if _, err := EncodePayload(JSONBytes(`{}`), "brotli"); err == nil {
+		t.Error("unsupported encoding must be rejected")
+	}
+	if _, err := DecodePayload(JSONBytes(`{"k":"v"}`), PayloadEncodingGzip); err == nil {
+		t.Error("corrupt gzip payload must be rejected")
+	}
+}
+
+// BenchmarkEncodePayloadGzip reports the stored size of gzip-encoded payloads
+// Run with: go test ./domain/entity -bench EncodePayload -run ^$
+func BenchmarkEncodePayloadGzip(b *testing.B) {
+	for _, name := range []string{"small", "medium", "large"} {
+		payload := samplePayloads()[name]
+		b.Run(name, func(b *testing.B) {
+			var stored JSONBytes
+			for i := 0; i < b.N; i++ {
+				var err error
+				if stored, err = EncodePayload(payload, PayloadEncodingGzip); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(payload)), "raw-B")
+			b.ReportMetric(float64(len(stored)), "stored-B")
+			b.ReportMetric(float64(len(stored))/float64(len(payload)), "ratio")
+		})
+	}
+}
+
+func BenchmarkDecodePayloadGzip(b *testing.B) {
+	for _, name := range []string{"small", "medium", "large"} {
+		stored, err := EncodePayload(samplePayloads()[name], PayloadEncodingGzip)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := DecodePayload(stored, PayloadEncodingGzip); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package entity
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Retry jitter bounds, in percent of the delay; jitter stays below 100% so a retry is never due
+// the moment its attempt failed
+const (
+	DefaultRetryJitterPercent = 25
+	MaxRetryJitterPercent     = 90
+)
+
+// DefaultMaxRetryBackoff caps the delay before a retry unless configured otherwise
+const DefaultMaxRetryBackoff = MaxRetryBackoffSecs * time.Second
+
+// RetryPolicy shapes the delays between a task's callback attempts: the task's
+// RetryBackoffSeconds doubles with each failure up to MaxBackoff, and is then moved by a random
+// share of up to JitterPercent either way, so tasks that failed together don't retry together
+type RetryPolicy struct {
+	JitterPercent int
+	MaxBackoff    time.Duration
+}
+
+// DefaultRetryPolicy is the policy tasks are retried under unless configured otherwise
+var DefaultRetryPolicy = RetryPolicy{JitterPercent: DefaultRetryJitterPercent, MaxBackoff: DefaultMaxRetryBackoff}
+
+// Validate checks the jitter and the cap
+func (p RetryPolicy) Validate() error {
+	if p.JitterPercent < 0 || p.JitterPercent > MaxRetryJitterPercent {
+		return fmt.Errorf("retry jitter must be between 0 and %d percent", MaxRetryJitterPercent)
+	}
+	if p.MaxBackoff < time.Second {
+		return fmt.Errorf("max retry backoff must be at least 1s")
+	}
+	return nil
+}
+
+// Backoff returns the delay, before jitter, of the retry following a task's failures-th failed
+// attempt, and whether MaxBackoff shortened it
+func (p RetryPolicy) Backoff(backoffSecs int, failures int) (delay time.Duration, capped bool) {
+	delay = time.Duration(max(backoffSecs, 0)) * time.Second
+	// Doubling stops once past the cap, so large failure counts can't overflow
+	for i := 0; i < failures && delay > 0 && delay <= p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		return p.MaxBackoff, true
+	}
+	return delay, false
+}
+
+// JitterRange returns the shortest and longest delay jitter can turn delay into
+func (p RetryPolicy) JitterRange(delay time.Duration) (lowest, highest time.Duration) {
+	spread := delay * time.Duration(p.JitterPercent) / 100
+	return delay - spread, delay + spread
+}
+
+// NextRetry returns when the task is retried after failing at now, given its failures so far
+func (p RetryPolicy) NextRetry(t *Task, now time.Time) time.Time {
+	delay, _ := p.Backoff(t.RetryBackoffSeconds, t.RetryCount)
+	lowest, highest := p.JitterRange(delay)
+	return now.Add(lowest + time.Duration(rand.Float64()*float64(highest-lowest)))
+}
+
+// ProjectedRetry is when one of a task's remaining retries runs if every attempt before it fails
+type ProjectedRetry struct {
+	Retry    int           // 1 for the first retry
+	Backoff  time.Duration // Delay after the previous attempt, before jitter
+	Capped   bool          // Whether MaxBackoff shortened Backoff
+	At       time.Time     // Without jitter
+	Earliest time.Time     // With every delay so far at its shortest
+	Latest   time.Time     // With every delay so far at its longest
+}
+
+// Schedule projects the task's remaining retries as of now, assuming each attempt fails as soon
+// as it starts; time spent on callbacks and waiting for a worker comes on top. A failed task's
+// pending retry, already jittered, is the first entry. Finished and deleted tasks have none
+func (p RetryPolicy) Schedule(t *Task, now time.Time) []ProjectedRetry {
+	var from time.Time // When the attempt preceding the next projected retry starts
+	var retries []ProjectedRetry
+	next := t.RetryCount + 1
+	switch {
+	case t.IsDeleted():
+		return nil
+	case t.Status == TaskStatusFailed && t.NextRetryAt != nil:
+		from = *t.NextRetryAt
+		backoff, capped := p.Backoff(t.RetryBackoffSeconds, t.RetryCount)
+		retries = append(retries, ProjectedRetry{
+			Retry: t.RetryCount, Backoff: backoff, Capped: capped, At: from, Earliest: from, Latest: from,
+		})
+	case t.Status == TaskStatusPending || t.Status == TaskStatusWaiting:
+		from = t.ScheduledAt
+		if from.Before(now) {
+			from = now
+		}
+	case t.Status == TaskStatusProcessing:
+		from = now
+	default:
+		return nil
+	}
+
+	at, earliest, latest := from, from, from
+	for retry := next; retry <= t.MaxRetries; retry++ {
+		backoff, capped := p.Backoff(t.RetryBackoffSeconds, retry)
+		lowest, highest := p.JitterRange(backoff)
+		at, earliest, latest = at.Add(backoff), earliest.Add(lowest), latest.Add(highest)
+		retries = append(retries, ProjectedRetry{
+			Retry: retry, Backoff: backoff, Capped: capped, At: at, Earliest: earliest, Latest: latest,
+		})
+	}
+	return retries
+}
@@ -0,0 +1,129 @@
+package entity
+
+import (
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// maxProjectedRetries is the highest retry budget tasks can be created with
+const maxProjectedRetries = 20
+
+// arbitraryPolicy maps quick-generated values onto a valid policy and backoff
+func arbitraryPolicy(jitter, maxBackoffSecs, backoffSecs uint32) (RetryPolicy, int) {
+	policy := RetryPolicy{
+		JitterPercent: int(jitter % (MaxRetryJitterPercent + 1)),
+		MaxBackoff:    time.Duration(maxBackoffSecs%(7*MaxRetryBackoffSecs)+1) * time.Second,
+	}
+	return policy, int(backoffSecs%MaxRetryBackoffSecs) + MinRetryBackoffSecs
+}
+
+func TestRetryBackoffGrowsMonotonicallyToTheCap(t *testing.T) {
+	property := func(jitter, maxBackoffSecs, backoffSecs uint32) bool {
+		policy, base := arbitraryPolicy(jitter, maxBackoffSecs, backoffSecs)
+		previous, _ := policy.Backoff(base, 0)
+		wasCapped := false
+		for failures := 1; failures <= maxProjectedRetries; failures++ {
+			delay, capped := policy.Backoff(base, failures)
+			switch {
+			case delay > policy.MaxBackoff:
+				t.Logf("%+v base %ds: retry %d backs off %v, past the cap", policy, base, failures, delay)
+				return false
+			case capped && delay != policy.MaxBackoff, wasCapped && !capped:
+				t.Logf("%+v base %ds: retry %d capped=%v at %v", policy, base, failures, capped, delay)
+				return false
+			case !capped && delay != 2*previous:
+				t.Logf("%+v base %ds: retry %d backs off %v after %v", policy, base, failures, delay, previous)
+				return false
+			}
+			previous, wasCapped = delay, capped
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryJitterStaysWithinPolicy(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	property := func(jitter, maxBackoffSecs, backoffSecs uint32, failures uint8) bool {
+		policy, base := arbitraryPolicy(jitter, maxBackoffSecs, backoffSecs)
+		task := &Task{RetryBackoffSeconds: base, RetryCount: int(failures % (maxProjectedRetries + 1))}
+		delay, _ := policy.Backoff(base, task.RetryCount)
+		lowest, highest := policy.JitterRange(delay)
+		if lowest <= 0 || highest > delay*2 {
+			return false
+		}
+		retryAt := policy.NextRetry(task, now).Sub(now)
+		return retryAt >= lowest && retryAt <= highest
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryScheduleIsOrderedAndBounded(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	property := func(jitter, maxBackoffSecs, backoffSecs uint32, maxRetries, failures uint8) bool {
+		policy, base := arbitraryPolicy(jitter, maxBackoffSecs, backoffSecs)
+		task := &Task{
+			Status:              TaskStatusProcessing,
+			RetryBackoffSeconds: base,
+			MaxRetries:          int(maxRetries % (maxProjectedRetries + 1)),
+		}
+		task.RetryCount = int(failures) % (task.MaxRetries + 1)
+
+		schedule := policy.Schedule(task, now)
+		if len(schedule) != task.MaxRetries-task.RetryCount {
+			return false
+		}
+		previous := ProjectedRetry{At: now, Earliest: now, Latest: now}
+		for _, retry := range schedule {
+			if !retry.Earliest.After(previous.Earliest) || !retry.At.After(previous.At) || !retry.Latest.After(previous.Latest) ||
+				retry.Earliest.After(retry.At) || retry.At.After(retry.Latest) || retry.Backoff > policy.MaxBackoff {
+				t.Logf("%+v task %+v: retry %+v after %+v", policy, task, retry, previous)
+				return false
+			}
+			previous = retry
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryScheduleStartsFromTheTaskState(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := RetryPolicy{JitterPercent: 10, MaxBackoff: 5 * time.Minute}
+	nextRetry := now.Add(90 * time.Second)
+	task := &Task{Status: TaskStatusFailed, RetryCount: 2, MaxRetries: 4, RetryBackoffSeconds: 30, NextRetryAt: &nextRetry}
+
+	schedule := policy.Schedule(task, now)
+	if len(schedule) != 3 {
+		t.Fatalf("got %d retries, expected the pending one and 2 more", len(schedule))
+	}
+	if pending := schedule[0]; pending.Retry != 2 || !pending.At.Equal(nextRetry) || !pending.Earliest.Equal(nextRetry) {
+		t.Errorf("pending retry = %+v, expected retry 2 at %v", pending, nextRetry)
+	}
+	if third := schedule[1]; third.Backoff != 4*time.Minute || !third.At.Equal(nextRetry.Add(4*time.Minute)) ||
+		!third.Earliest.Equal(nextRetry.Add(216*time.Second)) || third.Capped {
+		t.Errorf("retry 3 = %+v, expected 4m after the pending one, give or take 10%%", third)
+	}
+	if fourth := schedule[2]; fourth.Backoff != 5*time.Minute || !fourth.Capped {
+		t.Errorf("retry 4 = %+v, expected capped at 5m", fourth)
+	}
+
+	later := now.Add(time.Hour)
+	pending := &Task{Status: TaskStatusPending, ScheduledAt: later, MaxRetries: 1, RetryBackoffSeconds: 30}
+	if schedule := policy.Schedule(pending, now); len(schedule) != 1 || !schedule[0].At.Equal(later.Add(time.Minute)) {
+		t.Errorf("pending task schedule = %+v, expected one retry a minute after %v", schedule, later)
+	}
+
+	for _, status := range []TaskStatus{TaskStatusCompleted, TaskStatusDeadLettered, TaskStatusExpired} {
+		if schedule := policy.Schedule(&Task{Status: status, MaxRetries: 5}, now); len(schedule) != 0 {
+			t.Errorf("%s task has %d projected retries, expected none", status, len(schedule))
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Tag limits, checked when a task's tags are changed
+const (
+	MaxTags      = 20
+	MaxTagLength = 64
+)
+
+// ValidateTags checks the number of tags and each tag
+func ValidateTags(tags []string) error {
+	if len(tags) > MaxTags {
+		return fmt.Errorf("a task can have at most %d tags, got %d", MaxTags, len(tags))
+	}
+	for _, tag := range tags {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateTag checks that a tag is 1 to MaxTagLength letters, digits or any of "-_.:/", which
+// keeps tags usable in comma-separated filters and CSV exports
+func ValidateTag(tag string) error {
+	if tag == "" || len(tag) > MaxTagLength {
+		return fmt.Errorf("tag %q must be 1 to %d characters", tag, MaxTagLength)
+	}
+	for _, c := range tag {
+		if !validTagChar(c) {
+			return fmt.Errorf("tag %q may only contain letters, digits and \"-_.:/\"", tag)
+		}
+	}
+	return nil
+}
+
+func validTagChar(c rune) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '_' || c == '.' || c == ':' || c == '/'
+}
+
+// EditTags returns tags with add appended, except those already present, and remove taken out
+// Tags both added and removed are removed
+func EditTags(tags, add, remove []string) []string {
+	edited := make([]string, 0, len(tags)+len(add))
+	for _, tag := range slices.Concat(tags, add) {
+		if !slices.Contains(edited, tag) && !slices.Contains(remove, tag) {
+			edited = append(edited, tag)
+		}
+	}
+	return edited
+}
@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		wantErr bool
+	}{
+		{"No tags", nil, false},
+		{"Allowed characters", []string{"investigating", "team:billing", "v1.2", "region/eu-west_1"}, false},
+		{"Longest tag", []string{strings.Repeat("a", MaxTagLength)}, false},
+		{"Empty tag", []string{""}, true},
+		{"Tag too long", []string{strings.Repeat("a", MaxTagLength+1)}, true},
+		{"Space", []string{"on hold"}, true},
+		{"Comma", []string{"a,b"}, true},
+		{"Pipe", []string{"a|b"}, true},
+		{"Too many tags", make([]string, MaxTags+1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTags(tt.tags); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEditTags(t *testing.T) {
+	got := EditTags([]string{"email", "stale", "urgent"}, []string{"investigating", "email", "temp"}, []string{"stale", "temp"})
+	want := []string{"email", "urgent", "investigating"}
+	if !slices.Equal(got, want) {
+		t.Errorf("EditTags() = %v, want %v", got, want)
+	}
+}
@@ -1,7 +1,7 @@
 package entity
 
 import (
-	"math/rand"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,13 +11,62 @@ import (
 type TaskStatus string
 
 const (
+	TaskStatusWaiting      TaskStatus = "waiting" // Blocked until the task it depends on finishes
 	TaskStatusPending      TaskStatus = "pending"
 	TaskStatusProcessing   TaskStatus = "processing"
 	TaskStatusCompleted    TaskStatus = "completed"
 	TaskStatusFailed       TaskStatus = "failed"
 	TaskStatusDeadLettered TaskStatus = "dead_lettered"
+	TaskStatusExpired      TaskStatus = "expired" // Picked up after ExpiresAt, so never delivered
 )
 
+// Callback timeout bounds, in seconds
+const (
+	MinCallbackTimeoutSecs     = 5
+	MaxCallbackTimeoutSecs     = 300
+	DefaultCallbackTimeoutSecs = 30
+)
+
+// Retry backoff bounds, in seconds; the backoff doubles with each retry up to a day
+const (
+	MinRetryBackoffSecs     = 1
+	MaxRetryBackoffSecs     = 24 * 60 * 60
+	DefaultRetryBackoffSecs = 60
+)
+
+// MaxPayloadSize is the default limit on payload size, and the limit on a rendered callback body, in bytes
+const MaxPayloadSize = 1024 * 1024
+
+// MaxTaskIDLength matches the id column
+const MaxTaskIDLength = 64
+
+// MaxConcurrencyKeyLength matches the concurrency_key column
+const MaxConcurrencyKeyLength = 255
+
+// MaxErrorHistory is how many failures a task's ErrorHistory keeps
+const MaxErrorHistory = 10
+
+// ErrorRecord is one failed attempt of a task
+type ErrorRecord struct {
+	At         time.Time `json:"at"`
+	RetryCount int       `json:"retry_count"` // Retries made before the failed attempt
+	Error      string    `json:"error"`
+}
+
+// DependencyFailurePolicy decides what happens to a waiting task when the task it depends on
+// is dead-lettered, expired or cancelled
+type DependencyFailurePolicy string
+
+const (
+	DependencyFailureDeadLetter DependencyFailurePolicy = "dead_letter" // Dead-letter the dependent task too (default)
+	DependencyFailureRunAnyway  DependencyFailurePolicy = "run_anyway"  // Run the dependent task regardless
+)
+
+// Valid returns true if the policy is known; empty selects the default
+func (p DependencyFailurePolicy) Valid() bool {
+	return p == "" || p == DependencyFailureDeadLetter || p == DependencyFailureRunAnyway
+}
+
 // Task represents an asynchronous task with callback delivery
 type Task struct {
 	ID        string     `json:"id" db:"id"`
@@ -26,12 +75,22 @@ type Task struct {
 	CallbackURL string   `json:"callback_url" db:"callback_url"`
 	Status    TaskStatus `json:"status" db:"status"`
 
+	// PayloadEncoding is how Payload is stored (see PayloadEncodingGzip); Payload itself is always decoded
+	PayloadEncoding string `json:"-" db:"payload_encoding"`
+
+	// PayloadEncrypted is true while Payload holds ciphertext; the task service decrypts it on read
+	PayloadEncrypted bool `json:"-" db:"payload_encrypted"`
+
 	// Timing
 	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
 	ScheduledAt time.Time      `json:"scheduled_at" db:"scheduled_at"`
 	StartedAt   *time.Time     `json:"started_at,omitempty" db:"started_at"`
 	CompletedAt *time.Time     `json:"completed_at,omitempty" db:"completed_at"`
 
+	// DispatchLatencyMs is how late the last attempt started after it was due: after ScheduledAt,
+	// or NextRetryAt for a retry; nil until the task is first started
+	DispatchLatencyMs *int64 `json:"dispatch_latency_ms,omitempty" db:"dispatch_latency_ms"`
+
 	// Retry configuration
 	MaxRetries          int        `json:"max_retries" db:"max_retries"`
 	RetryCount          int        `json:"retry_count" db:"retry_count"`
@@ -39,17 +98,54 @@ type Task struct {
 	NextRetryAt         *time.Time `json:"next_retry_at,omitempty" db:"next_retry_at"`
 
 	// Callback tracking
-	CallbackAttempts    int        `json:"callback_attempts" db:"callback_attempts"`
-	CallbackTimeoutSecs int        `json:"callback_timeout_seconds" db:"callback_timeout_seconds"`
-	LastCallbackAt      *time.Time `json:"last_callback_at,omitempty" db:"last_callback_at"`
-	LastCallbackStatus  *int       `json:"last_callback_status,omitempty" db:"last_callback_status"`
-	LastCallbackError   *string    `json:"last_callback_error,omitempty" db:"last_callback_error"`
+	CallbackAttempts     int        `json:"callback_attempts" db:"callback_attempts"`
+	CallbackTimeoutSecs  int        `json:"callback_timeout_seconds" db:"callback_timeout_seconds"`
+	LastCallbackAt       *time.Time `json:"last_callback_at,omitempty" db:"last_callback_at"`
+	LastCallbackStatus   *int       `json:"last_callback_status,omitempty" db:"last_callback_status"`
+	LastCallbackError    *string    `json:"last_callback_error,omitempty" db:"last_callback_error"`
+	LastCallbackResponse *string    `json:"last_callback_response,omitempty" db:"last_callback_response"` // Truncated response body
+
+	// CallbackDurationMs is how long the last callback request took to respond; nil until one was sent
+	CallbackDurationMs *int64 `json:"callback_duration_ms,omitempty" db:"callback_duration_ms"`
+
+	// ErrorHistory holds the task's latest failures, oldest first, up to MaxErrorHistory
+	// Unlike ErrorMessage it survives later failures, so it shows what led to a dead letter
+	ErrorHistory []ErrorRecord `json:"error_history,omitempty" db:"error_history"`
+
+	// RetryableStatusCodes overrides the service's retryable response codes; nil inherits them
+	RetryableStatusCodes []int `json:"retryable_status_codes,omitempty" db:"retryable_status_codes"`
+
+	// FollowRedirects lets the callback follow redirects, resending the request with its body,
+	// however few the service follows for other tasks
+	FollowRedirects bool `json:"follow_redirects,omitempty" db:"follow_redirects"`
+
+	// CallbackBodyTemplate renders the callback body from the task (text/template); nil sends the payload as is
+	CallbackBodyTemplate *string `json:"callback_body_template,omitempty" db:"callback_body_template"`
+
+	// ConcurrencyKey groups tasks whose callbacks share a concurrency limit; nil uses the first tag
+	ConcurrencyKey *string `json:"concurrency_key,omitempty" db:"concurrency_key"`
+
+	// DependsOn is the parent task this one waits for; it stays waiting until the parent completes
+	DependsOn *string `json:"depends_on,omitempty" db:"depends_on"`
+
+	// DependencyFailurePolicy applies when the parent is dead-lettered or cancelled
+	DependencyFailurePolicy DependencyFailurePolicy `json:"dependency_failure_policy,omitempty" db:"dependency_failure_policy"`
+
+	// RequestID is the X-Request-ID of the API request that created the task; nil for tasks created outside a request
+	RequestID *string `json:"request_id,omitempty" db:"request_id"`
+
+	// ExpiresAt is when the task goes stale; a task picked up after it is expired instead of delivered
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+
+	// CallbackOAuth2 overrides the instance OAuth2 settings; never serialized since it holds a secret
+	CallbackOAuth2 *OAuth2Config `json:"-" db:"callback_oauth2"`
 
 	// Metadata
 	Priority      int      `json:"priority" db:"priority"` // 0-10, higher is more urgent
 	Tags          []string `json:"tags,omitempty" db:"tags"`
 	ErrorMessage  *string  `json:"error_message,omitempty" db:"error_message"`
 	WorkerID      string   `json:"worker_id,omitempty" db:"worker_id"`
+	TenantID      string   `json:"tenant_id,omitempty" db:"tenant_id"`
 
 	// Soft delete
 	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
@@ -68,82 +164,157 @@ func NewTask(name string, payload []byte, callbackURL string, scheduledAt time.T
 		ScheduledAt:           scheduledAt,
 		MaxRetries:           5,
 		RetryCount:           0,
-		RetryBackoffSeconds:  60,
+		RetryBackoffSeconds:  DefaultRetryBackoffSecs,
 		CallbackTimeoutSecs:  30,
 		Priority:             priority,
 	}
 }
 
-// CanRetry returns true if the task can be retried
-func (t *Task) CanRetry() bool {
-	return t.RetryCount < t.MaxRetries && t.Status == TaskStatusFailed
+// ValidCallbackTimeout returns true if CallbackTimeoutSecs is within the allowed range
+func (t *Task) ValidCallbackTimeout() bool {
+	return t.CallbackTimeoutSecs >= MinCallbackTimeoutSecs && t.CallbackTimeoutSecs <= MaxCallbackTimeoutSecs
+}
+
+// ValidRetryBackoff returns true if RetryBackoffSeconds is within the allowed range
+func (t *Task) ValidRetryBackoff() bool {
+	return t.RetryBackoffSeconds >= MinRetryBackoffSecs && t.RetryBackoffSeconds <= MaxRetryBackoffSecs
 }
 
-// ShouldExecuteNow returns true if the task is scheduled for immediate execution
-func (t *Task) ShouldExecuteNow() bool {
-	return t.ScheduledAt.Before(time.Now().Add(1 * time.Second))
+// ValidRetryableStatusCodes returns true if every retryable status code is a valid HTTP status
+func (t *Task) ValidRetryableStatusCodes() bool {
+	return ValidStatusCodes(t.RetryableStatusCodes)
 }
 
-// CalculateNextRetry calculates the next retry time with exponential backoff
-func (t *Task) CalculateNextRetry() time.Time {
-	backoff := t.RetryBackoffSeconds * (1 << t.RetryCount) // Exponential: 60, 120, 240, 480...
-	maxBackoff := 24 * 60 * 60 // 24 hours max
+// ValidStatusCodes returns true if every code is in the 100-599 HTTP status range
+func ValidStatusCodes(codes []int) bool {
+	for _, code := range codes {
+		if code < 100 || code > 599 {
+			return false
+		}
+	}
+	return true
+}
 
-	if backoff > maxBackoff {
-		backoff = maxBackoff
+// ConcurrencyGroup returns the key limiting how many of these tasks run at once:
+// the concurrency key, or the first tag when none is set
+func (t *Task) ConcurrencyGroup() string {
+	if t.ConcurrencyKey != nil {
+		return *t.ConcurrencyKey
+	}
+	if len(t.Tags) > 0 {
+		return t.Tags[0]
 	}
+	return ""
+}
+
+// CanRetry returns true if the task can be retried
+func (t *Task) CanRetry() bool {
+	return t.RetryCount < t.MaxRetries && t.Status == TaskStatusFailed
+}
 
-	// Add jitter (±25%)
-	jitter := int(float64(backoff) * 0.25 * (rand.Float64()*2 - 1))
-	backoff += jitter
+// ShouldExecuteNow returns true if the task is scheduled for immediate execution as of now
+func (t *Task) ShouldExecuteNow(now time.Time) bool {
+	return t.ScheduledAt.Before(now.Add(1 * time.Second))
+}
 
-	return time.Now().Add(time.Duration(backoff) * time.Second)
+// CalculateNextRetry calculates the next retry time after now with exponential backoff under
+// DefaultRetryPolicy
+func (t *Task) CalculateNextRetry(now time.Time) time.Time {
+	return DefaultRetryPolicy.NextRetry(t, now)
 }
 
-// MarkAsProcessing transitions task to processing status
-func (t *Task) MarkAsProcessing(workerID string) {
+// MarkAsProcessing transitions task to processing status, started at now
+func (t *Task) MarkAsProcessing(workerID string, now time.Time) {
+	due := t.ScheduledAt
+	if t.NextRetryAt != nil {
+		due = *t.NextRetryAt
+	}
+
 	t.Status = TaskStatusProcessing
-	now := time.Now()
 	t.StartedAt = &now
+
+	// Tasks run ahead of time, e.g. on demand, weren't late
+	latency := max(now.Sub(due), 0).Milliseconds()
+	t.DispatchLatencyMs = &latency
 }
 
-// MarkAsCompleted transitions task to completed status
-func (t *Task) MarkAsCompleted() {
+// MarkAsCompleted transitions task to completed status, completed at now
+func (t *Task) MarkAsCompleted(now time.Time) {
 	t.Status = TaskStatusCompleted
-	now := time.Now()
 	t.CompletedAt = &now
 }
 
-// MarkAsFailed transitions task to failed status with error message
-func (t *Task) MarkAsFailed(err error) {
+// MarkAsFailed transitions task to failed status with error message, failed at now, and
+// schedules its retry under DefaultRetryPolicy
+func (t *Task) MarkAsFailed(err error, now time.Time) {
+	t.MarkAsFailedWithPolicy(err, now, DefaultRetryPolicy)
+}
+
+// MarkAsFailedWithPolicy transitions task to failed status with error message, failed at now,
+// and schedules its retry under the policy
+func (t *Task) MarkAsFailedWithPolicy(err error, now time.Time, policy RetryPolicy) {
 	t.Status = TaskStatusFailed
+	t.RecordError(err, now)
 	t.RetryCount++
 	if err != nil {
 		errMsg := err.Error()
 		t.ErrorMessage = &errMsg
 	}
 
-	nextRetry := t.CalculateNextRetry()
+	nextRetry := policy.NextRetry(t, now)
 	t.NextRetryAt = &nextRetry
 }
 
+// RecordError appends a failure at now to ErrorHistory, dropping the oldest entries beyond MaxErrorHistory
+func (t *Task) RecordError(err error, now time.Time) {
+	if err == nil {
+		return
+	}
+	t.ErrorHistory = append(t.ErrorHistory, ErrorRecord{
+		At:         now.UTC(),
+		RetryCount: t.RetryCount,
+		Error:      err.Error(),
+	})
+	if n := len(t.ErrorHistory); n > MaxErrorHistory {
+		t.ErrorHistory = slices.Clone(t.ErrorHistory[n-MaxErrorHistory:])
+	}
+}
+
 // MarkAsDeadLettered transitions task to dead_lettered status
 func (t *Task) MarkAsDeadLettered() {
 	t.Status = TaskStatusDeadLettered
 }
 
+// IsExpired returns true if the task has an expiry and it has passed
+func (t *Task) IsExpired(now time.Time) bool {
+	return t.ExpiresAt != nil && now.After(*t.ExpiresAt)
+}
+
+// MarkAsExpired transitions task to expired status, expired at now
+func (t *Task) MarkAsExpired(now time.Time) {
+	t.Status = TaskStatusExpired
+	t.CompletedAt = &now
+	errMsg := "task expired before delivery"
+	t.ErrorMessage = &errMsg
+}
+
 // IsHighPriority returns true if task priority is greater than 5
 func (t *Task) IsHighPriority() bool {
 	return t.Priority > 5
 }
 
 // CanBeDeleted returns true if the task can be soft deleted
-// Only pending and failed tasks can be deleted
+// Only waiting, pending and failed tasks can be deleted
 func (t *Task) CanBeDeleted() bool {
-	return (t.Status == TaskStatusPending || t.Status == TaskStatusFailed) &&
+	return (t.Status == TaskStatusWaiting || t.Status == TaskStatusPending || t.Status == TaskStatusFailed) &&
 		t.DeletedAt == nil
 }
 
+// RunsDespiteFailedDependency returns true if the task should run when its parent fails
+func (t *Task) RunsDespiteFailedDependency() bool {
+	return t.DependencyFailurePolicy == DependencyFailureRunAnyway
+}
+
 // IsDeleted returns true if the task has been soft deleted
 func (t *Task) IsDeleted() bool {
 	return t.DeletedAt != nil
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TaskEvent is a recorded task event, kept so clients can replay the events they missed and
+// follow a task's status changes over time
+type TaskEvent struct {
+	ID         int64           `json:"id" db:"id"`
+	TaskID     string          `json:"task_id,omitempty" db:"task_id"` // Empty for bulk operation summaries
+	TenantID   string          `json:"tenant_id,omitempty" db:"tenant_id"`
+	Type       string          `json:"type" db:"event_type"`
+	FromStatus TaskStatus      `json:"from_status,omitempty" db:"from_status"` // Status in the task's previous event; empty for its first
+	ToStatus   TaskStatus      `json:"to_status,omitempty" db:"to_status"`
+	Metadata   json.RawMessage `json:"metadata,omitempty" db:"metadata"` // The event's other details, as sent to clients
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	Origin     string          `json:"origin,omitempty" db:"origin"` // Instance that broadcast the event, so it isn't fanned out back to it
+}
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"fmt"
 	"testing"
 	"time"
 )
@@ -21,6 +22,11 @@ func TestCanBeDeleted(t *testing.T) {
 			task:     &Task{Status: TaskStatusFailed, DeletedAt: nil},
 			expected: true,
 		},
+		{
+			name:     "Waiting task can be deleted",
+			task:     &Task{Status: TaskStatusWaiting, DeletedAt: nil},
+			expected: true,
+		},
 		{
 			name:     "Processing task cannot be deleted",
 			task:     &Task{Status: TaskStatusProcessing, DeletedAt: nil},
@@ -171,3 +177,167 @@ func TestIsHighPriority(t *testing.T) {
 		})
 	}
 }
+
+func TestIsExpired(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Second)
+	future := now.Add(time.Second)
+
+	tests := []struct {
+		name     string
+		task     *Task
+		expected bool
+	}{
+		{
+			name:     "Task without expiry never expires",
+			task:     &Task{},
+			expected: false,
+		},
+		{
+			name:     "Task past its expiry is expired",
+			task:     &Task{ExpiresAt: &past},
+			expected: true,
+		},
+		{
+			name:     "Task before its expiry is not expired",
+			task:     &Task{ExpiresAt: &future},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.task.IsExpired(now)
+			if result != tt.expected {
+				t.Errorf("IsExpired() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConcurrencyGroup(t *testing.T) {
+	key := "tenant-1"
+	tests := []struct {
+		name     string
+		task     *Task
+		expected string
+	}{
+		{
+			name:     "Concurrency key wins over tags",
+			task:     &Task{ConcurrencyKey: &key, Tags: []string{"email"}},
+			expected: "tenant-1",
+		},
+		{
+			name:     "First tag is the default",
+			task:     &Task{Tags: []string{"email", "welcome"}},
+			expected: "email",
+		},
+		{
+			name:     "Untagged tasks share the empty key",
+			task:     &Task{},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.task.ConcurrencyGroup()
+			if result != tt.expected {
+				t.Errorf("ConcurrencyGroup() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMarkAsProcessingDispatchLatency(t *testing.T) {
+	now := time.Now()
+	retryAt := now.Add(-3 * time.Second)
+
+	tests := []struct {
+		name     string
+		task     *Task
+		expected int64 // Milliseconds
+	}{
+		{
+			name:     "Latency is measured from the scheduled time",
+			task:     &Task{ScheduledAt: now.Add(-2 * time.Second)},
+			expected: 2000,
+		},
+		{
+			name:     "Retries are measured from their retry time",
+			task:     &Task{ScheduledAt: now.Add(-time.Hour), NextRetryAt: &retryAt},
+			expected: 3000,
+		},
+		{
+			name:     "Tasks started early have no latency",
+			task:     &Task{ScheduledAt: now.Add(time.Hour)},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.task.MarkAsProcessing("worker-1", now)
+			latency := tt.task.DispatchLatencyMs
+			if latency == nil || *latency != tt.expected {
+				t.Errorf("DispatchLatencyMs = %v, expected %d", latency, tt.expected)
+			}
+			if tt.task.StartedAt == nil || !tt.task.StartedAt.Equal(now) {
+				t.Errorf("StartedAt = %v, expected %v", tt.task.StartedAt, now)
+			}
+		})
+	}
+}
+
+func TestRecordErrorKeepsLatestFailures(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	task := &Task{MaxRetries: MaxErrorHistory + 5}
+	for i := 0; i < MaxErrorHistory+2; i++ {
+		task.MarkAsFailed(fmt.Errorf("attempt %d", i), start.Add(time.Duration(i)*time.Minute))
+	}
+	task.RecordError(nil, start)
+
+	if len(task.ErrorHistory) != MaxErrorHistory {
+		t.Fatalf("len(ErrorHistory) = %d, expected %d", len(task.ErrorHistory), MaxErrorHistory)
+	}
+	first, last := task.ErrorHistory[0], task.ErrorHistory[MaxErrorHistory-1]
+	if first.Error != "attempt 2" || first.RetryCount != 2 {
+		t.Errorf("oldest entry = %+v, expected attempt 2", first)
+	}
+	if last.Error != fmt.Sprintf("attempt %d", MaxErrorHistory+1) || last.RetryCount != MaxErrorHistory+1 {
+		t.Errorf("latest entry = %+v, expected attempt %d", last, MaxErrorHistory+1)
+	}
+	if want := start.Add(time.Duration(MaxErrorHistory+1) * time.Minute); !last.At.Equal(want) {
+		t.Errorf("latest entry at %v, expected %v", last.At, want)
+	}
+}
+
+func TestCalculateNextRetryBackoff(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	task := &Task{RetryBackoffSeconds: 60}
+
+	// The backoff doubles with every retry up to the cap, give or take 25% jitter
+	for retry := 0; retry <= 12; retry++ {
+		task.RetryCount = retry
+		base := min(60<<retry, MaxRetryBackoffSecs)
+		delay := task.CalculateNextRetry(now).Sub(now)
+		lowest := time.Duration(base) * time.Second * 3 / 4
+		highest := time.Duration(base) * time.Second * 5 / 4
+		if delay < lowest || delay > highest {
+			t.Errorf("retry %d backs off %v, expected between %v and %v", retry, delay, lowest, highest)
+		}
+	}
+}
+
+func TestMarkAsFailedSchedulesRetry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	task := &Task{Status: TaskStatusProcessing, MaxRetries: 3, RetryBackoffSeconds: 10}
+
+	task.MarkAsFailed(fmt.Errorf("connection refused"), now)
+	if task.Status != TaskStatusFailed || task.RetryCount != 1 {
+		t.Fatalf("status = %s, retry count = %d, expected failed with 1 retry", task.Status, task.RetryCount)
+	}
+	if task.NextRetryAt == nil || task.NextRetryAt.Before(now.Add(15*time.Second)) || task.NextRetryAt.After(now.Add(25*time.Second)) {
+		t.Errorf("NextRetryAt = %v, expected 20s after %v give or take 25%%", task.NextRetryAt, now)
+	}
+}
@@ -20,4 +20,7 @@ var (
 
 	// ErrTaskCannotRetry is thrown when a task cannot be retried
 	ErrTaskCannotRetry = errors.New("task cannot be retried")
+
+	// ErrQueueFull is thrown when a task is refused because too many tasks are pending
+	ErrQueueFull = errors.New("too many pending tasks")
 )
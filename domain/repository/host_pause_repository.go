@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// HostPauseRepository stores the callback hosts whose deliveries are on hold, so pauses outlive
+// restarts and apply on every replica
+type HostPauseRepository interface {
+	// Pause stores the pause, replacing the host's previous one
+	Pause(ctx context.Context, pause *entity.HostPause) error
+
+	// Resume removes the host's pause; it returns domain.ErrNotFound if the host isn't paused
+	Resume(ctx context.Context, host string) error
+
+	// List returns the stored pauses by host, including those that have ended
+	List(ctx context.Context) ([]*entity.HostPause, error)
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// LeaseRepository grants named, time-limited leases that at most one holder owns at a time
+// Replicas use it to elect a single scheduler leader
+type LeaseRepository interface {
+	// Acquire takes the lease for holder if it is free, expired or already held by holder,
+	// extending it to ttl from now; it reports whether holder owns the lease afterwards
+	Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+
+	// Release gives up the lease if holder owns it, so another holder can take it immediately
+	Release(ctx context.Context, name, holder string) error
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// TaskEventRepository records task events for replay and task timelines
+type TaskEventRepository interface {
+	// Append stores the event and sets its ID, which increases with every event; a task's event
+	// also gets its FromStatus from the ToStatus of the task's previous event
+	Append(ctx context.Context, event *entity.TaskEvent) error
+
+	// ListByTask returns up to limit of the task's events, oldest first
+	ListByTask(ctx context.Context, taskID string, limit int) ([]*entity.TaskEvent, error)
+
+	// ListByTasks returns the events of all the given tasks, oldest first
+	ListByTasks(ctx context.Context, taskIDs []string) ([]*entity.TaskEvent, error)
+
+	// ListSince returns up to limit events with IDs above afterID, oldest first; a non-empty
+	// tenantID restricts them to that tenant's
+	ListSince(ctx context.Context, afterID int64, tenantID string, limit int) ([]*entity.TaskEvent, error)
+
+	// LastID returns the ID of the newest event, or zero if there are none
+	LastID(ctx context.Context) (int64, error)
+
+	// DeleteBefore deletes the events recorded before cutoff and returns how many it deleted
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
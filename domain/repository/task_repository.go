@@ -13,34 +13,231 @@ import (
 type TaskRepository interface {
 	Create(ctx context.Context, task *entity.Task) error
 
+	// CreateBatch inserts the tasks in a single statement; either all of them are created or none
+	CreateBatch(ctx context.Context, tasks []*entity.Task) error
+
+	// ExistingIDs returns which of the IDs are taken, including by soft-deleted tasks,
+	// regardless of tenant
+	ExistingIDs(ctx context.Context, ids []string) ([]string, error)
+
+	// FindByID returns domain.ErrNotFound when no live task has the ID, and other errors only
+	// when the lookup itself failed
+	// FindByID returns domain.ErrNotFound when no live task has the ID, and other errors only
+	// when the lookup itself failed
 	FindByID(ctx context.Context, id string) (*entity.Task, error)
 
-	FindDueTasks(ctx context.Context, minPriority int, limit int) ([]*entity.Task, error)
+	// FindDueTasks returns up to limit due pending tasks with a priority above minPriority, or
+	// of any priority when it is -1, most urgent first; tasks whose concurrency group (see
+	// Task.ConcurrencyGroup) is in skipGroups are left out, so saturated groups can't crowd out
+	// the others
+	FindDueTasks(ctx context.Context, minPriority int, limit int, skipGroups []string) ([]*entity.Task, error)
 
 	FindPendingTasks(ctx context.Context, limit int) ([]*entity.Task, error)
 
 	FindFailedTasks(ctx context.Context, limit int) ([]*entity.Task, error)
 
+	// FindUpcomingTasks returns pending tasks that become due after now and no later than before,
+	// soonest first, so the scheduler can dispatch them on time from memory
+	FindUpcomingTasks(ctx context.Context, before time.Time, limit int) ([]*ScheduledTask, error)
+
+	// LatestCreatedID returns the ID of the most recently created task, or "" if there are none
+	// It is cheap enough to poll frequently for new tasks created by other replicas
+	LatestCreatedID(ctx context.Context) (string, error)
+
 	Update(ctx context.Context, task *entity.Task) error
 
+	// UpdateIfStatus updates the task only if it isn't deleted and its stored status is one of
+	// from, reporting whether it did; it is how a task is claimed without racing other claims
+	UpdateIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error)
+
+	// UpdateTags replaces the tags of a task that isn't deleted; Update leaves tags as created
+	UpdateTags(ctx context.Context, taskID string, tags []string) error
+
 	SoftDelete(ctx context.Context, taskID string, deletedBy string) error
 
+	// CountBulk returns how many tasks a bulk operation with the filter would affect
+	CountBulk(ctx context.Context, filter BulkFilter) (int64, error)
+
+	// BulkSoftDelete soft deletes the tasks matching the filter and returns their IDs
+	BulkSoftDelete(ctx context.Context, filter BulkFilter, deletedBy string) ([]string, error)
+
+	// BulkRetry resets the tasks matching the filter to pending and returns their IDs
+	BulkRetry(ctx context.Context, filter BulkFilter) ([]string, error)
+
 	List(ctx context.Context, filter TaskFilter) ([]*entity.Task, int64, error)
 
+	// ListStream calls fn for each task matching the filter, one row at a time, instead of loading
+	// them all. A positive Limit caps the rows visited, and with it a Page above 1 skips the earlier
+	// pages. An error from fn or the context's cancellation stops the scan and is returned
+	ListStream(ctx context.Context, filter TaskFilter, fn func(*entity.Task) error) error
+
 	CountByStatus(ctx context.Context) (map[entity.TaskStatus]int64, error)
 
-	CleanupExpiredData(ctx context.Context) (int64, error)
+	// StatsSummary summarizes task activity since the given time
+	StatsSummary(ctx context.Context, since time.Time) (*StatsSummary, error)
+
+	CountByTimeBucket(ctx context.Context, since time.Time, bucket time.Duration) ([]*TimeBucketCounts, error)
+
+	// StatsByGroup counts the tasks created since the given time per task name or tag, largest
+	// groups first and at most limit of them. A task counts once towards each of its tags
+	StatsByGroup(ctx context.Context, group StatsGroup, since time.Time, limit int) ([]*GroupStats, error)
+
+	// CountByCallbackHost counts the pending and failed tasks whose callback URL is on each of the
+	// hosts, named as by entity.CallbackHost; hosts without any are left out
+	CountByCallbackHost(ctx context.Context, hosts []string) (map[string]int64, error)
+
+	CleanupExpiredData(ctx context.Context, policy RetentionPolicy) (*CleanupResult, error)
+
+	// CleanupTasks deletes the finished tasks the cleanup selects, or only counts them in a dry run
+	CleanupTasks(ctx context.Context, cleanup Cleanup) (*CleanupResult, error)
 }
 
 // TaskFilter defines filtering options for listing tasks
+// Tenant scoping from the context (domain.WithTenant) is always applied in addition
 type TaskFilter struct {
-	Status    *entity.TaskStatus
-	Priority  *int
-	Tags      []string
-	DateFrom  *time.Time
-	DateTo    *time.Time
-	Page      int
-	Limit     int
-	SortBy    string // "created_at", "scheduled_at", "priority"
-	SortOrder string // "asc", "desc"
+	TenantID      *string // Explicit tenant filter for unscoped (admin) callers
+	Status        *entity.TaskStatus
+	DependsOn     *string  // Tasks depending on this parent task ID
+	ParentIDs     []string // Tasks depending on any of these task IDs
+	Priority      *int
+	Tags          []string
+	Name          string     // Exact name match
+	NamePrefix    string     // Name starts with this prefix
+	DateFrom      *time.Time // Created at or after
+	DateTo        *time.Time // Created at or before
+	ScheduledFrom *time.Time // Scheduled at or after
+	ScheduledTo   *time.Time // Scheduled at or before
+	Page          int
+	Limit         int
+	SortBy        string // "created_at", "scheduled_at", "priority"
+	SortOrder     string // "asc", "desc"
+}
+
+// BulkFilter selects the tasks of a bulk operation
+// Every set condition must match; tenant scoping from the context is always applied in addition
+type BulkFilter struct {
+	IDs      []string
+	Statuses []entity.TaskStatus // Statuses the operation applies to; required
+	Tag      string
+	DateFrom *time.Time // Created at or after
+	DateTo   *time.Time // Created at or before
+	Limit    int        // Maximum tasks affected, oldest first; required
+}
+
+// ScheduledTask identifies a pending task and when it becomes due
+type ScheduledTask struct {
+	ID          string    `db:"id"`
+	ScheduledAt time.Time `db:"scheduled_at"`
+}
+
+// StatsSummary holds task activity since a point in time
+type StatsSummary struct {
+	Created      int64 // Created in the window
+	Completed    int64 // Completed in the window
+	Failed       int64 // Failed and awaiting retry, last attempted in the window
+	DeadLettered int64 // Out of retries, last attempted in the window
+	Expired      int64 // Picked up past their expiry in the window
+
+	// Mean callback attempts of the tasks completed or dead-lettered in the window
+	AvgCallbackAttempts float64
+
+	// Nearest-rank percentiles of the time from creation to completion of the tasks
+	// completed in the window; zero when none were
+	P50CompletionLatencyMs float64
+	P95CompletionLatencyMs float64
+
+	// Nearest-rank percentiles of how late tasks started in the window were picked up,
+	// and of how long callbacks made in the window took; zero when there were none
+	P50DispatchLatencyMs  float64
+	P95DispatchLatencyMs  float64
+	P50CallbackDurationMs float64
+	P95CallbackDurationMs float64
+}
+
+// TimeBucketCounts holds task activity counts for one time bucket
+// Buckets are aligned to multiples of the bucket size since the Unix epoch
+type TimeBucketCounts struct {
+	Start                time.Time
+	Created              int64
+	Completed            int64
+	Failed               int64
+	DeadLettered         int64
+	AvgCallbackLatencyMs float64 // Mean time from pickup to last callback for completed tasks
+}
+
+// StatsGroup is what StatsByGroup groups tasks by
+type StatsGroup string
+
+const (
+	StatsGroupName StatsGroup = "name"
+	StatsGroupTag  StatsGroup = "tag"
+)
+
+// GroupStats holds the counts of one group of tasks
+type GroupStats struct {
+	Key      string // The task name or tag
+	Total    int64
+	ByStatus map[entity.TaskStatus]int64 // Only the statuses present
+
+	// Mean callback attempts of the group's completed and dead-lettered tasks
+	AvgCallbackAttempts float64
+}
+
+// RetentionPolicy controls how long finished tasks are kept before cleanup
+// A zero retention keeps tasks of that status forever
+type RetentionPolicy struct {
+	CompletedRetention    time.Duration
+	DeadLetteredRetention time.Duration
+	Archive               bool // Copy rows into task_queue_archive before deleting
+
+	// BeforeDelete, when set, is called with each batch of tasks about to be deleted, payloads
+	// decoded but still encrypted; an error leaves the batch in place and ends the cleanup
+	BeforeDelete func(ctx context.Context, tasks []*entity.Task) error
+}
+
+// Cleanup returns the cleanup that applies the policy at now
+func (p RetentionPolicy) Cleanup(now time.Time) Cleanup {
+	cleanup := Cleanup{
+		Cutoffs:      make(map[entity.TaskStatus]time.Time),
+		Archive:      p.Archive,
+		BeforeDelete: p.BeforeDelete,
+	}
+	for status, retention := range map[entity.TaskStatus]time.Duration{
+		entity.TaskStatusCompleted:    p.CompletedRetention,
+		entity.TaskStatusDeadLettered: p.DeadLetteredRetention,
+		entity.TaskStatusExpired:      p.DeadLetteredRetention, // Never delivered, like dead-lettered tasks
+	} {
+		if retention > 0 {
+			cleanup.Cutoffs[status] = now.Add(-retention)
+		}
+	}
+	return cleanup
+}
+
+// CleanableStatuses are the finished statuses cleanup can remove, in the order it removes them
+var CleanableStatuses = []entity.TaskStatus{
+	entity.TaskStatusCompleted,
+	entity.TaskStatusDeadLettered,
+	entity.TaskStatusExpired,
+}
+
+// Cleanup selects finished tasks to delete: those of each status in Cutoffs that finished before
+// its cutoff. Dead-lettered and expired tasks have no completion time, so their creation time counts
+type Cleanup struct {
+	Cutoffs map[entity.TaskStatus]time.Time // Keyed by CleanableStatuses; other statuses are ignored
+	DryRun  bool                            // Count the tasks without archiving or deleting them
+
+	// Archive and BeforeDelete are as in RetentionPolicy
+	Archive      bool
+	BeforeDelete func(ctx context.Context, tasks []*entity.Task) error
+}
+
+// CleanupResult reports the outcome of a cleanup run
+type CleanupResult struct {
+	Archived int64
+	Deleted  int64
+
+	// ByStatus counts the tasks deleted per status, or in a dry run those that would be;
+	// statuses without any are left out
+	ByStatus map[entity.TaskStatus]int64
 }
@@ -0,0 +1,19 @@
+package domain
+
+import "context"
+
+// requestIDKey is the context key for the originating request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the API request being served
+// Tasks created with this context record it so their lifecycle can be correlated
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID the context carries
+// ok is false outside of API requests, such as in the scheduler or workers
+func RequestIDFromContext(ctx context.Context) (requestID string, ok bool) {
+	requestID, ok = ctx.Value(requestIDKey{}).(string)
+	return requestID, ok && requestID != ""
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "context"
+
+// tenantKey is the context key for the tenant scope
+type tenantKey struct{}
+
+// WithTenant returns a context scoped to the given tenant
+// Repository queries made with this context only see the tenant's tasks
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant the context is scoped to
+// ok is false for unscoped (global) contexts such as the scheduler, workers or admins
+func TenantFromContext(ctx context.Context) (tenantID string, ok bool) {
+	tenantID, ok = ctx.Value(tenantKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
@@ -0,0 +1,65 @@
+// Package alert delivers the scheduler's operational alerts
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// defaultTimeout bounds each delivery when NewWebhook is given no HTTP client
+const defaultTimeout = 10 * time.Second
+
+// Webhook is a tasksvc.Alerter posting each alert as JSON to a URL
+// The body is the alert with an "alert" field naming it, e.g. "dead_letter_queue"
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates an alerter posting to rawURL; a nil client uses one with a ten-second timeout
+func NewWebhook(rawURL string, client *http.Client) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid alert webhook URL %q: want an http:// or https:// URL", rawURL)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Webhook{url: rawURL, client: client}, nil
+}
+
+// Alert posts a dead letter queue alert; any status but 2xx is an error
+func (w *Webhook) Alert(ctx context.Context, alert tasksvc.DeadLetterAlert) error {
+	body, err := json.Marshal(struct {
+		Alert string `json:"alert"`
+		tasksvc.DeadLetterAlert
+	}{"dead_letter_queue", alert})
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post alert: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("alert webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tasksvc "github.com/usual2970/later/task"
+)
+
+func TestWebhookPostsAlerts(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	webhook, err := NewWebhook(server.URL+"/alerts", server.Client())
+	require.NoError(t, err)
+	alert := tasksvc.DeadLetterAlert{
+		Firing:       true,
+		Reasons:      []string{tasksvc.DeadLetterAlertThreshold},
+		DeadLettered: 120,
+		Threshold:    100,
+		Since:        time.Date(2026, 10, 16, 3, 4, 5, 0, time.UTC),
+	}
+	require.NoError(t, webhook.Alert(context.Background(), alert))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(<-bodies, &got))
+	assert.Equal(t, "dead_letter_queue", got["alert"])
+	assert.Equal(t, true, got["firing"])
+	assert.Equal(t, []any{"threshold"}, got["reasons"])
+	assert.EqualValues(t, 120, got["dead_lettered"])
+
+	status = http.StatusBadGateway
+	err = webhook.Alert(context.Background(), alert)
+	<-bodies
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "502")
+}
+
+func TestNewWebhookRejectsInvalidURLs(t *testing.T) {
+	for _, raw := range []string{"", "hooks.example.com/alerts", "ftp://hooks.example.com"} {
+		_, err := NewWebhook(raw, nil)
+		assert.Error(t, err, raw)
+	}
+}
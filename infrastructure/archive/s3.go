@@ -0,0 +1,258 @@
+// Package archive writes the tasks the cleanup job deletes to long-term storage
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// DefaultRegion is the signing region used when S3Config leaves it empty, which S3-compatible
+// services such as MinIO accept
+const DefaultRegion = "us-east-1"
+
+// defaultTimeout bounds each upload when NewS3 is given no HTTP client
+const defaultTimeout = time.Minute
+
+// S3Config locates the bucket archives are written to and the credentials to write them with
+type S3Config struct {
+	// Endpoint is the base URL of an S3-compatible service, e.g. http://minio:9000; empty
+	// uses AWS S3 in Region
+	Endpoint string
+	Region   string
+	Bucket   string
+
+	// Prefix is prepended to object keys, e.g. "later/tasks"
+	Prefix string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Only for temporary credentials
+
+	// PathStyle addresses the bucket in the URL path instead of as a subdomain of the
+	// endpoint, as MinIO and most S3-compatible services expect
+	PathStyle bool
+}
+
+// Validate checks the configuration without connecting
+func (c S3Config) Validate() error {
+	if c.Bucket == "" {
+		return fmt.Errorf("archive bucket is required")
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return fmt.Errorf("archive access key ID and secret access key are required")
+	}
+	if _, err := c.endpoint(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c S3Config) region() string {
+	if c.Region == "" {
+		return DefaultRegion
+	}
+	return c.Region
+}
+
+// endpoint returns the service's base URL
+func (c S3Config) endpoint() (*url.URL, error) {
+	raw := c.Endpoint
+	if raw == "" {
+		raw = "https://s3." + c.region() + ".amazonaws.com"
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid archive endpoint %q: want an http:// or https:// URL", c.Endpoint)
+	}
+	return u, nil
+}
+
+// S3 is a tasksvc.ArchiveSink writing each batch as one NDJSON object, one record per line,
+// under a date partition of the prefix: {prefix}/date=2006-01-02/20060102T150405Z-{uuid}.ndjson
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3 creates a sink for the configured bucket; a nil client uses one with a one-minute timeout
+func NewS3(cfg S3Config, client *http.Client) (*S3, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	endpoint, _ := cfg.endpoint()
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+	return &S3{cfg: cfg, endpoint: endpoint, client: client, now: time.Now}, nil
+}
+
+// Write uploads the records as a new object
+func (s *S3) Write(ctx context.Context, records []*tasksvc.ArchiveRecord) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("failed to encode archive record: %w", err)
+		}
+	}
+	now := s.now().UTC()
+	return s.put(ctx, s.key(now), body.Bytes(), now)
+}
+
+// key names the object of a batch written at now
+func (s *S3) key(now time.Time) string {
+	key := "date=" + now.Format("2006-01-02") + "/" + now.Format("20060102T150405Z") + "-" + uuid.NewString() + ".ndjson"
+	if s.cfg.Prefix != "" {
+		key = s.cfg.Prefix + "/" + key
+	}
+	return key
+}
+
+// put uploads an object with a signed PUT request
+func (s *S3) put(ctx context.Context, key string, body []byte, now time.Time) error {
+	u := *s.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if s.cfg.PathStyle {
+		path += "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		path += "/" + key
+	}
+	u.Path, u.RawPath = path, escapePath(path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+	signV4(req, payloadHash, s.cfg.AccessKeyID, s.cfg.SecretAccessKey, s.cfg.region(), "s3", now)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload archive: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to upload archive %s: %s: %s", key, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// signV4 sets the X-Amz-Date and Authorization headers of req for AWS Signature Version 4
+// The host, Content-Type and X-Amz-* headers are signed; payloadHash is the hex SHA-256 of the body
+func signV4(req *http.Request, payloadHash, accessKeyID, secretAccessKey, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalQuery encodes query parameters sorted by name, then value
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// escapePath encodes each segment of an object path the way S3 signs it
+func escapePath(path string) string {
+	return uriEncode(path, false)
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and slashes unless encodeSlash
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
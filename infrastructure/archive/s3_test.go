@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain/entity"
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// TestSignV4 checks the signer against the get-vanilla case of the AWS Signature Version 4 test suite
+func TestSignV4(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	req.Header = http.Header{}
+	signV4(req, sha256Hex(nil), "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "service",
+		time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestS3WritesNDJSONBatches(t *testing.T) {
+	type upload struct {
+		method, path, contentType, auth, payloadHash string
+		body                                         []byte
+	}
+	uploads := make(chan upload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploads <- upload{r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type"), r.Header.Get("Authorization"),
+			r.Header.Get("X-Amz-Content-Sha256"), body}
+	}))
+	defer server.Close()
+
+	sink, err := NewS3(S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "compliance",
+		Prefix:          "/later/tasks/",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+	}, server.Client())
+	require.NoError(t, err)
+	sink.now = func() time.Time { return time.Date(2026, 10, 16, 3, 4, 5, 0, time.UTC) }
+
+	done := entity.NewTask("send_email", []byte(`{"to":"a@example.com"}`), "https://example.com/cb", time.Now(), 0)
+	done.Status = entity.TaskStatusCompleted
+	records := []*tasksvc.ArchiveRecord{
+		{Task: done, Events: []*entity.TaskEvent{{ID: 7, TaskID: done.ID, Type: "task_completed", ToStatus: entity.TaskStatusCompleted}}},
+		{Task: entity.NewTask("encrypted", []byte("ciphertext"), "https://example.com/cb", time.Now(), 0), PayloadEncrypted: true},
+	}
+	require.NoError(t, sink.Write(context.Background(), records))
+
+	got := <-uploads
+	assert.Equal(t, http.MethodPut, got.method)
+	assert.True(t, strings.HasPrefix(got.path, "/compliance/later/tasks/date%3D2026-10-16/20261016T030405Z-"), got.path)
+	assert.True(t, strings.HasSuffix(got.path, ".ndjson"))
+	assert.Equal(t, "application/x-ndjson", got.contentType)
+	assert.Contains(t, got.auth, "Credential=AKID/20261016/us-east-1/s3/aws4_request")
+	assert.Equal(t, sha256Hex(got.body), got.payloadHash)
+
+	// One record per line
+	var lines []map[string]json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(got.body))
+	for scanner.Scan() {
+		var line map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.Len(t, lines, 2)
+	assert.Contains(t, string(lines[0]["task"]), done.ID)
+	assert.Contains(t, string(lines[0]["events"]), `"task_completed"`)
+	assert.JSONEq(t, "true", string(lines[1]["payload_encrypted"]))
+}
+
+func TestS3ReportsFailedUploads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>AccessDenied</Code></Error>")
+	}))
+	defer server.Close()
+
+	sink, err := NewS3(S3Config{Endpoint: server.URL, Bucket: "b", AccessKeyID: "AKID", SecretAccessKey: "secret", PathStyle: true}, nil)
+	require.NoError(t, err)
+	err = sink.Write(context.Background(), []*tasksvc.ArchiveRecord{{Task: &entity.Task{ID: "a"}}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+	assert.Contains(t, err.Error(), "AccessDenied")
+}
+
+func TestS3ConfigValidate(t *testing.T) {
+	valid := S3Config{Bucket: "b", AccessKeyID: "AKID", SecretAccessKey: "secret"}
+	require.NoError(t, valid.Validate())
+
+	for name, cfg := range map[string]S3Config{
+		"no bucket":      {AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		"no credentials": {Bucket: "b"},
+		"bad endpoint":   {Bucket: "b", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: "minio:9000"},
+	} {
+		assert.Error(t, cfg.Validate(), name)
+	}
+}
@@ -0,0 +1,43 @@
+package clock
+
+import "time"
+
+// Clock tells the time and creates timers, so time-dependent code can be tested with a Fake
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	After(d time.Duration) <-chan time.Time
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+// Real is the Clock backed by the time package
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// OrReal returns c, or Real if c is nil
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
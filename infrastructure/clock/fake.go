@@ -0,0 +1,152 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when advanced, for deterministic tests
+// Timers and tickers fire during Advance once the fake time reaches them; like their
+// time package counterparts, a ticker that isn't read drops ticks instead of queueing them
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond // Signalled when waiters are added
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a pending timer, or a ticker when period is positive
+type fakeWaiter struct {
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewFake returns a Fake clock set to now
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel that receives the fake time once it has advanced by d
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &fakeWaiter{at: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.c <- f.now
+		return w.c
+	}
+	f.add(w)
+	return w.c
+}
+
+// NewTicker returns a ticker that ticks every d of fake time
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &fakeWaiter{at: f.now.Add(d), period: d, c: make(chan time.Time, 1)}
+	f.add(w)
+	return &fakeTicker{f: f, w: w}
+}
+
+// Advance moves the fake time forward by d, firing the timers and tickers due by then
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.at.After(f.now) {
+			pending = append(pending, w)
+			continue
+		}
+		select {
+		case w.c <- w.at:
+		default: // Nobody read the previous tick
+		}
+		if w.period > 0 {
+			for !w.at.After(f.now) {
+				w.at = w.at.Add(w.period)
+			}
+			pending = append(pending, w)
+		}
+	}
+	f.waiters = pending
+}
+
+// Waiters returns how many timers and tickers are pending
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil waits until at least n timers and tickers are pending, so a test can advance the
+// time once the code under test, e.g. a goroutine, has started waiting on the clock
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.changed.Wait()
+	}
+}
+
+// add registers a waiter; callers hold f.mu
+func (f *Fake) add(w *fakeWaiter) {
+	f.waiters = append(f.waiters, w)
+	f.changed.Broadcast()
+}
+
+// remove unregisters a waiter; callers hold f.mu
+func (f *Fake) remove(w *fakeWaiter) {
+	for i, other := range f.waiters {
+		if other == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.c }
+
+// Reset restarts the ticker with period d from the current fake time, dropping a pending tick
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+	t.w.at = t.f.now.Add(d)
+	t.w.period = d
+	select {
+	case <-t.w.c:
+	default:
+	}
+	t.f.add(t.w)
+}
+
+// Stop turns off the ticker
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+}
@@ -0,0 +1,88 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func received(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case at := <-c:
+		return at, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeAfter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	c := f.After(time.Minute)
+
+	f.Advance(59 * time.Second)
+	_, ok := received(c)
+	assert.False(t, ok, "Not due yet")
+
+	f.Advance(time.Second)
+	at, ok := received(c)
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Minute), at)
+	assert.Equal(t, start.Add(time.Minute), f.Now())
+	assert.Zero(t, f.Waiters(), "Timers fire once")
+
+	_, ok = received(f.After(0))
+	assert.True(t, ok, "Non-positive durations fire at once")
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ticker := f.NewTicker(10 * time.Second)
+
+	f.Advance(10 * time.Second)
+	_, ok := received(ticker.C())
+	assert.True(t, ok)
+
+	// Ticks that aren't read are dropped
+	f.Advance(35 * time.Second)
+	_, ok = received(ticker.C())
+	assert.True(t, ok)
+	_, ok = received(ticker.C())
+	assert.False(t, ok)
+
+	// The next tick stays on the original schedule
+	f.Advance(5 * time.Second)
+	_, ok = received(ticker.C())
+	assert.True(t, ok)
+
+	ticker.Reset(time.Minute)
+	f.Advance(50 * time.Second)
+	_, ok = received(ticker.C())
+	assert.False(t, ok, "Reset restarts the period")
+	f.Advance(10 * time.Second)
+	_, ok = received(ticker.C())
+	assert.True(t, ok)
+
+	ticker.Stop()
+	f.Advance(time.Hour)
+	_, ok = received(ticker.C())
+	assert.False(t, ok, "Stopped tickers don't tick")
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	f := NewFake(time.Now())
+	done := make(chan struct{})
+	go func() {
+		<-f.After(time.Second)
+		close(done)
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timer didn't fire")
+	}
+}
@@ -0,0 +1,113 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+
+	"go.uber.org/zap"
+)
+
+// Bus fans task events out between the instances sharing a database, so each instance's
+// WebSocket clients see the events of tasks processed anywhere
+// Events carry the Origin of the instance that broadcast them; every instance receives its
+// own events too and is expected to skip them
+type Bus interface {
+	// Publish sends an event broadcast on this instance to every instance
+	Publish(ctx context.Context, event *entity.TaskEvent) error
+
+	// Run calls deliver with each published event until Stop is called
+	Run(deliver func(*entity.TaskEvent))
+	Stop()
+}
+
+// Bus drivers
+const (
+	DriverNone     = ""         // Events stay on the instance that broadcast them
+	DriverDatabase = "database" // Instances poll the task_events table
+	DriverRedis    = "redis"    // Instances publish and subscribe on a Redis channel
+)
+
+// Defaults for Config fields left at zero
+const (
+	DefaultPollInterval = time.Second
+	DefaultRedisChannel = "later:task_events"
+)
+
+// Config selects and tunes a bus; the zero value is the no-op bus
+type Config struct {
+	Driver string
+
+	// PollInterval is how often the database driver checks for new events
+	PollInterval time.Duration
+
+	// RedisURL is the redis:// or rediss:// URL of the Redis server, with an optional
+	// user and password; RedisChannel is the channel events are published on
+	RedisURL     string
+	RedisChannel string
+}
+
+// Validate checks the configuration without connecting
+func (c Config) Validate() error {
+	switch c.Driver {
+	case DriverNone, DriverDatabase:
+	case DriverRedis:
+		if _, err := parseRedisURL(c.RedisURL); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown event bus driver %q: want %q or %q", c.Driver, DriverDatabase, DriverRedis)
+	}
+	if c.PollInterval < 0 {
+		return fmt.Errorf("event bus poll interval cannot be negative")
+	}
+	return nil
+}
+
+// New creates the configured bus; cfg must be valid
+// The database driver reads events from the repository the WebSocket hub records them in,
+// so events must be recorded for it to fan them out
+func New(cfg Config, events repository.TaskEventRepository, logger *zap.Logger) (Bus, error) {
+	switch cfg.Driver {
+	case DriverDatabase:
+		if events == nil {
+			return nil, fmt.Errorf("the database event bus needs recorded task events")
+		}
+		return NewDatabase(events, cfg.PollInterval, logger), nil
+	case DriverRedis:
+		channel := cfg.RedisChannel
+		if channel == "" {
+			channel = DefaultRedisChannel
+		}
+		return NewRedis(cfg.RedisURL, channel, logger)
+	default:
+		return NewNop(), nil
+	}
+}
+
+// nop is the bus of a single instance: nothing is published and nothing delivered
+type nop struct {
+	quit chan struct{}
+}
+
+// NewNop returns a bus that keeps events on the instance that broadcast them
+func NewNop() Bus {
+	return &nop{quit: make(chan struct{})}
+}
+
+func (b *nop) Publish(ctx context.Context, event *entity.TaskEvent) error { return nil }
+func (b *nop) Run(deliver func(*entity.TaskEvent))                        { <-b.quit }
+func (b *nop) Stop()                                                      { close(b.quit) }
+
+// parseRedisURL checks a Redis URL, returning it parsed
+func parseRedisURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Redis URL: want redis://[user:password@]host:port")
+	}
+	return u, nil
+}
@@ -0,0 +1,111 @@
+package eventbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+
+	"go.uber.org/zap"
+)
+
+// pollBatchSize is how many events a database poll reads per query
+const pollBatchSize = 500
+
+// Database fans events out through the task_events table: each instance already records the
+// events it broadcasts there, and polls it for the events recorded since its last poll
+// Events are delivered up to PollInterval late. Events recorded by concurrent writers can
+// commit out of ID order, so one committing after a poll has read past its ID is missed
+type Database struct {
+	events   repository.TaskEventRepository
+	interval time.Duration
+	logger   *zap.Logger
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+// NewDatabase creates a bus polling events every interval, DefaultPollInterval if zero
+func NewDatabase(events repository.TaskEventRepository, interval time.Duration, logger *zap.Logger) *Database {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	return &Database{
+		events:   events,
+		interval: interval,
+		logger:   logger,
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Publish does nothing: the hub has recorded the event already
+func (b *Database) Publish(ctx context.Context, event *entity.TaskEvent) error {
+	return nil
+}
+
+// Run delivers the events recorded after it starts until Stop is called
+func (b *Database) Run(deliver func(*entity.TaskEvent)) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	// Events already recorded aren't delivered; until the newest ID is known, a failed read
+	// is retried on every tick
+	after, err := b.lastID()
+	started := err == nil
+	if err != nil {
+		b.logger.Error("Failed to read the last task event", zap.Error(err))
+	}
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.quit:
+			return
+		}
+
+		if !started {
+			id, err := b.lastID()
+			if err != nil {
+				b.logger.Error("Failed to read the last task event", zap.Error(err))
+				continue
+			}
+			after, started = id, true
+			continue
+		}
+		after = b.poll(after, deliver)
+	}
+}
+
+// poll delivers the events after the given ID and returns the last one delivered
+func (b *Database) poll(after int64, deliver func(*entity.TaskEvent)) int64 {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		events, err := b.events.ListSince(ctx, after, "", pollBatchSize)
+		cancel()
+		if err != nil {
+			b.logger.Error("Failed to poll task events", zap.Error(err))
+			return after
+		}
+		for _, event := range events {
+			deliver(event)
+			after = event.ID
+		}
+		if len(events) < pollBatchSize {
+			return after
+		}
+	}
+}
+
+func (b *Database) lastID() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return b.events.LastID(ctx)
+}
+
+// Stop stops polling and waits for Run to return
+func (b *Database) Stop() {
+	close(b.quit)
+	<-b.done
+}
@@ -0,0 +1,56 @@
+package eventbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/repository/memory"
+)
+
+func TestDatabaseDeliversNewEvents(t *testing.T) {
+	ctx := context.Background()
+	events := memory.NewTaskEventRepository()
+	require.NoError(t, events.Append(ctx, &entity.TaskEvent{TaskID: "old", Type: "task_created", CreatedAt: time.Now()}))
+
+	bus := NewDatabase(events, 10*time.Millisecond, zap.NewNop())
+	delivered := make(chan *entity.TaskEvent, 10)
+	go bus.Run(func(event *entity.TaskEvent) { delivered <- event })
+	defer bus.Stop()
+
+	// Wait for a poll, so the bus has read past the existing event
+	time.Sleep(30 * time.Millisecond)
+	for _, id := range []string{"a", "b"} {
+		require.NoError(t, events.Append(ctx, &entity.TaskEvent{TaskID: id, Type: "task_created", Origin: "replica-2", CreatedAt: time.Now()}))
+	}
+
+	for _, want := range []string{"a", "b"} {
+		select {
+		case event := <-delivered:
+			assert.Equal(t, want, event.TaskID, "events recorded before the bus started aren't delivered")
+			assert.Equal(t, "replica-2", event.Origin)
+		case <-time.After(time.Second):
+			t.Fatalf("event %s was not delivered", want)
+		}
+	}
+	assert.NoError(t, bus.Publish(ctx, &entity.TaskEvent{TaskID: "c"}), "publishing is left to the recording")
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.NoError(t, Config{Driver: DriverDatabase, PollInterval: time.Second}.Validate())
+	assert.NoError(t, Config{Driver: DriverRedis, RedisURL: "redis://:secret@localhost:6379"}.Validate())
+	assert.NoError(t, Config{Driver: DriverRedis, RedisURL: "rediss://cache.internal"}.Validate())
+	assert.Error(t, Config{Driver: "kafka"}.Validate())
+	assert.Error(t, Config{Driver: DriverRedis}.Validate(), "Redis needs a URL")
+	assert.Error(t, Config{Driver: DriverRedis, RedisURL: "localhost:6379"}.Validate())
+	assert.Error(t, Config{Driver: DriverDatabase, PollInterval: -time.Second}.Validate())
+
+	_, err := New(Config{Driver: DriverDatabase}, nil, zap.NewNop())
+	assert.Error(t, err, "the database bus needs recorded events")
+}
@@ -0,0 +1,320 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+
+	"go.uber.org/zap"
+)
+
+// Redis connection timeouts and resubscription backoff
+const (
+	redisDialTimeout  = 5 * time.Second
+	redisWriteTimeout = 5 * time.Second
+	redisMinBackoff   = time.Second
+	redisMaxBackoff   = 30 * time.Second
+)
+
+// Redis fans events out over Redis pub/sub: each instance publishes the events it broadcasts
+// on a channel every instance subscribes to
+// Redis doesn't keep messages, so events published while an instance is reconnecting are
+// lost to it; its clients can catch up with ?since if events are recorded
+type Redis struct {
+	addr     string
+	useTLS   bool
+	host     string // For verifying the server's certificate
+	username string
+	password string
+	channel  string
+	logger   *zap.Logger
+
+	pubMu sync.Mutex
+	pub   *redisConn // nil until the first publish or after an error
+
+	subMu sync.Mutex
+	sub   *redisConn // Closed by Stop to interrupt Run's read
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewRedis creates a bus on the channel of the Redis server at rawURL, a
+// redis://[user:password@]host[:port] or rediss:// URL; it connects on first use
+func NewRedis(rawURL, channel string, logger *zap.Logger) (*Redis, error) {
+	u, err := parseRedisURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	password, _ := u.User.Password()
+	return &Redis{
+		addr:     addr,
+		useTLS:   u.Scheme == "rediss",
+		host:     u.Hostname(),
+		username: u.User.Username(),
+		password: password,
+		channel:  channel,
+		logger:   logger,
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Publish sends the event to every subscribed instance, reconnecting once if the connection
+// has gone stale
+func (b *Redis) Publish(ctx context.Context, event *entity.TaskEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+	for attempt := 0; ; attempt++ {
+		err = b.publish(ctx, payload)
+		if err == nil {
+			return nil
+		}
+		if b.pub != nil {
+			b.pub.Close()
+			b.pub = nil
+		}
+		var replyErr redisError
+		if attempt > 0 || errors.As(err, &replyErr) || ctx.Err() != nil {
+			return fmt.Errorf("failed to publish task event: %w", err)
+		}
+	}
+}
+
+// publish sends PUBLISH on the publishing connection, dialing it first if needed
+func (b *Redis) publish(ctx context.Context, payload []byte) error {
+	if b.pub == nil {
+		conn, err := b.dial(ctx)
+		if err != nil {
+			return err
+		}
+		b.pub = conn
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisWriteTimeout)
+	}
+	b.pub.SetDeadline(deadline)
+	_, err := b.pub.do("PUBLISH", b.channel, string(payload))
+	return err
+}
+
+// Run subscribes to the channel and delivers the events published on it until Stop is called,
+// resubscribing with a backoff whenever the connection is lost
+func (b *Redis) Run(deliver func(*entity.TaskEvent)) {
+	defer close(b.done)
+
+	backoff := redisMinBackoff
+	for {
+		subscribed, err := b.subscribe(deliver)
+		select {
+		case <-b.quit:
+			return
+		default:
+		}
+		if subscribed {
+			backoff = redisMinBackoff
+		}
+		b.logger.Warn("Lost the Redis event bus subscription, resubscribing",
+			zap.Duration("backoff", backoff), zap.Error(err))
+
+		select {
+		case <-time.After(backoff):
+		case <-b.quit:
+			return
+		}
+		backoff = min(backoff*2, redisMaxBackoff)
+	}
+}
+
+// subscribe delivers events until the subscription fails, reporting whether it was established
+func (b *Redis) subscribe(deliver func(*entity.TaskEvent)) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisDialTimeout)
+	conn, err := b.dial(ctx)
+	cancel()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	// Stop closes the connection to interrupt the read below
+	b.subMu.Lock()
+	select {
+	case <-b.quit:
+		b.subMu.Unlock()
+		return false, nil
+	default:
+	}
+	b.sub = conn
+	b.subMu.Unlock()
+	defer func() {
+		b.subMu.Lock()
+		b.sub = nil
+		b.subMu.Unlock()
+	}()
+
+	conn.SetDeadline(time.Now().Add(redisWriteTimeout))
+	if _, err := conn.do("SUBSCRIBE", b.channel); err != nil {
+		return false, err
+	}
+	conn.SetDeadline(time.Time{})
+	b.logger.Info("Subscribed to the Redis event bus", zap.String("channel", b.channel))
+
+	for {
+		reply, err := conn.readReply()
+		if err != nil {
+			return true, err
+		}
+		// Messages arrive as ["message", channel, payload]
+		fields, ok := reply.([]interface{})
+		if !ok || len(fields) != 3 || fields[0] != "message" {
+			continue
+		}
+		payload, _ := fields[2].(string)
+		var event entity.TaskEvent
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			b.logger.Warn("Ignoring malformed event on the Redis event bus", zap.Error(err))
+			continue
+		}
+		deliver(&event)
+	}
+}
+
+// Stop unsubscribes, waits for Run to return and closes the publishing connection
+func (b *Redis) Stop() {
+	close(b.quit)
+	b.subMu.Lock()
+	if b.sub != nil {
+		b.sub.Close()
+	}
+	b.subMu.Unlock()
+	<-b.done
+
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+	if b.pub != nil {
+		b.pub.Close()
+		b.pub = nil
+	}
+}
+
+// dial connects to the server and authenticates
+func (b *Redis) dial(ctx context.Context) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: redisDialTimeout}
+	var conn net.Conn
+	var err error
+	if b.useTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: b.host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", b.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", b.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	rc := &redisConn{Conn: conn, r: bufio.NewReader(conn)}
+	if b.password != "" {
+		args := []string{"AUTH", b.password}
+		if b.username != "" {
+			args = []string{"AUTH", b.username, b.password}
+		}
+		rc.SetDeadline(time.Now().Add(redisWriteTimeout))
+		if _, err := rc.do(args...); err != nil {
+			rc.Close()
+			return nil, fmt.Errorf("failed to authenticate with Redis: %w", err)
+		}
+		rc.SetDeadline(time.Time{})
+	}
+	return rc, nil
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisConn speaks just enough of the RESP2 protocol to publish and subscribe
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// do sends a command and reads its reply
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	if _, err := io.WriteString(c.Conn, b.String()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply reads one reply: a string, an int64, nil, an array of them or a redisError
+// An error reply is returned as the error
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err // A null bulk string has length -1
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,177 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// fakeRedis implements just enough of a Redis server for the bus: AUTH, PUBLISH and SUBSCRIBE
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu          sync.Mutex
+	subscribers map[string][]net.Conn
+	conns       []net.Conn
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeRedis{listener: listener, password: password, subscribers: map[string][]net.Conn{}}
+	go s.serve()
+	t.Cleanup(func() { listener.Close(); s.dropConnections() })
+	return s
+}
+
+func (s *fakeRedis) url(password string) string {
+	if password != "" {
+		return "redis://:" + password + "@" + s.listener.Addr().String()
+	}
+	return "redis://" + s.listener.Addr().String()
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+// dropConnections closes every client connection, as a restarting server would
+func (s *fakeRedis) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+	s.subscribers = map[string][]net.Conn{}
+}
+
+func (s *fakeRedis) subscriberCount(channel string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscribers[channel])
+}
+
+func (s *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	rc := &redisConn{Conn: conn, r: bufio.NewReader(conn)}
+	authed := s.password == ""
+	for {
+		reply, err := rc.readReply()
+		if err != nil {
+			return
+		}
+		args, _ := reply.([]interface{})
+		if len(args) == 0 {
+			return
+		}
+		command, _ := args[0].(string)
+		switch strings.ToUpper(command) {
+		case "AUTH":
+			if args[len(args)-1] == s.password {
+				authed = true
+				fmt.Fprint(conn, "+OK\r\n")
+			} else {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+			}
+		case "PUBLISH":
+			if !authed {
+				fmt.Fprint(conn, "-NOAUTH Authentication required\r\n")
+				continue
+			}
+			channel, payload := args[1].(string), args[2].(string)
+			s.mu.Lock()
+			subscribers := s.subscribers[channel]
+			for _, sub := range subscribers {
+				fmt.Fprintf(sub, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(channel), channel, len(payload), payload)
+			}
+			s.mu.Unlock()
+			fmt.Fprintf(conn, ":%d\r\n", len(subscribers))
+		case "SUBSCRIBE":
+			if !authed {
+				fmt.Fprint(conn, "-NOAUTH Authentication required\r\n")
+				continue
+			}
+			channel := args[1].(string)
+			s.mu.Lock()
+			s.subscribers[channel] = append(s.subscribers[channel], conn)
+			s.mu.Unlock()
+			fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(channel), channel)
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", command)
+		}
+	}
+}
+
+func TestRedisFansOutEvents(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+
+	subscriber, err := NewRedis(server.url("secret"), DefaultRedisChannel, zap.NewNop())
+	require.NoError(t, err)
+	delivered := make(chan *entity.TaskEvent, 10)
+	go subscriber.Run(func(event *entity.TaskEvent) { delivered <- event })
+	defer subscriber.Stop()
+
+	publisher, err := NewRedis(server.url("secret"), DefaultRedisChannel, zap.NewNop())
+	require.NoError(t, err)
+	go publisher.Run(func(*entity.TaskEvent) {})
+	defer publisher.Stop()
+	require.Eventually(t, func() bool { return server.subscriberCount(DefaultRedisChannel) == 2 }, time.Second, 5*time.Millisecond)
+	ctx := context.Background()
+	sent := &entity.TaskEvent{ID: 7, TaskID: "a", Type: "task_completed", ToStatus: entity.TaskStatusCompleted,
+		Metadata: []byte(`{"name":"send_email"}`), CreatedAt: time.Now().UTC().Truncate(time.Millisecond), Origin: "replica-1"}
+	require.NoError(t, publisher.Publish(ctx, sent))
+
+	select {
+	case event := <-delivered:
+		assert.Equal(t, sent.ID, event.ID)
+		assert.Equal(t, sent.TaskID, event.TaskID)
+		assert.Equal(t, sent.Origin, event.Origin)
+		assert.JSONEq(t, string(sent.Metadata), string(event.Metadata))
+		assert.True(t, sent.CreatedAt.Equal(event.CreatedAt))
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered")
+	}
+
+	// Both sides reconnect after the server drops them
+	server.dropConnections()
+	require.Eventually(t, func() bool { return server.subscriberCount(DefaultRedisChannel) == 2 }, 3*time.Second, 10*time.Millisecond)
+	require.NoError(t, publisher.Publish(ctx, &entity.TaskEvent{TaskID: "b"}))
+	select {
+	case event := <-delivered:
+		assert.Equal(t, "b", event.TaskID)
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered after reconnecting")
+	}
+}
+
+func TestRedisRejectsWrongPassword(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+
+	bus, err := NewRedis(server.url("wrong"), DefaultRedisChannel, zap.NewNop())
+	require.NoError(t, err)
+	err = bus.Publish(context.Background(), &entity.TaskEvent{TaskID: "a"})
+	assert.ErrorContains(t, err, "WRONGPASS")
+}
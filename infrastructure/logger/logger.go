@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"fmt"
 	"os"
 	"sync"
 
@@ -13,13 +14,27 @@ import (
 var (
 	globalLogger *zap.Logger
 	once         sync.Once
+
+	// level is shared by the global logger and every logger derived from it, so SetLevel
+	// applies to named child loggers too
+	level = zap.NewAtomicLevel()
+)
+
+// Outputs of the production logger
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputFile   = "file" // Filename, rotated
 )
 
 // Config defines logger configuration
 type Config struct {
 	Environment string // "development", "testing", "production"
 	Level       string // "debug", "info", "warn", "error"
-	// File logging configuration (only used in production)
+	// Output is where production logs go: OutputStdout, OutputStderr or OutputFile
+	// Empty uses stdout when running in a container and the file otherwise
+	Output string
+	// File logging configuration (only used in production with OutputFile)
 	Filename   string // Log file path
 	MaxSize    int    // Maximum size in megabytes
 	MaxBackups int    // Maximum number of old log files to retain
@@ -77,23 +92,67 @@ func InitFromEnv() error {
 		cfg.Level = logLevel
 	}
 
-	// Override log file path from ENV if specified
+	// Override log file path from ENV if specified; naming a file also selects file output
 	if logFile := os.Getenv("LOG_FILE"); logFile != "" {
 		cfg.Filename = logFile
+		cfg.Output = OutputFile
+	}
+
+	// Override log output from ENV if specified
+	if output := os.Getenv("LOG_OUTPUT"); output != "" {
+		cfg.Output = output
 	}
 
 	return Init(cfg)
 }
 
+// inContainer reports whether the process appears to run in a container, from the variables
+// Kubernetes and container runtimes such as Podman set
+func inContainer() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" || os.Getenv("container") != ""
+}
+
+// outputWriter returns where the production logger writes, per cfg.Output
+func outputWriter(cfg *Config) (zapcore.WriteSyncer, error) {
+	output := cfg.Output
+	if output == "" {
+		output = OutputFile
+		if inContainer() {
+			output = OutputStdout
+		}
+	}
+
+	switch output {
+	case OutputStdout:
+		return zapcore.Lock(os.Stdout), nil
+	case OutputStderr:
+		return zapcore.Lock(os.Stderr), nil
+	case OutputFile:
+		if cfg.Filename == "" {
+			return nil, fmt.Errorf("log output %q requires a filename", OutputFile)
+		}
+		// Configure log rotation with lumberjack
+		return zapcore.AddSync(&lumberjack.Logger{
+			Filename:   cfg.Filename,
+			MaxSize:    cfg.MaxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAge,
+			Compress:   cfg.Compress,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown log output %q: use %s, %s or %s", output, OutputStdout, OutputStderr, OutputFile)
+	}
+}
+
 // initLogger creates and sets the global logger
 func initLogger(cfg *Config) error {
 	var logger *zap.Logger
 	var err error
 
-	level := parseLogLevel(cfg.Level)
+	level.SetLevel(parseLogLevel(cfg.Level))
 
 	if cfg.Environment == "production" {
-		// Production: JSON logging to file with rotation
+		// Production: JSON logging to stdout, stderr or a rotated file
 		logger, err = newProductionLogger(cfg, level)
 	} else {
 		// Development/Testing: Console logging
@@ -108,15 +167,11 @@ func initLogger(cfg *Config) error {
 	return nil
 }
 
-// newProductionLogger creates a production logger with file rotation
-func newProductionLogger(cfg *Config, level zapcore.Level) (*zap.Logger, error) {
-	// Configure log rotation with lumberjack
-	writer := &lumberjack.Logger{
-		Filename:   cfg.Filename,
-		MaxSize:    cfg.MaxSize,
-		MaxBackups: cfg.MaxBackups,
-		MaxAge:     cfg.MaxAge,
-		Compress:   cfg.Compress,
+// newProductionLogger creates a production logger writing JSON to the configured output
+func newProductionLogger(cfg *Config, level zapcore.LevelEnabler) (*zap.Logger, error) {
+	writer, err := outputWriter(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create JSON encoder
@@ -129,7 +184,7 @@ func newProductionLogger(cfg *Config, level zapcore.Level) (*zap.Logger, error)
 	encoder := zapcore.NewJSONEncoder(encoderConfig)
 
 	// Create core
-	core := zapcore.NewCore(encoder, zapcore.AddSync(writer), level)
+	core := zapcore.NewCore(encoder, writer, level)
 
 	// Create logger with caller information
 	logger := zap.New(core,
@@ -146,7 +201,7 @@ func newProductionLogger(cfg *Config, level zapcore.Level) (*zap.Logger, error)
 }
 
 // newDevelopmentLogger creates a development logger with console output
-func newDevelopmentLogger(level zapcore.Level) (*zap.Logger, error) {
+func newDevelopmentLogger(level zap.AtomicLevel) (*zap.Logger, error) {
 	// Create development config
 	config := zap.NewDevelopmentConfig()
 
@@ -156,7 +211,7 @@ func newDevelopmentLogger(level zapcore.Level) (*zap.Logger, error) {
 	config.EncoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
 
 	// Set log level
-	config.Level = zap.NewAtomicLevelAt(level)
+	config.Level = level
 
 	// Build logger
 	logger, err := config.Build(
@@ -169,22 +224,52 @@ func newDevelopmentLogger(level zapcore.Level) (*zap.Logger, error) {
 	return logger, nil
 }
 
-// parseLogLevel converts string log level to zapcore.Level
+// parseLogLevel converts string log level to zapcore.Level, defaulting to info
 func parseLogLevel(level string) zapcore.Level {
+	if l, ok := lookupLevel(level); ok {
+		return l
+	}
+	return zapcore.InfoLevel
+}
+
+// lookupLevel converts a known string log level to zapcore.Level
+func lookupLevel(level string) (zapcore.Level, bool) {
 	switch level {
 	case "debug":
-		return zapcore.DebugLevel
+		return zapcore.DebugLevel, true
 	case "info":
-		return zapcore.InfoLevel
+		return zapcore.InfoLevel, true
 	case "warn", "warning":
-		return zapcore.WarnLevel
+		return zapcore.WarnLevel, true
 	case "error":
-		return zapcore.ErrorLevel
+		return zapcore.ErrorLevel, true
 	case "fatal":
-		return zapcore.FatalLevel
+		return zapcore.FatalLevel, true
 	default:
-		return zapcore.InfoLevel
+		return 0, false
+	}
+}
+
+// SetLevel changes the level of the global logger and every logger derived from it at runtime
+// Returns an error for unknown levels; use "debug", "info", "warn" or "error"
+func SetLevel(name string) error {
+	l, ok := lookupLevel(name)
+	if !ok {
+		return fmt.Errorf("unknown log level %q: use debug, info, warn or error", name)
 	}
+	level.SetLevel(l)
+	return nil
+}
+
+// Level returns the current level of the global logger, e.g. "info"
+func Level() string {
+	return level.Level().String()
+}
+
+// AtomicLevel returns the level SetLevel changes, so loggers built outside this package,
+// e.g. one passed to later.WithLogger, can follow it too
+func AtomicLevel() zap.AtomicLevel {
+	return level
 }
 
 // Get returns the global logger instance
@@ -269,11 +354,25 @@ func Int64(key string, val int64) zap.Field {
 	return zap.Int64(key, val)
 }
 
+// Bool constructs a bool field
+func Bool(key string, val bool) zap.Field {
+	return zap.Bool(key, val)
+}
+
 // Any constructs an arbitrary field
 func Any(key string, val interface{}) zap.Field {
 	return zap.Any(key, val)
 }
 
+// RequestID constructs the request_id field correlating a task's logs with the API request
+// that created it; the field is omitted when the ID is unknown
+func RequestID(requestID *string) zap.Field {
+	if requestID == nil || *requestID == "" {
+		return zap.Skip()
+	}
+	return zap.String("request_id", *requestID)
+}
+
 // Err constructs an error field
 func Err(err error) zap.Field {
 	return zap.Error(err)
@@ -1,11 +1,15 @@
 package logger
 
 import (
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // TestLoggerInitialization tests logger initialization with different configurations
@@ -173,6 +177,155 @@ func TestInitFromEnv(t *testing.T) {
 	}
 }
 
+// TestProductionLoggerOutput tests that production logs reach the configured output as JSON
+func TestProductionLoggerOutput(t *testing.T) {
+	// Capture the standard streams; outputs resolve them when the logger is built
+	capture := func(stream **os.File) func() string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("Failed to create pipe: %v", err)
+		}
+		original := *stream
+		*stream = w
+		return func() string {
+			*stream = original
+			w.Close()
+			out, _ := io.ReadAll(r)
+			return string(out)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		output    string
+		container bool
+		read      func(t *testing.T, filename string) func() string
+	}{
+		{
+			name:   "Stdout",
+			output: OutputStdout,
+			read:   func(t *testing.T, _ string) func() string { return capture(&os.Stdout) },
+		},
+		{
+			name:   "Stderr",
+			output: OutputStderr,
+			read:   func(t *testing.T, _ string) func() string { return capture(&os.Stderr) },
+		},
+		{
+			name:      "File, even in a container",
+			output:    OutputFile,
+			container: true,
+			read: func(t *testing.T, filename string) func() string {
+				return func() string {
+					out, _ := os.ReadFile(filename)
+					return string(out)
+				}
+			},
+		},
+		{
+			name:      "Default in a container is stdout",
+			container: true,
+			read:      func(t *testing.T, _ string) func() string { return capture(&os.Stdout) },
+		},
+		{
+			name: "Default elsewhere is the file",
+			read: func(t *testing.T, filename string) func() string {
+				return func() string {
+					out, _ := os.ReadFile(filename)
+					return string(out)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("container", "")
+			t.Setenv("KUBERNETES_SERVICE_HOST", "")
+			if tt.container {
+				t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+			}
+
+			filename := filepath.Join(t.TempDir(), "app.log")
+			read := tt.read(t, filename)
+			log, err := newProductionLogger(&Config{Environment: "production", Output: tt.output, Filename: filename}, zapcore.InfoLevel)
+			if err != nil {
+				t.Fatalf("Failed to create logger: %v", err)
+			}
+			log.Info("hello", zap.String("output", tt.name))
+			log.Sync()
+
+			out := read()
+			if !strings.Contains(out, `"msg":"hello"`) || !strings.Contains(out, `"output":"`+tt.name+`"`) {
+				t.Errorf("Output = %q, expected a JSON entry", out)
+			}
+		})
+	}
+}
+
+// TestProductionLoggerInvalidOutput tests that misconfigured outputs are rejected
+func TestProductionLoggerInvalidOutput(t *testing.T) {
+	for _, cfg := range []*Config{
+		{Environment: "production", Output: "syslog"},
+		{Environment: "production", Output: OutputFile},
+	} {
+		if _, err := newProductionLogger(cfg, zapcore.InfoLevel); err == nil {
+			t.Errorf("Output %q with filename %q: expected an error", cfg.Output, cfg.Filename)
+		}
+	}
+}
+
+// TestInitFromEnvOutput tests that LOG_OUTPUT and LOG_FILE select the output
+func TestInitFromEnvOutput(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("LOG_FILE", filepath.Join(t.TempDir(), "app.log"))
+	t.Setenv("LOG_OUTPUT", OutputStderr)
+	defer func() {
+		globalLogger = nil
+		once = *new(sync.Once)
+	}()
+
+	if err := InitFromEnv(); err != nil {
+		t.Fatalf("InitFromEnv failed: %v", err)
+	}
+	Info("Application started")
+	if _, err := os.Stat(os.Getenv("LOG_FILE")); !os.IsNotExist(err) {
+		t.Errorf("LOG_OUTPUT should win over LOG_FILE, but the file was created: %v", err)
+	}
+}
+
+// TestSetLevel tests that runtime level changes reach named child loggers
+func TestSetLevel(t *testing.T) {
+	cfg := DefaultConfig("testing")
+	cfg.Level = "info"
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer func() {
+		globalLogger = nil
+		once = *new(sync.Once)
+	}()
+
+	child := Named("worker").Named("pool")
+	if child.Core().Enabled(zapcore.DebugLevel) {
+		t.Fatal("Debug should be disabled at info")
+	}
+
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	if !child.Core().Enabled(zapcore.DebugLevel) || Level() != "debug" {
+		t.Error("Debug should be enabled on existing child loggers after SetLevel")
+	}
+
+	if err := SetLevel("verbose"); err == nil {
+		t.Error("Unknown levels should be rejected")
+	}
+	if Level() != "debug" {
+		t.Errorf("Level() = %q after a rejected change, expected debug", Level())
+	}
+}
+
 // Example_basicUsage demonstrates basic logger usage
 func Example_basicUsage() {
 	// Initialize logger from environment
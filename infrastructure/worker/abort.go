@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrAborted is the error recorded on a task whose callback was aborted by an operator
+	ErrAborted = errors.New("aborted by operator")
+
+	// ErrNotInFlight is returned by Abort for tasks whose callback isn't being delivered by
+	// this instance, either because it has finished or because another replica runs it
+	ErrNotInFlight = errors.New("task callback is not in flight on this instance")
+)
+
+// abortCause cancels an aborted task's callback, so the callback error carries ErrAborted
+type abortCause struct {
+	terminal bool // Dead-letter the task instead of failing it for a retry
+}
+
+func (a *abortCause) Error() string { return ErrAborted.Error() }
+
+func (a *abortCause) Is(target error) bool { return target == ErrAborted }
+
+// InFlight tracks the tasks whose callbacks are being delivered, keyed by task ID, so an
+// operator can abort a callback stuck on an unresponsive host
+// It is safe for concurrent use; a nil InFlight tracks nothing
+type InFlight struct {
+	mu    sync.Mutex
+	tasks map[string]*inFlightTask
+}
+
+type inFlightTask struct {
+	cancel context.CancelCauseFunc
+	abort  *abortCause // Set once aborted
+}
+
+// NewInFlight creates an empty tracker, shared by a worker pool and an executor
+func NewInFlight() *InFlight {
+	return &InFlight{tasks: make(map[string]*inFlightTask)}
+}
+
+// track derives the context a task's callback is delivered with, cancelled when the task is
+// aborted. untrack must be called once the callback returns; it reports the abort, if any
+func (f *InFlight) track(ctx context.Context, id string) (context.Context, func() *abortCause) {
+	if f == nil {
+		return ctx, func() *abortCause { return nil }
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	t := &inFlightTask{cancel: cancel}
+	f.mu.Lock()
+	f.tasks[id] = t
+	f.mu.Unlock()
+
+	return ctx, func() *abortCause {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.tasks[id] == t {
+			delete(f.tasks, id)
+		}
+		cancel(nil)
+		return t.abort
+	}
+}
+
+// Abort cancels the task's in-flight callback, so its HTTP request fails at once
+// The task is then failed with ErrAborted and retried like any failed task, or dead-lettered
+// when terminal is set. A callback that has already returned takes its normal course
+func (f *InFlight) Abort(id string, terminal bool) error {
+	if f == nil {
+		return ErrNotInFlight
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.tasks[id]
+	if !ok {
+		return ErrNotInFlight
+	}
+	if t.abort == nil {
+		t.abort = &abortCause{}
+	}
+	// Aborting again can only make the abort terminal
+	t.abort.terminal = t.abort.terminal || terminal
+	t.cancel(t.abort)
+	return nil
+}
+
+// Count returns how many task callbacks are in flight
+func (f *InFlight) Count() int {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.tasks)
+}
@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/domain/entity"
+)
+
+func TestAbortInFlightCallback(t *testing.T) {
+	// The receiver hangs like a dead host until the request is cancelled
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer receiver.Close()
+	defer close(release)
+
+	svc := &storeTaskService{tasks: map[string]entity.Task{}}
+	for _, id := range []string{"retried", "terminal"} {
+		svc.tasks[id] = entity.Task{ID: id, Status: entity.TaskStatusPending, CallbackURL: receiver.URL, MaxRetries: 3}
+	}
+	callbackSvc := callback.NewService(&http.Client{Timeout: time.Minute}, nil, "", 0, zap.NewNop())
+	inFlight := NewInFlight()
+	pool := NewWorkerPool(2, svc, callbackSvc, nil, zap.NewNop(), WithInFlight(inFlight))
+	pool.Start(2)
+	defer pool.Stop(context.Background())
+
+	assert.ErrorIs(t, inFlight.Abort("retried", false), ErrNotInFlight)
+	for _, id := range []string{"retried", "terminal"} {
+		task, err := svc.GetTask(context.Background(), id)
+		require.NoError(t, err)
+		require.True(t, pool.SubmitTask(task))
+	}
+	require.Eventually(t, func() bool { return inFlight.Count() == 2 }, time.Second, time.Millisecond)
+
+	start := time.Now()
+	require.NoError(t, inFlight.Abort("retried", false))
+	require.NoError(t, inFlight.Abort("terminal", true))
+	require.Eventually(t, func() bool {
+		return svc.status("retried") == entity.TaskStatusFailed && svc.status("terminal") == entity.TaskStatusDeadLettered
+	}, time.Second, time.Millisecond)
+	assert.Less(t, time.Since(start), time.Second, "the requests are cancelled at once")
+
+	// A non-terminal abort follows the retry rules
+	retried, err := svc.GetTask(context.Background(), "retried")
+	require.NoError(t, err)
+	assert.Equal(t, 1, retried.RetryCount)
+	assert.NotNil(t, retried.NextRetryAt)
+	require.NotNil(t, retried.ErrorMessage)
+	assert.Equal(t, "aborted by operator", *retried.ErrorMessage)
+
+	terminal, err := svc.GetTask(context.Background(), "terminal")
+	require.NoError(t, err)
+	require.NotNil(t, terminal.ErrorMessage)
+	assert.Equal(t, "aborted by operator", *terminal.ErrorMessage)
+
+	assert.Zero(t, inFlight.Count())
+	assert.ErrorIs(t, inFlight.Abort("retried", false), ErrNotInFlight)
+}
+
+func TestNilInFlight(t *testing.T) {
+	var inFlight *InFlight
+	ctx, untrack := inFlight.track(context.Background(), "1")
+	assert.NoError(t, ctx.Err())
+	assert.Nil(t, untrack())
+	assert.ErrorIs(t, inFlight.Abort("1", false), ErrNotInFlight)
+}
@@ -0,0 +1,254 @@
+package worker
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// DefaultMaxWaiting bounds the tasks a ConcurrencyLimiter holds back; beyond it, tasks of
+// saturated keys are refused and stay pending in the database for the next poll
+const DefaultMaxWaiting = 10000
+
+// ConcurrencyLimiter caps the tasks in flight per concurrency key (see Task.ConcurrencyGroup)
+// Tasks of a saturated key wait in a per-key FIFO and take over the slot of the next task of
+// that key to finish, so they start in submission order. Keys without a limit are unbounded
+// Limits can be changed at runtime
+type ConcurrencyLimiter struct {
+	mu         sync.Mutex
+	limits     map[string]int
+	inFlight   map[string]int
+	waiting    map[string][]*entity.Task
+	numWaiting int
+	maxWaiting int
+
+	// tracked holds the IDs of limited tasks waiting or in flight, so a task submitted again
+	// by the next poll while it is still held isn't run twice
+	tracked map[string]bool
+
+	run func(tasks []*entity.Task) // Runs tasks admitted by SetLimit and RemoveLimit
+}
+
+// NewConcurrencyLimiter creates a limiter with the given per-key limits
+// A zero maxWaiting uses DefaultMaxWaiting
+func NewConcurrencyLimiter(limits map[string]int, maxWaiting int) (*ConcurrencyLimiter, error) {
+	if maxWaiting <= 0 {
+		maxWaiting = DefaultMaxWaiting
+	}
+	l := &ConcurrencyLimiter{
+		limits:     make(map[string]int),
+		inFlight:   make(map[string]int),
+		waiting:    make(map[string][]*entity.Task),
+		maxWaiting: maxWaiting,
+		tracked:    make(map[string]bool),
+	}
+	for key, limit := range limits {
+		if err := ValidateConcurrencyLimit(key, limit); err != nil {
+			return nil, err
+		}
+		l.limits[key] = limit
+	}
+	return l, nil
+}
+
+// ValidateConcurrencyLimit checks a key's limit; keys must be named and limits positive
+func ValidateConcurrencyLimit(key string, limit int) error {
+	if key == "" {
+		return fmt.Errorf("concurrency limit key cannot be empty")
+	}
+	if limit <= 0 {
+		return fmt.Errorf("concurrency limit for %q must be positive", key)
+	}
+	return nil
+}
+
+// Limits returns a copy of the current per-key limits
+func (l *ConcurrencyLimiter) Limits() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limits := make(map[string]int, len(l.limits))
+	for key, limit := range l.limits {
+		limits[key] = limit
+	}
+	return limits
+}
+
+// InFlight returns the number of tasks running for key
+func (l *ConcurrencyLimiter) InFlight(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight[key]
+}
+
+// Waiting returns the number of tasks of key held back by its limit
+func (l *ConcurrencyLimiter) Waiting(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.waiting[key])
+}
+
+// Saturated returns the limited keys without a free slot, sorted; further tasks of these keys
+// would only be held, so pollers leave them in the database
+func (l *ConcurrencyLimiter) Saturated() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var keys []string
+	for key, limit := range l.limits {
+		if l.inFlight[key] >= limit || len(l.waiting[key]) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SetLimit sets key's limit, starting any waiting tasks it admits
+// Lowering a limit doesn't interrupt running tasks; it takes effect as they finish
+func (l *ConcurrencyLimiter) SetLimit(key string, limit int) error {
+	if err := ValidateConcurrencyLimit(key, limit); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.limits[key] = limit
+	var admitted []*entity.Task
+	for l.inFlight[key] < limit && len(l.waiting[key]) > 0 {
+		admitted = append(admitted, l.popWaitingLocked(key))
+		l.inFlight[key]++
+	}
+	run := l.run
+	l.mu.Unlock()
+
+	if len(admitted) > 0 && run != nil {
+		run(admitted)
+	}
+	return nil
+}
+
+// RemoveLimit makes key unbounded, starting its waiting tasks
+func (l *ConcurrencyLimiter) RemoveLimit(key string) {
+	l.mu.Lock()
+	delete(l.limits, key)
+	var admitted []*entity.Task
+	for len(l.waiting[key]) > 0 {
+		task := l.popWaitingLocked(key)
+		delete(l.tracked, task.ID)
+		admitted = append(admitted, task)
+	}
+	run := l.run
+	l.mu.Unlock()
+
+	if len(admitted) > 0 && run != nil {
+		run(admitted)
+	}
+}
+
+// attach sets how tasks admitted by a limit change are run; the worker pool using the
+// limiter attaches itself
+func (l *ConcurrencyLimiter) attach(run func(tasks []*entity.Task)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.run = run
+}
+
+// submit runs the task through send if its key has a free slot, holds it if the key is
+// saturated, and reports false if it could neither be sent nor held
+// send must not block; it is called with the limiter locked so a slot is only taken by a task
+// that was actually queued
+func (l *ConcurrencyLimiter) submit(task *entity.Task, send func(*entity.Task) bool) bool {
+	key := task.ConcurrencyGroup()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, limited := l.limits[key]
+	if !limited {
+		return send(task)
+	}
+	if l.tracked[task.ID] {
+		return true // Already waiting or running
+	}
+	if l.inFlight[key] < limit && len(l.waiting[key]) == 0 {
+		if !send(task) {
+			return false
+		}
+		l.inFlight[key]++
+		l.tracked[task.ID] = true
+		return true
+	}
+	if l.numWaiting >= l.maxWaiting {
+		return false
+	}
+	l.waiting[key] = append(l.waiting[key], task)
+	l.numWaiting++
+	l.tracked[task.ID] = true
+	return true
+}
+
+// release frees the task's slot, handing it to the next waiting task of the same key if the
+// limit allows; that task is returned and must be run by the caller
+func (l *ConcurrencyLimiter) release(task *entity.Task) *entity.Task {
+	key := task.ConcurrencyGroup()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.tracked[task.ID] {
+		return nil // Admitted while the key was unbounded
+	}
+	delete(l.tracked, task.ID)
+
+	if limit, limited := l.limits[key]; limited && l.inFlight[key] <= limit && len(l.waiting[key]) > 0 {
+		return l.popWaitingLocked(key)
+	}
+	l.decrementLocked(key)
+	return nil
+}
+
+// abandon frees the task's slot without handing it on, for tasks dropped during shutdown
+func (l *ConcurrencyLimiter) abandon(task *entity.Task) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tracked[task.ID] {
+		delete(l.tracked, task.ID)
+		l.decrementLocked(task.ConcurrencyGroup())
+	}
+}
+
+func (l *ConcurrencyLimiter) decrementLocked(key string) {
+	l.inFlight[key]--
+	if l.inFlight[key] <= 0 {
+		delete(l.inFlight, key)
+	}
+}
+
+// drain drops every waiting task and returns how many there were
+func (l *ConcurrencyLimiter) drain() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dropped := l.numWaiting
+	for key, tasks := range l.waiting {
+		for _, task := range tasks {
+			delete(l.tracked, task.ID)
+		}
+		delete(l.waiting, key)
+	}
+	l.numWaiting = 0
+	return dropped
+}
+
+func (l *ConcurrencyLimiter) popWaitingLocked(key string) *entity.Task {
+	tasks := l.waiting[key]
+	task := tasks[0]
+	tasks[0] = nil
+	if len(tasks) == 1 {
+		delete(l.waiting, key)
+	} else {
+		l.waiting[key] = tasks[1:]
+	}
+	l.numWaiting--
+	return task
+}
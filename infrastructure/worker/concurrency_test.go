@@ -0,0 +1,243 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// concurrencyTaskService tracks how many tasks of each key are processing at once
+// Each task holds its slot for a short while, then fails so the worker skips callback delivery
+type concurrencyTaskService struct {
+	hold time.Duration
+
+	mu      sync.Mutex
+	running map[string]int
+	peak    map[string]int
+	started []string
+}
+
+func newConcurrencyTaskService(hold time.Duration) *concurrencyTaskService {
+	return &concurrencyTaskService{hold: hold, running: make(map[string]int), peak: make(map[string]int)}
+}
+
+func (s *concurrencyTaskService) GetTask(ctx context.Context, id string) (*entity.Task, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *concurrencyTaskService) ResolveDependents(ctx context.Context, parent *entity.Task) error {
+	return nil
+}
+
+func (s *concurrencyTaskService) UpdateTask(ctx context.Context, task *entity.Task) error {
+	if task.Status != entity.TaskStatusProcessing {
+		return nil
+	}
+	key := task.ConcurrencyGroup()
+
+	s.mu.Lock()
+	s.running[key]++
+	s.peak[key] = max(s.peak[key], s.running[key])
+	s.started = append(s.started, task.ID)
+	s.mu.Unlock()
+
+	time.Sleep(s.hold)
+
+	s.mu.Lock()
+	s.running[key]--
+	s.mu.Unlock()
+	return errors.New("stop here")
+}
+
+func (s *concurrencyTaskService) UpdateTaskIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	err := s.UpdateTask(ctx, task)
+	return err == nil, err
+}
+
+func (s *concurrencyTaskService) startedIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.started...)
+}
+
+func (s *concurrencyTaskService) peakFor(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.peak[key]
+}
+
+func keyedTask(id, key string) *entity.Task {
+	return &entity.Task{ID: id, ConcurrencyKey: &key}
+}
+
+// submitUntilAccepted resubmits like the scheduler's next poll would when the queue is full
+func submitUntilAccepted(t *testing.T, pool WorkerPool, task *entity.Task) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return pool.SubmitTask(task)
+	}, 5*time.Second, time.Millisecond, "task %s was never accepted", task.ID)
+}
+
+func TestConcurrencyLimitEnforcedUnderLoad(t *testing.T) {
+	limiter, err := NewConcurrencyLimiter(map[string]int{"a": 2, "b": 1}, 0)
+	require.NoError(t, err)
+
+	svc := newConcurrencyTaskService(5 * time.Millisecond)
+	pool := NewWorkerPool(8, svc, nil, nil, zap.NewNop(), WithConcurrencyLimiter(limiter))
+	pool.Start(8)
+	defer pool.Stop(context.Background())
+
+	const perKey = 30
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			for i := 0; i < perKey; i++ {
+				submitUntilAccepted(t, pool, keyedTask(fmt.Sprintf("%s-%d", key, i), key))
+			}
+		}(key)
+	}
+	wg.Wait()
+
+	require.Eventually(t, func() bool {
+		return len(svc.startedIDs()) == 3*perKey
+	}, 10*time.Second, 10*time.Millisecond)
+
+	assert.LessOrEqual(t, svc.peakFor("a"), 2)
+	assert.LessOrEqual(t, svc.peakFor("b"), 1)
+	assert.Greater(t, svc.peakFor("c"), 2, "keys without a limit use the whole pool")
+	assert.Eventually(t, func() bool {
+		return limiter.InFlight("a") == 0 && limiter.InFlight("b") == 0
+	}, time.Second, 10*time.Millisecond, "every slot is released")
+}
+
+func TestConcurrencyLimitPreservesSubmissionOrder(t *testing.T) {
+	limiter, err := NewConcurrencyLimiter(map[string]int{"email": 1}, 0)
+	require.NoError(t, err)
+
+	svc := newConcurrencyTaskService(time.Millisecond)
+	pool := NewWorkerPool(4, svc, nil, nil, zap.NewNop(), WithConcurrencyLimiter(limiter))
+	pool.Start(4)
+	defer pool.Stop(context.Background())
+
+	var want []string
+	for i := 0; i < 20; i++ {
+		// The first tag is the default concurrency key
+		task := &entity.Task{ID: fmt.Sprintf("email-%02d", i), Tags: []string{"email"}}
+		require.True(t, pool.SubmitTask(task))
+		want = append(want, task.ID)
+	}
+
+	require.Eventually(t, func() bool {
+		return len(svc.startedIDs()) == len(want)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, want, svc.startedIDs())
+}
+
+func TestConcurrencyLimiterIgnoresResubmittedTasks(t *testing.T) {
+	limiter, err := NewConcurrencyLimiter(map[string]int{"a": 1}, 0)
+	require.NoError(t, err)
+
+	var sent []string
+	send := func(task *entity.Task) bool {
+		sent = append(sent, task.ID)
+		return true
+	}
+
+	assert.True(t, limiter.submit(keyedTask("1", "a"), send))
+	assert.True(t, limiter.submit(keyedTask("2", "a"), send))
+	// The next poll finds both still pending
+	assert.True(t, limiter.submit(keyedTask("1", "a"), send))
+	assert.True(t, limiter.submit(keyedTask("2", "a"), send))
+
+	assert.Equal(t, []string{"1"}, sent)
+	assert.Equal(t, 1, limiter.InFlight("a"))
+	assert.Equal(t, 1, limiter.Waiting("a"))
+
+	next := limiter.release(keyedTask("1", "a"))
+	require.NotNil(t, next)
+	assert.Equal(t, "2", next.ID)
+	assert.Nil(t, limiter.release(next))
+	assert.Equal(t, 0, limiter.InFlight("a"))
+}
+
+func TestConcurrencyLimiterSaturated(t *testing.T) {
+	limiter, err := NewConcurrencyLimiter(map[string]int{"a": 1, "b": 2}, 0)
+	require.NoError(t, err)
+	send := func(*entity.Task) bool { return true }
+
+	assert.Empty(t, limiter.Saturated())
+	require.True(t, limiter.submit(keyedTask("1", "a"), send))
+	require.True(t, limiter.submit(keyedTask("2", "b"), send))
+	require.True(t, limiter.submit(keyedTask("3", "c"), send))
+	assert.Equal(t, []string{"a"}, limiter.Saturated(), "b has a free slot and c no limit")
+
+	assert.Nil(t, limiter.release(keyedTask("1", "a")))
+	assert.Empty(t, limiter.Saturated())
+}
+
+func TestConcurrencyLimiterRefusesBeyondMaxWaiting(t *testing.T) {
+	limiter, err := NewConcurrencyLimiter(map[string]int{"a": 1}, 1)
+	require.NoError(t, err)
+	send := func(*entity.Task) bool { return true }
+
+	assert.True(t, limiter.submit(keyedTask("1", "a"), send))
+	assert.True(t, limiter.submit(keyedTask("2", "a"), send))
+	assert.False(t, limiter.submit(keyedTask("3", "a"), send), "left pending for the next poll")
+	assert.Equal(t, 1, limiter.drain())
+}
+
+func TestConcurrencyLimiterSetLimitAdmitsWaiting(t *testing.T) {
+	limiter, err := NewConcurrencyLimiter(map[string]int{"a": 1}, 0)
+	require.NoError(t, err)
+
+	var admitted []string
+	limiter.attach(func(tasks []*entity.Task) {
+		for _, task := range tasks {
+			admitted = append(admitted, task.ID)
+		}
+	})
+	send := func(*entity.Task) bool { return true }
+	for _, id := range []string{"1", "2", "3", "4"} {
+		require.True(t, limiter.submit(keyedTask(id, "a"), send))
+	}
+
+	require.NoError(t, limiter.SetLimit("a", 3))
+	assert.Equal(t, []string{"2", "3"}, admitted)
+	assert.Equal(t, 3, limiter.InFlight("a"))
+
+	limiter.RemoveLimit("a")
+	assert.Equal(t, []string{"2", "3", "4"}, admitted)
+	assert.Equal(t, map[string]int{}, limiter.Limits())
+
+	assert.Error(t, limiter.SetLimit("a", 0))
+	assert.Error(t, limiter.SetLimit("", 1))
+}
+
+func TestWorkerPoolStopAbandonsWaitingTasks(t *testing.T) {
+	limiter, err := NewConcurrencyLimiter(map[string]int{"a": 1}, 0)
+	require.NoError(t, err)
+
+	svc := &blockingTaskService{started: make(chan struct{}), release: make(chan struct{})}
+	pool := NewWorkerPool(2, svc, nil, nil, zap.NewNop(), WithConcurrencyLimiter(limiter))
+	pool.Start(2)
+	defer close(svc.release)
+
+	require.True(t, pool.SubmitTask(keyedTask("running", "a")))
+	<-svc.started
+	require.True(t, pool.SubmitTask(keyedTask("waiting", "a")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, 2, pool.Stop(ctx), "one in-flight task and one held by the limiter")
+}
@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotExecutable is returned by Execute for tasks that are neither pending nor failed
+	ErrNotExecutable = errors.New("only pending or failed tasks can be executed")
+
+	// ErrAlreadyClaimed is returned by Execute when the task is being processed elsewhere
+	ErrAlreadyClaimed = errors.New("task is already being processed")
+)
+
+// Execution is the outcome of running a task with an Executor
+type Execution struct {
+	Task    *entity.Task // The task as persisted after delivery
+	Attempt callback.Attempt
+	Err     error // Callback error; nil when the task completed
+}
+
+// Executor runs single tasks inline, bypassing the worker queue and concurrency limits
+// Tasks are claimed like a worker claims them, so a task is never run twice when the
+// scheduler dispatches it at the same time
+type Executor struct {
+	worker *Worker
+}
+
+// NewExecutor creates an executor persisting and broadcasting task updates like a worker pool
+// Tasks it runs are tracked in inFlight, which is shared with the pool so Abort reaches
+// callbacks run by either; nil leaves them unabortable
+func NewExecutor(
+	taskService TaskService,
+	callbackService *callback.Service,
+	broadcaster EventBroadcaster,
+	inFlight *InFlight,
+	logger *zap.Logger,
+) *Executor {
+	w := NewWorker(0, nil, taskService, callbackService, broadcaster, &sync.WaitGroup{}, &Counters{}, logger)
+	w.name = "inline"
+	w.inFlight = inFlight
+	return &Executor{worker: w}
+}
+
+// Abort cancels the callback of a task being processed by this instance; see InFlight.Abort
+func (e *Executor) Abort(id string, terminal bool) error {
+	return e.worker.inFlight.Abort(id, terminal)
+}
+
+// Execute claims a pending or failed task and delivers its callback with the task's timeout,
+// persisting the outcome as a worker would: completed, failed for a retry or dead-lettered
+// The delivery outlives ctx once started, so the outcome is always persisted
+// The task's expiry isn't checked, as running a task on demand is a deliberate decision
+func (e *Executor) Execute(ctx context.Context, id string) (*Execution, error) {
+	w := e.worker
+	task, err := w.taskService.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch task.Status {
+	case entity.TaskStatusPending, entity.TaskStatusFailed:
+	case entity.TaskStatusProcessing:
+		return nil, ErrAlreadyClaimed
+	default:
+		return nil, ErrNotExecutable
+	}
+
+	claimed, err := w.claim(ctx, task, entity.TaskStatusPending, entity.TaskStatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim task: %w", err)
+	}
+	if !claimed {
+		return nil, ErrAlreadyClaimed
+	}
+
+	w.logger.Info("Executing task inline",
+		zap.String("task_id", task.ID),
+		logger.RequestID(task.RequestID),
+		zap.String("task_name", task.Name))
+
+	execution := &Execution{Task: task}
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				w.logger.Error("Recovered panic while executing task",
+					zap.String("task_id", task.ID),
+					logger.RequestID(task.RequestID),
+					zap.Any("panic", r),
+					zap.Stack("stack"))
+				execution.Err = fmt.Errorf("panic: %v", r)
+				w.failAfterPanic(context.WithoutCancel(ctx), task, execution.Err)
+			}
+		}()
+		execution.Attempt, execution.Err = w.deliver(context.WithoutCancel(ctx), task)
+	}()
+	return execution, nil
+}
+
+// TestCallback delivers a sample callback right away without storing a task or touching the
+// circuit breaker; see callback.Service.TestDelivery
+func (e *Executor) TestCallback(ctx context.Context, task *entity.Task) (*callback.TestResult, error) {
+	return e.worker.callbackService.TestDelivery(ctx, task)
+}
@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+)
+
+// storeTaskService keeps tasks in memory with the claim semantics of the repository
+type storeTaskService struct {
+	mu    sync.Mutex
+	tasks map[string]entity.Task
+}
+
+func (s *storeTaskService) GetTask(ctx context.Context, id string) (*entity.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &task, nil
+}
+
+func (s *storeTaskService) ResolveDependents(ctx context.Context, parent *entity.Task) error {
+	return nil
+}
+
+func (s *storeTaskService) UpdateTask(ctx context.Context, task *entity.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[task.ID] = *task
+	return nil
+}
+
+func (s *storeTaskService) UpdateTaskIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Contains(from, s.tasks[task.ID].Status) {
+		return false, nil
+	}
+	s.tasks[task.ID] = *task
+	return true, nil
+}
+
+func (s *storeTaskService) status(id string) entity.TaskStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tasks[id].Status
+}
+
+func TestExecutorExecute(t *testing.T) {
+	var deliveries atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries.Add(1)
+		if r.Header.Get("X-Task-ID") == "failing" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	svc := &storeTaskService{tasks: map[string]entity.Task{}}
+	for id, status := range map[string]entity.TaskStatus{
+		"pending":    entity.TaskStatusPending,
+		"failing":    entity.TaskStatusFailed,
+		"processing": entity.TaskStatusProcessing,
+		"completed":  entity.TaskStatusCompleted,
+	} {
+		svc.tasks[id] = entity.Task{ID: id, Status: status, CallbackURL: receiver.URL, MaxRetries: 3, RetryCount: 1}
+	}
+	callbackSvc := callback.NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop())
+	executor := NewExecutor(svc, callbackSvc, nil, nil, zap.NewNop())
+	ctx := context.Background()
+
+	execution, err := executor.Execute(ctx, "pending")
+	require.NoError(t, err)
+	assert.NoError(t, execution.Err)
+	assert.Equal(t, http.StatusAccepted, execution.Attempt.StatusCode)
+	assert.Positive(t, execution.Attempt.Duration)
+	assert.Equal(t, entity.TaskStatusCompleted, execution.Task.Status)
+	assert.Equal(t, entity.TaskStatusCompleted, svc.status("pending"))
+
+	// A failed delivery is persisted for the next retry
+	execution, err = executor.Execute(ctx, "failing")
+	require.NoError(t, err)
+	assert.Error(t, execution.Err)
+	assert.Equal(t, http.StatusServiceUnavailable, execution.Attempt.StatusCode)
+	assert.Equal(t, entity.TaskStatusFailed, svc.status("failing"))
+	assert.Equal(t, 2, execution.Task.RetryCount)
+
+	_, err = executor.Execute(ctx, "processing")
+	assert.ErrorIs(t, err, ErrAlreadyClaimed)
+	_, err = executor.Execute(ctx, "completed")
+	assert.ErrorIs(t, err, ErrNotExecutable)
+	_, err = executor.Execute(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Equal(t, int32(2), deliveries.Load())
+}
+
+func TestExecutorAndWorkersRunTaskOnce(t *testing.T) {
+	var deliveries atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries.Add(1)
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer receiver.Close()
+
+	svc := &storeTaskService{tasks: map[string]entity.Task{}}
+	task := entity.Task{ID: "1", Status: entity.TaskStatusPending, CallbackURL: receiver.URL}
+	svc.tasks[task.ID] = task
+	callbackSvc := callback.NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop())
+	pool := NewWorkerPool(2, svc, callbackSvc, nil, zap.NewNop())
+	pool.Start(2)
+	defer pool.Stop(context.Background())
+
+	// The scheduler and an operator dispatch the same task at once
+	for i := 0; i < 2; i++ {
+		submitted := task
+		require.True(t, pool.SubmitTask(&submitted))
+	}
+	_, err := NewExecutor(svc, callbackSvc, nil, nil, zap.NewNop()).Execute(context.Background(), task.ID)
+	if err != nil {
+		assert.ErrorIs(t, err, ErrAlreadyClaimed)
+	}
+
+	require.Eventually(t, func() bool {
+		return svc.status(task.ID) == entity.TaskStatusCompleted
+	}, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(1), deliveries.Load())
+}
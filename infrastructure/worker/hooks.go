@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"sync"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"go.uber.org/zap"
+)
+
+// TaskHooks holds user callbacks invoked after a task state transition is persisted
+type TaskHooks struct {
+	OnCompleted    func(task *entity.Task)
+	OnFailed       func(task *entity.Task)
+	OnDeadLettered func(task *entity.Task)
+}
+
+// IsEmpty returns true if no hook is configured
+func (h TaskHooks) IsEmpty() bool {
+	return h.OnCompleted == nil && h.OnFailed == nil && h.OnDeadLettered == nil
+}
+
+// hookFor returns the hook registered for the task's status, if any
+func (h TaskHooks) hookFor(status entity.TaskStatus) func(*entity.Task) {
+	switch status {
+	case entity.TaskStatusCompleted:
+		return h.OnCompleted
+	case entity.TaskStatusFailed:
+		return h.OnFailed
+	case entity.TaskStatusDeadLettered:
+		return h.OnDeadLettered
+	default:
+		return nil
+	}
+}
+
+// HookRunner runs TaskHooks on a bounded queue outside the worker's critical path
+// It implements EventBroadcaster so it can be passed to the worker pool
+type HookRunner struct {
+	hooks       TaskHooks
+	queue       chan *entity.Task
+	concurrency int
+	wg          sync.WaitGroup
+	mu          sync.RWMutex
+	closed      bool
+	logger      *zap.Logger
+}
+
+// NewHookRunner creates a hook runner with the given concurrency and queue size
+func NewHookRunner(hooks TaskHooks, concurrency int, queueSize int, logger *zap.Logger) *HookRunner {
+	return &HookRunner{
+		hooks:       hooks,
+		queue:       make(chan *entity.Task, queueSize),
+		concurrency: concurrency,
+		logger:      logger,
+	}
+}
+
+// Start launches the hook goroutines
+func (r *HookRunner) Start() {
+	for i := 0; i < r.concurrency; i++ {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			for task := range r.queue {
+				r.run(task)
+			}
+		}()
+	}
+}
+
+// Stop stops accepting tasks and waits for queued hooks to finish
+func (r *HookRunner) Stop() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	close(r.queue)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+// BroadcastTaskUpdate queues the task's hook without blocking the caller
+// Hooks are dropped with a warning if the queue is full
+func (r *HookRunner) BroadcastTaskUpdate(task *entity.Task) {
+	if r.hooks.hookFor(task.Status) == nil {
+		return
+	}
+
+	// Hooks run asynchronously, so they receive a snapshot of the task
+	snapshot := *task
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.queue <- &snapshot:
+	default:
+		r.logger.Warn("Task hook queue full, dropping hook",
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID),
+			zap.String("status", string(task.Status)))
+	}
+}
+
+// run invokes a hook, recovering and logging any panic
+func (r *HookRunner) run(task *entity.Task) {
+	hook := r.hooks.hookFor(task.Status)
+	if hook == nil {
+		return
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			r.logger.Error("Task hook panicked",
+				zap.String("task_id", task.ID),
+				logger.RequestID(task.RequestID),
+				zap.String("status", string(task.Status)),
+				zap.Any("panic", recovered))
+		}
+	}()
+
+	hook(task)
+}
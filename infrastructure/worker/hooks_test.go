@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+func TestHookRunnerDispatchesByStatus(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string][]string{}
+	record := func(name string) func(*entity.Task) {
+		return func(task *entity.Task) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[name] = append(calls[name], task.ID)
+		}
+	}
+
+	runner := NewHookRunner(TaskHooks{
+		OnCompleted:    record("completed"),
+		OnDeadLettered: record("dead_lettered"),
+	}, 2, 10, zap.NewNop())
+	runner.Start()
+
+	runner.BroadcastTaskUpdate(&entity.Task{ID: "a", Status: entity.TaskStatusCompleted})
+	runner.BroadcastTaskUpdate(&entity.Task{ID: "b", Status: entity.TaskStatusFailed}) // no hook configured
+	runner.BroadcastTaskUpdate(&entity.Task{ID: "c", Status: entity.TaskStatusDeadLettered})
+	runner.BroadcastTaskUpdate(&entity.Task{ID: "d", Status: entity.TaskStatusProcessing})
+	runner.Stop()
+
+	assert.Equal(t, []string{"a"}, calls["completed"])
+	assert.Equal(t, []string{"c"}, calls["dead_lettered"])
+	assert.Len(t, calls, 2)
+}
+
+func TestHookRunnerRecoversPanics(t *testing.T) {
+	var completed int
+	runner := NewHookRunner(TaskHooks{
+		OnFailed: func(*entity.Task) { panic("boom") },
+		OnCompleted: func(*entity.Task) {
+			completed++
+		},
+	}, 1, 10, zap.NewNop())
+	runner.Start()
+
+	runner.BroadcastTaskUpdate(&entity.Task{ID: "a", Status: entity.TaskStatusFailed})
+	runner.BroadcastTaskUpdate(&entity.Task{ID: "b", Status: entity.TaskStatusCompleted})
+	runner.Stop()
+
+	assert.Equal(t, 1, completed, "hook goroutine should survive a panicking hook")
+}
+
+func TestHookRunnerDoesNotBlockWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	runner := NewHookRunner(TaskHooks{
+		OnCompleted: func(*entity.Task) { <-release },
+	}, 1, 1, zap.NewNop())
+	runner.Start()
+
+	// One task is running, one is queued, the rest must be dropped without blocking
+	for i := 0; i < 10; i++ {
+		runner.BroadcastTaskUpdate(&entity.Task{ID: "a", Status: entity.TaskStatusCompleted})
+	}
+
+	close(release)
+	runner.Stop()
+
+	// Updates after Stop are ignored
+	runner.BroadcastTaskUpdate(&entity.Task{ID: "b", Status: entity.TaskStatusCompleted})
+}
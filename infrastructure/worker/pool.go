@@ -2,12 +2,16 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/usual2970/later/callback"
 	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/clock"
+	"github.com/usual2970/later/infrastructure/logger"
 
 	"go.uber.org/zap"
 )
@@ -16,13 +20,67 @@ import (
 type TaskService interface {
 	GetTask(ctx context.Context, id string) (*entity.Task, error)
 	UpdateTask(ctx context.Context, task *entity.Task) error
+	// UpdateTaskIfStatus updates a task only if its stored status is one of from, reporting whether it did
+	UpdateTaskIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error)
+	// ResolveDependents releases the tasks waiting on a task that completed or was dead-lettered
+	ResolveDependents(ctx context.Context, parent *entity.Task) error
+}
+
+// retryPolicySource is implemented by task services that configure how failed tasks are retried;
+// tasks of other services are retried under entity.DefaultRetryPolicy
+type retryPolicySource interface {
+	RetryPolicy() entity.RetryPolicy
+}
+
+// hostPauseSource is implemented by task services that let callback hosts be paused; tasks of
+// other services are never held
+type hostPauseSource interface {
+	// HostHeldUntil reports whether the host is paused at now, and until when its tasks are held
+	HostHeldUntil(host string, now time.Time) (time.Time, bool)
+}
+
+// ErrShuttingDown is the cause in-flight callbacks are cancelled with when the pool's shutdown
+// deadline passes before they return
+var ErrShuttingDown = errors.New("worker pool is shutting down")
+
+// FinalWriteGrace bounds the write recording a task's outcome once the worker's context is
+// cancelled, so a callback interrupted by shutdown still leaves the task in a runnable state
+const FinalWriteGrace = 2 * time.Second
+
+// EventBroadcaster publishes task state changes to live subscribers
+type EventBroadcaster interface {
+	BroadcastTaskUpdate(task *entity.Task)
+}
+
+// Broadcasters fans a task update out to several broadcasters
+type Broadcasters []EventBroadcaster
+
+// BroadcastTaskUpdate forwards the update to every broadcaster
+func (b Broadcasters) BroadcastTaskUpdate(task *entity.Task) {
+	for _, broadcaster := range b {
+		broadcaster.BroadcastTaskUpdate(task)
+	}
 }
 
 // WorkerPool defines the interface for task worker pool
 type WorkerPool interface {
 	Start(workerCount int)
 	SubmitTask(task *entity.Task) bool
-	Stop()
+	// Stop rejects new submissions and waits for in-flight tasks until ctx is done
+	// It returns the number of tasks abandoned: queued tasks never started (still
+	// pending in the database) plus tasks still running at the deadline
+	// Callbacks still in flight at the deadline are cancelled and their tasks put back to
+	// pending; Stop waits up to FinalWriteGrace more for that to be recorded
+	Stop(ctx context.Context) int
+}
+
+// Counters tracks activity shared by all workers in a pool
+type Counters struct {
+	InFlight atomic.Int64 // Tasks currently being processed
+	Panics   atomic.Int64 // Panics recovered while processing tasks
+	Rejected atomic.Int64 // Submissions refused because the queue was full or the pool stopped
+
+	Delivering atomic.Int64 // Callbacks being delivered or their outcome persisted
 }
 
 // WorkerPoolStatus represents the status of the worker pool
@@ -34,10 +92,17 @@ type WorkerPoolStatus struct {
 // Worker represents a task worker
 type Worker struct {
 	id              int
+	name            string // Recorded as the task's worker ID
 	taskChan        <-chan *entity.Task
 	taskService     TaskService
 	callbackService *callback.Service
+	broadcaster     EventBroadcaster
 	wg              *sync.WaitGroup
+	counters        *Counters
+	limiter         *ConcurrencyLimiter // Optional; set by the pool
+	inFlight        *InFlight           // Optional; makes callbacks abortable
+	clock           clock.Clock
+	ctx             context.Context // Tasks are processed with it; cancelling it aborts their callbacks
 	quit            chan bool
 	logger          *zap.Logger
 }
@@ -48,15 +113,22 @@ func NewWorker(
 	taskChan <-chan *entity.Task,
 	taskService TaskService,
 	callbackService *callback.Service,
+	broadcaster EventBroadcaster,
 	wg *sync.WaitGroup,
+	counters *Counters,
 	logger *zap.Logger,
 ) *Worker {
 	return &Worker{
 		id:              id,
+		name:            fmt.Sprintf("worker-%d", id),
 		taskChan:        taskChan,
 		taskService:     taskService,
 		callbackService: callbackService,
+		broadcaster:     broadcaster,
 		wg:              wg,
+		counters:        counters,
+		clock:           clock.Real,
+		ctx:             context.Background(),
 		quit:            make(chan bool),
 		logger:          logger,
 	}
@@ -70,13 +142,21 @@ func (w *Worker) Start() {
 		w.logger.Info("Worker started", zap.Int("worker_id", w.id))
 
 		for {
+			// Prefer quitting over picking up queued tasks during shutdown
+			select {
+			case <-w.quit:
+				w.logger.Info("Worker stopping", zap.Int("worker_id", w.id))
+				return
+			default:
+			}
+
 			select {
 			case task := <-w.taskChan:
 				if task == nil {
 					// Channel closed
 					return
 				}
-				w.processTask(task)
+				w.run(task)
 
 			case <-w.quit:
 				w.logger.Info("Worker stopping", zap.Int("worker_id", w.id))
@@ -91,90 +171,312 @@ func (w *Worker) Stop() {
 	close(w.quit)
 }
 
-// processTask handles the execution of a single task
-func (w *Worker) processTask(task *entity.Task) {
-	ctx := context.Background()
+// run processes the task, then any waiting task of the same concurrency key handed its slot
+func (w *Worker) run(task *entity.Task) {
+	for task != nil {
+		w.counters.InFlight.Add(1)
+		w.safeProcessTask(w.ctx, task)
+		w.counters.InFlight.Add(-1)
+
+		if w.limiter == nil {
+			return
+		}
+		task = w.limiter.release(task)
 
+		select {
+		case <-w.quit:
+			if task != nil {
+				// Not started, so it stays pending in the database for the next start
+				w.limiter.abandon(task)
+			}
+			return
+		default:
+		}
+	}
+}
+
+// safeProcessTask processes a task, recovering from panics so the worker keeps running
+// A panicking task is failed like a callback error, so it is retried and eventually dead-lettered
+func (w *Worker) safeProcessTask(ctx context.Context, task *entity.Task) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.counters.Panics.Add(1)
+			w.logger.Error("Recovered panic while processing task",
+				zap.Int("worker_id", w.id),
+				zap.String("task_id", task.ID),
+				logger.RequestID(task.RequestID),
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+			w.failAfterPanic(ctx, task, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	w.processTask(ctx, task)
+}
+
+// failAfterPanic marks a task as failed, guarding against the failure path panicking too
+func (w *Worker) failAfterPanic(ctx context.Context, task *entity.Task, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Error("Panic while marking task as failed",
+				zap.Int("worker_id", w.id),
+				zap.String("task_id", task.ID),
+				logger.RequestID(task.RequestID),
+				zap.Any("panic", r))
+		}
+	}()
+
+	ctx, cancel := finalWriteContext(ctx)
+	defer cancel()
+	w.handleFailure(ctx, task, err)
+}
+
+// processTask handles the execution of a single task; cancelling ctx aborts its callback
+func (w *Worker) processTask(ctx context.Context, task *entity.Task) {
 	w.logger.Info("Processing task",
 		zap.Int("worker_id", w.id),
 		zap.String("task_id", task.ID),
+		logger.RequestID(task.RequestID),
 		zap.String("task_name", task.Name))
 
-	// Mark task as processing
-	workerID := fmt.Sprintf("worker-%d", w.id)
-	task.MarkAsProcessing(workerID)
-	task.WorkerID = workerID
+	// A task picked up past its expiry is stale, e.g. after an outage, so it isn't delivered
+	if task.IsExpired(w.clock.Now()) {
+		w.expire(ctx, task)
+		return
+	}
+
+	// A task for a paused host goes back to wait for the end of the pause
+	if until, held := w.heldUntil(task); held {
+		w.hold(ctx, task, until)
+		return
+	}
 
-	if err := w.taskService.UpdateTask(ctx, task); err != nil {
+	// Claim the task; it may have been deleted, or run by ExecuteNow, since it was submitted
+	claimed, err := w.claim(ctx, task, entity.TaskStatusPending)
+	if err != nil {
 		w.logger.Error("Failed to mark task as processing",
 			zap.Int("worker_id", w.id),
 			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID),
 			zap.Error(err))
 		return
 	}
+	if !claimed {
+		w.logger.Info("Task is no longer pending, skipping",
+			zap.Int("worker_id", w.id),
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID))
+		return
+	}
 
-	// Deliver callback
-	callbackErr := w.callbackService.DeliverCallback(ctx, task)
+	w.deliver(ctx, task)
+}
+
+// claim marks the task as processing by this worker if its stored status is one of from,
+// reporting whether it did; a task claimed elsewhere in the meantime is left alone
+func (w *Worker) claim(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	task.MarkAsProcessing(w.name, w.clock.Now())
+	task.WorkerID = w.name
 
-	if callbackErr != nil {
+	claimed, err := w.taskService.UpdateTaskIfStatus(ctx, task, from...)
+	if err != nil || !claimed {
+		return false, err
+	}
+	w.broadcast(task)
+	return true, nil
+}
+
+// heldUntil reports whether the task's callback host is paused, and until when
+func (w *Worker) heldUntil(task *entity.Task) (time.Time, bool) {
+	source, ok := w.taskService.(hostPauseSource)
+	if !ok {
+		return time.Time{}, false
+	}
+	return source.HostHeldUntil(entity.CallbackHost(task.CallbackURL), w.clock.Now())
+}
+
+// hold moves a pending task for a paused host to the end of the pause, so polling dispatches it
+// once the pause is over
+func (w *Worker) hold(ctx context.Context, task *entity.Task, until time.Time) {
+	task.ScheduledAt = until
+	held, err := w.taskService.UpdateTaskIfStatus(ctx, task, entity.TaskStatusPending)
+	if err != nil {
+		w.logger.Error("Failed to hold task for paused host",
+			zap.Int("worker_id", w.id),
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID),
+			zap.Error(err))
+		return
+	}
+	if !held {
+		w.logger.Info("Task is no longer pending, skipping",
+			zap.Int("worker_id", w.id),
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID))
+		return
+	}
+	w.broadcast(task)
+
+	w.logger.Info("Task held for paused callback host",
+		zap.Int("worker_id", w.id),
+		zap.String("task_id", task.ID),
+		logger.RequestID(task.RequestID),
+		zap.Time("until", until))
+}
+
+// expire moves a pending task past its expiry to the expired status without delivering it
+func (w *Worker) expire(ctx context.Context, task *entity.Task) {
+	task.MarkAsExpired(w.clock.Now())
+	expired, err := w.taskService.UpdateTaskIfStatus(ctx, task, entity.TaskStatusPending)
+	if err != nil {
+		w.logger.Error("Failed to mark task as expired",
+			zap.Int("worker_id", w.id),
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID),
+			zap.Error(err))
+		return
+	}
+	if !expired {
+		w.logger.Info("Task is no longer pending, skipping",
+			zap.Int("worker_id", w.id),
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID))
+		return
+	}
+	w.broadcast(task)
+	w.releaseDependents(ctx, task)
+
+	w.logger.Warn("Task expired before delivery",
+		zap.Int("worker_id", w.id),
+		zap.String("task_id", task.ID),
+		logger.RequestID(task.RequestID),
+		zap.Timep("expires_at", task.ExpiresAt))
+}
+
+// deliver delivers a claimed task's callback and persists the outcome
+// It returns the HTTP exchange and the callback error, nil if the task completed
+// Only the callback is aborted, by an operator or by cancelling ctx; the outcome is still
+// persisted, within FinalWriteGrace once ctx is cancelled
+func (w *Worker) deliver(ctx context.Context, task *entity.Task) (callback.Attempt, error) {
+	w.counters.Delivering.Add(1)
+	defer w.counters.Delivering.Add(-1)
+
+	callbackCtx, untrack := w.inFlight.track(ctx, task.ID)
+	attempt, callbackErr := w.callbackService.Deliver(callbackCtx, task)
+	abort := untrack()
+
+	interrupted := ctx.Err() != nil
+	ctx, cancel := finalWriteContext(ctx)
+	defer cancel()
+
+	if callbackErr != nil && abort == nil && interrupted {
+		// Not the receiver's fault, so the attempt doesn't count against the task's retries
+		w.release(ctx, task, callbackErr)
+	} else if callbackErr != nil {
+		if abort != nil {
+			callbackErr = abort
+		}
 		w.logger.Error("Task callback failed",
 			zap.Int("worker_id", w.id),
 			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID),
 			zap.Error(callbackErr))
 
-		// Handle failure with retry logic
-		if task.CanRetry() {
-			w.handleRetry(task, callbackErr)
+		// Permanent failures and terminal aborts skip the remaining retries
+		if errors.Is(callbackErr, callback.ErrPermanent) || (abort != nil && abort.terminal) {
+			w.deadLetter(ctx, task, callbackErr)
 		} else {
-			w.handleFailure(task, callbackErr)
+			w.handleFailure(ctx, task, callbackErr)
 		}
 	} else {
 		// Mark task as completed
-		task.MarkAsCompleted()
+		task.MarkAsCompleted(w.clock.Now())
 		if err := w.taskService.UpdateTask(ctx, task); err != nil {
 			w.logger.Error("Failed to mark task as completed",
 				zap.Int("worker_id", w.id),
 				zap.String("task_id", task.ID),
+				logger.RequestID(task.RequestID),
 				zap.Error(err))
-			return
+			return attempt, nil
 		}
+		w.broadcast(task)
+		w.releaseDependents(ctx, task)
 
 		w.logger.Info("Task completed successfully",
 			zap.Int("worker_id", w.id),
-			zap.String("task_id", task.ID))
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID))
 	}
+	return attempt, callbackErr
 }
 
-// handleRetry handles task retry with exponential backoff
-func (w *Worker) handleRetry(task *entity.Task, callbackErr error) {
-	ctx := context.Background()
-
-	// Use entity method for proper state transition
-	task.MarkAsFailed(callbackErr)
+// finalWriteContext returns the context a task's outcome is persisted with: ctx, or once ctx is
+// cancelled, a context without its cancellation bounded by FinalWriteGrace
+func finalWriteContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx.Err() == nil {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(context.WithoutCancel(ctx), FinalWriteGrace)
+}
 
-	// Update task in database
-	if err := w.taskService.UpdateTask(ctx, task); err != nil {
-		w.logger.Error("Failed to update task for retry",
+// release puts a task whose callback was interrupted by shutdown back to pending, so it is
+// delivered again once workers run, rather than left processing
+func (w *Worker) release(ctx context.Context, task *entity.Task, err error) {
+	task.Status = entity.TaskStatusPending
+	task.StartedAt = nil
+	task.WorkerID = ""
+	released, updateErr := w.taskService.UpdateTaskIfStatus(ctx, task, entity.TaskStatusProcessing)
+	if updateErr != nil {
+		w.logger.Error("Failed to release interrupted task",
 			zap.Int("worker_id", w.id),
 			zap.String("task_id", task.ID),
-			zap.Error(err))
+			logger.RequestID(task.RequestID),
+			zap.Error(updateErr))
+		return
+	}
+	if !released {
 		return
 	}
+	w.broadcast(task)
 
-	w.logger.Info("Task marked as failed for retry",
+	w.logger.Warn("Task callback interrupted by shutdown; it will be delivered again",
 		zap.Int("worker_id", w.id),
 		zap.String("task_id", task.ID),
-		zap.Int("retry_count", task.RetryCount),
-		zap.Time("next_retry_at", *task.NextRetryAt))
+		logger.RequestID(task.RequestID),
+		zap.Error(err))
 }
 
-// handleFailure handles task failure when max retries exceeded
-func (w *Worker) handleFailure(task *entity.Task, err error) {
-	ctx := context.Background()
+// deadLetter moves a task that must not be retried straight to the dead letter queue
+func (w *Worker) deadLetter(ctx context.Context, task *entity.Task, err error) {
+	task.RecordError(err, w.clock.Now())
+	task.MarkAsDeadLettered()
+	errMsg := err.Error()
+	task.ErrorMessage = &errMsg
 
+	if updateErr := w.taskService.UpdateTask(ctx, task); updateErr != nil {
+		w.logger.Error("Failed to mark task as dead_lettered",
+			zap.Int("worker_id", w.id),
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID),
+			zap.Error(updateErr))
+		return
+	}
+	w.broadcast(task)
+	w.releaseDependents(ctx, task)
+
+	w.logger.Error("Task moved to dead letter queue without retrying",
+		zap.Int("worker_id", w.id),
+		zap.String("task_id", task.ID),
+		logger.RequestID(task.RequestID),
+		zap.Error(err))
+}
+
+// handleFailure marks the task failed for a later retry, or dead-letters it once retries are exhausted
+func (w *Worker) handleFailure(ctx context.Context, task *entity.Task, err error) {
 	// Check if max retries exceeded
 	if task.RetryCount >= task.MaxRetries {
 		// Mark as dead lettered
+		task.RecordError(err, w.clock.Now())
 		task.MarkAsDeadLettered()
 		errMsg := fmt.Sprintf("Max retries (%d) exceeded: %v", task.MaxRetries, err)
 		task.ErrorMessage = &errMsg
@@ -183,25 +485,58 @@ func (w *Worker) handleFailure(task *entity.Task, err error) {
 			w.logger.Error("Failed to mark task as dead_lettered",
 				zap.Int("worker_id", w.id),
 				zap.String("task_id", task.ID),
+				logger.RequestID(task.RequestID),
 				zap.Error(updateErr))
 			return
 		}
+		w.broadcast(task)
+		w.releaseDependents(ctx, task)
 
 		w.logger.Error("Task moved to dead letter queue",
 			zap.Int("worker_id", w.id),
 			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID),
 			zap.Int("retry_count", task.RetryCount),
 			zap.Int("max_retries", task.MaxRetries))
 	} else {
 		// Just mark as failed
-		task.MarkAsFailed(err)
+		task.MarkAsFailedWithPolicy(err, w.clock.Now(), w.retryPolicy())
 		if updateErr := w.taskService.UpdateTask(ctx, task); updateErr != nil {
 			w.logger.Error("Failed to mark task as failed",
 				zap.Int("worker_id", w.id),
 				zap.String("task_id", task.ID),
+				logger.RequestID(task.RequestID),
 				zap.Error(updateErr))
 			return
 		}
+		w.broadcast(task)
+	}
+}
+
+// retryPolicy returns the policy the task service retries failed tasks under
+func (w *Worker) retryPolicy() entity.RetryPolicy {
+	if source, ok := w.taskService.(retryPolicySource); ok {
+		return source.RetryPolicy()
+	}
+	return entity.DefaultRetryPolicy
+}
+
+// releaseDependents moves tasks waiting on a finished task out of the waiting status
+// Failures are logged and leave the dependents waiting
+func (w *Worker) releaseDependents(ctx context.Context, task *entity.Task) {
+	if err := w.taskService.ResolveDependents(ctx, task); err != nil {
+		w.logger.Error("Failed to release dependent tasks",
+			zap.Int("worker_id", w.id),
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID),
+			zap.Error(err))
+	}
+}
+
+// broadcast publishes the task's persisted state if a broadcaster is configured
+func (w *Worker) broadcast(task *entity.Task) {
+	if w.broadcaster != nil {
+		w.broadcaster.BroadcastTaskUpdate(task)
 	}
 }
 
@@ -211,9 +546,66 @@ type workerPool struct {
 	taskChan        chan *entity.Task
 	taskService     TaskService
 	callbackService *callback.Service
+	broadcaster     EventBroadcaster
 	wg              *sync.WaitGroup
+	counters        Counters
+	limiter         *ConcurrencyLimiter
+	inFlight        *InFlight
+	clock           clock.Clock
+	queueBuffer     int
 	logger          *zap.Logger
-	quit            chan bool
+	mu              sync.RWMutex // Guards stopped against concurrent SubmitTask
+	stopped         bool
+
+	parent context.Context         // Set with WithContext
+	ctx    context.Context         // Workers process tasks with it
+	cancel context.CancelCauseFunc // Aborts the callbacks in flight
+}
+
+// PoolOption configures optional worker pool behaviour
+type PoolOption func(*workerPool)
+
+// WithConcurrencyLimiter caps the tasks in flight per concurrency key
+// Tasks of a saturated key are held by the limiter and started as that key's tasks finish
+func WithConcurrencyLimiter(limiter *ConcurrencyLimiter) PoolOption {
+	return func(p *workerPool) {
+		p.limiter = limiter
+	}
+}
+
+// WithInFlight tracks the tasks the pool's workers deliver, so they can be aborted with f
+func WithInFlight(f *InFlight) PoolOption {
+	return func(p *workerPool) {
+		p.inFlight = f
+	}
+}
+
+// WithQueueBuffer sets how many submitted tasks the pool buffers for its workers,
+// independently of the pool size; zero keeps the default of QueueCapacity
+func WithQueueBuffer(size int) PoolOption {
+	return func(p *workerPool) {
+		p.queueBuffer = size
+	}
+}
+
+// WithClock sets the clock the pool's workers timestamp tasks and schedule retries with
+func WithClock(c clock.Clock) PoolOption {
+	return func(p *workerPool) {
+		p.clock = c
+	}
+}
+
+// WithContext derives the context workers process tasks with from ctx, so cancelling it aborts
+// their callbacks in flight as a missed shutdown deadline does
+func WithContext(ctx context.Context) PoolOption {
+	return func(p *workerPool) {
+		p.parent = ctx
+	}
+}
+
+// QueueCapacity returns how many submitted tasks a pool of workerCount workers buffers by default
+func QueueCapacity(workerCount int) int {
+	return workerCount * 2
 }
 
 // NewWorkerPool creates a new worker pool
@@ -221,16 +613,31 @@ func NewWorkerPool(
 	workerCount int,
 	taskService TaskService,
 	callbackService *callback.Service,
+	broadcaster EventBroadcaster,
 	logger *zap.Logger,
+	opts ...PoolOption,
 ) WorkerPool {
-	return &workerPool{
-		taskChan:        make(chan *entity.Task, workerCount*2),
+	p := &workerPool{
 		taskService:     taskService,
 		callbackService: callbackService,
+		broadcaster:     broadcaster,
 		wg:              &sync.WaitGroup{},
+		clock:           clock.Real,
 		logger:          logger,
-		quit:            make(chan bool),
+		parent:          context.Background(),
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	p.ctx, p.cancel = context.WithCancelCause(p.parent)
+	if p.queueBuffer <= 0 {
+		p.queueBuffer = QueueCapacity(workerCount)
+	}
+	p.taskChan = make(chan *entity.Task, p.queueBuffer)
+	if p.limiter != nil {
+		p.limiter.attach(p.admit)
+	}
+	return p
 }
 
 // Start initializes and starts all workers
@@ -242,9 +649,15 @@ func (p *workerPool) Start(workerCount int) {
 			p.taskChan,
 			p.taskService,
 			p.callbackService,
+			p.broadcaster,
 			p.wg,
+			&p.counters,
 			p.logger,
 		)
+		p.workers[i].limiter = p.limiter
+		p.workers[i].inFlight = p.inFlight
+		p.workers[i].clock = p.clock
+		p.workers[i].ctx = p.ctx
 		p.workers[i].Start()
 	}
 
@@ -254,7 +667,16 @@ func (p *workerPool) Start(workerCount int) {
 }
 
 // Stop gracefully shuts down all workers
-func (p *workerPool) Stop() {
+func (p *workerPool) Stop(ctx context.Context) int {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return 0
+	}
+	p.stopped = true
+	p.mu.Unlock()
+	defer p.cancel(ErrShuttingDown)
+
 	p.logger.Info("Stopping worker pool")
 
 	// Stop all workers
@@ -262,7 +684,22 @@ func (p *workerPool) Stop() {
 		worker.Stop()
 	}
 
-	// Wait for all workers to finish
+	// Queued tasks are not started; no sender can be active once stopped is set
+	abandoned := 0
+	for drained := false; !drained; {
+		select {
+		case <-p.taskChan:
+			abandoned++
+		default:
+			drained = true
+		}
+	}
+	close(p.taskChan)
+	if p.limiter != nil {
+		abandoned += p.limiter.drain()
+	}
+
+	// Wait for in-flight tasks to finish
 	done := make(chan struct{})
 	go func() {
 		p.wg.Wait()
@@ -271,16 +708,87 @@ func (p *workerPool) Stop() {
 
 	select {
 	case <-done:
-		p.logger.Info("All workers stopped")
-	case <-time.After(30 * time.Second):
-		p.logger.Warn("Timeout waiting for workers to stop")
+		p.logger.Info("All workers stopped", zap.Int("abandoned", abandoned))
+	case <-ctx.Done():
+		inFlight := int(p.counters.InFlight.Load())
+		p.cancel(ErrShuttingDown)
+		p.awaitDeliveries(done)
+		p.logger.Warn("Shutdown deadline reached before workers stopped",
+			zap.Int("abandoned", abandoned+inFlight),
+			zap.Int("abandoned_queued", abandoned),
+			zap.Int("abandoned_in_flight", inFlight),
+			zap.Error(ctx.Err()),
+		)
+		abandoned += inFlight
 	}
 
-	close(p.taskChan)
+	return abandoned
+}
+
+// awaitDeliveries waits, up to FinalWriteGrace, for workers whose callbacks were cancelled to
+// record their tasks' outcome; workers stuck elsewhere, e.g. on the database, aren't waited for
+func (p *workerPool) awaitDeliveries(done <-chan struct{}) {
+	deadline := time.NewTimer(FinalWriteGrace)
+	defer deadline.Stop()
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+
+	for p.counters.Delivering.Load() > 0 {
+		select {
+		case <-done:
+			return
+		case <-deadline.C:
+			return
+		case <-tick.C:
+		}
+	}
 }
 
 // SubmitTask submits a task to the worker pool
+// Returns false if the pool is full or stopped
 func (p *workerPool) SubmitTask(task *entity.Task) bool {
+	if accepted, _ := p.submit(task); accepted {
+		return true
+	}
+	p.counters.Rejected.Add(1)
+	return false
+}
+
+// SubmitTaskWithin submits a task, waiting up to wait for queue space if the pool is full
+// Returns false if the queue stayed full or the pool is stopped
+func (p *workerPool) SubmitTaskWithin(task *entity.Task, wait time.Duration) bool {
+	deadline := time.Now().Add(wait)
+	for {
+		accepted, stopped := p.submit(task)
+		if accepted {
+			return true
+		}
+		remaining := time.Until(deadline)
+		if stopped || remaining <= 0 {
+			p.counters.Rejected.Add(1)
+			return false
+		}
+		time.Sleep(min(admitRetryInterval, remaining))
+	}
+}
+
+// submit queues the task without blocking, reporting whether it was accepted and whether
+// the pool is stopped
+func (p *workerPool) submit(task *entity.Task) (accepted, stopped bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return false, true
+	}
+	if p.limiter != nil {
+		return p.limiter.submit(task, p.trySend), false
+	}
+	return p.trySend(task), false
+}
+
+// trySend queues the task without blocking; callers hold p.mu
+func (p *workerPool) trySend(task *entity.Task) bool {
 	select {
 	case p.taskChan <- task:
 		return true
@@ -289,7 +797,64 @@ func (p *workerPool) SubmitTask(task *entity.Task) bool {
 	}
 }
 
+// admit queues tasks the limiter released after a limit change
+// They already hold their slots, so they wait for queue space rather than being refused
+func (p *workerPool) admit(tasks []*entity.Task) {
+	go func() {
+		for _, task := range tasks {
+			for !p.requeue(task) {
+				time.Sleep(admitRetryInterval)
+			}
+		}
+	}()
+}
+
+// admitRetryInterval is how often admit retries a full queue
+const admitRetryInterval = 10 * time.Millisecond
+
+// requeue queues an admitted task, reporting false if the queue is full
+// Once the pool is stopped the task is abandoned instead
+func (p *workerPool) requeue(task *entity.Task) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		p.limiter.abandon(task)
+		return true
+	}
+	return p.trySend(task)
+}
+
 // WorkerCount returns the number of active workers
 func (p *workerPool) WorkerCount() int {
 	return len(p.workers)
 }
+
+// PanicCount returns the number of panics recovered while processing tasks
+func (p *workerPool) PanicCount() int64 {
+	return p.counters.Panics.Load()
+}
+
+// QueueDepth returns the number of submitted tasks waiting for a worker
+func (p *workerPool) QueueDepth() int {
+	return len(p.taskChan)
+}
+
+// QueueCapacity returns how many submitted tasks the pool buffers
+func (p *workerPool) QueueCapacity() int {
+	return cap(p.taskChan)
+}
+
+// SaturatedKeys returns the concurrency keys whose limit is reached, if the pool has a limiter
+func (p *workerPool) SaturatedKeys() []string {
+	if p.limiter == nil {
+		return nil
+	}
+	return p.limiter.Saturated()
+}
+
+// RejectedCount returns the number of submissions refused because the queue was full or
+// the pool stopped
+func (p *workerPool) RejectedCount() int64 {
+	return p.counters.Rejected.Load()
+}
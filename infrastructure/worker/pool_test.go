@@ -0,0 +1,557 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/clock"
+)
+
+// blockingTaskService blocks UpdateTask until released, then fails it so the worker returns
+type blockingTaskService struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (s *blockingTaskService) GetTask(ctx context.Context, id string) (*entity.Task, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *blockingTaskService) ResolveDependents(ctx context.Context, parent *entity.Task) error {
+	return nil
+}
+
+func (s *blockingTaskService) UpdateTask(ctx context.Context, task *entity.Task) error {
+	s.once.Do(func() { close(s.started) })
+	<-s.release
+	return errors.New("released")
+}
+
+// UpdateTaskIfStatus claims like an unconditional update, as these tests don't race claims
+func (s *blockingTaskService) UpdateTaskIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	err := s.UpdateTask(ctx, task)
+	return err == nil, err
+}
+
+func newBlockingPool(workers int) (WorkerPool, *blockingTaskService) {
+	svc := &blockingTaskService{started: make(chan struct{}), release: make(chan struct{})}
+	pool := NewWorkerPool(workers, svc, nil, nil, zap.NewNop())
+	return pool, svc
+}
+
+func TestWorkerPoolStopRespectsDeadline(t *testing.T) {
+	pool, svc := newBlockingPool(1)
+	pool.Start(1)
+	defer close(svc.release)
+
+	require.True(t, pool.SubmitTask(&entity.Task{ID: "running"}))
+	<-svc.started
+	require.True(t, pool.SubmitTask(&entity.Task{ID: "queued"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	abandoned := pool.Stop(ctx)
+
+	assert.Less(t, time.Since(start), time.Second, "Stop must return at the caller's deadline")
+	assert.Equal(t, 2, abandoned, "one in-flight task and one queued task")
+}
+
+func TestWorkerPoolStopWaitsForInFlight(t *testing.T) {
+	pool, svc := newBlockingPool(1)
+	pool.Start(1)
+
+	require.True(t, pool.SubmitTask(&entity.Task{ID: "running"}))
+	<-svc.started
+
+	time.AfterFunc(20*time.Millisecond, func() { close(svc.release) })
+	abandoned := pool.Stop(context.Background())
+
+	assert.Equal(t, 0, abandoned)
+}
+
+func TestWorkerPoolRejectsSubmitAfterStop(t *testing.T) {
+	pool, _ := newBlockingPool(1)
+	pool.Start(1)
+	pool.Stop(context.Background())
+
+	assert.NotPanics(t, func() {
+		assert.False(t, pool.SubmitTask(&entity.Task{ID: "late"}))
+	})
+	assert.Equal(t, 0, pool.Stop(context.Background()), "Stop is idempotent")
+}
+
+func TestWorkerPoolSubmitTaskWithin(t *testing.T) {
+	p, svc := newBlockingPool(1)
+	pool := p.(*workerPool)
+	pool.Start(1)
+	defer pool.Stop(context.Background())
+
+	require.True(t, pool.SubmitTask(&entity.Task{ID: "running"}))
+	<-svc.started
+	require.True(t, pool.SubmitTask(&entity.Task{ID: "queued-1"}))
+	require.True(t, pool.SubmitTask(&entity.Task{ID: "queued-2"}))
+
+	// The queue is full: failing fast and waiting both count a rejection
+	assert.False(t, pool.SubmitTask(&entity.Task{ID: "fast"}))
+	start := time.Now()
+	assert.False(t, pool.SubmitTaskWithin(&entity.Task{ID: "waited"}, 50*time.Millisecond))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, int64(2), pool.RejectedCount())
+
+	// Space freed during the wait is used
+	time.AfterFunc(20*time.Millisecond, func() { close(svc.release) })
+	assert.True(t, pool.SubmitTaskWithin(&entity.Task{ID: "admitted"}, time.Second))
+	assert.Equal(t, int64(2), pool.RejectedCount())
+}
+
+func TestWorkerPoolQueueBuffer(t *testing.T) {
+	svc := &blockingTaskService{started: make(chan struct{}), release: make(chan struct{})}
+
+	pool := NewWorkerPool(4, svc, nil, nil, zap.NewNop()).(*workerPool)
+	assert.Equal(t, QueueCapacity(4), pool.QueueCapacity(), "defaults to twice the pool size")
+
+	pool = NewWorkerPool(1, svc, nil, nil, zap.NewNop(), WithQueueBuffer(3)).(*workerPool)
+	assert.Equal(t, 3, pool.QueueCapacity())
+	for i := 0; i < 3; i++ {
+		require.True(t, pool.SubmitTask(&entity.Task{ID: strconv.Itoa(i)}))
+	}
+	assert.Equal(t, 3, pool.QueueDepth())
+	assert.False(t, pool.SubmitTask(&entity.Task{ID: "overflow"}))
+}
+
+// slowTaskService stands in for a callback taking delay, then fails the task so the worker returns
+type slowTaskService struct {
+	delay time.Duration
+}
+
+func (s slowTaskService) GetTask(ctx context.Context, id string) (*entity.Task, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s slowTaskService) ResolveDependents(ctx context.Context, parent *entity.Task) error {
+	return nil
+}
+
+func (s slowTaskService) UpdateTask(ctx context.Context, task *entity.Task) error {
+	time.Sleep(s.delay)
+	return errors.New("done")
+}
+
+func (s slowTaskService) UpdateTaskIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	err := s.UpdateTask(ctx, task)
+	return err == nil, err
+}
+
+// BenchmarkWorkerPoolQueueBuffer submits bursts of tasks to 20 workers the way the scheduler
+// does: tasks that don't fit are retried after a short poll interval. Smaller buffers spend
+// more time waiting on polls and report more rejections per task
+func BenchmarkWorkerPoolQueueBuffer(b *testing.B) {
+	const (
+		workers      = 20
+		burst        = 500
+		pollInterval = time.Millisecond
+	)
+	for _, buffer := range []int{workers, QueueCapacity(workers), 200, 1000} {
+		b.Run(fmt.Sprintf("buffer=%d", buffer), func(b *testing.B) {
+			pool := NewWorkerPool(workers, slowTaskService{delay: 50 * time.Microsecond}, nil, nil, zap.NewNop(),
+				WithQueueBuffer(buffer)).(*workerPool)
+			pool.Start(workers)
+			defer pool.Stop(context.Background())
+
+			task := &entity.Task{ID: "bench"}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for pending := burst; pending > 0; {
+					for pending > 0 && pool.SubmitTask(task) {
+						pending--
+					}
+					if pending > 0 {
+						time.Sleep(pollInterval)
+					}
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(pool.RejectedCount())/float64(b.N*burst), "rejections/task")
+		})
+	}
+}
+
+// panickingTaskService panics when a task named "bad" is marked as processing
+type panickingTaskService struct {
+	mu      sync.Mutex
+	updates []*entity.Task
+}
+
+func (s *panickingTaskService) GetTask(ctx context.Context, id string) (*entity.Task, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *panickingTaskService) ResolveDependents(ctx context.Context, parent *entity.Task) error {
+	return nil
+}
+
+func (s *panickingTaskService) UpdateTask(ctx context.Context, task *entity.Task) error {
+	if task.Name == "bad" && task.Status == entity.TaskStatusProcessing {
+		panic("malformed payload")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := *task
+	s.updates = append(s.updates, &snapshot)
+
+	// Stop before callback delivery, which these tests don't exercise
+	return errors.New("stop here")
+}
+
+func (s *panickingTaskService) UpdateTaskIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	err := s.UpdateTask(ctx, task)
+	return err == nil, err
+}
+
+func TestWorkerRecoversFromPanic(t *testing.T) {
+	svc := &panickingTaskService{}
+	pool := NewWorkerPool(1, svc, nil, nil, zap.NewNop())
+	pool.Start(1)
+
+	require.True(t, pool.SubmitTask(&entity.Task{ID: "1", Name: "bad", MaxRetries: 3}))
+	require.True(t, pool.SubmitTask(&entity.Task{ID: "2", Name: "good"}))
+
+	require.Eventually(t, func() bool {
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+		return len(svc.updates) == 2
+	}, time.Second, 10*time.Millisecond, "the worker must keep processing after a panic")
+	pool.Stop(context.Background())
+
+	failed := svc.updates[0]
+	assert.Equal(t, "1", failed.ID)
+	assert.Equal(t, entity.TaskStatusFailed, failed.Status)
+	require.NotNil(t, failed.ErrorMessage)
+	assert.Contains(t, *failed.ErrorMessage, "malformed payload")
+
+	assert.Equal(t, "2", svc.updates[1].ID)
+	assert.Equal(t, int64(1), pool.(*workerPool).PanicCount())
+}
+
+// recordingTaskService records every persisted task state and each parent whose dependents are released
+type recordingTaskService struct {
+	mu       sync.Mutex
+	updates  []entity.Task
+	resolved []string
+}
+
+func (s *recordingTaskService) GetTask(ctx context.Context, id string) (*entity.Task, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *recordingTaskService) ResolveDependents(ctx context.Context, parent *entity.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolved = append(s.resolved, parent.ID)
+	return nil
+}
+
+func (s *recordingTaskService) UpdateTask(ctx context.Context, task *entity.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updates = append(s.updates, *task)
+	return nil
+}
+
+func (s *recordingTaskService) UpdateTaskIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	err := s.UpdateTask(ctx, task)
+	return err == nil, err
+}
+
+// settled returns the last persisted state once the task has left processing
+func (s *recordingTaskService) settled() (entity.Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.updates) == 0 || s.updates[len(s.updates)-1].Status == entity.TaskStatusProcessing {
+		return entity.Task{}, false
+	}
+	return s.updates[len(s.updates)-1], true
+}
+
+func TestWorkerHandlesCallbackFailures(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(code)
+	}))
+	defer receiver.Close()
+
+	tests := []struct {
+		name         string
+		status       int
+		wantStatus   entity.TaskStatus
+		wantRetry    int
+		wantErrors   int // Entries in the error history
+		expired      bool
+		wantResolved bool // Dependents are released once the task can no longer complete
+	}{
+		{name: "Success completes the task", status: 200, wantStatus: entity.TaskStatusCompleted, wantRetry: 0, wantResolved: true},
+		{name: "Retryable failure is scheduled for retry", status: 503, wantStatus: entity.TaskStatusFailed, wantRetry: 1, wantErrors: 1},
+		{name: "Permanent failure is dead-lettered", status: 422, wantStatus: entity.TaskStatusDeadLettered, wantRetry: 0, wantErrors: 1, wantResolved: true},
+		{name: "Stale task is expired without delivery", status: 200, expired: true, wantStatus: entity.TaskStatusExpired, wantRetry: 0, wantResolved: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &recordingTaskService{}
+			clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			callbackSvc := callback.NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop(), callback.WithClock(clk))
+			pool := NewWorkerPool(1, svc, callbackSvc, nil, zap.NewNop(), WithClock(clk))
+			pool.Start(1)
+
+			task := &entity.Task{
+				ID:                  "1",
+				CallbackURL:         fmt.Sprintf("%s/?status=%d", receiver.URL, tt.status),
+				MaxRetries:          3,
+				RetryBackoffSeconds: 10,
+			}
+			if tt.expired {
+				expiresAt := clk.Now().Add(-time.Second)
+				task.ExpiresAt = &expiresAt
+			}
+			require.True(t, pool.SubmitTask(task))
+			require.Eventually(t, func() bool {
+				_, ok := svc.settled()
+				return ok
+			}, time.Second, 10*time.Millisecond)
+			pool.Stop(context.Background())
+
+			final, _ := svc.settled()
+			assert.Equal(t, tt.wantStatus, final.Status)
+			assert.Equal(t, tt.wantRetry, final.RetryCount)
+			assert.Len(t, final.ErrorHistory, tt.wantErrors)
+			if tt.expired {
+				assert.Zero(t, final.CallbackAttempts)
+				assert.Nil(t, final.StartedAt, "never claimed")
+			} else {
+				assert.Equal(t, clk.Now(), *final.StartedAt)
+				assert.Equal(t, clk.Now(), *final.LastCallbackAt)
+			}
+			if tt.wantStatus == entity.TaskStatusFailed {
+				// The first retry backs off twice the task's 10s, give or take 25% jitter
+				require.NotNil(t, final.NextRetryAt)
+				assert.WithinRange(t, *final.NextRetryAt, clk.Now().Add(15*time.Second), clk.Now().Add(25*time.Second))
+			}
+
+			svc.mu.Lock()
+			defer svc.mu.Unlock()
+			if tt.wantResolved {
+				assert.Equal(t, []string{"1"}, svc.resolved)
+			} else {
+				assert.Empty(t, svc.resolved)
+			}
+		})
+	}
+}
+
+// TestWorkerRetryBackoffProgression fails a task until it is dead-lettered, advancing a fake
+// clock to each retry, and checks the backoff doubles between attempts
+func TestWorkerRetryBackoffProgression(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	svc := &recordingTaskService{}
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	callbackSvc := callback.NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop(), callback.WithClock(clk))
+	pool := NewWorkerPool(1, svc, callbackSvc, nil, zap.NewNop(), WithClock(clk))
+	pool.Start(1)
+	defer pool.Stop(context.Background())
+
+	task := &entity.Task{ID: "1", CallbackURL: receiver.URL, MaxRetries: 4, RetryBackoffSeconds: 10}
+	for attempt := 1; attempt <= task.MaxRetries+1; attempt++ {
+		svc.mu.Lock()
+		svc.updates = nil
+		svc.mu.Unlock()
+
+		require.True(t, pool.SubmitTask(task))
+		require.Eventually(t, func() bool {
+			_, ok := svc.settled()
+			return ok
+		}, time.Second, time.Millisecond)
+		final, _ := svc.settled()
+		assert.Equal(t, clk.Now(), final.ErrorHistory[len(final.ErrorHistory)-1].At, "attempt %d", attempt)
+
+		if attempt > task.MaxRetries {
+			assert.Equal(t, entity.TaskStatusDeadLettered, final.Status)
+			assert.Equal(t, task.MaxRetries, final.RetryCount)
+			break
+		}
+
+		require.Equal(t, entity.TaskStatusFailed, final.Status, "attempt %d", attempt)
+		require.Equal(t, attempt, final.RetryCount)
+		backoff := time.Duration(10<<attempt) * time.Second
+		assert.WithinRange(t, *final.NextRetryAt, clk.Now().Add(backoff*3/4), clk.Now().Add(backoff*5/4),
+			"attempt %d backs off about %s", attempt, backoff)
+
+		// The scheduler hands the task back once its retry is due
+		clk.Advance(final.NextRetryAt.Sub(clk.Now()))
+		task = &final
+		task.Status = entity.TaskStatusPending
+	}
+}
+
+// policyTaskService retries failed tasks under its own policy
+type policyTaskService struct {
+	recordingTaskService
+	policy entity.RetryPolicy
+}
+
+func (s *policyTaskService) RetryPolicy() entity.RetryPolicy {
+	return s.policy
+}
+
+func TestWorkerUsesTheServiceRetryPolicy(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	svc := &policyTaskService{policy: entity.RetryPolicy{JitterPercent: 0, MaxBackoff: 30 * time.Second}}
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	callbackSvc := callback.NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop(), callback.WithClock(clk))
+	pool := NewWorkerPool(1, svc, callbackSvc, nil, zap.NewNop(), WithClock(clk))
+	pool.Start(1)
+	defer pool.Stop(context.Background())
+
+	// Without jitter the delays are exact: 20s after the first failure, then capped at 30s
+	task := &entity.Task{ID: "1", CallbackURL: receiver.URL, MaxRetries: 4, RetryBackoffSeconds: 10}
+	for attempt, backoff := range []time.Duration{20 * time.Second, 30 * time.Second} {
+		svc.mu.Lock()
+		svc.updates = nil
+		svc.mu.Unlock()
+
+		require.True(t, pool.SubmitTask(task))
+		require.Eventually(t, func() bool {
+			_, ok := svc.settled()
+			return ok
+		}, time.Second, time.Millisecond)
+		final, _ := svc.settled()
+		require.Equal(t, entity.TaskStatusFailed, final.Status, "attempt %d", attempt+1)
+		assert.Equal(t, clk.Now().Add(backoff), *final.NextRetryAt, "attempt %d", attempt+1)
+
+		task = &final
+		task.Status = entity.TaskStatusPending
+	}
+}
+
+// pausingTaskService holds the callbacks to one host
+type pausingTaskService struct {
+	recordingTaskService
+	host  string
+	until time.Time
+}
+
+func (s *pausingTaskService) HostHeldUntil(host string, now time.Time) (time.Time, bool) {
+	return s.until, host == s.host
+}
+
+func TestWorkerHoldsTasksForPausedHosts(t *testing.T) {
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	svc := &pausingTaskService{host: "partner.example.com", until: clk.Now().Add(time.Hour)}
+	callbackSvc := callback.NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop())
+	pool := NewWorkerPool(1, svc, callbackSvc, nil, zap.NewNop(), WithClock(clk))
+	pool.Start(1)
+	defer pool.Stop(context.Background())
+
+	held := &entity.Task{ID: "held", CallbackURL: "https://Partner.example.com/hooks", Status: entity.TaskStatusPending, ScheduledAt: clk.Now()}
+	require.True(t, pool.SubmitTask(held))
+	require.Eventually(t, func() bool {
+		_, ok := svc.settled()
+		return ok
+	}, time.Second, time.Millisecond)
+	final, _ := svc.settled()
+	assert.Equal(t, entity.TaskStatusPending, final.Status)
+	assert.Equal(t, svc.until, final.ScheduledAt)
+	assert.Zero(t, final.CallbackAttempts)
+
+	// Other hosts are delivered as usual
+	require.True(t, pool.SubmitTask(&entity.Task{ID: "delivered", CallbackURL: receiver.URL, Status: entity.TaskStatusPending}))
+	require.Eventually(t, func() bool {
+		final, ok := svc.settled()
+		return ok && final.ID == "delivered"
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), hits.Load())
+}
+
+// liveContextTaskService refuses writes made with a cancelled context, as a database driver does
+type liveContextTaskService struct {
+	recordingTaskService
+}
+
+func (s *liveContextTaskService) UpdateTaskIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.recordingTaskService.UpdateTaskIfStatus(ctx, task, from...)
+}
+
+func TestStopInterruptsSlowCallbacks(t *testing.T) {
+	received := make(chan struct{})
+	interrupted := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		select {
+		case <-r.Context().Done():
+			close(interrupted)
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer receiver.Close()
+
+	svc := &liveContextTaskService{}
+	callbackSvc := callback.NewService(&http.Client{Timeout: time.Minute}, nil, "", 0, zap.NewNop())
+	pool := NewWorkerPool(1, svc, callbackSvc, nil, zap.NewNop())
+	pool.Start(1)
+
+	task := &entity.Task{ID: "slow", CallbackURL: receiver.URL, Status: entity.TaskStatusPending, MaxRetries: 3}
+	require.True(t, pool.SubmitTask(task))
+	<-received
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	assert.Equal(t, 1, pool.Stop(ctx), "the interrupted task is reported abandoned")
+	assert.Less(t, time.Since(started), time.Second, "Stop waits for the callback to be cancelled, not to finish")
+
+	select {
+	case <-interrupted:
+	case <-time.After(time.Second):
+		t.Fatal("the receiver's request wasn't cancelled")
+	}
+	final, ok := svc.settled()
+	require.True(t, ok, "the interrupted task's state is written after the deadline")
+	assert.Equal(t, entity.TaskStatusPending, final.Status, "the task runs again on the next start")
+	assert.Zero(t, final.RetryCount, "shutdown doesn't use up a retry")
+	assert.Nil(t, final.StartedAt)
+}
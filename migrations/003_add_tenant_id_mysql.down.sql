@@ -0,0 +1,6 @@
+-- Remove index
+DROP INDEX idx_tasks_tenant_status ON task_queue;
+
+-- Remove tenant ownership column
+ALTER TABLE task_queue
+DROP COLUMN tenant_id;
@@ -0,0 +1,6 @@
+-- Add tenant ownership column (empty string means no tenant)
+ALTER TABLE task_queue
+ADD COLUMN tenant_id VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Add index for tenant-scoped listing and stats
+CREATE INDEX idx_tasks_tenant_status ON task_queue(tenant_id, status);
@@ -0,0 +1,2 @@
+-- Remove index
+DROP INDEX idx_tasks_name ON task_queue;
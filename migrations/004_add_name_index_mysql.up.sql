@@ -0,0 +1,2 @@
+-- Add index for exact and prefix name searches
+CREATE INDEX idx_tasks_name ON task_queue(name);
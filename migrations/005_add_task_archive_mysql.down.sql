@@ -0,0 +1,2 @@
+-- Remove archive table
+DROP TABLE IF EXISTS task_queue_archive;
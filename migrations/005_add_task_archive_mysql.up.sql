@@ -0,0 +1,9 @@
+-- Archive table for tasks removed by the cleanup job
+-- Columns mirror task_queue followed by archived_at; keep them in sync when task_queue changes
+CREATE TABLE IF NOT EXISTS task_queue_archive LIKE task_queue;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN archived_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- Add index for time-based audit queries
+CREATE INDEX idx_tasks_archive_archived_at ON task_queue_archive(archived_at);
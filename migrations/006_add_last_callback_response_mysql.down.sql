@@ -0,0 +1,6 @@
+-- Remove last callback response column
+ALTER TABLE task_queue_archive
+DROP COLUMN last_callback_response;
+
+ALTER TABLE task_queue
+DROP COLUMN last_callback_response;
@@ -0,0 +1,7 @@
+-- Store a truncated snippet of the last callback response body
+-- Added after last_callback_error in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN last_callback_response TEXT NULL AFTER last_callback_error;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN last_callback_response TEXT NULL AFTER last_callback_error;
@@ -0,0 +1,6 @@
+-- Remove callback OAuth2 override column
+ALTER TABLE task_queue_archive
+DROP COLUMN callback_oauth2;
+
+ALTER TABLE task_queue
+DROP COLUMN callback_oauth2;
@@ -0,0 +1,7 @@
+-- Per-task OAuth2 client-credentials override for callback delivery, stored as JSON
+-- Added after last_callback_response in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN callback_oauth2 JSON NULL AFTER last_callback_response;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN callback_oauth2 JSON NULL AFTER last_callback_response;
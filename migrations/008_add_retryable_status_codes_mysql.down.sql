@@ -0,0 +1,6 @@
+-- Remove retryable status codes column
+ALTER TABLE task_queue_archive
+DROP COLUMN retryable_status_codes;
+
+ALTER TABLE task_queue
+DROP COLUMN retryable_status_codes;
@@ -0,0 +1,7 @@
+-- Per-task override of the retryable callback response status codes, stored as a JSON array
+-- Added after callback_oauth2 in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN retryable_status_codes JSON NULL AFTER callback_oauth2;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN retryable_status_codes JSON NULL AFTER callback_oauth2;
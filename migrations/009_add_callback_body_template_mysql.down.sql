@@ -0,0 +1,6 @@
+-- Remove callback body template column
+ALTER TABLE task_queue_archive
+DROP COLUMN callback_body_template;
+
+ALTER TABLE task_queue
+DROP COLUMN callback_body_template;
@@ -0,0 +1,7 @@
+-- Optional text/template rendering the callback body from the task
+-- Added after retryable_status_codes in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN callback_body_template TEXT NULL AFTER retryable_status_codes;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN callback_body_template TEXT NULL AFTER retryable_status_codes;
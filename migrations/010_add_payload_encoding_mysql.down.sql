@@ -0,0 +1,7 @@
+-- Remove payload encoding column
+-- Compressed payloads must be decompressed before rolling back, or they will be read as base64 strings
+ALTER TABLE task_queue_archive
+DROP COLUMN payload_encoding;
+
+ALTER TABLE task_queue
+DROP COLUMN payload_encoding;
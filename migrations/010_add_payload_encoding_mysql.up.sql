@@ -0,0 +1,7 @@
+-- How payload is stored: 'json' as is, or 'gzip' compressed and base64-encoded into a JSON string
+-- Added after callback_body_template in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN payload_encoding VARCHAR(16) NOT NULL DEFAULT 'json' AFTER callback_body_template;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN payload_encoding VARCHAR(16) NOT NULL DEFAULT 'json' AFTER callback_body_template;
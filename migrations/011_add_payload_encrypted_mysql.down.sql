@@ -0,0 +1,7 @@
+-- Remove payload encryption flag
+-- Encrypted payloads must be decrypted before rolling back, or they will be read as ciphertext
+ALTER TABLE task_queue_archive
+DROP COLUMN payload_encrypted;
+
+ALTER TABLE task_queue
+DROP COLUMN payload_encrypted;
@@ -0,0 +1,7 @@
+-- Whether payload holds AES-GCM ciphertext, stored as {"key_id": ..., "ciphertext": ...}
+-- Added after payload_encoding in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN payload_encrypted BOOLEAN NOT NULL DEFAULT FALSE AFTER payload_encoding;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN payload_encrypted BOOLEAN NOT NULL DEFAULT FALSE AFTER payload_encoding;
@@ -0,0 +1,2 @@
+-- Remove leader election leases
+DROP TABLE IF EXISTS scheduler_lock;
@@ -0,0 +1,9 @@
+-- Leases for leader election between replicas; the row named 'scheduler' is held by the
+-- replica running the scheduler. expires_at is in UTC and compared with the database clock,
+-- so replica clocks don't need to agree
+CREATE TABLE IF NOT EXISTS scheduler_lock (
+    name VARCHAR(64) NOT NULL PRIMARY KEY,
+    holder VARCHAR(255) NOT NULL,
+    expires_at DATETIME(3) NOT NULL,
+    acquired_at DATETIME(3) NOT NULL
+);
@@ -0,0 +1,6 @@
+-- Remove concurrency key
+ALTER TABLE task_queue_archive
+DROP COLUMN concurrency_key;
+
+ALTER TABLE task_queue
+DROP COLUMN concurrency_key;
@@ -0,0 +1,7 @@
+-- Concurrency group for per-key in-flight limits; NULL falls back to the first tag
+-- Added after payload_encrypted in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN concurrency_key VARCHAR(255) NULL AFTER payload_encrypted;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN concurrency_key VARCHAR(255) NULL AFTER payload_encrypted;
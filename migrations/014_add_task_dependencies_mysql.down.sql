@@ -0,0 +1,18 @@
+-- Remove task dependencies; waiting tasks become pending so the original status check holds
+UPDATE task_queue SET status = 'pending' WHERE status = 'waiting';
+
+ALTER TABLE task_queue DROP CHECK task_queue_status_check;
+
+ALTER TABLE task_queue
+ADD CONSTRAINT task_queue_chk_1
+    CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'dead_lettered'));
+
+DROP INDEX idx_tasks_depends_on ON task_queue;
+
+ALTER TABLE task_queue_archive
+DROP COLUMN dependency_failure_policy,
+DROP COLUMN depends_on;
+
+ALTER TABLE task_queue
+DROP COLUMN dependency_failure_policy,
+DROP COLUMN depends_on;
@@ -0,0 +1,20 @@
+-- Task chains: a task can wait for a parent task to finish before it becomes pending
+-- Added after concurrency_key in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN depends_on CHAR(36) NULL AFTER concurrency_key,
+ADD COLUMN dependency_failure_policy VARCHAR(20) NOT NULL DEFAULT 'dead_letter' AFTER depends_on;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN depends_on CHAR(36) NULL AFTER concurrency_key,
+ADD COLUMN dependency_failure_policy VARCHAR(20) NOT NULL DEFAULT 'dead_letter' AFTER depends_on;
+
+-- Find the tasks waiting on a parent when it finishes
+CREATE INDEX idx_tasks_depends_on ON task_queue(depends_on);
+
+-- Allow the waiting status; 001 created the status check unnamed, so MySQL named it task_queue_chk_1
+-- Only live tasks can wait, so task_queue_archive keeps its original check
+ALTER TABLE task_queue DROP CHECK task_queue_chk_1;
+
+ALTER TABLE task_queue
+ADD CONSTRAINT task_queue_status_check
+    CHECK (status IN ('waiting', 'pending', 'processing', 'completed', 'failed', 'dead_lettered'));
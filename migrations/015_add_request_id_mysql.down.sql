@@ -0,0 +1,6 @@
+-- Remove the originating request ID
+ALTER TABLE task_queue_archive
+DROP COLUMN request_id;
+
+ALTER TABLE task_queue
+DROP COLUMN request_id;
@@ -0,0 +1,7 @@
+-- ID of the API request that created the task, for correlating its lifecycle in logs and callbacks
+-- Added after dependency_failure_policy in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN request_id VARCHAR(128) NULL AFTER dependency_failure_policy;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN request_id VARCHAR(128) NULL AFTER dependency_failure_policy;
@@ -0,0 +1,21 @@
+-- Remove task expiry; expired tasks become dead-lettered so the previous status checks hold
+UPDATE task_queue SET status = 'dead_lettered' WHERE status = 'expired';
+UPDATE task_queue_archive SET status = 'dead_lettered' WHERE status = 'expired';
+
+ALTER TABLE task_queue_archive DROP CHECK task_queue_archive_status_check;
+
+ALTER TABLE task_queue_archive
+ADD CONSTRAINT task_queue_archive_chk_1
+    CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'dead_lettered'));
+
+ALTER TABLE task_queue DROP CHECK task_queue_status_check;
+
+ALTER TABLE task_queue
+ADD CONSTRAINT task_queue_status_check
+    CHECK (status IN ('waiting', 'pending', 'processing', 'completed', 'failed', 'dead_lettered'));
+
+ALTER TABLE task_queue_archive
+DROP COLUMN expires_at;
+
+ALTER TABLE task_queue
+DROP COLUMN expires_at;
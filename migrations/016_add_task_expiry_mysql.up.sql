@@ -0,0 +1,21 @@
+-- Time after which a task is expired instead of delivered, so stale callbacks don't fire late
+-- Added after request_id in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN expires_at TIMESTAMP NULL AFTER request_id;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN expires_at TIMESTAMP NULL AFTER request_id;
+
+-- Allow the expired status in both tables, as expired tasks are archived by the cleanup job
+-- task_queue_archive was created LIKE task_queue in 005, so MySQL named its copied check task_queue_archive_chk_1
+ALTER TABLE task_queue DROP CHECK task_queue_status_check;
+
+ALTER TABLE task_queue
+ADD CONSTRAINT task_queue_status_check
+    CHECK (status IN ('waiting', 'pending', 'processing', 'completed', 'failed', 'dead_lettered', 'expired'));
+
+ALTER TABLE task_queue_archive DROP CHECK task_queue_archive_chk_1;
+
+ALTER TABLE task_queue_archive
+ADD CONSTRAINT task_queue_archive_status_check
+    CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'dead_lettered', 'expired'));
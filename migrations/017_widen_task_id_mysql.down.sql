@@ -0,0 +1,8 @@
+-- Narrow task IDs back to UUIDs; fails while tasks with longer IDs remain, rather than truncating them
+ALTER TABLE task_queue_archive
+MODIFY COLUMN id CHAR(36) NOT NULL DEFAULT (UUID()),
+MODIFY COLUMN depends_on CHAR(36) NULL;
+
+ALTER TABLE task_queue
+MODIFY COLUMN id CHAR(36) NOT NULL DEFAULT (UUID()),
+MODIFY COLUMN depends_on CHAR(36) NULL;
@@ -0,0 +1,9 @@
+-- Room for client-supplied task IDs, e.g. an upstream event ID, when task.id_format is opaque
+-- depends_on holds task IDs too; both tables are widened so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+MODIFY COLUMN id VARCHAR(64) NOT NULL DEFAULT (UUID()),
+MODIFY COLUMN depends_on VARCHAR(64) NULL;
+
+ALTER TABLE task_queue_archive
+MODIFY COLUMN id VARCHAR(64) NOT NULL DEFAULT (UUID()),
+MODIFY COLUMN depends_on VARCHAR(64) NULL;
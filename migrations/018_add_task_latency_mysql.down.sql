@@ -0,0 +1,8 @@
+-- Remove per-task timings
+ALTER TABLE task_queue_archive
+DROP COLUMN callback_duration_ms,
+DROP COLUMN dispatch_latency_ms;
+
+ALTER TABLE task_queue
+DROP COLUMN callback_duration_ms,
+DROP COLUMN dispatch_latency_ms;
@@ -0,0 +1,9 @@
+-- Per-task timings: how late the last attempt started after it was due, and how long its callback took
+-- Added after expires_at in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN dispatch_latency_ms BIGINT NULL AFTER expires_at,
+ADD COLUMN callback_duration_ms BIGINT NULL AFTER dispatch_latency_ms;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN dispatch_latency_ms BIGINT NULL AFTER expires_at,
+ADD COLUMN callback_duration_ms BIGINT NULL AFTER dispatch_latency_ms;
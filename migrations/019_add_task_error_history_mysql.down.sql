@@ -0,0 +1,6 @@
+-- Remove the task error history
+ALTER TABLE task_queue_archive
+DROP COLUMN error_history;
+
+ALTER TABLE task_queue
+DROP COLUMN error_history;
@@ -0,0 +1,7 @@
+-- The task's latest failures, oldest first, so the cause of a dead-lettered task isn't lost
+-- Added after callback_duration_ms in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN error_history JSON NULL AFTER callback_duration_ms;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN error_history JSON NULL AFTER callback_duration_ms;
@@ -0,0 +1,2 @@
+-- Remove the due task index
+DROP INDEX idx_tasks_due ON task_queue;
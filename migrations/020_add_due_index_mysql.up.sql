@@ -0,0 +1,4 @@
+-- Serve FindDueTasks in its ORDER BY order, so picking the most urgent due tasks reads a few
+-- index entries instead of sorting every pending row. With deleted_at IS NULL and a status as
+-- equalities, priority DESC, scheduled_at is the order the scheduler takes tasks in
+CREATE INDEX idx_tasks_due ON task_queue(status, deleted_at, priority DESC, scheduled_at);
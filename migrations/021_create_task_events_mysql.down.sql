@@ -0,0 +1,2 @@
+-- Remove the task event history
+DROP TABLE IF EXISTS task_events;
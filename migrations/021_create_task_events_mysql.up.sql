@@ -0,0 +1,16 @@
+-- Task events as broadcast to WebSocket clients, so reconnecting clients can replay the ones
+-- they missed and GET /api/v1/tasks/{id}/timeline can list a task's status changes
+-- Bulk operation summaries have an empty task_id; events are deleted after
+-- scheduler.event_retention
+CREATE TABLE IF NOT EXISTS task_events (
+    id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    task_id VARCHAR(64) NOT NULL DEFAULT '',
+    tenant_id VARCHAR(255) NOT NULL DEFAULT '',
+    event_type VARCHAR(32) NOT NULL,
+    from_status VARCHAR(20) NOT NULL DEFAULT '',
+    to_status VARCHAR(20) NOT NULL DEFAULT '',
+    metadata JSON NULL,
+    created_at DATETIME(3) NOT NULL,
+    INDEX idx_task_events_task (task_id, id),
+    INDEX idx_task_events_created (created_at)
+);
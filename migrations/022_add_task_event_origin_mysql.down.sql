@@ -0,0 +1,3 @@
+-- Remove the task event origin
+ALTER TABLE task_events
+DROP COLUMN origin;
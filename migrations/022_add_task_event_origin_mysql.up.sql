@@ -0,0 +1,4 @@
+-- The instance that broadcast each task event, so instances polling task_events for the
+-- events of other instances skip their own
+ALTER TABLE task_events
+ADD COLUMN origin VARCHAR(64) NOT NULL DEFAULT '' AFTER created_at;
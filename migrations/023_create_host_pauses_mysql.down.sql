@@ -0,0 +1,2 @@
+-- Remove callback host pauses
+DROP TABLE IF EXISTS host_pauses;
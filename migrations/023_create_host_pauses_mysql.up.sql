@@ -0,0 +1,8 @@
+-- Callback hosts whose deliveries are on hold, e.g. during a receiver's maintenance window
+-- Tasks for a paused host are held until paused_until, or until the pause is removed when it
+-- is NULL; times are in UTC
+CREATE TABLE IF NOT EXISTS host_pauses (
+    host VARCHAR(255) NOT NULL PRIMARY KEY,
+    paused_until DATETIME(3) NULL,
+    created_at DATETIME(3) NOT NULL
+);
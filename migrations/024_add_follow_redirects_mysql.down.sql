@@ -0,0 +1,6 @@
+-- Remove follow redirects
+ALTER TABLE task_queue_archive
+DROP COLUMN follow_redirects;
+
+ALTER TABLE task_queue
+DROP COLUMN follow_redirects;
@@ -0,0 +1,7 @@
+-- Whether the task's callback follows redirects even when the server doesn't for other tasks
+-- Added after retryable_status_codes in both tables so task_queue_archive keeps mirroring task_queue
+ALTER TABLE task_queue
+ADD COLUMN follow_redirects BOOLEAN NOT NULL DEFAULT FALSE AFTER retryable_status_codes;
+
+ALTER TABLE task_queue_archive
+ADD COLUMN follow_redirects BOOLEAN NOT NULL DEFAULT FALSE AFTER retryable_status_codes;
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL schema migrations so binaries and
+// embedding applications don't need the migrations directory on disk
+package migrations
+
+import "embed"
+
+// MySQL holds the MySQL migrations, named NNN_description_mysql.{up,down}.sql
+//
+//go:embed *_mysql.up.sql *_mysql.down.sql
+var MySQL embed.FS
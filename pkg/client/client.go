@@ -0,0 +1,145 @@
+// Package client is a typed Go client for the Later HTTP API, for applications that run Later
+// as a separate service rather than embedding it with pkg/later
+//
+// Requests and responses use the same DTO types as the server, so they stay in sync with it
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Defaults for New
+const (
+	DefaultTimeout    = 30 * time.Second
+	DefaultMaxRetries = 2
+	DefaultBackoff    = 200 * time.Millisecond
+)
+
+// Client calls the Later HTTP API; it is safe for concurrent use
+type Client struct {
+	baseURL    string
+	apiKey     string
+	http       *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey authenticates every request with the key
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient sends requests with the given HTTP client instead of one with DefaultTimeout
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.http = httpClient }
+}
+
+// WithRetry sets how often a request that failed with a 5xx response is retried, waiting backoff
+// before the first retry and doubling it for each one after; zero retries disables retrying
+// Only reads and deletes are retried, since the API has no way to deduplicate a repeated POST
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// New creates a client for the server at baseURL, e.g. "http://localhost:8080"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		http:       &http.Client{Timeout: DefaultTimeout},
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// do sends a request to the API path, e.g. "/tasks/ID", encoding body as JSON when set and
+// decoding a successful response into out when set
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	target := c.baseURL + "/api/v1" + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	retries := 0
+	if method == http.MethodGet || method == http.MethodDelete {
+		retries = c.maxRetries
+	}
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, method, target, encoded, out)
+		apiErr, ok := err.(*APIError)
+		if !ok || apiErr.StatusCode < http.StatusInternalServerError || attempt == retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send makes a single attempt at a request
+func (c *Client) send(ctx context.Context, method, target string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		_ = json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, req.URL.Path, err)
+	}
+	return nil
+}
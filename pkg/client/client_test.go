@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/domain/entity"
+)
+
+const taskID = "00000000-0000-0000-0000-000000000001"
+
+func TestCreateTask(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/tasks", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]any{"order_id": float64(42)}, body["payload"], "the payload is sent as a document")
+		assert.Equal(t, "charge", body["name"])
+		assert.NotContains(t, body, "id", "the server assigns the ID unless one is given")
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(dto.TaskResponse{ID: taskID, Name: "charge", Status: entity.TaskStatusPending})
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithAPIKey("secret"))
+	task, err := c.CreateTask(context.Background(), &dto.CreateTaskRequest{
+		Name:        "charge",
+		Payload:     entity.JSONBytes(`{"order_id":42}`),
+		CallbackURL: "https://example.com/callback",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, taskID, task.ID)
+
+	// Invalid requests fail without a round trip
+	_, err = c.CreateTask(context.Background(), &dto.CreateTaskRequest{
+		Name:        "charge",
+		Payload:     entity.JSONBytes(`{}`),
+		CallbackURL: "https://example.com/callback",
+		Priority:    11,
+	})
+	assert.ErrorIs(t, err, ErrBadRequest)
+}
+
+func TestTypedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/tasks/" + taskID + "/retry":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_status","message":"Only failed tasks can be retried"}`))
+		case "/api/v1/tasks":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"conflict","message":"A task with this ID already exists","task_id":"` + taskID + `"}`))
+		case "/api/v1/tasks/stats":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized","message":"API key required"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"task_not_found","message":"Task not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL)
+	ctx := context.Background()
+
+	_, err := c.GetTask(ctx, taskID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualError(t, err, "task_not_found: Task not found (404)")
+
+	_, err = c.RetryTask(ctx, taskID)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	assert.NotErrorIs(t, err, ErrNotFound)
+
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "Only failed tasks can be retried", apiErr.Message)
+
+	_, err = c.Stats(ctx, "")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	_, err = c.CreateTask(ctx, &dto.CreateTaskRequest{
+		ID:          taskID,
+		Name:        "charge",
+		Payload:     entity.JSONBytes(`{}`),
+		CallbackURL: "https://example.com/callback",
+	})
+	assert.ErrorIs(t, err, ErrConflict)
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, taskID, apiErr.TaskID)
+}
+
+func TestRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "24h", r.URL.Query().Get("window"))
+		json.NewEncoder(w).Encode(dto.StatsResponse{Total: 7, Window: "24h"})
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithRetry(2, time.Millisecond))
+	stats, err := c.Stats(context.Background(), "24h")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), stats.Total)
+	assert.Equal(t, int32(3), calls.Load(), "retried twice")
+
+	// Gives up once retries are exhausted
+	calls.Store(-10)
+	_, err = c.GetTask(context.Background(), taskID)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, int32(-7), calls.Load())
+
+	// POSTs aren't retried, since the server may have acted on the first attempt
+	calls.Store(0)
+	_, err = c.ResurrectTask(context.Background(), taskID)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestListTasks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "dead_lettered", query.Get("status"))
+		assert.Equal(t, "acme", query.Get("tenant_id"))
+		assert.Equal(t, "1", query.Get("page"))
+		assert.Equal(t, "50", query.Get("limit"), "defaults are applied")
+		assert.Equal(t, "created_at", query.Get("sort_by"))
+		assert.Equal(t, "2026-01-02T15:00:00Z", query.Get("scheduled_to"))
+		json.NewEncoder(w).Encode(dto.TaskListResponse{
+			Tasks:      []*dto.TaskResponse{{ID: taskID, Status: entity.TaskStatusDeadLettered}},
+			Pagination: dto.PaginationInfo{Page: 1, Limit: 50, Total: 1, TotalPages: 1},
+		})
+	}))
+	defer server.Close()
+
+	status := entity.TaskStatusDeadLettered
+	tenant := "acme"
+	scheduledTo := "2026-01-02T15:00:00Z"
+	query := &dto.ListTasksQuery{Status: &status, TenantID: &tenant, ScheduledTo: &scheduledTo}
+	list, err := New(server.URL).ListTasks(context.Background(), query)
+	require.NoError(t, err)
+	require.Len(t, list.Tasks, 1)
+	assert.Equal(t, taskID, list.Tasks[0].ID)
+	assert.Zero(t, query.Limit, "the caller's query is left alone")
+}
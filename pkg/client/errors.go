@@ -0,0 +1,58 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNotFound is returned when the task doesn't exist or isn't visible to the API key
+	ErrNotFound = errors.New("task not found")
+
+	// ErrInvalidStatus is returned when the task's status doesn't allow the operation,
+	// e.g. retrying a task that hasn't failed
+	ErrInvalidStatus = errors.New("operation not allowed in the task's status")
+
+	// ErrBadRequest is returned when the server rejects the request as invalid
+	ErrBadRequest = errors.New("invalid request")
+
+	// ErrUnauthorized is returned when the API key is missing, unknown or not permitted
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrConflict is returned when a task is created with an ID that is already taken
+	ErrConflict = errors.New("task already exists")
+)
+
+// APIError is an error response from the API
+// It matches the sentinel errors above with errors.Is, according to its status and code
+type APIError struct {
+	StatusCode int
+	Code       string `json:"error"`
+	Message    string `json:"message"`
+	TaskID     string `json:"task_id,omitempty"` // The conflicting task, for ErrConflict
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("server returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s (%d)", e.Code, e.Message, e.StatusCode)
+}
+
+// Is reports whether the response corresponds to the target sentinel error
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrInvalidStatus:
+		return e.Code == "invalid_status"
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
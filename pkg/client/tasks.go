@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+)
+
+// CreateTask submits a task; the response's EstimatedExecution tells how it was dispatched
+// The request is validated before sending, so one the server would reject fails with ErrBadRequest
+// without a round trip
+func (c *Client) CreateTask(ctx context.Context, req *dto.CreateTaskRequest) (*dto.TaskResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadRequest, err)
+	}
+
+	// entity.JSONBytes marshals to a string, so the payload is sent as the raw document
+	body := struct {
+		*dto.CreateTaskRequest
+		Payload json.RawMessage `json:"payload"`
+	}{req, json.RawMessage(req.Payload)}
+
+	var task dto.TaskResponse
+	if err := c.do(ctx, http.MethodPost, "/tasks", nil, body, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetTask fetches a task with the tasks depending on it
+func (c *Client) GetTask(ctx context.Context, id string) (*dto.TaskResponse, error) {
+	var task dto.TaskResponse
+	if err := c.do(ctx, http.MethodGet, taskPath(id), nil, nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListTasks fetches a page of tasks matching the query; zero Page and Limit use the first page of 50
+func (c *Client) ListTasks(ctx context.Context, query *dto.ListTasksQuery) (*dto.TaskListResponse, error) {
+	q := *query
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	var list dto.TaskListResponse
+	if err := c.do(ctx, http.MethodGet, "/tasks", listQueryValues(&q), nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// UpcomingTasks fetches a page of the pending tasks due within the window (e.g. "1h"), soonest first
+// An empty window uses the server's default; zero limit uses pages of 50
+func (c *Client) UpcomingTasks(ctx context.Context, within string, limit int) (*dto.TaskListResponse, error) {
+	query := url.Values{}
+	if within != "" {
+		query.Set("within", within)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var list dto.TaskListResponse
+	if err := c.do(ctx, http.MethodGet, "/tasks/upcoming", query, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// DeleteTask cancels a waiting, pending or failed task
+func (c *Client) DeleteTask(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, taskPath(id), nil, nil, nil)
+}
+
+// RetryTask resets a failed or dead-lettered task to pending with its retries cleared
+func (c *Client) RetryTask(ctx context.Context, id string) (*dto.TaskResponse, error) {
+	return c.RetryTaskWithOptions(ctx, id, nil)
+}
+
+// RetryTaskWithOptions is RetryTask with a delayed restart or a new retry budget; nil req
+// keeps the task's own settings
+func (c *Client) RetryTaskWithOptions(ctx context.Context, id string, req *dto.RetryTaskRequest) (*dto.TaskResponse, error) {
+	var body any
+	if req != nil {
+		body = req
+	}
+	var task dto.TaskResponse
+	if err := c.do(ctx, http.MethodPost, taskPath(id)+"/retry", nil, body, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ResurrectTask resets a dead-lettered task to pending with its retries cleared
+//
+// Deprecated: RetryTask accepts dead-lettered tasks
+func (c *Client) ResurrectTask(ctx context.Context, id string) (*dto.TaskResponse, error) {
+	var task dto.TaskResponse
+	if err := c.do(ctx, http.MethodPost, taskPath(id)+"/resurrect", nil, nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Stats fetches task counts and activity within the window ("1h", "24h" or "7d");
+// an empty window uses the server's default
+func (c *Client) Stats(ctx context.Context, window string) (*dto.StatsResponse, error) {
+	query := url.Values{}
+	if window != "" {
+		query.Set("window", window)
+	}
+
+	var stats dto.StatsResponse
+	if err := c.do(ctx, http.MethodGet, "/tasks/stats", query, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func taskPath(id string) string {
+	return "/tasks/" + url.PathEscape(id)
+}
+
+// listQueryValues encodes list parameters under the names ListTasksQuery binds
+func listQueryValues(q *dto.ListTasksQuery) url.Values {
+	values := url.Values{}
+	values.Set("page", strconv.Itoa(q.Page))
+	values.Set("limit", strconv.Itoa(q.Limit))
+	values.Set("sort_by", q.SortBy)
+	values.Set("sort_order", q.SortOrder)
+	if q.TenantID != nil {
+		values.Set("tenant_id", *q.TenantID)
+	}
+	if q.Status != nil {
+		values.Set("status", string(*q.Status))
+	}
+	if q.Priority != nil {
+		values.Set("priority", strconv.Itoa(*q.Priority))
+	}
+	if q.DateFrom != nil {
+		values.Set("date_from", *q.DateFrom)
+	}
+	if q.DateTo != nil {
+		values.Set("date_to", *q.DateTo)
+	}
+	if q.ScheduledFrom != nil {
+		values.Set("scheduled_from", *q.ScheduledFrom)
+	}
+	if q.ScheduledTo != nil {
+		values.Set("scheduled_to", *q.ScheduledTo)
+	}
+	for key, value := range map[string]string{"name": q.Name, "name_prefix": q.NamePrefix, "tags": q.Tags} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	return values
+}
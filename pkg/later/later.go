@@ -11,21 +11,49 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/delivery/rest/middleware"
+	"github.com/usual2970/later/delivery/websocket"
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
 	"github.com/usual2970/later/domain/repository"
 	"github.com/usual2970/later/infrastructure/circuitbreaker"
+	"github.com/usual2970/later/infrastructure/eventbus"
 	"github.com/usual2970/later/infrastructure/worker"
+	"github.com/usual2970/later/repository/memory"
 	"github.com/usual2970/later/repository/mysql"
 	tasksvc "github.com/usual2970/later/task"
 )
 
+const (
+	// defaultHookConcurrency is the number of goroutines running task hooks
+	defaultHookConcurrency = 4
+
+	// defaultHookQueueSize bounds pending hook invocations before they are dropped
+	defaultHookQueueSize = 256
+
+	// defaultHealthCheckTimeout bounds the database ping of a health check
+	defaultHealthCheckTimeout = 2 * time.Second
+
+	// defaultHealthCheckCacheTTL is how long a health check reuses the last ping result
+	defaultHealthCheckCacheTTL = 5 * time.Second
+)
+
 // Later is the main struct that manages the task queue system
 type Later struct {
 	// Core components
 	taskService     *tasksvc.Service
 	scheduler       *tasksvc.Scheduler
+	elector         *tasksvc.LeaderElector // nil unless leader election is enabled
 	workerPool      worker.WorkerPool
+	executor        *worker.Executor
+	limiter         *worker.ConcurrencyLimiter
 	callbackService *callback.Service
 	taskRepo        repository.TaskRepository
+	hub             *websocket.Hub                 // nil unless WebSocket events are enabled
+	taskEvents      repository.TaskEventRepository // nil unless WebSocket events are enabled
+	hookRunner      *worker.HookRunner             // nil unless task hooks are configured
+	waiters         *taskWaiters
+	createLimiter   *middleware.RateLimiter // nil unless task creation is rate limited
 
 	// Database
 	db      *sqlx.DB
@@ -37,26 +65,44 @@ type Later struct {
 	logger *zap.Logger
 
 	// Lifecycle
-	ctx     context.Context
-	cancel  context.CancelFunc
-	started bool
-	mu      sync.RWMutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	started  bool
+	stopping bool // Set once Shutdown begins so readiness fails while draining
+	mu       sync.RWMutex
+
+	// Last database ping of a health check, reused for HealthCheckCacheTTL
+	pingMu  sync.Mutex
+	pingAt  time.Time
+	pingErr error
 }
 
 // New creates a new Later instance with functional options
 func New(opts ...Option) (*Later, error) {
 	// Apply default config
 	cfg := &Config{
-		DBMode:          DBModeSeparate,
-		WorkerPoolSize:  20,
-		AutoMigration:   true,
-		RoutePrefix:     "/api/v1",
-		CallbackTimeout: 30 * time.Second,
-		Logger:          zap.L(), // Use global logger
+		DBMode:                DBModeSeparate,
+		WorkerPoolSize:        20,
+		AutoMigration:         true,
+		RoutePrefix:           "/api/v1",
+		CallbackTimeout:       30 * time.Second,
+		CallbackResponseLimit: callback.DefaultResponseBodyLimit,
+		RetryPolicy:           entity.DefaultRetryPolicy,
+		MaxPayloadSize:        entity.MaxPayloadSize,
+		TaskIDFormat:          tasksvc.IDFormatUUID,
+		HealthCheckTimeout:    defaultHealthCheckTimeout,
+		HealthCheckCacheTTL:   defaultHealthCheckCacheTTL,
+		WaitPollInterval:      defaultWaitPollInterval,
+		Logger:                zap.L(), // Use global logger
 		SchedulerConfig: tasksvc.SchedulerConfig{
 			HighPriorityInterval:   2 * time.Second,
 			NormalPriorityInterval: 3 * time.Second,
+			RetryInterval:          tasksvc.DefaultRetryInterval,
 			CleanupInterval:        30 * time.Second,
+			CompletedRetention:     tasksvc.DefaultCleanupRetention,
+			DeadLetteredRetention:  tasksvc.DefaultCleanupRetention,
+			EventRetention:         tasksvc.DefaultEventRetention,
+			PollJitter:             tasksvc.DefaultPollJitter,
 		},
 	}
 
@@ -68,12 +114,23 @@ func New(opts ...Option) (*Later, error) {
 	}
 
 	// Validate configuration
-	if cfg.DBMode == DBModeShared && cfg.DB == nil {
+	if cfg.TaskRepository != nil {
+		if cfg.LeaderElection {
+			return nil, fmt.Errorf("leader election requires a database, not a custom task repository")
+		}
+		if cfg.EventBus.Driver == eventbus.DriverDatabase {
+			return nil, fmt.Errorf("the database event bus requires a database, not a custom task repository")
+		}
+	} else if cfg.DBMode == DBModeShared && cfg.DB == nil {
 		return nil, fmt.Errorf("shared DB mode requires DB connection")
-	}
-	if cfg.DBMode == DBModeSeparate && cfg.DSN == "" {
+	} else if cfg.DBMode == DBModeSeparate && cfg.DSN == "" {
 		return nil, fmt.Errorf("separate DB mode requires DSN")
 	}
+	queueCapacity := cfg.queueCapacity()
+	if err := cfg.SchedulerConfig.ValidateBatchSizes(queueCapacity); err != nil {
+		return nil, err
+	}
+	cfg.SchedulerConfig = cfg.SchedulerConfig.WithBatchDefaults(queueCapacity)
 
 	// Initialize Later instance
 	l := &Later{
@@ -82,16 +139,21 @@ func New(opts ...Option) (*Later, error) {
 		dbMode: cfg.DBMode,
 	}
 	l.ctx, l.cancel = context.WithCancel(context.Background())
-
-	// Setup database
-	if err := l.setupDatabase(); err != nil {
-		return nil, fmt.Errorf("database setup failed: %w", err)
+	if cfg.CreateRateLimit > 0 {
+		l.createLimiter = middleware.NewRateLimiter(cfg.CreateRateLimit, cfg.CreateRateBurst)
 	}
 
-	// Run migrations
-	if cfg.AutoMigration {
-		if err := l.runMigrations(); err != nil {
-			return nil, fmt.Errorf("migration failed: %w", err)
+	// Setup database, unless tasks are stored elsewhere
+	if cfg.TaskRepository == nil {
+		if err := l.setupDatabase(); err != nil {
+			return nil, fmt.Errorf("database setup failed: %w", err)
+		}
+
+		// Run migrations
+		if cfg.AutoMigration {
+			if err := l.runMigrations(); err != nil {
+				return nil, fmt.Errorf("migration failed: %w", err)
+			}
 		}
 	}
 
@@ -104,6 +166,8 @@ func New(opts ...Option) (*Later, error) {
 		zap.String("db_mode", modeToString(cfg.DBMode)),
 		zap.Int("worker_pool_size", cfg.WorkerPoolSize),
 		zap.String("route_prefix", cfg.RoutePrefix),
+		zap.Bool("websocket_events", cfg.WebSocketEvents),
+		zap.Bool("leader_election", cfg.LeaderElection),
 	)
 
 	return l, nil
@@ -153,17 +217,20 @@ func (l *Later) setupDatabase() error {
 	return nil
 }
 
+// dbOptions returns the repository options logging to Later's logger, including statements
+// over the slow query threshold
+func (l *Later) dbOptions() []mysql.Option {
+	return []mysql.Option{
+		mysql.WithLogger(l.logger.Named("db")),
+		mysql.WithSlowQueryLog(l.config.SlowQueryThreshold),
+	}
+}
+
 func (l *Later) runMigrations() error {
 	l.logger.Info("Running database migrations")
-	// Use existing migration logic from repository/mysql/
-	// Note: RunMigrations expects a migrationsDir parameter
-	// For now, we'll use the default migrations directory
-	err := mysql.RunMigrations(l.db, "migrations")
-	if err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
-	}
-	l.logger.Info("Database migrations completed successfully")
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	return l.RunMigrations(ctx)
 }
 
 func (l *Later) initComponents() error {
@@ -173,28 +240,157 @@ func (l *Later) initComponents() error {
 	cb := circuitbreaker.NewCircuitBreaker(5, 60*time.Second)
 
 	// Callback service
+	client := l.config.CallbackHTTPClient
+	if client == nil {
+		var err error
+		transport := l.config.CallbackTransport
+		transport.URLPolicy = l.config.CallbackURLPolicy
+		client, err = callback.NewHTTPClient(l.config.CallbackTimeout, transport)
+		if err != nil {
+			return fmt.Errorf("failed to create callback HTTP client: %w", err)
+		}
+	}
+	var callbackOpts []callback.ServiceOption
+	var taskOpts []tasksvc.ServiceOption
+	if l.config.CallbackURLPolicy != nil {
+		callbackOpts = append(callbackOpts, callback.WithURLPolicy(l.config.CallbackURLPolicy))
+		taskOpts = append(taskOpts, tasksvc.WithCallbackURLPolicy(l.config.CallbackURLPolicy))
+	}
+	taskOpts = append(taskOpts,
+		tasksvc.WithMaxPayloadSize(l.config.MaxPayloadSize),
+		tasksvc.WithPayloadCompression(l.config.PayloadCompressionMinSize),
+		tasksvc.WithDefaultTaskTTL(l.config.DefaultTaskTTL),
+		tasksvc.WithBacklogLimit(l.config.MaxPendingTasks, l.config.BacklogBypassPriority),
+		tasksvc.WithPayloadSchemas(l.config.PayloadSchemas),
+		tasksvc.WithTaskIDFormat(l.config.TaskIDFormat),
+		tasksvc.WithRetryPolicy(l.config.RetryPolicy),
+		tasksvc.WithLogger(l.logger.Named("task")),
+	)
+	if l.config.PayloadEncryptionKey != nil {
+		payloadCipher, err := tasksvc.NewPayloadCipher(l.config.PayloadEncryptionKey, l.config.PayloadDecryptionKeys...)
+		if err != nil {
+			return fmt.Errorf("failed to create payload cipher: %w", err)
+		}
+		taskOpts = append(taskOpts, tasksvc.WithPayloadCipher(payloadCipher))
+		l.config.SchedulerConfig.PayloadCipher = payloadCipher
+	}
+	if l.config.RetryableStatusCodes != nil {
+		callbackOpts = append(callbackOpts, callback.WithRetryableStatusCodes(l.config.RetryableStatusCodes))
+	}
+	if l.config.CallbackMaxRedirects > 0 {
+		callbackOpts = append(callbackOpts, callback.WithMaxRedirects(l.config.CallbackMaxRedirects))
+	}
+	if l.config.CallbackPreviousSecret != "" {
+		callbackOpts = append(callbackOpts, callback.WithPreviousSigningSecret(l.config.CallbackPreviousSecret))
+	}
+	if l.config.CallbackOAuth2 != nil {
+		callbackOpts = append(callbackOpts, callback.WithOAuth2(*l.config.CallbackOAuth2))
+	}
 	l.callbackService = callback.NewService(
-		l.config.CallbackTimeout,
+		client,
 		cb,
 		l.config.CallbackSecret,
+		l.config.CallbackResponseLimit,
 		l.logger.Named("callback"),
+		callbackOpts...,
 	)
 
 	// Repository
-	l.taskRepo = mysql.NewTaskRepository(l.db)
+	l.taskRepo = l.config.TaskRepository
+	if l.taskRepo == nil {
+		l.taskRepo = mysql.NewTaskRepositoryWithPrefix(l.db, l.config.TablePrefix, l.dbOptions()...)
+	}
+
+	// Recorded task events, replayed to reconnecting clients and listed as task timelines
+	if l.config.WebSocketEvents {
+		if l.config.TaskRepository != nil {
+			l.taskEvents = memory.NewTaskEventRepository()
+		} else {
+			l.taskEvents = mysql.NewTaskEventRepository(l.db, l.config.TablePrefix, l.dbOptions()...)
+		}
+		taskOpts = append(taskOpts, tasksvc.WithEventHistory(l.taskEvents))
+		l.config.SchedulerConfig.TaskEvents = l.taskEvents
+	}
+
+	// Paused callback hosts, kept with the tasks
+	if l.config.TaskRepository != nil {
+		taskOpts = append(taskOpts, tasksvc.WithHostPauses(memory.NewHostPauseRepository()))
+	} else {
+		taskOpts = append(taskOpts, tasksvc.WithHostPauses(mysql.NewHostPauseRepository(l.db, l.config.TablePrefix, l.dbOptions()...)))
+	}
 
 	// Task service
-	l.taskService = tasksvc.NewService(l.taskRepo)
+	l.taskService = tasksvc.NewService(l.taskRepo, taskOpts...)
+
+	// Task waiters, resolving WaitForTask as soon as a worker finishes the task
+	l.waiters = newTaskWaiters()
+	broadcasters := worker.Broadcasters{l.waiters}
+
+	// WebSocket hub (optional)
+	if l.config.WebSocketEvents {
+		bus, err := eventbus.New(l.config.EventBus, l.taskEvents, l.logger.Named("eventbus"))
+		if err != nil {
+			return fmt.Errorf("failed to create event bus: %w", err)
+		}
+		l.hub = websocket.NewHub(l.logger.Named("websocket"), l.config.WebSocket,
+			websocket.WithEventStore(l.taskEvents), websocket.WithEventBus(bus),
+			websocket.WithPayloadRedaction(l.config.PayloadRedaction))
+		broadcasters = append(broadcasters, l.hub)
+	}
+
+	// Task hooks (optional)
+	if !l.config.Hooks.IsEmpty() {
+		l.hookRunner = worker.NewHookRunner(
+			l.config.Hooks,
+			defaultHookConcurrency,
+			defaultHookQueueSize,
+			l.logger.Named("hooks"),
+		)
+		broadcasters = append(broadcasters, l.hookRunner)
+	}
 
-	// Worker pool
+	// Worker pool, capping tasks in flight per concurrency key and tracking callbacks so
+	// operators can abort them
+	limiter, err := worker.NewConcurrencyLimiter(l.config.ConcurrencyLimits, 0)
+	if err != nil {
+		return fmt.Errorf("invalid concurrency limits: %w", err)
+	}
+	l.limiter = limiter
+	inFlight := worker.NewInFlight()
 	l.workerPool = worker.NewWorkerPool(
 		l.config.WorkerPoolSize,
 		l.taskService,
 		l.callbackService,
+		broadcasters,
 		l.logger.Named("worker"),
+		worker.WithConcurrencyLimiter(limiter),
+		worker.WithQueueBuffer(l.config.TaskQueueBuffer),
+		worker.WithInFlight(inFlight),
+		worker.WithContext(l.ctx),
 	)
+	l.executor = worker.NewExecutor(l.taskService, l.callbackService, broadcasters, inFlight, l.logger.Named("worker"))
+
+	// Leader election (optional)
+	if l.config.LeaderElection {
+		if l.config.LeaderLease.Logger == nil {
+			l.config.LeaderLease.Logger = l.logger.Named("leader")
+		}
+		l.elector = tasksvc.NewLeaderElector(
+			mysql.NewLeaseRepository(l.db, l.config.TablePrefix, l.dbOptions()...),
+			l.config.LeaderLease,
+		)
+		l.config.SchedulerConfig.Leader = l.elector
+	}
+
+	// Insert notifications (optional)
+	if l.config.NotifyEnabled {
+		l.config.SchedulerConfig.Notifier = tasksvc.NewInsertWatcher(l.taskRepo, l.config.NotifyMin, l.config.NotifyMax)
+	}
 
 	// Scheduler
+	if l.config.SchedulerConfig.Logger == nil {
+		l.config.SchedulerConfig.Logger = l.logger.Named("scheduler")
+	}
 	l.scheduler = tasksvc.NewScheduler(
 		l.taskRepo,
 		l.workerPool,
@@ -205,6 +401,21 @@ func (l *Later) initComponents() error {
 	return nil
 }
 
+// broadcast publishes a task event to WebSocket subscribers if enabled
+func (l *Later) broadcast(eventType string, task *entity.Task) {
+	if l.hub != nil {
+		l.hub.Broadcast(websocket.NewTaskEvent(eventType, task))
+	}
+}
+
+// broadcastBulk publishes one summary event for a bulk operation that changed count tasks
+func (l *Later) broadcastBulk(ctx context.Context, eventType string, count int64, status entity.TaskStatus) {
+	if l.hub != nil && count > 0 {
+		tenantID, _ := domain.TenantFromContext(ctx)
+		l.hub.Broadcast(websocket.NewBulkEvent(eventType, count, status, tenantID))
+	}
+}
+
 // modeToString converts DBMode to string for logging
 func modeToString(mode DBMode) string {
 	switch mode {
@@ -5,6 +5,12 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/delivery/websocket"
+	"github.com/usual2970/later/infrastructure/eventbus"
+	"github.com/usual2970/later/repository/memory"
+	tasksvc "github.com/usual2970/later/task"
 )
 
 // TestNewWithInvalidOptions tests that New() returns errors for invalid options
@@ -34,6 +40,30 @@ func TestNewWithInvalidOptions(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Invalid health check timeout",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithHealthCheck(0, time.Second),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Drain timeout exceeds shutdown timeout",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithShutdownTimeout(time.Second, 2*time.Second),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative initial poll jitter",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithInitialPoll(0, -time.Second),
+			},
+			wantErr: true,
+		},
 		{
 			name: "Invalid route prefix",
 			opts: []Option{
@@ -50,6 +80,59 @@ func TestNewWithInvalidOptions(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Scheduler batch size exceeds worker queue capacity",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithWorkerPoolSize(2),
+				WithSchedulerMaxBatchFactor(5),
+				WithSchedulerBatchSizes(10, 25, 10),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Scheduler batch size exceeds task queue buffer",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithTaskQueueBuffer(4),
+				WithSchedulerMaxBatchFactor(5),
+				WithSchedulerBatchSizes(10, 25, 10),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid task queue buffer",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithTaskQueueBuffer(0),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid retry poll interval",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithRetryPollInterval(0),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Leader renew interval not shorter than lease",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithLeaderElection(true),
+				WithLeaderLease(5*time.Second, 5*time.Second),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Insert notification max interval below min",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithInsertNotifications(time.Second, 100*time.Millisecond),
+			},
+			wantErr: true,
+		},
 		{
 			name: "Nil logger",
 			opts: []Option{
@@ -58,6 +141,227 @@ func TestNewWithInvalidOptions(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Nil task hook",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithOnTaskCompleted(nil),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative cleanup retention",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithCleanupRetention(-time.Hour, 0),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Dead letter alert without thresholds",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithDeadLetterAlert(tasksvc.DeadLetterAlertConfig{}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative task event retention",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithTaskEventRetention(-time.Hour),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid table prefix",
+			opts: []Option{
+				WithSharedDB(nil),
+				WithTablePrefix("later-"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid slow query threshold",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithSlowQueryLog(0),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid WebSocket allowed origin",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithWebSocketAllowedOrigins("dash.example.com"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Payload redaction by fields without any",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithPayloadRedaction(websocket.RedactionFields),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative WebSocket client limit",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithWebSocketLimits(websocket.HubConfig{MaxClients: -1}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Nil callback HTTP client",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithCallbackHTTPClient(nil),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid callback proxy URL",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithCallbackTransport(callback.TransportOptions{ProxyURL: "://proxy"}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid callback URL denylist",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithCallbackURLPolicy(callback.URLPolicyConfig{Denylist: []string{"10.0.0.0/40"}}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Relative OAuth2 token URL",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithCallbackOAuth2("/oauth2/token", "later", "secret", nil),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Zero max payload size",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithMaxPayloadSize(0),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative payload compression min size",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithPayloadCompression(-1),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid payload encryption key size",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithPayloadEncryption([]byte("too-short")),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unsupported payload schema",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithPayloadSchema("send-email", []byte(`{"$ref": "#/$defs/email"}`)),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid task ID format",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithTaskIDFormat("ulid"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid max pending tasks",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithMaxPendingTasks(0, 8),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid create rate limit",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithCreateRateLimit(0, 10),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid request logging mode",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithRequestLogging("verbose"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Callback secret rotation without a current secret",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithCallbackSecrets("", "old-secret"),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Nil task repository",
+			opts:    []Option{WithTaskRepository(nil)},
+			wantErr: true,
+		},
+		{
+			name: "Leader election with a custom task repository",
+			opts: []Option{
+				WithTaskRepository(memory.NewTaskRepository()),
+				WithLeaderElection(true),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Nil archive sink",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithArchiveSink(nil),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unknown event bus driver",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithEventBus(eventbus.Config{Driver: "kafka"}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Database event bus with a custom task repository",
+			opts: []Option{
+				WithTaskRepository(memory.NewTaskRepository()),
+				WithEventBus(eventbus.Config{Driver: eventbus.DriverDatabase}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Empty tenant ID",
+			opts: []Option{
+				WithSeparateDB("user:pass@tcp(localhost:3306)/test"),
+				WithTenantAPIKeys(map[string]string{"key": ""}),
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -143,8 +447,11 @@ func TestDBModeString(t *testing.T) {
 
 // TestTaskFilterConversion tests TaskFilter to repository.TaskFilter conversion
 func TestTaskFilterConversion(t *testing.T) {
+	soon := time.Now().Add(time.Hour)
 	filter := &TaskFilter{
+		ScheduledBefore: &soon,
 		Status:       "pending",
+		NamePrefix:   "invoice-",
 		Page:         1,
 		Limit:        10,
 		SortBy:       "created_at",
@@ -165,4 +472,10 @@ func TestTaskFilterConversion(t *testing.T) {
 	if repoFilter.SortOrder != filter.SortOrder {
 		t.Errorf("SortOrder = %v, want %v", repoFilter.SortOrder, filter.SortOrder)
 	}
+	if repoFilter.NamePrefix != filter.NamePrefix {
+		t.Errorf("NamePrefix = %v, want %v", repoFilter.NamePrefix, filter.NamePrefix)
+	}
+	if repoFilter.ScheduledTo != filter.ScheduledBefore || repoFilter.ScheduledFrom != nil {
+		t.Errorf("scheduled range = %v..%v, want ..%v", repoFilter.ScheduledFrom, repoFilter.ScheduledTo, soon)
+	}
 }
@@ -3,6 +3,9 @@ package later
 import (
 	"context"
 	"fmt"
+	"time"
+
+	tasksvc "github.com/usual2970/later/task"
 
 	"go.uber.org/zap"
 )
@@ -19,38 +22,89 @@ func (l *Later) Start() error {
 
 	l.logger.Info("Starting Later")
 
+	// Start task hooks before workers can emit transitions
+	if l.hookRunner != nil {
+		l.hookRunner.Start()
+	}
+
 	// Start worker pool
 	l.workerPool.Start(l.config.WorkerPoolSize)
 
+	// Compete for the scheduler lease; followers skip polling until they win it
+	if l.elector != nil {
+		go l.elector.Run()
+	}
+
 	// Start scheduler in background goroutine
 	go l.scheduler.Start()
 
+	// Start WebSocket hub if enabled
+	if l.hub != nil {
+		go l.hub.Run()
+	}
+
 	l.started = true
 	l.logger.Info("Later started successfully")
 	return nil
 }
 
 // Shutdown gracefully stops Later
-// Waits for in-flight tasks to complete or until context is cancelled
+// Waits for in-flight tasks to complete or until context is cancelled, or until the timeouts
+// set with WithShutdownTimeout expire
+// Callbacks still in flight then are cancelled, and their tasks put back to pending for the next start
 func (l *Later) Shutdown(ctx context.Context) error {
 	l.mu.Lock()
 	if !l.started {
 		l.mu.Unlock()
 		return nil
 	}
+	l.stopping = true
 	l.mu.Unlock()
 
-	l.logger.Info("Shutting down Later")
+	l.logger.Info("Shutting down Later",
+		zap.Duration("shutdown_timeout", l.config.ShutdownTimeout),
+		zap.Duration("drain_timeout", l.config.DrainTimeout),
+	)
+	if l.config.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.config.ShutdownTimeout)
+		defer cancel()
+	}
 
 	// Stop scheduler (stops polling)
 	l.scheduler.Stop()
 
-	// Stop worker pool (waits for in-flight tasks)
-	// Note: Current worker pool implementation doesn't accept context
-	// It has a fixed 30-second timeout internally
-	l.workerPool.Stop()
+	// Release the scheduler lease so another replica takes over without waiting for it to expire
+	if l.elector != nil {
+		l.elector.Stop()
+	}
+
+	// Stop worker pool (waits for in-flight tasks until the drain deadline)
+	drainCtx := ctx
+	if l.config.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		drainCtx, cancel = context.WithTimeout(ctx, l.config.DrainTimeout)
+		defer cancel()
+	}
+	drainStart := time.Now()
+	if abandoned := l.workerPool.Stop(drainCtx); abandoned > 0 {
+		l.logger.Warn("Tasks abandoned during shutdown; they will be retried on next start",
+			zap.Int("abandoned", abandoned),
+			zap.Duration("elapsed", time.Since(drainStart)),
+		)
+	}
 
-	// Wait for context cancellation or immediate return
+	// Close WebSocket clients after the last worker events are published
+	if l.hub != nil {
+		l.hub.Stop()
+	}
+
+	// Drain queued task hooks
+	if l.hookRunner != nil {
+		l.hookRunner.Stop()
+	}
+
+	// Report a missed deadline to the caller
 	select {
 	case <-ctx.Done():
 		l.logger.Warn("Shutdown context cancelled", zap.Error(ctx.Err()))
@@ -73,22 +127,36 @@ func (l *Later) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// schedulerStallFactor is how many high priority poll intervals may pass without a scheduler
+// tick before HealthCheck reports the scheduler stalled
+const schedulerStallFactor = 3
+
 // HealthCheck returns health status for monitoring
+// Ready is false until Start completes, once Shutdown begins, while the database is unreachable
+// and when the scheduler has stopped ticking
+// The database ping is bounded by the health check timeout and its result cached briefly,
+// so a hung database can't stall health probes
 func (l *Later) HealthCheck() HealthStatus {
 	l.mu.RLock()
-	defer l.mu.RUnlock()
+	started, stopping := l.started, l.stopping
+	l.mu.RUnlock()
 
 	status := HealthStatus{
-		Started: l.started,
+		Started: started,
 	}
 
-	if !l.started {
+	if !started {
 		status.Status = "stopped"
 		return status
 	}
+	if stopping {
+		status.Status = "stopping"
+		return status
+	}
 
-	// Check database
-	if err := l.db.Ping(); err != nil {
+	// Check database; pool usage is reported either way, as exhaustion is a common cause
+	status.DBPool = l.dbPoolStatus()
+	if err := l.pingDB(); err != nil {
 		status.Status = "unhealthy"
 		status.Database = "disconnected"
 		status.Error = err.Error()
@@ -96,10 +164,25 @@ func (l *Later) HealthCheck() HealthStatus {
 	}
 	status.Database = "connected"
 
-	// Check scheduler status
-	// Note: Current scheduler doesn't expose IsRunning()
-	// We'll assume it's running if Later is started
+	// Check the scheduler is still ticking; followers tick too, they just don't poll
+	if last := l.scheduler.LastActivity(); !last.IsZero() {
+		if stale := time.Since(last); stale > schedulerStallFactor*l.config.SchedulerConfig.HighPriorityInterval {
+			status.Status = "unhealthy"
+			status.Scheduler = "stalled"
+			status.Error = fmt.Sprintf("scheduler has not ticked for %s", stale.Round(time.Second))
+			return status
+		}
+	}
 	status.Scheduler = "running"
+	if l.elector != nil {
+		status.Leader = &LeaderStatus{
+			Holder:   l.elector.Holder(),
+			IsLeader: l.elector.IsLeader(),
+		}
+		if !status.Leader.IsLeader {
+			status.Scheduler = "standby"
+		}
+	}
 
 	// Check worker pool status
 	// Note: Current worker pool exposes WorkerCount() but not ActiveCount()
@@ -113,23 +196,113 @@ func (l *Later) HealthCheck() HealthStatus {
 		Active: activeWorkers,
 		Total:  l.config.WorkerPoolSize,
 	}
+	if wp, ok := l.workerPool.(interface{ PanicCount() int64 }); ok {
+		status.Workers.Panics = wp.PanicCount()
+	}
+	if wp, ok := l.workerPool.(interface {
+		QueueDepth() int
+		QueueCapacity() int
+	}); ok {
+		status.Workers.Queued = wp.QueueDepth()
+		status.Workers.QueueCapacity = wp.QueueCapacity()
+	}
+	if wp, ok := l.workerPool.(interface{ RejectedCount() int64 }); ok {
+		status.Workers.SubmitRejected = wp.RejectedCount()
+	}
+
+	if backlog, ok := l.taskService.Backlog(); ok {
+		status.Backlog = &backlog
+	}
+	if alert, ok := l.scheduler.DeadLetterAlert(); ok {
+		status.DeadLetterAlert = alert.Firing
+	}
 
 	status.Status = "healthy"
+	status.Ready = true
 	return status
 }
 
+// pingDB pings the database within the health check timeout, reusing a recent result
+// Concurrent checks wait for the ping in flight rather than starting their own
+func (l *Later) pingDB() error {
+	if l.db == nil {
+		// Tasks are stored in a custom repository, with no database to ping
+		return nil
+	}
+
+	l.pingMu.Lock()
+	defer l.pingMu.Unlock()
+
+	if !l.pingAt.IsZero() && time.Since(l.pingAt) < l.config.HealthCheckCacheTTL {
+		return l.pingErr
+	}
+
+	timeout := l.config.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	l.pingErr = l.db.PingContext(ctx)
+	l.pingAt = time.Now()
+	return l.pingErr
+}
+
+// dbPoolStatus returns the connection pool usage of Later's database, or nil without one
+// With a shared database the pool, and so the figures, include the application's connections
+func (l *Later) dbPoolStatus() *DBPoolStatus {
+	if l.db == nil {
+		return nil
+	}
+	stats := l.db.Stats()
+	return &DBPoolStatus{
+		MaxOpen:        stats.MaxOpenConnections,
+		Open:           stats.OpenConnections,
+		InUse:          stats.InUse,
+		Idle:           stats.Idle,
+		WaitCount:      stats.WaitCount,
+		WaitDurationMs: stats.WaitDuration.Milliseconds(),
+	}
+}
+
 // HealthStatus represents the health status of Later
 type HealthStatus struct {
-	Status    string       `json:"status"`     // healthy, unhealthy, stopped
+	Status    string       `json:"status"`     // healthy, unhealthy, stopping, stopped
 	Database  string       `json:"database"`   // connected, disconnected
-	Scheduler string       `json:"scheduler"`  // running, stopped
+	Scheduler string       `json:"scheduler"`  // running, standby (follower), stalled
+	DBPool    *DBPoolStatus `json:"db_pool,omitempty"` // Unset when tasks are stored in a custom repository
+	Leader    *LeaderStatus `json:"leader,omitempty"` // Set when leader election is enabled
 	Workers   *WorkerStatus `json:"workers,omitempty"`
+	Backlog   *tasksvc.BacklogStatus `json:"backlog,omitempty"` // Set when a backlog limit is configured
+	DeadLetterAlert bool `json:"dead_letter_alert"` // The dead letter queue alert is raised; only on the leader
 	Started   bool         `json:"started"`
+	Ready     bool         `json:"ready"` // Started, not shutting down and the database is reachable
 	Error     string       `json:"error,omitempty"`
 }
 
+// DBPoolStatus reports database connection pool usage, from sql.DBStats
+type DBPoolStatus struct {
+	MaxOpen        int   `json:"max_open"` // Zero when unlimited
+	Open           int   `json:"open"`
+	InUse          int   `json:"in_use"`
+	Idle           int   `json:"idle"`
+	WaitCount      int64 `json:"wait_count"`       // Total waits for a free connection
+	WaitDurationMs int64 `json:"wait_duration_ms"` // Total time spent waiting for one
+}
+
+// LeaderStatus reports this replica's part in leader election
+type LeaderStatus struct {
+	Holder   string `json:"holder"`    // This replica's lease holder ID
+	IsLeader bool   `json:"is_leader"` // Whether this replica runs the scheduler
+}
+
 // WorkerStatus represents the status of the worker pool
 type WorkerStatus struct {
-	Active int `json:"active"`
-	Total  int `json:"total"`
+	Active         int   `json:"active"`
+	Total          int   `json:"total"`
+	Queued         int   `json:"queued"`         // Submitted tasks waiting for a worker
+	QueueCapacity  int   `json:"queue_capacity"` // Submitted tasks the pool buffers
+	Panics         int64 `json:"panics"`                // Panics recovered while processing tasks
+	SubmitRejected int64 `json:"submit_rejected_total"` // Submissions refused because the queue was full
 }
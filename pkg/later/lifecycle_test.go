@@ -0,0 +1,277 @@
+package later
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/repository/memory"
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// hangingConnector never connects, standing in for a database that stopped answering
+type hangingConnector struct{}
+
+func (hangingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (hangingConnector) Driver() driver.Driver { return nil }
+
+// idleConnector hands out connections that answer pings and nothing else
+type idleConnector struct{}
+
+func (idleConnector) Connect(ctx context.Context) (driver.Conn, error) { return idleConn{}, nil }
+
+func (idleConnector) Driver() driver.Driver { return nil }
+
+type idleConn struct{}
+
+func (idleConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (idleConn) Close() error                              { return nil }
+func (idleConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func newHangingLater(timeout, cacheTTL time.Duration) *Later {
+	return &Later{
+		db:      sqlx.NewDb(sql.OpenDB(hangingConnector{}), "mysql"),
+		config:  &Config{HealthCheckTimeout: timeout, HealthCheckCacheTTL: cacheTTL},
+		logger:  testLogger(),
+		started: true,
+	}
+}
+
+// TestHealthCheckBoundsDatabasePing tests that a hung database can't stall health checks
+func TestHealthCheckBoundsDatabasePing(t *testing.T) {
+	l := newHangingLater(100*time.Millisecond, time.Minute)
+
+	start := time.Now()
+	status := l.HealthCheck()
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, "unhealthy", status.Status)
+	assert.Equal(t, "disconnected", status.Database)
+	assert.False(t, status.Ready)
+	assert.NotEmpty(t, status.Error)
+
+	// The failed ping is reused instead of waiting out the timeout again
+	start = time.Now()
+	status = l.HealthCheck()
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, "unhealthy", status.Status)
+}
+
+// TestHealthCheckReleasesLockDuringPing tests that lifecycle calls don't wait on a health check
+func TestHealthCheckReleasesLockDuringPing(t *testing.T) {
+	l := newHangingLater(500*time.Millisecond, 0)
+
+	done := make(chan HealthStatus)
+	go func() { done <- l.HealthCheck() }()
+	time.Sleep(50 * time.Millisecond)
+
+	locked := make(chan struct{})
+	go func() {
+		l.mu.Lock()
+		l.mu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("HealthCheck held the lifecycle lock while pinging the database")
+	}
+
+	status := <-done
+	require.Equal(t, "unhealthy", status.Status)
+}
+
+// TestDBPoolStatus tests that connection pool usage is reported, even while the database is down
+func TestDBPoolStatus(t *testing.T) {
+	l := newHangingLater(50*time.Millisecond, 0)
+	status := l.HealthCheck()
+	assert.Equal(t, "unhealthy", status.Status)
+	assert.NotNil(t, status.DBPool)
+
+	db := sqlx.NewDb(sql.OpenDB(idleConnector{}), "mysql")
+	db.SetMaxOpenConns(4)
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+	l.db = db
+	assert.Equal(t, &DBPoolStatus{MaxOpen: 4, Open: 1, InUse: 1}, l.dbPoolStatus())
+
+	l.db = nil
+	assert.Nil(t, l.dbPoolStatus(), "none without a database")
+}
+
+// followerOnly keeps the scheduler from polling, so it ticks without a repository
+type followerOnly struct{}
+
+func (followerOnly) IsLeader() bool { return false }
+
+// TestHealthCheckDetectsStalledScheduler tests that a scheduler that stopped ticking is reported
+func TestHealthCheckDetectsStalledScheduler(t *testing.T) {
+	cfg := tasksvc.SchedulerConfig{
+		HighPriorityInterval:   10 * time.Millisecond,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        time.Hour,
+		Leader:                 followerOnly{},
+	}
+	l := &Later{
+		db:          sqlx.NewDb(sql.OpenDB(idleConnector{}), "mysql"),
+		config:      &Config{HealthCheckTimeout: time.Second, SchedulerConfig: cfg},
+		logger:      testLogger(),
+		taskService: tasksvc.NewService(nil),
+		scheduler:   tasksvc.NewScheduler(nil, nil, cfg),
+		started:     true,
+	}
+
+	go l.scheduler.Start()
+	require.Eventually(t, func() bool { return !l.scheduler.LastActivity().IsZero() }, time.Second, 5*time.Millisecond)
+	status := l.HealthCheck()
+	assert.Equal(t, "healthy", status.Status)
+	assert.Equal(t, "running", status.Scheduler)
+
+	// Stopping the scheduler behind Later's back stands in for its goroutine dying
+	l.scheduler.Stop()
+	time.Sleep(50 * time.Millisecond)
+	status = l.HealthCheck()
+	assert.Equal(t, "unhealthy", status.Status)
+	assert.Equal(t, "stalled", status.Scheduler)
+	assert.False(t, status.Ready)
+	assert.Contains(t, status.Error, "scheduler has not ticked")
+}
+
+// drainPool records the deadline Shutdown gives in-flight tasks
+type drainPool struct {
+	idlePool
+	deadline time.Time
+}
+
+func (p *drainPool) Stop(ctx context.Context) int {
+	p.deadline, _ = ctx.Deadline()
+	return 0
+}
+
+func TestShutdownTimeouts(t *testing.T) {
+	tests := []struct {
+		name            string
+		shutdownTimeout time.Duration
+		drainTimeout    time.Duration
+		wantDrain       time.Duration
+	}{
+		{"Drain timeout bounds the workers", 10 * time.Second, time.Second, time.Second},
+		{"Workers drain up to the shutdown timeout", 10 * time.Second, 0, 10 * time.Second},
+	}
+	schedulerCfg := tasksvc.SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        time.Hour,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &drainPool{}
+			_, cancel := context.WithCancel(context.Background())
+			l := &Later{
+				config:     &Config{ShutdownTimeout: tt.shutdownTimeout, DrainTimeout: tt.drainTimeout},
+				logger:     testLogger(),
+				scheduler:  tasksvc.NewScheduler(nil, nil, schedulerCfg),
+				workerPool: pool,
+				cancel:     cancel,
+				started:    true,
+			}
+
+			start := time.Now()
+			require.NoError(t, l.Shutdown(context.Background()))
+			assert.WithinDuration(t, start.Add(tt.wantDrain), pool.deadline, 100*time.Millisecond)
+		})
+	}
+}
+
+// TestLifecycleWithTaskRepository tests a full run on the in-memory repository: no database is
+// needed to start, deliver a task, report health and shut down
+func TestLifecycleWithTaskRepository(t *testing.T) {
+	delivered := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- struct{}{}
+	}))
+	defer server.Close()
+
+	l, err := New(
+		WithTaskRepository(memory.NewTaskRepository()),
+		WithLogger(testLogger()),
+		WithWorkerPoolSize(1),
+		WithInitialPoll(0, 0),
+	)
+	require.NoError(t, err)
+	require.NoError(t, l.Start())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	task, err := l.CreateTask(ctx, &CreateTaskRequest{
+		Name:        "send_email",
+		Payload:     []byte(`{}`),
+		CallbackURL: server.URL,
+	})
+	require.NoError(t, err)
+	finished, err := l.WaitForTask(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusCompleted, finished.Status)
+	<-delivered
+
+	health := l.HealthCheck()
+	assert.Equal(t, "healthy", health.Status, health.Error)
+	assert.Equal(t, "connected", health.Database)
+	assert.Error(t, l.RunMigrations(ctx), "there's no database to migrate")
+
+	assert.NoError(t, l.Shutdown(ctx))
+}
+
+// TestTaskTimelineWithTaskRepository tests that streamed events are recorded in memory when no
+// database is used, and listed as the task's timeline
+func TestTaskTimelineWithTaskRepository(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	l, err := New(
+		WithTaskRepository(memory.NewTaskRepository()),
+		WithLogger(testLogger()),
+		WithWorkerPoolSize(1),
+		WithInitialPoll(0, 0),
+		WithWebSocketEvents(true),
+	)
+	require.NoError(t, err)
+	require.NoError(t, l.Start())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	defer l.Shutdown(ctx)
+	task, err := l.CreateTask(ctx, &CreateTaskRequest{
+		Name:        "send_email",
+		Payload:     []byte(`{}`),
+		CallbackURL: server.URL,
+	})
+	require.NoError(t, err)
+	_, err = l.WaitForTask(ctx, task.ID)
+	require.NoError(t, err)
+
+	var timeline []*entity.TaskEvent
+	require.Eventually(t, func() bool {
+		timeline, err = l.GetTaskTimeline(ctx, task.ID)
+		require.NoError(t, err)
+		return len(timeline) > 0 && timeline[len(timeline)-1].ToStatus == entity.TaskStatusCompleted
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, entity.TaskStatusPending, timeline[0].ToStatus)
+	assert.Empty(t, timeline[0].FromStatus)
+	last := timeline[len(timeline)-1]
+	assert.Equal(t, "task_completed", last.Type)
+	assert.Equal(t, entity.TaskStatusProcessing, last.FromStatus)
+}
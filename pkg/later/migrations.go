@@ -3,12 +3,53 @@ package later
 import (
 	"context"
 	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/migrations"
+	"github.com/usual2970/later/repository/mysql"
 )
 
 // RunMigrations explicitly runs database migrations
 // This can be called manually if AutoMigration is disabled
+// Only migrations not yet recorded in schema_migrations are applied
 func (l *Later) RunMigrations(ctx context.Context) error {
-	return l.runMigrations()
+	migrator, err := l.migrator()
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	l.logger.Info("Database migrations completed successfully", zap.Ints("applied", applied))
+	return nil
+}
+
+// RollbackMigrations reverts the given number of most recently applied migrations
+func (l *Later) RollbackMigrations(ctx context.Context, steps int) error {
+	migrator, err := l.migrator()
+	if err != nil {
+		return err
+	}
+
+	reverted, err := migrator.Down(ctx, steps)
+	if err != nil {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+
+	l.logger.Info("Database migrations rolled back", zap.Ints("reverted", reverted))
+	return nil
+}
+
+// migrator returns the migrator for Later's tables, or an error without a database
+func (l *Later) migrator() (*mysql.Migrator, error) {
+	if l.db == nil {
+		return nil, fmt.Errorf("no database to migrate: tasks are stored in a custom repository")
+	}
+	return mysql.NewMigrator(l.db, migrations.MySQL, l.config.TablePrefix, l.dbOptions()...)
 }
 
 // Close closes the database connection if Later owns it
@@ -2,11 +2,21 @@ package later
 
 import (
 	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 
+	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/delivery/rest/middleware"
+	"github.com/usual2970/later/delivery/websocket"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/eventbus"
+	"github.com/usual2970/later/infrastructure/worker"
+	"github.com/usual2970/later/repository/mysql"
 	tasksvc "github.com/usual2970/later/task"
 )
 
@@ -24,24 +34,107 @@ type Config struct {
 	DSN           string
 	DBConfig      DatabaseConfig
 	AutoMigration bool
+	TablePrefix   string
+
+	// SlowQueryThreshold logs statements taking at least this long at Warn; zero disables it
+	SlowQueryThreshold time.Duration
+
+	// TaskRepository replaces the MySQL task store, e.g. with repository/memory in tests;
+	// no database is used then
+	TaskRepository repository.TaskRepository
 
 	// HTTP
-	RoutePrefix string
+	RoutePrefix     string
+	WebSocketEvents bool
+	WebSocket       websocket.HubConfig // Client limit and keepalive of the event stream
+	EventBus        eventbus.Config     // Fans events out between replicas; the zero value keeps them local
+	APIKeys         []string
+	Tenant          middleware.TenantConfig
+	PayloadKeys     []string // Keys that see payloads in task responses; empty allows all
+	CreateRateLimit float64  // Task creations per second per API key or client IP; zero disables the limit
+	CreateRateBurst int
+	RequestLogging  middleware.RequestLogConfig
+
+	// PayloadRedaction sets how much of a task's payload WebSocket events carry
+	PayloadRedaction websocket.PayloadRedaction
+
+	// RouteMiddleware runs on every route, after Later's request ID and default middleware
+	RouteMiddleware          []gin.HandlerFunc
+	DisableDefaultMiddleware bool // Leaves request logging and panic recovery to the host
 
 	// Worker Pool
-	WorkerPoolSize int
+	WorkerPoolSize    int
+	TaskQueueBuffer   int            // Submitted tasks buffered for the workers; zero uses twice the pool size
+	ConcurrencyLimits map[string]int // Max tasks in flight per concurrency key; others are unbounded
+
+	// Tasks
+	MaxPayloadSize            int
+	PayloadCompressionMinSize int                               // Zero stores payloads uncompressed
+	DefaultTaskTTL            time.Duration                     // Zero leaves tasks without an expiry unless they set one
+	MaxPendingTasks           int64                             // New tasks are refused at this many pending; zero disables the limit
+	BacklogBypassPriority     int                               // Tasks with at least this priority ignore MaxPendingTasks; zero disables the bypass
+	PayloadSchemas            map[string]*tasksvc.PayloadSchema // By task name; other tasks' payloads aren't validated
+	TaskIDFormat              string                            // Format of the IDs tasks may be created with: tasksvc.IDFormatUUID or IDFormatOpaque
+	PayloadEncryptionKey      []byte
+	PayloadDecryptionKeys     [][]byte
 
 	// Scheduler
 	SchedulerConfig tasksvc.SchedulerConfig
+	LeaderElection  bool // Only the replica holding the scheduler lease polls and cleans up
+	LeaderLease     tasksvc.LeaderElectionConfig
+	NotifyEnabled   bool // Poll for new tasks adaptively and wake the scheduler when any are created
+	NotifyMin       time.Duration
+	NotifyMax       time.Duration
 
 	// Callback
-	CallbackTimeout time.Duration
-	CallbackSecret  string
+	CallbackTimeout        time.Duration
+	CallbackSecret         string
+	CallbackPreviousSecret string // Also signs callbacks, in X-Signature-Previous, during a rotation
+	CallbackResponseLimit  int64
+	CallbackHTTPClient     *http.Client // Overrides CallbackTimeout and CallbackTransport when set
+	CallbackTransport      callback.TransportOptions
+	CallbackURLPolicy      *callback.URLPolicy // nil accepts any callback URL
+	CallbackOAuth2         *entity.OAuth2Config
+	RetryableStatusCodes   []int // nil retries 5xx and 429
+	CallbackMaxRedirects   int   // Zero fails callbacks that redirect, unless the task follows redirects
+	RetryPolicy            entity.RetryPolicy
+
+	// Hooks
+	Hooks worker.TaskHooks
+
+	// WaitForTask
+	WaitPollInterval time.Duration // How often to re-read a task not finished by this instance's workers
+
+	// Health checks
+	HealthCheckTimeout  time.Duration // Bounds the database ping of a health check
+	HealthCheckCacheTTL time.Duration // Reuses the last ping result this long; zero pings on every check
+
+	// Shutdown; zero leaves the bound to the context passed to Shutdown
+	ShutdownTimeout time.Duration // Bounds the whole of Shutdown
+	DrainTimeout    time.Duration // Bounds the wait for in-flight tasks during Shutdown
 
 	// Logging
 	Logger *zap.Logger
 }
 
+// acceptedAPIKeys returns every key allowed on task routes: plain, admin and tenant-mapped
+func (c *Config) acceptedAPIKeys() []string {
+	keys := append([]string{}, c.APIKeys...)
+	keys = append(keys, c.Tenant.AdminKeys...)
+	for key := range c.Tenant.KeyTenants {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// queueCapacity returns how many submitted tasks the worker pool buffers
+func (c *Config) queueCapacity() int {
+	if c.TaskQueueBuffer > 0 {
+		return c.TaskQueueBuffer
+	}
+	return worker.QueueCapacity(c.WorkerPoolSize)
+}
+
 // DatabaseConfig holds database-specific configuration
 type DatabaseConfig struct {
 	MaxOpenConns int
@@ -139,7 +232,45 @@ func WithConnectionMaxIdleTime(idleTime time.Duration) DBOption {
 	}
 }
 
+// WithTaskRepository stores tasks in repo instead of MySQL, e.g. memory.NewTaskRepository() for
+// unit tests and examples; database options and migrations are then ignored
+// Leader election needs the database, so it can't be combined with this option
+func WithTaskRepository(repo repository.TaskRepository) Option {
+	return func(c *Config) error {
+		if repo == nil {
+			return fmt.Errorf("task repository cannot be nil")
+		}
+		c.TaskRepository = repo
+		return nil
+	}
+}
+
+// WithTablePrefix prefixes all of Later's table names, e.g. "later_" for later_task_queue
+// Useful with WithSharedDB to avoid collisions with the application's tables
+func WithTablePrefix(prefix string) Option {
+	return func(c *Config) error {
+		if err := mysql.ValidateTablePrefix(prefix); err != nil {
+			return err
+		}
+		c.TablePrefix = prefix
+		return nil
+	}
+}
+
+// WithSlowQueryLog logs Later's database statements taking at least threshold at Warn, with
+// their duration and rows affected, to find which of them load a shared database
+func WithSlowQueryLog(threshold time.Duration) Option {
+	return func(c *Config) error {
+		if threshold <= 0 {
+			return fmt.Errorf("slow query threshold must be positive")
+		}
+		c.SlowQueryThreshold = threshold
+		return nil
+	}
+}
+
 // WithRoutePrefix sets the HTTP route prefix for Later's endpoints
+// RegisterRoutesGroup ignores it and mounts them under the group's path instead
 // Defaults to "/api/v1"
 func WithRoutePrefix(prefix string) Option {
 	return func(c *Config) error {
@@ -151,6 +282,75 @@ func WithRoutePrefix(prefix string) Option {
 	}
 }
 
+// WithRouteMiddleware adds the host application's middleware, e.g. its authentication, to every
+// route RegisterRoutes and RegisterRoutesGroup mount, health probes included. Handlers run in the order given, after
+// Later's request ID, logger and recovery middleware and before its API key checks, so they can
+// reject a request before any of Later's handlers run. Calling it again adds to the handlers
+func WithRouteMiddleware(handlers ...gin.HandlerFunc) Option {
+	return func(c *Config) error {
+		for _, handler := range handlers {
+			if handler == nil {
+				return fmt.Errorf("route middleware cannot be nil")
+			}
+		}
+		c.RouteMiddleware = append(c.RouteMiddleware, handlers...)
+		return nil
+	}
+}
+
+// WithDisableDefaultMiddleware leaves out Later's request logger and panic recovery, for hosts
+// whose engine already logs and recovers every request. Request IDs are still assigned, since
+// tasks record the ID of the request that created them
+func WithDisableDefaultMiddleware(disable bool) Option {
+	return func(c *Config) error {
+		c.DisableDefaultMiddleware = disable
+		return nil
+	}
+}
+
+// WithCreateRateLimit limits POST /tasks and /tasks/import per API key, or per client IP
+// without one, to rps requests per second with bursts of up to burst; excess requests get
+// 429 with a Retry-After header. A burst of zero uses rps rounded up
+func WithCreateRateLimit(rps float64, burst int) Option {
+	return func(c *Config) error {
+		if rps <= 0 {
+			return fmt.Errorf("create rate limit must be positive")
+		}
+		if burst < 0 {
+			return fmt.Errorf("create rate burst cannot be negative")
+		}
+		c.CreateRateLimit = rps
+		c.CreateRateBurst = burst
+		return nil
+	}
+}
+
+// WithRequestLogging selects which HTTP requests are logged: middleware.RequestLogAll,
+// RequestLogErrors or RequestLogOff. With RequestLogAll, fast successful requests are
+// logged at Debug so health probes and polling clients stay out of Info logs
+// Defaults to middleware.RequestLogAll
+func WithRequestLogging(mode middleware.RequestLogMode) Option {
+	return func(c *Config) error {
+		if !mode.Valid() {
+			return fmt.Errorf("invalid request logging mode %q", mode)
+		}
+		c.RequestLogging.Mode = mode
+		return nil
+	}
+}
+
+// WithSlowRequestThreshold logs successful requests taking longer than threshold at Info
+// instead of Debug; a negative threshold never does. Defaults to 1 second
+func WithSlowRequestThreshold(threshold time.Duration) Option {
+	return func(c *Config) error {
+		if threshold == 0 {
+			return fmt.Errorf("slow request threshold cannot be zero")
+		}
+		c.RequestLogging.SlowThreshold = threshold
+		return nil
+	}
+}
+
 // WithWorkerPoolSize sets the number of worker pool workers
 // Defaults to 20
 func WithWorkerPoolSize(size int) Option {
@@ -163,6 +363,36 @@ func WithWorkerPoolSize(size int) Option {
 	}
 }
 
+// WithTaskQueueBuffer sets how many submitted tasks are buffered for the workers, independently
+// of the pool size. Due tasks that don't fit wait for the next poll, so a larger buffer absorbs
+// bursts with less polling churn. Scheduler batch sizes are validated against it
+// Defaults to twice the worker pool size
+func WithTaskQueueBuffer(size int) Option {
+	return func(c *Config) error {
+		if size <= 0 {
+			return fmt.Errorf("task queue buffer must be positive")
+		}
+		c.TaskQueueBuffer = size
+		return nil
+	}
+}
+
+// WithConcurrencyLimits caps the tasks in flight per concurrency key
+// A task's key is its ConcurrencyKey, or its first tag when unset; keys without a limit are
+// unbounded. Tasks of a saturated key wait and start in submission order as slots free up
+// Limits can be changed at runtime with SetConcurrencyLimit or the admin routes
+func WithConcurrencyLimits(limits map[string]int) Option {
+	return func(c *Config) error {
+		for key, limit := range limits {
+			if err := worker.ValidateConcurrencyLimit(key, limit); err != nil {
+				return err
+			}
+		}
+		c.ConcurrencyLimits = limits
+		return nil
+	}
+}
+
 // WithLogger sets a custom logger for Later
 // Defaults to global zap logger
 func WithLogger(logger *zap.Logger) Option {
@@ -200,6 +430,234 @@ func WithSchedulerIntervals(high, normal, cleanup time.Duration) Option {
 	}
 }
 
+// WithRetryPollInterval sets how often failed tasks due for retry are polled
+// Retries are polled on their own ticker, independently of pending tasks
+// Defaults to 5 seconds
+func WithRetryPollInterval(interval time.Duration) Option {
+	return func(c *Config) error {
+		if interval <= 0 {
+			return fmt.Errorf("retry poll interval must be positive")
+		}
+		c.SchedulerConfig.RetryInterval = interval
+		return nil
+	}
+}
+
+// WithInitialPoll delays the scheduler's first poll after Start by delay plus a random share
+// of jitter, so replicas started together don't poll the database in lockstep
+// Defaults to no delay and 1 second of jitter
+func WithInitialPoll(delay, jitter time.Duration) Option {
+	return func(c *Config) error {
+		if delay < 0 || jitter < 0 {
+			return fmt.Errorf("initial poll delay and jitter cannot be negative")
+		}
+		c.SchedulerConfig.InitialPollDelay = delay
+		c.SchedulerConfig.PollJitter = jitter
+		return nil
+	}
+}
+
+// WithSchedulerBatchSizes sets how many due tasks each poll fetches:
+// high: high-priority polls (priority > 5)
+// normal: normal-priority polls and the cleanup tick's sweep across all priorities
+// retry: polls for failed tasks due for retry
+// Sizes may not exceed the worker queue capacity (twice the pool size) times the max batch factor
+// Defaults to 50, 100 and 100
+func WithSchedulerBatchSizes(high, normal, retry int) Option {
+	return func(c *Config) error {
+		if high <= 0 || normal <= 0 || retry <= 0 {
+			return fmt.Errorf("scheduler batch sizes must be positive")
+		}
+		c.SchedulerConfig.HighPriorityBatchSize = high
+		c.SchedulerConfig.NormalPriorityBatchSize = normal
+		c.SchedulerConfig.RetryBatchSize = retry
+		return nil
+	}
+}
+
+// WithSchedulerMaxBatchFactor sets how many times the worker queue capacity a batch may be
+// Tasks that don't fit in the queue are left for the next poll, so larger batches only add load
+// Defaults to 10
+func WithSchedulerMaxBatchFactor(factor int) Option {
+	return func(c *Config) error {
+		if factor <= 0 {
+			return fmt.Errorf("scheduler max batch factor must be positive")
+		}
+		c.SchedulerConfig.MaxBatchFactor = factor
+		return nil
+	}
+}
+
+// WithLeaderElection lets several replicas share a database: they compete for a lease in the
+// scheduler_lock table and only the holder runs the scheduler and cleanup, while every replica
+// serves the API and runs workers. Leadership is reported by HealthCheck
+// Disabled by default
+func WithLeaderElection(enabled bool) Option {
+	return func(c *Config) error {
+		c.LeaderElection = enabled
+		return nil
+	}
+}
+
+// WithLeaderLease sets how long the scheduler lease lasts without renewal and how often the
+// leader renews it; another replica takes over within about leaseTimeout when the leader dies
+// Defaults to 15 seconds and a third of the lease timeout
+func WithLeaderLease(leaseTimeout, renewInterval time.Duration) Option {
+	return func(c *Config) error {
+		if leaseTimeout <= 0 || renewInterval <= 0 {
+			return fmt.Errorf("leader lease timeout and renew interval must be positive")
+		}
+		cfg := tasksvc.LeaderElectionConfig{LeaseTimeout: leaseTimeout, RenewInterval: renewInterval}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		c.LeaderLease.LeaseTimeout = leaseTimeout
+		c.LeaderLease.RenewInterval = renewInterval
+		return nil
+	}
+}
+
+// WithInsertNotifications wakes the scheduler as soon as tasks are created on any replica,
+// instead of waiting up to a poll interval. The newest task is checked every minInterval after
+// an insert, backing off to maxInterval while none are created; the tickers remain a fallback
+// Zero intervals use 100ms and 1s
+func WithInsertNotifications(minInterval, maxInterval time.Duration) Option {
+	return func(c *Config) error {
+		if minInterval < 0 || maxInterval < 0 {
+			return fmt.Errorf("insert notification intervals cannot be negative")
+		}
+		if minInterval > 0 && maxInterval > 0 && maxInterval < minInterval {
+			return fmt.Errorf("insert notification max interval must be at least the min interval")
+		}
+		c.NotifyEnabled = true
+		c.NotifyMin = minInterval
+		c.NotifyMax = maxInterval
+		return nil
+	}
+}
+
+// WithDelayQueue holds tasks due within two normal poll intervals in memory and dispatches
+// them at their scheduled time rather than on the next poll. The database stays the source
+// of truth: each task is re-read before dispatch, and polling covers anything lost on restart
+// Disabled by default
+func WithDelayQueue(enabled bool) Option {
+	return func(c *Config) error {
+		c.SchedulerConfig.DelayQueue = enabled
+		return nil
+	}
+}
+
+// WithImmediateSubmitWait makes a task due on creation, retry or resurrection wait up to wait
+// for worker queue space instead of being left to the next poll when the queue is full
+// The wait delays the call that submits the task. Defaults to zero, failing fast
+func WithImmediateSubmitWait(wait time.Duration) Option {
+	return func(c *Config) error {
+		if wait < 0 {
+			return fmt.Errorf("immediate submit wait cannot be negative")
+		}
+		c.SchedulerConfig.ImmediateSubmitWait = wait
+		return nil
+	}
+}
+
+// WithCleanupRetention configures how long completed and dead-lettered tasks are kept
+// before the cleanup job removes them. A zero duration keeps tasks of that status forever
+// Defaults to 30 days for both
+func WithCleanupRetention(completed, deadLettered time.Duration) Option {
+	return func(c *Config) error {
+		if completed < 0 || deadLettered < 0 {
+			return fmt.Errorf("cleanup retention cannot be negative")
+		}
+		c.SchedulerConfig.CompletedRetention = completed
+		c.SchedulerConfig.DeadLetteredRetention = deadLettered
+		return nil
+	}
+}
+
+// WithTaskEventRetention configures how long the cleanup job keeps recorded task events
+// A zero duration keeps them forever. Defaults to 7 days
+func WithTaskEventRetention(retention time.Duration) Option {
+	return func(c *Config) error {
+		if retention < 0 {
+			return fmt.Errorf("task event retention cannot be negative")
+		}
+		c.SchedulerConfig.EventRetention = retention
+		return nil
+	}
+}
+
+// WithCleanupArchive copies expired tasks into the task_queue_archive table before deleting them
+func WithCleanupArchive(enabled bool) Option {
+	return func(c *Config) error {
+		c.SchedulerConfig.ArchiveBeforeDelete = enabled
+		return nil
+	}
+}
+
+// WithArchiveSink writes each batch of expired tasks, with their recorded events, to sink before
+// the cleanup job deletes it, e.g. an archive.NewS3 bucket; a batch the sink fails to write is kept
+// and retried on the next cleanup. GetMetrics reports the archived and deleted totals
+func WithArchiveSink(sink tasksvc.ArchiveSink) Option {
+	return func(c *Config) error {
+		if sink == nil {
+			return fmt.Errorf("archive sink cannot be nil")
+		}
+		c.SchedulerConfig.ArchiveSink = sink
+		return nil
+	}
+}
+
+// WithDeadLetterAlert raises an alert from the cleanup job when more than cfg.Threshold tasks are
+// dead-lettered or more than cfg.GrowthPerHour were dead-lettered within an hour; a zero threshold
+// is disabled. cfg.Alerter, e.g. an alert.NewWebhook, is notified when the alert is raised and
+// when it clears, and HealthCheck reports it as dead_letter_alert
+func WithDeadLetterAlert(cfg tasksvc.DeadLetterAlertConfig) Option {
+	return func(c *Config) error {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		if !cfg.Enabled() {
+			return fmt.Errorf("dead letter alert needs a threshold or a growth per hour")
+		}
+		c.SchedulerConfig.DeadLetterAlert = cfg
+		return nil
+	}
+}
+
+// WithHealthCheck bounds the database ping done by HealthCheck and the health endpoints to
+// timeout, and reuses its result for cacheTTL so probes don't cost a round-trip each
+// A zero cacheTTL pings on every check. Defaults to 2 seconds and 5 seconds
+func WithHealthCheck(timeout, cacheTTL time.Duration) Option {
+	return func(c *Config) error {
+		if timeout <= 0 {
+			return fmt.Errorf("health check timeout must be positive")
+		}
+		if cacheTTL < 0 {
+			return fmt.Errorf("health check cache TTL cannot be negative")
+		}
+		c.HealthCheckTimeout = timeout
+		c.HealthCheckCacheTTL = cacheTTL
+		return nil
+	}
+}
+
+// WithShutdownTimeout bounds Shutdown, and drainTimeout the part of it spent waiting for
+// in-flight tasks, in addition to the context passed to Shutdown
+// A zero drainTimeout waits for tasks up to the shutdown timeout; both default to no bound
+func WithShutdownTimeout(shutdownTimeout, drainTimeout time.Duration) Option {
+	return func(c *Config) error {
+		if shutdownTimeout <= 0 {
+			return fmt.Errorf("shutdown timeout must be positive")
+		}
+		if drainTimeout < 0 || drainTimeout > shutdownTimeout {
+			return fmt.Errorf("drain timeout must be between 0 and the shutdown timeout")
+		}
+		c.ShutdownTimeout = shutdownTimeout
+		c.DrainTimeout = drainTimeout
+		return nil
+	}
+}
+
 // WithCallbackTimeout sets the HTTP timeout for callback delivery
 // Defaults to 30 seconds
 func WithCallbackTimeout(timeout time.Duration) Option {
@@ -220,3 +678,456 @@ func WithCallbackSecret(secret string) Option {
 		return nil
 	}
 }
+
+// WithCallbackSecrets rotates the HMAC secret without breaking receivers: callbacks are
+// signed with current in X-Signature and with previous in X-Signature-Previous, and
+// callback.VerifySignature accepts either. Drop previous once every receiver uses current
+func WithCallbackSecrets(current, previous string) Option {
+	return func(c *Config) error {
+		if current == "" {
+			return fmt.Errorf("current callback secret cannot be empty")
+		}
+		c.CallbackSecret = current
+		c.CallbackPreviousSecret = previous
+		return nil
+	}
+}
+
+// WithCallbackResponseLimit sets how many bytes of a callback response body are read
+// The body is drained up to the limit so connections can be reused; larger bodies are abandoned
+// Defaults to 64KB
+func WithCallbackResponseLimit(bytes int64) Option {
+	return func(c *Config) error {
+		if bytes <= 0 {
+			return fmt.Errorf("callback response limit must be positive")
+		}
+		c.CallbackResponseLimit = bytes
+		return nil
+	}
+}
+
+// WithCallbackHTTPClient delivers callbacks through the given client, e.g. one with a custom transport
+// The client's own Timeout applies; WithCallbackTimeout and WithCallbackTransport are ignored
+// WithCallbackURLPolicy can't refuse denied addresses when this client dials them; build it with
+// callback.NewHTTPClient and TransportOptions.URLPolicy to keep that
+// Per-task callback timeouts still apply on top of it
+func WithCallbackHTTPClient(client *http.Client) Option {
+	return func(c *Config) error {
+		if client == nil {
+			return fmt.Errorf("callback HTTP client cannot be nil")
+		}
+		c.CallbackHTTPClient = client
+		return nil
+	}
+}
+
+// WithCallbackTransport tunes the transport of the default callback client:
+// idle connections per host, TLS config (e.g. client certificates for mTLS),
+// an egress proxy URL and keep-alives
+func WithCallbackTransport(opts callback.TransportOptions) Option {
+	return func(c *Config) error {
+		if err := opts.Validate(); err != nil {
+			return err
+		}
+		c.CallbackTransport = opts
+		return nil
+	}
+}
+
+// WithCallbackURLPolicy restricts callback URLs to protect against server-side request forgery
+// URLs are checked when tasks are created (rejected as invalid input) and again before each
+// delivery, when the addresses their hosts resolve to are refused as they are dialled
+// A nil Denylist denies callback.DefaultDeniedNetworks (link-local and private ranges)
+// Defaults to accepting any URL
+func WithCallbackURLPolicy(cfg callback.URLPolicyConfig) Option {
+	return func(c *Config) error {
+		policy, err := callback.NewURLPolicy(cfg)
+		if err != nil {
+			return err
+		}
+		c.CallbackURLPolicy = policy
+		return nil
+	}
+}
+
+// WithCallbackOAuth2 authenticates callbacks with a bearer token from the OAuth2 client-credentials grant
+// Tokens are cached and refreshed shortly before expiry; failing to get one is retried like a failed delivery
+// Tasks can override these settings with CreateTaskRequest.CallbackOAuth2
+func WithCallbackOAuth2(tokenURL, clientID, clientSecret string, scopes []string) Option {
+	return func(c *Config) error {
+		cfg := &entity.OAuth2Config{
+			TokenURL:     tokenURL,
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Scopes:       scopes,
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		c.CallbackOAuth2 = cfg
+		return nil
+	}
+}
+
+// WithRetryableStatusCodes sets the callback response codes that are retried
+// Any other non-2xx code fails the task without further retries; network errors are always retried
+// Tasks can override the set with CreateTaskRequest.RetryableStatusCodes
+// Defaults to 5xx and 429
+func WithRetryableStatusCodes(codes []int) Option {
+	return func(c *Config) error {
+		if !entity.ValidStatusCodes(codes) {
+			return fmt.Errorf("retryable status codes must be between 100 and 599")
+		}
+		c.RetryableStatusCodes = append([]int{}, codes...)
+		return nil
+	}
+}
+
+// WithCallbackMaxRedirects lets every callback follow up to n redirects, at most
+// callback.MaxRedirects, resending the same POST to each location
+// Tasks can follow redirects regardless with CreateTaskRequest.FollowRedirects
+// Defaults to 0, which fails deliveries answered with a redirect
+func WithCallbackMaxRedirects(n int) Option {
+	return func(c *Config) error {
+		if n < 0 || n > callback.MaxRedirects {
+			return fmt.Errorf("callback max redirects must be between 0 and %d", callback.MaxRedirects)
+		}
+		c.CallbackMaxRedirects = n
+		return nil
+	}
+}
+
+// WithRetryPolicy sets how failed tasks back off: a task's RetryBackoffSeconds doubles with each
+// failure up to maxBackoff, then moves by a random share of up to jitterPercent either way
+// Defaults to 25% jitter and a one day cap
+func WithRetryPolicy(jitterPercent int, maxBackoff time.Duration) Option {
+	return func(c *Config) error {
+		policy := entity.RetryPolicy{JitterPercent: jitterPercent, MaxBackoff: maxBackoff}
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		c.RetryPolicy = policy
+		return nil
+	}
+}
+
+// WithMaxPayloadSize rejects tasks whose payload exceeds the given number of bytes
+// Defaults to 1MB
+func WithMaxPayloadSize(bytes int) Option {
+	return func(c *Config) error {
+		if bytes <= 0 {
+			return fmt.Errorf("max payload size must be positive")
+		}
+		c.MaxPayloadSize = bytes
+		return nil
+	}
+}
+
+// WithPayloadCompression gzip-compresses payloads of at least minSize bytes at rest
+// Payloads are decompressed when read, so callbacks and task APIs always see the original JSON
+// Small payloads grow when compressed; 1024 is a reasonable threshold
+// Defaults to 0, which disables compression
+func WithPayloadCompression(minSize int) Option {
+	return func(c *Config) error {
+		if minSize < 0 {
+			return fmt.Errorf("payload compression min size cannot be negative")
+		}
+		c.PayloadCompressionMinSize = minSize
+		return nil
+	}
+}
+
+// WithDefaultTaskTTL expires tasks still not run ttl after their scheduled time, e.g. after an
+// outage, instead of delivering their stale callbacks; tasks setting ExpiresAt keep their own
+// Defaults to 0, which never expires tasks
+func WithDefaultTaskTTL(ttl time.Duration) Option {
+	return func(c *Config) error {
+		if ttl < 0 {
+			return fmt.Errorf("default task TTL cannot be negative")
+		}
+		c.DefaultTaskTTL = ttl
+		return nil
+	}
+}
+
+// WithMaxPendingTasks refuses new tasks, with an error wrapping domain.ErrQueueFull and a 429
+// queue_full response over HTTP, while max or more tasks are pending. Tasks with a priority of
+// at least bypassPriority are still accepted; a bypassPriority of zero disables the bypass
+// The pending count is cached for a few seconds, so the backlog can overshoot max slightly
+func WithMaxPendingTasks(max int64, bypassPriority int) Option {
+	return func(c *Config) error {
+		if max <= 0 {
+			return fmt.Errorf("max pending tasks must be positive")
+		}
+		if bypassPriority < 0 || bypassPriority > 10 {
+			return fmt.Errorf("backlog bypass priority must be between 0 and 10")
+		}
+		c.MaxPendingTasks = max
+		c.BacklogBypassPriority = bypassPriority
+		return nil
+	}
+}
+
+// WithTaskIDFormat sets the format of the IDs tasks may be created with: tasksvc.IDFormatUUID
+// (default) or tasksvc.IDFormatOpaque, which accepts IDs such as an upstream event ID of up to
+// 64 letters, digits, '-', '_', '.' and ':'. Tasks created without an ID get a UUID either way
+func WithTaskIDFormat(format string) Option {
+	return func(c *Config) error {
+		if !tasksvc.ValidIDFormat(format) {
+			return fmt.Errorf("task ID format must be %q or %q", tasksvc.IDFormatUUID, tasksvc.IDFormatOpaque)
+		}
+		c.TaskIDFormat = format
+		return nil
+	}
+}
+
+// WithPayloadSchema rejects tasks named name whose payload doesn't match the JSON Schema,
+// with an error wrapping domain.ErrBadParamInput and a 400 listing the mismatches over HTTP
+// Tasks with other names are accepted unchecked; see tasksvc.PayloadSchema for the keywords supported
+func WithPayloadSchema(name string, schema []byte) Option {
+	return func(c *Config) error {
+		if name == "" {
+			return fmt.Errorf("payload schema task name cannot be empty")
+		}
+		compiled, err := tasksvc.CompilePayloadSchema(schema)
+		if err != nil {
+			return fmt.Errorf("payload schema for %s: %w", name, err)
+		}
+		if c.PayloadSchemas == nil {
+			c.PayloadSchemas = make(map[string]*tasksvc.PayloadSchema)
+		}
+		c.PayloadSchemas[name] = compiled
+		return nil
+	}
+}
+
+// WithPayloadEncryption encrypts payloads at rest with AES-GCM using the given 16, 24 or 32 byte key
+// Payloads are decrypted before callback delivery and in task APIs
+// Encrypted payloads are not compressed
+func WithPayloadEncryption(key []byte) Option {
+	return func(c *Config) error {
+		if _, err := tasksvc.NewPayloadCipher(key); err != nil {
+			return err
+		}
+		c.PayloadEncryptionKey = key
+		return nil
+	}
+}
+
+// WithPayloadDecryptionKeys accepts retired keys for decrypting payloads stored before a key rotation
+// New payloads are always encrypted with the WithPayloadEncryption key
+func WithPayloadDecryptionKeys(keys ...[]byte) Option {
+	return func(c *Config) error {
+		for _, key := range keys {
+			if _, err := tasksvc.NewPayloadCipher(key); err != nil {
+				return err
+			}
+		}
+		c.PayloadDecryptionKeys = keys
+		return nil
+	}
+}
+
+// WithWebSocketEvents enables the real-time task event stream
+// When enabled, RegisterRoutes mounts GET {prefix}/tasks/stream, and events are recorded in
+// the task_events table, or in memory with WithTaskRepository, for clients reconnecting with
+// ?since and for GetTaskTimeline
+// Defaults to false
+func WithWebSocketEvents(enabled bool) Option {
+	return func(c *Config) error {
+		c.WebSocketEvents = enabled
+		return nil
+	}
+}
+
+// WithWebSocketLimits caps the event stream's clients, refusing further connections with 503,
+// and tunes their keepalive; zero fields keep the defaults
+// Origins set with WithWebSocketAllowedOrigins are kept unless cfg lists its own
+func WithWebSocketLimits(cfg websocket.HubConfig) Option {
+	return func(c *Config) error {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		if cfg.AllowedOrigins == nil {
+			cfg.AllowedOrigins = c.WebSocket.AllowedOrigins
+		}
+		c.WebSocket = cfg
+		return nil
+	}
+}
+
+// WithEventBus fans the event stream out between replicas, so each replica's clients see the
+// events of tasks processed by any of them: eventbus.DriverDatabase polls the task_events table,
+// eventbus.DriverRedis publishes on a Redis channel
+// The database driver needs the MySQL store, since WithTaskRepository keeps events in memory
+// Defaults to keeping events on the replica that broadcast them
+func WithEventBus(cfg eventbus.Config) Option {
+	return func(c *Config) error {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		c.EventBus = cfg
+		return nil
+	}
+}
+
+// WithPayloadRedaction sets how much of a task's payload WebSocket events carry:
+// websocket.RedactionOmit sends none, RedactionNone sends it whole, and RedactionFields masks the
+// values of the given JSON keys at any depth. API keys without payload access, as limited by
+// WithPayloadAPIKeys, are never sent payloads
+// Defaults to RedactionOmit
+func WithPayloadRedaction(mode string, fields ...string) Option {
+	return func(c *Config) error {
+		r := websocket.PayloadRedaction{Mode: mode, Fields: fields}
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		c.PayloadRedaction = r
+		return nil
+	}
+}
+
+// WithWebSocketAllowedOrigins lets browser pages on other origins open the event stream, such as
+// "https://dash.example.com", or "https://*.example.com" for any of its subdomains; "*" allows any
+// Defaults to pages served from the same host, and non-browser clients, which send no Origin
+func WithWebSocketAllowedOrigins(origins ...string) Option {
+	return func(c *Config) error {
+		cfg := websocket.HubConfig{AllowedOrigins: origins}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		c.WebSocket.AllowedOrigins = origins
+		return nil
+	}
+}
+
+// WithOnTaskCompleted registers a hook invoked after a task is persisted as completed
+// Hooks run asynchronously on a bounded queue; panics are recovered and logged
+func WithOnTaskCompleted(fn func(task *entity.Task)) Option {
+	return func(c *Config) error {
+		if fn == nil {
+			return fmt.Errorf("OnTaskCompleted hook cannot be nil")
+		}
+		c.Hooks.OnCompleted = fn
+		return nil
+	}
+}
+
+// WithOnTaskFailed registers a hook invoked after a task is persisted as failed
+// Hooks run asynchronously on a bounded queue; panics are recovered and logged
+func WithOnTaskFailed(fn func(task *entity.Task)) Option {
+	return func(c *Config) error {
+		if fn == nil {
+			return fmt.Errorf("OnTaskFailed hook cannot be nil")
+		}
+		c.Hooks.OnFailed = fn
+		return nil
+	}
+}
+
+// WithOnTaskDeadLettered registers a hook invoked after a task is moved to the dead letter queue
+// Hooks run asynchronously on a bounded queue; panics are recovered and logged
+func WithOnTaskDeadLettered(fn func(task *entity.Task)) Option {
+	return func(c *Config) error {
+		if fn == nil {
+			return fmt.Errorf("OnTaskDeadLettered hook cannot be nil")
+		}
+		c.Hooks.OnDeadLettered = fn
+		return nil
+	}
+}
+
+// WithWaitPollInterval sets how often WaitForTask re-reads a task from the database
+// Tasks finished by this instance's workers resolve immediately; polling catches the rest
+// Defaults to 1 second
+func WithWaitPollInterval(interval time.Duration) Option {
+	return func(c *Config) error {
+		if interval <= 0 {
+			return fmt.Errorf("wait poll interval must be positive")
+		}
+		c.WaitPollInterval = interval
+		return nil
+	}
+}
+
+// WithAPIKeys requires one of the given API keys on all task routes
+// Keys are accepted via "Authorization: Bearer", "X-API-Key" or the api_key query parameter
+// The health endpoint stays open
+func WithAPIKeys(keys []string) Option {
+	return func(c *Config) error {
+		if len(keys) == 0 {
+			return fmt.Errorf("API keys cannot be empty")
+		}
+		for _, key := range keys {
+			if key == "" {
+				return fmt.Errorf("API key cannot be empty")
+			}
+		}
+		c.APIKeys = keys
+		return nil
+	}
+}
+
+// WithTenantHeader scopes requests to the tenant named in the given trusted header
+// Only use this behind a gateway that sets the header; API key mappings take precedence
+func WithTenantHeader(header string) Option {
+	return func(c *Config) error {
+		if header == "" {
+			return fmt.Errorf("tenant header cannot be empty")
+		}
+		c.Tenant.Header = header
+		return nil
+	}
+}
+
+// WithTenantAPIKeys maps API keys to tenant IDs; each key only sees its tenant's tasks
+// The keys are accepted on all task routes in addition to those from WithAPIKeys
+func WithTenantAPIKeys(keyTenants map[string]string) Option {
+	return func(c *Config) error {
+		if len(keyTenants) == 0 {
+			return fmt.Errorf("tenant API keys cannot be empty")
+		}
+		for key, tenantID := range keyTenants {
+			if key == "" || tenantID == "" {
+				return fmt.Errorf("tenant API key and tenant ID cannot be empty")
+			}
+		}
+		c.Tenant.KeyTenants = keyTenants
+		return nil
+	}
+}
+
+// WithAdminAPIKeys grants the given API keys an unscoped view across all tenants
+func WithAdminAPIKeys(keys []string) Option {
+	return func(c *Config) error {
+		if len(keys) == 0 {
+			return fmt.Errorf("admin API keys cannot be empty")
+		}
+		for _, key := range keys {
+			if key == "" {
+				return fmt.Errorf("admin API key cannot be empty")
+			}
+		}
+		c.Tenant.AdminKeys = keys
+		return nil
+	}
+}
+
+// WithPayloadAPIKeys limits stored payloads in task responses to the given API keys
+// Listings, exports, task details, retries and executions requested with any other key have
+// their payloads redacted
+// The keys must also be accepted, e.g. through WithAPIKeys
+func WithPayloadAPIKeys(keys []string) Option {
+	return func(c *Config) error {
+		if len(keys) == 0 {
+			return fmt.Errorf("payload API keys cannot be empty")
+		}
+		for _, key := range keys {
+			if key == "" {
+				return fmt.Errorf("payload API key cannot be empty")
+			}
+		}
+		c.PayloadKeys = keys
+		return nil
+	}
+}
@@ -1,91 +1,188 @@
 package later
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
-	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/delivery/rest"
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/middleware"
+	"github.com/usual2970/later/delivery/rest/response"
+	"github.com/usual2970/later/delivery/websocket"
+	"github.com/usual2970/later/domain"
 	"github.com/usual2970/later/infrastructure/logger"
+	tasksvc "github.com/usual2970/later/task"
 )
 
 // RegisterRoutes registers Later's HTTP routes with the provided Gin engine
-// The routes will be mounted under the configured RoutePrefix
-func (l *Later) RegisterRoutes(engine *gin.Engine) error {
+// The routes will be mounted under the configured RoutePrefix; see RegisterRoutesGroup
+func (l *Later) RegisterRoutes(engine *gin.Engine) (gin.RoutesInfo, error) {
 	if engine == nil {
-		return fmt.Errorf("engine cannot be nil")
+		return nil, fmt.Errorf("engine cannot be nil")
 	}
+	return l.RegisterRoutesGroup(engine.Group(l.config.RoutePrefix))
+}
+
+// RegisterRoutesGroup registers Later's HTTP routes directly under the provided group, e.g. a
+// versioned group of the host application; RoutePrefix doesn't apply. Every route runs, in
+// order: the group's own middleware, Later's request ID middleware, its logger and recovery
+// unless disabled with WithDisableDefaultMiddleware, the handlers added with
+// WithRouteMiddleware, and then the route's API key, tenant and rate limit checks before its
+// handler. Returns the registered routes, with their full paths
+func (l *Later) RegisterRoutesGroup(group *gin.RouterGroup) (gin.RoutesInfo, error) {
+	if group == nil {
+		return nil, fmt.Errorf("route group cannot be nil")
+	}
+
+	// Keep Later's middleware off the host's group
+	group = group.Group("")
+
+	// Apply Later's middleware, then the host's
+	group.Use(middleware.RequestID())
+	if !l.config.DisableDefaultMiddleware {
+		group.Use(l.loggerMiddleware())
+		group.Use(l.recoveryMiddleware())
+	}
+	group.Use(l.config.RouteMiddleware...)
 
-	// Create route group with prefix
-	group := engine.Group(l.config.RoutePrefix)
+	var routes routeList
 
-	// Apply Later's middleware
-	group.Use(l.loggerMiddleware())
-	group.Use(l.recoveryMiddleware())
+	// Health check endpoints: /healthz for liveness, /readyz for readiness
+	routes.handle(group, http.MethodGet, "/health", l.healthCheckHandler)
+	routes.handle(group, http.MethodGet, "/healthz", l.livenessHandler)
+	routes.handle(group, http.MethodGet, "/readyz", l.readinessHandler)
 
-	// Health check endpoint
-	group.GET("/health", l.healthCheckHandler)
+	// Task routes (protected by API keys and scoped to a tenant when configured)
+	tasks := group.Group("/tasks",
+		middleware.APIKeyAuth(l.config.acceptedAPIKeys()),
+		middleware.TenantScope(l.config.Tenant),
+		middleware.PayloadAccess(l.config.PayloadKeys),
+	)
+	h := rest.NewHandler(l.taskService, l.scheduler, l.executor, l.hub)
+	{
+		routes.handle(tasks, http.MethodPost, "", middleware.RateLimit(l.createLimiter), h.CreateTask)
+		routes.handle(tasks, http.MethodPost, "/validate", h.ValidateTask)
+		routes.handle(tasks, http.MethodGet, "", l.listTasksHandler(h))
+		routes.handle(tasks, http.MethodGet, "/export", h.ExportTasks)
+		routes.handle(tasks, http.MethodGet, "/upcoming", h.UpcomingTasks)
+		routes.handle(tasks, http.MethodPost, "/import", middleware.RateLimit(l.createLimiter), h.ImportTasks)
+		routes.handle(tasks, http.MethodGet, "/:id", h.GetTask)
+		routes.handle(tasks, http.MethodGet, "/:id/timeline", h.GetTaskTimeline)
+		routes.handle(tasks, http.MethodGet, "/:id/retry-schedule", h.GetRetrySchedule)
+		routes.handle(tasks, http.MethodPut, "/:id/tags", h.SetTaskTags)
+		routes.handle(tasks, http.MethodPatch, "/:id/tags", h.EditTaskTags)
+		routes.handle(tasks, http.MethodDelete, "/:id", l.deleteTaskHandler)
+		routes.handle(tasks, http.MethodPost, "/:id/retry", h.RetryTask)
+		routes.handle(tasks, http.MethodPost, "/:id/resurrect", h.ResurrectTask)
+		routes.handle(tasks, http.MethodPost, "/:id/execute", h.ExecuteTask)
+		routes.handle(tasks, http.MethodPost, "/:id/abort", h.AbortTask)
+		routes.handle(tasks, http.MethodPost, "/bulk/delete", l.bulkDeleteHandler)
+		routes.handle(tasks, http.MethodPost, "/bulk/retry", l.bulkRetryHandler)
+		routes.handle(tasks, http.MethodGet, "/stats", l.getStatsHandler)
+		routes.handle(tasks, http.MethodGet, "/stats/timeseries", l.getTimeSeriesHandler)
+		routes.handle(tasks, http.MethodGet, "/stats/by-name", h.GetStatsByName)
+		routes.handle(tasks, http.MethodGet, "/stats/by-tag", h.GetStatsByTag)
+	}
+
+	// Real-time task events
+	if l.hub != nil {
+		routes.handle(tasks, http.MethodGet, "/stream", websocket.ServeWS(l.hub))
+	}
+
+	// Sample deliveries for receivers to test against
+	callbacks := group.Group("/callbacks",
+		middleware.APIKeyAuth(l.config.acceptedAPIKeys()),
+		middleware.TenantScope(l.config.Tenant),
+	)
+	routes.handle(callbacks, http.MethodPost, "/test", middleware.RateLimit(l.createLimiter), h.TestCallback)
 
-	// Task routes
-	tasks := group.Group("/tasks")
+	// Runtime settings, restricted to admin keys
+	admin := group.Group("/admin",
+		middleware.APIKeyAuth(l.config.acceptedAPIKeys()),
+		middleware.TenantScope(l.config.Tenant),
+		middleware.AdminOnly(l.config.Tenant.AdminKeys),
+	)
 	{
-		tasks.POST("", l.createTaskHandler)
-		tasks.GET("", l.listTasksHandler)
-		tasks.GET("/:id", l.getTaskHandler)
-		tasks.DELETE("/:id", l.deleteTaskHandler)
-		tasks.POST("/:id/retry", l.retryTaskHandler)
-		tasks.POST("/:id/resurrect", l.resurrectTaskHandler)
-		tasks.GET("/stats", l.getStatsHandler)
+		adminHandler := rest.NewAdminHandler(l.limiter, l.scheduler)
+		routes.handle(admin, http.MethodGet, "/concurrency-limits", adminHandler.ListConcurrencyLimits)
+		routes.handle(admin, http.MethodPut, "/concurrency-limits/:key", adminHandler.SetConcurrencyLimit)
+		routes.handle(admin, http.MethodDelete, "/concurrency-limits/:key", adminHandler.DeleteConcurrencyLimit)
+		routes.handle(admin, http.MethodPut, "/log-level", adminHandler.SetLogLevel)
+		routes.handle(admin, http.MethodPost, "/cleanup", adminHandler.RunCleanup)
+
+		// Callback hosts held during incidents
+		routes.handle(admin, http.MethodGet, "/hosts", h.ListPausedHosts)
+		routes.handle(admin, http.MethodPost, "/hosts/:host/pause", h.PauseHost)
+		routes.handle(admin, http.MethodDelete, "/hosts/:host/pause", h.ResumeHost)
 	}
 
 	l.logger.Info("Routes registered successfully",
-		zap.String("prefix", l.config.RoutePrefix),
-		zap.Int("endpoints", 7),
+		zap.String("prefix", group.BasePath()),
+		zap.Int("endpoints", len(routes)),
+		zap.Int("route_middleware", len(l.config.RouteMiddleware)),
+		zap.Bool("default_middleware", !l.config.DisableDefaultMiddleware),
 	)
 
-	return nil
+	return gin.RoutesInfo(routes), nil
 }
 
-// loggerMiddleware logs HTTP requests
-func (l *Later) loggerMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		start := time.Now()
-		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
-
-		// Process request
-		c.Next()
-
-		// Log request details
-		latency := time.Since(start)
-		statusCode := c.Writer.Status()
-		clientIP := c.ClientIP()
-		method := c.Request.Method
-
-		logger.Info("HTTP request",
-			logger.String("method", method),
-			logger.String("path", path),
-			logger.String("query", query),
-			logger.Int("status", statusCode),
-			logger.String("latency", latency.String()),
-			logger.String("client_ip", clientIP),
-			logger.Int("response_size", c.Writer.Size()),
-		)
+// Handler returns Later's HTTP routes as an http.Handler, for applications built on net/http or
+// routers such as chi rather than Gin. It serves the routes RegisterRoutes would, with the same
+// handlers, middleware and responses, under RoutePrefix, so it is mounted at that prefix:
+//
+//	laterSDK, _ := later.New(later.WithRoutePrefix("/internal/tasks"), ...)
+//	mux.Mount("/internal/tasks", laterSDK.Handler())
+//
+// Gin only runs inside the handler; its engine trusts no proxy headers, so rate limits key on
+// the RemoteAddr the host's own middleware leaves. Each call builds a new handler
+func (l *Later) Handler() http.Handler {
+	engine := gin.New()
+	// Can only fail for proxy lists that don't parse
+	_ = engine.SetTrustedProxies(nil)
+	// Can only fail for a nil group
+	_, _ = l.RegisterRoutes(engine)
+	return engine
+}
 
-		// Log errors if any
-		if len(c.Errors) > 0 {
-			for _, e := range c.Errors {
-				logger.Error("HTTP request error",
-					logger.String("type", e.Error()),
-					logger.String("error", e.Error()),
-				)
-			}
-		}
+// routeList records the routes registered through it, like gin.Engine.Routes does for an engine
+type routeList gin.RoutesInfo
+
+// handle registers the route on group and records it
+func (r *routeList) handle(group *gin.RouterGroup, method, relativePath string, handlers ...gin.HandlerFunc) {
+	group.Handle(method, relativePath, handlers...)
+	handler := handlers[len(handlers)-1]
+	*r = append(*r, gin.RouteInfo{
+		Method:      method,
+		Path:        joinPaths(group.BasePath(), relativePath),
+		Handler:     runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name(),
+		HandlerFunc: handler,
+	})
+}
+
+// joinPaths joins a group's base path and a route's relative path as gin does
+func joinPaths(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+	joined := path.Join(base, relative)
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
 	}
+	return joined
+}
+
+// loggerMiddleware logs HTTP requests as configured with WithRequestLogging
+func (l *Later) loggerMiddleware() gin.HandlerFunc {
+	return middleware.RequestLogger(l.logger.Named("http"), l.config.RequestLogging)
 }
 
 // recoveryMiddleware recovers from panics
@@ -116,233 +213,32 @@ func (l *Later) healthCheckHandler(c *gin.Context) {
 	c.JSON(httpStatus, status)
 }
 
-// createTaskHandler handles POST /tasks
-func (l *Later) createTaskHandler(c *gin.Context) {
-	var req CreateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid_request",
-			"message": err.Error(),
-		})
-		return
-	}
-
-	// Validate request
-	if req.Name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "validation_error",
-			"message": "name is required",
-		})
-		return
-	}
-
-	if req.CallbackURL == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "validation_error",
-			"message": "callback_url is required",
-		})
-		return
-	}
-
-	// Set defaults
-	if req.ScheduledAt.IsZero() {
-		req.ScheduledAt = time.Now()
-	}
-
-	if req.MaxRetries == 0 {
-		req.MaxRetries = 5
-	}
-
-	// Create task
-	task, err := l.CreateTask(c.Request.Context(), &req)
-	if err != nil {
-		logger.Error("Failed to create task",
-			logger.String("handler", "createTaskHandler"),
-			logger.Any("error", err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_error",
-			"message": "Failed to create task",
-		})
-		return
-	}
-
-	// Build response
-	estimatedExec := "scheduled"
-	if task.ShouldExecuteNow() {
-		estimatedExec = "immediate"
-	}
-
-	// Convert JSONBytes to string for JSON response
-	var payloadStr string
-	if len(task.Payload) > 0 {
-		payloadStr = string(task.Payload)
-	}
-
-	c.JSON(http.StatusAccepted, gin.H{
-		"id":                  task.ID,
-		"name":                task.Name,
-		"payload":             payloadStr,
-		"callback_url":        task.CallbackURL,
-		"status":              task.Status,
-		"created_at":          task.CreatedAt,
-		"scheduled_for":       task.ScheduledAt,
-		"max_retries":         task.MaxRetries,
-		"retry_count":         task.RetryCount,
-		"callback_attempts":   task.CallbackAttempts,
-		"priority":            task.Priority,
-		"tags":                task.Tags,
-		"estimated_execution": estimatedExec,
+// livenessHandler reports that the process is serving requests
+func (l *Later) livenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "ok",
+		"timestamp": time.Now().Format(time.RFC3339),
 	})
 }
 
-// getTaskHandler handles GET /tasks/:id
-func (l *Later) getTaskHandler(c *gin.Context) {
-	id := c.Param("id")
-
-	task, err := l.GetTask(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "task_not_found",
-			"message": "Task not found",
-		})
-		return
-	}
+// readinessHandler returns the health status with 503 unless Later is ready for traffic
+func (l *Later) readinessHandler(c *gin.Context) {
+	status := l.HealthCheck()
 
-	// Convert JSONBytes to string for JSON response
-	var payloadStr string
-	if len(task.Payload) > 0 {
-		payloadStr = string(task.Payload)
+	httpStatus := http.StatusOK
+	if !status.Ready {
+		httpStatus = http.StatusServiceUnavailable
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":                task.ID,
-		"name":              task.Name,
-		"payload":           payloadStr,
-		"callback_url":      task.CallbackURL,
-		"status":            task.Status,
-		"created_at":        task.CreatedAt,
-		"scheduled_for":     task.ScheduledAt,
-		"started_at":        task.StartedAt,
-		"completed_at":      task.CompletedAt,
-		"max_retries":       task.MaxRetries,
-		"retry_count":       task.RetryCount,
-		"callback_attempts": task.CallbackAttempts,
-		"priority":          task.Priority,
-		"tags":              task.Tags,
-		"error_message":     task.ErrorMessage,
-	})
+	c.JSON(httpStatus, status)
 }
 
 // listTasksHandler handles GET /tasks
-func (l *Later) listTasksHandler(c *gin.Context) {
-	// Parse query parameters
-	var filter TaskFilter
-
-	// Set defaults
-	filter.Page = 1
-	filter.Limit = 10
-	filter.SortBy = "created_at"
-	filter.SortOrder = "DESC"
-
-	// Parse pagination
-	if page := c.Query("page"); page != "" {
-		if p, err := strconv.Atoi(page); err == nil && p > 0 {
-			filter.Page = p
-		}
-	}
-
-	if limit := c.Query("limit"); limit != "" {
-		if l, err := strconv.Atoi(limit); err == nil && l > 0 && l <= 100 {
-			filter.Limit = l
-		}
-	}
-
-	// Parse filters
-	if status := c.Query("status"); status != "" {
-		filter.Status = status
-	}
-
-	if sortBy := c.Query("sort_by"); sortBy != "" {
-		filter.SortBy = sortBy
-	}
-
-	if sortOrder := c.Query("sort_order"); sortOrder != "" {
-		filter.SortOrder = sortOrder
-	}
-
-	logger.Info("Listing tasks",
-		logger.String("handler", "listTasksHandler"),
-		logger.Int("page", filter.Page),
-		logger.Int("limit", filter.Limit),
-		logger.String("status", filter.Status),
-		logger.String("sort_by", filter.SortBy),
-		logger.String("sort_order", filter.SortOrder),
-	)
-
-	// Fetch tasks
-	tasks, total, err := l.ListTasks(c.Request.Context(), &filter)
-	if err != nil {
-		logger.Error("Failed to list tasks",
-			logger.String("handler", "listTasksHandler"),
-			logger.Any("error", err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_error",
-			"message": "Failed to list tasks",
-		})
-		return
-	}
-
-	logger.Info("Successfully fetched tasks",
-		logger.String("handler", "listTasksHandler"),
-		logger.Int("count", len(tasks)),
-		logger.Int64("total", total),
-	)
-
-	// Convert to response format
-	taskResponses := make([]gin.H, len(tasks))
-	for i, task := range tasks {
-		// Convert JSONBytes to string
-		var payloadStr string
-		if len(task.Payload) > 0 {
-			payloadStr = string(task.Payload)
-		}
-
-		taskResponses[i] = gin.H{
-			"id":                task.ID,
-			"name":              task.Name,
-			"payload":           payloadStr,
-			"callback_url":      task.CallbackURL,
-			"status":            task.Status,
-			"created_at":        task.CreatedAt,
-			"scheduled_for":     task.ScheduledAt,
-			"started_at":        task.StartedAt,
-			"completed_at":      task.CompletedAt,
-			"max_retries":       task.MaxRetries,
-			"retry_count":       task.RetryCount,
-			"callback_attempts": task.CallbackAttempts,
-			"priority":          task.Priority,
-			"tags":              task.Tags,
-			"error_message":     task.ErrorMessage,
-		}
-	}
-
-	// Calculate pagination
-	totalPages := int(total) / filter.Limit
-	if int(total)%filter.Limit != 0 {
-		totalPages++
+// It is the REST server's list, except that page and limit are optional
+func (l *Later) listTasksHandler(h *rest.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.ListTasksFrom(c, dto.ListTasksQuery{Page: 1, Limit: 10})
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"tasks": taskResponses,
-		"pagination": gin.H{
-			"page":        filter.Page,
-			"limit":       filter.Limit,
-			"total":       total,
-			"total_pages": totalPages,
-		},
-	})
 }
 
 // deleteTaskHandler handles DELETE /tasks/:id
@@ -358,10 +254,7 @@ func (l *Later) deleteTaskHandler(c *gin.Context) {
 	// Get task first to validate
 	task, err := l.GetTask(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "task_not_found",
-			"message": "Task not found",
-		})
+		response.DomainError(c, err, "Failed to get task")
 		return
 	}
 
@@ -379,175 +272,118 @@ func (l *Later) deleteTaskHandler(c *gin.Context) {
 		logger.Error("Failed to delete task",
 			logger.String("handler", "deleteTaskHandler"),
 			logger.String("task_id", id),
+			logger.RequestID(task.RequestID),
 			logger.Any("error", err),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_error",
-			"message": "Failed to delete task",
-		})
+		response.DomainError(c, err, "Failed to delete task")
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
-// retryTaskHandler handles POST /tasks/:id/retry
-func (l *Later) retryTaskHandler(c *gin.Context) {
-	id := c.Param("id")
-
-	// Get task
-	task, err := l.GetTask(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "task_not_found",
-			"message": "Task not found",
-		})
-		return
+// bulkDeleteHandler handles POST /tasks/bulk/delete
+func (l *Later) bulkDeleteHandler(c *gin.Context) {
+	deletedBy := "system"
+	if userID := c.GetHeader("X-User-ID"); userID != "" {
+		deletedBy = userID
 	}
 
-	// Can only retry failed tasks
-	if task.Status != entity.TaskStatusFailed {
+	l.bulkHandler(c, "bulkDeleteHandler", func(sel tasksvc.BulkSelector) (*tasksvc.BulkResult, error) {
+		return l.BulkDeleteTasks(c.Request.Context(), sel, deletedBy)
+	})
+}
+
+// bulkRetryHandler handles POST /tasks/bulk/retry
+func (l *Later) bulkRetryHandler(c *gin.Context) {
+	l.bulkHandler(c, "bulkRetryHandler", func(sel tasksvc.BulkSelector) (*tasksvc.BulkResult, error) {
+		return l.BulkRetryTasks(c.Request.Context(), sel)
+	})
+}
+
+// bulkHandler binds a bulk request, runs the operation and writes its result
+func (l *Later) bulkHandler(c *gin.Context, handler string, run func(tasksvc.BulkSelector) (*tasksvc.BulkResult, error)) {
+	var req dto.BulkTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid_status",
-			"message": "Can only retry failed tasks",
+			"error":   "invalid_request",
+			"message": err.Error(),
 		})
 		return
 	}
 
-	// Retry task
-	retriedTask, err := l.RetryTask(c.Request.Context(), id)
+	result, err := run(req.ToSelector())
 	if err != nil {
-		logger.Error("Failed to retry task",
-			logger.String("handler", "retryTaskHandler"),
-			logger.String("task_id", id),
-			logger.Any("error", err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_error",
-			"message": "Failed to retry task",
-		})
+		if status, _ := response.StatusFor(err); status == http.StatusInternalServerError {
+			logger.Error("Bulk operation failed",
+				logger.String("handler", handler),
+				logger.Any("error", err),
+			)
+		}
+		response.DomainError(c, err, "Failed to update tasks")
 		return
 	}
 
-	// Convert JSONBytes to string
-	var payloadStr string
-	if len(retriedTask.Payload) > 0 {
-		payloadStr = string(retriedTask.Payload)
-	}
-
-	c.JSON(http.StatusAccepted, gin.H{
-		"id":                  retriedTask.ID,
-		"name":                retriedTask.Name,
-		"payload":             payloadStr,
-		"callback_url":        retriedTask.CallbackURL,
-		"status":              retriedTask.Status,
-		"created_at":          retriedTask.CreatedAt,
-		"scheduled_for":       retriedTask.ScheduledAt,
-		"max_retries":         retriedTask.MaxRetries,
-		"retry_count":         retriedTask.RetryCount,
-		"callback_attempts":   retriedTask.CallbackAttempts,
-		"priority":            retriedTask.Priority,
-		"tags":                retriedTask.Tags,
-		"estimated_execution": "immediate",
-	})
+	c.JSON(http.StatusOK, dto.BulkTaskResponse{Count: result.Count, DryRun: result.DryRun})
 }
 
-// resurrectTaskHandler handles POST /tasks/:id/resurrect
-func (l *Later) resurrectTaskHandler(c *gin.Context) {
-	id := c.Param("id")
-
-	// Get task
-	task, err := l.GetTask(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "task_not_found",
-			"message": "Task not found",
-		})
-		return
-	}
-
-	// Can only resurrect dead_lettered tasks
-	if task.Status != entity.TaskStatusDeadLettered {
+// getStatsHandler handles GET /tasks/stats
+func (l *Later) getStatsHandler(c *gin.Context) {
+	window := c.DefaultQuery("window", tasksvc.DefaultStatsWindow)
+	stats, err := l.GetStatsForWindow(c.Request.Context(), window)
+	if errors.Is(err, domain.ErrBadParamInput) {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid_status",
-			"message": "Can only resurrect dead_lettered tasks",
+			"error":   "invalid_query",
+			"message": "window must be one of 1h, 24h, 7d",
 		})
 		return
 	}
-
-	// Reset task for resurrection
-	task.Status = entity.TaskStatusPending
-	task.RetryCount = 0
-	task.NextRetryAt = nil
-	task.ErrorMessage = nil
-	task.StartedAt = nil
-	task.CompletedAt = nil
-
-	if err := l.taskService.UpdateTask(c.Request.Context(), task); err != nil {
-		logger.Error("Failed to resurrect task",
-			logger.String("handler", "resurrectTaskHandler"),
-			logger.String("task_id", id),
+	if err != nil {
+		logger.Error("Failed to get stats",
+			logger.String("handler", "getStatsHandler"),
 			logger.Any("error", err),
 		)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
-			"message": "Failed to resurrect task",
+			"message": "Failed to get statistics",
 		})
 		return
 	}
 
-	logger.Info("Task resurrected",
-		logger.String("handler", "resurrectTaskHandler"),
-		logger.String("task_id", id),
-	)
-
-	// Submit immediately if due now
-	if task.ShouldExecuteNow() {
-		l.scheduler.SubmitTaskImmediately(task)
-	}
-
-	// Convert JSONBytes to string
-	var payloadStr string
-	if len(task.Payload) > 0 {
-		payloadStr = string(task.Payload)
-	}
-
-	c.JSON(http.StatusAccepted, gin.H{
-		"id":                  task.ID,
-		"name":                task.Name,
-		"payload":             payloadStr,
-		"callback_url":        task.CallbackURL,
-		"status":              task.Status,
-		"created_at":          task.CreatedAt,
-		"scheduled_for":       task.ScheduledAt,
-		"max_retries":         task.MaxRetries,
-		"retry_count":         task.RetryCount,
-		"callback_attempts":   task.CallbackAttempts,
-		"priority":            task.Priority,
-		"tags":                task.Tags,
-		"estimated_execution": "immediate",
+	c.JSON(http.StatusOK, gin.H{
+		"total":                 stats.Total,
+		"by_status":             stats.ByStatus,
+		"last_24h":              stats.Last24h,
+		"window":                stats.Window,
+		"recent":                stats.Recent,
+		"callback_success_rate": stats.CallbackSuccessRate,
 	})
 }
 
-// getStatsHandler handles GET /tasks/stats
-func (l *Later) getStatsHandler(c *gin.Context) {
-	stats, err := l.GetStats(c.Request.Context())
+// getTimeSeriesHandler handles GET /tasks/stats/timeseries
+func (l *Later) getTimeSeriesHandler(c *gin.Context) {
+	window := c.DefaultQuery("window", tasksvc.DefaultStatsWindow)
+	bucket := c.DefaultQuery("bucket", "1h")
+
+	series, err := l.GetTimeSeries(c.Request.Context(), window, bucket)
+	if errors.Is(err, domain.ErrBadParamInput) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_query",
+			"message": err.Error(),
+		})
+		return
+	}
 	if err != nil {
-		logger.Error("Failed to get stats",
-			logger.String("handler", "getStatsHandler"),
+		logger.Error("Failed to get time series",
+			logger.String("handler", "getTimeSeriesHandler"),
 			logger.Any("error", err),
 		)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
-			"message": "Failed to get statistics",
+			"message": "Failed to get time series",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"total":                 stats.Total,
-		"by_status":             stats.ByStatus,
-		"last_24h":              stats.Last24h,
-		"callback_success_rate": stats.CallbackSuccessRate,
-	})
+	c.JSON(http.StatusOK, series)
 }
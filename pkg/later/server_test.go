@@ -2,15 +2,34 @@ package later
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/rest/middleware"
+	"github.com/usual2970/later/delivery/websocket"
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/logger"
+	"github.com/usual2970/later/infrastructure/worker"
+	"github.com/usual2970/later/repository/memory"
+	tasksvc "github.com/usual2970/later/task"
 )
 
 // TestRegisterRoutes tests that routes are registered correctly
@@ -19,12 +38,72 @@ func TestRegisterRoutes(t *testing.T) {
 	// doesn't panic when given valid input
 	t.Run("RegisterRoutes with nil engine", func(t *testing.T) {
 		l := &Later{}
-		err := l.RegisterRoutes(nil)
+		_, err := l.RegisterRoutes(nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "engine cannot be nil")
+
+		_, err = l.RegisterRoutesGroup(nil)
+		assert.Error(t, err)
 	})
 }
 
+// TestRegisterRoutesGroup tests mounting Later under a host's group, behind its middleware
+func TestRegisterRoutesGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	l := &Later{
+		config: &Config{RoutePrefix: "/api/v1"},
+		logger: testLogger(),
+	}
+	router := gin.New()
+	v2 := router.Group("/v2", func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer host" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	})
+	routes, err := l.RegisterRoutesGroup(v2.Group("/later"))
+	assert.NoError(t, err)
+
+	// The host's routes on the same group don't get Later's middleware
+	v2.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+
+	// The routes returned are the ones the engine serves, prefixed with the group's path
+	served := router.Routes()
+	for _, route := range routes {
+		assert.True(t, strings.HasPrefix(route.Path, "/v2/later/"), route.Path)
+		assert.True(t, slices.ContainsFunc(served, func(r gin.RouteInfo) bool {
+			return r.Method == route.Method && r.Path == route.Path
+		}), "%s %s not served", route.Method, route.Path)
+	}
+	assert.Len(t, routes, len(served)-1)
+	assert.True(t, slices.ContainsFunc(routes, func(r gin.RouteInfo) bool {
+		return r.Method == http.MethodPost && r.Path == "/v2/later/tasks" && strings.HasSuffix(r.Handler, "CreateTask-fm")
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/later/healthz", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "group middleware should apply")
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/later/healthz", nil)
+	req.Header.Set("Authorization", "Bearer host")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get(middleware.RequestIDHeader))
+
+	req = httptest.NewRequest(http.MethodGet, "/v2/ping", nil)
+	req.Header.Set("Authorization", "Bearer host")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(middleware.RequestIDHeader))
+
+	// RegisterRoutes mounts the same routes under RoutePrefix
+	assert.Len(t, registerRoutes(t, l, gin.New()), len(routes))
+}
+
 // TestHealthCheckHandler tests the health check endpoint
 func TestHealthCheckHandler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -37,7 +116,7 @@ func TestHealthCheckHandler(t *testing.T) {
 	}
 
 	router := gin.New()
-	err := l.RegisterRoutes(router)
+	_, err := l.RegisterRoutes(router)
 	assert.NoError(t, err)
 
 	req, _ := http.NewRequest("GET", "/api/v1/health", nil)
@@ -53,6 +132,46 @@ func TestHealthCheckHandler(t *testing.T) {
 	assert.Equal(t, "stopped", response["status"])
 }
 
+// TestHealthProbeHandlers tests the liveness and readiness endpoints
+func TestHealthProbeHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		started  bool
+		stopping bool
+		status   string
+	}{
+		{"before start", false, false, "stopped"},
+		{"shutting down", true, true, "stopping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Later{
+				config:   &Config{RoutePrefix: "/api/v1"},
+				logger:   testLogger(),
+				started:  tt.started,
+				stopping: tt.stopping,
+			}
+			router := gin.New()
+			registerRoutes(t, l, router)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/healthz", nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/readyz", nil))
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+			var response HealthStatus
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.False(t, response.Ready)
+			assert.Equal(t, tt.status, response.Status)
+		})
+	}
+}
+
 // TestCreateTaskHandler tests the create task endpoint
 func TestCreateTaskHandler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -65,7 +184,7 @@ func TestCreateTaskHandler(t *testing.T) {
 	}
 
 	router := gin.New()
-	err := l.RegisterRoutes(router)
+	_, err := l.RegisterRoutes(router)
 	assert.NoError(t, err)
 
 	t.Run("Create task with invalid JSON", func(t *testing.T) {
@@ -136,7 +255,7 @@ func TestRoutePrefix(t *testing.T) {
 			}
 
 			router := gin.New()
-			err := l.RegisterRoutes(router)
+			_, err := l.RegisterRoutes(router)
 			assert.NoError(t, err)
 
 			req, _ := http.NewRequest("GET", tt.reqPath, nil)
@@ -160,7 +279,7 @@ func TestMiddleware(t *testing.T) {
 	}
 
 	router := gin.New()
-	l.RegisterRoutes(router)
+	registerRoutes(t, l, router)
 
 	t.Run("Logger middleware is applied", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/api/v1/health", nil)
@@ -182,7 +301,515 @@ func TestMiddleware(t *testing.T) {
 	})
 }
 
+// TestRouteMiddleware tests that the host's middleware runs in order before Later's handlers
+func TestRouteMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var order []string
+	record := func(name string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			// Later's request ID is assigned before the host's middleware
+			order = append(order, name+":"+c.GetString(middleware.ContextKeyRequestID))
+			c.Next()
+		}
+	}
+	hostAuth := func(c *gin.Context) {
+		if c.GetHeader("X-Host-Token") != "secret" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "host_unauthorized"})
+			return
+		}
+		c.Next()
+	}
+
+	config := &Config{RoutePrefix: "/api/v1"}
+	for _, opt := range []Option{WithRouteMiddleware(record("first"), record("second")), WithRouteMiddleware(hostAuth)} {
+		assert.NoError(t, opt(config))
+	}
+	l := &Later{config: config, logger: testLogger()}
+	router := gin.New()
+	registerRoutes(t, l, router)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/stats", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "host auth should reject before the handler runs")
+	assert.Contains(t, w.Body.String(), "host_unauthorized")
+	assert.Equal(t, []string{"first:req-1", "second:req-1"}, order)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/healthz", nil)
+	req.Header.Set("X-Host-Token", "secret")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, order, 4)
+
+	assert.Error(t, WithRouteMiddleware(nil)(&Config{}))
+}
+
+// TestDisableDefaultMiddleware tests that panics reach the host's recovery without Later's
+func TestDisableDefaultMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	panicking := func(c *gin.Context) { panic("host middleware failed") }
+	for _, disabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("disabled=%v", disabled), func(t *testing.T) {
+			config := &Config{RoutePrefix: "/api/v1"}
+			assert.NoError(t, WithRouteMiddleware(panicking)(config))
+			assert.NoError(t, WithDisableDefaultMiddleware(disabled)(config))
+			l := &Later{config: config, logger: testLogger()}
+			router := gin.New()
+			registerRoutes(t, l, router)
+
+			serve := func() {
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/healthz", nil))
+			}
+			if disabled {
+				assert.Panics(t, serve)
+			} else {
+				assert.NotPanics(t, serve)
+			}
+		})
+	}
+}
+
+// registerRoutes registers l's routes with router, failing the test on error
+func registerRoutes(t *testing.T, l *Later, router *gin.Engine) gin.RoutesInfo {
+	t.Helper()
+	routes, err := l.RegisterRoutes(router)
+	assert.NoError(t, err)
+	return routes
+}
+
 // testLogger returns a test logger instance
 func testLogger() *zap.Logger {
 	return zap.NewNop()
 }
+
+// TestStreamRoute tests that the WebSocket stream is mounted when events are enabled
+func TestStreamRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	l := &Later{
+		config: &Config{RoutePrefix: "/api/v1"},
+		logger: testLogger(),
+		hub:    websocket.NewHub(testLogger(), websocket.HubConfig{}),
+	}
+
+	router := gin.New()
+	registerRoutes(t, l, router)
+
+	// A plain GET without upgrade headers is rejected by the upgrader
+	req, _ := http.NewRequest("GET", "/api/v1/tasks/stream", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestConcurrencyLimitRoutes tests adjusting concurrency limits through the admin routes
+func TestConcurrencyLimitRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter, err := worker.NewConcurrencyLimiter(map[string]int{"email": 5}, 0)
+	assert.NoError(t, err)
+
+	l := &Later{
+		config: &Config{
+			RoutePrefix: "/api/v1",
+			APIKeys:     []string{"plain-key"},
+			Tenant:      middleware.TenantConfig{AdminKeys: []string{"admin-key"}},
+		},
+		logger:  testLogger(),
+		limiter: limiter,
+	}
+
+	router := gin.New()
+	registerRoutes(t, l, router)
+
+	send := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Non-admin key is forbidden", func(t *testing.T) {
+		w := send("PUT", "/api/v1/admin/concurrency-limits/email", "plain-key", `{"max_in_flight": 1}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, map[string]int{"email": 5}, l.ConcurrencyLimits())
+	})
+
+	t.Run("Admin key sets a limit", func(t *testing.T) {
+		w := send("PUT", "/api/v1/admin/concurrency-limits/sms", "admin-key", `{"max_in_flight": 2}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]int{"email": 5, "sms": 2}, l.ConcurrencyLimits())
+	})
+
+	t.Run("Limit must be positive", func(t *testing.T) {
+		w := send("PUT", "/api/v1/admin/concurrency-limits/sms", "admin-key", `{"max_in_flight": 0}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Admin key lists limits", func(t *testing.T) {
+		w := send("GET", "/api/v1/admin/concurrency-limits", "admin-key", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Limits []map[string]interface{} `json:"limits"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp.Limits, 2)
+		assert.Equal(t, "email", resp.Limits[0]["key"])
+	})
+
+	t.Run("Admin key removes a limit", func(t *testing.T) {
+		w := send("DELETE", "/api/v1/admin/concurrency-limits/email", "admin-key", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, map[string]int{"sms": 2}, l.ConcurrencyLimits())
+
+		w = send("DELETE", "/api/v1/admin/concurrency-limits/email", "admin-key", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Admin key changes the log level", func(t *testing.T) {
+		defer logger.SetLevel(logger.Level())
+
+		w := send("PUT", "/api/v1/admin/log-level", "plain-key", `{"level": "debug"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = send("PUT", "/api/v1/admin/log-level", "admin-key", `{"level": "verbose"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = send("PUT", "/api/v1/admin/log-level", "admin-key", `{"level": "debug"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"level": "debug"}`, w.Body.String())
+		assert.Equal(t, "debug", logger.Level())
+	})
+}
+
+// TestBulkRoutes tests that the bulk routes are mounted and validate their selection
+func TestBulkRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	l := &Later{
+		config:      &Config{RoutePrefix: "/api/v1"},
+		logger:      testLogger(),
+		taskService: tasksvc.NewService(nil),
+	}
+
+	router := gin.New()
+	registerRoutes(t, l, router)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Limit is required", `{"status": "failed"}`},
+		{"Selection is required", `{"limit": 100}`},
+		{"IDs and filter are exclusive", `{"ids": ["a"], "tag": "billing", "limit": 100}`},
+	}
+	for _, path := range []string{"/api/v1/tasks/bulk/delete", "/api/v1/tasks/bulk/retry"} {
+		for _, tt := range tests {
+			t.Run(path+"/"+tt.name, func(t *testing.T) {
+				req, _ := http.NewRequest("POST", path, bytes.NewReader([]byte(tt.body)))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			})
+		}
+	}
+}
+
+// taskMapRepository serves a fixed set of tasks; lookups of brokenTaskID fail like a lost connection
+type taskMapRepository struct {
+	repository.TaskRepository
+	tasks map[string]*entity.Task
+}
+
+const brokenTaskID = "00000000-0000-0000-0000-00000000dead"
+
+func (r *taskMapRepository) FindByID(ctx context.Context, id string) (*entity.Task, error) {
+	if id == brokenTaskID {
+		return nil, fmt.Errorf("failed to find task: %w", errors.New("connection refused"))
+	}
+	task, ok := r.tasks[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	found := *task
+	return &found, nil
+}
+
+func (r *taskMapRepository) Create(ctx context.Context, task *entity.Task) error {
+	if _, ok := r.tasks[task.ID]; ok {
+		return fmt.Errorf("%w: %s", domain.ErrConflict, task.ID)
+	}
+	stored := *task
+	r.tasks[task.ID] = &stored
+	return nil
+}
+
+func (r *taskMapRepository) UpdateIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	stored, ok := r.tasks[task.ID]
+	if !ok || !slices.Contains(from, stored.Status) {
+		return false, nil
+	}
+	updated := *task
+	r.tasks[task.ID] = &updated
+	return true, nil
+}
+
+func TestRetryTaskHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	repo := memory.NewTaskRepository()
+	const deadID = "00000000-0000-0000-0000-000000000003"
+	assert.NoError(t, repo.Create(ctx, &entity.Task{ID: deadID, Status: entity.TaskStatusDeadLettered, RetryCount: 5, MaxRetries: 5}))
+	l, err := New(WithTaskRepository(repo), WithLogger(testLogger()))
+	assert.NoError(t, err)
+	router := gin.New()
+	registerRoutes(t, l, router)
+
+	w := httptest.NewRecorder()
+	body := `{"scheduled_for":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `","max_retries":2}`
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/tasks/"+deadID+"/retry", strings.NewReader(body)))
+	assert.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	stored, err := repo.FindByID(ctx, deadID)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusPending, stored.Status)
+	assert.Equal(t, 2, stored.MaxRetries)
+	assert.Zero(t, stored.RetryCount)
+
+	// The task is pending now; resurrect behaves like retry and refuses it
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/tasks/"+deadID+"/resurrect", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_status")
+}
+
+// TestTaskDetailsRedactPayload tests that task details and retries hide payloads from keys
+// without payload access, as listings do
+func TestTaskDetailsRedactPayload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	repo := memory.NewTaskRepository()
+	const pendingID, failedID = "00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002"
+	for id, status := range map[string]entity.TaskStatus{pendingID: entity.TaskStatusPending, failedID: entity.TaskStatusDeadLettered} {
+		task := entity.NewTask("send_email", []byte(`{"ssn":"123-45-6789"}`), "https://example.com/callback", time.Now(), 3)
+		task.ID, task.Status = id, status
+		assert.NoError(t, repo.Create(ctx, task))
+	}
+	l, err := New(WithTaskRepository(repo), WithLogger(testLogger()),
+		WithAPIKeys([]string{"plain-key", "payload-key"}), WithPayloadAPIKeys([]string{"payload-key"}))
+	assert.NoError(t, err)
+	handler := l.Handler()
+
+	serve := func(method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodGet, "/api/v1/tasks/"+pendingID, "plain-key")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "123-45-6789")
+	assert.Contains(t, w.Body.String(), `"payload_redacted":true`)
+
+	w = serve(http.MethodGet, "/api/v1/tasks/"+pendingID, "payload-key")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "123-45-6789")
+
+	w = serve(http.MethodPost, "/api/v1/tasks/"+failedID+"/retry", "plain-key")
+	assert.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "123-45-6789")
+	assert.Contains(t, w.Body.String(), `"payload_redacted":true`)
+}
+
+// TestTaskResponsesMatchREST tests that the embedded task routes answer with the REST server's
+// task responses, fields and all
+func TestTaskResponsesMatchREST(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	l, err := New(WithTaskRepository(memory.NewTaskRepository()), WithLogger(testLogger()))
+	assert.NoError(t, err)
+	handler := l.Handler()
+
+	body := `{"name":"sync_order","payload":{"order":1},"callback_url":"https://example.com/callback","concurrency_key":"erp"}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+	assert.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var created dto.TaskResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.EstimatedExecution)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+created.ID, nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var fetched dto.TaskResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &fetched))
+	if assert.NotNil(t, fetched.ConcurrencyKey) {
+		assert.Equal(t, "erp", *fetched.ConcurrencyKey)
+	}
+	assert.JSONEq(t, `{"order":1}`, fetched.Payload)
+}
+
+// TestHandler tests serving the routes from a net/http mux, with the same responses as Gin
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	repo := memory.NewTaskRepository()
+	const taskID = "00000000-0000-0000-0000-000000000004"
+	assert.NoError(t, repo.Create(ctx, &entity.Task{ID: taskID, Name: "send_email", Status: entity.TaskStatusPending}))
+	l, err := New(WithTaskRepository(repo), WithLogger(testLogger()), WithRoutePrefix("/internal/tasks"))
+	assert.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.Handle("/internal/tasks/", l.Handler())
+	mux.HandleFunc("/app", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) })
+	router := gin.New()
+	registerRoutes(t, l, router)
+
+	for _, tt := range []struct {
+		name, method, path, body string
+		status                   int
+	}{
+		{"Liveness", "GET", "/internal/tasks/healthz", "", http.StatusOK},
+		{"Get task", "GET", "/internal/tasks/tasks/" + taskID, "", http.StatusOK},
+		{"Missing task", "GET", "/internal/tasks/tasks/" + uuid.NewString(), "", http.StatusNotFound},
+		{"Invalid task", "POST", "/internal/tasks/tasks", `{"name":"send_email"}`, http.StatusBadRequest},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			serve := func(handler http.Handler) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set(middleware.RequestIDHeader, "req-1")
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				return w
+			}
+			w := serve(mux)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Equal(t, "req-1", w.Header().Get(middleware.RequestIDHeader))
+			if tt.name != "Liveness" { // Carries the time of the request
+				assert.JSONEq(t, serve(router).Body.String(), w.Body.String())
+			}
+		})
+	}
+
+	// The host's own routes are untouched
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/app", nil))
+	assert.Equal(t, http.StatusTeapot, w.Code)
+}
+
+// TestListTasksHandler tests that the embedded list parses its query like the REST server
+func TestListTasksHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	repo := memory.NewTaskRepository()
+	now := time.Now()
+	for i, priority := range []int{1, 3, 2} {
+		task := entity.NewTask("send_email", nil, "https://example.com/callback", now, 3)
+		task.ID = fmt.Sprintf("00000000-0000-0000-0000-00000000010%d", i)
+		task.Priority = priority
+		task.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+		assert.NoError(t, repo.Create(ctx, task))
+	}
+	l, err := New(WithTaskRepository(repo), WithLogger(testLogger()))
+	assert.NoError(t, err)
+	handler := l.Handler()
+
+	list := func(query string) (int, []int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks"+query, nil))
+		var body struct {
+			Tasks []struct {
+				Priority int `json:"priority"`
+			} `json:"tasks"`
+			Pagination map[string]interface{} `json:"pagination"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		var priorities []int
+		for _, task := range body.Tasks {
+			priorities = append(priorities, task.Priority)
+		}
+		return w.Code, priorities, body.Pagination
+	}
+
+	code, priorities, pagination := list("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{2, 3, 1}, priorities, "newest first by default")
+	assert.EqualValues(t, 10, pagination["limit"])
+
+	code, priorities, _ = list("?sort_by=priority&sort_order=asc&limit=2")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{1, 2}, priorities)
+
+	// Unknown sort columns fall back to the default instead of reaching the repository
+	code, priorities, _ = list("?sort_by=" + url.QueryEscape("(SELECT SLEEP(5))") + "&sort_order=" + url.QueryEscape("DESC; DROP TABLE task_queue"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{2, 3, 1}, priorities)
+
+	for _, query := range []string{"?page=0", "?limit=500", "?status=pending&scheduled_from=tomorrow"} {
+		code, _, _ = list(query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+// TestTaskErrorResponses tests the status and error code of each failure mode
+func TestTaskErrorResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const pendingID, completedID = "00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002"
+	repo := &taskMapRepository{tasks: map[string]*entity.Task{
+		pendingID:   {ID: pendingID, Status: entity.TaskStatusPending},
+		completedID: {ID: completedID, Status: entity.TaskStatusCompleted},
+	}}
+	l := newTaskAPITestLater(repo)
+	l.config.RoutePrefix = "/api/v1"
+	router := gin.New()
+	registerRoutes(t, l, router)
+
+	create := func(id string) string {
+		return `{"id":"` + id + `","name":"send_email","payload":{},"callback_url":"https://example.com/callback"}`
+	}
+	tests := []struct {
+		name, method, path, body string
+		status                   int
+		code                     string
+	}{
+		{"Get missing task", "GET", "/api/v1/tasks/" + uuid.NewString(), "", http.StatusNotFound, "task_not_found"},
+		{"Get with failing database", "GET", "/api/v1/tasks/" + brokenTaskID, "", http.StatusInternalServerError, "internal_error"},
+		{"Delete missing task", "DELETE", "/api/v1/tasks/" + uuid.NewString(), "", http.StatusNotFound, "task_not_found"},
+		{"Delete completed task", "DELETE", "/api/v1/tasks/" + completedID, "", http.StatusBadRequest, "invalid_status"},
+		{"Retry pending task", "POST", "/api/v1/tasks/" + pendingID + "/retry", "", http.StatusBadRequest, "invalid_status"},
+		{"Retry with failing database", "POST", "/api/v1/tasks/" + brokenTaskID + "/retry", "", http.StatusInternalServerError, "internal_error"},
+		{"Resurrect missing task", "POST", "/api/v1/tasks/" + uuid.NewString() + "/resurrect", "", http.StatusNotFound, "task_not_found"},
+		{"Create with taken ID", "POST", "/api/v1/tasks", create(pendingID), http.StatusConflict, "conflict"},
+		{"Create with invalid ID", "POST", "/api/v1/tasks", create("evt-1"), http.StatusBadRequest, "validation_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			var body map[string]string
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body["error"])
+			assert.NotContains(t, body["message"], "connection refused", "internal errors aren't exposed")
+		})
+	}
+}
@@ -2,78 +2,164 @@ package later
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/delivery/websocket"
+	"github.com/usual2970/later/domain"
 	"github.com/usual2970/later/domain/entity"
 	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/logger"
+	"github.com/usual2970/later/infrastructure/worker"
 	tasksvc "github.com/usual2970/later/task"
 )
 
 // CreateTask creates a new task
+// Returns an error wrapping domain.ErrQueueFull while the backlog limit is reached, or
+// domain.ErrConflict when req.ID is already taken
 func (l *Later) CreateTask(ctx context.Context, req *CreateTaskRequest) (*entity.Task, error) {
+	task, err := l.newTask(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := l.createTask(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// ValidateTask runs a request through CreateTask's checks without creating the task, and
+// returns the task CreateTask would make, defaults applied
+// Returns the errors CreateTask would, including domain.ErrConflict when req.ID is taken
+func (l *Later) ValidateTask(ctx context.Context, req *CreateTaskRequest) (*entity.Task, error) {
+	task, err := l.newTask(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.taskService.ValidateTask(ctx, task); err != nil {
+		return nil, fmt.Errorf("invalid task: %w", err)
+	}
+	return task, nil
+}
+
+// newTask validates a request and builds the task it describes
+func (l *Later) newTask(req *CreateTaskRequest) (*entity.Task, error) {
 	if req == nil {
 		return nil, fmt.Errorf("request cannot be nil")
 	}
 
-	task := &entity.Task{
-		ID:          uuid.New().String(),
-		Name:        req.Name,
-		Payload:     entity.JSONBytes(req.Payload),
-		CallbackURL: req.CallbackURL,
-		ScheduledAt: req.ScheduledAt,
-		Priority:    req.Priority,
-		MaxRetries:  req.MaxRetries,
-		Tags:        req.Tags,
-		Status:      entity.TaskStatusPending,
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
 
+	scheduledAt := req.ScheduledAt
+	if scheduledAt.IsZero() {
+		scheduledAt = time.Now()
+	}
+
+	// Start from NewTask's defaults so unset fields get the same values as REST-created tasks
+	task := entity.NewTask(req.Name, req.Payload, req.CallbackURL, scheduledAt, req.Priority)
+	if req.ID != "" {
+		if err := l.taskService.ValidateID(req.ID); err != nil {
+			return nil, err
+		}
+		task.ID = req.ID
+	}
+	task.MaxRetries = req.MaxRetries
+	if req.TimeoutSeconds != 0 {
+		task.CallbackTimeoutSecs = req.TimeoutSeconds
+	}
+	if req.RetryBackoffSeconds != 0 {
+		task.RetryBackoffSeconds = req.RetryBackoffSeconds
+	}
+	task.Tags = req.Tags
+	task.CallbackOAuth2 = req.CallbackOAuth2
+	task.RetryableStatusCodes = req.RetryableStatusCodes
+	task.FollowRedirects = req.FollowRedirects
+	task.CallbackBodyTemplate = req.CallbackBodyTemplate
+	task.ConcurrencyKey = req.ConcurrencyKey
+	task.DependsOn = req.DependsOn
+	task.DependencyFailurePolicy = req.DependencyFailurePolicy
+	task.ExpiresAt = req.ExpiresAt
+	return task, nil
+}
+
+// createTask stores a task built by CreateTask or the create handler and schedules it,
+// returning how it will be dispatched
+func (l *Later) createTask(ctx context.Context, task *entity.Task) (tasksvc.Dispatch, error) {
 	if err := l.taskService.CreateTask(ctx, task); err != nil {
 		l.logger.Error("Failed to create task",
-			zap.String("task_name", req.Name),
+			zap.String("task_name", task.Name),
 			zap.Error(err),
 		)
-		return nil, fmt.Errorf("failed to create task: %w", err)
+		return "", fmt.Errorf("failed to create task: %w", err)
 	}
 
 	l.logger.Info("Task created",
 		zap.String("task_id", task.ID),
+		logger.RequestID(task.RequestID),
 		zap.String("task_name", task.Name),
 		zap.Time("scheduled_at", task.ScheduledAt),
 	)
+	l.broadcast(websocket.EventTaskCreated, task)
 
-	// Submit immediately if due now
-	if task.ShouldExecuteNow() {
-		l.scheduler.SubmitTaskImmediately(task)
-		l.logger.Debug("Task submitted for immediate execution",
-			zap.String("task_id", task.ID),
-		)
-	}
-
-	return task, nil
+	// Submit now if due, or hold in the delay queue if due shortly
+	return l.scheduler.ScheduleTask(task), nil
 }
 
 // GetTask retrieves a task by ID
+// Returns an error wrapping domain.ErrNotFound when no task has the ID; other errors mean the
+// lookup itself failed
 func (l *Later) GetTask(ctx context.Context, id string) (*entity.Task, error) {
 	if id == "" {
-		return nil, fmt.Errorf("task ID cannot be empty")
+		return nil, fmt.Errorf("%w: task ID cannot be empty", domain.ErrBadParamInput)
 	}
 
 	task, err := l.taskService.GetTask(ctx, id)
 	if err != nil {
-		l.logger.Error("Failed to get task",
-			zap.String("task_id", id),
-			zap.Error(err),
-		)
+		if !errors.Is(err, domain.ErrNotFound) {
+			l.logger.Error("Failed to get task",
+				zap.String("task_id", id),
+				zap.Error(err),
+			)
+		}
 		return nil, err
 	}
 
 	return task, nil
 }
 
+// GetTaskChildren returns the tasks that depend on a task, oldest first
+func (l *Later) GetTaskChildren(ctx context.Context, id string) ([]*entity.Task, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: task ID cannot be empty", domain.ErrBadParamInput)
+	}
+	return l.taskService.GetChildren(ctx, id)
+}
+
+// GetTaskTimeline returns the events recorded for a task, oldest first, with the status each
+// moved it from and to; events are only recorded while WebSocket events are enabled
+func (l *Later) GetTaskTimeline(ctx context.Context, id string) ([]*entity.TaskEvent, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: task ID cannot be empty", domain.ErrBadParamInput)
+	}
+	return l.taskService.GetTaskTimeline(ctx, id)
+}
+
+// GetRetrySchedule projects when a task's remaining retries run if its attempts keep failing,
+// under the policy set with WithRetryPolicy
+func (l *Later) GetRetrySchedule(ctx context.Context, id string) (*tasksvc.RetrySchedule, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: task ID cannot be empty", domain.ErrBadParamInput)
+	}
+	return l.taskService.GetRetrySchedule(ctx, id)
+}
+
 // ListTasks lists tasks with pagination and filters
 func (l *Later) ListTasks(ctx context.Context, filter *TaskFilter) ([]*entity.Task, int64, error) {
 	if filter == nil {
@@ -100,9 +186,11 @@ func (l *Later) ListTasks(ctx context.Context, filter *TaskFilter) ([]*entity.Ta
 }
 
 // DeleteTask soft-deletes a task
+// Returns an error wrapping domain.ErrNotFound for a missing task, or domain.ErrTaskCannotDelete
+// unless the task is pending, waiting or failed
 func (l *Later) DeleteTask(ctx context.Context, id, deletedBy string) error {
 	if id == "" {
-		return fmt.Errorf("task ID cannot be empty")
+		return fmt.Errorf("%w: task ID cannot be empty", domain.ErrBadParamInput)
 	}
 
 	if err := l.taskService.DeleteTask(ctx, id, deletedBy); err != nil {
@@ -113,6 +201,7 @@ func (l *Later) DeleteTask(ctx context.Context, id, deletedBy string) error {
 		)
 		return err
 	}
+	l.scheduler.ForgetTask(id)
 
 	l.logger.Info("Task deleted",
 		zap.String("task_id", id),
@@ -122,48 +211,156 @@ func (l *Later) DeleteTask(ctx context.Context, id, deletedBy string) error {
 	return nil
 }
 
-// RetryTask resets a failed task for retry
+// RetryTask resets a failed or dead-lettered task to pending with its retries cleared
+// Returns an error wrapping domain.ErrNotFound for a missing task, or domain.ErrTaskCannotRetry
+// for tasks in other statuses
 func (l *Later) RetryTask(ctx context.Context, id string) (*entity.Task, error) {
+	return l.RetryTaskWithOptions(ctx, id, tasksvc.RetryOptions{})
+}
+
+// RetryTaskWithOptions is RetryTask with a delayed restart or a new retry budget
+func (l *Later) RetryTaskWithOptions(ctx context.Context, id string, opts tasksvc.RetryOptions) (*entity.Task, error) {
+	task, _, err := l.retryTask(ctx, id, opts)
+	return task, err
+}
+
+// retryTask resets a failed or dead-lettered task for retry and schedules it, returning how it
+// will be dispatched
+func (l *Later) retryTask(ctx context.Context, id string, opts tasksvc.RetryOptions) (*entity.Task, tasksvc.Dispatch, error) {
 	if id == "" {
-		return nil, fmt.Errorf("task ID cannot be empty")
+		return nil, "", fmt.Errorf("%w: task ID cannot be empty", domain.ErrBadParamInput)
 	}
 
-	task, err := l.taskService.GetTask(ctx, id)
+	task, err := l.taskService.RetryTask(ctx, id, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	l.logger.Info("Task retried",
+		zap.String("task_id", id),
+		logger.RequestID(task.RequestID),
+	)
+	l.broadcast(websocket.EventTaskUpdated, task)
+
+	// Submit now if due, or hold in the delay queue if due shortly
+	return task, l.scheduler.ScheduleTask(task), nil
+}
+
+// SetTaskTags replaces a task's tags, in any status; an empty list removes them all
+// Returns an error wrapping domain.ErrNotFound for a missing task, or domain.ErrBadParamInput for
+// invalid tags: see entity.ValidateTag
+func (l *Later) SetTaskTags(ctx context.Context, id string, tags []string) (*entity.Task, error) {
+	task, err := l.taskService.SetTags(ctx, id, tags)
 	if err != nil {
 		return nil, err
 	}
+	l.broadcast(websocket.EventTaskUpdated, task)
+	return task, nil
+}
 
-	if task.Status != entity.TaskStatusFailed {
-		return nil, fmt.Errorf("can only retry failed tasks, current status: %s", task.Status)
+// EditTaskTags adds and removes some of a task's tags, keeping the others; a tag in both lists
+// is removed. It returns the same errors as SetTaskTags
+func (l *Later) EditTaskTags(ctx context.Context, id string, add, remove []string) (*entity.Task, error) {
+	task, err := l.taskService.EditTags(ctx, id, add, remove)
+	if err != nil {
+		return nil, err
 	}
+	l.broadcast(websocket.EventTaskUpdated, task)
+	return task, nil
+}
 
-	task.Status = entity.TaskStatusPending
-	task.RetryCount = 0
-	task.NextRetryAt = nil
+// ExecuteTaskNow runs a pending or failed task's callback inline, bypassing the worker queue and
+// concurrency limits, and returns the delivery outcome with the task as persisted afterwards
+// The task is claimed first, so it is never delivered twice when the scheduler dispatches it too;
+// tasks already being processed fail with worker.ErrAlreadyClaimed and tasks in other statuses
+// with worker.ErrNotExecutable. A failed delivery isn't an error: see Execution.Err
+func (l *Later) ExecuteTaskNow(ctx context.Context, id string) (*worker.Execution, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: task ID cannot be empty", domain.ErrBadParamInput)
+	}
 
-	if err := l.taskService.UpdateTask(ctx, task); err != nil {
-		l.logger.Error("Failed to retry task",
-			zap.String("task_id", id),
-			zap.Error(err),
-		)
+	execution, err := l.executor.Execute(ctx, id)
+	if err != nil {
 		return nil, err
 	}
+	l.scheduler.ForgetTask(id)
+	return execution, nil
+}
 
-	l.logger.Info("Task retried",
+// AbortTask cancels the in-flight callback of a task processed by this instance, so a request
+// stuck on an unresponsive host fails at once; the task is then failed for a retry as usual, or
+// dead-lettered when terminal is set
+// Returns an error wrapping domain.ErrNotFound for a missing task, or worker.ErrNotInFlight
+// unless this instance is delivering the task's callback
+func (l *Later) AbortTask(ctx context.Context, id string, terminal bool) error {
+	if id == "" {
+		return fmt.Errorf("%w: task ID cannot be empty", domain.ErrBadParamInput)
+	}
+
+	// Looked up first so tasks of other tenants can't be aborted
+	if _, err := l.taskService.GetTask(ctx, id); err != nil {
+		return err
+	}
+	if err := l.executor.Abort(id, terminal); err != nil {
+		return err
+	}
+
+	l.logger.Info("Task aborted",
 		zap.String("task_id", id),
+		zap.Bool("terminal", terminal),
 	)
+	return nil
+}
 
-	// Submit immediately if due now
-	if task.ShouldExecuteNow() {
-		l.scheduler.SubmitTaskImmediately(task)
+// BulkDeleteTasks soft deletes the pending, waiting and failed tasks selected by ID or by filter,
+// at most sel.Limit of them; with sel.DryRun set it only counts them
+func (l *Later) BulkDeleteTasks(ctx context.Context, sel tasksvc.BulkSelector, deletedBy string) (*tasksvc.BulkResult, error) {
+	result, err := l.taskService.BulkDelete(ctx, sel, deletedBy)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range result.IDs {
+		l.scheduler.ForgetTask(id)
 	}
 
-	return task, nil
+	if !result.DryRun {
+		l.logger.Info("Tasks bulk deleted",
+			zap.Int64("count", result.Count),
+			zap.String("deleted_by", deletedBy),
+		)
+		l.broadcastBulk(ctx, websocket.EventTasksBulkDeleted, result.Count, "")
+	}
+	return result, nil
+}
+
+// BulkRetryTasks resets the failed tasks selected by ID or by filter for retry, at most
+// sel.Limit of them; with sel.DryRun set it only counts them
+func (l *Later) BulkRetryTasks(ctx context.Context, sel tasksvc.BulkSelector) (*tasksvc.BulkResult, error) {
+	result, err := l.taskService.BulkRetry(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.DryRun {
+		l.logger.Info("Tasks bulk retried",
+			zap.Int64("count", result.Count),
+		)
+		l.broadcastBulk(ctx, websocket.EventTasksBulkRetried, result.Count, entity.TaskStatusPending)
+
+		// Retried tasks are due now; poll for them instead of waiting for the next tick
+		l.scheduler.Wake()
+	}
+	return result, nil
 }
 
-// GetStats returns task statistics
+// GetStats returns task statistics with activity for the last 24 hours
 func (l *Later) GetStats(ctx context.Context) (*tasksvc.Stats, error) {
-	stats, err := l.taskService.GetStats(ctx)
+	return l.GetStatsForWindow(ctx, tasksvc.DefaultStatsWindow)
+}
+
+// GetStatsForWindow returns task statistics with activity for the given window ("1h", "24h" or "7d")
+func (l *Later) GetStatsForWindow(ctx context.Context, window string) (*tasksvc.Stats, error) {
+	stats, err := l.taskService.GetStatsForWindow(ctx, window)
 	if err != nil {
 		l.logger.Error("Failed to get stats",
 			zap.Error(err),
@@ -174,6 +371,91 @@ func (l *Later) GetStats(ctx context.Context) (*tasksvc.Stats, error) {
 	return stats, nil
 }
 
+// GetTimeSeries returns task activity over a window ("1h", "24h" or "7d") in buckets of the given size (e.g. "1h")
+func (l *Later) GetTimeSeries(ctx context.Context, window string, bucket string) (*tasksvc.TimeSeries, error) {
+	series, err := l.taskService.GetTimeSeries(ctx, window, bucket)
+	if err != nil {
+		l.logger.Error("Failed to get time series",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// GetStatsByName returns task counts per task name for the tasks created within a window
+// ("1h", "24h" or "7d"), at most tasksvc.MaxStatsGroups names, largest first
+func (l *Later) GetStatsByName(ctx context.Context, window string) (*tasksvc.GroupedStats, error) {
+	stats, err := l.taskService.GetStatsByName(ctx, window)
+	if err != nil {
+		l.logger.Error("Failed to get stats by name",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// GetStatsByTag returns task counts per tag for the tasks created within a window ("1h", "24h"
+// or "7d"), at most tasksvc.MaxStatsGroups tags, largest first
+func (l *Later) GetStatsByTag(ctx context.Context, window string) (*tasksvc.GroupedStats, error) {
+	stats, err := l.taskService.GetStatsByTag(ctx, window)
+	if err != nil {
+		l.logger.Error("Failed to get stats by tag",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// TestCallback delivers a sample payload to callbackURL right away, signed like a real callback,
+// so receivers can check their handling before going live; see callback.Service.TestDelivery
+// A delivery the receiver rejects isn't an error: the result reports how a task would be treated
+func (l *Later) TestCallback(ctx context.Context, callbackURL string, payload []byte) (*callback.TestResult, error) {
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+	task := entity.NewTask(dto.DefaultTestCallbackName, payload, callbackURL, time.Now(), 0)
+	return l.callbackService.TestDelivery(ctx, task)
+}
+
+// ConcurrencyLimits returns the current per-key concurrency limits
+func (l *Later) ConcurrencyLimits() map[string]int {
+	return l.limiter.Limits()
+}
+
+// SetConcurrencyLimit caps the tasks in flight for key, starting any waiting tasks the new
+// limit admits; lowering a limit lets running tasks finish
+func (l *Later) SetConcurrencyLimit(key string, limit int) error {
+	return l.limiter.SetLimit(key, limit)
+}
+
+// RemoveConcurrencyLimit makes key unbounded and starts its waiting tasks
+func (l *Later) RemoveConcurrencyLimit(key string) {
+	l.limiter.RemoveLimit(key)
+}
+
+// PauseHost holds the callbacks of tasks whose callback URL is on host until the given time or,
+// with a nil until, until ResumeHost; held tasks run afterwards without intervention. See
+// tasksvc.Service.PauseHost
+func (l *Later) PauseHost(ctx context.Context, host string, until *time.Time) (*entity.HostPause, error) {
+	return l.taskService.PauseHost(ctx, host, until)
+}
+
+// ResumeHost ends the host's pause; it returns domain.ErrNotFound if the host isn't paused
+func (l *Later) ResumeHost(ctx context.Context, host string) error {
+	return l.taskService.ResumeHost(ctx, host)
+}
+
+// PausedHosts returns the hosts paused now, by host, with the tasks each pause holds
+func (l *Later) PausedHosts(ctx context.Context) ([]*tasksvc.HostPauseStatus, error) {
+	return l.taskService.PausedHosts(ctx)
+}
+
 // GetMetrics returns real-time metrics
 // Note: This is a simplified version using available APIs
 // In the future, we can add more detailed metrics
@@ -188,6 +470,32 @@ func (l *Later) GetMetrics() Metrics {
 
 	if health.Workers != nil {
 		metrics.ActiveWorkers = health.Workers.Active
+		metrics.WorkerQueueDepth = health.Workers.Queued
+		metrics.SubmitRejected = health.Workers.SubmitRejected
+	}
+	if l.createLimiter != nil {
+		metrics.CreateRateLimited = l.createLimiter.Rejected()
+	}
+	if health.Backlog != nil {
+		metrics.MaxPendingTasks = health.Backlog.MaxPending
+	}
+	if l.scheduler != nil {
+		metrics.SaturatedPolls = l.scheduler.SaturatedPolls()
+		cleanup := l.scheduler.CleanupCounts()
+		metrics.ArchivedTasks = cleanup.Archived
+		metrics.DeletedTasks = cleanup.Deleted
+		metrics.ArchiveFailures = cleanup.ArchiveFailures
+	}
+	if l.hub != nil {
+		metrics.WebSocketClients = l.hub.ClientCount()
+		metrics.WebSocketRejected = l.hub.RejectedCount()
+	}
+	if pool := l.dbPoolStatus(); pool != nil {
+		metrics.DBOpenConnections = pool.Open
+		metrics.DBInUseConnections = pool.InUse
+		metrics.DBIdleConnections = pool.Idle
+		metrics.DBWaitCount = pool.WaitCount
+		metrics.DBWaitDurationMs = pool.WaitDurationMs
 	}
 
 	// Try to get stats for success rate
@@ -202,6 +510,10 @@ func (l *Later) GetMetrics() Metrics {
 
 // CreateTaskRequest represents a request to create a task
 type CreateTaskRequest struct {
+	// ID is assigned when empty; otherwise it must have the format set with WithTaskIDFormat,
+	// and creating a task under a taken ID fails with an error wrapping domain.ErrConflict
+	ID string `json:"id"`
+
 	Name        string    `json:"name"`
 	Payload     []byte    `json:"payload"`
 	CallbackURL string    `json:"callback_url"`
@@ -209,27 +521,99 @@ type CreateTaskRequest struct {
 	Priority    int       `json:"priority"`
 	MaxRetries  int       `json:"max_retries"`
 	Tags        []string  `json:"tags"`
+
+	// TimeoutSeconds bounds each callback attempt (5-300, default 30)
+	// The configured callback client timeout remains an upper bound
+	TimeoutSeconds int `json:"timeout_seconds"`
+
+	// RetryBackoffSeconds delays the first retry; the delay doubles with each retry
+	// (1-86400, default 60)
+	RetryBackoffSeconds int `json:"retry_backoff_seconds"`
+
+	// RetryableStatusCodes overrides the instance's retryable response codes for this task
+	RetryableStatusCodes []int `json:"retryable_status_codes"`
+
+	// FollowRedirects lets the callback follow up to callback.MaxRedirects redirects, even when
+	// the instance follows none
+	FollowRedirects bool `json:"follow_redirects"`
+
+	// CallbackBodyTemplate renders the callback body with text/template, e.g.
+	// {"event": {{.Payload}}, "task_id": {{json .ID}}}; see callback.TemplateData
+	CallbackBodyTemplate *string `json:"callback_body_template"`
+
+	// CallbackOAuth2 overrides the instance's OAuth2 client-credentials settings for this task
+	CallbackOAuth2 *entity.OAuth2Config `json:"callback_oauth2"`
+
+	// ConcurrencyKey selects the concurrency limit applied to the callback; defaults to the first tag
+	ConcurrencyKey *string `json:"concurrency_key"`
+
+	// DependsOn holds the task back in the waiting status until the parent task completes
+	DependsOn *string `json:"depends_on"`
+
+	// DependencyFailurePolicy decides what happens if the parent is dead-lettered, expired or
+	// cancelled: entity.DependencyFailureDeadLetter (default) or entity.DependencyFailureRunAnyway
+	DependencyFailurePolicy entity.DependencyFailurePolicy `json:"dependency_failure_policy"`
+
+	// ExpiresAt is when the task goes stale: picked up any later, it is expired instead of delivered
+	// nil uses the scheduled time plus the default TTL (see WithDefaultTaskTTL), if one is set
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Validate checks the request with the same rules as the REST API's create request
+// Errors wrap domain.ErrBadParamInput
+func (r *CreateTaskRequest) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrBadParamInput)
+	}
+	if r.CallbackURL == "" {
+		return fmt.Errorf("%w: callback_url is required", domain.ErrBadParamInput)
+	}
+
+	shared := dto.CreateTaskRequest{
+		MaxRetries: &r.MaxRetries,
+		Priority:   r.Priority,
+	}
+	if r.TimeoutSeconds != 0 {
+		shared.TimeoutSeconds = &r.TimeoutSeconds
+	}
+	if r.RetryBackoffSeconds != 0 {
+		shared.RetryBackoffSeconds = &r.RetryBackoffSeconds
+	}
+	if !r.ScheduledAt.IsZero() {
+		shared.ScheduledFor = &dto.CustomTime{Time: r.ScheduledAt}
+	}
+	if err := shared.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrBadParamInput, err)
+	}
+	return nil
 }
 
 // TaskFilter represents filters for listing tasks
 type TaskFilter struct {
-	Status        string     `json:"status"`
-	Priority      *int       `json:"priority"`
-	CreatedAfter  *time.Time `json:"created_after"`
-	CreatedBefore *time.Time `json:"created_before"`
-	Page          int        `json:"page"`
-	Limit         int        `json:"limit"`
-	SortBy        string     `json:"sort_by"`
-	SortOrder     string     `json:"sort_order"`
+	Status          string     `json:"status"`
+	Priority        *int       `json:"priority"`
+	Name            string     `json:"name"`
+	NamePrefix      string     `json:"name_prefix"`
+	CreatedAfter    *time.Time `json:"created_after"`
+	CreatedBefore   *time.Time `json:"created_before"`
+	ScheduledAfter  *time.Time `json:"scheduled_after"`
+	ScheduledBefore *time.Time `json:"scheduled_before"`
+	Page            int        `json:"page"`
+	Limit           int        `json:"limit"`
+	SortBy          string     `json:"sort_by"`
+	SortOrder       string     `json:"sort_order"`
+	TenantID        string     `json:"tenant_id"` // Only narrows results; the context tenant always applies
 }
 
 // toRepositoryFilter converts TaskFilter to repository.TaskFilter
 func (f *TaskFilter) toRepositoryFilter() repository.TaskFilter {
 	repoFilter := repository.TaskFilter{
-		Page:      f.Page,
-		Limit:     f.Limit,
-		SortBy:    f.SortBy,
-		SortOrder: f.SortOrder,
+		Name:       f.Name,
+		NamePrefix: f.NamePrefix,
+		Page:       f.Page,
+		Limit:      f.Limit,
+		SortBy:     f.SortBy,
+		SortOrder:  f.SortOrder,
 	}
 
 	// Convert status string to TaskStatus pointer
@@ -244,6 +628,13 @@ func (f *TaskFilter) toRepositoryFilter() repository.TaskFilter {
 	// Set date filters (map created_after/before to date_from/date_to)
 	repoFilter.DateFrom = f.CreatedAfter
 	repoFilter.DateTo = f.CreatedBefore
+	repoFilter.ScheduledFrom = f.ScheduledAfter
+	repoFilter.ScheduledTo = f.ScheduledBefore
+
+	if f.TenantID != "" {
+		tenantID := f.TenantID
+		repoFilter.TenantID = &tenantID
+	}
 
 	return repoFilter
 }
@@ -253,4 +644,24 @@ type Metrics struct {
 	QueueDepth          int64   `json:"queue_depth"`
 	ActiveWorkers       int     `json:"active_workers"`
 	CallbackSuccessRate float64 `json:"callback_success_rate"`
+	WorkerQueueDepth    int     `json:"worker_queue_depth"`        // Submitted tasks waiting for a worker
+	SubmitRejected      int64   `json:"submit_rejected_total"`     // Tasks refused by a full worker queue, left to polling
+	CreateRateLimited   int64   `json:"create_rate_limited_total"` // Task creation requests refused by the rate limit
+	MaxPendingTasks     int64   `json:"max_pending_tasks"`         // Backlog limit; zero when new tasks are never refused
+	SaturatedPolls      int64   `json:"saturated_polls_total"`     // Scheduler polls skipped or shortened by a full worker queue
+	WebSocketClients    int     `json:"websocket_clients"`         // Connected event stream clients
+	WebSocketRejected   int64   `json:"websocket_rejected_total"`  // Event stream connections refused over the client limit
+
+	// Cleanup job totals: tasks written to the archive sink, expired tasks deleted, and batches
+	// kept because the sink failed
+	ArchivedTasks   int64 `json:"archived_tasks_total"`
+	DeletedTasks    int64 `json:"deleted_tasks_total"`
+	ArchiveFailures int64 `json:"archive_failures_total"`
+
+	// Database connection pool usage, including the application's connections with a shared database
+	DBOpenConnections  int   `json:"db_open_connections"`
+	DBInUseConnections int   `json:"db_in_use_connections"`
+	DBIdleConnections  int   `json:"db_idle_connections"`
+	DBWaitCount        int64 `json:"db_wait_count_total"`       // Waits for a free connection
+	DBWaitDurationMs   int64 `json:"db_wait_duration_ms_total"` // Time spent waiting for one
 }
@@ -0,0 +1,117 @@
+package later
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// capturingRepository records the tasks the SDK persists
+type capturingRepository struct {
+	repository.TaskRepository
+	created []*entity.Task
+}
+
+func (r *capturingRepository) Create(ctx context.Context, task *entity.Task) error {
+	stored := *task
+	r.created = append(r.created, &stored)
+	return nil
+}
+
+// idlePool accepts submitted tasks without running them
+type idlePool struct{}
+
+func (idlePool) Start(workerCount int)             {}
+func (idlePool) SubmitTask(task *entity.Task) bool { return true }
+func (idlePool) Stop(ctx context.Context) int      { return 0 }
+
+func newTaskAPITestLater(repo repository.TaskRepository) *Later {
+	return &Later{
+		config:      &Config{},
+		logger:      testLogger(),
+		taskService: tasksvc.NewService(repo),
+		scheduler: tasksvc.NewScheduler(repo, idlePool{}, tasksvc.SchedulerConfig{
+			HighPriorityInterval:   time.Hour,
+			NormalPriorityInterval: time.Hour,
+			CleanupInterval:        time.Hour,
+		}),
+	}
+}
+
+func TestCreateTaskAppliesDefaults(t *testing.T) {
+	repo := &capturingRepository{}
+	l := newTaskAPITestLater(repo)
+
+	_, err := l.CreateTask(context.Background(), &CreateTaskRequest{
+		Name:        "send_email",
+		Payload:     []byte(`{"to":"user@example.com"}`),
+		CallbackURL: "https://example.com/callback",
+		MaxRetries:  3,
+	})
+	require.NoError(t, err)
+	require.Len(t, repo.created, 1)
+
+	stored := repo.created[0]
+	assert.Equal(t, entity.DefaultCallbackTimeoutSecs, stored.CallbackTimeoutSecs)
+	assert.Equal(t, entity.DefaultRetryBackoffSecs, stored.RetryBackoffSeconds)
+	assert.Equal(t, 3, stored.MaxRetries)
+	assert.False(t, stored.ScheduledAt.IsZero())
+	assert.False(t, stored.CreatedAt.IsZero())
+	now := time.Now()
+	assert.True(t, stored.CalculateNextRetry(now).After(now.Add(30*time.Second)), "retries back off")
+
+	_, err = l.CreateTask(context.Background(), &CreateTaskRequest{
+		Name:                "send_email",
+		Payload:             []byte(`{}`),
+		CallbackURL:         "https://example.com/callback",
+		TimeoutSeconds:      10,
+		RetryBackoffSeconds: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, repo.created, 2)
+	assert.Equal(t, 10, repo.created[1].CallbackTimeoutSecs)
+	assert.Equal(t, 5, repo.created[1].RetryBackoffSeconds)
+}
+
+func TestCreateTaskValidation(t *testing.T) {
+	repo := &capturingRepository{}
+	l := newTaskAPITestLater(repo)
+
+	valid := func() *CreateTaskRequest {
+		return &CreateTaskRequest{Name: "send_email", Payload: []byte(`{}`), CallbackURL: "https://example.com/callback"}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(req *CreateTaskRequest)
+	}{
+		{"Missing name", func(req *CreateTaskRequest) { req.Name = "" }},
+		{"Missing callback URL", func(req *CreateTaskRequest) { req.CallbackURL = "" }},
+		{"Timeout below range", func(req *CreateTaskRequest) { req.TimeoutSeconds = 1 }},
+		{"Timeout above range", func(req *CreateTaskRequest) { req.TimeoutSeconds = 301 }},
+		{"Negative backoff", func(req *CreateTaskRequest) { req.RetryBackoffSeconds = -1 }},
+		{"Backoff above range", func(req *CreateTaskRequest) { req.RetryBackoffSeconds = entity.MaxRetryBackoffSecs + 1 }},
+		{"Too many retries", func(req *CreateTaskRequest) { req.MaxRetries = 21 }},
+		{"Priority above range", func(req *CreateTaskRequest) { req.Priority = 11 }},
+		{"Scheduled beyond a year", func(req *CreateTaskRequest) { req.ScheduledAt = time.Now().AddDate(2, 0, 0) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(req)
+			_, err := l.CreateTask(context.Background(), req)
+			assert.True(t, errors.Is(err, domain.ErrBadParamInput), "got %v", err)
+		})
+	}
+	assert.Empty(t, repo.created)
+}
@@ -0,0 +1,107 @@
+package later
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/delivery/rest"
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// TestCreateTaskValidationParity sends the same invalid requests to the REST server's handler
+// and the embedded routes and expects the same rejection from both
+func TestCreateTaskValidationParity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Invalid requests never reach the repository
+	svc := tasksvc.NewService(nil, tasksvc.WithMaxPayloadSize(64))
+
+	restRouter := gin.New()
+	restRouter.POST("/api/v1/tasks", rest.NewHandler(svc, nil, nil, nil).CreateTask)
+
+	embedded := &Later{
+		config:      &Config{RoutePrefix: "/api/v1"},
+		logger:      testLogger(),
+		taskService: svc,
+	}
+	embeddedRouter := gin.New()
+	_, err := embedded.RegisterRoutes(embeddedRouter)
+	require.NoError(t, err)
+
+	valid := map[string]interface{}{
+		"name":         "send_email",
+		"payload":      map[string]string{"to": "user@example.com"},
+		"callback_url": "https://example.com/callback",
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		req := make(map[string]interface{}, len(valid)+1)
+		for k, v := range valid {
+			req[k] = v
+		}
+		if value == nil {
+			delete(req, key)
+		} else {
+			req[key] = value
+		}
+		return req
+	}
+	nextYear := time.Now().AddDate(2, 0, 0)
+
+	tests := []struct {
+		name     string
+		request  map[string]interface{}
+		wantCode string
+	}{
+		{"Missing name", with("name", nil), "invalid_request"},
+		{"Missing callback_url", with("callback_url", nil), "invalid_request"},
+		{"Malformed callback_url", with("callback_url", "not a url"), "invalid_request"},
+		{"Missing payload", with("payload", nil), "invalid_request"},
+		{"Unparseable scheduled_for", with("scheduled_for", "next tuesday"), "invalid_request"},
+		{"Priority above range", with("priority", 11), "validation_error"},
+		{"Negative priority", with("priority", -1), "validation_error"},
+		{"Timeout below range", with("timeout_seconds", 1), "validation_error"},
+		{"Timeout above range", with("timeout_seconds", 301), "validation_error"},
+		{"Too many retries", with("max_retries", 21), "validation_error"},
+		{"Scheduled beyond a year (RFC3339)", with("scheduled_for", nextYear.Format(time.RFC3339)), "validation_error"},
+		{"Scheduled beyond a year (space format)", with("scheduled_for", nextYear.Format("2006-01-02 15:04:05")), "validation_error"},
+		{"Scheduled beyond a year (scheduled_at)", with("scheduled_at", nextYear.Format(time.RFC3339)), "validation_error"},
+		{"Payload too large", with("payload", map[string]string{"blob": strings.Repeat("x", 100)}), "validation_error"},
+	}
+
+	send := func(router *gin.Engine, body []byte) (int, string) {
+		req, _ := http.NewRequest("POST", "/api/v1/tasks", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var resp struct {
+			Error string `json:"error"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Error
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.request)
+			require.NoError(t, err)
+
+			restStatus, restCode := send(restRouter, body)
+			embeddedStatus, embeddedCode := send(embeddedRouter, body)
+
+			assert.Equal(t, http.StatusBadRequest, restStatus)
+			assert.Equal(t, tt.wantCode, restCode)
+			assert.Equal(t, restStatus, embeddedStatus, "embedded status differs from REST")
+			assert.Equal(t, restCode, embeddedCode, "embedded error code differs from REST")
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package later
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+)
+
+// defaultWaitPollInterval is how often WaitForTask re-reads a task it hasn't heard about
+const defaultWaitPollInterval = time.Second
+
+// WaitForTask blocks until the task is completed, dead-lettered or expired and returns its final state
+// Updates from this instance's workers resolve the wait immediately; tasks finished by another
+// replica, or moved to the dead letter queue by the scheduler, are noticed by polling the
+// database every WaitPollInterval
+// Returns ctx.Err() if the context ends first, so bound the wait with a deadline
+func (l *Later) WaitForTask(ctx context.Context, id string) (*entity.Task, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: task ID cannot be empty", domain.ErrBadParamInput)
+	}
+
+	// Subscribe before the first read so an update landing in between isn't missed
+	updates, unsubscribe := l.waiters.subscribe(id)
+	defer unsubscribe()
+
+	task, err := l.taskService.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if isTerminal(task.Status) {
+		return task, nil
+	}
+
+	interval := l.config.WaitPollInterval
+	if interval <= 0 {
+		interval = defaultWaitPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case task := <-updates:
+			return task, nil
+		case <-ticker.C:
+			task, err := l.taskService.GetTask(ctx, id)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return nil, err
+			}
+			if isTerminal(task.Status) {
+				return task, nil
+			}
+		}
+	}
+}
+
+// isTerminal reports whether a task will not run again without a retry or resurrect
+func isTerminal(status entity.TaskStatus) bool {
+	return status == entity.TaskStatusCompleted || status == entity.TaskStatusDeadLettered ||
+		status == entity.TaskStatusExpired
+}
+
+// taskWaiters hands terminal task updates from the worker pool to WaitForTask callers
+type taskWaiters struct {
+	mu      sync.Mutex
+	waiters map[string]map[chan *entity.Task]struct{}
+}
+
+func newTaskWaiters() *taskWaiters {
+	return &taskWaiters{waiters: make(map[string]map[chan *entity.Task]struct{})}
+}
+
+// subscribe returns a channel receiving the task once it reaches a terminal status
+// Call unsubscribe when done waiting
+func (w *taskWaiters) subscribe(id string) (updates <-chan *entity.Task, unsubscribe func()) {
+	ch := make(chan *entity.Task, 1)
+
+	w.mu.Lock()
+	if w.waiters[id] == nil {
+		w.waiters[id] = make(map[chan *entity.Task]struct{})
+	}
+	w.waiters[id][ch] = struct{}{}
+	w.mu.Unlock()
+
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.waiters[id], ch)
+		if len(w.waiters[id]) == 0 {
+			delete(w.waiters, id)
+		}
+	}
+}
+
+// BroadcastTaskUpdate wakes the task's waiters if it has reached a terminal status
+func (w *taskWaiters) BroadcastTaskUpdate(task *entity.Task) {
+	if !isTerminal(task.Status) {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.waiters[task.ID] {
+		// Each waiter gets its own snapshot, as the worker keeps using the task
+		snapshot := *task
+		select {
+		case ch <- &snapshot:
+		default:
+		}
+	}
+}
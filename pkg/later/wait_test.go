@@ -0,0 +1,114 @@
+package later
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+)
+
+// statusRepository serves one task whose status the test changes
+type statusRepository struct {
+	repository.TaskRepository
+	mu    sync.Mutex
+	task  entity.Task
+	reads int
+}
+
+func (r *statusRepository) FindByID(ctx context.Context, id string) (*entity.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reads++
+	if id != r.task.ID {
+		return nil, domain.ErrNotFound
+	}
+	task := r.task
+	return &task, nil
+}
+
+func (r *statusRepository) setStatus(status entity.TaskStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.task.Status = status
+}
+
+func newWaitTestLater(status entity.TaskStatus, pollInterval time.Duration) (*Later, *statusRepository) {
+	repo := &statusRepository{task: entity.Task{ID: "task-1", Status: status}}
+	l := newTaskAPITestLater(repo)
+	l.config.WaitPollInterval = pollInterval
+	l.waiters = newTaskWaiters()
+	return l, repo
+}
+
+func TestWaitForTaskAlreadyTerminal(t *testing.T) {
+	l, repo := newWaitTestLater(entity.TaskStatusDeadLettered, time.Hour)
+
+	task, err := l.WaitForTask(context.Background(), "task-1")
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusDeadLettered, task.Status)
+	assert.Equal(t, 1, repo.reads)
+	assert.Empty(t, l.waiters.waiters, "the waiter is removed")
+
+	_, err = l.WaitForTask(context.Background(), "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestWaitForTaskResolvesOnWorkerUpdate(t *testing.T) {
+	l, repo := newWaitTestLater(entity.TaskStatusProcessing, time.Hour)
+
+	done := make(chan *entity.Task)
+	go func() {
+		task, err := l.WaitForTask(context.Background(), "task-1")
+		assert.NoError(t, err)
+		done <- task
+	}()
+
+	// Wait for the subscription, then report progress and completion as the worker pool would
+	require.Eventually(t, func() bool {
+		l.waiters.mu.Lock()
+		defer l.waiters.mu.Unlock()
+		return len(l.waiters.waiters["task-1"]) == 1
+	}, time.Second, time.Millisecond)
+	l.waiters.BroadcastTaskUpdate(&entity.Task{ID: "task-1", Status: entity.TaskStatusFailed})
+	l.waiters.BroadcastTaskUpdate(&entity.Task{ID: "other", Status: entity.TaskStatusCompleted})
+	l.waiters.BroadcastTaskUpdate(&entity.Task{ID: "task-1", Status: entity.TaskStatusCompleted})
+
+	select {
+	case task := <-done:
+		assert.Equal(t, entity.TaskStatusCompleted, task.Status)
+	case <-time.After(time.Second):
+		t.Fatal("WaitForTask did not return after the completion event")
+	}
+	assert.Equal(t, 1, repo.reads, "no polling needed")
+}
+
+func TestWaitForTaskPolls(t *testing.T) {
+	l, repo := newWaitTestLater(entity.TaskStatusPending, time.Millisecond)
+
+	// Finished elsewhere, so no event arrives
+	time.AfterFunc(20*time.Millisecond, func() { repo.setStatus(entity.TaskStatusCompleted) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	task, err := l.WaitForTask(ctx, "task-1")
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusCompleted, task.Status)
+}
+
+func TestWaitForTaskTimeout(t *testing.T) {
+	l, _ := newWaitTestLater(entity.TaskStatusPending, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	task, err := l.WaitForTask(ctx, "task-1")
+	assert.Nil(t, task)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, l.waiters.waiters)
+}
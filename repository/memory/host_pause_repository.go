@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+)
+
+// hostPauseRepository implements repository.HostPauseRepository in memory
+type hostPauseRepository struct {
+	mu     sync.RWMutex
+	pauses map[string]entity.HostPause
+}
+
+// NewHostPauseRepository creates an empty in-memory host pause repository for tests and examples
+// Pauses are lost when the process exits
+func NewHostPauseRepository() repository.HostPauseRepository {
+	return &hostPauseRepository{pauses: make(map[string]entity.HostPause)}
+}
+
+func (r *hostPauseRepository) Pause(ctx context.Context, pause *entity.HostPause) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pauses[pause.Host] = copyHostPause(pause)
+	return nil
+}
+
+func (r *hostPauseRepository) Resume(ctx context.Context, host string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pauses[host]; !ok {
+		return fmt.Errorf("%w: host %s is not paused", domain.ErrNotFound, host)
+	}
+	delete(r.pauses, host)
+	return nil
+}
+
+func (r *hostPauseRepository) List(ctx context.Context) ([]*entity.HostPause, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pauses := make([]*entity.HostPause, 0, len(r.pauses))
+	for _, pause := range r.pauses {
+		stored := copyHostPause(&pause)
+		pauses = append(pauses, &stored)
+	}
+	slices.SortFunc(pauses, func(a, b *entity.HostPause) int {
+		return strings.Compare(a.Host, b.Host)
+	})
+	return pauses, nil
+}
+
+// copyHostPause copies a pause, so the stored one only changes through the repository
+func copyHostPause(pause *entity.HostPause) entity.HostPause {
+	copied := *pause
+	if pause.Until != nil {
+		until := *pause.Until
+		copied.Until = &until
+	}
+	return copied
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+)
+
+// taskEventRepository implements repository.TaskEventRepository in memory
+type taskEventRepository struct {
+	mu     sync.RWMutex
+	events []*entity.TaskEvent // In ID order
+	lastID int64
+}
+
+// NewTaskEventRepository creates an empty in-memory task event repository for tests and examples
+// Events are lost when the process exits
+func NewTaskEventRepository() repository.TaskEventRepository {
+	return &taskEventRepository{}
+}
+
+func (r *taskEventRepository) Append(ctx context.Context, event *entity.TaskEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.FromStatus = ""
+	if event.TaskID != "" {
+		for i := len(r.events) - 1; i >= 0; i-- {
+			if r.events[i].TaskID == event.TaskID {
+				event.FromStatus = r.events[i].ToStatus
+				break
+			}
+		}
+	}
+	r.lastID++
+	event.ID = r.lastID
+
+	stored := *event
+	stored.Metadata = slices.Clone(event.Metadata)
+	r.events = append(r.events, &stored)
+	return nil
+}
+
+func (r *taskEventRepository) ListByTask(ctx context.Context, taskID string, limit int) ([]*entity.TaskEvent, error) {
+	if taskID == "" {
+		return nil, nil
+	}
+	return r.list(limit, func(event *entity.TaskEvent) bool {
+		return event.TaskID == taskID
+	}), nil
+}
+
+func (r *taskEventRepository) ListByTasks(ctx context.Context, taskIDs []string) ([]*entity.TaskEvent, error) {
+	ids := make(map[string]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		ids[id] = id != ""
+	}
+	return r.list(math.MaxInt, func(event *entity.TaskEvent) bool {
+		return ids[event.TaskID]
+	}), nil
+}
+
+func (r *taskEventRepository) ListSince(ctx context.Context, afterID int64, tenantID string, limit int) ([]*entity.TaskEvent, error) {
+	return r.list(limit, func(event *entity.TaskEvent) bool {
+		return event.ID > afterID && (tenantID == "" || event.TenantID == tenantID)
+	}), nil
+}
+
+// list returns copies of up to limit events matching keep, oldest first
+func (r *taskEventRepository) list(limit int, keep func(*entity.TaskEvent) bool) []*entity.TaskEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []*entity.TaskEvent
+	for _, event := range r.events {
+		if len(events) >= limit {
+			break
+		}
+		if keep(event) {
+			copied := *event
+			copied.Metadata = slices.Clone(event.Metadata)
+			events = append(events, &copied)
+		}
+	}
+	return events
+}
+
+func (r *taskEventRepository) LastID(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastID, nil
+}
+
+func (r *taskEventRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.events[:0]
+	for _, event := range r.events {
+		if !event.CreatedAt.Before(cutoff) {
+			kept = append(kept, event)
+		}
+	}
+	deleted := int64(len(r.events) - len(kept))
+	clear(r.events[len(kept):])
+	r.events = kept
+	return deleted, nil
+}
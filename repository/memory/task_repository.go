@@ -0,0 +1,730 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/clock"
+)
+
+// taskRepository implements repository.TaskRepository in memory
+// It follows the MySQL repository's semantics, so code tested against it behaves the same in
+// production: soft-deleted tasks keep their IDs, tenant scoping from the context applies to
+// the same methods, and only the columns MySQL persists on create and update are stored
+type taskRepository struct {
+	clock clock.Clock
+
+	mu       sync.RWMutex
+	tasks    map[string]*entity.Task
+	archived []*entity.Task // Tasks copied aside by an archiving cleanup
+}
+
+// NewTaskRepository creates an empty in-memory task repository for tests and examples
+// Tasks are lost when the process exits
+func NewTaskRepository() repository.TaskRepository {
+	return NewTaskRepositoryWithClock(clock.Real)
+}
+
+// NewTaskRepositoryWithClock creates an in-memory task repository that decides which tasks
+// are due with the given clock, e.g. a clock.Fake
+func NewTaskRepositoryWithClock(c clock.Clock) repository.TaskRepository {
+	return &taskRepository{clock: c, tasks: make(map[string]*entity.Task)}
+}
+
+func (r *taskRepository) Create(ctx context.Context, task *entity.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tasks[task.ID]; ok {
+		return fmt.Errorf("%w: %s", domain.ErrConflict, task.ID)
+	}
+	r.tasks[task.ID] = created(task)
+	return nil
+}
+
+func (r *taskRepository) CreateBatch(ctx context.Context, tasks []*entity.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check every ID first, so either all of the tasks are created or none
+	seen := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		if _, ok := r.tasks[task.ID]; ok || seen[task.ID] {
+			return fmt.Errorf("%w: %s", domain.ErrConflict, task.ID)
+		}
+		seen[task.ID] = true
+	}
+	for _, task := range tasks {
+		r.tasks[task.ID] = created(task)
+	}
+	return nil
+}
+
+// created returns the stored copy of a new task: the fields MySQL inserts, with its defaults
+func created(task *entity.Task) *entity.Task {
+	stored := &entity.Task{
+		ID:                      task.ID,
+		Name:                    task.Name,
+		Payload:                 slices.Clone(task.Payload),
+		CallbackURL:             task.CallbackURL,
+		Status:                  task.Status,
+		CreatedAt:               task.CreatedAt,
+		ScheduledAt:             task.ScheduledAt,
+		MaxRetries:              task.MaxRetries,
+		RetryCount:              task.RetryCount,
+		RetryBackoffSeconds:     task.RetryBackoffSeconds,
+		CallbackTimeoutSecs:     task.CallbackTimeoutSecs,
+		Priority:                task.Priority,
+		Tags:                    slices.Clone(task.Tags),
+		TenantID:                task.TenantID,
+		CallbackOAuth2:          task.CallbackOAuth2,
+		RetryableStatusCodes:    slices.Clone(task.RetryableStatusCodes),
+		FollowRedirects:         task.FollowRedirects,
+		CallbackBodyTemplate:    task.CallbackBodyTemplate,
+		PayloadEncoding:         task.PayloadEncoding,
+		PayloadEncrypted:        task.PayloadEncrypted,
+		ConcurrencyKey:          task.ConcurrencyKey,
+		DependsOn:               task.DependsOn,
+		DependencyFailurePolicy: task.DependencyFailurePolicy,
+		RequestID:               task.RequestID,
+		ExpiresAt:               task.ExpiresAt,
+	}
+	if stored.PayloadEncoding == "" {
+		stored.PayloadEncoding = entity.PayloadEncodingJSON
+	}
+	if stored.DependencyFailurePolicy == "" {
+		stored.DependencyFailurePolicy = entity.DependencyFailureDeadLetter
+	}
+	return stored
+}
+
+// cloneTask returns a copy of a stored task that callers may modify freely
+func cloneTask(task *entity.Task) *entity.Task {
+	c := *task
+	c.Payload = slices.Clone(task.Payload)
+	c.Tags = slices.Clone(task.Tags)
+	c.ErrorHistory = slices.Clone(task.ErrorHistory)
+	c.RetryableStatusCodes = slices.Clone(task.RetryableStatusCodes)
+	return &c
+}
+
+func (r *taskRepository) ExistingIDs(ctx context.Context, ids []string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var existing []string
+	for _, id := range ids {
+		if _, ok := r.tasks[id]; ok {
+			existing = append(existing, id)
+		}
+	}
+	return existing, nil
+}
+
+func (r *taskRepository) FindByID(ctx context.Context, id string) (*entity.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	task, ok := r.tasks[id]
+	if !ok || task.DeletedAt != nil || !inTenant(ctx, task) {
+		return nil, domain.ErrNotFound
+	}
+	return cloneTask(task), nil
+}
+
+// FindDueTasks returns due pending tasks, most urgent first
+// Like the MySQL repository it doesn't reserve them: a task is claimed with UpdateIfStatus,
+// which is atomic, so concurrent pollers may see the same task but only one claims it
+func (r *taskRepository) FindDueTasks(ctx context.Context, minPriority int, limit int, skipGroups []string) ([]*entity.Task, error) {
+	now := r.clock.Now()
+	tasks := r.selectTasks(func(t *entity.Task) bool {
+		return t.Status == entity.TaskStatusPending && !t.ScheduledAt.After(now) && t.DeletedAt == nil &&
+			(minPriority == -1 || t.Priority > minPriority) && !slices.Contains(skipGroups, t.ConcurrencyGroup())
+	})
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Priority != tasks[j].Priority {
+			return tasks[i].Priority > tasks[j].Priority
+		}
+		return tasks[i].ScheduledAt.Before(tasks[j].ScheduledAt)
+	})
+	return limitTasks(tasks, limit), nil
+}
+
+func (r *taskRepository) FindPendingTasks(ctx context.Context, limit int) ([]*entity.Task, error) {
+	return r.FindDueTasks(ctx, -1, limit, nil)
+}
+
+func (r *taskRepository) FindFailedTasks(ctx context.Context, limit int) ([]*entity.Task, error) {
+	now := r.clock.Now()
+	tasks := r.selectTasks(func(t *entity.Task) bool {
+		return t.Status == entity.TaskStatusFailed && t.NextRetryAt != nil && !t.NextRetryAt.After(now) &&
+			t.DeletedAt == nil
+	})
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].NextRetryAt.Before(*tasks[j].NextRetryAt)
+	})
+	return limitTasks(tasks, limit), nil
+}
+
+func (r *taskRepository) FindUpcomingTasks(ctx context.Context, before time.Time, limit int) ([]*repository.ScheduledTask, error) {
+	now := r.clock.Now()
+	tasks := r.selectTasks(func(t *entity.Task) bool {
+		return t.Status == entity.TaskStatusPending && t.ScheduledAt.After(now) && !t.ScheduledAt.After(before) &&
+			t.DeletedAt == nil
+	})
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].ScheduledAt.Before(tasks[j].ScheduledAt)
+	})
+
+	var upcoming []*repository.ScheduledTask
+	for _, task := range limitTasks(tasks, limit) {
+		upcoming = append(upcoming, &repository.ScheduledTask{ID: task.ID, ScheduledAt: task.ScheduledAt})
+	}
+	return upcoming, nil
+}
+
+func (r *taskRepository) LatestCreatedID(ctx context.Context) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var latest *entity.Task
+	for _, task := range r.tasks {
+		if latest == nil || task.CreatedAt.After(latest.CreatedAt) ||
+			task.CreatedAt.Equal(latest.CreatedAt) && task.ID > latest.ID {
+			latest = task
+		}
+	}
+	if latest == nil {
+		return "", nil
+	}
+	return latest.ID, nil
+}
+
+func (r *taskRepository) Update(ctx context.Context, task *entity.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.tasks[task.ID]; ok && inTenant(ctx, stored) {
+		applyUpdate(stored, task)
+	}
+	return nil
+}
+
+func (r *taskRepository) UpdateIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.tasks[task.ID]
+	if !ok || stored.DeletedAt != nil || !inTenant(ctx, stored) || !slices.Contains(from, stored.Status) {
+		return false, nil
+	}
+	applyUpdate(stored, task)
+	return true, nil
+}
+
+// applyUpdate copies the task's mutable fields, those MySQL updates, to the stored task
+func applyUpdate(stored, task *entity.Task) {
+	stored.Status = task.Status
+	stored.ScheduledAt = task.ScheduledAt
+	stored.MaxRetries = task.MaxRetries
+	stored.StartedAt = task.StartedAt
+	stored.CompletedAt = task.CompletedAt
+	stored.RetryCount = task.RetryCount
+	stored.NextRetryAt = task.NextRetryAt
+	stored.CallbackAttempts = task.CallbackAttempts
+	stored.LastCallbackAt = task.LastCallbackAt
+	stored.LastCallbackStatus = task.LastCallbackStatus
+	stored.LastCallbackError = task.LastCallbackError
+	stored.LastCallbackResponse = task.LastCallbackResponse
+	stored.DispatchLatencyMs = task.DispatchLatencyMs
+	stored.CallbackDurationMs = task.CallbackDurationMs
+	stored.ErrorHistory = slices.Clone(task.ErrorHistory)
+	stored.ErrorMessage = task.ErrorMessage
+	stored.WorkerID = task.WorkerID
+}
+
+func (r *taskRepository) UpdateTags(ctx context.Context, taskID string, tags []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.tasks[taskID]; ok && stored.DeletedAt == nil && inTenant(ctx, stored) {
+		stored.Tags = slices.Clone(tags)
+	}
+	return nil
+}
+
+func (r *taskRepository) SoftDelete(ctx context.Context, taskID string, deletedBy string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	task, ok := r.tasks[taskID]
+	if !ok || task.DeletedAt != nil || !inTenant(ctx, task) {
+		// Task either doesn't exist or is already deleted
+		return fmt.Errorf("task not found or already deleted")
+	}
+	now := r.clock.Now().UTC()
+	task.DeletedAt = &now
+	task.DeletedBy = &deletedBy
+	return nil
+}
+
+func (r *taskRepository) CountBulk(ctx context.Context, filter repository.BulkFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	matched, err := r.bulkMatches(ctx, filter)
+	return int64(len(matched)), err
+}
+
+func (r *taskRepository) BulkSoftDelete(ctx context.Context, filter repository.BulkFilter, deletedBy string) ([]string, error) {
+	now := r.clock.Now().UTC()
+	return r.bulkUpdate(ctx, filter, func(task *entity.Task) {
+		task.DeletedAt = &now
+		task.DeletedBy = &deletedBy
+	})
+}
+
+func (r *taskRepository) BulkRetry(ctx context.Context, filter repository.BulkFilter) ([]string, error) {
+	return r.bulkUpdate(ctx, filter, func(task *entity.Task) {
+		task.Status = entity.TaskStatusPending
+		task.RetryCount = 0
+		task.NextRetryAt = nil
+	})
+}
+
+// bulkUpdate applies set to the tasks matching the filter under a single lock
+func (r *taskRepository) bulkUpdate(ctx context.Context, filter repository.BulkFilter, set func(*entity.Task)) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	matched, err := r.bulkMatches(ctx, filter)
+	if err != nil || len(matched) == 0 {
+		return nil, err
+	}
+	ids := make([]string, 0, len(matched))
+	for _, task := range matched {
+		set(task)
+		ids = append(ids, task.ID)
+	}
+	return ids, nil
+}
+
+// bulkMatches returns the live stored tasks matching a bulk filter, oldest first up to its
+// limit; callers hold r.mu
+func (r *taskRepository) bulkMatches(ctx context.Context, filter repository.BulkFilter) ([]*entity.Task, error) {
+	if len(filter.Statuses) == 0 || filter.Limit <= 0 {
+		return nil, fmt.Errorf("bulk filter needs statuses and a limit")
+	}
+
+	var matched []*entity.Task
+	for _, task := range r.tasks {
+		switch {
+		case task.DeletedAt != nil, !slices.Contains(filter.Statuses, task.Status), !inTenant(ctx, task):
+		case len(filter.IDs) > 0 && !slices.Contains(filter.IDs, task.ID):
+		case filter.Tag != "" && !slices.Contains(task.Tags, filter.Tag):
+		case filter.DateFrom != nil && task.CreatedAt.Before(*filter.DateFrom):
+		case filter.DateTo != nil && task.CreatedAt.After(*filter.DateTo):
+		default:
+			matched = append(matched, task)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID < matched[j].ID })
+	sortByCreated(matched, true)
+	return limitTasks(matched, filter.Limit), nil
+}
+
+func (r *taskRepository) List(ctx context.Context, filter repository.TaskFilter) ([]*entity.Task, int64, error) {
+	tasks := r.listTasks(ctx, filter)
+	total := int64(len(tasks))
+
+	offset := max((filter.Page-1)*filter.Limit, 0)
+	if offset >= len(tasks) || filter.Limit <= 0 {
+		return nil, total, nil
+	}
+	return limitTasks(tasks[offset:], filter.Limit), total, nil
+}
+
+// ListStream calls fn for each task matching the filter
+// A positive filter.Limit caps the number of tasks, and a Page above 1 skips the earlier pages
+func (r *taskRepository) ListStream(ctx context.Context, filter repository.TaskFilter, fn func(*entity.Task) error) error {
+	tasks := r.listTasks(ctx, filter)
+	if filter.Limit > 0 {
+		offset := min(max((filter.Page-1)*filter.Limit, 0), len(tasks))
+		tasks = limitTasks(tasks[offset:], filter.Limit)
+	}
+	for _, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listTasks returns copies of the live tasks matching a list filter, in the filter's order
+func (r *taskRepository) listTasks(ctx context.Context, filter repository.TaskFilter) []*entity.Task {
+	tasks := r.selectTasks(func(t *entity.Task) bool {
+		return t.DeletedAt == nil && inTenant(ctx, t) && matchesList(t, filter)
+	})
+
+	ascending := strings.EqualFold(filter.SortOrder, "asc")
+	switch filter.SortBy {
+	case "scheduled_at":
+		sort.SliceStable(tasks, func(i, j int) bool {
+			if ascending {
+				return tasks[i].ScheduledAt.Before(tasks[j].ScheduledAt)
+			}
+			return tasks[i].ScheduledAt.After(tasks[j].ScheduledAt)
+		})
+	case "priority":
+		sort.SliceStable(tasks, func(i, j int) bool {
+			if ascending {
+				return tasks[i].Priority < tasks[j].Priority
+			}
+			return tasks[i].Priority > tasks[j].Priority
+		})
+	case "created_at":
+		sortByCreated(tasks, ascending)
+	default:
+		sortByCreated(tasks, false)
+	}
+	return tasks
+}
+
+// matchesList reports whether a task matches the conditions of a list filter
+func matchesList(task *entity.Task, filter repository.TaskFilter) bool {
+	switch {
+	case filter.TenantID != nil && task.TenantID != *filter.TenantID:
+	case filter.Status != nil && task.Status != *filter.Status:
+	case filter.DependsOn != nil && (task.DependsOn == nil || *task.DependsOn != *filter.DependsOn):
+	case len(filter.ParentIDs) > 0 && (task.DependsOn == nil || !slices.Contains(filter.ParentIDs, *task.DependsOn)):
+	case filter.Priority != nil && task.Priority < *filter.Priority:
+	case len(filter.Tags) > 0 && !slices.Contains(task.Tags, filter.Tags[0]):
+	case filter.Name != "" && task.Name != filter.Name:
+	case filter.NamePrefix != "" && !strings.HasPrefix(task.Name, filter.NamePrefix):
+	case filter.DateFrom != nil && task.CreatedAt.Before(*filter.DateFrom):
+	case filter.DateTo != nil && task.CreatedAt.After(*filter.DateTo):
+	case filter.ScheduledFrom != nil && task.ScheduledAt.Before(*filter.ScheduledFrom):
+	case filter.ScheduledTo != nil && task.ScheduledAt.After(*filter.ScheduledTo):
+	default:
+		return true
+	}
+	return false
+}
+
+func (r *taskRepository) CountByStatus(ctx context.Context) (map[entity.TaskStatus]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make(map[entity.TaskStatus]int64)
+	for _, task := range r.tasks {
+		if task.DeletedAt == nil && inTenant(ctx, task) {
+			result[task.Status]++
+		}
+	}
+	return result, nil
+}
+
+func (r *taskRepository) StatsSummary(ctx context.Context, since time.Time) (*repository.StatsSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var summary repository.StatsSummary
+	var attempts, finished int
+	var completion, dispatch, callback []float64
+	for _, task := range r.tasks {
+		if task.DeletedAt != nil || !inTenant(ctx, task) {
+			continue
+		}
+		// Failed and dead-lettered tasks have no finish time, so they count by their last attempt
+		attempted := lastAttempt(task)
+		completedInWindow := task.Status == entity.TaskStatusCompleted && task.CompletedAt != nil && !task.CompletedAt.Before(since)
+
+		if !task.CreatedAt.Before(since) {
+			summary.Created++
+		}
+		switch {
+		case completedInWindow:
+			summary.Completed++
+			attempts += task.CallbackAttempts
+			finished++
+			completion = append(completion, milliseconds(task.CompletedAt.Sub(task.CreatedAt)))
+		case task.Status == entity.TaskStatusFailed && !attempted.Before(since):
+			summary.Failed++
+		case task.Status == entity.TaskStatusDeadLettered && !attempted.Before(since):
+			summary.DeadLettered++
+			attempts += task.CallbackAttempts
+			finished++
+		case task.Status == entity.TaskStatusExpired && task.CompletedAt != nil && !task.CompletedAt.Before(since):
+			summary.Expired++
+		}
+
+		if task.DispatchLatencyMs != nil && task.StartedAt != nil && !task.StartedAt.Before(since) {
+			dispatch = append(dispatch, float64(*task.DispatchLatencyMs))
+		}
+		if task.CallbackDurationMs != nil && task.LastCallbackAt != nil && !task.LastCallbackAt.Before(since) {
+			callback = append(callback, float64(*task.CallbackDurationMs))
+		}
+	}
+
+	if finished > 0 {
+		summary.AvgCallbackAttempts = float64(attempts) / float64(finished)
+	}
+	summary.P50CompletionLatencyMs, summary.P95CompletionLatencyMs = percentiles(completion)
+	summary.P50DispatchLatencyMs, summary.P95DispatchLatencyMs = percentiles(dispatch)
+	summary.P50CallbackDurationMs, summary.P95CallbackDurationMs = percentiles(callback)
+	return &summary, nil
+}
+
+// percentiles returns the nearest-rank p50 and p95 of the values, zero when there are none
+func percentiles(values []float64) (p50, p95 float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	slices.Sort(values)
+	rank := func(p float64) float64 {
+		return values[int(math.Ceil(p*float64(len(values))))-1]
+	}
+	return rank(0.50), rank(0.95)
+}
+
+func (r *taskRepository) CountByTimeBucket(ctx context.Context, since time.Time, bucket time.Duration) ([]*repository.TimeBucketCounts, error) {
+	bucketSeconds := int64(bucket / time.Second)
+	if bucketSeconds <= 0 {
+		return nil, fmt.Errorf("bucket must be at least one second")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Each event is bucketed by the time it happened
+	buckets := make(map[int64]*repository.TimeBucketCounts)
+	latencies := make(map[int64][]float64)
+	at := func(t time.Time) *repository.TimeBucketCounts {
+		index := t.Unix() / bucketSeconds
+		if t.Unix() < 0 && t.Unix()%bucketSeconds != 0 {
+			index-- // Floor, as MySQL does
+		}
+		counts, ok := buckets[index]
+		if !ok {
+			counts = &repository.TimeBucketCounts{Start: time.Unix(index*bucketSeconds, 0).UTC()}
+			buckets[index] = counts
+		}
+		return counts
+	}
+
+	for _, task := range r.tasks {
+		if task.DeletedAt != nil || !inTenant(ctx, task) {
+			continue
+		}
+		if !task.CreatedAt.Before(since) {
+			at(task.CreatedAt).Created++
+		}
+		if task.Status == entity.TaskStatusCompleted && task.CompletedAt != nil && !task.CompletedAt.Before(since) {
+			counts := at(*task.CompletedAt)
+			counts.Completed++
+			if task.StartedAt != nil && task.LastCallbackAt != nil {
+				index := counts.Start.Unix() / bucketSeconds
+				latencies[index] = append(latencies[index], milliseconds(task.LastCallbackAt.Sub(*task.StartedAt)))
+			}
+		}
+		if attempted := lastAttempt(task); !attempted.Before(since) {
+			switch task.Status {
+			case entity.TaskStatusFailed:
+				at(attempted).Failed++
+			case entity.TaskStatusDeadLettered:
+				at(attempted).DeadLettered++
+			}
+		}
+	}
+
+	result := make([]*repository.TimeBucketCounts, 0, len(buckets))
+	for index, counts := range buckets {
+		if values := latencies[index]; len(values) > 0 {
+			var sum float64
+			for _, v := range values {
+				sum += v
+			}
+			counts.AvgCallbackLatencyMs = sum / float64(len(values))
+		}
+		result = append(result, counts)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Start.Before(result[j].Start) })
+	return result, nil
+}
+
+func (r *taskRepository) CountByCallbackHost(ctx context.Context, hosts []string) (map[string]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make(map[string]int64)
+	for _, task := range r.tasks {
+		if task.DeletedAt != nil || !inTenant(ctx, task) ||
+			(task.Status != entity.TaskStatusPending && task.Status != entity.TaskStatusFailed) {
+			continue
+		}
+		if host := entity.CallbackHost(task.CallbackURL); slices.Contains(hosts, host) {
+			result[host]++
+		}
+	}
+	return result, nil
+}
+
+func (r *taskRepository) StatsByGroup(ctx context.Context, group repository.StatsGroup, since time.Time, limit int) ([]*repository.GroupStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groups := make(map[string]*repository.GroupStats)
+	attempts := make(map[string][2]int) // Callback attempts and count of the finished tasks
+	for _, task := range r.tasks {
+		if task.DeletedAt != nil || !inTenant(ctx, task) || task.CreatedAt.Before(since) {
+			continue
+		}
+		var keys []string
+		switch group {
+		case repository.StatsGroupName:
+			keys = []string{task.Name}
+		case repository.StatsGroupTag:
+			keys = task.Tags
+		default:
+			return nil, fmt.Errorf("unsupported stats group %q", group)
+		}
+
+		for _, key := range keys {
+			stats, ok := groups[key]
+			if !ok {
+				stats = &repository.GroupStats{Key: key, ByStatus: make(map[entity.TaskStatus]int64)}
+				groups[key] = stats
+			}
+			stats.Total++
+			stats.ByStatus[task.Status]++
+			if task.Status == entity.TaskStatusCompleted || task.Status == entity.TaskStatusDeadLettered {
+				a := attempts[key]
+				attempts[key] = [2]int{a[0] + task.CallbackAttempts, a[1] + 1}
+			}
+		}
+	}
+
+	result := make([]*repository.GroupStats, 0, len(groups))
+	for key, stats := range groups {
+		if a := attempts[key]; a[1] > 0 {
+			stats.AvgCallbackAttempts = float64(a[0]) / float64(a[1])
+		}
+		result = append(result, stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Key < result[j].Key
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (r *taskRepository) CleanupExpiredData(ctx context.Context, policy repository.RetentionPolicy) (*repository.CleanupResult, error) {
+	return r.CleanupTasks(ctx, policy.Cleanup(r.clock.Now().UTC()))
+}
+
+func (r *taskRepository) CleanupTasks(ctx context.Context, cleanup repository.Cleanup) (*repository.CleanupResult, error) {
+	result := &repository.CleanupResult{ByStatus: make(map[entity.TaskStatus]int64)}
+
+	expired := r.selectTasks(func(task *entity.Task) bool {
+		cutoff, ok := cleanup.Cutoffs[task.Status]
+		if !ok || !slices.Contains(repository.CleanableStatuses, task.Status) {
+			return false
+		}
+		// Dead-lettered tasks have no completed_at, so fall back to their creation time
+		finished := task.CreatedAt
+		if task.CompletedAt != nil {
+			finished = *task.CompletedAt
+		}
+		return finished.Before(cutoff)
+	})
+	if cleanup.DryRun {
+		for _, task := range expired {
+			result.ByStatus[task.Status]++
+		}
+		return result, nil
+	}
+	if len(expired) == 0 {
+		return result, nil
+	}
+
+	// The expired tasks form a single batch
+	if cleanup.BeforeDelete != nil {
+		if err := cleanup.BeforeDelete(ctx, expired); err != nil {
+			return result, fmt.Errorf("failed to archive tasks before deleting them: %w", err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, task := range expired {
+		stored, ok := r.tasks[task.ID]
+		if !ok {
+			continue
+		}
+		if cleanup.Archive {
+			r.archived = append(r.archived, stored)
+			result.Archived++
+		}
+		delete(r.tasks, task.ID)
+		result.Deleted++
+		result.ByStatus[stored.Status]++
+	}
+	return result, nil
+}
+
+// selectTasks returns copies of the stored tasks matching the predicate, in no particular order
+func (r *taskRepository) selectTasks(match func(*entity.Task) bool) []*entity.Task {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var tasks []*entity.Task
+	for _, task := range r.tasks {
+		if match(task) {
+			tasks = append(tasks, cloneTask(task))
+		}
+	}
+	// A stable base order, so ties sort the same way every time
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks
+}
+
+// sortByCreated sorts tasks by creation time
+func sortByCreated(tasks []*entity.Task, ascending bool) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if ascending {
+			return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+		}
+		return tasks[i].CreatedAt.After(tasks[j].CreatedAt)
+	})
+}
+
+// limitTasks returns at most limit of the tasks
+func limitTasks(tasks []*entity.Task, limit int) []*entity.Task {
+	if limit >= 0 && len(tasks) > limit {
+		return tasks[:limit]
+	}
+	return tasks
+}
+
+// inTenant reports whether the task is visible to the context's tenant, if it is scoped to one
+func inTenant(ctx context.Context, task *entity.Task) bool {
+	tenantID, ok := domain.TenantFromContext(ctx)
+	return !ok || task.TenantID == tenantID
+}
+
+// lastAttempt returns when the task was last started, or created if it never was
+func lastAttempt(task *entity.Task) time.Time {
+	if task.StartedAt != nil {
+		return *task.StartedAt
+	}
+	return task.CreatedAt
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
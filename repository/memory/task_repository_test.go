@@ -0,0 +1,244 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/clock"
+	"github.com/usual2970/later/repository/repotest"
+)
+
+func newTask(name string, scheduledAt time.Time) *entity.Task {
+	return entity.NewTask(name, []byte(`{"k":"v"}`), "https://example.com/callback", scheduledAt, 0)
+}
+
+func TestTaskRepositoryConformance(t *testing.T) {
+	repotest.TestTaskRepository(t, func(t *testing.T) repository.TaskRepository {
+		return NewTaskRepository()
+	})
+}
+
+func TestTaskEventRepositoryConformance(t *testing.T) {
+	repotest.TestTaskEventRepository(t, func(t *testing.T) repository.TaskEventRepository {
+		return NewTaskEventRepository()
+	})
+}
+
+func TestHostPauseRepositoryConformance(t *testing.T) {
+	repotest.TestHostPauseRepository(t, func(t *testing.T) repository.HostPauseRepository {
+		return NewHostPauseRepository()
+	})
+}
+
+// TestTaskRepositoryCopies tests that stored tasks only change through the repository
+func TestTaskRepositoryCopies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTaskRepository()
+	task := newTask("crud", time.Now())
+	require.NoError(t, repo.Create(ctx, task))
+	assert.ErrorIs(t, repo.Create(ctx, task), domain.ErrConflict)
+
+	found, err := repo.FindByID(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.PayloadEncodingJSON, found.PayloadEncoding, "create applies the column defaults")
+
+	// Reads return copies, so only Update changes the stored task
+	found.Status = entity.TaskStatusFailed
+	found.Payload[0] = 'x'
+	found.RecordError(errors.New("connection refused"), time.Now())
+	stored, err := repo.FindByID(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusPending, stored.Status)
+	assert.JSONEq(t, `{"k":"v"}`, string(stored.Payload))
+
+	require.NoError(t, repo.Update(ctx, found))
+	stored, err = repo.FindByID(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusFailed, stored.Status)
+	assert.JSONEq(t, `{"k":"v"}`, string(stored.Payload), "the payload isn't updatable")
+	if assert.Len(t, stored.ErrorHistory, 1) {
+		assert.Equal(t, "connection refused", stored.ErrorHistory[0].Error)
+	}
+
+	require.NoError(t, repo.SoftDelete(ctx, task.ID, "test"))
+	assert.Error(t, repo.SoftDelete(ctx, task.ID, "test"), "already deleted")
+	_, err = repo.FindByID(ctx, task.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	existing, err := repo.ExistingIDs(ctx, []string{task.ID, "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{task.ID}, existing, "soft-deleted tasks still hold their IDs")
+}
+
+func TestTaskRepositoryFindDueTasks(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	repo := NewTaskRepositoryWithClock(clk)
+	now := clk.Now()
+
+	seed := func(name string, scheduledAt time.Time, priority int) *entity.Task {
+		task := newTask(name, scheduledAt)
+		task.Priority = priority
+		require.NoError(t, repo.Create(ctx, task))
+		return task
+	}
+	old := seed("old", now.Add(-time.Hour), 0)
+	recent := seed("recent", now.Add(-time.Minute), 0)
+	urgent := seed("urgent", now, 9)
+	future := seed("future", now.Add(time.Minute), 9)
+	deleted := seed("deleted", now.Add(-time.Hour), 9)
+	require.NoError(t, repo.SoftDelete(ctx, deleted.ID, "test"))
+
+	names := func(tasks []*entity.Task) []string {
+		var names []string
+		for _, task := range tasks {
+			names = append(names, task.Name)
+		}
+		return names
+	}
+	due, err := repo.FindDueTasks(ctx, -1, 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{urgent.Name, old.Name, recent.Name}, names(due), "most urgent first")
+
+	due, err = repo.FindDueTasks(ctx, 5, 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{urgent.Name}, names(due), "only priorities above the minimum")
+
+	due, err = repo.FindDueTasks(ctx, -1, 1, nil)
+	require.NoError(t, err)
+	assert.Len(t, due, 1, "capped at the limit")
+
+	upcoming, err := repo.FindUpcomingTasks(ctx, now.Add(time.Hour), 10)
+	require.NoError(t, err)
+	if assert.Len(t, upcoming, 1) {
+		assert.Equal(t, future.ID, upcoming[0].ID)
+	}
+
+	clk.Advance(time.Minute)
+	due, err = repo.FindDueTasks(ctx, -1, 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{urgent.Name, future.Name, old.Name, recent.Name}, names(due), "due once the clock passes it")
+}
+
+func TestTaskRepositoryBulk(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTaskRepository()
+	created := time.Now().Add(-time.Hour)
+	var ids []string
+	for i := 0; i < 5; i++ {
+		task := newTask("bulk", time.Now())
+		task.CreatedAt = created.Add(time.Duration(i) * time.Minute)
+		task.Status = entity.TaskStatusFailed
+		task.RetryCount = 3
+		task.Tags = []string{"bulk"}
+		require.NoError(t, repo.Create(ctx, task))
+		ids = append(ids, task.ID)
+	}
+
+	_, err := repo.CountBulk(ctx, repository.BulkFilter{Tag: "bulk", Limit: 3})
+	assert.Error(t, err, "statuses are required")
+
+	failed := repository.BulkFilter{Statuses: []entity.TaskStatus{entity.TaskStatusFailed}, Tag: "bulk", Limit: 3}
+	count, err := repo.CountBulk(ctx, failed)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count, "capped at the limit")
+
+	retried, err := repo.BulkRetry(ctx, failed)
+	require.NoError(t, err)
+	assert.Equal(t, ids[:3], retried, "oldest first")
+	task, err := repo.FindByID(ctx, retried[0])
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusPending, task.Status)
+	assert.Zero(t, task.RetryCount)
+
+	failed.Limit = 10
+	deleted, err := repo.BulkSoftDelete(ctx, failed, "test")
+	require.NoError(t, err)
+	assert.Equal(t, ids[3:], deleted, "only the tasks still failed")
+	count, err = repo.CountBulk(ctx, failed)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestTaskRepositoryTenantScoping(t *testing.T) {
+	repo := NewTaskRepository()
+	acme := domain.WithTenant(context.Background(), "acme")
+	globex := domain.WithTenant(context.Background(), "globex")
+	task := newTask("tenant", time.Now())
+	task.TenantID = "acme"
+	require.NoError(t, repo.Create(acme, task))
+
+	_, err := repo.FindByID(globex, task.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Error(t, repo.SoftDelete(globex, task.ID, "test"))
+	tasks, total, err := repo.List(globex, repository.TaskFilter{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+	assert.Zero(t, total)
+
+	counts, err := repo.CountByStatus(acme)
+	require.NoError(t, err)
+	assert.Equal(t, map[entity.TaskStatus]int64{entity.TaskStatusPending: 1}, counts)
+
+	// Unscoped callers see every tenant
+	_, err = repo.FindByID(context.Background(), task.ID)
+	assert.NoError(t, err)
+}
+
+func TestTaskRepositoryStatsSummary(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTaskRepository()
+	now := time.Now().UTC().Truncate(time.Second)
+	seed := func(status entity.TaskStatus, age, latency time.Duration, attempts int) {
+		created := now.Add(-age)
+		task := newTask("stats", created)
+		task.CreatedAt = created
+		require.NoError(t, repo.Create(ctx, task))
+
+		task.Status = status
+		task.StartedAt = &created
+		task.CallbackAttempts = attempts
+		if status == entity.TaskStatusCompleted {
+			completed := created.Add(latency)
+			task.CompletedAt = &completed
+			task.LastCallbackAt = &completed
+			dispatch, callback := latency.Milliseconds()/10, latency.Milliseconds()/100
+			task.DispatchLatencyMs, task.CallbackDurationMs = &dispatch, &callback
+		}
+		require.NoError(t, repo.Update(ctx, task))
+	}
+
+	// The same fixtures as the MySQL repository's test, which must give the same summary
+	for i := 1; i <= 10; i++ {
+		seed(entity.TaskStatusCompleted, 30*time.Minute, time.Duration(i)*time.Second, 1)
+	}
+	seed(entity.TaskStatusDeadLettered, 20*time.Minute, 0, 4)
+	seed(entity.TaskStatusDeadLettered, 20*time.Minute, 0, 4)
+	seed(entity.TaskStatusFailed, 10*time.Minute, 0, 2)
+	seed(entity.TaskStatusPending, time.Minute, 0, 0)
+	seed(entity.TaskStatusCompleted, 3*time.Hour, time.Hour, 9)
+	seed(entity.TaskStatusDeadLettered, 3*time.Hour, 0, 9)
+
+	summary, err := repo.StatsSummary(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, &repository.StatsSummary{
+		Created:                14,
+		Completed:              10,
+		Failed:                 1,
+		DeadLettered:           2,
+		AvgCallbackAttempts:    18.0 / 12,
+		P50CompletionLatencyMs: 5000,
+		P95CompletionLatencyMs: 10000,
+		P50DispatchLatencyMs:   500,
+		P95DispatchLatencyMs:   1000,
+		P50CallbackDurationMs:  50,
+		P95CallbackDurationMs:  100,
+	}, summary)
+}
@@ -0,0 +1,81 @@
+//go:build integration
+
+package mysql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/migrations"
+	"github.com/usual2970/later/repository/repotest"
+)
+
+// TestTaskRepositoryConformance runs the shared repository suite against the database in
+// LATER_TEST_MYSQL_DSN; see `make test-integration`
+// Each subtest migrates its own table prefix, so it starts from empty tables
+func TestTaskRepositoryConformance(t *testing.T) {
+	db := testDB(t)
+
+	repotest.TestTaskRepository(t, func(t *testing.T) repository.TaskRepository {
+		ctx := context.Background()
+		prefix := "it_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12] + "_"
+		migrator, err := NewMigrator(db, migrations.MySQL, prefix)
+		require.NoError(t, err)
+		_, err = migrator.Up(ctx)
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			for _, table := range []string{TaskArchiveTable, TaskQueueTable, SchedulerLockTable, TaskEventsTable, HostPausesTable, SchemaMigrationsTable} {
+				db.ExecContext(ctx, "DROP TABLE IF EXISTS "+prefix+table)
+			}
+		})
+		return NewTaskRepositoryWithPrefix(db, prefix)
+	})
+}
+
+// TestTaskEventRepositoryConformance runs the event repository suite against its own table prefix
+func TestTaskEventRepositoryConformance(t *testing.T) {
+	db := testDB(t)
+
+	repotest.TestTaskEventRepository(t, func(t *testing.T) repository.TaskEventRepository {
+		ctx := context.Background()
+		prefix := "it_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12] + "_"
+		migrator, err := NewMigrator(db, migrations.MySQL, prefix)
+		require.NoError(t, err)
+		_, err = migrator.Up(ctx)
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			for _, table := range []string{TaskArchiveTable, TaskQueueTable, SchedulerLockTable, TaskEventsTable, HostPausesTable, SchemaMigrationsTable} {
+				db.ExecContext(ctx, "DROP TABLE IF EXISTS "+prefix+table)
+			}
+		})
+		return NewTaskEventRepository(db, prefix)
+	})
+}
+
+// TestHostPauseRepositoryConformance runs the host pause repository suite against its own table prefix
+func TestHostPauseRepositoryConformance(t *testing.T) {
+	db := testDB(t)
+
+	repotest.TestHostPauseRepository(t, func(t *testing.T) repository.HostPauseRepository {
+		ctx := context.Background()
+		prefix := "it_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12] + "_"
+		migrator, err := NewMigrator(db, migrations.MySQL, prefix)
+		require.NoError(t, err)
+		_, err = migrator.Up(ctx)
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			for _, table := range []string{TaskArchiveTable, TaskQueueTable, SchedulerLockTable, TaskEventsTable, HostPausesTable, SchemaMigrationsTable} {
+				db.ExecContext(ctx, "DROP TABLE IF EXISTS "+prefix+table)
+			}
+		})
+		return NewHostPauseRepository(db, prefix)
+	})
+}
@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/infrastructure/logger"
+)
+
+// Option configures a MySQL repository
+type Option func(*options)
+
+type options struct {
+	log                *zap.Logger
+	slowQueryThreshold time.Duration
+	unprepared         bool
+}
+
+// WithLogger sets the logger for slow queries and migration progress; without it they go to the
+// global logger, named "db"
+func WithLogger(log *zap.Logger) Option {
+	return func(o *options) {
+		o.log = log
+	}
+}
+
+// applyOptions returns the options set by opts, with the default logger if none was given
+func applyOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.log == nil {
+		o.log = logger.Named("db")
+	}
+	return o
+}
+
+// conn is the part of *sqlx.DB the repositories use
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+}
+
+// newConn returns db, wrapped to run the prepared queries as prepared statements and to time
+// its statements as the options ask
+func newConn(db *sqlx.DB, opts []Option, prepared ...string) conn {
+	o := applyOptions(opts)
+	var c conn = db
+	if len(prepared) > 0 && !o.unprepared {
+		c = newPreparedConn(c, db, prepared)
+	}
+	if o.slowQueryThreshold <= 0 {
+		return c
+	}
+	return &timedConn{conn: c, threshold: o.slowQueryThreshold, log: o.log}
+}
@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+)
+
+// hostPauseRepository implements repository.HostPauseRepository with rows in host_pauses
+type hostPauseRepository struct {
+	db    conn
+	table string
+}
+
+// NewHostPauseRepository creates a MySQL host pause repository whose table is named with the
+// given prefix; the prefix must have been checked with ValidateTablePrefix
+func NewHostPauseRepository(db *sqlx.DB, prefix string, opts ...Option) repository.HostPauseRepository {
+	return &hostPauseRepository{db: newConn(db, opts), table: prefix + HostPausesTable}
+}
+
+func (r *hostPauseRepository) Pause(ctx context.Context, pause *entity.HostPause) error {
+	var until interface{}
+	if pause.Until != nil {
+		until = pause.Until.UTC()
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO `+r.table+` (host, paused_until, created_at) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE paused_until = VALUES(paused_until), created_at = VALUES(created_at)
+	`, pause.Host, until, pause.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to pause host: %w", err)
+	}
+	return nil
+}
+
+func (r *hostPauseRepository) Resume(ctx context.Context, host string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM `+r.table+` WHERE host = ?`, host)
+	if err != nil {
+		return fmt.Errorf("failed to resume host: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to resume host: %w", err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("%w: host %s is not paused", domain.ErrNotFound, host)
+	}
+	return nil
+}
+
+func (r *hostPauseRepository) List(ctx context.Context) ([]*entity.HostPause, error) {
+	var pauses []*entity.HostPause
+	err := r.db.SelectContext(ctx, &pauses, `
+		SELECT host, paused_until, created_at FROM `+r.table+` ORDER BY host`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list host pauses: %w", err)
+	}
+	return pauses, nil
+}
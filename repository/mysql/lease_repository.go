@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/usual2970/later/domain/repository"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// leaseRepository implements repository.LeaseRepository with rows in scheduler_lock
+// Expiry is computed with the database clock so replicas with skewed clocks agree
+type leaseRepository struct {
+	db    conn
+	table string
+}
+
+// NewLeaseRepository creates a MySQL lease repository whose table is named with the given prefix
+// The prefix must have been checked with ValidateTablePrefix
+func NewLeaseRepository(db *sqlx.DB, prefix string, opts ...Option) repository.LeaseRepository {
+	return &leaseRepository{db: newConn(db, opts), table: prefix + SchedulerLockTable}
+}
+
+func (r *leaseRepository) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	micros := ttl.Microseconds()
+
+	// Create the lease if nobody has taken it yet
+	_, err := r.db.ExecContext(ctx, `
+		INSERT IGNORE INTO `+r.table+` (name, holder, expires_at, acquired_at)
+		VALUES (?, ?, UTC_TIMESTAMP(3) + INTERVAL ? MICROSECOND, UTC_TIMESTAMP(3))
+	`, name, holder, micros)
+	if err != nil {
+		return false, fmt.Errorf("failed to create lease: %w", err)
+	}
+
+	// Renew it if we hold it, or take it over once the previous holder let it expire
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE `+r.table+`
+		SET acquired_at = IF(holder = ?, acquired_at, UTC_TIMESTAMP(3)),
+			holder = ?,
+			expires_at = UTC_TIMESTAMP(3) + INTERVAL ? MICROSECOND
+		WHERE name = ? AND (holder = ? OR expires_at <= UTC_TIMESTAMP(3))
+	`, holder, holder, micros, name, holder)
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+
+	// Rows affected can't tell a renewal apart from a no-op, so read the holder back
+	var current string
+	err = r.db.GetContext(ctx, &current, `SELECT holder FROM `+r.table+` WHERE name = ?`, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read lease: %w", err)
+	}
+	return current == holder, nil
+}
+
+func (r *leaseRepository) Release(ctx context.Context, name, holder string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM `+r.table+` WHERE name = ? AND holder = ?`, name, holder)
+	if err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/migrations"
+)
+
+// TestLeaseRepository runs against a real database when LATER_TEST_MYSQL_DSN is set
+func TestLeaseRepository(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	migrator, err := NewMigrator(db, migrations.MySQL, "")
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	leases := NewLeaseRepository(db, "")
+	name := "test-" + uuid.New().String()
+
+	acquired, err := leases.Acquire(ctx, name, "a", 200*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = leases.Acquire(ctx, name, "b", 200*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, acquired, "a held lease can't be taken")
+
+	acquired, err = leases.Acquire(ctx, name, "a", 200*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, acquired, "the holder renews its lease")
+
+	time.Sleep(250 * time.Millisecond)
+	acquired, err = leases.Acquire(ctx, name, "b", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired, "an expired lease is taken over")
+
+	require.NoError(t, leases.Release(ctx, name, "b"))
+	acquired, err = leases.Acquire(ctx, name, "a", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired, "a released lease is free at once")
+	require.NoError(t, leases.Release(ctx, name, "a"))
+}
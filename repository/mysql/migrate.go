@@ -0,0 +1,297 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// migrationLockName serializes migrations across instances starting at the same time
+const migrationLockName = "later_schema_migrations"
+
+// migrationLockTimeout is how long to wait for another instance's migrations, in seconds
+const migrationLockTimeout = 60
+
+// migrationFilePattern matches NNN_description_mysql.up.sql and .down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)_mysql\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string // Empty if the migration cannot be reverted
+}
+
+// LoadMigrations reads the migrations in fsys, sorted by version
+func LoadMigrations(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("conflicting migrations for version %d: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrator applies versioned migrations and records them in schema_migrations
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []*Migration
+	prefix     string
+	table      string // Prefixed schema_migrations table
+	log        *zap.Logger
+}
+
+// NewMigrator creates a migrator for the migrations in fsys
+// Task tables and the schema_migrations table are named with tablePrefix; of the options, only
+// WithLogger applies
+func NewMigrator(db *sqlx.DB, fsys fs.FS, tablePrefix string, opts ...Option) (*Migrator, error) {
+	if err := ValidateTablePrefix(tablePrefix); err != nil {
+		return nil, err
+	}
+
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		prefix:     tablePrefix,
+		table:      tablePrefix + SchemaMigrationsTable,
+		log:        applyOptions(opts).log,
+	}, nil
+}
+
+// Up applies all unapplied migrations in order and returns the versions applied
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	var applied []int
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if done[migration.Version] {
+				continue
+			}
+
+			if err := m.execStatements(ctx, conn, prefixTables(migration.Up, m.prefix)); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx,
+				"INSERT INTO "+m.table+" (version, name) VALUES (?, ?)",
+				migration.Version, migration.Name,
+			); err != nil {
+				return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+			}
+
+			m.log.Info("MySQL migration applied",
+				zap.Int("version", migration.Version),
+				zap.String("name", migration.Name),
+			)
+			applied = append(applied, migration.Version)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the given number of most recently applied migrations and returns the versions reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("steps must be positive")
+	}
+
+	var reverted []int
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if !done[migration.Version] {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted", migration.Version, migration.Name)
+			}
+
+			if err := m.execStatements(ctx, conn, prefixTables(migration.Down, m.prefix)); err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx,
+				"DELETE FROM "+m.table+" WHERE version = ?", migration.Version,
+			); err != nil {
+				return fmt.Errorf("failed to unrecord migration %d: %w", migration.Version, err)
+			}
+
+			m.log.Info("MySQL migration reverted",
+				zap.Int("version", migration.Version),
+				zap.String("name", migration.Name),
+			)
+			reverted = append(reverted, migration.Version)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Version returns the highest applied migration version, or 0 if none
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var version int
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		return conn.GetContext(ctx, &version, "SELECT COALESCE(MAX(version), 0) FROM "+m.table)
+	})
+	return version, err
+}
+
+// withLock runs fn on a single connection holding the migration lock
+// MySQL DDL is not transactional, so a named lock is what keeps concurrent runs apart
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	lockName := m.prefix + migrationLockName
+	var locked int
+	if err := conn.GetContext(ctx, &locked, "SELECT GET_LOCK(?, ?)", lockName, migrationLockTimeout); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if locked != 1 {
+		return fmt.Errorf("timed out waiting for migration lock")
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS `+m.table+` (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4
+	`); err != nil {
+		return fmt.Errorf("failed to create %s table: %w", m.table, err)
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions returns the set of recorded migration versions
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sqlx.Conn) (map[int]bool, error) {
+	var versions []int
+	if err := conn.SelectContext(ctx, &versions, "SELECT version FROM "+m.table); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", m.table, err)
+	}
+
+	done := make(map[int]bool, len(versions))
+	for _, v := range versions {
+		done[v] = true
+	}
+	return done, nil
+}
+
+// execStatements executes each statement of a migration in order
+// Statements are run one at a time so the connection does not need multiStatements
+func (m *Migrator) execStatements(ctx context.Context, conn *sqlx.Conn, sql string) error {
+	for _, stmt := range splitStatements(sql) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			if isAlreadyApplied(err) {
+				// Databases created before schema_migrations existed may already have this change
+				m.log.Info("MySQL migrations: skipping statement, object already in desired state", zap.Error(err))
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a migration into statements, dropping "--" comment lines
+func splitStatements(sql string) []string {
+	var lines []string
+	for _, line := range strings.Split(sql, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var statements []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
+
+// isAlreadyApplied reports whether err means the object already exists (or is already gone)
+func isAlreadyApplied(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	switch mysqlErr.Number {
+	case 1050, // Table already exists
+		1060, // Duplicate column name
+		1061, // Duplicate key name
+		1091, // Can't DROP; check that column/key exists
+		3821, // Check constraint not found
+		3822: // Duplicate check constraint name
+		return true
+	}
+	return false
+}
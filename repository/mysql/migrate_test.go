@@ -0,0 +1,137 @@
+package mysql
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/migrations"
+)
+
+func TestLoadEmbeddedMigrations(t *testing.T) {
+	loaded, err := LoadMigrations(migrations.MySQL)
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+
+	for i, m := range loaded {
+		assert.Equal(t, i+1, m.Version, "migrations must be numbered consecutively")
+		assert.NotEmpty(t, splitStatements(m.Up), "migration %d has no statements", m.Version)
+		if m.Version > 1 {
+			assert.NotEmpty(t, m.Down, "migration %d has no down file", m.Version)
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("Sorted by version", func(t *testing.T) {
+		loaded, err := LoadMigrations(fstest.MapFS{
+			"010_later_mysql.up.sql":   {Data: []byte("SELECT 10")},
+			"002_early_mysql.up.sql":   {Data: []byte("SELECT 2")},
+			"002_early_mysql.down.sql": {Data: []byte("SELECT -2")},
+			"001_init_schema.up.sql":   {Data: []byte("postgres, ignored")},
+			"README.md":                {Data: []byte("ignored")},
+		})
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+		assert.Equal(t, 2, loaded[0].Version)
+		assert.Equal(t, "SELECT -2", loaded[0].Down)
+		assert.Equal(t, 10, loaded[1].Version)
+		assert.Empty(t, loaded[1].Down)
+	})
+
+	t.Run("Missing up file", func(t *testing.T) {
+		_, err := LoadMigrations(fstest.MapFS{
+			"003_orphan_mysql.down.sql": {Data: []byte("SELECT 1")},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Conflicting names", func(t *testing.T) {
+		_, err := LoadMigrations(fstest.MapFS{
+			"003_one_mysql.up.sql": {Data: []byte("SELECT 1")},
+			"003_two_mysql.up.sql": {Data: []byte("SELECT 2")},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestSplitStatements(t *testing.T) {
+	sql := `-- Create table; with a semicolon in a comment
+CREATE TABLE t (id INT);
+
+-- Index
+CREATE INDEX idx ON t(id);
+`
+	assert.Equal(t, []string{"CREATE TABLE t (id INT)", "CREATE INDEX idx ON t(id)"}, splitStatements(sql))
+}
+
+// testDB connects to the database in LATER_TEST_MYSQL_DSN or skips the test
+func testDB(t testing.TB) *sqlx.DB {
+	t.Helper()
+	dsn := os.Getenv("LATER_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("LATER_TEST_MYSQL_DSN not set")
+	}
+
+	dsn, err := parseDSN(dsn)
+	require.NoError(t, err)
+	db, err := sqlx.Connect("mysql", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestMigratorIdempotent runs against a real database when LATER_TEST_MYSQL_DSN is set
+func TestMigratorIdempotent(t *testing.T) {
+	db := testDB(t)
+
+	for _, prefix := range []string{"", "later_test_"} {
+		t.Run("prefix="+prefix, func(t *testing.T) {
+			ctx := context.Background()
+			migrator, err := NewMigrator(db, migrations.MySQL, prefix)
+			require.NoError(t, err)
+
+			_, err = migrator.Up(ctx)
+			require.NoError(t, err)
+			latest, err := migrator.Version(ctx)
+			require.NoError(t, err)
+
+			applied, err := migrator.Up(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, applied, "second run must not reapply migrations")
+
+			reverted, err := migrator.Down(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, []int{latest}, reverted)
+
+			applied, err = migrator.Up(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []int{latest}, applied)
+		})
+	}
+}
+
+func TestPrefixTables(t *testing.T) {
+	sql := `CREATE TABLE IF NOT EXISTS task_queue_archive LIKE task_queue;
+CREATE INDEX idx_tasks_name ON task_queue(name);
+ALTER TABLE task_queue DROP CHECK task_queue_chk_1;`
+
+	assert.Equal(t, sql, prefixTables(sql, ""))
+	assert.Equal(t, `CREATE TABLE IF NOT EXISTS later_task_queue_archive LIKE later_task_queue;
+CREATE INDEX idx_tasks_name ON later_task_queue(name);
+ALTER TABLE later_task_queue DROP CHECK later_task_queue_chk_1;`, prefixTables(sql, "later_"))
+}
+
+func TestValidateTablePrefix(t *testing.T) {
+	assert.NoError(t, ValidateTablePrefix(""))
+	assert.NoError(t, ValidateTablePrefix("later_"))
+	assert.Error(t, ValidateTablePrefix("later-"))
+	assert.Error(t, ValidateTablePrefix("x; DROP TABLE users; --"))
+	assert.Error(t, ValidateTablePrefix(strings.Repeat("a", 50)))
+}
@@ -0,0 +1,107 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// WithPreparedStatements controls whether the statements run on every poll are prepared once
+// and reused, which is the default; disable it behind proxies that don't support them
+func WithPreparedStatements(enabled bool) Option {
+	return func(o *options) {
+		o.unprepared = !enabled
+	}
+}
+
+// preparedConn runs a fixed set of hot statements as prepared statements
+// Without them the driver prepares, executes and closes every statement with arguments,
+// three round trips instead of one. database/sql prepares a statement again on each connection
+// it runs on, so statements survive reconnects; one the server has lost is prepared again
+type preparedConn struct {
+	conn
+	db      *sqlx.DB
+	queries map[string]bool
+
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newPreparedConn(c conn, db *sqlx.DB, queries []string) *preparedConn {
+	p := &preparedConn{
+		conn:    c,
+		db:      db,
+		queries: make(map[string]bool, len(queries)),
+		stmts:   make(map[string]*sql.Stmt, len(queries)),
+	}
+	for _, query := range queries {
+		p.queries[query] = true
+	}
+	return p
+}
+
+// stmt returns the prepared statement for query, preparing it on first use, or nil if query
+// isn't one to prepare or can't be prepared yet, e.g. before the migrations have run
+func (c *preparedConn) stmt(ctx context.Context, query string) *sql.Stmt {
+	if !c.queries[query] {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt
+	}
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil
+	}
+	c.stmts[query] = stmt
+	return stmt
+}
+
+// forget drops a statement the server no longer accepts, so its next use prepares it again
+func (c *preparedConn) forget(query string, stmt *sql.Stmt) {
+	c.mu.Lock()
+	if c.stmts[query] == stmt {
+		delete(c.stmts, query)
+	}
+	c.mu.Unlock()
+	stmt.Close()
+}
+
+func (c *preparedConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if stmt := c.stmt(ctx, query); stmt != nil {
+		result, err := stmt.ExecContext(ctx, args...)
+		if !isStaleStatement(err) {
+			return result, err
+		}
+		c.forget(query, stmt)
+	}
+	return c.conn.ExecContext(ctx, query, args...)
+}
+
+func (c *preparedConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt := c.stmt(ctx, query); stmt != nil {
+		rows, err := stmt.QueryContext(ctx, args...)
+		if !isStaleStatement(err) {
+			return rows, err
+		}
+		c.forget(query, stmt)
+	}
+	return c.conn.QueryContext(ctx, query, args...)
+}
+
+// isStaleStatement reports whether err means the server has lost a prepared statement, or
+// needs it prepared again after a schema change
+func isStaleStatement(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == 1243 || // Unknown prepared statement handler
+		mysqlErr.Number == 1615 // Prepared statement needs to be re-prepared
+}
@@ -0,0 +1,162 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/migrations"
+)
+
+// countingConnector hands out connections that count the statements prepared on them
+// Like the MySQL driver without interpolateParams, they run statements with arguments only
+// through Prepare
+type countingConnector struct {
+	mu       sync.Mutex
+	prepares int
+	failNext error // Returned by the next statement execution
+}
+
+func (c *countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return countingConn{c}, nil
+}
+
+func (c *countingConnector) Driver() driver.Driver { return nil }
+
+func (c *countingConnector) prepared() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.prepares
+}
+
+type countingConn struct{ c *countingConnector }
+
+func (conn countingConn) Prepare(query string) (driver.Stmt, error) {
+	conn.c.mu.Lock()
+	conn.c.prepares++
+	conn.c.mu.Unlock()
+	return countingStmt(conn), nil
+}
+
+func (countingConn) Close() error              { return nil }
+func (countingConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type countingStmt struct{ c *countingConnector }
+
+func (countingStmt) Close() error  { return nil }
+func (countingStmt) NumInput() int { return -1 }
+
+func (s countingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	if err := s.c.failNext; err != nil {
+		s.c.failNext = nil
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s countingStmt) Query(args []driver.Value) (driver.Rows, error) { return emptyRows{}, nil }
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string              { return []string{"id"} }
+func (emptyRows) Close() error                   { return nil }
+func (emptyRows) Next(dest []driver.Value) error { return io.EOF }
+
+func TestPreparedStatements(t *testing.T) {
+	connector := &countingConnector{}
+	db := sqlx.NewDb(sql.OpenDB(connector), "mysql")
+	defer db.Close()
+	ctx := context.Background()
+	const hot, cold = "UPDATE t SET a = ? WHERE id = ?", "UPDATE t SET b = ? WHERE id = ?"
+
+	c := newConn(db, nil, hot)
+	for i := 0; i < 3; i++ {
+		_, err := c.ExecContext(ctx, hot, 1, "x")
+		require.NoError(t, err)
+		rows, err := c.QueryContext(ctx, "SELECT id FROM t WHERE id = ?", "x")
+		require.NoError(t, err)
+		rows.Close()
+	}
+	assert.Equal(t, 4, connector.prepared(), "the hot statement is prepared once, others every time")
+
+	// A statement the server lost is run unprepared, then prepared again
+	connector.failNext = &mysqldriver.MySQLError{Number: 1243, Message: "Unknown prepared statement handler"}
+	_, err := c.ExecContext(ctx, hot, 1, "x")
+	require.NoError(t, err)
+	assert.Equal(t, 5, connector.prepared())
+	_, err = c.ExecContext(ctx, hot, 1, "x")
+	require.NoError(t, err)
+	_, err = c.ExecContext(ctx, hot, 1, "x")
+	require.NoError(t, err)
+	assert.Equal(t, 6, connector.prepared())
+
+	// Other errors are returned as they are
+	connector.failNext = &mysqldriver.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	_, err = c.ExecContext(ctx, hot, 1, "x")
+	assert.True(t, isDuplicateEntry(err))
+
+	unprepared := newConn(db, []Option{WithPreparedStatements(false)}, cold)
+	_, err = unprepared.ExecContext(ctx, cold, 1, "x")
+	require.NoError(t, err)
+	_, err = unprepared.ExecContext(ctx, cold, 1, "x")
+	require.NoError(t, err)
+	assert.Equal(t, 8, connector.prepared(), "prepared statements can be disabled")
+}
+
+// BenchmarkSchedulerPoll measures a scheduler poll and the updates claiming its tasks, with
+// and without prepared statements, against LATER_TEST_MYSQL_DSN
+func BenchmarkSchedulerPoll(b *testing.B) {
+	db := testDB(b)
+	migrator, err := NewMigrator(db, migrations.MySQL, "")
+	require.NoError(b, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(b, err)
+
+	ctx := context.Background()
+	seed := NewTaskRepository(db)
+	var tasks []*entity.Task
+	for i := 0; i < 10; i++ {
+		// Never due, so polls see the same table whatever other tests left in it
+		task := entity.NewTask("bench", []byte(`{}`), "https://example.com/callback", time.Now().Add(time.Hour), 0)
+		require.NoError(b, seed.Create(ctx, task))
+		tasks = append(tasks, task)
+	}
+
+	for _, prepared := range []bool{true, false} {
+		name := "prepared"
+		if !prepared {
+			name = "unprepared"
+		}
+		b.Run(name, func(b *testing.B) {
+			repo := NewTaskRepository(db, WithPreparedStatements(prepared))
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					if _, err := repo.FindDueTasks(ctx, -1, 10, nil); err != nil {
+						b.Error(err)
+						return
+					}
+					if err := repo.Update(ctx, tasks[i%len(tasks)]); err != nil {
+						b.Error(err)
+						return
+					}
+					i++
+				}
+			})
+		})
+	}
+}
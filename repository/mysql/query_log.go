@@ -0,0 +1,99 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WithSlowQueryLog logs statements taking at least threshold at Warn, with the collapsed query
+// text, duration, rows and error, to the logger set by WithLogger; a threshold of zero disables it
+// Statements run inside a transaction aren't timed
+func WithSlowQueryLog(threshold time.Duration) Option {
+	return func(o *options) {
+		o.slowQueryThreshold = threshold
+	}
+}
+
+// timedConn reports the statements run through it that take at least threshold
+// Queries returning *sql.Rows are timed until their first result arrives, not until the
+// caller has scanned them
+type timedConn struct {
+	conn
+	threshold time.Duration
+	log       *zap.Logger
+}
+
+func (c *timedConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := c.conn.ExecContext(ctx, query, args...)
+	if elapsed := time.Since(start); elapsed >= c.threshold {
+		rows := int64(-1)
+		if err == nil {
+			if affected, rowsErr := result.RowsAffected(); rowsErr == nil {
+				rows = affected
+			}
+		}
+		c.slow(query, elapsed, rows, err)
+	}
+	return result, err
+}
+
+func (c *timedConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := c.conn.QueryContext(ctx, query, args...)
+	if elapsed := time.Since(start); elapsed >= c.threshold {
+		c.slow(query, elapsed, -1, err)
+	}
+	return rows, err
+}
+
+func (c *timedConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := c.conn.QueryRowContext(ctx, query, args...)
+	if elapsed := time.Since(start); elapsed >= c.threshold {
+		c.slow(query, elapsed, -1, row.Err())
+	}
+	return row
+}
+
+func (c *timedConn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := c.conn.GetContext(ctx, dest, query, args...)
+	if elapsed := time.Since(start); elapsed >= c.threshold {
+		rows := int64(1)
+		if err != nil {
+			rows = 0
+		}
+		c.slow(query, elapsed, rows, err)
+	}
+	return err
+}
+
+func (c *timedConn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := c.conn.SelectContext(ctx, dest, query, args...)
+	if elapsed := time.Since(start); elapsed >= c.threshold {
+		rows := int64(-1)
+		if v := reflect.ValueOf(dest); v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Slice {
+			rows = int64(v.Elem().Len())
+		}
+		c.slow(query, elapsed, rows, err)
+	}
+	return err
+}
+
+// slow logs a statement that ran for at least the threshold; its arguments are never included
+// rows is the number changed by a write or read by a Select or Get, or -1 when not known up front
+func (c *timedConn) slow(query string, elapsed time.Duration, rows int64, err error) {
+	c.log.Warn("Slow database query",
+		zap.String("query", strings.Join(strings.Fields(query), " ")),
+		zap.Duration("duration", elapsed),
+		zap.Int64("rows", rows),
+		zap.Error(err),
+	)
+}
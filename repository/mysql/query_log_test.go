@@ -0,0 +1,71 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/migrations"
+)
+
+func TestSlowQueryLogDisabled(t *testing.T) {
+	db, err := sqlx.Open("mysql", "later:later@tcp(127.0.0.1:1)/later")
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Same(t, db, newConn(db, nil))
+	assert.Same(t, db, newConn(db, []Option{WithSlowQueryLog(0)}), "a zero threshold disables it")
+}
+
+func TestSlowQueryLogFailedStatement(t *testing.T) {
+	// Nothing listens on port 1, so every statement fails without a server
+	db, err := sqlx.Open("mysql", "later:later@tcp(127.0.0.1:1)/later?timeout=1s")
+	require.NoError(t, err)
+	defer db.Close()
+
+	core, logs := observer.New(zap.WarnLevel)
+	c := newConn(db, []Option{WithLogger(zap.New(core)), WithSlowQueryLog(time.Nanosecond)})
+	_, err = c.ExecContext(context.Background(), "UPDATE t\n\t\tSET a = ?\n\t\tWHERE id = ?", 1, "x")
+	require.Error(t, err)
+	var names []string
+	err = c.SelectContext(context.Background(), &names, "SELECT name FROM t")
+	require.Error(t, err)
+
+	reported := logs.FilterMessage("Slow database query").All()
+	require.Len(t, reported, 2)
+	fields := reported[0].ContextMap()
+	assert.Equal(t, "UPDATE t SET a = ? WHERE id = ?", fields["query"], "whitespace is collapsed")
+	assert.Equal(t, int64(-1), fields["rows"])
+	assert.NotEmpty(t, fields["error"])
+	assert.Positive(t, fields["duration"])
+	assert.Equal(t, int64(0), reported[1].ContextMap()["rows"], "nothing was selected")
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	db := testDB(t)
+	migrator, err := NewMigrator(db, migrations.MySQL, "")
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	core, logs := observer.New(zap.WarnLevel)
+	repo := NewTaskRepository(db, WithLogger(zap.New(core)), WithSlowQueryLog(time.Nanosecond))
+	task := entity.NewTask("slow", []byte(`{}`), "https://example.com/callback", time.Now(), 0)
+	require.NoError(t, repo.Create(context.Background(), task))
+	_, err = repo.FindByID(context.Background(), task.ID)
+	require.NoError(t, err)
+
+	reported := logs.FilterMessage("Slow database query").All()
+	require.Len(t, reported, 2)
+	fields := reported[0].ContextMap()
+	assert.Contains(t, fields["query"], "INSERT INTO task_queue")
+	assert.Equal(t, int64(1), fields["rows"], "rows inserted")
+	assert.NotContains(t, fields, "error")
+}
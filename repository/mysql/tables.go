@@ -0,0 +1,45 @@
+package mysql
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Unprefixed table names
+const (
+	TaskQueueTable        = "task_queue"
+	TaskArchiveTable      = "task_queue_archive"
+	SchedulerLockTable    = "scheduler_lock"
+	TaskEventsTable       = "task_events"
+	HostPausesTable       = "host_pauses"
+	SchemaMigrationsTable = "schema_migrations"
+)
+
+// maxTablePrefixLength keeps the longest prefixed name within MySQL's 64 character limit
+const maxTablePrefixLength = 64 - len(TaskArchiveTable)
+
+var (
+	tablePrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_]*$`)
+	// Also matches identifiers derived from a table name, such as its check constraints
+	tableNamePattern = regexp.MustCompile(`\b(task_queue_archive|task_queue|scheduler_lock|task_events|host_pauses)`)
+)
+
+// ValidateTablePrefix checks that prefix is safe to use in table identifiers
+func ValidateTablePrefix(prefix string) error {
+	if !tablePrefixPattern.MatchString(prefix) {
+		return fmt.Errorf("table prefix may only contain letters, digits and underscores")
+	}
+	if len(prefix) > maxTablePrefixLength {
+		return fmt.Errorf("table prefix cannot be longer than %d characters", maxTablePrefixLength)
+	}
+	return nil
+}
+
+// prefixTables rewrites the table names in migration SQL to use prefix, along with constraint
+// names starting with them, which must be unique across the schema
+func prefixTables(sql, prefix string) string {
+	if prefix == "" {
+		return sql
+	}
+	return tableNamePattern.ReplaceAllString(sql, prefix+"$1")
+}
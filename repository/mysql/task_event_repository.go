@@ -0,0 +1,126 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+)
+
+// taskEventRepository implements repository.TaskEventRepository with rows in task_events
+type taskEventRepository struct {
+	db    conn
+	table string
+}
+
+// taskEventColumns selects a task event; a NULL metadata column can't be scanned into a
+// json.RawMessage, so it is read as empty
+const taskEventColumns = `id, task_id, tenant_id, event_type, from_status, to_status,
+	COALESCE(CAST(metadata AS CHAR), '') AS metadata, created_at, origin`
+
+// NewTaskEventRepository creates a MySQL task event repository whose table is named with the
+// given prefix; the prefix must have been checked with ValidateTablePrefix
+func NewTaskEventRepository(db *sqlx.DB, prefix string, opts ...Option) repository.TaskEventRepository {
+	return &taskEventRepository{db: newConn(db, opts), table: prefix + TaskEventsTable}
+}
+
+func (r *taskEventRepository) Append(ctx context.Context, event *entity.TaskEvent) error {
+	var metadata interface{}
+	if len(event.Metadata) > 0 {
+		metadata = string(event.Metadata)
+	}
+
+	// A task's previous status is read in the same statement, so concurrent events for
+	// different tasks don't need a transaction
+	query := `
+		INSERT INTO ` + r.table + ` (task_id, tenant_id, event_type, from_status, to_status, metadata, created_at, origin)
+		SELECT ?, ?, ?, COALESCE((
+			SELECT e.to_status FROM ` + r.table + ` e WHERE e.task_id = ? AND e.task_id != '' ORDER BY e.id DESC LIMIT 1
+		), ''), ?, ?, ?, ?`
+	result, err := r.db.ExecContext(ctx, query,
+		event.TaskID, event.TenantID, event.Type, event.TaskID, event.ToStatus, metadata, event.CreatedAt.UTC(), event.Origin)
+	if err != nil {
+		return fmt.Errorf("failed to record task event: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read task event ID: %w", err)
+	}
+	event.ID = id
+	return nil
+}
+
+func (r *taskEventRepository) ListByTask(ctx context.Context, taskID string, limit int) ([]*entity.TaskEvent, error) {
+	var events []*entity.TaskEvent
+	err := r.db.SelectContext(ctx, &events, `
+		SELECT `+taskEventColumns+`
+		FROM `+r.table+`
+		WHERE task_id = ? AND task_id != ''
+		ORDER BY id
+		LIMIT ?
+	`, taskID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *taskEventRepository) ListByTasks(ctx context.Context, taskIDs []string) ([]*entity.TaskEvent, error) {
+	if len(taskIDs) == 0 {
+		return nil, nil
+	}
+	query, args, err := sqlx.In(`
+		SELECT `+taskEventColumns+`
+		FROM `+r.table+`
+		WHERE task_id IN (?) AND task_id != ''
+		ORDER BY id
+	`, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	var events []*entity.TaskEvent
+	if err := r.db.SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list task events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *taskEventRepository) ListSince(ctx context.Context, afterID int64, tenantID string, limit int) ([]*entity.TaskEvent, error) {
+	query := `
+		SELECT ` + taskEventColumns + `
+		FROM ` + r.table + `
+		WHERE id > ?`
+	args := []interface{}{afterID}
+	if tenantID != "" {
+		query += tenantCondition
+		args = append(args, tenantID)
+	}
+	query += ` ORDER BY id LIMIT ?`
+	args = append(args, limit)
+
+	var events []*entity.TaskEvent
+	if err := r.db.SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list task events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *taskEventRepository) LastID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := r.db.GetContext(ctx, &id, `SELECT COALESCE(MAX(id), 0) FROM `+r.table); err != nil {
+		return 0, fmt.Errorf("failed to read the last task event ID: %w", err)
+	}
+	return id, nil
+}
+
+func (r *taskEventRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM `+r.table+` WHERE created_at < ?`, cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete task events: %w", err)
+	}
+	return result.RowsAffected()
+}
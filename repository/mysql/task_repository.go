@@ -2,153 +2,259 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/usual2970/later/domain"
 	"github.com/usual2970/later/domain/entity"
 	"github.com/usual2970/later/domain/repository"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
 // taskRepository implements repository.TaskRepository
 type taskRepository struct {
-	db *sqlx.DB
+	db           conn
+	table        string
+	archiveTable string
+
+	// Statements run on every poll, prepared once
+	findDueQuery      string
+	findDueAboveQuery string // Only priorities above a minimum
+	updateQuery       string // Ends in its WHERE clause
 }
 
 // NewTaskRepository creates a new MySQL task repository
-func NewTaskRepository(db *sqlx.DB) repository.TaskRepository {
-	return &taskRepository{db: db}
+func NewTaskRepository(db *sqlx.DB, opts ...Option) repository.TaskRepository {
+	return NewTaskRepositoryWithPrefix(db, "", opts...)
 }
 
-func (r *taskRepository) Create(ctx context.Context, task *entity.Task) error {
-	query := `
-		INSERT INTO task_queue (
-			id, name, payload, callback_url, status,
-			created_at, scheduled_at, max_retries, retry_count,
-			retry_backoff_seconds, callback_timeout_seconds, priority, tags
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-
-	// Convert tags to JSON for MySQL
-	tagsJSON, err := json.Marshal(task.Tags)
-	if err != nil {
-		return fmt.Errorf("failed to marshal tags: %w", err)
+// NewTaskRepositoryWithPrefix creates a MySQL task repository whose tables are named
+// with the given prefix, e.g. "later_" for later_task_queue
+// The prefix must have been checked with ValidateTablePrefix
+func NewTaskRepositoryWithPrefix(db *sqlx.DB, prefix string, opts ...Option) repository.TaskRepository {
+	r := &taskRepository{
+		table:        prefix + TaskQueueTable,
+		archiveTable: prefix + TaskArchiveTable,
 	}
+	// Both variants read idx_tasks_due in order and stop at the limit; a single query taking
+	// an optional minimum as (? = -1 OR priority > ?) can't use the priority range and sorts
+	r.findDueQuery = dueTasksQuery(r.table, "")
+	r.findDueAboveQuery = dueTasksQuery(r.table, "AND priority > ?")
+	r.updateQuery = `
+		UPDATE ` + r.table + ` SET
+			status = ?,
+			scheduled_at = ?,
+			max_retries = ?,
+			started_at = ?,
+			completed_at = ?,
+			retry_count = ?,
+			next_retry_at = ?,
+			callback_attempts = ?,
+			last_callback_at = ?,
+			last_callback_status = ?,
+			last_callback_error = ?,
+			last_callback_response = ?,
+			dispatch_latency_ms = ?,
+			callback_duration_ms = ?,
+			error_history = ?,
+			error_message = ?,
+			worker_id = NULLIF(?, '')
+		WHERE id = ?`
 
-	_, err = r.db.ExecContext(ctx, query,
-		task.ID, task.Name, task.Payload, task.CallbackURL, task.Status,
-		task.CreatedAt, task.ScheduledAt, task.MaxRetries, task.RetryCount,
-		task.RetryBackoffSeconds, task.CallbackTimeoutSecs, task.Priority, tagsJSON,
+	// Updates and single-status claims, with and without tenant scoping
+	claimQuery := r.updateQuery + claimCondition(1)
+	r.db = newConn(db, opts,
+		r.findDueQuery, r.findDueAboveQuery,
+		r.updateQuery, r.updateQuery+tenantCondition,
+		claimQuery, claimQuery+tenantCondition,
 	)
+	return r
+}
 
+func (r *taskRepository) Create(ctx context.Context, task *entity.Task) error {
+	args, err := createArgs(task)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, "INSERT INTO "+r.table+" ("+createColumns+") VALUES "+createPlaceholders, args...)
+	if isDuplicateEntry(err) {
+		return fmt.Errorf("%w: %s", domain.ErrConflict, task.ID)
+	}
 	return err
 }
 
-func (r *taskRepository) FindByID(ctx context.Context, id string) (*entity.Task, error) {
-	query := `
-		SELECT id, name, payload, callback_url, status,
-			   created_at, scheduled_at, started_at, completed_at,
-			   max_retries, retry_count, retry_backoff_seconds, next_retry_at,
-			   callback_attempts, callback_timeout_seconds, last_callback_at,
-			   last_callback_status, last_callback_error, priority, tags, error_message,
-			   deleted_at, deleted_by
-		FROM task_queue
-		WHERE id = ? AND deleted_at IS NULL
-	`
+func (r *taskRepository) CreateBatch(ctx context.Context, tasks []*entity.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	var args []interface{}
+	for _, task := range tasks {
+		taskArgs, err := createArgs(task)
+		if err != nil {
+			return fmt.Errorf("task %s: %w", task.ID, err)
+		}
+		args = append(args, taskArgs...)
+	}
+	values := createPlaceholders + strings.Repeat(", "+createPlaceholders, len(tasks)-1)
+	_, err := r.db.ExecContext(ctx, "INSERT INTO "+r.table+" ("+createColumns+") VALUES "+values, args...)
+	if isDuplicateEntry(err) {
+		// The driver's message names the taken ID
+		return fmt.Errorf("%w: %v", domain.ErrConflict, err)
+	}
+	return err
+}
 
-	var task entity.Task
-	var tagsJSON []byte
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&task.ID, &task.Name, &task.Payload, &task.CallbackURL, &task.Status,
-		&task.CreatedAt, &task.ScheduledAt, &task.StartedAt, &task.CompletedAt,
-		&task.MaxRetries, &task.RetryCount, &task.RetryBackoffSeconds, &task.NextRetryAt,
-		&task.CallbackAttempts, &task.CallbackTimeoutSecs, &task.LastCallbackAt,
-		&task.LastCallbackStatus, &task.LastCallbackError, &task.Priority, &tagsJSON, &task.ErrorMessage,
-		&task.DeletedAt, &task.DeletedBy,
-	)
+// isDuplicateEntry reports whether err means an inserted ID is already taken
+// The primary key is the only unique index, so soft-deleted tasks still hold their IDs
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 // Duplicate entry for key
+}
+
+func (r *taskRepository) ExistingIDs(ctx context.Context, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	query, args, err := sqlx.In("SELECT id FROM "+r.table+" WHERE id IN (?)", ids)
 	if err != nil {
 		return nil, err
 	}
+	var existing []string
+	if err := r.db.SelectContext(ctx, &existing, query, args...); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
 
-	// Unmarshal tags from JSON
-	if tagsJSON != nil {
-		if err := json.Unmarshal(tagsJSON, &task.Tags); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+// createColumns are the columns set when inserting a task, in createArgs order
+const createColumns = `id, name, payload, callback_url, status,
+	created_at, scheduled_at, max_retries, retry_count,
+	retry_backoff_seconds, callback_timeout_seconds, priority, tags, tenant_id,
+	callback_oauth2, retryable_status_codes, follow_redirects, callback_body_template, payload_encoding,
+	payload_encrypted, concurrency_key, depends_on, dependency_failure_policy, request_id, expires_at`
+
+// createPlaceholders is the VALUES row for createColumns
+const createPlaceholders = "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+// createArgs returns the values inserted for a task, encoding its payload and JSON columns
+func createArgs(task *entity.Task) ([]interface{}, error) {
+	encoding := task.PayloadEncoding
+	if encoding == "" {
+		encoding = entity.PayloadEncodingJSON
+	}
+	policy := task.DependencyFailurePolicy
+	if policy == "" {
+		policy = entity.DependencyFailureDeadLetter
+	}
+	payload, err := entity.EncodePayload(task.Payload, encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	// Convert tags to JSON for MySQL
+	tagsJSON, err := json.Marshal(task.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	var oauth2JSON []byte
+	if task.CallbackOAuth2 != nil {
+		if oauth2JSON, err = json.Marshal(task.CallbackOAuth2); err != nil {
+			return nil, fmt.Errorf("failed to marshal callback oauth2 config: %w", err)
 		}
 	}
 
-	return &task, nil
+	var retryableJSON []byte
+	if task.RetryableStatusCodes != nil {
+		if retryableJSON, err = json.Marshal(task.RetryableStatusCodes); err != nil {
+			return nil, fmt.Errorf("failed to marshal retryable status codes: %w", err)
+		}
+	}
+
+	return []interface{}{
+		task.ID, task.Name, payload, task.CallbackURL, task.Status,
+		task.CreatedAt, task.ScheduledAt, task.MaxRetries, task.RetryCount,
+		task.RetryBackoffSeconds, task.CallbackTimeoutSecs, task.Priority, tagsJSON, task.TenantID,
+		oauth2JSON, retryableJSON, task.FollowRedirects, task.CallbackBodyTemplate, encoding,
+		task.PayloadEncrypted, task.ConcurrencyKey, task.DependsOn, policy, task.RequestID, task.ExpiresAt,
+	}, nil
 }
 
-func (r *taskRepository) FindDueTasks(ctx context.Context, minPriority int, limit int) ([]*entity.Task, error) {
-	query := `
-		SELECT id, name, payload, callback_url, status,
-			   created_at, scheduled_at, started_at, completed_at,
-			   max_retries, retry_count, retry_backoff_seconds, next_retry_at,
-			   callback_attempts, callback_timeout_seconds, last_callback_at,
-			   last_callback_status, last_callback_error, priority, tags, error_message,
-			   deleted_at, deleted_by
-		FROM task_queue
+func (r *taskRepository) FindByID(ctx context.Context, id string) (*entity.Task, error) {
+	query := `SELECT ` + listColumns + ` FROM ` + r.table + ` WHERE id = ? AND deleted_at IS NULL`
+	args := []interface{}{id}
+	query, args = scopeToTenant(ctx, query, args)
+
+	task, err := scanListedTask(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+	return task, nil
+}
+
+// concurrencyGroupColumn computes Task.ConcurrencyGroup: the concurrency key, else the first tag
+const concurrencyGroupColumn = `COALESCE(concurrency_key, JSON_UNQUOTE(JSON_EXTRACT(tags, '$[0]')), '')`
+
+// dueTasksQuery returns the statement selecting and locking the most urgent due tasks, with
+// priorityCondition added
+func dueTasksQuery(table, priorityCondition string) string {
+	return `
+		SELECT ` + listColumns + `
+		FROM ` + table + `
 		WHERE status = 'pending'
-		  AND scheduled_at <= UTC_TIMESTAMP()
 		  AND deleted_at IS NULL
-		  AND (? = -1 OR priority > ?)
+		  ` + priorityCondition + `
+		  AND scheduled_at <= UTC_TIMESTAMP()
 		ORDER BY priority DESC, scheduled_at ASC
 		LIMIT ?
 		FOR UPDATE SKIP LOCKED
 	`
+}
 
-	rows, err := r.db.QueryContext(ctx, query, minPriority, minPriority, limit)
-	if err != nil {
-		return nil, err
+func (r *taskRepository) FindDueTasks(ctx context.Context, minPriority int, limit int, skipGroups []string) ([]*entity.Task, error) {
+	query, args := r.findDueQuery, []interface{}{limit}
+	if minPriority != -1 {
+		query, args = r.findDueAboveQuery, []interface{}{minPriority, limit}
 	}
-	defer rows.Close()
-
-	var tasks []*entity.Task
-	for rows.Next() {
-		var task entity.Task
-		var tagsJSON []byte
-		err := rows.Scan(
-			&task.ID, &task.Name, &task.Payload, &task.CallbackURL, &task.Status,
-			&task.CreatedAt, &task.ScheduledAt, &task.StartedAt, &task.CompletedAt,
-			&task.MaxRetries, &task.RetryCount, &task.RetryBackoffSeconds, &task.NextRetryAt,
-			&task.CallbackAttempts, &task.CallbackTimeoutSecs, &task.LastCallbackAt,
-			&task.LastCallbackStatus, &task.LastCallbackError, &task.Priority, &tagsJSON, &task.ErrorMessage,
-			&task.DeletedAt, &task.DeletedBy,
-		)
-		if err != nil {
-			return nil, err
+	if len(skipGroups) > 0 {
+		// Only built while some group is saturated, so it isn't worth preparing
+		condition := "AND " + concurrencyGroupColumn + " NOT IN (?" + strings.Repeat(", ?", len(skipGroups)-1) + ")"
+		args = nil
+		if minPriority != -1 {
+			condition = "AND priority > ? " + condition
+			args = append(args, minPriority)
 		}
-
-		// Unmarshal tags from JSON
-		if tagsJSON != nil {
-			if err := json.Unmarshal(tagsJSON, &task.Tags); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
-			}
+		for _, group := range skipGroups {
+			args = append(args, group)
 		}
-
-		tasks = append(tasks, &task)
+		query, args = dueTasksQuery(r.table, condition), append(args, limit)
 	}
-
-	return tasks, rows.Err()
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanListedTasks(rows)
 }
 
 func (r *taskRepository) FindPendingTasks(ctx context.Context, limit int) ([]*entity.Task, error) {
-	return r.FindDueTasks(ctx, -1, limit)
+	return r.FindDueTasks(ctx, -1, limit, nil)
 }
 
 func (r *taskRepository) FindFailedTasks(ctx context.Context, limit int) ([]*entity.Task, error) {
 	query := `
-		SELECT id, name, payload, callback_url, status,
-			   created_at, scheduled_at, started_at, completed_at,
-			   max_retries, retry_count, retry_backoff_seconds, next_retry_at,
-			   callback_attempts, callback_timeout_seconds, last_callback_at,
-			   last_callback_status, last_callback_error, priority, tags, error_message,
-			   deleted_at, deleted_by
-		FROM task_queue
+		SELECT ` + listColumns + `
+		FROM ` + r.table + `
 		WHERE status = 'failed'
 		  AND next_retry_at <= UTC_TIMESTAMP()
 		  AND deleted_at IS NULL
@@ -161,72 +267,113 @@ func (r *taskRepository) FindFailedTasks(ctx context.Context, limit int) ([]*ent
 		return nil, err
 	}
 	defer rows.Close()
+	return scanListedTasks(rows)
+}
 
-	var tasks []*entity.Task
-	for rows.Next() {
-		var task entity.Task
-		var tagsJSON []byte
-		err := rows.Scan(
-			&task.ID, &task.Name, &task.Payload, &task.CallbackURL, &task.Status,
-			&task.CreatedAt, &task.ScheduledAt, &task.StartedAt, &task.CompletedAt,
-			&task.MaxRetries, &task.RetryCount, &task.RetryBackoffSeconds, &task.NextRetryAt,
-			&task.CallbackAttempts, &task.CallbackTimeoutSecs, &task.LastCallbackAt,
-			&task.LastCallbackStatus, &task.LastCallbackError, &task.Priority, &tagsJSON, &task.ErrorMessage,
-			&task.DeletedAt, &task.DeletedBy,
-		)
-		if err != nil {
-			return nil, err
-		}
+func (r *taskRepository) FindUpcomingTasks(ctx context.Context, before time.Time, limit int) ([]*repository.ScheduledTask, error) {
+	query := `
+		SELECT id, scheduled_at
+		FROM ` + r.table + `
+		WHERE status = 'pending'
+		  AND scheduled_at > UTC_TIMESTAMP()
+		  AND scheduled_at <= ?
+		  AND deleted_at IS NULL
+		ORDER BY scheduled_at ASC
+		LIMIT ?
+	`
 
-		// Unmarshal tags from JSON
-		if tagsJSON != nil {
-			if err := json.Unmarshal(tagsJSON, &task.Tags); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
-			}
-		}
+	var tasks []*repository.ScheduledTask
+	if err := r.db.SelectContext(ctx, &tasks, query, before.UTC(), limit); err != nil {
+		return nil, fmt.Errorf("failed to find upcoming tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+func (r *taskRepository) Update(ctx context.Context, task *entity.Task) error {
+	query, args, err := r.update(task)
+	if err != nil {
+		return err
+	}
+	query, args = scopeToTenant(ctx, query, args)
+
+	_, err = r.db.ExecContext(ctx, query, args...)
 
-		tasks = append(tasks, &task)
+	return err
+}
+
+func (r *taskRepository) UpdateIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	if len(from) == 0 {
+		return false, nil
 	}
+	query, args, err := r.update(task)
+	if err != nil {
+		return false, err
+	}
+	query += claimCondition(len(from))
+	for _, status := range from {
+		args = append(args, status)
+	}
+	query, args = scopeToTenant(ctx, query, args)
 
-	return tasks, rows.Err()
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
 }
 
-func (r *taskRepository) Update(ctx context.Context, task *entity.Task) error {
-	query := `
-		UPDATE task_queue SET
-			status = ?,
-			started_at = ?,
-			completed_at = ?,
-			retry_count = ?,
-			next_retry_at = ?,
-			callback_attempts = ?,
-			last_callback_at = ?,
-			last_callback_status = ?,
-			last_callback_error = ?,
-			error_message = ?
-		WHERE id = ?
-	`
+// update returns the statement persisting the task's mutable fields, ending in its WHERE clause
+func (r *taskRepository) update(task *entity.Task) (string, []interface{}, error) {
+	var historyJSON []byte
+	if task.ErrorHistory != nil {
+		var err error
+		if historyJSON, err = json.Marshal(task.ErrorHistory); err != nil {
+			return "", nil, fmt.Errorf("failed to marshal error history: %w", err)
+		}
+	}
 
-	_, err := r.db.ExecContext(ctx, query,
-		task.Status, task.StartedAt, task.CompletedAt,
-		task.RetryCount, task.NextRetryAt,
+	args := []interface{}{
+		task.Status, task.ScheduledAt, task.MaxRetries,
+		task.StartedAt, task.CompletedAt, task.RetryCount, task.NextRetryAt,
 		task.CallbackAttempts, task.LastCallbackAt,
 		task.LastCallbackStatus, task.LastCallbackError,
-		task.ErrorMessage,
-		task.ID,
-	)
+		task.LastCallbackResponse, task.DispatchLatencyMs, task.CallbackDurationMs,
+		historyJSON, task.ErrorMessage, task.WorkerID, task.ID,
+	}
+	return r.updateQuery, args, nil
+}
 
+func (r *taskRepository) UpdateTags(ctx context.Context, taskID string, tags []string) error {
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+	query := `
+		UPDATE ` + r.table + `
+		SET tags = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`
+	args := []interface{}{tagsJSON, taskID}
+	query, args = scopeToTenant(ctx, query, args)
+
+	_, err = r.db.ExecContext(ctx, query, args...)
 	return err
 }
 
 func (r *taskRepository) SoftDelete(ctx context.Context, taskID string, deletedBy string) error {
 	query := `
-		UPDATE task_queue
+		UPDATE ` + r.table + `
 		SET deleted_at = UTC_TIMESTAMP(), deleted_by = ?
 		WHERE id = ? AND deleted_at IS NULL
 	`
+	args := []interface{}{deletedBy, taskID}
+	query, args = scopeToTenant(ctx, query, args)
 
-	result, err := r.db.ExecContext(ctx, query, deletedBy, taskID)
+	result, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -245,16 +392,216 @@ func (r *taskRepository) SoftDelete(ctx context.Context, taskID string, deletedB
 	return nil
 }
 
+func (r *taskRepository) CountBulk(ctx context.Context, filter repository.BulkFilter) (int64, error) {
+	whereClause, args, err := bulkWhere(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	query := "SELECT COUNT(*) FROM (SELECT id FROM " + r.table + " " + whereClause + " LIMIT ?) matched"
+	err = r.db.GetContext(ctx, &count, query, append(args, filter.Limit)...)
+	return count, err
+}
+
+func (r *taskRepository) BulkSoftDelete(ctx context.Context, filter repository.BulkFilter, deletedBy string) ([]string, error) {
+	return r.bulkUpdate(ctx, filter, "deleted_at = UTC_TIMESTAMP(), deleted_by = ?", deletedBy)
+}
+
+func (r *taskRepository) BulkRetry(ctx context.Context, filter repository.BulkFilter) ([]string, error) {
+	return r.bulkUpdate(ctx, filter, "status = ?, retry_count = 0, next_retry_at = NULL", entity.TaskStatusPending)
+}
+
+// bulkUpdate applies set to the tasks matching the filter in one transaction: their rows are
+// locked by a single select, then updated by a single statement
+func (r *taskRepository) bulkUpdate(ctx context.Context, filter repository.BulkFilter, set string, setArgs ...interface{}) ([]string, error) {
+	whereClause, args, err := bulkWhere(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var ids []string
+	query := "SELECT id FROM " + r.table + " " + whereClause + " ORDER BY created_at LIMIT ? FOR UPDATE"
+	if err := tx.SelectContext(ctx, &ids, query, append(args, filter.Limit)...); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query, args, err = sqlx.In("UPDATE "+r.table+" SET "+set+" WHERE id IN (?)", append(setArgs, ids)...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
+// bulkWhere builds the WHERE clause selecting the live tasks matching a bulk filter
+func bulkWhere(ctx context.Context, filter repository.BulkFilter) (string, []interface{}, error) {
+	if len(filter.Statuses) == 0 || filter.Limit <= 0 {
+		return "", nil, fmt.Errorf("bulk filter needs statuses and a limit")
+	}
+
+	statuses := make([]string, 0, len(filter.Statuses))
+	for _, status := range filter.Statuses {
+		statuses = append(statuses, string(status))
+	}
+	whereClause := "WHERE deleted_at IS NULL AND status IN (?)"
+	args := []interface{}{statuses}
+
+	if tenantID, ok := domain.TenantFromContext(ctx); ok {
+		whereClause += " AND tenant_id = ?"
+		args = append(args, tenantID)
+	}
+	if len(filter.IDs) > 0 {
+		whereClause += " AND id IN (?)"
+		args = append(args, filter.IDs)
+	}
+	if filter.Tag != "" {
+		whereClause += " AND JSON_CONTAINS(tags, JSON_QUOTE(?))"
+		args = append(args, filter.Tag)
+	}
+	if filter.DateFrom != nil {
+		whereClause += " AND created_at >= ?"
+		args = append(args, *filter.DateFrom)
+	}
+	if filter.DateTo != nil {
+		whereClause += " AND created_at <= ?"
+		args = append(args, *filter.DateTo)
+	}
+
+	return sqlx.In(whereClause, args...)
+}
+
 func (r *taskRepository) List(ctx context.Context, filter repository.TaskFilter) ([]*entity.Task, int64, error) {
 	startTime := time.Now()
+	if _, err := listOrderBy(filter); err != nil {
+		return nil, 0, err
+	}
+	whereClause, args := listWhere(ctx, filter)
+
+	// Count total
+	countQuery := "SELECT COUNT(*) FROM " + r.table + " " + whereClause
+	var total int64
+	err := r.db.GetContext(ctx, &total, countQuery, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit <= 0 {
+		return nil, total, nil
+	}
+
+	// Fetch the page
+	tasks := make([]*entity.Task, 0, filter.Limit)
+	err = r.ListStream(ctx, filter, func(task *entity.Task) error {
+		tasks = append(tasks, task)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[List] Query failed: %v", err)
+		return nil, 0, err
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("[List] Query completed: fetched %d tasks (total: %d) in %v", len(tasks), total, duration)
+
+	return tasks, total, nil
+}
+
+// ListStream scans the tasks matching the filter, calling fn for each row as it is read
+// A positive filter.Limit caps the number of rows, and a Page above 1 skips the earlier pages
+func (r *taskRepository) ListStream(ctx context.Context, filter repository.TaskFilter, fn func(*entity.Task) error) error {
+	orderBy, err := listOrderBy(filter)
+	if err != nil {
+		return err
+	}
+	whereClause, args := listWhere(ctx, filter)
+	query := "SELECT " + listColumns + " FROM " + r.table + " " + whereClause + " ORDER BY " + orderBy
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+		if filter.Page > 1 {
+			query += " OFFSET ?"
+			args = append(args, (filter.Page-1)*filter.Limit)
+		}
+	}
+
+	// Closing rows that weren't read to the end makes the driver read and discard the rest, so
+	// stopping early cancels the query first; deferred calls run last in, first out
+	ctx, cancel := context.WithCancel(ctx)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return err
+	}
+	defer rows.Close()
+	defer cancel()
+
+	for rows.Next() {
+		// Rows the driver already buffered are still returned after cancellation
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		task, err := scanListedTask(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// listColumns are the task columns selected wherever whole tasks are read, in scanListedTask order
+const listColumns = `id, name, payload, callback_url, status,
+	created_at, scheduled_at, started_at, completed_at,
+	max_retries, retry_count, retry_backoff_seconds, next_retry_at,
+	callback_attempts, callback_timeout_seconds, last_callback_at,
+	last_callback_status, last_callback_error, last_callback_response, callback_oauth2, retryable_status_codes, follow_redirects, callback_body_template, payload_encoding, payload_encrypted, concurrency_key, depends_on, dependency_failure_policy, request_id, expires_at, dispatch_latency_ms, callback_duration_ms, error_history, priority, tags, error_message, COALESCE(worker_id, '') AS worker_id,
+	deleted_at, deleted_by, tenant_id`
+
+// listWhere builds the WHERE clause selecting the live tasks matching a list filter
+func listWhere(ctx context.Context, filter repository.TaskFilter) (string, []interface{}) {
 	whereClause := "WHERE deleted_at IS NULL"
 	args := []interface{}{}
 
+	if tenantID, ok := domain.TenantFromContext(ctx); ok {
+		whereClause += " AND tenant_id = ?"
+		args = append(args, tenantID)
+	}
+
+	if filter.TenantID != nil {
+		whereClause += " AND tenant_id = ?"
+		args = append(args, *filter.TenantID)
+	}
+
 	if filter.Status != nil {
 		whereClause += " AND status = ?"
 		args = append(args, *filter.Status)
 	}
 
+	if filter.DependsOn != nil {
+		whereClause += " AND depends_on = ?"
+		args = append(args, *filter.DependsOn)
+	}
+
+	if len(filter.ParentIDs) > 0 {
+		whereClause += " AND depends_on IN (?" + strings.Repeat(", ?", len(filter.ParentIDs)-1) + ")"
+		for _, id := range filter.ParentIDs {
+			args = append(args, id)
+		}
+	}
+
 	if filter.Priority != nil {
 		whereClause += " AND priority >= ?"
 		args = append(args, *filter.Priority)
@@ -266,6 +613,16 @@ func (r *taskRepository) List(ctx context.Context, filter repository.TaskFilter)
 		args = append(args, filter.Tags[0])
 	}
 
+	if filter.Name != "" {
+		whereClause += " AND name = ?"
+		args = append(args, filter.Name)
+	}
+
+	if filter.NamePrefix != "" {
+		whereClause += " AND name LIKE ?"
+		args = append(args, escapeLike(filter.NamePrefix)+"%")
+	}
+
 	if filter.DateFrom != nil {
 		whereClause += " AND created_at >= ?"
 		args = append(args, *filter.DateFrom)
@@ -276,83 +633,125 @@ func (r *taskRepository) List(ctx context.Context, filter repository.TaskFilter)
 		args = append(args, *filter.DateTo)
 	}
 
-	// Count total
-	countQuery := "SELECT COUNT(*) FROM task_queue " + whereClause
-	var total int64
-	err := r.db.GetContext(ctx, &total, countQuery, args...)
-	if err != nil {
-		return nil, 0, err
+	// With a status filter, idx_tasks_status_scheduled_priority serves scheduled_at ranges
+	if filter.ScheduledFrom != nil {
+		whereClause += " AND scheduled_at >= ?"
+		args = append(args, *filter.ScheduledFrom)
 	}
 
-	// Build ORDER BY
-	orderBy := "created_at DESC"
-	if filter.SortBy != "" {
-		orderBy = filter.SortBy + " " + filter.SortOrder
+	if filter.ScheduledTo != nil {
+		whereClause += " AND scheduled_at <= ?"
+		args = append(args, *filter.ScheduledTo)
 	}
 
-	// Add pagination
-	offset := (filter.Page - 1) * filter.Limit
-	whereClause += fmt.Sprintf(" ORDER BY %s LIMIT ? OFFSET ?", orderBy)
-	args = append(args, filter.Limit, offset)
+	return whereClause, args
+}
 
-	// Fetch tasks
-	query := `
-		SELECT id, name, payload, callback_url, status,
-			   created_at, scheduled_at, started_at, completed_at,
-			   max_retries, retry_count, retry_backoff_seconds, next_retry_at,
-			   callback_attempts, callback_timeout_seconds, last_callback_at,
-			   last_callback_status, last_callback_error, priority, tags, error_message,
-			   deleted_at, deleted_by
-		FROM task_queue
-	` + whereClause
+// listSortColumns are the columns lists can be ordered by
+var listSortColumns = map[string]bool{"created_at": true, "scheduled_at": true, "priority": true}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		log.Printf("[List] Query failed: %v", err)
-		return nil, 0, err
+// listOrderBy returns the ORDER BY expression for a list filter; both parts end up in the SQL,
+// so anything but a known column and direction is refused
+func listOrderBy(filter repository.TaskFilter) (string, error) {
+	if filter.SortBy == "" {
+		return "created_at DESC", nil
 	}
-	defer rows.Close()
+	if !listSortColumns[filter.SortBy] {
+		return "", fmt.Errorf("%w: cannot sort by %q", domain.ErrBadParamInput, filter.SortBy)
+	}
+	switch order := strings.ToUpper(filter.SortOrder); order {
+	case "":
+		return filter.SortBy + " DESC", nil
+	case "ASC", "DESC":
+		return filter.SortBy + " " + order, nil
+	}
+	return "", fmt.Errorf("%w: invalid sort order %q", domain.ErrBadParamInput, filter.SortOrder)
+}
 
+// rowScanner is a *sql.Rows, or the *sql.Row of a single-row query
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanListedTasks scans every row selected with listColumns
+func scanListedTasks(rows *sql.Rows) ([]*entity.Task, error) {
 	var tasks []*entity.Task
 	for rows.Next() {
-		var task entity.Task
-		var tagsJSON []byte
-		err := rows.Scan(
-			&task.ID, &task.Name, &task.Payload, &task.CallbackURL, &task.Status,
-			&task.CreatedAt, &task.ScheduledAt, &task.StartedAt, &task.CompletedAt,
-			&task.MaxRetries, &task.RetryCount, &task.RetryBackoffSeconds, &task.NextRetryAt,
-			&task.CallbackAttempts, &task.CallbackTimeoutSecs, &task.LastCallbackAt,
-			&task.LastCallbackStatus, &task.LastCallbackError, &task.Priority, &tagsJSON, &task.ErrorMessage,
-			&task.DeletedAt, &task.DeletedBy,
-		)
+		task, err := scanListedTask(rows)
 		if err != nil {
-			return nil, 0, err
-		}
-
-		// Unmarshal tags from JSON
-		if tagsJSON != nil {
-			if err := json.Unmarshal(tagsJSON, &task.Tags); err != nil {
-				return nil, 0, fmt.Errorf("failed to unmarshal tags: %w", err)
-			}
+			return nil, err
 		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
 
-		tasks = append(tasks, &task)
+// scanListedTask scans a row selected with listColumns
+func scanListedTask(row rowScanner) (*entity.Task, error) {
+	var task entity.Task
+	var tagsJSON, oauth2JSON, retryableJSON, historyJSON []byte
+	err := row.Scan(
+		&task.ID, &task.Name, &task.Payload, &task.CallbackURL, &task.Status,
+		&task.CreatedAt, &task.ScheduledAt, &task.StartedAt, &task.CompletedAt,
+		&task.MaxRetries, &task.RetryCount, &task.RetryBackoffSeconds, &task.NextRetryAt,
+		&task.CallbackAttempts, &task.CallbackTimeoutSecs, &task.LastCallbackAt,
+		&task.LastCallbackStatus, &task.LastCallbackError, &task.LastCallbackResponse, &oauth2JSON, &retryableJSON, &task.FollowRedirects, &task.CallbackBodyTemplate, &task.PayloadEncoding, &task.PayloadEncrypted, &task.ConcurrencyKey, &task.DependsOn, &task.DependencyFailurePolicy, &task.RequestID, &task.ExpiresAt, &task.DispatchLatencyMs, &task.CallbackDurationMs, &historyJSON, &task.Priority, &tagsJSON, &task.ErrorMessage, &task.WorkerID,
+		&task.DeletedAt, &task.DeletedBy, &task.TenantID,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	duration := time.Since(startTime)
-	log.Printf("[List] Query completed: fetched %d tasks (total: %d) in %v", len(tasks), total, duration)
+	// Unmarshal tags from JSON
+	if tagsJSON != nil {
+		if err := json.Unmarshal(tagsJSON, &task.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+	}
+	if oauth2JSON != nil {
+		if err := json.Unmarshal(oauth2JSON, &task.CallbackOAuth2); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal callback oauth2 config: %w", err)
+		}
+	}
+	if retryableJSON != nil {
+		if err := json.Unmarshal(retryableJSON, &task.RetryableStatusCodes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal retryable status codes: %w", err)
+		}
+	}
+	if historyJSON != nil {
+		if err := json.Unmarshal(historyJSON, &task.ErrorHistory); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal error history: %w", err)
+		}
+	}
+	if task.Payload, err = entity.DecodePayload(task.Payload, task.PayloadEncoding); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	return &task, nil
+}
 
-	return tasks, total, rows.Err()
+func (r *taskRepository) LatestCreatedID(ctx context.Context) (string, error) {
+	// Served from idx_tasks_created_at, which covers the primary key
+	var id string
+	err := r.db.GetContext(ctx, &id, `SELECT id FROM `+r.table+` ORDER BY created_at DESC, id DESC LIMIT 1`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find latest task: %w", err)
+	}
+	return id, nil
 }
 
 func (r *taskRepository) CountByStatus(ctx context.Context) (map[entity.TaskStatus]int64, error) {
 	query := `
 		SELECT status, COUNT(*) as count
-		FROM task_queue where deleted_at IS NULL
-		GROUP BY status
+		FROM ` + r.table + ` where deleted_at IS NULL
 	`
+	args := []interface{}{}
+	query, args = scopeToTenant(ctx, query, args)
+	query += " GROUP BY status"
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -371,41 +770,414 @@ func (r *taskRepository) CountByStatus(ctx context.Context) (map[entity.TaskStat
 	return result, rows.Err()
 }
 
-func (r *taskRepository) CleanupExpiredData(ctx context.Context) (int64, error) {
-	// Clean up tasks completed or dead_lettered more than 30 days ago
-	// Delete in batches to avoid long-running transactions
-	batchSize := 1000
-	var totalDeleted int64
+func (r *taskRepository) StatsSummary(ctx context.Context, since time.Time) (*repository.StatsSummary, error) {
+	// Failed and dead-lettered tasks have no finish time, so they count by their last attempt
+	query := `
+		SELECT
+			COUNT(CASE WHEN created_at >= ? THEN 1 END),
+			COUNT(CASE WHEN status = 'completed' AND completed_at >= ? THEN 1 END),
+			COUNT(CASE WHEN status = 'failed' AND COALESCE(started_at, created_at) >= ? THEN 1 END),
+			COUNT(CASE WHEN status = 'dead_lettered' AND COALESCE(started_at, created_at) >= ? THEN 1 END),
+			COUNT(CASE WHEN status = 'expired' AND completed_at >= ? THEN 1 END),
+			COALESCE(AVG(CASE
+				WHEN status = 'completed' AND completed_at >= ? THEN callback_attempts
+				WHEN status = 'dead_lettered' AND COALESCE(started_at, created_at) >= ? THEN callback_attempts
+			END), 0)
+		FROM ` + r.table + ` WHERE deleted_at IS NULL
+	`
+	args := []interface{}{since, since, since, since, since, since, since}
+	query, args = scopeToTenant(ctx, query, args)
+
+	var summary repository.StatsSummary
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&summary.Created, &summary.Completed,
+		&summary.Failed, &summary.DeadLettered, &summary.Expired, &summary.AvgCallbackAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	latencies := `
+		SELECT TIMESTAMPDIFF(MICROSECOND, created_at, completed_at) / 1000 AS latency_ms
+		FROM ` + r.table + ` WHERE deleted_at IS NULL AND status = 'completed' AND completed_at >= ?`
+	summary.P50CompletionLatencyMs, summary.P95CompletionLatencyMs, err = r.percentiles(ctx, latencies, since)
+	if err != nil {
+		return nil, err
+	}
+
+	latencies = `
+		SELECT dispatch_latency_ms AS latency_ms
+		FROM ` + r.table + ` WHERE deleted_at IS NULL AND dispatch_latency_ms IS NOT NULL AND started_at >= ?`
+	summary.P50DispatchLatencyMs, summary.P95DispatchLatencyMs, err = r.percentiles(ctx, latencies, since)
+	if err != nil {
+		return nil, err
+	}
+
+	latencies = `
+		SELECT callback_duration_ms AS latency_ms
+		FROM ` + r.table + ` WHERE deleted_at IS NULL AND callback_duration_ms IS NOT NULL AND last_callback_at >= ?`
+	summary.P50CallbackDurationMs, summary.P95CallbackDurationMs, err = r.percentiles(ctx, latencies, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+// percentiles returns the p50 and p95 of the latency_ms column selected by latencies,
+// which takes the window start as its only argument; zero when it selects no rows
+func (r *taskRepository) percentiles(ctx context.Context, latencies string, since time.Time) (p50, p95 float64, err error) {
+	args := []interface{}{since}
+	latencies, args = scopeToTenant(ctx, latencies, args)
+
+	// Nearest-rank percentiles: the value at rank ceil(p * n) of the sorted latencies
+	query := `
+		SELECT
+			COALESCE(MAX(CASE WHEN rn = CEIL(0.50 * n) THEN latency_ms END), 0),
+			COALESCE(MAX(CASE WHEN rn = CEIL(0.95 * n) THEN latency_ms END), 0)
+		FROM (
+			SELECT latency_ms, ROW_NUMBER() OVER (ORDER BY latency_ms) AS rn, COUNT(*) OVER () AS n
+			FROM (` + latencies + `) latencies
+		) ranked
+	`
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&p50, &p95)
+	return p50, p95, err
+}
+
+func (r *taskRepository) CountByTimeBucket(ctx context.Context, since time.Time, bucket time.Duration) ([]*repository.TimeBucketCounts, error) {
+	bucketSeconds := int64(bucket / time.Second)
+	if bucketSeconds <= 0 {
+		return nil, fmt.Errorf("bucket must be at least one second")
+	}
+
+	// Each event is bucketed by the time it happened, then the streams are merged
+	created := `
+		SELECT FLOOR(UNIX_TIMESTAMP(created_at) / ?) AS bucket,
+			1 AS created, 0 AS completed, 0 AS failed, 0 AS dead_lettered,
+			NULL AS latency_ms
+		FROM ` + r.table + ` WHERE deleted_at IS NULL AND created_at >= ?`
+	completed := `
+		SELECT FLOOR(UNIX_TIMESTAMP(completed_at) / ?),
+			0, 1, 0, 0,
+			TIMESTAMPDIFF(MICROSECOND, started_at, last_callback_at) / 1000
+		FROM ` + r.table + ` WHERE deleted_at IS NULL AND status = 'completed' AND completed_at >= ?`
+	failed := `
+		SELECT FLOOR(UNIX_TIMESTAMP(COALESCE(started_at, created_at)) / ?),
+			0, 0, status = 'failed', status = 'dead_lettered',
+			NULL
+		FROM ` + r.table + ` WHERE deleted_at IS NULL AND status IN ('failed', 'dead_lettered')
+			AND COALESCE(started_at, created_at) >= ?`
+
+	args := []interface{}{}
+	parts := make([]string, 0, 3)
+	for _, part := range []string{created, completed, failed} {
+		partArgs := []interface{}{bucketSeconds, since}
+		part, partArgs = scopeToTenant(ctx, part, partArgs)
+		parts = append(parts, part)
+		args = append(args, partArgs...)
+	}
+
+	query := `
+		SELECT bucket, SUM(created), SUM(completed), SUM(failed), SUM(dead_lettered),
+			COALESCE(AVG(latency_ms), 0)
+		FROM (` + strings.Join(parts, " UNION ALL ") + `) events
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*repository.TimeBucketCounts
+	for rows.Next() {
+		var index int64
+		counts := &repository.TimeBucketCounts{}
+		if err := rows.Scan(&index, &counts.Created, &counts.Completed, &counts.Failed,
+			&counts.DeadLettered, &counts.AvgCallbackLatencyMs); err != nil {
+			return nil, err
+		}
+		counts.Start = time.Unix(index*bucketSeconds, 0).UTC()
+		result = append(result, counts)
+	}
+
+	return result, rows.Err()
+}
+
+// groupStatuses are the statuses StatsByGroup counts, one column each
+var groupStatuses = []entity.TaskStatus{
+	entity.TaskStatusWaiting, entity.TaskStatusPending, entity.TaskStatusProcessing, entity.TaskStatusCompleted,
+	entity.TaskStatusFailed, entity.TaskStatusDeadLettered, entity.TaskStatusExpired,
+}
+
+func (r *taskRepository) StatsByGroup(ctx context.Context, group repository.StatsGroup, since time.Time, limit int) ([]*repository.GroupStats, error) {
+	var key, from string
+	switch group {
+	case repository.StatsGroupName:
+		key, from = "name", r.table
+	case repository.StatsGroupTag:
+		// Each tag of the JSON array becomes a row of its own
+		key = "jt.tag"
+		from = r.table + ` t, JSON_TABLE(t.tags, '$[*]' COLUMNS (tag VARCHAR(255) PATH '$')) jt`
+	default:
+		return nil, fmt.Errorf("unsupported stats group %q", group)
+	}
+
+	var args []interface{}
+	columns := make([]string, len(groupStatuses))
+	for i, status := range groupStatuses {
+		columns[i] = "COUNT(CASE WHEN status = ? THEN 1 END)"
+		args = append(args, status)
+	}
+	query := `
+		SELECT ` + key + ` AS group_key, COUNT(*), ` + strings.Join(columns, ", ") + `,
+			COALESCE(AVG(CASE WHEN status IN ('completed', 'dead_lettered') THEN callback_attempts END), 0)
+		FROM ` + from + ` WHERE deleted_at IS NULL AND created_at >= ?`
+	args = append(args, since)
+	query, args = scopeToTenant(ctx, query, args)
+	query += " GROUP BY group_key ORDER BY COUNT(*) DESC, group_key"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*repository.GroupStats
+	counts := make([]int64, len(groupStatuses))
+	for rows.Next() {
+		stats := &repository.GroupStats{ByStatus: make(map[entity.TaskStatus]int64)}
+		dest := []interface{}{&stats.Key, &stats.Total}
+		for i := range counts {
+			dest = append(dest, &counts[i])
+		}
+		dest = append(dest, &stats.AvgCallbackAttempts)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, count := range counts {
+			if count > 0 {
+				stats.ByStatus[groupStatuses[i]] = count
+			}
+		}
+		result = append(result, stats)
+	}
+
+	return result, rows.Err()
+}
+
+// callbackHostExpr extracts entity.CallbackHost from callback_url: what follows the scheme, up to
+// the path, query or fragment, without user info or port. Bracketed IPv6 hosts don't match
+const callbackHostExpr = `LOWER(SUBSTRING_INDEX(SUBSTRING_INDEX(SUBSTRING_INDEX(SUBSTRING_INDEX(SUBSTRING_INDEX(
+	SUBSTRING_INDEX(callback_url, '://', -1), '/', 1), '?', 1), '#', 1), '@', -1), ':', 1))`
+
+func (r *taskRepository) CountByCallbackHost(ctx context.Context, hosts []string) (map[string]int64, error) {
+	result := make(map[string]int64)
+	if len(hosts) == 0 {
+		return result, nil
+	}
+	inner := `SELECT ` + callbackHostExpr + ` AS host FROM ` + r.table + `
+		WHERE deleted_at IS NULL AND status IN (?, ?)`
+	args := []interface{}{entity.TaskStatusPending, entity.TaskStatusFailed}
+	inner, args = scopeToTenant(ctx, inner, args)
+
+	query, inArgs, err := sqlx.In(`SELECT h.host, COUNT(*) FROM (`+inner+`) h WHERE h.host IN (?) GROUP BY h.host`, hosts)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, query, append(args, inArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var host string
+		var count int64
+		if err := rows.Scan(&host, &count); err != nil {
+			return nil, err
+		}
+		result[host] = count
+	}
+	return result, rows.Err()
+}
+
+func (r *taskRepository) CleanupExpiredData(ctx context.Context, policy repository.RetentionPolicy) (*repository.CleanupResult, error) {
+	return r.CleanupTasks(ctx, policy.Cleanup(time.Now().UTC()))
+}
+
+func (r *taskRepository) CleanupTasks(ctx context.Context, cleanup repository.Cleanup) (*repository.CleanupResult, error) {
+	result := &repository.CleanupResult{ByStatus: make(map[entity.TaskStatus]int64)}
+	if cleanup.DryRun {
+		return result, r.countExpired(ctx, cleanup.Cutoffs, result)
+	}
+
+	for _, status := range repository.CleanableStatuses {
+		cutoff, ok := cleanup.Cutoffs[status]
+		if !ok {
+			continue
+		}
+		if err := r.cleanupStatus(ctx, status, cutoff.UTC(), cleanup, result); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// countExpired counts per status the tasks a cleanup with the given cutoffs would delete
+func (r *taskRepository) countExpired(ctx context.Context, cutoffs map[entity.TaskStatus]time.Time, result *repository.CleanupResult) error {
+	var conditions []string
+	var args []interface{}
+	for _, status := range repository.CleanableStatuses {
+		if cutoff, ok := cutoffs[status]; ok {
+			conditions = append(conditions, "(status = ? AND COALESCE(completed_at, created_at) < ?)")
+			args = append(args, status, cutoff.UTC())
+		}
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT status, COUNT(*) FROM `+r.table+`
+		WHERE `+strings.Join(conditions, " OR ")+`
+		GROUP BY status
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to count expired tasks: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status entity.TaskStatus
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return err
+		}
+		result.ByStatus[status] = count
+	}
+	return rows.Err()
+}
+
+// cleanupStatus removes tasks with the given status that finished before cutoff
+// Rows are processed in batches, each in its own transaction to avoid long-running locks
+func (r *taskRepository) cleanupStatus(ctx context.Context, status entity.TaskStatus, cutoff time.Time, cleanup repository.Cleanup, result *repository.CleanupResult) error {
+	const batchSize = 1000
 
 	for {
-		query := `
-			DELETE tq
-			FROM task_queue tq
-			INNER JOIN (
-				SELECT id FROM task_queue
-				WHERE status IN ('completed', 'dead_lettered')
-				  AND completed_at < DATE_SUB(UTC_TIMESTAMP(), INTERVAL 30 DAY)
-				LIMIT ?
-			) AS tmp ON tq.id = tmp.id
-		`
-
-		result, err := r.db.ExecContext(ctx, query, batchSize)
+		tx, err := r.db.BeginTxx(ctx, nil)
 		if err != nil {
-			return totalDeleted, err
+			return err
 		}
 
-		rowsAffected, err := result.RowsAffected()
+		ids, err := r.selectExpired(ctx, tx, status, cutoff, batchSize, cleanup.BeforeDelete)
+		if err != nil || len(ids) == 0 {
+			tx.Rollback()
+			return err
+		}
+
+		if cleanup.Archive {
+			query, args, err := sqlx.In(`
+				INSERT INTO `+r.archiveTable+`
+				SELECT tq.*, UTC_TIMESTAMP() FROM `+r.table+` tq WHERE tq.id IN (?)
+			`, ids)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			archived, err := tx.ExecContext(ctx, query, args...)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to archive tasks: %w", err)
+			}
+			count, _ := archived.RowsAffected()
+			result.Archived += count
+		}
+
+		query, args, err := sqlx.In("DELETE FROM "+r.table+" WHERE id IN (?)", ids)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		deleted, err := tx.ExecContext(ctx, query, args...)
 		if err != nil {
-			return totalDeleted, err
+			tx.Rollback()
+			return err
 		}
 
-		totalDeleted += rowsAffected
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		count, _ := deleted.RowsAffected()
+		result.Deleted += count
+		result.ByStatus[status] += count
 
-		// If we deleted fewer than the batch size, we're done
-		if rowsAffected < int64(batchSize) {
-			break
+		// If we processed fewer than the batch size, we're done
+		if len(ids) < batchSize {
+			return nil
 		}
 	}
+}
+
+// selectExpired locks and returns the IDs of up to limit tasks with the given status that finished
+// before cutoff; with beforeDelete, the whole rows are read and passed to it first
+func (r *taskRepository) selectExpired(ctx context.Context, tx *sqlx.Tx, status entity.TaskStatus, cutoff time.Time, limit int,
+	beforeDelete func(context.Context, []*entity.Task) error) ([]string, error) {
+	// Dead-lettered tasks have no completed_at, so fall back to their creation time
+	const condition = ` WHERE status = ? AND COALESCE(completed_at, created_at) < ? LIMIT ? FOR UPDATE`
+
+	var ids []string
+	if beforeDelete == nil {
+		err := tx.SelectContext(ctx, &ids, `SELECT id FROM `+r.table+condition, status, cutoff, limit)
+		return ids, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+listColumns+` FROM `+r.table+condition, status, cutoff, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tasks, err := scanListedTasks(rows)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+
+	if err := beforeDelete(ctx, tasks); err != nil {
+		return nil, fmt.Errorf("failed to archive tasks before deleting them: %w", err)
+	}
+	return ids, nil
+}
+
+// scopeToTenant appends a tenant condition to a query ending in a WHERE clause
+// if the context is scoped to a tenant
+func scopeToTenant(ctx context.Context, query string, args []interface{}) (string, []interface{}) {
+	if tenantID, ok := domain.TenantFromContext(ctx); ok {
+		query += tenantCondition
+		args = append(args, tenantID)
+	}
+	return query, args
+}
+
+// tenantCondition is the condition scopeToTenant adds
+const tenantCondition = " AND tenant_id = ?"
+
+// claimCondition is the condition UpdateIfStatus adds for the given number of statuses
+func claimCondition(statuses int) string {
+	return " AND deleted_at IS NULL AND status IN (?" + strings.Repeat(", ?", statuses-1) + ")"
+}
+
+// likeEscaper escapes LIKE wildcards so a prefix is matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
 
-	return totalDeleted, nil
+// escapeLike returns s with LIKE wildcards escaped
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
 }
@@ -0,0 +1,493 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/migrations"
+)
+
+// TestTaskRepositoryCRUD runs against a real database when LATER_TEST_MYSQL_DSN is set
+func TestTaskRepositoryCRUD(t *testing.T) {
+	db := testDB(t)
+
+	for _, prefix := range []string{"", "later_test_"} {
+		t.Run("prefix="+prefix, func(t *testing.T) {
+			ctx := context.Background()
+			migrator, err := NewMigrator(db, migrations.MySQL, prefix)
+			require.NoError(t, err)
+			_, err = migrator.Up(ctx)
+			require.NoError(t, err)
+
+			repo := NewTaskRepositoryWithPrefix(db, prefix)
+			name := "crud-" + uuid.New().String()
+			task := entity.NewTask(name, []byte(`{"k":"v"}`), "https://example.com/callback", time.Now().UTC(), 0)
+			requestID := "req-" + name
+			task.RequestID = &requestID
+
+			require.NoError(t, repo.Create(ctx, task))
+			assert.ErrorIs(t, repo.Create(ctx, task), domain.ErrConflict)
+
+			found, err := repo.FindByID(ctx, task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, name, found.Name)
+			if assert.NotNil(t, found.RequestID) {
+				assert.Equal(t, requestID, *found.RequestID)
+			}
+
+			found.Status = entity.TaskStatusFailed
+			dispatch, callback := int64(1500), int64(250)
+			found.DispatchLatencyMs, found.CallbackDurationMs = &dispatch, &callback
+			found.RecordError(errors.New("connection refused"), time.Now())
+			require.NoError(t, repo.Update(ctx, found))
+			updated, err := repo.FindByID(ctx, task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, &dispatch, updated.DispatchLatencyMs)
+			assert.Equal(t, &callback, updated.CallbackDurationMs)
+			if assert.Len(t, updated.ErrorHistory, 1) {
+				assert.Equal(t, "connection refused", updated.ErrorHistory[0].Error)
+			}
+
+			tasks, total, err := repo.List(ctx, repository.TaskFilter{Name: name, Page: 1, Limit: 10})
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), total)
+			require.Len(t, tasks, 1)
+			assert.Equal(t, entity.TaskStatusFailed, tasks[0].Status)
+
+			compressed := entity.NewTask(name+"-gzip", []byte(`{"k":"v"}`), "https://example.com/callback", time.Now().UTC(), 0)
+			compressed.PayloadEncoding = entity.PayloadEncodingGzip
+			require.NoError(t, repo.Create(ctx, compressed))
+			found, err = repo.FindByID(ctx, compressed.ID)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"k":"v"}`, string(found.Payload), "payloads are decompressed when read")
+
+			child := entity.NewTask(name+"-child", []byte(`{}`), "https://example.com/callback", time.Now().UTC(), 0)
+			child.Status = entity.TaskStatusWaiting
+			child.DependsOn = &compressed.ID
+			require.NoError(t, repo.Create(ctx, child))
+			children, _, err := repo.List(ctx, repository.TaskFilter{DependsOn: &compressed.ID, Page: 1, Limit: 10})
+			require.NoError(t, err)
+			require.Len(t, children, 1)
+			assert.Equal(t, entity.TaskStatusWaiting, children[0].Status)
+			assert.Equal(t, entity.DependencyFailureDeadLetter, children[0].DependencyFailurePolicy)
+
+			require.NoError(t, repo.SoftDelete(ctx, task.ID, "test"))
+			_, total, err = repo.List(ctx, repository.TaskFilter{Name: name, Page: 1, Limit: 10})
+			require.NoError(t, err)
+			assert.Equal(t, int64(0), total)
+		})
+	}
+}
+
+func TestIsDuplicateEntry(t *testing.T) {
+	assert.True(t, isDuplicateEntry(fmt.Errorf("insert: %w", &mysqldriver.MySQLError{Number: 1062})))
+	assert.False(t, isDuplicateEntry(&mysqldriver.MySQLError{Number: 1452}))
+	assert.False(t, isDuplicateEntry(errors.New("connection refused")))
+	assert.False(t, isDuplicateEntry(nil))
+}
+
+func TestBulkWhere(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	where, args, err := bulkWhere(context.Background(), repository.BulkFilter{
+		IDs:      []string{"a", "b"},
+		Statuses: []entity.TaskStatus{entity.TaskStatusPending, entity.TaskStatusFailed},
+		Tag:      "billing",
+		DateFrom: &from,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "WHERE deleted_at IS NULL AND status IN (?, ?) AND id IN (?, ?) AND JSON_CONTAINS(tags, JSON_QUOTE(?)) AND created_at >= ?", where)
+	assert.Equal(t, []interface{}{"pending", "failed", "a", "b", "billing", from}, args)
+
+	_, _, err = bulkWhere(context.Background(), repository.BulkFilter{Statuses: []entity.TaskStatus{entity.TaskStatusFailed}})
+	assert.Error(t, err, "a limit is required")
+}
+
+func TestListWhereScheduledRange(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	status := entity.TaskStatusPending
+	where, args := listWhere(context.Background(), repository.TaskFilter{
+		Status:        &status,
+		DateFrom:      &from,
+		ScheduledFrom: &from,
+		ScheduledTo:   &to,
+	})
+	assert.Equal(t, "WHERE deleted_at IS NULL AND status = ? AND created_at >= ? AND scheduled_at >= ? AND scheduled_at <= ?", where)
+	assert.Equal(t, []interface{}{status, from, from, to}, args)
+}
+
+func TestListOrderBy(t *testing.T) {
+	for _, tt := range []struct {
+		sortBy, sortOrder, orderBy string
+	}{
+		{"", "", "created_at DESC"},
+		{"scheduled_at", "asc", "scheduled_at ASC"},
+		{"priority", "DESC", "priority DESC"},
+		{"created_at", "", "created_at DESC"},
+	} {
+		orderBy, err := listOrderBy(repository.TaskFilter{SortBy: tt.sortBy, SortOrder: tt.sortOrder})
+		require.NoError(t, err)
+		assert.Equal(t, tt.orderBy, orderBy)
+	}
+
+	for _, filter := range []repository.TaskFilter{
+		{SortBy: "(SELECT SLEEP(5))"},
+		{SortBy: "name"},
+		{SortBy: "created_at", SortOrder: "DESC, (SELECT 1)"},
+	} {
+		_, err := listOrderBy(filter)
+		assert.ErrorIs(t, err, domain.ErrBadParamInput, "%+v", filter)
+	}
+}
+
+// TestTaskRepositoryBulk runs against a real database when LATER_TEST_MYSQL_DSN is set
+func TestTaskRepositoryBulk(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	migrator, err := NewMigrator(db, migrations.MySQL, "")
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	repo := NewTaskRepository(db)
+	tag := "bulk-" + uuid.New().String()
+	for i := 0; i < 5; i++ {
+		task := entity.NewTask("bulk", []byte(`{}`), "https://example.com/callback", time.Now().UTC(), 0)
+		task.Status = entity.TaskStatusFailed
+		task.RetryCount = 3
+		task.Tags = []string{tag}
+		require.NoError(t, repo.Create(ctx, task))
+	}
+
+	failed := repository.BulkFilter{Statuses: []entity.TaskStatus{entity.TaskStatusFailed}, Tag: tag, Limit: 3}
+	count, err := repo.CountBulk(ctx, failed)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count, "capped at the limit")
+
+	retried, err := repo.BulkRetry(ctx, failed)
+	require.NoError(t, err)
+	require.Len(t, retried, 3)
+	task, err := repo.FindByID(ctx, retried[0])
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusPending, task.Status)
+	assert.Equal(t, 0, task.RetryCount)
+
+	failed.Limit = 10
+	deleted, err := repo.BulkSoftDelete(ctx, failed, "test")
+	require.NoError(t, err)
+	assert.Len(t, deleted, 2, "only the tasks still failed")
+	count, err = repo.CountBulk(ctx, failed)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+// TestSoftDeletedTasksAreNotDue runs against a real database when LATER_TEST_MYSQL_DSN is set
+func TestSoftDeletedTasksAreNotDue(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	migrator, err := NewMigrator(db, migrations.MySQL, "")
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	// Top priority and long overdue, so both sort ahead of anything else in the table
+	repo := NewTaskRepository(db)
+	overdue := time.Now().UTC().Add(-365 * 24 * time.Hour)
+	kept := entity.NewTask("due-kept", []byte(`{}`), "https://example.com/callback", overdue, 0)
+	kept.Priority = 10
+	deleted := entity.NewTask("due-deleted", []byte(`{}`), "https://example.com/callback", overdue, 0)
+	deleted.Priority = 10
+	require.NoError(t, repo.Create(ctx, kept))
+	require.NoError(t, repo.Create(ctx, deleted))
+	t.Cleanup(func() { repo.SoftDelete(ctx, kept.ID, "test") })
+
+	require.NoError(t, repo.SoftDelete(ctx, deleted.ID, "test"))
+
+	due, err := repo.FindDueTasks(ctx, -1, 100, nil)
+	require.NoError(t, err)
+	ids := make([]string, 0, len(due))
+	for _, task := range due {
+		ids = append(ids, task.ID)
+	}
+	assert.Contains(t, ids, kept.ID)
+	assert.NotContains(t, ids, deleted.ID, "a cancelled task must never be delivered")
+}
+
+// TestFindDueTasksPlan checks that both due task queries read idx_tasks_due in ORDER BY order
+// Before migration 020 and the split into two queries, no index matched ORDER BY priority DESC,
+// scheduled_at past the (? = -1 OR priority > ?) condition, so EXPLAIN reported "Using filesort"
+// and every due pending row was sorted to pick the first few. The plan is logged with -v
+func TestFindDueTasksPlan(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	prefix := "plan_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12] + "_"
+	migrator, err := NewMigrator(db, migrations.MySQL, prefix)
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		for _, table := range []string{TaskArchiveTable, TaskQueueTable, SchedulerLockTable, TaskEventsTable, HostPausesTable, SchemaMigrationsTable} {
+			db.ExecContext(ctx, "DROP TABLE IF EXISTS "+prefix+table)
+		}
+	})
+
+	// Enough rows across statuses and priorities for the optimizer to prefer an index
+	repo := NewTaskRepositoryWithPrefix(db, prefix).(*taskRepository)
+	statuses := []entity.TaskStatus{entity.TaskStatusPending, entity.TaskStatusCompleted, entity.TaskStatusFailed}
+	for batch := 0; batch < 10; batch++ {
+		tasks := make([]*entity.Task, 0, 500)
+		for i := 0; i < 500; i++ {
+			task := entity.NewTask("plan", []byte(`{}`), "https://example.com/callback", time.Now().Add(time.Duration(i-250)*time.Minute), 0)
+			task.Priority = i % 10
+			task.Status = statuses[(batch*500+i)%len(statuses)]
+			tasks = append(tasks, task)
+		}
+		require.NoError(t, repo.CreateBatch(ctx, tasks))
+	}
+	_, err = db.ExecContext(ctx, "ANALYZE TABLE "+repo.table)
+	require.NoError(t, err)
+
+	explain := func(query string, args ...interface{}) map[string]string {
+		rows, err := db.QueryContext(ctx, "EXPLAIN "+query, args...)
+		require.NoError(t, err)
+		defer rows.Close()
+		columns, err := rows.Columns()
+		require.NoError(t, err)
+		require.True(t, rows.Next())
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		require.NoError(t, rows.Scan(dest...))
+		plan := make(map[string]string, len(columns))
+		for i, column := range columns {
+			plan[column] = values[i].String
+		}
+		t.Logf("EXPLAIN: %v", plan)
+		return plan
+	}
+
+	for name, plan := range map[string]map[string]string{
+		"any priority":   explain(repo.findDueQuery, 10),
+		"above priority": explain(repo.findDueAboveQuery, 5, 10),
+	} {
+		assert.Equal(t, "idx_tasks_due", plan["key"], name)
+		assert.NotContains(t, plan["Extra"], "Using filesort", name)
+	}
+}
+
+// TestTaskRepositoryStatsSummary runs against a real database when LATER_TEST_MYSQL_DSN is set
+func TestTaskRepositoryStatsSummary(t *testing.T) {
+	db := testDB(t)
+	migrator, err := NewMigrator(db, migrations.MySQL, "")
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	// A fresh tenant keeps the fixtures apart from other rows in the table
+	ctx := domain.WithTenant(context.Background(), "stats-"+uuid.New().String())
+	repo := NewTaskRepository(db)
+	now := time.Now().UTC().Truncate(time.Second)
+	seed := func(status entity.TaskStatus, age, latency time.Duration, attempts int) {
+		created := now.Add(-age)
+		task := entity.NewTask("stats", []byte(`{}`), "https://example.com/callback", created, 0)
+		task.TenantID, _ = domain.TenantFromContext(ctx)
+		task.CreatedAt = created
+		require.NoError(t, repo.Create(ctx, task))
+
+		task.Status = status
+		task.StartedAt = &created
+		task.CallbackAttempts = attempts
+		if status == entity.TaskStatusCompleted {
+			completed := created.Add(latency)
+			task.CompletedAt = &completed
+			task.LastCallbackAt = &completed
+			dispatch, callback := latency.Milliseconds()/10, latency.Milliseconds()/100
+			task.DispatchLatencyMs, task.CallbackDurationMs = &dispatch, &callback
+		}
+		require.NoError(t, repo.Update(ctx, task))
+	}
+
+	// Within the hour: ten completed with latencies of 1s to 10s, two dead-lettered, one
+	// awaiting retry and one pending; older activity is outside the window
+	for i := 1; i <= 10; i++ {
+		seed(entity.TaskStatusCompleted, 30*time.Minute, time.Duration(i)*time.Second, 1)
+	}
+	seed(entity.TaskStatusDeadLettered, 20*time.Minute, 0, 4)
+	seed(entity.TaskStatusDeadLettered, 20*time.Minute, 0, 4)
+	seed(entity.TaskStatusFailed, 10*time.Minute, 0, 2)
+	seed(entity.TaskStatusPending, time.Minute, 0, 0)
+	seed(entity.TaskStatusCompleted, 3*time.Hour, time.Hour, 9)
+	seed(entity.TaskStatusDeadLettered, 3*time.Hour, 0, 9)
+
+	summary, err := repo.StatsSummary(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, &repository.StatsSummary{
+		Created:                14,
+		Completed:              10,
+		Failed:                 1,
+		DeadLettered:           2,
+		AvgCallbackAttempts:    18.0 / 12,
+		P50CompletionLatencyMs: 5000,
+		P95CompletionLatencyMs: 10000,
+		P50DispatchLatencyMs:   500,
+		P95DispatchLatencyMs:   1000,
+		P50CallbackDurationMs:  50,
+		P95CallbackDurationMs:  100,
+	}, summary)
+
+	empty, err := repo.StatsSummary(domain.WithTenant(context.Background(), "stats-none"), now)
+	require.NoError(t, err)
+	assert.Equal(t, &repository.StatsSummary{}, empty)
+}
+
+func TestTaskRepositoryListStream(t *testing.T) {
+	db := testDB(t)
+	migrator, err := NewMigrator(db, migrations.MySQL, "")
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	ctx := domain.WithTenant(context.Background(), "export-"+uuid.New().String())
+	repo := NewTaskRepository(db)
+	var ids []string
+	for i := 0; i < 5; i++ {
+		task := entity.NewTask("export", []byte(`{}`), "https://example.com/callback", time.Now(), 0)
+		task.TenantID, _ = domain.TenantFromContext(ctx)
+		task.CreatedAt = time.Now().UTC().Truncate(time.Second).Add(time.Duration(i) * time.Second)
+		require.NoError(t, repo.Create(ctx, task))
+		ids = append(ids, task.ID)
+	}
+	require.NoError(t, repo.SoftDelete(ctx, ids[4], "test"))
+
+	// Rows are visited oldest first with the requested sort, skipping deleted tasks
+	var exported []string
+	filter := repository.TaskFilter{SortBy: "created_at", SortOrder: "asc"}
+	require.NoError(t, repo.ListStream(ctx, filter, func(task *entity.Task) error {
+		exported = append(exported, task.ID)
+		return nil
+	}))
+	assert.Equal(t, ids[:4], exported)
+
+	// The limit caps the rows, and an error from the callback stops the scan
+	exported = nil
+	filter.Limit = 2
+	require.NoError(t, repo.ListStream(ctx, filter, func(task *entity.Task) error {
+		exported = append(exported, task.ID)
+		return nil
+	}))
+	assert.Equal(t, ids[:2], exported)
+
+	stop := errors.New("stop")
+	visited := 0
+	err = repo.ListStream(ctx, repository.TaskFilter{}, func(task *entity.Task) error {
+		visited++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, visited)
+
+	// A page skips the earlier pages, and cancelling the context stops the scan
+	exported = nil
+	filter.Page = 2
+	require.NoError(t, repo.ListStream(ctx, filter, func(task *entity.Task) error {
+		exported = append(exported, task.ID)
+		return nil
+	}))
+	assert.Equal(t, ids[2:4], exported)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	visited = 0
+	err = repo.ListStream(cancelCtx, repository.TaskFilter{}, func(task *entity.Task) error {
+		visited++
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, visited)
+}
+
+func TestTaskRepositoryCreateBatch(t *testing.T) {
+	db := testDB(t)
+	migrator, err := NewMigrator(db, migrations.MySQL, "")
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	repo := NewTaskRepository(db)
+	var tasks []*entity.Task
+	for i := 0; i < 3; i++ {
+		tasks = append(tasks, entity.NewTask("import", []byte(`{"n":1}`), "https://example.com/callback", time.Now(), 0))
+	}
+	require.NoError(t, repo.CreateBatch(ctx, tasks))
+	require.NoError(t, repo.SoftDelete(ctx, tasks[2].ID, "test"))
+
+	stored, err := repo.FindByID(ctx, tasks[1].ID)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"n":1}`, string(stored.Payload))
+
+	// Soft-deleted tasks still hold their IDs
+	missing := uuid.New().String()
+	existing, err := repo.ExistingIDs(ctx, []string{tasks[0].ID, tasks[2].ID, missing})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{tasks[0].ID, tasks[2].ID}, existing)
+
+	// A batch with a taken ID stores nothing
+	fresh := entity.NewTask("import", []byte(`{}`), "https://example.com/callback", time.Now(), 0)
+	assert.ErrorIs(t, repo.CreateBatch(ctx, []*entity.Task{fresh, tasks[0]}), domain.ErrConflict)
+	_, err = repo.FindByID(ctx, fresh.ID)
+	assert.Error(t, err)
+}
+
+func TestTaskRepositoryUpdateIfStatus(t *testing.T) {
+	db := testDB(t)
+	migrator, err := NewMigrator(db, migrations.MySQL, "")
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	repo := NewTaskRepository(db)
+	task := entity.NewTask("claim", []byte(`{}`), "https://example.com/callback", time.Now(), 0)
+	require.NoError(t, repo.Create(ctx, task))
+
+	// Only one of two claims of the same pending task wins
+	first, second := *task, *task
+	first.MarkAsProcessing("worker-1", time.Now())
+	second.MarkAsProcessing("worker-2", time.Now())
+	claimed, err := repo.UpdateIfStatus(ctx, &first, entity.TaskStatusPending)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = repo.UpdateIfStatus(ctx, &second, entity.TaskStatusPending, entity.TaskStatusFailed)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	stored, err := repo.FindByID(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusProcessing, stored.Status)
+
+	// Deleted tasks can't be claimed
+	deleted := entity.NewTask("claim", []byte(`{}`), "https://example.com/callback", time.Now(), 0)
+	require.NoError(t, repo.Create(ctx, deleted))
+	require.NoError(t, repo.SoftDelete(ctx, deleted.ID, "test"))
+	deleted.MarkAsProcessing("worker-1", time.Now())
+	claimed, err = repo.UpdateIfStatus(ctx, deleted, entity.TaskStatusPending)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
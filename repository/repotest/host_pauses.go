@@ -0,0 +1,56 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+)
+
+// TestHostPauseRepository runs the conformance suite against the host pause repository newRepo
+// creates, which must start empty
+func TestHostPauseRepository(t *testing.T, newRepo func(t *testing.T) repository.HostPauseRepository) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	start := now()
+
+	pauses, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pauses)
+
+	until := start.Add(time.Hour)
+	require.NoError(t, repo.Pause(ctx, &entity.HostPause{Host: "partner.example.com", Until: &until, CreatedAt: start}))
+	require.NoError(t, repo.Pause(ctx, &entity.HostPause{Host: "api.example.com", CreatedAt: start}))
+
+	pauses, err = repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, pauses, 2)
+	assert.Equal(t, "api.example.com", pauses[0].Host)
+	assert.Nil(t, pauses[0].Until)
+	assert.Equal(t, "partner.example.com", pauses[1].Host)
+	if assert.NotNil(t, pauses[1].Until) {
+		assert.True(t, until.Equal(*pauses[1].Until), "until = %v", pauses[1].Until)
+	}
+	assert.True(t, start.Equal(pauses[1].CreatedAt))
+
+	// Pausing a paused host replaces its pause
+	require.NoError(t, repo.Pause(ctx, &entity.HostPause{Host: "partner.example.com", CreatedAt: start.Add(time.Minute)}))
+	pauses, err = repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, pauses, 2)
+	assert.Nil(t, pauses[1].Until)
+
+	require.NoError(t, repo.Resume(ctx, "api.example.com"))
+	assert.True(t, errors.Is(repo.Resume(ctx, "api.example.com"), domain.ErrNotFound))
+	pauses, err = repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, pauses, 1)
+	assert.Equal(t, "partner.example.com", pauses[0].Host)
+}
@@ -0,0 +1,97 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+)
+
+// TestTaskEventRepository runs the conformance suite against the event repository newRepo
+// creates, which must start empty
+func TestTaskEventRepository(t *testing.T, newRepo func(t *testing.T) repository.TaskEventRepository) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	start := now()
+
+	last, err := repo.LastID(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, last)
+
+	record := func(taskID, tenantID, eventType string, to entity.TaskStatus, at time.Time) *entity.TaskEvent {
+		t.Helper()
+		event := &entity.TaskEvent{
+			TaskID:    taskID,
+			TenantID:  tenantID,
+			Type:      eventType,
+			ToStatus:  to,
+			Metadata:  []byte(`{"name":"send_email"}`),
+			CreatedAt: at,
+			Origin:    "replica-1",
+		}
+		require.NoError(t, repo.Append(ctx, event))
+		return event
+	}
+
+	created := record("a", "", "task_created", entity.TaskStatusPending, start.Add(-2*time.Hour))
+	record("b", "acme", "task_created", entity.TaskStatusPending, start.Add(-time.Hour))
+	processing := record("a", "", "task_updated", entity.TaskStatusProcessing, start)
+	bulk := record("", "", "tasks_bulk_retried", entity.TaskStatusPending, start)
+	completed := record("a", "", "task_completed", entity.TaskStatusCompleted, start)
+
+	assert.Greater(t, processing.ID, created.ID, "IDs increase")
+	assert.Empty(t, created.FromStatus, "a task's first event has no previous status")
+	assert.Empty(t, bulk.FromStatus)
+
+	timeline, err := repo.ListByTask(ctx, "a", 10)
+	require.NoError(t, err)
+	require.Len(t, timeline, 3)
+	assert.Equal(t, []entity.TaskStatus{"", entity.TaskStatusPending, entity.TaskStatusProcessing},
+		[]entity.TaskStatus{timeline[0].FromStatus, timeline[1].FromStatus, timeline[2].FromStatus})
+	assert.Equal(t, completed.ID, timeline[2].ID)
+	assert.Equal(t, "task_completed", timeline[2].Type)
+	assert.JSONEq(t, `{"name":"send_email"}`, string(timeline[2].Metadata))
+	assert.True(t, start.Equal(timeline[2].CreatedAt))
+	assert.Equal(t, "replica-1", timeline[2].Origin)
+
+	last, err = repo.LastID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, completed.ID, last)
+
+	limited, err := repo.ListByTask(ctx, "a", 2)
+	require.NoError(t, err)
+	assert.Len(t, limited, 2, "limit caps the events, oldest first")
+	assert.Equal(t, created.ID, limited[0].ID)
+
+	batch, err := repo.ListByTasks(ctx, []string{"a", "b", "missing"})
+	require.NoError(t, err)
+	require.Len(t, batch, 4, "the events of every listed task, oldest first")
+	assert.Equal(t, []string{"a", "b", "a", "a"},
+		[]string{batch[0].TaskID, batch[1].TaskID, batch[2].TaskID, batch[3].TaskID})
+
+	since, err := repo.ListSince(ctx, created.ID, "", 10)
+	require.NoError(t, err)
+	require.Len(t, since, 4, "events of every tenant, bulk summaries included")
+	assert.Equal(t, processing.ID, since[1].ID)
+
+	since, err = repo.ListSince(ctx, created.ID, "acme", 10)
+	require.NoError(t, err)
+	require.Len(t, since, 1)
+	assert.Equal(t, "b", since[0].TaskID)
+
+	since, err = repo.ListSince(ctx, created.ID, "", 2)
+	require.NoError(t, err)
+	assert.Len(t, since, 2)
+
+	deleted, err := repo.DeleteBefore(ctx, start.Add(-30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	timeline, err = repo.ListByTask(ctx, "a", 10)
+	require.NoError(t, err)
+	assert.Len(t, timeline, 2)
+}
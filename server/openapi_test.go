@@ -0,0 +1,306 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/configs"
+	"github.com/usual2970/later/delivery/rest/openapi"
+)
+
+func TestOpenAPISpecMatchesResponses(t *testing.T) {
+	s, _ := newTestServer(t, configs.ServerConfig{})
+	spec := loadSpec(t)
+	scheduled := time.Now().Add(time.Hour).UTC().Format("2006-01-02 15:04")
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		path   string // Path template in the spec
+		status int
+	}{
+		{"health", http.MethodGet, "/health", "", "/health", http.StatusOK},
+		{"liveness", http.MethodGet, "/healthz", "", "/healthz", http.StatusOK},
+		{"readiness before start", http.MethodGet, "/readyz", "", "/readyz", http.StatusServiceUnavailable},
+		{"spec", http.MethodGet, "/openapi.json", "", "/openapi.json", http.StatusOK},
+		{"create", http.MethodPost, "/api/v1/tasks", `{"name":"send_email","payload":{"to":"user@example.com"},"callback_url":"https://example.com/callback","scheduled_for":"` + scheduled + `","tags":["email"]}`, "/api/v1/tasks", http.StatusAccepted},
+		{"create invalid", http.MethodPost, "/api/v1/tasks", `{"name":"send_email","payload":{},"callback_url":"https://example.com/callback","timeout_seconds":1}`, "/api/v1/tasks", http.StatusBadRequest},
+		{"create malformed", http.MethodPost, "/api/v1/tasks", `{"name":`, "/api/v1/tasks", http.StatusBadRequest},
+		{"validate", http.MethodPost, "/api/v1/tasks/validate", `{"name":"send_email","payload":{"to":"user@example.com"},"callback_url":"https://example.com/callback","scheduled_for":"` + scheduled + `"}`, "/api/v1/tasks/validate", http.StatusOK},
+		{"validate invalid", http.MethodPost, "/api/v1/tasks/validate", `{"name":"send_email","payload":{},"callback_url":"https://example.com/callback","retry_backoff_seconds":86401}`, "/api/v1/tasks/validate", http.StatusBadRequest},
+		{"validate taken ID", http.MethodPost, "/api/v1/tasks/validate", `{"id":"` + pendingTaskID + `","name":"send_email","payload":{},"callback_url":"https://example.com/callback"}`, "/api/v1/tasks/validate", http.StatusConflict},
+		{"list", http.MethodGet, "/api/v1/tasks?page=1&limit=20", "", "/api/v1/tasks", http.StatusOK},
+		{"list invalid", http.MethodGet, "/api/v1/tasks?page=0&limit=20", "", "/api/v1/tasks", http.StatusBadRequest},
+		{"upcoming", http.MethodGet, "/api/v1/tasks/upcoming?within=30m", "", "/api/v1/tasks/upcoming", http.StatusOK},
+		{"upcoming invalid", http.MethodGet, "/api/v1/tasks/upcoming?within=soon", "", "/api/v1/tasks/upcoming", http.StatusBadRequest},
+		{"get", http.MethodGet, "/api/v1/tasks/" + pendingTaskID, "", "/api/v1/tasks/{id}", http.StatusOK},
+		{"get dead-lettered", http.MethodGet, "/api/v1/tasks/" + deadTaskID, "", "/api/v1/tasks/{id}", http.StatusOK},
+		{"get missing", http.MethodGet, "/api/v1/tasks/" + missingTaskID, "", "/api/v1/tasks/{id}", http.StatusNotFound},
+		{"timeline", http.MethodGet, "/api/v1/tasks/" + deadTaskID + "/timeline", "", "/api/v1/tasks/{id}/timeline", http.StatusOK},
+		{"timeline without events", http.MethodGet, "/api/v1/tasks/" + pendingTaskID + "/timeline", "", "/api/v1/tasks/{id}/timeline", http.StatusOK},
+		{"timeline missing", http.MethodGet, "/api/v1/tasks/" + missingTaskID + "/timeline", "", "/api/v1/tasks/{id}/timeline", http.StatusNotFound},
+		{"retry schedule", http.MethodGet, "/api/v1/tasks/" + failedTaskID + "/retry-schedule", "", "/api/v1/tasks/{id}/retry-schedule", http.StatusOK},
+		{"retry schedule finished", http.MethodGet, "/api/v1/tasks/" + deadTaskID + "/retry-schedule", "", "/api/v1/tasks/{id}/retry-schedule", http.StatusOK},
+		{"retry schedule missing", http.MethodGet, "/api/v1/tasks/" + missingTaskID + "/retry-schedule", "", "/api/v1/tasks/{id}/retry-schedule", http.StatusNotFound},
+		{"set tags", http.MethodPut, "/api/v1/tasks/" + pendingTaskID + "/tags", `{"tags":["email","investigating"]}`, "/api/v1/tasks/{id}/tags", http.StatusOK},
+		{"set tags invalid", http.MethodPut, "/api/v1/tasks/" + pendingTaskID + "/tags", `{"tags":["a,b"]}`, "/api/v1/tasks/{id}/tags", http.StatusBadRequest},
+		{"edit tags", http.MethodPatch, "/api/v1/tasks/" + pendingTaskID + "/tags", `{"remove":["investigating"]}`, "/api/v1/tasks/{id}/tags", http.StatusOK},
+		{"edit tags missing", http.MethodPatch, "/api/v1/tasks/" + missingTaskID + "/tags", `{"add":["a"]}`, "/api/v1/tasks/{id}/tags", http.StatusNotFound},
+		{"retry", http.MethodPost, "/api/v1/tasks/" + failedTaskID + "/retry", "", "/api/v1/tasks/{id}/retry", http.StatusAccepted},
+		{"retry pending", http.MethodPost, "/api/v1/tasks/" + pendingTaskID + "/retry", "", "/api/v1/tasks/{id}/retry", http.StatusBadRequest},
+		{"execute dead-lettered", http.MethodPost, "/api/v1/tasks/" + deadTaskID + "/execute", "", "/api/v1/tasks/{id}/execute", http.StatusBadRequest},
+		{"execute missing", http.MethodPost, "/api/v1/tasks/" + missingTaskID + "/execute", "", "/api/v1/tasks/{id}/execute", http.StatusNotFound},
+		{"resurrect", http.MethodPost, "/api/v1/tasks/" + deadTaskID + "/resurrect", "", "/api/v1/tasks/{id}/resurrect", http.StatusAccepted},
+		{"resurrect missing", http.MethodPost, "/api/v1/tasks/" + missingTaskID + "/resurrect", "", "/api/v1/tasks/{id}/resurrect", http.StatusNotFound},
+		{"delete", http.MethodDelete, "/api/v1/tasks/" + childTaskID, "", "/api/v1/tasks/{id}", http.StatusNoContent},
+		{"delete missing", http.MethodDelete, "/api/v1/tasks/" + missingTaskID, "", "/api/v1/tasks/{id}", http.StatusNotFound},
+		{"import tasks", http.MethodPost, "/api/v1/tasks/import", `{"id":"` + failedTaskID + `","name":"send_email","payload":"{}","callback_url":"https://example.com/callback"}` + "\n" + `{"name":"send_email"}`, "/api/v1/tasks/import", http.StatusOK},
+		{"import nothing", http.MethodPost, "/api/v1/tasks/import", "", "/api/v1/tasks/import", http.StatusBadRequest},
+		{"bulk delete", http.MethodPost, "/api/v1/tasks/bulk/delete", `{"status":"failed","limit":10}`, "/api/v1/tasks/bulk/delete", http.StatusOK},
+		{"bulk delete dry run", http.MethodPost, "/api/v1/tasks/bulk/delete", `{"tag":"email","limit":10,"dry_run":true}`, "/api/v1/tasks/bulk/delete", http.StatusOK},
+		{"bulk retry", http.MethodPost, "/api/v1/tasks/bulk/retry", `{"ids":["` + failedTaskID + `"],"limit":10}`, "/api/v1/tasks/bulk/retry", http.StatusOK},
+		{"bulk retry invalid", http.MethodPost, "/api/v1/tasks/bulk/retry", `{"limit":10}`, "/api/v1/tasks/bulk/retry", http.StatusBadRequest},
+		{"stats", http.MethodGet, "/api/v1/tasks/stats?window=1h", "", "/api/v1/tasks/stats", http.StatusOK},
+		{"stats invalid", http.MethodGet, "/api/v1/tasks/stats?window=2h", "", "/api/v1/tasks/stats", http.StatusBadRequest},
+		{"timeseries", http.MethodGet, "/api/v1/tasks/stats/timeseries?window=24h&bucket=1h", "", "/api/v1/tasks/stats/timeseries", http.StatusOK},
+		{"stats by name", http.MethodGet, "/api/v1/tasks/stats/by-name?window=7d", "", "/api/v1/tasks/stats/by-name", http.StatusOK},
+		{"stats by tag", http.MethodGet, "/api/v1/tasks/stats/by-tag", "", "/api/v1/tasks/stats/by-tag", http.StatusOK},
+		{"stats by tag invalid", http.MethodGet, "/api/v1/tasks/stats/by-tag?window=30d", "", "/api/v1/tasks/stats/by-tag", http.StatusBadRequest},
+		{"test callback", http.MethodPost, "/api/v1/callbacks/test", `{"callback_url":"` + receiver.URL + `","payload":{"order_id":42}}`, "/api/v1/callbacks/test", http.StatusOK},
+		{"test callback invalid", http.MethodPost, "/api/v1/callbacks/test", `{"callback_url":"` + receiver.URL + `","timeout_seconds":1}`, "/api/v1/callbacks/test", http.StatusBadRequest},
+		{"list limits", http.MethodGet, "/api/v1/admin/concurrency-limits", "", "/api/v1/admin/concurrency-limits", http.StatusOK},
+		{"set limit", http.MethodPut, "/api/v1/admin/concurrency-limits/email", `{"max_in_flight":5}`, "/api/v1/admin/concurrency-limits/{key}", http.StatusOK},
+		{"set limit invalid", http.MethodPut, "/api/v1/admin/concurrency-limits/email", `{"max_in_flight":0}`, "/api/v1/admin/concurrency-limits/{key}", http.StatusBadRequest},
+		{"delete limit", http.MethodDelete, "/api/v1/admin/concurrency-limits/email", "", "/api/v1/admin/concurrency-limits/{key}", http.StatusNoContent},
+		{"cleanup", http.MethodPost, "/api/v1/admin/cleanup", `{"older_than":"720h","statuses":["completed","expired"],"dry_run":true}`, "/api/v1/admin/cleanup", http.StatusOK},
+		{"cleanup invalid", http.MethodPost, "/api/v1/admin/cleanup", `{"older_than":"720h","statuses":["pending"]}`, "/api/v1/admin/cleanup", http.StatusBadRequest},
+		{"pause host", http.MethodPost, "/api/v1/admin/hosts/example.com/pause", `{"until":"2099-01-01T00:00:00Z"}`, "/api/v1/admin/hosts/{host}/pause", http.StatusOK},
+		{"pause host invalid", http.MethodPost, "/api/v1/admin/hosts/example.com/pause", `{"until":"2001-01-01T00:00:00Z"}`, "/api/v1/admin/hosts/{host}/pause", http.StatusBadRequest},
+		{"list paused hosts", http.MethodGet, "/api/v1/admin/hosts", "", "/api/v1/admin/hosts", http.StatusOK},
+		{"resume host", http.MethodDelete, "/api/v1/admin/hosts/example.com/pause", "", "/api/v1/admin/hosts/{host}/pause", http.StatusNoContent},
+		{"resume unpaused host", http.MethodDelete, "/api/v1/admin/hosts/example.com/pause", "", "/api/v1/admin/hosts/{host}/pause", http.StatusNotFound},
+		{"delete missing limit", http.MethodDelete, "/api/v1/admin/concurrency-limits/sms", "", "/api/v1/admin/concurrency-limits/{key}", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			s.engine.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+			spec.checkResponse(t, tt.method, tt.path, rec)
+		})
+	}
+}
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	s, _ := newTestServer(t, configs.ServerConfig{})
+	spec := loadSpec(t)
+
+	paths := spec.doc["paths"].(map[string]any)
+	for _, route := range s.engine.Routes() {
+		path := route.Path
+		for _, segment := range strings.Split(path, "/") {
+			if strings.HasPrefix(segment, ":") {
+				path = strings.Replace(path, segment, "{"+segment[1:]+"}", 1)
+			}
+		}
+		item, ok := paths[path].(map[string]any)
+		if assert.True(t, ok, "path %s is missing from the spec", path) {
+			assert.Contains(t, item, strings.ToLower(route.Method), "%s %s is missing from the spec", route.Method, path)
+		}
+	}
+}
+
+func TestSwaggerUIFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		status  int
+	}{
+		{"enabled", true, http.StatusOK},
+		{"disabled", false, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t, configs.ServerConfig{SwaggerUI: tt.enabled})
+
+			rec := httptest.NewRecorder()
+			s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.enabled {
+				assert.Contains(t, rec.Body.String(), `url: "/openapi.json"`)
+			}
+
+			// The spec is served either way
+			rec = httptest.NewRecorder()
+			s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, openapi.Spec, rec.Body.Bytes())
+		})
+	}
+}
+
+// apiSpec checks JSON values against the subset of OpenAPI schemas the spec uses
+type apiSpec struct {
+	doc map[string]any
+}
+
+func loadSpec(t *testing.T) *apiSpec {
+	t.Helper()
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(openapi.Spec, &doc))
+	require.Equal(t, "3.0.3", doc["openapi"])
+	return &apiSpec{doc: doc}
+}
+
+// checkResponse validates a recorded response against the operation's declared responses
+func (s *apiSpec) checkResponse(t *testing.T, method, path string, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	item, ok := s.doc["paths"].(map[string]any)[path].(map[string]any)
+	require.True(t, ok, "path %s is missing from the spec", path)
+	op, ok := item[strings.ToLower(method)].(map[string]any)
+	require.True(t, ok, "%s %s is missing from the spec", method, path)
+	resp, ok := op["responses"].(map[string]any)[fmt.Sprint(rec.Code)].(map[string]any)
+	require.True(t, ok, "%s %s does not document status %d", method, path, rec.Code)
+	resp = s.resolve(resp)
+
+	content, ok := resp["content"].(map[string]any)
+	if !ok {
+		assert.Empty(t, rec.Body.String(), "undocumented response body")
+		return
+	}
+	schema := content["application/json"].(map[string]any)["schema"].(map[string]any)
+
+	var body any
+	decoder := json.NewDecoder(bytes.NewReader(rec.Body.Bytes()))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&body), rec.Body.String())
+	for _, problem := range s.validate(schema, body, "$") {
+		t.Error(problem)
+	}
+}
+
+// resolve follows a local $ref such as #/components/schemas/Task
+func (s *apiSpec) resolve(node map[string]any) map[string]any {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		var target any = s.doc
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			target = target.(map[string]any)[part]
+		}
+		node = target.(map[string]any)
+	}
+}
+
+func (s *apiSpec) validate(schema map[string]any, value any, at string) []string {
+	schema = s.resolve(schema)
+	var problems []string
+	for _, sub := range asSlice(schema["allOf"]) {
+		problems = append(problems, s.validate(sub.(map[string]any), value, at)...)
+	}
+
+	if value == nil {
+		if schema["nullable"] != true && schema["type"] != nil {
+			problems = append(problems, fmt.Sprintf("%s: null is not allowed", at))
+		}
+		return problems
+	}
+
+	if enum := asSlice(schema["enum"]); enum != nil {
+		found := false
+		for _, allowed := range enum {
+			found = found || allowed == value
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: %v is not one of %v", at, value, enum))
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected an object, got %T", at, value))
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range asSlice(schema["required"]) {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required property %s", at, name))
+			}
+		}
+		for name, field := range object {
+			if property, ok := properties[name].(map[string]any); ok {
+				problems = append(problems, s.validate(property, field, at+"."+name)...)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					problems = append(problems, fmt.Sprintf("%s: undocumented property %s", at, name))
+				}
+			case map[string]any:
+				problems = append(problems, s.validate(additional, field, at+"."+name)...)
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected an array, got %T", at, value))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range array {
+				problems = append(problems, s.validate(items, item, fmt.Sprintf("%s[%d]", at, i))...)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected a string, got %T", at, value))
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q is not an RFC 3339 date-time", at, str))
+			}
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if _, err := number.Int64(); !ok || err != nil {
+			problems = append(problems, fmt.Sprintf("%s: expected an integer, got %v", at, value))
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected a number, got %T", at, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected a boolean, got %T", at, value))
+		}
+	}
+	return problems
+}
+
+func asSlice(value any) []any {
+	slice, _ := value.([]any)
+	return slice
+}
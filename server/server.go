@@ -2,13 +2,18 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/usual2970/later/configs"
 	"github.com/usual2970/later/delivery/rest"
 	"github.com/usual2970/later/delivery/rest/middleware"
+	"github.com/usual2970/later/delivery/rest/openapi"
+	"github.com/usual2970/later/delivery/websocket"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,15 +22,31 @@ import (
 type Server struct {
 	engine     *gin.Engine
 	config     configs.ServerConfig
+	auth       configs.AuthConfig
 	handler    *rest.Handler
+	admin      *rest.AdminHandler
+	hub        *websocket.Hub
 	httpServer *http.Server
+
+	createLimiter *middleware.RateLimiter // nil when task creation isn't rate limited
+
+	// HTTPS certificates, nil when serving plain HTTP; watched for changes until shutdown
+	certs     *certReloader
+	stopWatch chan struct{}
+	stopOnce  sync.Once
+
+	// Readiness: ready is set once background processing has started and cleared on shutdown;
+	// readinessCheck verifies dependencies such as the database on every probe
+	ready          atomic.Bool
+	readinessCheck func(ctx context.Context) error
 }
 
 // NewServer creates a new HTTP server
-func NewServer(cfg configs.ServerConfig, h *rest.Handler) *Server {
+func NewServer(cfg configs.ServerConfig, authCfg configs.AuthConfig, h *rest.Handler, admin *rest.AdminHandler, hub *websocket.Hub) *Server {
 	engine := gin.New()
 
-	// Add middleware
+	// Add middleware; request IDs come first so every later log line can carry them
+	engine.Use(middleware.RequestID())
 	engine.Use(middleware.Logger())
 	engine.Use(middleware.Recovery())
 	engine.Use(middleware.CORS())
@@ -33,7 +54,17 @@ func NewServer(cfg configs.ServerConfig, h *rest.Handler) *Server {
 	s := &Server{
 		engine:  engine,
 		config:  cfg,
+		auth:    authCfg,
 		handler: h,
+		admin:   admin,
+		hub:     hub,
+	}
+	if cfg.CreateRateLimit.RPS > 0 {
+		s.createLimiter = middleware.NewRateLimiter(cfg.CreateRateLimit.RPS, cfg.CreateRateLimit.Burst)
+	}
+	if cfg.TLS.Enabled() {
+		s.certs = newCertReloader(cfg.TLS)
+		s.stopWatch = make(chan struct{})
 	}
 
 	// Register routes
@@ -44,43 +75,177 @@ func NewServer(cfg configs.ServerConfig, h *rest.Handler) *Server {
 
 // registerRoutes sets up all API routes
 func (s *Server) registerRoutes(engine *gin.Engine, h *rest.Handler) {
-	// Health check
-	engine.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "ok",
-			"timestamp": time.Now().Format(time.RFC3339),
-		})
-	})
+	// Liveness only checks that the process is serving; /health is kept for existing probes
+	engine.GET("/health", s.liveness)
+	engine.GET("/healthz", s.liveness)
+	engine.GET("/readyz", s.readiness)
+
+	// API description, open like the health checks
+	engine.GET("/openapi.json", openapi.ServeSpec)
+	if s.config.SwaggerUI {
+		engine.GET("/docs", openapi.ServeUI)
+	}
 
-	// API v1 routes
-	v1 := engine.Group("/api/v1")
+	// API v1 routes (health stays open, everything else honors API keys and tenant scoping)
+	v1 := engine.Group("/api/v1",
+		middleware.APIKeyAuth(s.auth.AcceptedKeys()),
+		middleware.TenantScope(middleware.TenantConfig{
+			Header:     s.auth.TenantHeader,
+			KeyTenants: s.auth.KeyTenants(),
+			AdminKeys:  s.auth.AdminKeys,
+		}),
+		middleware.PayloadAccess(s.auth.PayloadKeys),
+	)
 	{
 		// Task routes
-		v1.POST("/tasks", h.CreateTask)
+		v1.POST("/tasks", middleware.RateLimit(s.createLimiter), h.CreateTask)
+		v1.POST("/tasks/validate", h.ValidateTask)
 		v1.GET("/tasks", h.ListTasks)
+		v1.GET("/tasks/export", h.ExportTasks)
+		v1.GET("/tasks/upcoming", h.UpcomingTasks)
+		v1.POST("/tasks/import", middleware.RateLimit(s.createLimiter), h.ImportTasks)
 		v1.GET("/tasks/:id", h.GetTask)
+		v1.GET("/tasks/:id/timeline", h.GetTaskTimeline)
+		v1.GET("/tasks/:id/retry-schedule", h.GetRetrySchedule)
+		v1.PUT("/tasks/:id/tags", h.SetTaskTags)
+		v1.PATCH("/tasks/:id/tags", h.EditTaskTags)
 		v1.DELETE("/tasks/:id", h.CancelTask)
 		v1.POST("/tasks/:id/retry", h.RetryTask)
 		v1.POST("/tasks/:id/resurrect", h.ResurrectTask)
+		v1.POST("/tasks/:id/execute", h.ExecuteTask)
+		v1.POST("/tasks/:id/abort", h.AbortTask)
+
+		// Bulk operations, scoped to the caller's tenant
+		v1.POST("/tasks/bulk/delete", h.BulkDeleteTasks)
+		v1.POST("/tasks/bulk/retry", h.BulkRetryTasks)
 
 		// Statistics
 		v1.GET("/tasks/stats", h.GetStats)
+		v1.GET("/tasks/stats/timeseries", h.GetStatsTimeSeries)
+		v1.GET("/tasks/stats/by-name", h.GetStatsByName)
+		v1.GET("/tasks/stats/by-tag", h.GetStatsByTag)
+
+		// Real-time task events
+		v1.GET("/tasks/stream", websocket.ServeWS(s.hub))
+
+		// Sample deliveries for receivers to test against
+		v1.POST("/callbacks/test", middleware.RateLimit(s.createLimiter), h.TestCallback)
+	}
+
+	// Runtime settings, restricted to admin keys
+	admin := v1.Group("/admin", middleware.AdminOnly(s.auth.AdminKeys))
+	{
+		admin.GET("/concurrency-limits", s.admin.ListConcurrencyLimits)
+		admin.PUT("/concurrency-limits/:key", s.admin.SetConcurrencyLimit)
+		admin.DELETE("/concurrency-limits/:key", s.admin.DeleteConcurrencyLimit)
+		admin.PUT("/log-level", s.admin.SetLogLevel)
+		admin.POST("/cleanup", s.admin.RunCleanup)
+
+		// Callback hosts held during incidents
+		admin.GET("/hosts", h.ListPausedHosts)
+		admin.POST("/hosts/:host/pause", h.PauseHost)
+		admin.DELETE("/hosts/:host/pause", h.ResumeHost)
+	}
+}
+
+// liveness reports that the process is serving requests
+func (s *Server) liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "ok",
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// readiness reports whether the server should receive traffic: background processing has
+// started, shutdown hasn't begun and the readiness check passes
+func (s *Server) readiness(c *gin.Context) {
+	if !s.ready.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":    "not_ready",
+			"timestamp": time.Now().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if s.readinessCheck != nil {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+		defer cancel()
+		if err := s.readinessCheck(ctx); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":    "not_ready",
+				"timestamp": time.Now().Format(time.RFC3339),
+				"error":     err.Error(),
+			})
+			return
+		}
 	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "ready",
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
 }
 
-// ListenAndServe starts the HTTP server
+// readinessTimeout bounds the dependency check of a single readiness probe
+const readinessTimeout = 2 * time.Second
+
+// SetReadinessCheck sets the dependency check run by /readyz, e.g. a database ping
+// Must be called before the server starts serving
+func (s *Server) SetReadinessCheck(check func(ctx context.Context) error) {
+	s.readinessCheck = check
+}
+
+// SetReady marks whether background processing is running; /readyz reports 503 until it is
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// RateLimitedCount returns how many task creation requests were refused by the rate limit
+func (s *Server) RateLimitedCount() int64 {
+	if s.createLimiter == nil {
+		return 0
+	}
+	return s.createLimiter.Rejected()
+}
+
+// ListenAndServe starts the HTTP server, serving HTTPS when server.tls is configured
 func (s *Server) ListenAndServe() error {
 	s.httpServer = &http.Server{
 		Addr:    s.config.Address(),
 		Handler: s.engine,
 	}
 
-	log.Printf("Starting HTTP server on %s", s.config.Address())
-	return s.httpServer.ListenAndServe()
+	if s.certs == nil {
+		log.Printf("Starting HTTP server on %s", s.config.Address())
+		return s.httpServer.ListenAndServe()
+	}
+
+	if err := s.certs.reload(); err != nil {
+		return err
+	}
+	s.httpServer.TLSConfig = s.certs.tlsConfig()
+
+	go s.certs.watch(s.stopWatch)
+
+	log.Printf("Starting HTTPS server on %s (mTLS: %v)", s.config.Address(), s.config.TLS.ClientCAFile != "")
+	return s.httpServer.ListenAndServeTLS("", "")
+}
+
+// ReloadTLS reloads the TLS certificate, key and client CAs from their files, e.g. on SIGHUP
+// Connections already established keep their certificate; an invalid file keeps the previous one
+func (s *Server) ReloadTLS() error {
+	if s.certs == nil {
+		return fmt.Errorf("TLS is not configured")
+	}
+	return s.certs.reload()
 }
 
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
 	log.Println("Shutting down HTTP server...")
+	s.SetReady(false)
+	if s.stopWatch != nil {
+		s.stopOnce.Do(func() { close(s.stopWatch) })
+	}
 	return s.httpServer.Shutdown(ctx)
 }
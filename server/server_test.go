@@ -0,0 +1,892 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/configs"
+	"github.com/usual2970/later/delivery/rest"
+	"github.com/usual2970/later/delivery/rest/dto"
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/worker"
+	"github.com/usual2970/later/repository/memory"
+	tasksvc "github.com/usual2970/later/task"
+)
+
+// memoryRepository keeps tasks in a map, enough to drive every handler
+type memoryRepository struct {
+	repository.TaskRepository
+	mu      sync.Mutex
+	tasks   map[string]*entity.Task
+	findErr error // Returned by FindByID when set, as if the database were unreachable
+}
+
+func (r *memoryRepository) Create(ctx context.Context, task *entity.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tasks[task.ID]; ok {
+		return fmt.Errorf("%w: %s", domain.ErrConflict, task.ID)
+	}
+	stored := *task
+	r.tasks[task.ID] = &stored
+	return nil
+}
+
+func (r *memoryRepository) CreateBatch(ctx context.Context, tasks []*entity.Task) error {
+	for _, task := range tasks {
+		if err := r.Create(ctx, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) ExistingIDs(ctx context.Context, ids []string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var existing []string
+	for _, id := range ids {
+		if _, ok := r.tasks[id]; ok {
+			existing = append(existing, id)
+		}
+	}
+	return existing, nil
+}
+
+func (r *memoryRepository) FindByID(ctx context.Context, id string) (*entity.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.findErr != nil {
+		return nil, r.findErr
+	}
+	task, ok := r.tasks[id]
+	if !ok || task.DeletedAt != nil {
+		return nil, domain.ErrNotFound
+	}
+	found := *task
+	return &found, nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, task *entity.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *task
+	r.tasks[task.ID] = &stored
+	return nil
+}
+
+func (r *memoryRepository) UpdateIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.tasks[task.ID]
+	if !ok || stored.DeletedAt != nil || !slices.Contains(from, stored.Status) {
+		return false, nil
+	}
+	updated := *task
+	r.tasks[task.ID] = &updated
+	return true, nil
+}
+
+func (r *memoryRepository) UpdateTags(ctx context.Context, taskID string, tags []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if task, ok := r.tasks[taskID]; ok {
+		task.Tags = tags
+	}
+	return nil
+}
+
+func (r *memoryRepository) SoftDelete(ctx context.Context, taskID string, deletedBy string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.tasks[taskID].DeletedAt = &now
+	r.tasks[taskID].DeletedBy = &deletedBy
+	return nil
+}
+
+func (r *memoryRepository) FindDueTasks(ctx context.Context, minPriority int, limit int, skipGroups []string) ([]*entity.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []*entity.Task
+	for _, task := range r.tasks {
+		if task.DeletedAt == nil && task.Status == entity.TaskStatusPending &&
+			!task.ScheduledAt.After(time.Now()) && task.Priority > minPriority {
+			due = append(due, task)
+		}
+	}
+	return due, nil
+}
+
+func (r *memoryRepository) List(ctx context.Context, filter repository.TaskFilter) ([]*entity.Task, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var tasks []*entity.Task
+	for _, task := range r.tasks {
+		if task.DeletedAt != nil || (filter.Status != nil && task.Status != *filter.Status) {
+			continue
+		}
+		if filter.DependsOn != nil && (task.DependsOn == nil || *task.DependsOn != *filter.DependsOn) {
+			continue
+		}
+		if (filter.ScheduledFrom != nil && task.ScheduledAt.Before(*filter.ScheduledFrom)) ||
+			(filter.ScheduledTo != nil && task.ScheduledAt.After(*filter.ScheduledTo)) {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, int64(len(tasks)), nil
+}
+
+func (r *memoryRepository) ListStream(ctx context.Context, filter repository.TaskFilter, fn func(*entity.Task) error) error {
+	tasks, _, _ := r.List(ctx, filter)
+	for i, task := range tasks {
+		if filter.Limit > 0 && i == filter.Limit {
+			break
+		}
+		exported := *task
+		if err := fn(&exported); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) CountByStatus(ctx context.Context) (map[entity.TaskStatus]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := map[entity.TaskStatus]int64{}
+	for _, task := range r.tasks {
+		if task.DeletedAt == nil {
+			counts[task.Status]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *memoryRepository) CountByCallbackHost(ctx context.Context, hosts []string) (map[string]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := map[string]int64{}
+	for _, task := range r.tasks {
+		held := task.Status == entity.TaskStatusPending || task.Status == entity.TaskStatusFailed
+		if task.DeletedAt == nil && held && slices.Contains(hosts, entity.CallbackHost(task.CallbackURL)) {
+			counts[entity.CallbackHost(task.CallbackURL)]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *memoryRepository) StatsSummary(ctx context.Context, since time.Time) (*repository.StatsSummary, error) {
+	return &repository.StatsSummary{
+		Created: 4, Completed: 1, Failed: 1, DeadLettered: 1,
+		AvgCallbackAttempts: 2.5, P50CompletionLatencyMs: 120, P95CompletionLatencyMs: 950,
+	}, nil
+}
+
+func (r *memoryRepository) CountByTimeBucket(ctx context.Context, since time.Time, bucket time.Duration) ([]*repository.TimeBucketCounts, error) {
+	return []*repository.TimeBucketCounts{{Start: since.Truncate(bucket).Add(bucket), Created: 2, Completed: 1, AvgCallbackLatencyMs: 12.5}}, nil
+}
+
+func (r *memoryRepository) StatsByGroup(ctx context.Context, group repository.StatsGroup, since time.Time, limit int) ([]*repository.GroupStats, error) {
+	return []*repository.GroupStats{{
+		Key:                 "send_email",
+		Total:               3,
+		ByStatus:            map[entity.TaskStatus]int64{entity.TaskStatusCompleted: 2, entity.TaskStatusDeadLettered: 1},
+		AvgCallbackAttempts: 1.5,
+	}}, nil
+}
+
+func (r *memoryRepository) CountBulk(ctx context.Context, filter repository.BulkFilter) (int64, error) {
+	return 3, nil
+}
+
+func (r *memoryRepository) BulkSoftDelete(ctx context.Context, filter repository.BulkFilter, deletedBy string) ([]string, error) {
+	return []string{}, nil
+}
+
+func (r *memoryRepository) BulkRetry(ctx context.Context, filter repository.BulkFilter) ([]string, error) {
+	return []string{}, nil
+}
+
+func (r *memoryRepository) CleanupTasks(ctx context.Context, cleanup repository.Cleanup) (*repository.CleanupResult, error) {
+	result := &repository.CleanupResult{ByStatus: map[entity.TaskStatus]int64{}}
+	for status := range cleanup.Cutoffs {
+		if status != entity.TaskStatusExpired {
+			result.ByStatus[status] = 2
+		}
+	}
+	return result, nil
+}
+
+// idlePool accepts submitted tasks without running them
+type idlePool struct{}
+
+func (idlePool) Start(workerCount int)             {}
+func (idlePool) SubmitTask(task *entity.Task) bool { return true }
+func (idlePool) Stop(ctx context.Context) int      { return 0 }
+
+const (
+	pendingTaskID = "00000000-0000-0000-0000-000000000001"
+	failedTaskID  = "00000000-0000-0000-0000-000000000002"
+	deadTaskID    = "00000000-0000-0000-0000-000000000003"
+	childTaskID   = "00000000-0000-0000-0000-000000000004"
+	missingTaskID = "00000000-0000-0000-0000-0000000000ff"
+)
+
+// newTestServer serves the API from an in-memory repository seeded with a pending, failed,
+// dead-lettered and waiting task
+func newTestServer(t *testing.T, cfg configs.ServerConfig) (*Server, *memoryRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	now := time.Now()
+	started := now.Add(-time.Minute)
+	errMsg := "callback returned 500"
+	parent := pendingTaskID
+	tasks := map[string]*entity.Task{}
+	for id, status := range map[string]entity.TaskStatus{
+		pendingTaskID: entity.TaskStatusPending,
+		failedTaskID:  entity.TaskStatusFailed,
+		deadTaskID:    entity.TaskStatusDeadLettered,
+		childTaskID:   entity.TaskStatusWaiting,
+	} {
+		task := entity.NewTask("send_email", []byte(`{"to":"user@example.com"}`), "https://example.com/callback", now, 3)
+		task.ID = id
+		task.Status = status
+		task.Tags = []string{"email"}
+		if status != entity.TaskStatusPending && status != entity.TaskStatusWaiting {
+			task.StartedAt = &started
+			task.CompletedAt = &now
+			task.ErrorMessage = &errMsg
+		}
+		tasks[id] = task
+	}
+	tasks[childTaskID].DependsOn = &parent
+	tasks[childTaskID].DependencyFailurePolicy = entity.DependencyFailureRunAnyway
+
+	repo := &memoryRepository{tasks: tasks}
+	scheduler := tasksvc.NewScheduler(repo, idlePool{}, tasksvc.SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        time.Hour,
+	})
+	limiter, err := worker.NewConcurrencyLimiter(map[string]int{"email": 2}, 0)
+	require.NoError(t, err)
+
+	events := memory.NewTaskEventRepository()
+	for _, status := range []entity.TaskStatus{entity.TaskStatusPending, entity.TaskStatusProcessing, entity.TaskStatusDeadLettered} {
+		require.NoError(t, events.Append(context.Background(), &entity.TaskEvent{
+			TaskID: deadTaskID, Type: "task_updated", ToStatus: status, Metadata: []byte(`{"name":"send_email"}`), CreatedAt: now,
+		}))
+	}
+
+	svc := tasksvc.NewService(repo, tasksvc.WithEventHistory(events), tasksvc.WithHostPauses(memory.NewHostPauseRepository()))
+	callbackSvc := callback.NewService(&http.Client{Timeout: time.Second}, nil, "", 0, zap.NewNop())
+	h := rest.NewHandler(svc, scheduler, worker.NewExecutor(svc, callbackSvc, nil, worker.NewInFlight(), zap.NewNop()), nil)
+	return NewServer(cfg, configs.AuthConfig{}, h, rest.NewAdminHandler(limiter, scheduler), nil), repo
+}
+
+func TestCancelTaskStopsDelivery(t *testing.T) {
+	s, repo := newTestServer(t, configs.ServerConfig{})
+
+	due, err := repo.FindDueTasks(context.Background(), -1, 10, nil)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	require.Equal(t, pendingTaskID, due[0].ID)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/tasks/"+pendingTaskID, nil)
+	req.Header.Set("X-User-ID", "operator")
+	s.engine.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	// The row is soft deleted, so the scheduler never picks it up again; only the child that
+	// runs anyway once its parent is cancelled becomes due
+	stored := repo.tasks[pendingTaskID]
+	assert.NotNil(t, stored.DeletedAt)
+	if assert.NotNil(t, stored.DeletedBy) {
+		assert.Equal(t, "operator", *stored.DeletedBy)
+	}
+	due, err = repo.FindDueTasks(context.Background(), -1, 10, nil)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, childTaskID, due[0].ID)
+
+	rec = httptest.NewRecorder()
+	s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+pendingTaskID, nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestReadiness(t *testing.T) {
+	s, _ := newTestServer(t, configs.ServerConfig{})
+	var pingErr error
+	s.SetReadinessCheck(func(ctx context.Context) error { return pingErr })
+
+	probe := func() int {
+		rec := httptest.NewRecorder()
+		s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, probe(), "not ready before start")
+
+	s.SetReady(true)
+	assert.Equal(t, http.StatusOK, probe())
+
+	pingErr = errors.New("connection refused")
+	assert.Equal(t, http.StatusServiceUnavailable, probe(), "not ready while the database is unreachable")
+	pingErr = nil
+
+	s.httpServer = &http.Server{}
+	require.NoError(t, s.Shutdown(context.Background()))
+	assert.Equal(t, http.StatusServiceUnavailable, probe(), "not ready once shutdown begins")
+
+	// Liveness is unaffected by readiness
+	rec := httptest.NewRecorder()
+	s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestExportTasks(t *testing.T) {
+	s, _ := newTestServer(t, configs.ServerConfig{})
+	export := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/export?"+query, nil))
+		return rec
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		rec := export("format=csv&status=failed")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Regexp(t, `^attachment; filename="tasks-\d{8}T\d{6}Z\.csv"$`, rec.Header().Get("Content-Disposition"))
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, dto.ExportCSVHeader, records[0])
+		assert.Equal(t, failedTaskID, records[1][0])
+		assert.Equal(t, "callback returned 500", records[1][len(records[1])-1])
+	})
+
+	t.Run("csv with payload", func(t *testing.T) {
+		rec := export("format=csv&status=failed&include_payload=true")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "payload", records[0][len(records[0])-1])
+		assert.Equal(t, `{"to":"user@example.com"}`, records[1][len(records[1])-1])
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		rec := export("format=ndjson")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Header().Get("Content-Disposition"), ".ndjson")
+
+		var ids []string
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var task dto.TaskResponse
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &task))
+			assert.Empty(t, task.Payload, "payloads are only exported on request")
+			ids = append(ids, task.ID)
+		}
+		assert.Equal(t, []string{pendingTaskID, failedTaskID, deadTaskID, childTaskID}, ids)
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		rec := export("format=xml")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, rec.Header().Get("Content-Disposition"))
+	})
+}
+
+func TestImportTasks(t *testing.T) {
+	s, repo := newTestServer(t, configs.ServerConfig{})
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/api/v1/tasks/export?format=ndjson&status=dead_lettered&include_payload=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	exported := rec.Body.String()
+
+	// Re-importing the export into the same store only works with the IDs remapped
+	body := exported + "\n" + `{"name":"no_callback","payload":{}}` + "\n"
+	rec = serve(httptest.NewRequest(http.MethodPost, "/api/v1/tasks/import", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var report dto.ImportTasksResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, 0, report.Imported)
+	assert.Equal(t, 2, report.Failed)
+	require.Len(t, report.Results, 2)
+	assert.Equal(t, 1, report.Results[0].Line)
+	assert.Contains(t, report.Results[0].Error, "already exists")
+	assert.Equal(t, 3, report.Results[1].Line, "blank lines still count")
+	assert.Contains(t, report.Results[1].Error, "CallbackURL")
+
+	rec = serve(httptest.NewRequest(http.MethodPost, "/api/v1/tasks/import?on_duplicate=remap", strings.NewReader(exported)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	report = dto.ImportTasksResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.Equal(t, 1, report.Imported, rec.Body.String())
+	result := report.Results[0]
+	assert.Equal(t, deadTaskID, result.OriginalID)
+
+	imported := repo.tasks[result.ID]
+	require.NotNil(t, imported)
+	assert.Equal(t, entity.TaskStatusPending, imported.Status)
+	assert.Equal(t, "send_email", imported.Name)
+	assert.JSONEq(t, `{"to":"user@example.com"}`, string(imported.Payload))
+	assert.Equal(t, []string{"email"}, imported.Tags)
+
+	rec = serve(httptest.NewRequest(http.MethodPost, "/api/v1/tasks/import?on_duplicate=skip", strings.NewReader(exported)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = serve(httptest.NewRequest(http.MethodPost, "/api/v1/tasks/import", strings.NewReader("\n")))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestExecuteTask(t *testing.T) {
+	s, repo := newTestServer(t, configs.ServerConfig{})
+	spec := loadSpec(t)
+
+	var deliveries int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries++
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+	repo.tasks[pendingTaskID].CallbackURL = receiver.URL
+
+	execute := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/tasks/"+id+"/execute", nil))
+		return rec
+	}
+
+	rec := execute(pendingTaskID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	spec.checkResponse(t, http.MethodPost, "/api/v1/tasks/{id}/execute", rec)
+	var resp dto.ExecuteTaskResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, resp.Delivered)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Empty(t, resp.Error)
+	assert.Equal(t, entity.TaskStatusCompleted, resp.Task.Status)
+	assert.Equal(t, entity.TaskStatusCompleted, repo.tasks[pendingTaskID].Status)
+	assert.Equal(t, 1, deliveries)
+
+	// Completed tasks can't run again
+	rec = execute(pendingTaskID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid_status")
+
+	// Nor can tasks a worker is already running
+	repo.tasks[failedTaskID].Status = entity.TaskStatusProcessing
+	rec = execute(failedTaskID)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	spec.checkResponse(t, http.MethodPost, "/api/v1/tasks/{id}/execute", rec)
+	assert.Equal(t, 1, deliveries)
+}
+
+func TestExecuteTaskRedactsPayload(t *testing.T) {
+	base, repo := newTestServer(t, configs.ServerConfig{})
+	auth := configs.AuthConfig{APIKeys: []string{"plain-key", "payload-key"}, PayloadKeys: []string{"payload-key"}}
+	s := NewServer(configs.ServerConfig{}, auth, base.handler, base.admin, nil)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	for key, id := range map[string]string{"plain-key": pendingTaskID, "payload-key": failedTaskID} {
+		repo.tasks[id].CallbackURL = receiver.URL
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/"+id+"/execute", nil)
+		req.Header.Set("X-API-Key", key)
+		s.engine.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var resp dto.ExecuteTaskResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.True(t, resp.Delivered)
+		if key == "plain-key" {
+			assert.Empty(t, resp.Task.Payload, "payload shown without payload access")
+			assert.True(t, resp.Task.PayloadRedacted)
+		} else {
+			assert.JSONEq(t, `{"to":"user@example.com"}`, resp.Task.Payload)
+			assert.False(t, resp.Task.PayloadRedacted)
+		}
+	}
+}
+
+func TestTaskDetailsRedactPayload(t *testing.T) {
+	base, _ := newTestServer(t, configs.ServerConfig{})
+	auth := configs.AuthConfig{APIKeys: []string{"plain-key", "payload-key"}, PayloadKeys: []string{"payload-key"}}
+	s := NewServer(configs.ServerConfig{}, auth, base.handler, base.admin, nil)
+
+	for _, tt := range []struct {
+		name, method, path, key string
+		status                  int
+		redacted                bool
+	}{
+		{"Get without payload access", http.MethodGet, "/api/v1/tasks/" + pendingTaskID, "plain-key", http.StatusOK, true},
+		{"Get with payload access", http.MethodGet, "/api/v1/tasks/" + pendingTaskID, "payload-key", http.StatusOK, false},
+		{"Retry without payload access", http.MethodPost, "/api/v1/tasks/" + failedTaskID + "/retry", "plain-key", http.StatusAccepted, true},
+		{"Retry with payload access", http.MethodPost, "/api/v1/tasks/" + deadTaskID + "/retry", "payload-key", http.StatusAccepted, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-API-Key", tt.key)
+			s.engine.ServeHTTP(rec, req)
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+
+			var resp dto.TaskResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.redacted, resp.PayloadRedacted)
+			if tt.redacted {
+				assert.Empty(t, resp.Payload)
+				assert.NotContains(t, rec.Body.String(), "user@example.com")
+			} else {
+				assert.JSONEq(t, `{"to":"user@example.com"}`, resp.Payload)
+			}
+		})
+	}
+}
+
+func TestUpcomingTasks(t *testing.T) {
+	s, repo := newTestServer(t, configs.ServerConfig{})
+	spec := loadSpec(t)
+
+	later := entity.NewTask("send_email", nil, "https://example.com/callback", time.Now().Add(2*time.Hour), 3)
+	later.ID = "00000000-0000-0000-0000-000000000005"
+	repo.tasks[later.ID] = later
+
+	upcoming := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/upcoming"+query, nil))
+		return rec
+	}
+
+	// Only pending tasks due within the default hour
+	rec := upcoming("")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	spec.checkResponse(t, http.MethodGet, "/api/v1/tasks/upcoming", rec)
+	var list dto.TaskListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Tasks, 1)
+	assert.Equal(t, pendingTaskID, list.Tasks[0].ID)
+	assert.Equal(t, 50, list.Pagination.Limit)
+
+	rec = upcoming("?within=3h")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Len(t, list.Tasks, 2)
+
+	for _, within := range []string{"soon", "-1h", "200h"} {
+		rec = upcoming("?within=" + within)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, within)
+		assert.Contains(t, rec.Body.String(), "invalid_filter")
+	}
+}
+
+func TestCreateRateLimit(t *testing.T) {
+	s, _ := newTestServer(t, configs.ServerConfig{CreateRateLimit: configs.RateLimitConfig{RPS: 0.01, Burst: 2}})
+	spec := loadSpec(t)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+	create := `{"name":"send_email","payload":{},"callback_url":"https://example.com/callback"}`
+
+	for i := 0; i < 2; i++ {
+		rec := post("/api/v1/tasks", create)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	rec := post("/api/v1/tasks", create)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	spec.checkResponse(t, http.MethodPost, "/api/v1/tasks", rec)
+	assert.Equal(t, "100", rec.Header().Get("Retry-After"))
+
+	// Imports share the client's bucket; other routes aren't limited
+	rec = post("/api/v1/tasks/import", create)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	spec.checkResponse(t, http.MethodPost, "/api/v1/tasks/import", rec)
+	rec = post("/api/v1/tasks/bulk/retry", `{"ids":["`+failedTaskID+`"],"limit":10}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, int64(2), s.RateLimitedCount())
+}
+
+func TestCreateTaskWithTakenID(t *testing.T) {
+	s, repo := newTestServer(t, configs.ServerConfig{})
+	spec := loadSpec(t)
+
+	post := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		body := `{"id":"` + id + `","name":"send_email","payload":{},"callback_url":"https://example.com/callback"}`
+		s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+		return rec
+	}
+
+	id := "8f14e45f-ceea-467a-9575-0b0f1a0c0b1e"
+	rec := post(id)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	require.NotNil(t, repo.tasks[id], "the task is stored under the requested ID")
+
+	rec = post(id)
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	spec.checkResponse(t, http.MethodPost, "/api/v1/tasks", rec)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "conflict", body["error"])
+	assert.Equal(t, id, body["task_id"])
+
+	rec = post("not-a-uuid")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestTaskErrorResponses(t *testing.T) {
+	s, repo := newTestServer(t, configs.ServerConfig{})
+	spec := loadSpec(t)
+	missing := "00000000-0000-0000-0000-0000000000ff"
+	create := func(id string) string {
+		return `{"id":"` + id + `","name":"send_email","payload":{},"callback_url":"https://example.com/callback"}`
+	}
+
+	tests := []struct {
+		name, method, path, route, body string
+		failing                         bool // The database is unreachable
+		status                          int
+		code                            string
+	}{
+		{"Get missing task", http.MethodGet, "/api/v1/tasks/" + missing, "/api/v1/tasks/{id}", "", false, http.StatusNotFound, "task_not_found"},
+		{"Get with failing database", http.MethodGet, "/api/v1/tasks/" + pendingTaskID, "/api/v1/tasks/{id}", "", true, http.StatusInternalServerError, "internal_error"},
+		{"Delete missing task", http.MethodDelete, "/api/v1/tasks/" + missing, "/api/v1/tasks/{id}", "", false, http.StatusNotFound, "task_not_found"},
+		{"Delete dead-lettered task", http.MethodDelete, "/api/v1/tasks/" + deadTaskID, "/api/v1/tasks/{id}", "", false, http.StatusBadRequest, "invalid_status"},
+		{"Retry pending task", http.MethodPost, "/api/v1/tasks/" + pendingTaskID + "/retry", "/api/v1/tasks/{id}/retry", "", false, http.StatusBadRequest, "invalid_status"},
+		{"Retry with failing database", http.MethodPost, "/api/v1/tasks/" + failedTaskID + "/retry", "/api/v1/tasks/{id}/retry", "", true, http.StatusInternalServerError, "internal_error"},
+		{"Resurrect missing task", http.MethodPost, "/api/v1/tasks/" + missing + "/resurrect", "/api/v1/tasks/{id}/resurrect", "", false, http.StatusNotFound, "task_not_found"},
+		{"Create with taken ID", http.MethodPost, "/api/v1/tasks", "/api/v1/tasks", create(pendingTaskID), false, http.StatusConflict, "conflict"},
+		{"Create with invalid ID", http.MethodPost, "/api/v1/tasks", "/api/v1/tasks", create("evt-1"), false, http.StatusBadRequest, "validation_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo.mu.Lock()
+			repo.findErr = nil
+			if tt.failing {
+				repo.findErr = fmt.Errorf("failed to find task: %w", errors.New("connection refused"))
+			}
+			repo.mu.Unlock()
+
+			rec := httptest.NewRecorder()
+			s.engine.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+			spec.checkResponse(t, tt.method, tt.route, rec)
+
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body["error"])
+			assert.NotContains(t, body["message"], "connection refused", "internal errors aren't exposed")
+		})
+	}
+}
+
+func TestRetryTask(t *testing.T) {
+	s, repo := newTestServer(t, configs.ServerConfig{})
+	spec := loadSpec(t)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+
+	// Dead-lettered tasks can be retried, with a delayed restart and a fresh retry budget
+	restart := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	rec := post("/api/v1/tasks/"+deadTaskID+"/retry", `{"scheduled_for":"`+restart.Format(time.RFC3339)+`","max_retries":8}`)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	spec.checkResponse(t, http.MethodPost, "/api/v1/tasks/{id}/retry", rec)
+	var task dto.TaskResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &task))
+	assert.Equal(t, entity.TaskStatusPending, task.Status)
+	assert.Equal(t, 8, task.MaxRetries)
+	assert.True(t, restart.Equal(task.ScheduledFor), "got %v", task.ScheduledFor)
+	assert.True(t, restart.Equal(repo.tasks[deadTaskID].ScheduledAt))
+
+	// Resurrect is an alias, so it retries failed tasks too, and the body is optional
+	rec = post("/api/v1/tasks/"+failedTaskID+"/resurrect", "")
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	spec.checkResponse(t, http.MethodPost, "/api/v1/tasks/{id}/resurrect", rec)
+	assert.Equal(t, entity.TaskStatusPending, repo.tasks[failedTaskID].Status)
+
+	for _, body := range []string{`{"max_retries":21}`, `{"scheduled_for":"soon"}`} {
+		rec = post("/api/v1/tasks/"+pendingTaskID+"/retry", body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		spec.checkResponse(t, http.MethodPost, "/api/v1/tasks/{id}/retry", rec)
+	}
+}
+
+func TestAbortTask(t *testing.T) {
+	s, repo := newTestServer(t, configs.ServerConfig{})
+	spec := loadSpec(t)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+
+	// The receiver hangs like a dead host until the request is cancelled
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer receiver.Close()
+	defer close(release)
+	repo.mu.Lock()
+	repo.tasks[pendingTaskID].CallbackURL = receiver.URL
+	repo.mu.Unlock()
+
+	rec := post("/api/v1/tasks/"+pendingTaskID+"/abort", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "only processing tasks can be aborted")
+	spec.checkResponse(t, http.MethodPost, "/api/v1/tasks/{id}/abort", rec)
+	rec = post("/api/v1/tasks/"+missingTaskID+"/abort", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	spec.checkResponse(t, http.MethodPost, "/api/v1/tasks/{id}/abort", rec)
+
+	executed := make(chan *httptest.ResponseRecorder)
+	go func() { executed <- post("/api/v1/tasks/"+pendingTaskID+"/execute", "") }()
+	require.Eventually(t, func() bool {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+		return repo.tasks[pendingTaskID].Status == entity.TaskStatusProcessing
+	}, time.Second, time.Millisecond)
+
+	// The task is claimed just before its callback starts
+	require.Eventually(t, func() bool {
+		rec = post("/api/v1/tasks/"+pendingTaskID+"/abort", `{"terminal":true}`)
+		return rec.Code != http.StatusConflict
+	}, time.Second, time.Millisecond)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	spec.checkResponse(t, http.MethodPost, "/api/v1/tasks/{id}/abort", rec)
+
+	rec = <-executed
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var execution dto.ExecuteTaskResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &execution))
+	assert.False(t, execution.Delivered)
+	assert.Equal(t, "aborted by operator", execution.Error)
+	assert.Equal(t, entity.TaskStatusDeadLettered, execution.Task.Status)
+
+	// Once the callback has returned there is nothing left to abort
+	repo.mu.Lock()
+	repo.tasks[pendingTaskID].Status = entity.TaskStatusProcessing
+	repo.mu.Unlock()
+	rec = post("/api/v1/tasks/"+pendingTaskID+"/abort", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	spec.checkResponse(t, http.MethodPost, "/api/v1/tasks/{id}/abort", rec)
+	assert.Contains(t, rec.Body.String(), "not_in_flight")
+}
+
+func TestRunCleanup(t *testing.T) {
+	s, _ := newTestServer(t, configs.ServerConfig{})
+	spec := loadSpec(t)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/cleanup", strings.NewReader(body)))
+		spec.checkResponse(t, http.MethodPost, "/api/v1/admin/cleanup", rec)
+		return rec
+	}
+
+	// Every requested status is reported, even one without tasks
+	rec := post(`{"older_than":"720h","statuses":["completed","expired"],"dry_run":true}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var result dto.CleanupResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, map[entity.TaskStatus]int64{entity.TaskStatusCompleted: 2, entity.TaskStatusExpired: 0}, result.ByStatus)
+	assert.Equal(t, int64(2), result.Total)
+	assert.True(t, result.DryRun)
+
+	for _, body := range []string{
+		`{"older_than":"720h","statuses":[]}`,
+		`{"older_than":"720h","statuses":["pending"]}`,
+		`{"older_than":"a month","statuses":["completed"]}`,
+		`{"older_than":"-1h","statuses":["completed"]}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, post(body).Code, body)
+	}
+}
+
+func TestTaskTags(t *testing.T) {
+	s, repo := newTestServer(t, configs.ServerConfig{})
+	spec := loadSpec(t)
+
+	send := func(method, id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.engine.ServeHTTP(rec, httptest.NewRequest(method, "/api/v1/tasks/"+id+"/tags", strings.NewReader(body)))
+		spec.checkResponse(t, method, "/api/v1/tasks/{id}/tags", rec)
+		return rec
+	}
+
+	// Dead-lettered tasks can be labelled too
+	rec := send(http.MethodPatch, deadTaskID, `{"add":["investigating"]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var tags dto.TaskTagsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tags))
+	assert.Equal(t, []string{"email", "investigating"}, tags.Tags)
+
+	rec = send(http.MethodPatch, deadTaskID, `{"remove":["investigating"]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"email"}, repo.tasks[deadTaskID].Tags)
+
+	rec = send(http.MethodPut, deadTaskID, `{"tags":[]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"id":"`+deadTaskID+`","tags":[]}`, rec.Body.String())
+
+	for _, tt := range []struct {
+		method, id, body string
+		status           int
+	}{
+		{http.MethodPut, deadTaskID, `{}`, http.StatusBadRequest},
+		{http.MethodPut, deadTaskID, `{"tags":["on hold"]}`, http.StatusBadRequest},
+		{http.MethodPatch, deadTaskID, `{}`, http.StatusBadRequest},
+		{http.MethodPatch, missingTaskID, `{"add":["a"]}`, http.StatusNotFound},
+	} {
+		assert.Equal(t, tt.status, send(tt.method, tt.id, tt.body).Code, "%s %s", tt.method, tt.body)
+	}
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/configs"
+	"github.com/usual2970/later/infrastructure/logger"
+)
+
+// tlsWatchInterval is how often the certificate files are checked for changes
+const tlsWatchInterval = 10 * time.Second
+
+// certReloader serves the TLS certificate, and the client CAs for mTLS, from files that are
+// reloaded on demand or when they change, so certificates can be rotated without a restart
+type certReloader struct {
+	cfg    configs.TLSConfig
+	logger *zap.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool // nil without mTLS
+	stamp     string         // Sizes and modification times of the files when last loaded
+
+	failing bool // The last check failed to reload the files; logged once per streak
+}
+
+func newCertReloader(cfg configs.TLSConfig) *certReloader {
+	return &certReloader{cfg: cfg, logger: logger.Get()}
+}
+
+// reload loads the files, keeping the previous certificate if any of them is invalid
+func (r *certReloader) reload() error {
+	stamp := r.fileStamp()
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no PEM certificates found in TLS client CA file %s", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.stamp = stamp
+	return nil
+}
+
+// reloadIfChanged reloads the files if any of them changed since they were last loaded
+func (r *certReloader) reloadIfChanged() (bool, error) {
+	r.mu.RLock()
+	unchanged := r.fileStamp() == r.stamp
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	return true, r.reload()
+}
+
+// fileStamp summarizes the files' sizes and modification times; it follows symlinks, so
+// certificates mounted from a Kubernetes secret are picked up when the secret is updated
+func (r *certReloader) fileStamp() string {
+	var stamp string
+	for _, name := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.ClientCAFile} {
+		if name == "" {
+			continue
+		}
+		if info, err := os.Stat(name); err == nil {
+			stamp += fmt.Sprintf("%d:%d;", info.Size(), info.ModTime().UnixNano())
+		} else {
+			stamp += "missing;"
+		}
+	}
+	return stamp
+}
+
+// watch reloads the files when they change, until stop is closed
+// Invalid files, e.g. a certificate written before its key, are retried on every check until
+// they load, and the previous certificate is served meanwhile
+func (r *certReloader) watch(stop <-chan struct{}) {
+	ticker := time.NewTicker(tlsWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.check()
+		}
+	}
+}
+
+// check reloads the files if they changed, logging the first failure of a streak and the
+// reload that ends it
+func (r *certReloader) check() {
+	changed, err := r.reloadIfChanged()
+	switch {
+	case err != nil:
+		if !r.failing {
+			r.failing = true
+			r.logger.Warn("Failed to reload changed TLS certificate, serving the previous one",
+				zap.Duration("retry_interval", tlsWatchInterval), zap.Error(err))
+		}
+	case r.failing:
+		r.failing = false
+		r.logger.Info("TLS certificate reloaded after earlier failures")
+	case changed:
+		r.logger.Info("TLS certificate reloaded")
+	}
+}
+
+// tlsConfig returns the server TLS config, which always uses the latest loaded files
+func (r *certReloader) tlsConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		},
+	}
+	if r.cfg.ClientCAFile == "" {
+		return cfg
+	}
+
+	// ClientCAs can't change on a shared config, so each handshake gets one with the latest CAs
+	base := cfg.Clone()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		perClient := base.Clone()
+		perClient.ClientCAs = r.clientCAs
+		perClient.ClientAuth = tls.RequireAndVerifyClientCert
+		return perClient, nil
+	}
+	return cfg
+}
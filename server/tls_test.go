@@ -0,0 +1,211 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/usual2970/later/configs"
+)
+
+// testCA issues certificates for the TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Later Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key with the given serial number
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeServerCert writes a server certificate with the given serial number to the config's files
+func (ca *testCA) writeServerCert(t *testing.T, cfg configs.TLSConfig, serial int64) {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, serial, x509.ExtKeyUsageServerAuth)
+	require.NoError(t, os.WriteFile(cfg.CertFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(cfg.KeyFile, keyPEM, 0o600))
+}
+
+// serveTLS serves an empty 200 response with the reloader's certificates
+func serveTLS(t *testing.T, certs *certReloader) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", certs.tlsConfig())
+	require.NoError(t, err)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+// peerSerial makes a request on a new connection and returns the server certificate's serial number
+func peerSerial(t *testing.T, url string, tlsCfg *tls.Config) (int64, error) {
+	t.Helper()
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg, DisableKeepAlives: true}}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.TLS.PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+func TestTLSCertificateReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	cfg := configs.TLSConfig{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	ca.writeServerCert(t, cfg, 100)
+
+	certs := newCertReloader(cfg)
+	require.NoError(t, certs.reload())
+	url := serveTLS(t, certs)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientCfg := &tls.Config{RootCAs: roots}
+
+	serial, err := peerSerial(t, url, clientCfg)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), serial)
+
+	changed, err := certs.reloadIfChanged()
+	require.NoError(t, err)
+	assert.False(t, changed, "Unchanged files aren't reloaded")
+
+	t.Run("Changed files are reloaded", func(t *testing.T) {
+		ca.writeServerCert(t, cfg, 101)
+		changed, err := certs.reloadIfChanged()
+		require.NoError(t, err)
+		assert.True(t, changed)
+
+		serial, err := peerSerial(t, url, clientCfg)
+		require.NoError(t, err)
+		assert.Equal(t, int64(101), serial)
+	})
+
+	t.Run("Invalid files keep the previous certificate", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(cfg.KeyFile, []byte("not a key"), 0o600))
+		_, err := certs.reloadIfChanged()
+		assert.Error(t, err)
+
+		serial, err := peerSerial(t, url, clientCfg)
+		require.NoError(t, err)
+		assert.Equal(t, int64(101), serial)
+	})
+}
+
+func TestTLSReloadFailuresLoggedOnce(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	cfg := configs.TLSConfig{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	ca.writeServerCert(t, cfg, 100)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	certs := newCertReloader(cfg)
+	certs.logger = zap.New(core)
+	require.NoError(t, certs.reload())
+
+	require.NoError(t, os.WriteFile(cfg.KeyFile, []byte("not a key"), 0o600))
+	for i := 0; i < 3; i++ {
+		certs.check()
+	}
+	failures := logs.FilterMessageSnippet("Failed to reload")
+	require.Equal(t, 1, failures.Len(), "a failure streak is logged once")
+	assert.Equal(t, zapcore.WarnLevel, failures.All()[0].Level)
+
+	ca.writeServerCert(t, cfg, 101)
+	certs.check()
+	certs.check()
+	recovered := logs.FilterMessageSnippet("after earlier failures")
+	require.Equal(t, 1, recovered.Len())
+	assert.Equal(t, zapcore.InfoLevel, recovered.All()[0].Level)
+	assert.Equal(t, 2, logs.Len(), "unchanged files log nothing")
+}
+
+func TestTLSClientCertificates(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	cfg := configs.TLSConfig{
+		CertFile:     filepath.Join(dir, "tls.crt"),
+		KeyFile:      filepath.Join(dir, "tls.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	ca.writeServerCert(t, cfg, 100)
+	require.NoError(t, os.WriteFile(cfg.ClientCAFile, ca.pem, 0o600))
+
+	certs := newCertReloader(cfg)
+	require.NoError(t, certs.reload())
+	url := serveTLS(t, certs)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	_, err := peerSerial(t, url, &tls.Config{RootCAs: roots})
+	assert.Error(t, err, "Clients without a certificate are refused")
+
+	certPEM, keyPEM := ca.issue(t, 200, x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	_, err = peerSerial(t, url, &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}})
+	assert.NoError(t, err)
+}
+
+func TestReloadTLSWithoutTLS(t *testing.T) {
+	srv, _ := newTestServer(t, configs.ServerConfig{})
+	assert.Error(t, srv.ReloadTLS())
+}
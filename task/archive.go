@@ -0,0 +1,77 @@
+package task
+
+import (
+	"context"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// ArchiveSink keeps finished tasks once the cleanup job deletes them from the database, e.g. in
+// object storage for compliance
+type ArchiveSink interface {
+	// Write stores a batch of records; the batch's tasks are only deleted if it returns nil
+	Write(ctx context.Context, records []*ArchiveRecord) error
+}
+
+// ArchiveRecord is an archived task with the events recorded for it
+// The task's callback attempts are kept on the task: callback_attempts counts them,
+// error_history lists the latest failures and last_callback_* describe the final attempt
+type ArchiveRecord struct {
+	Task *entity.Task `json:"task"`
+
+	// PayloadEncrypted is true when the task's payload is ciphertext under the instance's
+	// payload encryption key; archives are written without decrypting payloads
+	PayloadEncrypted bool `json:"payload_encrypted,omitempty"`
+
+	// Events are empty unless task events are recorded and still retained
+	Events []*entity.TaskEvent `json:"events,omitempty"`
+}
+
+// CleanupCounts totals what the cleanup job has done since the scheduler started
+type CleanupCounts struct {
+	Archived        int64 // Tasks written to the archive sink
+	Deleted         int64 // Expired tasks deleted
+	ArchiveFailures int64 // Batches left in place because the sink failed
+}
+
+// CleanupCounts returns the cleanup job's totals
+func (s *Scheduler) CleanupCounts() CleanupCounts {
+	return CleanupCounts{
+		Archived:        s.archivedTasks.Load(),
+		Deleted:         s.deletedTasks.Load(),
+		ArchiveFailures: s.archiveFailures.Load(),
+	}
+}
+
+// archiveTasks writes a batch of tasks about to be deleted to the archive sink, with their
+// recorded events
+func (s *Scheduler) archiveTasks(ctx context.Context, tasks []*entity.Task) error {
+	records := make([]*ArchiveRecord, len(tasks))
+	byID := make(map[string]*ArchiveRecord, len(tasks))
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		records[i] = &ArchiveRecord{Task: task, PayloadEncrypted: task.PayloadEncrypted}
+		byID[task.ID] = records[i]
+		ids[i] = task.ID
+	}
+
+	if s.taskEvents != nil {
+		events, err := s.taskEvents.ListByTasks(ctx, ids)
+		if err != nil {
+			s.archiveFailures.Add(1)
+			return err
+		}
+		for _, event := range events {
+			if record, ok := byID[event.TaskID]; ok {
+				record.Events = append(record.Events, event)
+			}
+		}
+	}
+
+	if err := s.archiveSink.Write(ctx, records); err != nil {
+		s.archiveFailures.Add(1)
+		return err
+	}
+	s.archivedTasks.Add(int64(len(records)))
+	return nil
+}
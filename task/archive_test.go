@@ -0,0 +1,90 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/clock"
+	"github.com/usual2970/later/repository/memory"
+)
+
+// fakeSink records the batches written to it, failing while err is set
+type fakeSink struct {
+	err     error
+	batches [][]*ArchiveRecord
+}
+
+func (s *fakeSink) Write(ctx context.Context, records []*ArchiveRecord) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, records)
+	return nil
+}
+
+func TestSchedulerArchivesBeforeDeleting(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	repo := memory.NewTaskRepositoryWithClock(clk)
+	events := memory.NewTaskEventRepository()
+
+	finished := clk.Now().Add(-48 * time.Hour)
+	done := entity.NewTask("send_email", []byte(`{"to":"a@example.com"}`), "https://example.com/cb", finished, 0)
+	done.Status = entity.TaskStatusCompleted
+	done.CompletedAt = &finished
+	done.CallbackAttempts = 2
+	done.ErrorHistory = []entity.ErrorRecord{{At: finished, Error: "callback returned 503"}}
+	now := clk.Now()
+	recent := entity.NewTask("recent", nil, "https://example.com/cb", now, 0)
+	recent.Status = entity.TaskStatusCompleted
+	recent.CompletedAt = &now
+	for _, task := range []*entity.Task{done, recent} {
+		require.NoError(t, repo.Create(ctx, task))
+		require.NoError(t, repo.Update(ctx, task))
+	}
+	for _, status := range []entity.TaskStatus{entity.TaskStatusPending, entity.TaskStatusCompleted} {
+		require.NoError(t, events.Append(ctx, &entity.TaskEvent{TaskID: done.ID, Type: "task_updated", ToStatus: status, CreatedAt: finished}))
+	}
+
+	sink := &fakeSink{err: errors.New("bucket unavailable")}
+	scheduler := NewScheduler(repo, &recordingPool{}, SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        time.Hour,
+		CompletedRetention:     24 * time.Hour,
+		TaskEvents:             events,
+		ArchiveSink:            sink,
+		Logger:                 zap.NewNop(),
+		Clock:                  clk,
+	})
+
+	// A failed write keeps the batch for the next cleanup
+	scheduler.cleanupExpiredTasks()
+	_, err := repo.FindByID(ctx, done.ID)
+	require.NoError(t, err, "the task isn't deleted when archiving fails")
+	assert.Equal(t, CleanupCounts{ArchiveFailures: 1}, scheduler.CleanupCounts())
+
+	sink.err = nil
+	scheduler.cleanupExpiredTasks()
+	require.Len(t, sink.batches, 1)
+	require.Len(t, sink.batches[0], 1, "only expired tasks are archived")
+	record := sink.batches[0][0]
+	assert.Equal(t, done.ID, record.Task.ID)
+	assert.Equal(t, 2, record.Task.CallbackAttempts)
+	assert.Len(t, record.Task.ErrorHistory, 1)
+	require.Len(t, record.Events, 2)
+	assert.Equal(t, entity.TaskStatusCompleted, record.Events[1].ToStatus)
+
+	_, err = repo.FindByID(ctx, done.ID)
+	assert.Error(t, err, "the task is deleted once archived")
+	_, err = repo.FindByID(ctx, recent.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, CleanupCounts{Archived: 1, Deleted: 1, ArchiveFailures: 1}, scheduler.CleanupCounts())
+}
@@ -0,0 +1,122 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+
+	"go.uber.org/zap"
+)
+
+// DefaultBacklogRefreshInterval is how long the pending count checked by a backlog limit is reused
+const DefaultBacklogRefreshInterval = 5 * time.Second
+
+// BacklogStatus reports the pending backlog against its limit
+type BacklogStatus struct {
+	Pending    int64     `json:"pending"` // As of CountedAt
+	MaxPending int64     `json:"max_pending"`
+	CountedAt  time.Time `json:"counted_at"` // Zero until the backlog has been counted
+	Full       bool      `json:"full"`       // New tasks below the bypass priority are refused
+}
+
+// backlogLimit refuses new tasks while the pending count is at its maximum
+// The count is cached for the refresh interval so creating a task doesn't count the table
+type backlogLimit struct {
+	repo           repository.TaskRepository
+	maxPending     int64
+	bypassPriority int // Tasks with at least this priority are always accepted; zero disables the bypass
+	interval       time.Duration
+	logger         *zap.Logger
+
+	refreshing sync.Mutex // Held by the one caller refreshing the count
+	mu         sync.Mutex
+	pending    int64
+	countedAt  time.Time
+}
+
+// WithBacklogLimit refuses new tasks with domain.ErrQueueFull while maxPending or more tasks are
+// pending, unless their priority is at least bypassPriority (zero disables the bypass)
+// The pending count is refreshed at most every DefaultBacklogRefreshInterval, so the backlog
+// can overshoot the limit by the tasks created in between
+func WithBacklogLimit(maxPending int64, bypassPriority int) ServiceOption {
+	return func(s *Service) {
+		if maxPending <= 0 {
+			s.backlog = nil
+			return
+		}
+		s.backlog = &backlogLimit{
+			maxPending:     maxPending,
+			bypassPriority: bypassPriority,
+			interval:       DefaultBacklogRefreshInterval,
+		}
+	}
+}
+
+// admit returns domain.ErrQueueFull if the task must be refused
+func (b *backlogLimit) admit(task *entity.Task) error {
+	if b.bypassPriority > 0 && task.Priority >= b.bypassPriority {
+		return nil
+	}
+	if status := b.status(); status.Full {
+		return fmt.Errorf("%w: %d tasks are pending, the limit is %d", domain.ErrQueueFull, status.Pending, status.MaxPending)
+	}
+	return nil
+}
+
+// status returns the cached backlog, counting it first if the count is stale
+// Callers arriving while another refreshes get the previous count rather than waiting
+func (b *backlogLimit) status() BacklogStatus {
+	if b.stale() && b.refreshing.TryLock() {
+		if b.stale() {
+			b.refresh()
+		}
+		b.refreshing.Unlock()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BacklogStatus{
+		Pending:    b.pending,
+		MaxPending: b.maxPending,
+		CountedAt:  b.countedAt,
+		Full:       b.pending >= b.maxPending,
+	}
+}
+
+func (b *backlogLimit) stale() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Since(b.countedAt) >= b.interval
+}
+
+// refresh counts pending tasks across all tenants; on failure the previous count is kept
+// until the next interval, so a struggling database isn't queried on every request
+func (b *backlogLimit) refresh() {
+	// Not the caller's context: the backlog isn't scoped to its tenant or request
+	ctx, cancel := context.WithTimeout(context.Background(), b.interval)
+	defer cancel()
+	counts, err := b.repo.CountByStatus(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.countedAt = time.Now()
+	if err != nil {
+		b.logger.Error("Failed to count pending tasks for the backlog limit", zap.Error(err))
+		return
+	}
+	b.pending = counts[entity.TaskStatusPending]
+}
+
+// Backlog returns the pending backlog as last counted for the backlog limit, refreshing a stale
+// count; ok is false when no limit is configured
+func (s *Service) Backlog() (status BacklogStatus, ok bool) {
+	if s.backlog == nil {
+		return BacklogStatus{}, false
+	}
+	return s.backlog.status(), true
+}
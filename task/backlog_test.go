@@ -0,0 +1,78 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+)
+
+// countingRepository records how often the backlog is counted
+type countingRepository struct {
+	fakeRepository
+	counts int
+}
+
+func (r *countingRepository) CountByStatus(ctx context.Context) (map[entity.TaskStatus]int64, error) {
+	r.counts++
+	return r.fakeRepository.CountByStatus(ctx)
+}
+
+func TestBacklogLimit(t *testing.T) {
+	repo := &countingRepository{}
+	svc := NewService(repo, WithBacklogLimit(2, 8))
+	newTask := func(priority int) *entity.Task {
+		return entity.NewTask("send_email", nil, "https://example.com/callback", time.Now(), priority)
+	}
+	ctx := domain.WithTenant(context.Background(), "acme")
+
+	// The count is cached, so the backlog may overshoot until it is refreshed
+	for i := 0; i < 3; i++ {
+		require.NoError(t, svc.CreateTask(ctx, newTask(0)))
+	}
+	assert.Equal(t, 1, repo.counts, "counted once per interval")
+
+	svc.backlog.countedAt = time.Time{}
+	err := svc.CreateTask(ctx, newTask(5))
+	assert.True(t, errors.Is(err, domain.ErrQueueFull), "got %v", err)
+	assert.Equal(t, 2, repo.counts)
+
+	// Urgent tasks bypass the limit
+	require.NoError(t, svc.CreateTask(ctx, newTask(8)))
+
+	status, ok := svc.Backlog()
+	require.True(t, ok)
+	assert.Equal(t, int64(3), status.Pending)
+	assert.Equal(t, int64(2), status.MaxPending)
+	assert.True(t, status.Full)
+	assert.False(t, status.CountedAt.IsZero())
+
+	// Imports are refused task by task
+	results, err := svc.ImportTasks(ctx, []*entity.Task{newTask(0), newTask(9)}, false)
+	require.NoError(t, err)
+	assert.True(t, errors.Is(results[0].Err, domain.ErrQueueFull))
+	assert.NoError(t, results[1].Err)
+
+	// Once the backlog drains below the limit, tasks are accepted again
+	for _, task := range repo.tasks {
+		task.Status = entity.TaskStatusCompleted
+	}
+	svc.backlog.countedAt = time.Time{}
+	require.NoError(t, svc.CreateTask(ctx, newTask(0)))
+}
+
+func TestBacklogWithoutLimit(t *testing.T) {
+	repo := &countingRepository{}
+	svc := NewService(repo, WithBacklogLimit(0, 0))
+
+	require.NoError(t, svc.CreateTask(context.Background(), &entity.Task{}))
+	_, ok := svc.Backlog()
+	assert.False(t, ok)
+	assert.Zero(t, repo.counts)
+}
@@ -0,0 +1,171 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+
+	"go.uber.org/zap"
+)
+
+// MaxBulkLimit caps the tasks a single bulk operation may affect
+const MaxBulkLimit = 10000
+
+// deletableStatuses are the statuses bulk delete applies to, matching Task.CanBeDeleted
+var deletableStatuses = []entity.TaskStatus{entity.TaskStatusWaiting, entity.TaskStatusPending, entity.TaskStatusFailed}
+
+// retryableStatuses are the statuses bulk retry applies to
+var retryableStatuses = []entity.TaskStatus{entity.TaskStatusFailed}
+
+// BulkSelector selects the tasks of a bulk operation, either by ID or by filter but not both
+// Tasks in statuses the operation doesn't apply to are skipped
+type BulkSelector struct {
+	IDs      []string
+	Status   *entity.TaskStatus
+	Tag      string
+	DateFrom *time.Time
+	DateTo   *time.Time
+	Limit    int // Maximum tasks affected, oldest first; 1 to MaxBulkLimit
+	DryRun   bool
+}
+
+// BulkResult reports the tasks a bulk operation affected, or would affect on a dry run
+type BulkResult struct {
+	Count  int64
+	DryRun bool
+	IDs    []string // Affected tasks; empty on a dry run
+}
+
+// BulkDelete soft deletes the selected pending, waiting and failed tasks
+// Tasks waiting on them follow their dependency failure policy, as with DeleteTask
+func (s *Service) BulkDelete(ctx context.Context, sel BulkSelector, deletedBy string) (*BulkResult, error) {
+	filter, err := sel.filter(deletableStatuses)
+	if err != nil {
+		return nil, err
+	}
+	if sel.DryRun {
+		return s.countBulk(ctx, filter)
+	}
+
+	ids, err := s.repo.BulkSoftDelete(ctx, filter, deletedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete tasks: %w", err)
+	}
+	if err := s.releaseDependentsOfCancelled(ctx, ids); err != nil {
+		s.logger.Error("Failed to release tasks depending on bulk-deleted tasks",
+			zap.Int("deleted", len(ids)),
+			zap.Error(err))
+	}
+	return &BulkResult{Count: int64(len(ids)), IDs: ids}, nil
+}
+
+// BulkRetry resets the selected failed tasks to pending with their retry count cleared
+func (s *Service) BulkRetry(ctx context.Context, sel BulkSelector) (*BulkResult, error) {
+	filter, err := sel.filter(retryableStatuses)
+	if err != nil {
+		return nil, err
+	}
+	if sel.DryRun {
+		return s.countBulk(ctx, filter)
+	}
+
+	ids, err := s.repo.BulkRetry(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry tasks: %w", err)
+	}
+	return &BulkResult{Count: int64(len(ids)), IDs: ids}, nil
+}
+
+func (s *Service) countBulk(ctx context.Context, filter repository.BulkFilter) (*BulkResult, error) {
+	count, err := s.repo.CountBulk(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+	return &BulkResult{Count: count, DryRun: true}, nil
+}
+
+// releaseDependentsOfCancelled resolves the tasks waiting on any of the cancelled tasks
+func (s *Service) releaseDependentsOfCancelled(ctx context.Context, ids []string) error {
+	now := time.Now()
+	waiting := entity.TaskStatusWaiting
+	for start := 0; start < len(ids); start += dependentsBatchSize {
+		parentIDs := ids[start:min(start+dependentsBatchSize, len(ids))]
+		for {
+			children, _, err := s.repo.List(ctx, repository.TaskFilter{
+				ParentIDs: parentIDs,
+				Status:    &waiting,
+				Page:      1,
+				Limit:     dependentsBatchSize,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, child := range children {
+				parent := &entity.Task{ID: *child.DependsOn, DeletedAt: &now}
+				resolveDependent(child, parent)
+				if err := s.repo.Update(ctx, child); err != nil {
+					return fmt.Errorf("failed to release task %s: %w", child.ID, err)
+				}
+				s.logReleased(child, parent)
+				if child.Status == entity.TaskStatusDeadLettered {
+					if err := s.resolveDependents(ctx, child, 1); err != nil {
+						return err
+					}
+				}
+			}
+
+			// Released tasks no longer match, so the next query returns the rest
+			if len(children) < dependentsBatchSize {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// filter validates the selector and builds the repository filter for an operation that
+// applies to the given statuses
+func (sel BulkSelector) filter(applicable []entity.TaskStatus) (repository.BulkFilter, error) {
+	filter := repository.BulkFilter{
+		IDs:      sel.IDs,
+		Statuses: applicable,
+		Tag:      sel.Tag,
+		DateFrom: sel.DateFrom,
+		DateTo:   sel.DateTo,
+		Limit:    sel.Limit,
+	}
+
+	if sel.Limit < 1 || sel.Limit > MaxBulkLimit {
+		return filter, fmt.Errorf("%w: limit must be between 1 and %d", domain.ErrBadParamInput, MaxBulkLimit)
+	}
+
+	byFilter := sel.Status != nil || sel.Tag != "" || sel.DateFrom != nil || sel.DateTo != nil
+	switch {
+	case len(sel.IDs) > 0 && byFilter:
+		return filter, fmt.Errorf("%w: select tasks by ids or by filter, not both", domain.ErrBadParamInput)
+	case len(sel.IDs) == 0 && !byFilter:
+		return filter, fmt.Errorf("%w: ids or a filter (status, tag, date range) is required", domain.ErrBadParamInput)
+	}
+
+	if sel.DateFrom != nil && sel.DateTo != nil && sel.DateFrom.After(*sel.DateTo) {
+		return filter, fmt.Errorf("%w: date_from must not be after date_to", domain.ErrBadParamInput)
+	}
+
+	if sel.Status != nil {
+		valid := false
+		for _, status := range applicable {
+			valid = valid || status == *sel.Status
+		}
+		if !valid {
+			return filter, fmt.Errorf("%w: operation does not apply to %s tasks", domain.ErrBadParamInput, *sel.Status)
+		}
+		filter.Statuses = []entity.TaskStatus{*sel.Status}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,93 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+)
+
+func TestBulkSelectorValidation(t *testing.T) {
+	svc := NewService(&fakeRepository{})
+	ctx := context.Background()
+	failed := entity.TaskStatusFailed
+	completed := entity.TaskStatusCompleted
+	later, earlier := time.Now(), time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		sel  BulkSelector
+	}{
+		{"Limit is required", BulkSelector{Status: &failed}},
+		{"Limit is capped", BulkSelector{Status: &failed, Limit: MaxBulkLimit + 1}},
+		{"Selection is required", BulkSelector{Limit: 10}},
+		{"IDs and filter are exclusive", BulkSelector{IDs: []string{"a"}, Tag: "billing", Limit: 10}},
+		{"Date range is ordered", BulkSelector{DateFrom: &later, DateTo: &earlier, Limit: 10}},
+		{"Status must apply", BulkSelector{Status: &completed, Limit: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.BulkDelete(ctx, tt.sel, "test")
+			assert.True(t, errors.Is(err, domain.ErrBadParamInput), "got %v", err)
+		})
+	}
+
+	pending := entity.TaskStatusPending
+	_, err := svc.BulkRetry(ctx, BulkSelector{Status: &pending, Limit: 10})
+	assert.True(t, errors.Is(err, domain.ErrBadParamInput), "only failed tasks can be retried")
+}
+
+func TestBulkRetry(t *testing.T) {
+	repo := &fakeRepository{}
+	for i := 0; i < 5; i++ {
+		repo.tasks = append(repo.tasks, &entity.Task{ID: fmt.Sprintf("failed-%d", i), Status: entity.TaskStatusFailed, RetryCount: 3, Tags: []string{"deploy-42"}})
+	}
+	repo.tasks = append(repo.tasks,
+		&entity.Task{ID: "other", Status: entity.TaskStatusFailed},
+		&entity.Task{ID: "done", Status: entity.TaskStatusCompleted, Tags: []string{"deploy-42"}},
+	)
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	result, err := svc.BulkRetry(ctx, BulkSelector{Tag: "deploy-42", Limit: 3, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), result.Count, "the dry run count respects the limit")
+	assert.True(t, result.DryRun)
+	assert.Equal(t, entity.TaskStatusFailed, status(t, repo, "failed-0"), "a dry run changes nothing")
+
+	result, err = svc.BulkRetry(ctx, BulkSelector{Tag: "deploy-42", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.Count)
+	assert.Len(t, result.IDs, 5)
+	assert.Equal(t, entity.TaskStatusPending, status(t, repo, "failed-4"))
+	assert.Equal(t, entity.TaskStatusFailed, status(t, repo, "other"))
+	assert.Equal(t, entity.TaskStatusCompleted, status(t, repo, "done"))
+}
+
+func TestBulkDelete(t *testing.T) {
+	repo := &fakeRepository{}
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	require.NoError(t, svc.CreateTask(ctx, &entity.Task{ID: "pdf", Status: entity.TaskStatusPending}))
+	require.NoError(t, svc.CreateTask(ctx, &entity.Task{ID: "running", Status: entity.TaskStatusProcessing}))
+	createDependent(t, svc, "invoice", "pdf", "")
+	createDependent(t, svc, "alert", "pdf", entity.DependencyFailureRunAnyway)
+	createDependent(t, svc, "receipt", "invoice", "")
+
+	result, err := svc.BulkDelete(ctx, BulkSelector{IDs: []string{"pdf", "running"}, Limit: 10}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pdf"}, result.IDs, "processing tasks cannot be deleted")
+
+	assert.Equal(t, entity.TaskStatusDeadLettered, status(t, repo, "invoice"))
+	assert.Equal(t, entity.TaskStatusPending, status(t, repo, "alert"))
+	assert.Equal(t, entity.TaskStatusDeadLettered, status(t, repo, "receipt"))
+	assert.Equal(t, entity.TaskStatusProcessing, status(t, repo, "running"))
+}
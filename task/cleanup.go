@@ -0,0 +1,57 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+)
+
+// CleanupRequest selects the finished tasks an on-demand cleanup removes
+type CleanupRequest struct {
+	Statuses  []entity.TaskStatus // Any of repository.CleanableStatuses
+	OlderThan time.Duration       // Tasks that finished at least this long ago
+	DryRun    bool                // Only count the tasks
+}
+
+// Validate checks that the request selects cleanable statuses and a positive age
+func (r CleanupRequest) Validate() error {
+	if len(r.Statuses) == 0 {
+		return fmt.Errorf("at least one status is required")
+	}
+	for _, status := range r.Statuses {
+		if !slices.Contains(repository.CleanableStatuses, status) {
+			return fmt.Errorf("status %q cannot be cleaned up: want one of %v", status, repository.CleanableStatuses)
+		}
+	}
+	if r.OlderThan <= 0 {
+		return fmt.Errorf("older_than must be positive")
+	}
+	return nil
+}
+
+// Cleanup deletes the finished tasks the request selects now, once any cleanup in progress is
+// done, or counts them in a dry run. Tasks are archived as in scheduled cleanups, and the
+// cleanup runs whether or not this replica is the leader
+func (s *Scheduler) Cleanup(ctx context.Context, req CleanupRequest) (*repository.CleanupResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	cutoff := s.clock.Now().Add(-req.OlderThan)
+	cleanup := repository.Cleanup{
+		Cutoffs:      make(map[entity.TaskStatus]time.Time, len(req.Statuses)),
+		DryRun:       req.DryRun,
+		Archive:      s.retention.Archive,
+		BeforeDelete: s.retention.BeforeDelete,
+	}
+	for _, status := range req.Statuses {
+		cleanup.Cutoffs[status] = cutoff
+	}
+	return s.runCleanup(ctx, "on_demand", func() (*repository.CleanupResult, error) {
+		return s.taskRepo.CleanupTasks(ctx, cleanup)
+	})
+}
@@ -0,0 +1,94 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/clock"
+	"github.com/usual2970/later/repository/memory"
+)
+
+func TestSchedulerCleanupOnDemand(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	repo := memory.NewTaskRepositoryWithClock(clk)
+
+	seed := func(status entity.TaskStatus, age time.Duration) *entity.Task {
+		finished := clk.Now().Add(-age)
+		task := entity.NewTask("send_email", nil, "https://example.com/cb", finished, 0)
+		task.Status = status
+		task.CreatedAt = finished
+		task.CompletedAt = &finished
+		require.NoError(t, repo.Create(ctx, task))
+		require.NoError(t, repo.Update(ctx, task))
+		return task
+	}
+	old := seed(entity.TaskStatusCompleted, 48*time.Hour)
+	recent := seed(entity.TaskStatusCompleted, time.Hour)
+	dead := seed(entity.TaskStatusDeadLettered, 48*time.Hour)
+
+	// Retentions far longer than the tasks' ages, so only on-demand cleanups remove them
+	scheduler := NewScheduler(repo, &recordingPool{}, SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        time.Hour,
+		CompletedRetention:     365 * 24 * time.Hour,
+		Logger:                 zap.NewNop(),
+		Clock:                  clk,
+	})
+	req := CleanupRequest{Statuses: []entity.TaskStatus{entity.TaskStatusCompleted}, OlderThan: 24 * time.Hour, DryRun: true}
+
+	result, err := scheduler.Cleanup(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, map[entity.TaskStatus]int64{entity.TaskStatusCompleted: 1}, result.ByStatus)
+	assert.Zero(t, result.Deleted)
+	_, err = repo.FindByID(ctx, old.ID)
+	require.NoError(t, err, "a dry run deletes nothing")
+
+	req.DryRun = false
+	result, err = scheduler.Cleanup(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Deleted)
+	_, err = repo.FindByID(ctx, old.ID)
+	assert.Error(t, err)
+	for _, task := range []*entity.Task{recent, dead} {
+		_, err = repo.FindByID(ctx, task.ID)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int64(1), scheduler.CleanupCounts().Deleted)
+
+	for _, invalid := range []CleanupRequest{
+		{OlderThan: time.Hour},
+		{Statuses: []entity.TaskStatus{entity.TaskStatusPending}, OlderThan: time.Hour},
+		{Statuses: []entity.TaskStatus{entity.TaskStatusCompleted}},
+	} {
+		_, err := scheduler.Cleanup(ctx, invalid)
+		assert.Error(t, err, "%+v", invalid)
+	}
+}
+
+func TestSchedulerCleanupWaitsForRunningCleanup(t *testing.T) {
+	scheduler := NewScheduler(memory.NewTaskRepository(), &recordingPool{}, SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        time.Hour,
+		Logger:                 zap.NewNop(),
+	})
+
+	// A cleanup in progress holds the lock
+	scheduler.cleanupLock <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := scheduler.Cleanup(ctx, CleanupRequest{Statuses: []entity.TaskStatus{entity.TaskStatusCompleted}, OlderThan: time.Hour})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	<-scheduler.cleanupLock
+	_, err = scheduler.Cleanup(context.Background(), CleanupRequest{Statuses: []entity.TaskStatus{entity.TaskStatusCompleted}, OlderThan: time.Hour})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,217 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/usual2970/later/domain/entity"
+
+	"go.uber.org/zap"
+)
+
+// DeadLetterAlertClearRatio is the hysteresis of dead letter alerts: a raised alert only clears
+// once the dead-lettered count and its growth are both back to at most this share of their
+// thresholds, so a count hovering around a threshold doesn't raise and clear it on every check
+const DeadLetterAlertClearRatio = 0.8
+
+// deadLetterGrowthWindow is the period GrowthPerHour is measured over
+const deadLetterGrowthWindow = time.Hour
+
+// alertTimeout bounds each notification of the Alerter
+const alertTimeout = 10 * time.Second
+
+// DeadLetterAlertConfig raises an alert when the dead letter queue grows too large or too fast
+// The leader's cleanup tick checks the dead-lettered count; zero thresholds are disabled
+type DeadLetterAlertConfig struct {
+	Threshold     int64 // Raise once more than this many tasks are dead-lettered
+	GrowthPerHour int64 // Raise once the count grew by more than this over the last hour
+
+	// Alerter is notified when the alert is raised and when it clears; nil only logs the alert
+	// and reports it through Scheduler.DeadLetterAlert
+	Alerter Alerter
+}
+
+// Enabled reports whether either threshold is set
+func (c DeadLetterAlertConfig) Enabled() bool {
+	return c.Threshold > 0 || c.GrowthPerHour > 0
+}
+
+// Validate checks the thresholds
+func (c DeadLetterAlertConfig) Validate() error {
+	if c.Threshold < 0 || c.GrowthPerHour < 0 {
+		return fmt.Errorf("dead letter alert thresholds cannot be negative")
+	}
+	return nil
+}
+
+// Alerter delivers alerts raised by the scheduler, e.g. to a webhook or a paging service
+type Alerter interface {
+	// Alert delivers a raised or cleared alert; a failed delivery is retried on the next check
+	Alert(ctx context.Context, alert DeadLetterAlert) error
+}
+
+// AlerterFunc adapts a function to an Alerter
+type AlerterFunc func(ctx context.Context, alert DeadLetterAlert) error
+
+// Alert calls f
+func (f AlerterFunc) Alert(ctx context.Context, alert DeadLetterAlert) error {
+	return f(ctx, alert)
+}
+
+// Reasons a dead letter alert is raised
+const (
+	DeadLetterAlertThreshold = "threshold" // More tasks are dead-lettered than the threshold
+	DeadLetterAlertGrowth    = "growth"    // The count grew faster than the growth threshold
+)
+
+// DeadLetterAlert summarizes the dead letter queue when an alert is raised or cleared
+type DeadLetterAlert struct {
+	Firing         bool      `json:"firing"`           // False once the alert clears
+	Reasons        []string  `json:"reasons"`          // Why it was raised; empty once cleared
+	DeadLettered   int64     `json:"dead_lettered"`    // Tasks dead-lettered at the check
+	GrowthLastHour int64     `json:"growth_last_hour"` // Increase of the count over the last hour
+	Threshold      int64     `json:"threshold"`
+	GrowthPerHour  int64     `json:"growth_per_hour"`
+	Since          time.Time `json:"since"` // When the alert was raised
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// deadLetterSample is the dead-lettered count at a check
+type deadLetterSample struct {
+	at    time.Time
+	count int64
+}
+
+// deadLetterMonitor tracks the dead-lettered count across checks and decides when the alert is
+// raised or cleared
+type deadLetterMonitor struct {
+	cfg DeadLetterAlertConfig
+
+	mu       sync.Mutex
+	samples  []deadLetterSample // Oldest first, back to the last one at least an hour old
+	current  DeadLetterAlert    // The latest raised or cleared alert
+	notified bool               // Whether the Alerter accepted current
+}
+
+func newDeadLetterMonitor(cfg DeadLetterAlertConfig) *deadLetterMonitor {
+	return &deadLetterMonitor{cfg: cfg, notified: true}
+}
+
+// observe records the count at now and returns the alert to deliver, if any: one just raised or
+// cleared, reported by changed, or one whose delivery failed before
+func (m *deadLetterMonitor) observe(now time.Time, count int64) (alert *DeadLetterAlert, changed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.samples = append(m.samples, deadLetterSample{at: now, count: count})
+	cutoff := now.Add(-deadLetterGrowthWindow)
+	for len(m.samples) > 1 && !m.samples[1].at.After(cutoff) {
+		m.samples = m.samples[1:]
+	}
+	growth := max(count-m.samples[0].count, 0)
+
+	var reasons []string
+	if m.cfg.Threshold > 0 && count > m.cfg.Threshold {
+		reasons = append(reasons, DeadLetterAlertThreshold)
+	}
+	if m.cfg.GrowthPerHour > 0 && growth > m.cfg.GrowthPerHour {
+		reasons = append(reasons, DeadLetterAlertGrowth)
+	}
+
+	switch {
+	case !m.current.Firing && len(reasons) > 0:
+		m.current = DeadLetterAlert{Firing: true, Reasons: reasons, Since: now}
+		m.notified, changed = false, true
+	case m.current.Firing && len(reasons) == 0 && m.cleared(count, growth):
+		m.current = DeadLetterAlert{Since: m.current.Since}
+		m.notified, changed = false, true
+	}
+	m.current.DeadLettered = count
+	m.current.GrowthLastHour = growth
+	m.current.Threshold = m.cfg.Threshold
+	m.current.GrowthPerHour = m.cfg.GrowthPerHour
+	m.current.CheckedAt = now
+
+	if m.notified {
+		return nil, false
+	}
+	pending := m.current
+	return &pending, changed
+}
+
+// cleared reports whether a raised alert's count and growth have fallen far enough below the
+// thresholds for it to clear
+func (m *deadLetterMonitor) cleared(count, growth int64) bool {
+	below := func(value, threshold int64) bool {
+		return threshold <= 0 || float64(value) <= DeadLetterAlertClearRatio*float64(threshold)
+	}
+	return below(count, m.cfg.Threshold) && below(growth, m.cfg.GrowthPerHour)
+}
+
+// delivered marks alert as accepted by the Alerter, unless the state changed meanwhile
+func (m *deadLetterMonitor) delivered(alert *DeadLetterAlert) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current.Firing == alert.Firing && m.current.Since.Equal(alert.Since) {
+		m.notified = true
+	}
+}
+
+// status returns the latest alert state
+func (m *deadLetterMonitor) status() DeadLetterAlert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current
+}
+
+// DeadLetterAlert returns the state of the dead letter alert as of the last check, and whether
+// the alert is configured. Only the leader checks, so other replicas never report it firing
+func (s *Scheduler) DeadLetterAlert() (DeadLetterAlert, bool) {
+	if s.deadLetters == nil {
+		return DeadLetterAlert{}, false
+	}
+	return s.deadLetters.status(), true
+}
+
+// checkDeadLetters counts dead-lettered tasks and raises or clears the dead letter alert
+func (s *Scheduler) checkDeadLetters(ctx context.Context) {
+	if s.deadLetters == nil {
+		return
+	}
+	counts, err := s.taskRepo.CountByStatus(ctx)
+	if err != nil {
+		s.logger.Error("Failed to count dead-lettered tasks", zap.Error(err))
+		return
+	}
+
+	alert, changed := s.deadLetters.observe(s.clock.Now(), counts[entity.TaskStatusDeadLettered])
+	if alert == nil {
+		return
+	}
+	if changed {
+		fields := []zap.Field{
+			zap.Strings("reasons", alert.Reasons),
+			zap.Int64("dead_lettered", alert.DeadLettered),
+			zap.Int64("growth_last_hour", alert.GrowthLastHour),
+		}
+		if alert.Firing {
+			s.logger.Warn("Dead letter queue alert raised", fields...)
+		} else {
+			s.logger.Info("Dead letter queue alert cleared", fields...)
+		}
+	}
+
+	if s.deadLetters.cfg.Alerter == nil {
+		s.deadLetters.delivered(alert)
+		return
+	}
+	alertCtx, cancel := context.WithTimeout(ctx, alertTimeout)
+	defer cancel()
+	if err := s.deadLetters.cfg.Alerter.Alert(alertCtx, *alert); err != nil {
+		s.logger.Error("Failed to deliver dead letter queue alert", zap.Error(err))
+		return
+	}
+	s.deadLetters.delivered(alert)
+}
@@ -0,0 +1,124 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/infrastructure/clock"
+	"github.com/usual2970/later/repository/memory"
+)
+
+func TestDeadLetterMonitorThresholdHysteresis(t *testing.T) {
+	m := newDeadLetterMonitor(DeadLetterAlertConfig{Threshold: 10})
+	now := time.Now()
+
+	alert, _ := m.observe(now, 10)
+	assert.Nil(t, alert, "the threshold itself doesn't raise the alert")
+
+	alert, changed := m.observe(now, 11)
+	require.NotNil(t, alert)
+	assert.True(t, changed)
+	assert.True(t, alert.Firing)
+	assert.Equal(t, []string{DeadLetterAlertThreshold}, alert.Reasons)
+	m.delivered(alert)
+
+	// Dropping just below the threshold keeps the alert raised
+	for _, count := range []int64{10, 9, 11} {
+		alert, _ = m.observe(now, count)
+		assert.Nil(t, alert, "count %d", count)
+		assert.True(t, m.status().Firing, "count %d", count)
+	}
+
+	alert, changed = m.observe(now, 8)
+	require.NotNil(t, alert)
+	assert.True(t, changed)
+	assert.False(t, alert.Firing)
+	assert.Empty(t, alert.Reasons)
+	assert.Equal(t, int64(8), alert.DeadLettered)
+}
+
+func TestDeadLetterMonitorGrowth(t *testing.T) {
+	m := newDeadLetterMonitor(DeadLetterAlertConfig{GrowthPerHour: 5})
+	start := time.Now()
+
+	alert, _ := m.observe(start, 100)
+	assert.Nil(t, alert, "a large but steady queue doesn't raise the growth alert")
+	alert, _ = m.observe(start.Add(30*time.Minute), 104)
+	assert.Nil(t, alert)
+
+	alert, _ = m.observe(start.Add(50*time.Minute), 106)
+	require.NotNil(t, alert)
+	assert.True(t, alert.Firing)
+	assert.Equal(t, []string{DeadLetterAlertGrowth}, alert.Reasons)
+	assert.Equal(t, int64(6), alert.GrowthLastHour)
+	m.delivered(alert)
+
+	// Growth is measured against the count an hour ago, here at 50 minutes
+	alert, _ = m.observe(start.Add(2*time.Hour), 106)
+	require.NotNil(t, alert)
+	assert.False(t, alert.Firing)
+	assert.Zero(t, alert.GrowthLastHour)
+}
+
+func TestSchedulerDeliversDeadLetterAlerts(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	repo := memory.NewTaskRepositoryWithClock(clk)
+	deadLetter := func() {
+		task := entity.NewTask("send_email", nil, "https://example.com/cb", clk.Now(), 0)
+		task.Status = entity.TaskStatusDeadLettered
+		require.NoError(t, repo.Create(ctx, task))
+		require.NoError(t, repo.Update(ctx, task))
+	}
+
+	var delivered []DeadLetterAlert
+	var deliveryErr error
+	scheduler := NewScheduler(repo, &recordingPool{}, SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        time.Hour,
+		DeadLetterAlert: DeadLetterAlertConfig{
+			Threshold: 1,
+			Alerter: AlerterFunc(func(ctx context.Context, alert DeadLetterAlert) error {
+				if deliveryErr != nil {
+					return deliveryErr
+				}
+				delivered = append(delivered, alert)
+				return nil
+			}),
+		},
+		Logger: zap.NewNop(),
+		Clock:  clk,
+	})
+
+	deadLetter()
+	scheduler.cleanupExpiredTasks()
+	status, ok := scheduler.DeadLetterAlert()
+	require.True(t, ok)
+	assert.False(t, status.Firing)
+	assert.Empty(t, delivered)
+
+	// A failed delivery is retried on the next check
+	deadLetter()
+	deliveryErr = errors.New("connection refused")
+	scheduler.cleanupExpiredTasks()
+	status, _ = scheduler.DeadLetterAlert()
+	assert.True(t, status.Firing)
+	assert.Equal(t, int64(2), status.DeadLettered)
+
+	deliveryErr = nil
+	clk.Advance(time.Minute)
+	scheduler.cleanupExpiredTasks()
+	require.Len(t, delivered, 1)
+	assert.True(t, delivered[0].Firing)
+
+	scheduler.cleanupExpiredTasks()
+	assert.Len(t, delivered, 1, "a delivered alert isn't sent again")
+}
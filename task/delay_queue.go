@@ -0,0 +1,197 @@
+package task
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// DefaultDelayQueueSize bounds the tasks held in memory; tasks beyond it are left to polling
+const DefaultDelayQueueSize = 10000
+
+// delayEntry is a task waiting in the delay queue
+type delayEntry struct {
+	id    string
+	at    time.Time
+	index int // Position in the heap, maintained by delayHeap
+}
+
+// delayHeap is a min-heap of entries ordered by due time
+type delayHeap []*delayEntry
+
+func (h delayHeap) Len() int           { return len(h) }
+func (h delayHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h delayHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *delayHeap) Push(x any) {
+	entry := x.(*delayEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *delayHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// DelayQueue holds the IDs of tasks due within the next poll interval and hands each to a
+// dispatch function at its scheduled time, so near-future tasks don't wait for a poll
+// It only speeds dispatch up: the database stays the source of truth, so the dispatch function
+// must re-check the task, and anything lost from memory (crash, restart, full queue) is still
+// found by the next poll
+type DelayQueue struct {
+	horizon time.Duration
+	size    int
+
+	mu      sync.Mutex
+	entries delayHeap
+	byID    map[string]*delayEntry
+
+	changed chan struct{} // Signals Run that the earliest entry may have changed
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+// NewDelayQueue creates a queue accepting tasks due within horizon, holding at most size of them
+// A zero size uses DefaultDelayQueueSize
+func NewDelayQueue(horizon time.Duration, size int) *DelayQueue {
+	if size <= 0 {
+		size = DefaultDelayQueueSize
+	}
+	return &DelayQueue{
+		horizon: horizon,
+		size:    size,
+		byID:    make(map[string]*delayEntry),
+		changed: make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Horizon returns how far ahead tasks are accepted
+func (q *DelayQueue) Horizon() time.Duration {
+	return q.horizon
+}
+
+// Add schedules the task to be dispatched at at, replacing any earlier entry for it
+// It reports false, dropping any earlier entry, when at is beyond the horizon or the queue is full
+func (q *DelayQueue) Add(id string, at time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if at.After(time.Now().Add(q.horizon)) {
+		q.removeLocked(id)
+		return false
+	}
+
+	if entry, ok := q.byID[id]; ok {
+		entry.at = at
+		heap.Fix(&q.entries, entry.index)
+	} else {
+		if len(q.entries) >= q.size {
+			return false
+		}
+		entry := &delayEntry{id: id, at: at}
+		heap.Push(&q.entries, entry)
+		q.byID[id] = entry
+	}
+	q.signal()
+	return true
+}
+
+// Remove drops the task's entry, e.g. when it is deleted
+func (q *DelayQueue) Remove(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.removeLocked(id)
+}
+
+// Len returns the number of tasks waiting
+func (q *DelayQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+func (q *DelayQueue) removeLocked(id string) {
+	if entry, ok := q.byID[id]; ok {
+		heap.Remove(&q.entries, entry.index)
+		delete(q.byID, id)
+		q.signal()
+	}
+}
+
+// signal wakes Run to re-arm its timer; signals are coalesced
+func (q *DelayQueue) signal() {
+	select {
+	case q.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run calls dispatch with each task ID as it becomes due until Stop is called
+func (q *DelayQueue) Run(dispatch func(id string)) {
+	defer close(q.done)
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		for _, id := range q.popDue() {
+			dispatch(id)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(q.untilNext())
+
+		select {
+		case <-timer.C:
+		case <-q.changed:
+		case <-q.quit:
+			return
+		}
+	}
+}
+
+// Stop stops dispatching and waits for Run to return
+func (q *DelayQueue) Stop() {
+	close(q.quit)
+	<-q.done
+}
+
+// popDue removes and returns the entries that are due
+func (q *DelayQueue) popDue() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var due []string
+	now := time.Now()
+	for len(q.entries) > 0 && !q.entries[0].at.After(now) {
+		entry := heap.Pop(&q.entries).(*delayEntry)
+		delete(q.byID, entry.id)
+		due = append(due, entry.id)
+	}
+	return due
+}
+
+// untilNext returns how long until the earliest entry is due, or an hour if there is none
+func (q *DelayQueue) untilNext() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) == 0 {
+		return time.Hour
+	}
+	return time.Until(q.entries[0].at)
+}
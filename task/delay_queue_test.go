@@ -0,0 +1,158 @@
+package task
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+)
+
+// dispatchRecorder records when the delay queue dispatched each task
+type dispatchRecorder struct {
+	mu    sync.Mutex
+	order []string
+	at    map[string]time.Time
+}
+
+func (r *dispatchRecorder) dispatch(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.order = append(r.order, id)
+	r.at[id] = time.Now()
+}
+
+func (r *dispatchRecorder) dispatched() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.order...)
+}
+
+func TestDelayQueueDispatchesAtScheduledTime(t *testing.T) {
+	q := NewDelayQueue(time.Second, 0)
+	rec := &dispatchRecorder{at: make(map[string]time.Time)}
+	go q.Run(rec.dispatch)
+	defer q.Stop()
+
+	start := time.Now()
+	due := map[string]time.Time{
+		"c": start.Add(90 * time.Millisecond),
+		"a": start.Add(30 * time.Millisecond),
+		"b": start.Add(60 * time.Millisecond),
+	}
+	for id, at := range due {
+		require.True(t, q.Add(id, at))
+	}
+
+	require.Eventually(t, func() bool { return len(rec.dispatched()) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"a", "b", "c"}, rec.dispatched())
+	for id, at := range due {
+		assert.False(t, rec.at[id].Before(at), "%s dispatched early", id)
+		assert.Less(t, rec.at[id].Sub(at), 20*time.Millisecond, "%s dispatched late", id)
+	}
+	assert.Zero(t, q.Len())
+}
+
+func TestDelayQueueInvalidation(t *testing.T) {
+	q := NewDelayQueue(time.Second, 2)
+	rec := &dispatchRecorder{at: make(map[string]time.Time)}
+	go q.Run(rec.dispatch)
+	defer q.Stop()
+
+	now := time.Now()
+	assert.False(t, q.Add("far", now.Add(time.Minute)), "tasks beyond the horizon are left to polling")
+
+	require.True(t, q.Add("deleted", now.Add(30*time.Millisecond)))
+	require.True(t, q.Add("rescheduled", now.Add(30*time.Millisecond)))
+	assert.False(t, q.Add("overflow", now.Add(30*time.Millisecond)), "a full queue leaves tasks to polling")
+
+	q.Remove("deleted")
+	require.True(t, q.Add("rescheduled", now.Add(80*time.Millisecond)), "rescheduling replaces the entry")
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, rec.dispatched())
+	require.Eventually(t, func() bool { return len(rec.dispatched()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"rescheduled"}, rec.dispatched())
+
+	require.True(t, q.Add("moved", now.Add(200*time.Millisecond)))
+	assert.False(t, q.Add("moved", now.Add(time.Minute)), "rescheduling beyond the horizon drops the entry")
+	assert.Zero(t, q.Len())
+}
+
+// delayedRepository serves tasks for the delay queue's re-check before dispatch
+type delayedRepository struct {
+	repository.TaskRepository
+
+	mu       sync.Mutex
+	tasks    map[string]*entity.Task
+	upcoming []*repository.ScheduledTask
+}
+
+func (r *delayedRepository) FindByID(ctx context.Context, id string) (*entity.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	task, ok := r.tasks[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	copied := *task
+	return &copied, nil
+}
+
+func (r *delayedRepository) FindDueTasks(ctx context.Context, minPriority int, limit int, skipGroups []string) ([]*entity.Task, error) {
+	return nil, nil
+}
+
+func (r *delayedRepository) FindFailedTasks(ctx context.Context, limit int) ([]*entity.Task, error) {
+	return nil, nil
+}
+
+func (r *delayedRepository) FindUpcomingTasks(ctx context.Context, before time.Time, limit int) ([]*repository.ScheduledTask, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.upcoming, nil
+}
+
+func TestSchedulerDelayQueue(t *testing.T) {
+	now := time.Now()
+	created := &entity.Task{ID: "created", Status: entity.TaskStatusPending, ScheduledAt: now.Add(1500 * time.Millisecond)}
+	polled := &entity.Task{ID: "polled", Status: entity.TaskStatusPending, ScheduledAt: now.Add(1200 * time.Millisecond)}
+	deleted := &entity.Task{ID: "deleted", Status: entity.TaskStatusPending, ScheduledAt: now.Add(1200 * time.Millisecond)}
+	repo := &delayedRepository{
+		tasks:    map[string]*entity.Task{"created": created, "polled": polled},
+		upcoming: []*repository.ScheduledTask{{ID: "polled", ScheduledAt: polled.ScheduledAt}},
+	}
+	pool := &timingPool{submitted: make(map[string]time.Time)}
+
+	// Polls are far apart, so only the delay queue can dispatch the tasks on time
+	scheduler := NewScheduler(repo, pool, SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		RetryInterval:          time.Hour,
+		CleanupInterval:        time.Hour,
+		DelayQueue:             true,
+	})
+	go scheduler.Start()
+	defer scheduler.Stop()
+
+	scheduler.ScheduleTask(created)
+	scheduler.ScheduleTask(deleted) // Soft-deleted before it is due, so the re-check doesn't find it
+
+	require.Eventually(t, func() bool { return pool.count() == 2 }, 3*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	assert.NotContains(t, pool.submitted, "deleted", "the database decides whether a task still runs")
+	for _, task := range []*entity.Task{created, polled} {
+		at := pool.submitted[task.ID]
+		assert.False(t, at.Before(task.ScheduledAt), "%s dispatched early", task.ID)
+		assert.Less(t, at.Sub(task.ScheduledAt), 50*time.Millisecond, "%s dispatched late", task.ID)
+	}
+}
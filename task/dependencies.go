@@ -0,0 +1,200 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"go.uber.org/zap"
+)
+
+// MaxDependencyDepth bounds how many ancestors a task chain may have
+const MaxDependencyDepth = 100
+
+// MaxListedChildren caps the dependent tasks returned with a task
+const MaxListedChildren = 100
+
+// dependentsBatchSize is how many waiting tasks are released per query when a parent finishes
+const dependentsBatchSize = 100
+
+// prepareDependency validates a new task's parent and sets its initial status: waiting until
+// the parent finishes, or already resolved if it has
+func (s *Service) prepareDependency(ctx context.Context, task *entity.Task) error {
+	if !task.DependencyFailurePolicy.Valid() {
+		return fmt.Errorf("%w: dependency_failure_policy must be %q or %q",
+			domain.ErrBadParamInput, entity.DependencyFailureDeadLetter, entity.DependencyFailureRunAnyway)
+	}
+	if task.DependsOn == nil {
+		if task.DependencyFailurePolicy != "" {
+			return fmt.Errorf("%w: dependency_failure_policy requires depends_on", domain.ErrBadParamInput)
+		}
+		return nil
+	}
+
+	// Walk up the chain; with the parent scoped to the caller's tenant, a missing ancestor
+	// means the parent isn't visible to them
+	parent, err := s.repo.FindByID(ctx, *task.DependsOn)
+	if errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("%w: parent task %s not found", domain.ErrBadParamInput, *task.DependsOn)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find parent task: %w", err)
+	}
+	seen := map[string]bool{task.ID: true}
+	for ancestor, depth := parent, 1; ; depth++ {
+		if seen[ancestor.ID] {
+			return fmt.Errorf("%w: depends_on would create a dependency cycle", domain.ErrBadParamInput)
+		}
+		if depth > MaxDependencyDepth {
+			return fmt.Errorf("%w: task chains cannot be deeper than %d", domain.ErrBadParamInput, MaxDependencyDepth)
+		}
+		seen[ancestor.ID] = true
+
+		if ancestor.DependsOn == nil {
+			break
+		}
+		if seen[*ancestor.DependsOn] {
+			return fmt.Errorf("%w: depends_on would create a dependency cycle", domain.ErrBadParamInput)
+		}
+		if ancestor, err = s.repo.FindByID(ctx, *ancestor.DependsOn); err != nil {
+			break // Deleted ancestors end the chain
+		}
+	}
+
+	task.Status = entity.TaskStatusWaiting
+	resolveDependent(task, parent)
+	return nil
+}
+
+// ResolveDependents releases the tasks waiting on a parent that has finished: they become pending
+// when it completed; when it was dead-lettered, expired or cancelled, each follows its failure policy
+// Tasks dead-lettered this way release their own dependents in turn
+func (s *Service) ResolveDependents(ctx context.Context, parent *entity.Task) error {
+	return s.resolveDependents(ctx, parent, 0)
+}
+
+func (s *Service) resolveDependents(ctx context.Context, parent *entity.Task, depth int) error {
+	if !parentFinished(parent) || depth > MaxDependencyDepth {
+		return nil
+	}
+
+	waiting := entity.TaskStatusWaiting
+	released := make(map[string]bool)
+	for {
+		children, _, err := s.repo.List(ctx, repository.TaskFilter{
+			DependsOn: &parent.ID,
+			Status:    &waiting,
+			Page:      1,
+			Limit:     dependentsBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find tasks depending on %s: %w", parent.ID, err)
+		}
+
+		for _, child := range children {
+			if released[child.ID] {
+				return fmt.Errorf("task %s depending on %s was not released", child.ID, parent.ID)
+			}
+			released[child.ID] = true
+
+			resolveDependent(child, parent)
+			if err := s.repo.Update(ctx, child); err != nil {
+				return fmt.Errorf("failed to release task %s: %w", child.ID, err)
+			}
+			s.logReleased(child, parent)
+			if child.Status == entity.TaskStatusDeadLettered {
+				if err := s.resolveDependents(ctx, child, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Released tasks no longer match, so the next query returns the rest
+		if len(children) < dependentsBatchSize {
+			return nil
+		}
+	}
+}
+
+// GetChildren returns the tasks that depend on the task, oldest first
+func (s *Service) GetChildren(ctx context.Context, id string) ([]*entity.Task, error) {
+	children, _, err := s.List(ctx, &repository.TaskFilter{
+		DependsOn: &id,
+		Page:      1,
+		Limit:     MaxListedChildren,
+		SortBy:    "created_at",
+		SortOrder: "asc",
+	})
+	return children, err
+}
+
+// releaseAfterCreate closes the race with a parent finishing while its child was being created:
+// the worker may have released the parent's dependents just before the child was stored
+func (s *Service) releaseAfterCreate(ctx context.Context, task *entity.Task) {
+	if task.Status != entity.TaskStatusWaiting {
+		return
+	}
+	parent, err := s.repo.FindByID(ctx, *task.DependsOn)
+	if err != nil || !parentFinished(parent) {
+		return
+	}
+
+	stored := *task
+	resolveDependent(&stored, parent)
+	if err := s.repo.Update(ctx, &stored); err != nil {
+		s.logger.Error("Failed to release task after its parent finished",
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID),
+			zap.Error(err))
+		return
+	}
+	s.logReleased(&stored, parent)
+	task.Status = stored.Status
+	task.ErrorMessage = stored.ErrorMessage
+}
+
+// logReleased records a task leaving the waiting status because its parent finished
+func (s *Service) logReleased(child, parent *entity.Task) {
+	s.logger.Info("Released dependent task",
+		zap.String("task_id", child.ID),
+		logger.RequestID(child.RequestID),
+		zap.String("parent_id", parent.ID),
+		zap.String("status", string(child.Status)))
+}
+
+// parentFinished reports whether a parent will never complete from here on: it completed,
+// was dead-lettered or expired, or was cancelled
+func parentFinished(parent *entity.Task) bool {
+	return parent.Status == entity.TaskStatusCompleted ||
+		parent.Status == entity.TaskStatusDeadLettered ||
+		parent.Status == entity.TaskStatusExpired ||
+		parent.IsDeleted()
+}
+
+// resolveDependent moves a waiting task on according to its parent's state; it is left
+// waiting while the parent can still complete
+func resolveDependent(child, parent *entity.Task) {
+	switch {
+	case !parentFinished(parent):
+		return
+	case parent.Status == entity.TaskStatusCompleted && !parent.IsDeleted():
+		child.Status = entity.TaskStatusPending
+	case child.RunsDespiteFailedDependency():
+		child.Status = entity.TaskStatusPending
+	default:
+		child.MarkAsDeadLettered()
+		reason := "dead-lettered"
+		if parent.IsDeleted() {
+			reason = "cancelled"
+		} else if parent.Status == entity.TaskStatusExpired {
+			reason = "expired"
+		}
+		errMsg := fmt.Sprintf("Dependency %s was %s", parent.ID, reason)
+		child.ErrorMessage = &errMsg
+	}
+}
@@ -0,0 +1,190 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/repository/memory"
+)
+
+// createDependent creates a pending task that depends on parentID
+func createDependent(t *testing.T, svc *Service, id, parentID string, policy entity.DependencyFailurePolicy) *entity.Task {
+	t.Helper()
+	task := &entity.Task{ID: id, Status: entity.TaskStatusPending, DependsOn: &parentID, DependencyFailurePolicy: policy}
+	require.NoError(t, svc.CreateTask(context.Background(), task))
+	return task
+}
+
+// status returns the stored status of a task
+func status(t *testing.T, repo repository.TaskRepository, id string) entity.TaskStatus {
+	t.Helper()
+	task, err := repo.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	return task.Status
+}
+
+func TestCreateDependentTask(t *testing.T) {
+	repo := memory.NewTaskRepository()
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	require.NoError(t, svc.CreateTask(ctx, &entity.Task{ID: "pdf", Status: entity.TaskStatusPending}))
+
+	invoice := createDependent(t, svc, "invoice", "pdf", "")
+	assert.Equal(t, entity.TaskStatusWaiting, invoice.Status)
+	assert.Equal(t, entity.TaskStatusWaiting, status(t, repo, "invoice"))
+
+	t.Run("Parent must exist", func(t *testing.T) {
+		missing := "missing"
+		err := svc.CreateTask(ctx, &entity.Task{ID: "orphan", Status: entity.TaskStatusPending, DependsOn: &missing})
+		assert.True(t, errors.Is(err, domain.ErrBadParamInput))
+	})
+
+	t.Run("Policy must be known", func(t *testing.T) {
+		parent := "pdf"
+		err := svc.CreateTask(ctx, &entity.Task{ID: "bad", DependsOn: &parent, DependencyFailurePolicy: "ignore"})
+		assert.True(t, errors.Is(err, domain.ErrBadParamInput))
+
+		err = svc.CreateTask(ctx, &entity.Task{ID: "no-parent", DependencyFailurePolicy: entity.DependencyFailureRunAnyway})
+		assert.True(t, errors.Is(err, domain.ErrBadParamInput), "a policy needs a parent")
+	})
+
+	t.Run("Cycles are rejected", func(t *testing.T) {
+		self := "self"
+		err := svc.CreateTask(ctx, &entity.Task{ID: "self", DependsOn: &self})
+		assert.True(t, errors.Is(err, domain.ErrBadParamInput))
+
+		// a -> b -> a, as if a had been stored depending on b before b existed
+		b := "b"
+		require.NoError(t, repo.Create(ctx, &entity.Task{ID: "a", Status: entity.TaskStatusWaiting, DependsOn: &b}))
+		err = svc.CreateTask(ctx, &entity.Task{ID: "b", DependsOn: &[]string{"a"}[0]})
+		assert.True(t, errors.Is(err, domain.ErrBadParamInput))
+		assert.Contains(t, err.Error(), "cycle")
+	})
+
+	t.Run("Chains are bounded", func(t *testing.T) {
+		chain := memory.NewTaskRepository()
+		require.NoError(t, chain.Create(ctx, &entity.Task{ID: "link-0", Status: entity.TaskStatusPending}))
+		for i := 1; i <= MaxDependencyDepth; i++ {
+			parent := fmt.Sprintf("link-%d", i-1)
+			require.NoError(t, chain.Create(ctx, &entity.Task{ID: fmt.Sprintf("link-%d", i), Status: entity.TaskStatusWaiting, DependsOn: &parent}))
+		}
+		parent := fmt.Sprintf("link-%d", MaxDependencyDepth)
+		err := NewService(chain).CreateTask(ctx, &entity.Task{ID: "too-deep", DependsOn: &parent})
+		assert.True(t, errors.Is(err, domain.ErrBadParamInput))
+	})
+}
+
+func TestCreateDependentOfFinishedParent(t *testing.T) {
+	repo := memory.NewTaskRepository()
+	require.NoError(t, repo.Create(context.Background(), &entity.Task{ID: "done", Status: entity.TaskStatusCompleted}))
+	require.NoError(t, repo.Create(context.Background(), &entity.Task{ID: "dead", Status: entity.TaskStatusDeadLettered}))
+	svc := NewService(repo)
+
+	assert.Equal(t, entity.TaskStatusPending, createDependent(t, svc, "after-done", "done", "").Status)
+	assert.Equal(t, entity.TaskStatusPending, createDependent(t, svc, "after-dead-anyway", "dead", entity.DependencyFailureRunAnyway).Status)
+
+	dead := createDependent(t, svc, "after-dead", "dead", "")
+	assert.Equal(t, entity.TaskStatusDeadLettered, dead.Status)
+	require.NotNil(t, dead.ErrorMessage)
+	assert.Contains(t, *dead.ErrorMessage, "dead")
+}
+
+func TestResolveDependentsOnCompletion(t *testing.T) {
+	repo := memory.NewTaskRepository()
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	require.NoError(t, svc.CreateTask(ctx, &entity.Task{ID: "pdf", Status: entity.TaskStatusPending}))
+	for i := 0; i < dependentsBatchSize+5; i++ {
+		createDependent(t, svc, fmt.Sprintf("invoice-%d", i), "pdf", "")
+	}
+	createDependent(t, svc, "email", "invoice-0", "")
+
+	parent, err := repo.FindByID(ctx, "pdf")
+	require.NoError(t, err)
+	parent.MarkAsCompleted(time.Now())
+	require.NoError(t, repo.Update(ctx, parent))
+	require.NoError(t, svc.ResolveDependents(ctx, parent))
+
+	for i := 0; i < dependentsBatchSize+5; i++ {
+		assert.Equal(t, entity.TaskStatusPending, status(t, repo, fmt.Sprintf("invoice-%d", i)))
+	}
+	assert.Equal(t, entity.TaskStatusWaiting, status(t, repo, "email"), "grandchildren wait for their own parent")
+
+	children, err := svc.GetChildren(ctx, "invoice-0")
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, "email", children[0].ID)
+}
+
+func TestResolveDependentsOnFailure(t *testing.T) {
+	repo := memory.NewTaskRepository()
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	require.NoError(t, svc.CreateTask(ctx, &entity.Task{ID: "pdf", Status: entity.TaskStatusPending}))
+	createDependent(t, svc, "invoice", "pdf", entity.DependencyFailureDeadLetter)
+	createDependent(t, svc, "alert", "pdf", entity.DependencyFailureRunAnyway)
+	createDependent(t, svc, "receipt", "invoice", "")
+	createDependent(t, svc, "cleanup", "invoice", entity.DependencyFailureRunAnyway)
+
+	parent, err := repo.FindByID(ctx, "pdf")
+	require.NoError(t, err)
+	parent.MarkAsDeadLettered()
+	require.NoError(t, repo.Update(ctx, parent))
+	require.NoError(t, svc.ResolveDependents(ctx, parent))
+
+	assert.Equal(t, entity.TaskStatusDeadLettered, status(t, repo, "invoice"))
+	assert.Equal(t, entity.TaskStatusPending, status(t, repo, "alert"))
+	assert.Equal(t, entity.TaskStatusDeadLettered, status(t, repo, "receipt"), "dead-lettering cascades down the chain")
+	assert.Equal(t, entity.TaskStatusPending, status(t, repo, "cleanup"))
+}
+
+func TestResolveDependentsOnExpiry(t *testing.T) {
+	repo := memory.NewTaskRepository()
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	require.NoError(t, svc.CreateTask(ctx, &entity.Task{ID: "reminder", Status: entity.TaskStatusPending}))
+	createDependent(t, svc, "followup", "reminder", "")
+
+	parent, err := repo.FindByID(ctx, "reminder")
+	require.NoError(t, err)
+	parent.MarkAsExpired(time.Now())
+	require.NoError(t, repo.Update(ctx, parent))
+	require.NoError(t, svc.ResolveDependents(ctx, parent))
+
+	child, err := repo.FindByID(ctx, "followup")
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusDeadLettered, child.Status)
+	assert.Equal(t, "Dependency reminder was expired", *child.ErrorMessage)
+}
+
+func TestDeleteTaskReleasesDependents(t *testing.T) {
+	repo := memory.NewTaskRepository()
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	require.NoError(t, svc.CreateTask(ctx, &entity.Task{ID: "pdf", Status: entity.TaskStatusPending}))
+	createDependent(t, svc, "invoice", "pdf", "")
+	createDependent(t, svc, "alert", "pdf", entity.DependencyFailureRunAnyway)
+
+	require.NoError(t, svc.DeleteTask(ctx, "pdf", "test"))
+
+	invoice, err := repo.FindByID(ctx, "invoice")
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusDeadLettered, invoice.Status)
+	require.NotNil(t, invoice.ErrorMessage)
+	assert.Contains(t, *invoice.ErrorMessage, "cancelled")
+	assert.Equal(t, entity.TaskStatusPending, status(t, repo, "alert"))
+}
@@ -0,0 +1,121 @@
+package task
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// ErrPayloadKeyUnavailable is returned when an encrypted payload's key is not configured
+var ErrPayloadKeyUnavailable = errors.New("payload encryption key unavailable")
+
+// encryptedPayload is how an encrypted payload is stored in the JSON payload column
+type encryptedPayload struct {
+	KeyID      string `json:"key_id"`
+	Ciphertext []byte `json:"ciphertext"` // Nonce followed by the AES-GCM sealed payload
+}
+
+// PayloadCipher encrypts payloads at rest with AES-GCM
+// Each ciphertext records the ID of the key that sealed it, so keys can be rotated:
+// new payloads use the primary key while older keys remain available for decryption
+type PayloadCipher struct {
+	keyID string
+	keys  map[string]cipher.AEAD
+}
+
+// NewPayloadCipher creates a cipher that encrypts with key and decrypts with key or any of
+// decryptionKeys. Keys must be 16, 24 or 32 bytes (AES-128, AES-192 or AES-256)
+func NewPayloadCipher(key []byte, decryptionKeys ...[]byte) (*PayloadCipher, error) {
+	c := &PayloadCipher{keyID: PayloadKeyID(key), keys: make(map[string]cipher.AEAD)}
+	for _, k := range append([][]byte{key}, decryptionKeys...) {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload encryption key: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		c.keys[PayloadKeyID(k)] = aead
+	}
+	return c, nil
+}
+
+// PayloadKeyID identifies a key without revealing it: the first 8 bytes of its SHA-256, in hex
+func PayloadKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// KeyID returns the ID of the key new payloads are encrypted with
+func (c *PayloadCipher) KeyID() string {
+	return c.keyID
+}
+
+// Encrypt replaces the task's payload with its ciphertext and marks it encrypted
+// The task ID is authenticated with the payload, so ciphertexts can't be moved between tasks
+func (c *PayloadCipher) Encrypt(task *entity.Task) error {
+	aead := c.keys[c.keyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	stored, err := json.Marshal(encryptedPayload{
+		KeyID:      c.keyID,
+		Ciphertext: aead.Seal(nonce, nonce, task.Payload, []byte(task.ID)),
+	})
+	if err != nil {
+		return err
+	}
+	task.Payload = stored
+	task.PayloadEncrypted = true
+	return nil
+}
+
+// Decrypt restores the plaintext payload of an encrypted task; other tasks are left as is
+func (c *PayloadCipher) Decrypt(task *entity.Task) error {
+	if !task.PayloadEncrypted {
+		return nil
+	}
+
+	var stored encryptedPayload
+	if err := json.Unmarshal(task.Payload, &stored); err != nil {
+		return fmt.Errorf("invalid encrypted payload: %w", err)
+	}
+	aead, ok := c.keys[stored.KeyID]
+	if !ok {
+		return fmt.Errorf("%w: key %s", ErrPayloadKeyUnavailable, stored.KeyID)
+	}
+	if len(stored.Ciphertext) < aead.NonceSize() {
+		return fmt.Errorf("invalid encrypted payload: ciphertext too short")
+	}
+
+	nonce, sealed := stored.Ciphertext[:aead.NonceSize()], stored.Ciphertext[aead.NonceSize():]
+	payload, err := aead.Open(nil, nonce, sealed, []byte(task.ID))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt payload: %w", err)
+	}
+	task.Payload = payload
+	task.PayloadEncrypted = false
+	return nil
+}
+
+// decryptPayload decrypts the task's payload with the cipher, which may be nil when
+// encryption isn't configured; encrypted payloads then fail with ErrPayloadKeyUnavailable
+func decryptPayload(c *PayloadCipher, task *entity.Task) error {
+	if !task.PayloadEncrypted {
+		return nil
+	}
+	if c == nil {
+		return fmt.Errorf("%w: payload encryption is not configured", ErrPayloadKeyUnavailable)
+	}
+	return c.Decrypt(task)
+}
@@ -0,0 +1,62 @@
+package task
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+func TestPayloadCipher(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	payload := entity.JSONBytes(`{"email":"user@example.com"}`)
+
+	oldCipher, err := NewPayloadCipher(oldKey)
+	require.NoError(t, err)
+	rotated, err := NewPayloadCipher(newKey, oldKey)
+	require.NoError(t, err)
+
+	t.Run("Round trip", func(t *testing.T) {
+		task := &entity.Task{ID: "1", Payload: payload}
+		require.NoError(t, rotated.Encrypt(task))
+		assert.True(t, task.PayloadEncrypted)
+		assert.NotContains(t, string(task.Payload), "user@example.com")
+		assert.Contains(t, string(task.Payload), rotated.KeyID())
+
+		require.NoError(t, rotated.Decrypt(task))
+		assert.False(t, task.PayloadEncrypted)
+		assert.Equal(t, payload, task.Payload)
+	})
+
+	t.Run("Retired key still decrypts", func(t *testing.T) {
+		task := &entity.Task{ID: "2", Payload: payload}
+		require.NoError(t, oldCipher.Encrypt(task))
+		require.NoError(t, rotated.Decrypt(task))
+		assert.Equal(t, payload, task.Payload)
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		task := &entity.Task{ID: "3", Payload: payload}
+		require.NoError(t, rotated.Encrypt(task))
+		err := oldCipher.Decrypt(task)
+		assert.True(t, errors.Is(err, ErrPayloadKeyUnavailable))
+		assert.True(t, task.PayloadEncrypted)
+	})
+
+	t.Run("Ciphertext is bound to its task", func(t *testing.T) {
+		task := &entity.Task{ID: "4", Payload: payload}
+		require.NoError(t, rotated.Encrypt(task))
+		moved := &entity.Task{ID: "5", Payload: task.Payload, PayloadEncrypted: true}
+		assert.Error(t, rotated.Decrypt(moved))
+	})
+
+	t.Run("Invalid key size", func(t *testing.T) {
+		_, err := NewPayloadCipher([]byte("short"))
+		assert.Error(t, err)
+	})
+}
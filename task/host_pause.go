@@ -0,0 +1,203 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+
+	"go.uber.org/zap"
+)
+
+// DefaultHostPauseRefreshInterval is how long the stored host pauses are reused before they are
+// loaded again, which is how soon pauses made on other replicas take effect
+const DefaultHostPauseRefreshInterval = 10 * time.Second
+
+// HostPauseRecheckInterval is how long a task for a host paused without an end is held before
+// it is checked again, and so how late it may start once the host is resumed
+const HostPauseRecheckInterval = time.Minute
+
+// HostPauseStatus is a paused host and the tasks its pause holds
+type HostPauseStatus struct {
+	entity.HostPause
+	HeldTasks int64 `json:"held_tasks"` // Pending and failed tasks for the host
+}
+
+// hostPauses caches the stored pauses, so workers can check a task's host without a query
+type hostPauses struct {
+	repo     repository.HostPauseRepository
+	interval time.Duration
+	logger   *zap.Logger
+
+	refreshing sync.Mutex // Held by the one caller reloading the pauses
+	mu         sync.Mutex
+	pauses     map[string]*entity.HostPause
+	loadedAt   time.Time
+}
+
+// WithHostPauses lets callback hosts be paused with PauseHost, storing the pauses in repo so
+// they survive restarts and apply on every replica sharing it
+func WithHostPauses(repo repository.HostPauseRepository) ServiceOption {
+	return func(s *Service) {
+		s.hostPauses = &hostPauses{repo: repo, interval: DefaultHostPauseRefreshInterval}
+	}
+}
+
+// heldUntil returns the end of the host's pause at now, loading the pauses first if they are
+// stale. Callers arriving while another loads them check the previous pauses rather than waiting
+func (h *hostPauses) heldUntil(host string, now time.Time) (time.Time, bool) {
+	if h.stale() && h.refreshing.TryLock() {
+		if h.stale() {
+			h.refresh(context.Background())
+		}
+		h.refreshing.Unlock()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pause, ok := h.pauses[host]
+	if !ok || !pause.Active(now) {
+		return time.Time{}, false
+	}
+	if pause.Until == nil {
+		return now.Add(HostPauseRecheckInterval), true
+	}
+	return *pause.Until, true
+}
+
+func (h *hostPauses) stale() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Since(h.loadedAt) >= h.interval
+}
+
+// refresh loads the stored pauses and returns them; on failure the previous pauses are kept
+// until the next interval, so a struggling database isn't queried for every task
+func (h *hostPauses) refresh(ctx context.Context) ([]*entity.HostPause, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.interval)
+	defer cancel()
+	stored, err := h.repo.List(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.loadedAt = time.Now()
+	if err != nil {
+		h.logger.Error("Failed to load host pauses", zap.Error(err))
+		return nil, err
+	}
+	h.pauses = make(map[string]*entity.HostPause, len(stored))
+	for _, pause := range stored {
+		h.pauses[pause.Host] = pause
+	}
+	return stored, nil
+}
+
+// set records a pause made on this replica, or its removal with a nil pause
+func (h *hostPauses) set(host string, pause *entity.HostPause) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pauses == nil {
+		h.pauses = make(map[string]*entity.HostPause)
+	}
+	if pause == nil {
+		delete(h.pauses, host)
+		return
+	}
+	h.pauses[host] = pause
+}
+
+// normalizeHost checks a host named by an operator and lowercases it like entity.CallbackHost
+func normalizeHost(host string) (string, error) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" || strings.ContainsAny(host, "/:@?# ") {
+		return "", fmt.Errorf("%w: host must be a hostname without scheme, port or path", domain.ErrBadParamInput)
+	}
+	return host, nil
+}
+
+// PauseHost holds the callbacks of all tasks whose callback URL is on host, until the given
+// time or, with a nil until, until ResumeHost. Workers put a held task back with its scheduled
+// time moved to the end of the pause, so it runs afterwards without intervention; tasks of a
+// pause without an end are checked again every HostPauseRecheckInterval. Pausing a paused
+// host replaces its pause. Other replicas apply the pause within DefaultHostPauseRefreshInterval
+func (s *Service) PauseHost(ctx context.Context, host string, until *time.Time) (*entity.HostPause, error) {
+	if s.hostPauses == nil {
+		return nil, fmt.Errorf("%w: host pauses are not enabled", domain.ErrBadParamInput)
+	}
+	host, err := normalizeHost(host)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if until != nil && !until.After(now) {
+		return nil, fmt.Errorf("%w: until must be in the future", domain.ErrBadParamInput)
+	}
+
+	pause := &entity.HostPause{Host: host, Until: until, CreatedAt: now}
+	if err := s.hostPauses.repo.Pause(ctx, pause); err != nil {
+		return nil, err
+	}
+	s.hostPauses.set(host, pause)
+	return pause, nil
+}
+
+// ResumeHost ends the host's pause; its held tasks run once their scheduled time comes, at most
+// HostPauseRecheckInterval later for a pause without an end
+// Returns domain.ErrNotFound if the host isn't paused
+func (s *Service) ResumeHost(ctx context.Context, host string) error {
+	if s.hostPauses == nil {
+		return fmt.Errorf("%w: host pauses are not enabled", domain.ErrBadParamInput)
+	}
+	host, err := normalizeHost(host)
+	if err != nil {
+		return err
+	}
+	if err := s.hostPauses.repo.Resume(ctx, host); err != nil {
+		return err
+	}
+	s.hostPauses.set(host, nil)
+	return nil
+}
+
+// PausedHosts returns the hosts paused now, by host, with the tasks each pause holds
+func (s *Service) PausedHosts(ctx context.Context) ([]*HostPauseStatus, error) {
+	if s.hostPauses == nil {
+		return []*HostPauseStatus{}, nil
+	}
+	stored, err := s.hostPauses.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	paused := make([]*HostPauseStatus, 0, len(stored))
+	hosts := make([]string, 0, len(stored))
+	for _, pause := range stored {
+		if pause.Active(now) {
+			paused = append(paused, &HostPauseStatus{HostPause: *pause})
+			hosts = append(hosts, pause.Host)
+		}
+	}
+	counts, err := s.repo.CountByCallbackHost(ctx, hosts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count held tasks: %w", err)
+	}
+	for _, status := range paused {
+		status.HeldTasks = counts[status.Host]
+	}
+	return paused, nil
+}
+
+// HostHeldUntil reports whether the host of a task's callback URL is paused at now, and until
+// when its tasks are held; workers check it before delivering
+func (s *Service) HostHeldUntil(host string, now time.Time) (time.Time, bool) {
+	if s.hostPauses == nil {
+		return time.Time{}, false
+	}
+	return s.hostPauses.heldUntil(host, now)
+}
@@ -0,0 +1,73 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/repository/memory"
+)
+
+func TestPauseHost(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewTaskRepository()
+	pauses := memory.NewHostPauseRepository()
+	svc := NewService(repo, WithHostPauses(pauses))
+	now := time.Now()
+
+	for _, status := range []entity.TaskStatus{entity.TaskStatusPending, entity.TaskStatusFailed, entity.TaskStatusCompleted} {
+		task := entity.NewTask("send_email", nil, "https://partner.example.com/hooks", now, 0)
+		task.Status = status
+		require.NoError(t, repo.Create(ctx, task))
+	}
+
+	_, held := svc.HostHeldUntil("partner.example.com", now)
+	assert.False(t, held)
+
+	until := now.Add(time.Hour)
+	pause, err := svc.PauseHost(ctx, " Partner.Example.com ", &until)
+	require.NoError(t, err)
+	assert.Equal(t, "partner.example.com", pause.Host)
+	heldUntil, held := svc.HostHeldUntil("partner.example.com", now)
+	assert.True(t, held)
+	assert.Equal(t, until, heldUntil)
+	_, held = svc.HostHeldUntil("other.example.com", now)
+	assert.False(t, held)
+
+	_, err = svc.PauseHost(ctx, "indefinite.example.com", nil)
+	require.NoError(t, err)
+	heldUntil, held = svc.HostHeldUntil("indefinite.example.com", now)
+	assert.True(t, held)
+	assert.Equal(t, now.Add(HostPauseRecheckInterval), heldUntil, "tasks of a pause without an end are rechecked")
+
+	paused, err := svc.PausedHosts(ctx)
+	require.NoError(t, err)
+	require.Len(t, paused, 2)
+	assert.Equal(t, "indefinite.example.com", paused[0].Host)
+	assert.Zero(t, paused[0].HeldTasks)
+	assert.Equal(t, "partner.example.com", paused[1].Host)
+	assert.Equal(t, int64(2), paused[1].HeldTasks, "completed tasks aren't held")
+
+	require.NoError(t, svc.ResumeHost(ctx, "partner.example.com"))
+	_, held = svc.HostHeldUntil("partner.example.com", now)
+	assert.False(t, held)
+	assert.True(t, errors.Is(svc.ResumeHost(ctx, "partner.example.com"), domain.ErrNotFound))
+
+	past := now.Add(-time.Minute)
+	for _, bad := range []struct {
+		host  string
+		until *time.Time
+	}{{"", nil}, {"https://partner.example.com", nil}, {"partner.example.com:8443", nil}, {"partner.example.com", &past}} {
+		_, err := svc.PauseHost(ctx, bad.host, bad.until)
+		assert.True(t, errors.Is(err, domain.ErrBadParamInput), "%q: got %v", bad.host, err)
+	}
+
+	_, err = NewService(repo).PauseHost(ctx, "partner.example.com", nil)
+	assert.True(t, errors.Is(err, domain.ErrBadParamInput), "pauses need a repository")
+}
@@ -0,0 +1,64 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+)
+
+// Formats of the IDs clients may give their tasks; IDs the service assigns are UUIDs either way
+const (
+	IDFormatUUID   = "uuid"   // Canonical UUIDs only (default)
+	IDFormatOpaque = "opaque" // Up to entity.MaxTaskIDLength letters, digits, '-', '_', '.' and ':', not reserved
+)
+
+// opaqueID keeps IDs safe to use as a URL path segment without escaping
+var opaqueID = regexp.MustCompile(`^[A-Za-z0-9._:-]+$`)
+
+// reservedIDs are the static segments of the /tasks routes, which would shadow a task's own
+// routes: a task "stats" couldn't be fetched and "bulk" couldn't be retried
+var reservedIDs = []string{"validate", "export", "upcoming", "import", "bulk", "stats", "stream"}
+
+// ValidIDFormat reports whether format is IDFormatUUID or IDFormatOpaque
+func ValidIDFormat(format string) bool {
+	return format == IDFormatUUID || format == IDFormatOpaque
+}
+
+// WithTaskIDFormat sets the format of the IDs clients may give their tasks, e.g. IDFormatOpaque
+// to reuse an upstream event ID as the task ID (default IDFormatUUID)
+func WithTaskIDFormat(format string) ServiceOption {
+	return func(s *Service) {
+		s.idFormat = format
+	}
+}
+
+// ValidateID rejects a client-supplied task ID that doesn't have the configured format
+// CreateTask stores tasks under whatever ID they carry, so callers taking the ID from a
+// request check it first; ImportTasks checks the IDs it is given itself
+func (s *Service) ValidateID(id string) error {
+	if s.idFormat == IDFormatOpaque {
+		if len(id) > entity.MaxTaskIDLength || !opaqueID.MatchString(id) {
+			return fmt.Errorf("%w: id must be at most %d letters, digits, '-', '_', '.' or ':'",
+				domain.ErrBadParamInput, entity.MaxTaskIDLength)
+		}
+		// Path cleaning rewrites "." and ".." segments
+		if strings.Trim(id, ".") == "" {
+			return fmt.Errorf("%w: id cannot consist of dots only", domain.ErrBadParamInput)
+		}
+		if slices.ContainsFunc(reservedIDs, func(reserved string) bool { return strings.EqualFold(id, reserved) }) {
+			return fmt.Errorf("%w: id %q is reserved for a route", domain.ErrBadParamInput, id)
+		}
+		return nil
+	}
+	// uuid.Parse also accepts braced and urn: forms, which would be stored as given
+	if _, err := uuid.Parse(id); err != nil || len(id) != 36 {
+		return fmt.Errorf("%w: id must be a UUID", domain.ErrBadParamInput)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+)
+
+func TestValidateID(t *testing.T) {
+	id := uuid.New().String()
+	tests := []struct {
+		id     string
+		uuid   bool // Accepted with IDFormatUUID
+		opaque bool // Accepted with IDFormatOpaque
+	}{
+		{id, true, true},
+		{strings.ToUpper(id), true, true},
+		{"{" + id + "}", false, false},
+		{"urn:uuid:" + id, false, true},
+		{"evt_01HZX3K9Q2:order.created", false, true},
+		{strings.Repeat("a", 64), false, true},
+		{strings.Repeat("a", 65), false, false},
+		{"orders/42", false, false},
+		{"has space", false, false},
+		{"", false, false},
+		{".", false, false},
+		{"..", false, false},
+		{"...", false, false},
+		{"v1.2", false, true},
+		{"validate", false, false},
+		{"export", false, false},
+		{"upcoming", false, false},
+		{"import", false, false},
+		{"bulk", false, false},
+		{"stats", false, false},
+		{"Stats", false, false},
+		{"stream", false, false},
+		{"stats-2024", false, true},
+	}
+
+	for _, format := range []string{IDFormatUUID, IDFormatOpaque} {
+		svc := NewService(&fakeRepository{}, WithTaskIDFormat(format))
+		for _, tt := range tests {
+			want := tt.uuid
+			if format == IDFormatOpaque {
+				want = tt.opaque
+			}
+			err := svc.ValidateID(tt.id)
+			if want {
+				assert.NoError(t, err, "%s: %q", format, tt.id)
+			} else {
+				assert.True(t, errors.Is(err, domain.ErrBadParamInput), "%s: %q: got %v", format, tt.id, err)
+			}
+		}
+	}
+}
+
+func TestImportTasksValidatesIDs(t *testing.T) {
+	tasks := []*entity.Task{newImportedTask("evt-1", "opaque"), newImportedTask(uuid.New().String(), "uuid")}
+
+	results, err := NewService(&fakeRepository{}).ImportTasks(context.Background(), tasks, false)
+	require.NoError(t, err)
+	assert.True(t, errors.Is(results[0].Err, domain.ErrBadParamInput), "got %v", results[0].Err)
+	assert.NoError(t, results[1].Err)
+
+	tasks = []*entity.Task{newImportedTask("evt-1", "opaque")}
+	results, err = NewService(&fakeRepository{}, WithTaskIDFormat(IDFormatOpaque)).ImportTasks(context.Background(), tasks, false)
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "evt-1", results[0].ID)
+}
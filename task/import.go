@@ -0,0 +1,148 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// MaxImportTasks caps the tasks a single import may create
+const MaxImportTasks = MaxBulkLimit
+
+// importBatchSize is how many imported tasks are inserted per statement
+const importBatchSize = 100
+
+// ImportResult reports the outcome of importing one task
+type ImportResult struct {
+	ID         string // ID the task was stored under
+	OriginalID string // ID the task was imported with, when it was remapped
+	Err        error  // Why the task was not imported
+}
+
+// ImportTasks creates the tasks, validated as by CreateTask, in batches
+// The tasks keep their IDs, which must have the configured format; an ID that is already taken, by a stored task or an earlier one in
+// the import, is rejected with domain.ErrConflict unless remapIDs is set, in which case the task
+// gets a new ID and later tasks depending on the old one follow it
+// A task depending on another in the same import must come after it
+// Results are returned in task order; the error is only set when the import couldn't proceed
+func (s *Service) ImportTasks(ctx context.Context, tasks []*entity.Task, remapIDs bool) ([]ImportResult, error) {
+	if len(tasks) > MaxImportTasks {
+		return nil, fmt.Errorf("%w: at most %d tasks can be imported at once", domain.ErrBadParamInput, MaxImportTasks)
+	}
+
+	results := make([]ImportResult, len(tasks))
+	taken := map[string]bool{}
+	remapped := map[string]string{}
+	var pending []int // Prepared tasks awaiting insertion, by index
+
+	for start := 0; start < len(tasks); start += importBatchSize {
+		chunk := tasks[start:min(start+importBatchSize, len(tasks))]
+		ids := make([]string, 0, len(chunk))
+		for _, task := range chunk {
+			ids = append(ids, task.ID)
+		}
+		existing, err := s.repo.ExistingIDs(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check task IDs: %w", err)
+		}
+		for _, id := range existing {
+			taken[id] = true
+		}
+
+		for i := start; i < start+len(chunk); i++ {
+			task := tasks[i]
+			if err := s.ValidateID(task.ID); err != nil {
+				results[i].Err = err
+				continue
+			}
+			if taken[task.ID] {
+				if !remapIDs {
+					results[i].Err = fmt.Errorf("%w: %s", domain.ErrConflict, task.ID)
+					continue
+				}
+				results[i].OriginalID = task.ID
+				remapped[task.ID] = uuid.New().String()
+				task.ID = remapped[task.ID]
+			}
+			if task.DependsOn != nil {
+				if id, ok := remapped[*task.DependsOn]; ok {
+					task.DependsOn = &id
+				}
+				// The parent has to be stored before the dependency is checked
+				for _, j := range pending {
+					if tasks[j].ID == *task.DependsOn {
+						s.insertImported(ctx, tasks, pending, results)
+						pending = pending[:0]
+						break
+					}
+				}
+			}
+
+			if err := s.prepareCreate(ctx, task); err != nil {
+				results[i].Err = err
+				continue
+			}
+			taken[task.ID] = true
+			pending = append(pending, i)
+		}
+
+		s.insertImported(ctx, tasks, pending, results)
+		pending = pending[:0]
+	}
+	return results, nil
+}
+
+// insertImported stores the prepared tasks at the given indexes in one statement, falling back to
+// one insert per task when the batch fails so each failure is reported against its own task
+func (s *Service) insertImported(ctx context.Context, tasks []*entity.Task, indexes []int, results []ImportResult) {
+	if len(indexes) == 0 {
+		return
+	}
+
+	batch := make([]*entity.Task, 0, len(indexes))
+	restores := make([]func(), 0, len(indexes))
+	for _, i := range indexes {
+		restore, err := s.sealPayload(tasks[i])
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		batch = append(batch, tasks[i])
+		restores = append(restores, restore)
+	}
+	defer func() {
+		for _, restore := range restores {
+			restore()
+		}
+	}()
+
+	failed := map[*entity.Task]error{}
+	if err := s.repo.CreateBatch(ctx, batch); err != nil {
+		s.logger.Warn("Import batch failed, inserting tasks one by one",
+			zap.Int("tasks", len(batch)),
+			zap.Error(err))
+		for _, task := range batch {
+			if err := s.repo.Create(ctx, task); err != nil {
+				failed[task] = err
+			}
+		}
+	}
+
+	for _, i := range indexes {
+		task := tasks[i]
+		if results[i].Err != nil {
+			continue
+		}
+		if err := failed[task]; err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].ID = task.ID
+		s.releaseAfterCreate(ctx, task)
+	}
+}
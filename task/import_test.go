@@ -0,0 +1,92 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+)
+
+func newImportedTask(id, name string) *entity.Task {
+	task := entity.NewTask(name, []byte(`{}`), "https://example.com/callback", time.Now(), 0)
+	task.ID = id
+	return task
+}
+
+func TestImportTasks(t *testing.T) {
+	existingID, parentID, childID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	repo := &fakeRepository{tasks: []*entity.Task{newImportedTask(existingID, "stored")}}
+	svc := NewService(repo)
+
+	child := newImportedTask(childID, "child")
+	child.DependsOn = &parentID
+	tasks := []*entity.Task{
+		newImportedTask(existingID, "duplicate"),
+		newImportedTask(parentID, "parent"),
+		child,
+		newImportedTask(uuid.New().String(), "unstorable"),
+		newImportedTask(uuid.New().String(), ""),
+	}
+	tasks[4].CallbackTimeoutSecs = 1
+
+	results, err := svc.ImportTasks(context.Background(), tasks, false)
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	assert.True(t, errors.Is(results[0].Err, domain.ErrConflict), "got %v", results[0].Err)
+	assert.Equal(t, parentID, results[1].ID)
+	require.NoError(t, results[2].Err)
+	assert.Equal(t, childID, results[2].ID)
+	assert.Error(t, results[3].Err, "a failed batch is retried task by task")
+	assert.True(t, errors.Is(results[4].Err, domain.ErrBadParamInput), "got %v", results[4].Err)
+
+	// The child forced its parent's batch out first so its dependency could be checked
+	assert.Equal(t, 2, repo.batches)
+	stored, err := repo.FindByID(context.Background(), childID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusWaiting, stored.Status)
+}
+
+func TestImportTasksRemapsDuplicateIDs(t *testing.T) {
+	parentID, childID := uuid.New().String(), uuid.New().String()
+	repo := &fakeRepository{tasks: []*entity.Task{newImportedTask(parentID, "stored")}}
+	svc := NewService(repo)
+
+	child := newImportedTask(childID, "child")
+	child.DependsOn = &parentID
+	tasks := []*entity.Task{newImportedTask(parentID, "parent"), child, newImportedTask(childID, "again")}
+
+	results, err := svc.ImportTasks(context.Background(), tasks, true)
+	require.NoError(t, err)
+	for _, result := range results {
+		require.NoError(t, result.Err)
+	}
+
+	assert.Equal(t, parentID, results[0].OriginalID)
+	assert.NotEqual(t, parentID, results[0].ID)
+	assert.Equal(t, childID, results[1].ID, "free IDs are kept")
+	assert.Empty(t, results[1].OriginalID)
+	assert.Equal(t, childID, results[2].OriginalID, "IDs taken earlier in the import are remapped too")
+
+	// The child follows its parent to the new ID
+	stored, err := repo.FindByID(context.Background(), childID)
+	require.NoError(t, err)
+	assert.Equal(t, results[0].ID, *stored.DependsOn)
+}
+
+func TestImportTasksLimit(t *testing.T) {
+	tasks := make([]*entity.Task, MaxImportTasks+1)
+	for i := range tasks {
+		tasks[i] = newImportedTask(fmt.Sprint(i), "task")
+	}
+	_, err := NewService(&fakeRepository{}).ImportTasks(context.Background(), tasks, false)
+	assert.True(t, errors.Is(err, domain.ErrBadParamInput), "got %v", err)
+}
@@ -0,0 +1,175 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/logger"
+)
+
+// SchedulerLeaseName is the lease replicas compete for to run the scheduler
+const SchedulerLeaseName = "scheduler"
+
+// DefaultLeaseTimeout is how long a leader's lease lasts without renewal when not configured
+// A dead leader is replaced within roughly this long
+const DefaultLeaseTimeout = 15 * time.Second
+
+// Leadership reports whether this replica should run the scheduler
+type Leadership interface {
+	IsLeader() bool
+}
+
+// LeaderElectionConfig configures a LeaderElector
+type LeaderElectionConfig struct {
+	Holder        string        // Identifies this replica; defaults to hostname, pid and a random suffix
+	LeaseTimeout  time.Duration // Zero uses DefaultLeaseTimeout
+	RenewInterval time.Duration // Zero renews three times per lease timeout
+	Logger        *zap.Logger   // nil uses the global logger
+}
+
+// Validate checks that a leader renews its lease before it expires
+func (cfg LeaderElectionConfig) Validate() error {
+	if cfg.LeaseTimeout < 0 || cfg.RenewInterval < 0 {
+		return fmt.Errorf("leader election lease timeout and renew interval cannot be negative")
+	}
+	cfg = cfg.withDefaults()
+	if cfg.RenewInterval >= cfg.LeaseTimeout {
+		return fmt.Errorf("leader election renew interval %s must be shorter than the lease timeout %s",
+			cfg.RenewInterval, cfg.LeaseTimeout)
+	}
+	return nil
+}
+
+// withDefaults returns the config with zero values replaced by their defaults
+func (cfg LeaderElectionConfig) withDefaults() LeaderElectionConfig {
+	if cfg.Holder == "" {
+		host, _ := os.Hostname()
+		cfg.Holder = fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8])
+	}
+	if cfg.LeaseTimeout == 0 {
+		cfg.LeaseTimeout = DefaultLeaseTimeout
+	}
+	if cfg.RenewInterval == 0 {
+		cfg.RenewInterval = cfg.LeaseTimeout / 3
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.Named("leader")
+	}
+	return cfg
+}
+
+// LeaderElector competes for the scheduler lease so that only one replica polls and cleans up,
+// while every replica keeps serving the API and running workers
+// Leadership is held until the lease would expire, measured from before each renewal,
+// so a leader that can't reach the database steps down before another replica can take over
+type LeaderElector struct {
+	leases repository.LeaseRepository
+	cfg    LeaderElectionConfig
+
+	mu         sync.RWMutex
+	leaseUntil time.Time // Local deadline of the lease we hold; zero when following
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewLeaderElector creates an elector for the scheduler lease; call Run to start competing
+func NewLeaderElector(leases repository.LeaseRepository, cfg LeaderElectionConfig) *LeaderElector {
+	return &LeaderElector{
+		leases: leases,
+		cfg:    cfg.withDefaults(),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Holder returns the ID this replica holds the lease under
+func (e *LeaderElector) Holder() string {
+	return e.cfg.Holder
+}
+
+// IsLeader reports whether this replica currently holds an unexpired lease
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return time.Now().Before(e.leaseUntil)
+}
+
+// Run tries to acquire or renew the lease every renew interval until Stop is called
+func (e *LeaderElector) Run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.cfg.RenewInterval)
+	defer ticker.Stop()
+
+	e.renew()
+	for {
+		select {
+		case <-ticker.C:
+			e.renew()
+		case <-e.quit:
+			e.release()
+			return
+		}
+	}
+}
+
+// Stop stops competing and releases the lease if held, so another replica takes over at once
+func (e *LeaderElector) Stop() {
+	close(e.quit)
+	<-e.done
+}
+
+// renew acquires or extends the lease and records how long leadership lasts
+func (e *LeaderElector) renew() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.RenewInterval)
+	defer cancel()
+
+	// The lease can't outlive ttl from before the request, whatever the database's clock says
+	start := time.Now()
+	acquired, err := e.leases.Acquire(ctx, SchedulerLeaseName, e.cfg.Holder, e.cfg.LeaseTimeout)
+	if err != nil {
+		// Keep the current deadline: leadership lapses on its own if renewals keep failing
+		e.cfg.Logger.Error("Failed to renew scheduler lease", zap.Error(err))
+		return
+	}
+
+	wasLeader := e.IsLeader()
+	e.mu.Lock()
+	if acquired {
+		e.leaseUntil = start.Add(e.cfg.LeaseTimeout)
+	} else {
+		e.leaseUntil = time.Time{}
+	}
+	e.mu.Unlock()
+
+	if acquired && !wasLeader {
+		e.cfg.Logger.Info("Acquired scheduler lease", zap.String("holder", e.cfg.Holder))
+	} else if !acquired && wasLeader {
+		e.cfg.Logger.Info("Lost scheduler lease; now a follower", zap.String("holder", e.cfg.Holder))
+	}
+}
+
+// release gives up the lease if this replica holds it
+func (e *LeaderElector) release() {
+	if !e.IsLeader() {
+		return
+	}
+
+	e.mu.Lock()
+	e.leaseUntil = time.Time{}
+	e.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.leases.Release(ctx, SchedulerLeaseName, e.cfg.Holder); err != nil {
+		e.cfg.Logger.Error("Failed to release scheduler lease", zap.Error(err))
+	}
+}
@@ -0,0 +1,125 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain/entity"
+)
+
+// memoryLeases is an in-memory repository.LeaseRepository; holders marked down can't reach it
+type memoryLeases struct {
+	mu      sync.Mutex
+	holder  string
+	expires time.Time
+	down    map[string]bool
+}
+
+func (m *memoryLeases) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.down[holder] {
+		return false, errors.New("connection refused")
+	}
+	now := time.Now()
+	if m.holder == holder || !now.Before(m.expires) {
+		m.holder = holder
+		m.expires = now.Add(ttl)
+	}
+	return m.holder == holder, nil
+}
+
+func (m *memoryLeases) Release(ctx context.Context, name, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.holder == holder {
+		m.holder = ""
+		m.expires = time.Time{}
+	}
+	return nil
+}
+
+func (m *memoryLeases) setDown(holder string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.down[holder] = true
+}
+
+func TestLeaderElectionFailover(t *testing.T) {
+	leases := &memoryLeases{down: make(map[string]bool)}
+	lease := 200 * time.Millisecond
+	cfg := func(holder string) LeaderElectionConfig {
+		return LeaderElectionConfig{Holder: holder, LeaseTimeout: lease, RenewInterval: 20 * time.Millisecond}
+	}
+
+	a := NewLeaderElector(leases, cfg("a"))
+	go a.Run()
+	require.Eventually(t, a.IsLeader, time.Second, 5*time.Millisecond)
+
+	b := NewLeaderElector(leases, cfg("b"))
+	go b.Run()
+	defer b.Stop()
+	time.Sleep(3 * 20 * time.Millisecond)
+	assert.False(t, b.IsLeader(), "only one replica leads while the lease is renewed")
+
+	// The leader loses the database: it steps down and b takes over within the lease timeout
+	died := time.Now()
+	leases.setDown("a")
+	require.Eventually(t, b.IsLeader, 2*lease, 5*time.Millisecond)
+	assert.False(t, a.IsLeader(), "a leader that can't renew steps down once its lease expires")
+	assert.Less(t, time.Since(died), lease+2*20*time.Millisecond)
+	a.Stop()
+}
+
+func TestLeaderElectionReleaseOnStop(t *testing.T) {
+	leases := &memoryLeases{down: make(map[string]bool)}
+	a := NewLeaderElector(leases, LeaderElectionConfig{Holder: "a", LeaseTimeout: time.Hour})
+	go a.Run()
+	require.Eventually(t, a.IsLeader, time.Second, 5*time.Millisecond)
+	a.Stop()
+	assert.False(t, a.IsLeader())
+
+	// A released lease is taken at once rather than after the hour-long timeout
+	b := NewLeaderElector(leases, LeaderElectionConfig{Holder: "b", LeaseTimeout: time.Hour})
+	go b.Run()
+	defer b.Stop()
+	require.Eventually(t, b.IsLeader, time.Second, 5*time.Millisecond)
+}
+
+func TestLeaderElectionConfigValidate(t *testing.T) {
+	assert.NoError(t, LeaderElectionConfig{}.Validate())
+	assert.NoError(t, LeaderElectionConfig{LeaseTimeout: 30 * time.Second}.Validate())
+	assert.Error(t, LeaderElectionConfig{LeaseTimeout: 5 * time.Second, RenewInterval: 5 * time.Second}.Validate())
+	assert.Error(t, LeaderElectionConfig{LeaseTimeout: -time.Second}.Validate())
+}
+
+// staticLeadership is a Leadership with a fixed answer
+type staticLeadership bool
+
+func (l staticLeadership) IsLeader() bool { return bool(l) }
+
+func TestSchedulerFollowerDoesNotPoll(t *testing.T) {
+	repo := &backlogRepository{retry: &entity.Task{ID: "retry", Status: entity.TaskStatusFailed}, retryDue: true}
+	pool := &recordingPool{submitted: make(map[string]entity.TaskStatus)}
+
+	scheduler := NewScheduler(repo, pool, SchedulerConfig{
+		HighPriorityInterval:   5 * time.Millisecond,
+		NormalPriorityInterval: 5 * time.Millisecond,
+		RetryInterval:          5 * time.Millisecond,
+		CleanupInterval:        5 * time.Millisecond,
+		Leader:                 staticLeadership(false),
+	})
+	go scheduler.Start()
+	time.Sleep(50 * time.Millisecond)
+	scheduler.Stop()
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	assert.Empty(t, pool.submitted, "followers leave polling to the leader")
+}
@@ -0,0 +1,104 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"go.uber.org/zap"
+)
+
+// Default adaptive poll intervals for InsertWatcher
+const (
+	DefaultNotifyMinInterval = 100 * time.Millisecond
+	DefaultNotifyMaxInterval = time.Second
+)
+
+// TaskNotifier tells the scheduler that new tasks may be due, so it polls without waiting
+// for its next tick. Run calls wake for every notification until Stop is called
+// A database with change notifications (e.g. Postgres LISTEN/NOTIFY on task insert) can
+// implement it directly; MySQL has none, so InsertWatcher polls for inserts instead
+type TaskNotifier interface {
+	Run(wake func())
+	Stop()
+}
+
+// InsertWatcher is an adaptive short-poll for task inserts from any replica
+// It checks the newest task ID, cheap to read from the created_at index, and wakes the
+// scheduler when it changes. The interval drops to the minimum after an insert and doubles
+// towards the maximum while nothing is created, so idle databases see little extra load
+// Inserts within the same second as the previous newest task can be missed; the scheduler's
+// tickers remain the safety net
+type InsertWatcher struct {
+	repo        repository.TaskRepository
+	minInterval time.Duration
+	maxInterval time.Duration
+	logger      *zap.Logger
+	quit        chan struct{}
+	done        chan struct{}
+}
+
+// NewInsertWatcher creates a watcher polling between minInterval and maxInterval
+// Zero intervals use DefaultNotifyMinInterval and DefaultNotifyMaxInterval
+func NewInsertWatcher(repo repository.TaskRepository, minInterval, maxInterval time.Duration) *InsertWatcher {
+	if minInterval <= 0 {
+		minInterval = DefaultNotifyMinInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = DefaultNotifyMaxInterval
+	}
+	return &InsertWatcher{
+		repo:        repo,
+		minInterval: minInterval,
+		maxInterval: max(minInterval, maxInterval),
+		logger:      logger.Named("scheduler"),
+		quit:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Run polls for inserts until Stop is called
+func (w *InsertWatcher) Run(wake func()) {
+	defer close(w.done)
+
+	latest, _ := w.latestID()
+	interval := w.minInterval
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-w.quit:
+			return
+		}
+
+		id, err := w.latestID()
+		switch {
+		case err != nil:
+			w.logger.Error("Failed to check for new tasks", zap.Error(err))
+			interval = w.maxInterval
+		case id != latest:
+			latest = id
+			wake()
+			interval = w.minInterval
+		default:
+			interval = min(interval*2, w.maxInterval)
+		}
+		timer.Reset(interval)
+	}
+}
+
+// Stop stops polling and waits for Run to return
+func (w *InsertWatcher) Stop() {
+	close(w.quit)
+	<-w.done
+}
+
+func (w *InsertWatcher) latestID() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return w.repo.LatestCreatedID(ctx)
+}
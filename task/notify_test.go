@@ -0,0 +1,160 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+)
+
+// insertRepository holds tasks inserted by "another replica"; each is due once, at creation
+type insertRepository struct {
+	repository.TaskRepository
+
+	mu      sync.Mutex
+	pending []*entity.Task
+	latest  string
+	created map[string]time.Time
+}
+
+func (r *insertRepository) insert(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = append(r.pending, &entity.Task{ID: id, Status: entity.TaskStatusPending})
+	r.latest = id
+	r.created[id] = time.Now()
+}
+
+func (r *insertRepository) FindDueTasks(ctx context.Context, minPriority int, limit int, skipGroups []string) ([]*entity.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tasks := r.pending
+	r.pending = nil
+	return tasks, nil
+}
+
+func (r *insertRepository) FindFailedTasks(ctx context.Context, limit int) ([]*entity.Task, error) {
+	return nil, nil
+}
+
+func (r *insertRepository) LatestCreatedID(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.latest, nil
+}
+
+// timingPool records when each task was submitted
+type timingPool struct {
+	mu        sync.Mutex
+	submitted map[string]time.Time
+}
+
+func (p *timingPool) Start(workerCount int) {}
+
+func (p *timingPool) SubmitTask(task *entity.Task) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.submitted[task.ID] = time.Now()
+	return true
+}
+
+func (p *timingPool) Stop(ctx context.Context) int { return 0 }
+
+func (p *timingPool) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.submitted)
+}
+
+// dispatchLatencyP99 inserts tasks at random intervals and returns the p99 time from
+// creation to submission to the worker pool
+func dispatchLatencyP99(t *testing.T, pollInterval time.Duration, notifier func(repository.TaskRepository) TaskNotifier) time.Duration {
+	t.Helper()
+	const tasks = 40
+
+	repo := &insertRepository{created: make(map[string]time.Time)}
+	pool := &timingPool{submitted: make(map[string]time.Time)}
+	cfg := SchedulerConfig{
+		HighPriorityInterval:   pollInterval,
+		NormalPriorityInterval: pollInterval,
+		RetryInterval:          time.Hour,
+		CleanupInterval:        time.Hour,
+	}
+	if notifier != nil {
+		cfg.Notifier = notifier(repo)
+	}
+	scheduler := NewScheduler(repo, pool, cfg)
+	go scheduler.Start()
+	defer scheduler.Stop()
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < tasks; i++ {
+		time.Sleep(time.Duration(rng.Int63n(int64(pollInterval / 10))))
+		repo.insert(fmt.Sprintf("task-%d", i))
+	}
+	require.Eventually(t, func() bool { return pool.count() == tasks }, 3*pollInterval, 5*time.Millisecond)
+
+	repo.mu.Lock()
+	pool.mu.Lock()
+	defer repo.mu.Unlock()
+	defer pool.mu.Unlock()
+	latencies := make([]time.Duration, 0, tasks)
+	for id, created := range repo.created {
+		latencies = append(latencies, pool.submitted[id].Sub(created))
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return latencies[(len(latencies)*99+99)/100-1]
+}
+
+// TestInsertWatcherDispatchLatency compares creation-to-dispatch latency for tasks created on
+// another replica, with tickers alone and with the adaptive insert watcher. Intervals are scaled
+// down from the defaults: 300ms tickers stand in for 3s and the watcher polls every 10-100ms
+func TestInsertWatcherDispatchLatency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("measures latency over several poll intervals")
+	}
+	poll := 300 * time.Millisecond
+
+	tickers := dispatchLatencyP99(t, poll, nil)
+	notified := dispatchLatencyP99(t, poll, func(repo repository.TaskRepository) TaskNotifier {
+		return NewInsertWatcher(repo, 10*time.Millisecond, 100*time.Millisecond)
+	})
+
+	t.Logf("p99 creation-to-dispatch latency: tickers %v, insert watcher %v", tickers, notified)
+	assert.Less(t, notified, tickers/2, "waking on inserts must cut dispatch latency")
+}
+
+func TestInsertWatcherBacksOffWhileIdle(t *testing.T) {
+	repo := &insertRepository{created: make(map[string]time.Time)}
+	watcher := NewInsertWatcher(repo, 5*time.Millisecond, 40*time.Millisecond)
+
+	var mu sync.Mutex
+	wakes := 0
+	go watcher.Run(func() {
+		mu.Lock()
+		wakes++
+		mu.Unlock()
+	})
+	defer watcher.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	assert.Zero(t, wakes, "no inserts, no wakes")
+	mu.Unlock()
+
+	repo.insert("a")
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return wakes == 1
+	}, 100*time.Millisecond, 5*time.Millisecond, "an idle watcher still notices inserts within its max interval")
+}
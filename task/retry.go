@@ -0,0 +1,94 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+)
+
+// WithRetryPolicy sets the policy failed tasks are retried under (default entity.DefaultRetryPolicy)
+// Workers pick it up from the service through RetryPolicy
+func WithRetryPolicy(policy entity.RetryPolicy) ServiceOption {
+	return func(s *Service) {
+		s.retryPolicy = policy
+	}
+}
+
+// RetryPolicy returns the policy failed tasks are retried under
+func (s *Service) RetryPolicy() entity.RetryPolicy {
+	return s.retryPolicy
+}
+
+// RetrySchedule projects when a task's remaining retries run if its attempts keep failing
+type RetrySchedule struct {
+	Task    *entity.Task
+	Policy  entity.RetryPolicy
+	Retries []entity.ProjectedRetry // Empty once the task is finished or out of retries
+}
+
+// GetRetrySchedule projects the remaining retries of a task under the service's retry policy
+// (see entity.RetryPolicy.Schedule). Like GetTask it returns domain.ErrNotFound for deleted
+// tasks and other tenants' tasks
+func (s *Service) GetRetrySchedule(ctx context.Context, id string) (*RetrySchedule, error) {
+	task, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &RetrySchedule{
+		Task:    task,
+		Policy:  s.retryPolicy,
+		Retries: s.retryPolicy.Schedule(task, time.Now()),
+	}, nil
+}
+
+// RetryOptions adjusts a task as it is retried
+type RetryOptions struct {
+	ScheduledFor *time.Time // Delays the restart; nil runs the task as soon as it is due
+	MaxRetries   *int       // Replaces the task's retry budget
+}
+
+// RetryTask resets a failed or dead-lettered task to pending with its retries, last error and
+// run times cleared, so it runs again with a fresh retry budget
+// Returns an error wrapping domain.ErrNotFound for a missing task, or domain.ErrTaskCannotRetry
+// for tasks in other statuses, including tasks that changed status while being retried
+func (s *Service) RetryTask(ctx context.Context, id string, opts RetryOptions) (*entity.Task, error) {
+	if opts.MaxRetries != nil && *opts.MaxRetries < 0 {
+		return nil, fmt.Errorf("%w: max_retries cannot be negative", domain.ErrBadParamInput)
+	}
+
+	task, err := s.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from := task.Status
+	if from != entity.TaskStatusFailed && from != entity.TaskStatusDeadLettered {
+		return nil, fmt.Errorf("%w: only failed or dead-lettered tasks can be retried, current status: %s", domain.ErrTaskCannotRetry, from)
+	}
+
+	task.Status = entity.TaskStatusPending
+	task.RetryCount = 0
+	task.NextRetryAt = nil
+	task.ErrorMessage = nil
+	task.StartedAt = nil
+	task.CompletedAt = nil
+	if opts.ScheduledFor != nil {
+		task.ScheduledAt = opts.ScheduledFor.UTC()
+	}
+	if opts.MaxRetries != nil {
+		task.MaxRetries = *opts.MaxRetries
+	}
+
+	// Conditional, so a task a worker or another retry picked up meanwhile isn't reset under it
+	updated, err := s.repo.UpdateIfStatus(ctx, task, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry task: %w", err)
+	}
+	if !updated {
+		return nil, fmt.Errorf("%w: task is no longer %s", domain.ErrTaskCannotRetry, from)
+	}
+	return task, nil
+}
@@ -0,0 +1,95 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+)
+
+func TestRetryTask(t *testing.T) {
+	message := "callback returned 500"
+	started := time.Now().Add(-time.Hour)
+	repo := &fakeRepository{tasks: []*entity.Task{
+		{ID: "failed", Status: entity.TaskStatusFailed, RetryCount: 2, MaxRetries: 5, NextRetryAt: &started},
+		{ID: "dead", Status: entity.TaskStatusDeadLettered, RetryCount: 5, MaxRetries: 5, ErrorMessage: &message, StartedAt: &started, CompletedAt: &started},
+		{ID: "pending", Status: entity.TaskStatusPending},
+	}}
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	task, err := svc.RetryTask(ctx, "failed", RetryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusPending, task.Status)
+	assert.Zero(t, task.RetryCount)
+	assert.Nil(t, task.NextRetryAt)
+	assert.Equal(t, 5, task.MaxRetries)
+
+	// Dead-lettered tasks are reset like resurrect did, with the options applied
+	restart := time.Now().Add(time.Hour).Truncate(time.Second)
+	maxRetries := 10
+	task, err = svc.RetryTask(ctx, "dead", RetryOptions{ScheduledFor: &restart, MaxRetries: &maxRetries})
+	require.NoError(t, err)
+	stored, err := repo.FindByID(ctx, "dead")
+	require.NoError(t, err)
+	for _, task := range []*entity.Task{task, stored} {
+		assert.Equal(t, entity.TaskStatusPending, task.Status)
+		assert.Zero(t, task.RetryCount)
+		assert.Nil(t, task.ErrorMessage)
+		assert.Nil(t, task.StartedAt)
+		assert.Nil(t, task.CompletedAt)
+		assert.True(t, restart.Equal(task.ScheduledAt))
+		assert.Equal(t, 10, task.MaxRetries)
+	}
+
+	// The dead-lettered task is pending now, so a second retry is refused
+	_, err = svc.RetryTask(ctx, "dead", RetryOptions{})
+	assert.True(t, errors.Is(err, domain.ErrTaskCannotRetry), "got %v", err)
+
+	_, err = svc.RetryTask(ctx, "pending", RetryOptions{})
+	assert.True(t, errors.Is(err, domain.ErrTaskCannotRetry), "got %v", err)
+
+	_, err = svc.RetryTask(ctx, "missing", RetryOptions{})
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "got %v", err)
+
+	negative := -1
+	_, err = svc.RetryTask(ctx, "failed", RetryOptions{MaxRetries: &negative})
+	assert.True(t, errors.Is(err, domain.ErrBadParamInput), "got %v", err)
+}
+
+func TestGetRetrySchedule(t *testing.T) {
+	nextRetry := time.Now().Add(time.Minute)
+	repo := &fakeRepository{tasks: []*entity.Task{
+		{ID: "failed", Status: entity.TaskStatusFailed, RetryCount: 2, MaxRetries: 5, RetryBackoffSeconds: 60, NextRetryAt: &nextRetry},
+		{ID: "completed", Status: entity.TaskStatusCompleted, MaxRetries: 5},
+	}}
+	policy := entity.RetryPolicy{JitterPercent: 0, MaxBackoff: 5 * time.Minute}
+	svc := NewService(repo, WithRetryPolicy(policy))
+	ctx := context.Background()
+	assert.Equal(t, policy, svc.RetryPolicy())
+
+	schedule, err := svc.GetRetrySchedule(ctx, "failed")
+	require.NoError(t, err)
+	assert.Equal(t, policy, schedule.Policy)
+	require.Len(t, schedule.Retries, 4, "the pending retry and the 3 left after it")
+	assert.Equal(t, nextRetry, schedule.Retries[0].At)
+	assert.Equal(t, 4*time.Minute, schedule.Retries[0].Backoff)
+	for _, retry := range schedule.Retries[1:] {
+		assert.True(t, retry.Capped, "retry %d", retry.Retry)
+		assert.Equal(t, 5*time.Minute, retry.Backoff, "retry %d", retry.Retry)
+	}
+	assert.Equal(t, nextRetry.Add(15*time.Minute), schedule.Retries[3].At)
+
+	schedule, err = svc.GetRetrySchedule(ctx, "completed")
+	require.NoError(t, err)
+	assert.Empty(t, schedule.Retries)
+
+	_, err = svc.GetRetrySchedule(ctx, "missing")
+	assert.True(t, errors.Is(err, domain.ErrNotFound))
+}
@@ -2,11 +2,16 @@ package task
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/usual2970/later/domain/entity"
 	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/clock"
+	"github.com/usual2970/later/infrastructure/logger"
 	"github.com/usual2970/later/infrastructure/worker"
 
 	"go.uber.org/zap"
@@ -14,14 +19,48 @@ import (
 
 // Scheduler handles tiered polling for task scheduling
 type Scheduler struct {
-	highPriorityTicker   *time.Ticker
-	normalPriorityTicker *time.Ticker
-	cleanupTicker        *time.Ticker
-
-	taskRepo   repository.TaskRepository
-	workerPool worker.WorkerPool
-	logger     *zap.Logger
-	quit       chan struct{}
+	highPriorityTicker   clock.Ticker
+	normalPriorityTicker clock.Ticker
+	retryTicker          clock.Ticker
+	cleanupTicker        clock.Ticker
+
+	taskRepo    repository.TaskRepository
+	workerPool  worker.WorkerPool
+	retention   repository.RetentionPolicy
+	taskEvents  repository.TaskEventRepository // nil keeps recorded events
+	eventTTL    time.Duration
+	archiveSink ArchiveSink        // nil deletes expired tasks without archiving them
+	deadLetters *deadLetterMonitor // nil unless a dead letter alert is configured
+	cfg         SchedulerConfig    // With the batch size defaults filled in
+	cipher      *PayloadCipher
+	leader      Leadership
+	notifier    TaskNotifier
+	delayQueue  *DelayQueue // nil unless enabled
+	submitWait  time.Duration
+	clock       clock.Clock
+	logger      *zap.Logger
+	wake        chan struct{}
+	quit        chan struct{}
+
+	// Delay before the first poll, plus a random share of pollJitter; jitter is swapped in tests
+	initialPollDelay time.Duration
+	pollJitter       time.Duration
+	jitter           func(bound time.Duration) time.Duration
+
+	// When each ticker last fired, so a scheduler goroutine that died can be noticed
+	tickMu   sync.Mutex
+	lastTick map[string]time.Time
+
+	// Polls skipped or shortened because the worker queue was full or nearly so
+	saturatedPolls atomic.Int64
+
+	// Held while a cleanup runs
+	cleanupLock chan struct{}
+
+	// Cleanup totals reported by CleanupCounts
+	archivedTasks   atomic.Int64
+	deletedTasks    atomic.Int64
+	archiveFailures atomic.Int64
 }
 
 // NewScheduler creates a new scheduler with tiered polling
@@ -30,106 +69,615 @@ func NewScheduler(
 	workerPool worker.WorkerPool,
 	cfg SchedulerConfig,
 ) *Scheduler {
-	return &Scheduler{
-		highPriorityTicker:   time.NewTicker(cfg.HighPriorityInterval),
-		normalPriorityTicker: time.NewTicker(cfg.NormalPriorityInterval),
-		cleanupTicker:        time.NewTicker(cfg.CleanupInterval),
+	var delayQueue *DelayQueue
+	if cfg.DelayQueue {
+		delayQueue = NewDelayQueue(cfg.delayHorizon(), cfg.DelayQueueSize)
+	}
+	log := cfg.Logger
+	if log == nil {
+		log = logger.Named("scheduler")
+	}
+	clk := clock.OrReal(cfg.Clock)
+
+	s := &Scheduler{
+		highPriorityTicker:   clk.NewTicker(cfg.HighPriorityInterval),
+		normalPriorityTicker: clk.NewTicker(cfg.NormalPriorityInterval),
+		retryTicker:          clk.NewTicker(cfg.retryInterval()),
+		cleanupTicker:        clk.NewTicker(cfg.CleanupInterval),
 		taskRepo:             repo,
 		workerPool:           workerPool,
-		logger:               zap.NewNop(), // TODO: Use proper logger
+		retention:            cfg.retentionPolicy(),
+		taskEvents:           cfg.TaskEvents,
+		eventTTL:             cfg.EventRetention,
+		archiveSink:          cfg.ArchiveSink,
+		cfg:                  cfg.WithBatchDefaults(0),
+		cipher:               cfg.PayloadCipher,
+		leader:               cfg.Leader,
+		notifier:             cfg.Notifier,
+		delayQueue:           delayQueue,
+		submitWait:           cfg.ImmediateSubmitWait,
+		clock:                clk,
+		logger:               log,
+		wake:                 make(chan struct{}, 1),
 		quit:                 make(chan struct{}),
+		lastTick:             make(map[string]time.Time),
+		cleanupLock:          make(chan struct{}, 1),
+		initialPollDelay:     cfg.InitialPollDelay,
+		pollJitter:           cfg.PollJitter,
+		jitter:               randomJitter,
+	}
+	if s.archiveSink != nil {
+		s.retention.BeforeDelete = s.archiveTasks
 	}
+	if cfg.DeadLetterAlert.Enabled() {
+		s.deadLetters = newDeadLetterMonitor(cfg.DeadLetterAlert)
+	}
+	return s
 }
 
+// randomJitter returns a random duration in [0, bound)
+func randomJitter(bound time.Duration) time.Duration {
+	if bound <= 0 {
+		return 0
+	}
+	return rand.N(bound)
+}
+
+// DefaultCleanupRetention is how long finished tasks are kept when not configured
+const DefaultCleanupRetention = 30 * 24 * time.Hour
+
+// DefaultEventRetention is how long recorded task events are kept when not configured
+const DefaultEventRetention = 7 * 24 * time.Hour
+
+// Default poll batch sizes, used when SchedulerConfig leaves them zero
+const (
+	DefaultHighPriorityBatchSize   = 50
+	DefaultNormalPriorityBatchSize = 100
+	DefaultRetryBatchSize          = 100
+
+	// DefaultMaxBatchFactor allows batches of up to ten times the worker queue capacity
+	DefaultMaxBatchFactor = 10
+)
+
+// DefaultRetryInterval is how often failed tasks due for retry are polled when not configured
+const DefaultRetryInterval = 5 * time.Second
+
 type SchedulerConfig struct {
 	HighPriorityInterval   time.Duration
 	NormalPriorityInterval time.Duration
+	RetryInterval          time.Duration // Zero uses DefaultRetryInterval
 	CleanupInterval        time.Duration
+
+	// Cleanup retention; zero keeps tasks of that status forever
+	CompletedRetention    time.Duration
+	DeadLetteredRetention time.Duration
+	ArchiveBeforeDelete   bool // Copy rows into task_queue_archive before deleting
+
+	// TaskEvents has the events recorded for stream replay and task timelines deleted once
+	// they are older than EventRetention; leaving either unset keeps them forever
+	TaskEvents     repository.TaskEventRepository
+	EventRetention time.Duration
+
+	// ArchiveSink receives each batch of expired tasks, with their recorded events, before the
+	// cleanup deletes it; a batch the sink fails to write is kept and retried on the next cleanup
+	ArchiveSink ArchiveSink
+
+	// DeadLetterAlert raises an alert from the cleanup tick when the dead letter queue grows
+	// past its thresholds; disabled unless a threshold is set
+	DeadLetterAlert DeadLetterAlertConfig
+
+	// Poll batch sizes; zero uses the defaults. The cleanup tick's sweep across all
+	// priorities uses NormalPriorityBatchSize
+	HighPriorityBatchSize   int
+	NormalPriorityBatchSize int
+	RetryBatchSize          int
+
+	// MaxBatchFactor bounds batch sizes to this multiple of the worker queue capacity, since
+	// tasks that don't fit in the queue wait for the next poll; zero uses DefaultMaxBatchFactor
+	MaxBatchFactor int
+
+	// PayloadCipher decrypts payloads before tasks reach workers; use the task service's cipher
+	PayloadCipher *PayloadCipher
+
+	// Leader restricts polling and cleanup to the elected replica; nil always runs them
+	Leader Leadership
+
+	// Notifier wakes the scheduler to poll as soon as tasks are created, with the tickers as a
+	// fallback; nil relies on the tickers alone
+	Notifier TaskNotifier
+
+	// Logger receives the scheduler's logs; nil uses the global logger
+	Logger *zap.Logger
+
+	// DelayQueue holds tasks due within twice the normal poll interval in memory and dispatches
+	// them at their scheduled time instead of on the next poll; DelayQueueSize bounds it,
+	// zero using DefaultDelayQueueSize
+	DelayQueue     bool
+	DelayQueueSize int
+
+	// ImmediateSubmitWait is how long submitting a task due now waits for worker queue space
+	// before leaving it to the next poll; zero fails fast. It delays the create, retry or
+	// resurrect call that submits the task
+	ImmediateSubmitWait time.Duration
+
+	// InitialPollDelay postpones the first poll after Start, plus a random delay of up to
+	// PollJitter, after which the tickers restart. Replicas rolled out together then spread
+	// their polls instead of querying the database in lockstep; zero polls at once
+	InitialPollDelay time.Duration
+	PollJitter       time.Duration
+
+	// Clock drives the tickers and stamps dispatched tasks; nil uses the real clock
+	// The delay queue always runs on real timers
+	Clock clock.Clock
+}
+
+// DefaultPollJitter is the initial poll jitter the server and SDK use when not configured
+const DefaultPollJitter = time.Second
+
+// delayHorizon is how far ahead the delay queue accepts tasks; twice the normal poll interval
+// so each poll's look-ahead overlaps the next
+func (cfg SchedulerConfig) delayHorizon() time.Duration {
+	return 2 * cfg.NormalPriorityInterval
+}
+
+// retryInterval returns the retry poll interval, falling back to the default
+func (cfg SchedulerConfig) retryInterval() time.Duration {
+	if cfg.RetryInterval > 0 {
+		return cfg.RetryInterval
+	}
+	return DefaultRetryInterval
+}
+
+// retentionPolicy returns the cleanup policy for the repository
+func (cfg SchedulerConfig) retentionPolicy() repository.RetentionPolicy {
+	return repository.RetentionPolicy{
+		CompletedRetention:    cfg.CompletedRetention,
+		DeadLetteredRetention: cfg.DeadLetteredRetention,
+		Archive:               cfg.ArchiveBeforeDelete,
+	}
+}
+
+// WithBatchDefaults returns the config with zero batch sizes replaced by their defaults
+// A positive queueCapacity caps the defaults at MaxBatchFactor times the capacity so small
+// worker pools stay valid; explicitly configured sizes are left as they are
+func (cfg SchedulerConfig) WithBatchDefaults(queueCapacity int) SchedulerConfig {
+	if cfg.MaxBatchFactor == 0 {
+		cfg.MaxBatchFactor = DefaultMaxBatchFactor
+	}
+	limit := cfg.MaxBatchFactor * queueCapacity
+
+	for _, b := range []struct {
+		size *int
+		def  int
+	}{
+		{&cfg.HighPriorityBatchSize, DefaultHighPriorityBatchSize},
+		{&cfg.NormalPriorityBatchSize, DefaultNormalPriorityBatchSize},
+		{&cfg.RetryBatchSize, DefaultRetryBatchSize},
+	} {
+		if *b.size == 0 {
+			*b.size = b.def
+			if queueCapacity > 0 {
+				*b.size = min(b.def, limit)
+			}
+		}
+	}
+	return cfg
+}
+
+// ValidateBatchSizes checks that batch sizes are not negative and don't exceed MaxBatchFactor
+// times the worker queue capacity (see worker.QueueCapacity); zero sizes are not checked
+func (cfg SchedulerConfig) ValidateBatchSizes(queueCapacity int) error {
+	if cfg.MaxBatchFactor < 0 {
+		return fmt.Errorf("scheduler max batch factor cannot be negative")
+	}
+	factor := cfg.MaxBatchFactor
+	if factor == 0 {
+		factor = DefaultMaxBatchFactor
+	}
+
+	for _, b := range []struct {
+		name string
+		size int
+	}{
+		{"high priority", cfg.HighPriorityBatchSize},
+		{"normal priority", cfg.NormalPriorityBatchSize},
+		{"retry", cfg.RetryBatchSize},
+	} {
+		if b.size < 0 {
+			return fmt.Errorf("scheduler %s batch size cannot be negative", b.name)
+		}
+		if b.size > factor*queueCapacity {
+			return fmt.Errorf("scheduler %s batch size %d exceeds %d times the worker queue capacity of %d",
+				b.name, b.size, factor, queueCapacity)
+		}
+	}
+	return nil
 }
 
 // Start begins the tiered polling scheduler
 func (s *Scheduler) Start() {
 	defer s.highPriorityTicker.Stop()
 	defer s.normalPriorityTicker.Stop()
+	defer s.retryTicker.Stop()
 	defer s.cleanupTicker.Stop()
 
-	log.Println("Scheduler started with tiered polling")
+	s.logger.Info("Scheduler started with tiered polling")
 
-	// Initial poll
-	s.pollDueTasks("high", 5, 50)
-	s.pollDueTasks("normal", 0, 100)
+	if s.notifier != nil {
+		go s.notifier.Run(s.Wake)
+		defer s.notifier.Stop()
+	}
+	if s.delayQueue != nil {
+		go s.delayQueue.Run(s.dispatchDelayed)
+		defer s.delayQueue.Stop()
+	}
+
+	// Initial poll, once the start delay has passed
+	if !s.waitInitialPoll() {
+		s.logger.Info("Scheduler stopping")
+		return
+	}
+	s.tick("start", func() {
+		s.pollDueTasks("high", 5, s.cfg.HighPriorityBatchSize)
+		s.pollDueTasks("normal", 0, s.cfg.NormalPriorityBatchSize)
+		s.pollUpcomingTasks(s.cfg.NormalPriorityBatchSize)
+		s.pollRetryTasks(s.cfg.RetryBatchSize)
+	})
 
 	for {
 		select {
-		case <-s.highPriorityTicker.C:
-			s.pollDueTasks("high", 5, 50)
-
-		case <-s.normalPriorityTicker.C:
-			s.pollDueTasks("normal", 0, 100)
-
-		case <-s.cleanupTicker.C:
-			s.pollDueTasks("low", -1, 200)
-			s.cleanupExpiredTasks()
+		case <-s.highPriorityTicker.C():
+			s.tick("high", func() {
+				s.pollDueTasks("high", 5, s.cfg.HighPriorityBatchSize)
+			})
+
+		case <-s.normalPriorityTicker.C():
+			s.tick("normal", func() {
+				s.pollDueTasks("normal", 0, s.cfg.NormalPriorityBatchSize)
+				s.pollUpcomingTasks(s.cfg.NormalPriorityBatchSize)
+			})
+
+		case <-s.wake:
+			// New tasks may be due on any priority; the tickers still catch anything missed
+			s.tick("notify", func() {
+				s.pollDueTasks("notify", -1, s.cfg.NormalPriorityBatchSize)
+			})
+
+		case <-s.retryTicker.C():
+			// Retries have their own ticker so a backlog of pending tasks can't starve them
+			s.tick("retry", func() {
+				s.pollRetryTasks(s.cfg.RetryBatchSize)
+			})
+
+		case <-s.cleanupTicker.C():
+			s.tick("cleanup", func() {
+				s.pollDueTasks("low", -1, s.cfg.NormalPriorityBatchSize)
+				s.cleanupExpiredTasks()
+			})
 
 		case <-s.quit:
-			log.Println("Scheduler stopping...")
+			s.logger.Info("Scheduler stopping")
 			return
 		}
 	}
 }
 
+// waitInitialPoll waits out the initial poll delay and jitter, then restarts the tickers so
+// later polls keep the offset. Returns false if the scheduler was stopped meanwhile
+func (s *Scheduler) waitInitialPoll() bool {
+	delay := s.initialPollDelay + s.jitter(s.pollJitter)
+	if delay <= 0 {
+		return true
+	}
+
+	s.logger.Debug("Delaying initial poll", zap.Duration("delay", delay))
+	select {
+	case <-s.clock.After(delay):
+	case <-s.quit:
+		return false
+	}
+
+	s.highPriorityTicker.Reset(s.cfg.HighPriorityInterval)
+	s.normalPriorityTicker.Reset(s.cfg.NormalPriorityInterval)
+	s.retryTicker.Reset(s.cfg.retryInterval())
+	s.cleanupTicker.Reset(s.cfg.CleanupInterval)
+	return true
+}
+
+// tick records the ticker's heartbeat and runs its polls, recovering from a panic so one bad
+// poll doesn't stop scheduling for good
+func (s *Scheduler) tick(ticker string, poll func()) {
+	s.tickMu.Lock()
+	s.lastTick[ticker] = s.clock.Now()
+	s.tickMu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Recovered panic while polling",
+				zap.String("ticker", ticker),
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+	poll()
+}
+
+// LastActivity returns when any of the scheduler's tickers last fired; zero before Start
+// Followers keep ticking without polling, so a stale time means the scheduler loop is stuck or gone
+func (s *Scheduler) LastActivity() time.Time {
+	s.tickMu.Lock()
+	defer s.tickMu.Unlock()
+
+	var last time.Time
+	for _, at := range s.lastTick {
+		if at.After(last) {
+			last = at
+		}
+	}
+	return last
+}
+
 // Stop gracefully stops the scheduler
 func (s *Scheduler) Stop() {
 	close(s.quit)
 }
 
-// SubmitTaskImmediately submits a task directly to the worker pool
-func (s *Scheduler) SubmitTaskImmediately(task *entity.Task) {
-	if s.workerPool.SubmitTask(task) {
-		log.Printf("Task submitted immediately: %s (priority: %d)", task.ID, task.Priority)
+// Wake makes the scheduler poll for due tasks now instead of at its next tick
+// Wakes arriving while a poll is pending are coalesced
+func (s *Scheduler) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Dispatch describes how a scheduled task will reach a worker
+type Dispatch string
+
+const (
+	// DispatchImmediate means the task was handed to the worker pool
+	DispatchImmediate Dispatch = "immediate"
+	// DispatchQueuedForPoll means the task is due but the worker queue was full, so the next
+	// poll picks it up
+	DispatchQueuedForPoll Dispatch = "queued_for_poll"
+	// DispatchScheduled means the task runs at its scheduled time
+	DispatchScheduled Dispatch = "scheduled"
+	// DispatchAfterDependency means the task runs once the task it depends on finishes
+	DispatchAfterDependency Dispatch = "after_dependency"
+)
+
+// SubmitTaskImmediately submits a task directly to the worker pool, waiting up to
+// ImmediateSubmitWait for queue space. Returns false if the task was left to the next poll
+func (s *Scheduler) SubmitTaskImmediately(task *entity.Task) bool {
+	var submitted bool
+	if waiter, ok := s.workerPool.(interface {
+		SubmitTaskWithin(*entity.Task, time.Duration) bool
+	}); ok && s.submitWait > 0 {
+		submitted = waiter.SubmitTaskWithin(task, s.submitWait)
 	} else {
-		log.Printf("Worker pool full, task will be picked up by next poll: %s", task.ID)
+		submitted = s.workerPool.SubmitTask(task)
+	}
+
+	if submitted {
+		s.logger.Info("Task submitted immediately",
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID),
+			zap.Int("priority", task.Priority))
+	} else {
+		s.logger.Warn("Worker pool full, task will be picked up by next poll",
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID))
+	}
+	return submitted
+}
+
+// isLeader reports whether this replica should poll and clean up
+func (s *Scheduler) isLeader() bool {
+	return s.leader == nil || s.leader.IsLeader()
+}
+
+// queueSpace returns how many more tasks the worker pool can queue, capped at limit
+// Pools that don't report their queue are assumed to have room for the whole batch
+func (s *Scheduler) queueSpace(limit int) int {
+	pool, ok := s.workerPool.(interface {
+		QueueDepth() int
+		QueueCapacity() int
+	})
+	if !ok || pool.QueueCapacity() <= 0 {
+		return limit
+	}
+	space := max(pool.QueueCapacity()-pool.QueueDepth(), 0)
+	if space < limit {
+		s.saturatedPolls.Add(1)
+		return space
+	}
+	return limit
+}
+
+// SaturatedPolls returns how many polls were skipped or fetched fewer tasks because the worker
+// queue was full or nearly so
+func (s *Scheduler) SaturatedPolls() int64 {
+	return s.saturatedPolls.Load()
+}
+
+// saturatedKeys returns the concurrency keys the worker pool would only hold tasks for; their
+// tasks are left out of polls, as held tasks stay pending and would fill every batch
+func (s *Scheduler) saturatedKeys() []string {
+	if pool, ok := s.workerPool.(interface{ SaturatedKeys() []string }); ok {
+		return pool.SaturatedKeys()
 	}
+	return nil
 }
 
 func (s *Scheduler) pollDueTasks(tier string, minPriority int, limit int) {
+	if !s.isLeader() {
+		return
+	}
+
+	// Fetch no more than the workers can take; the rest stay pending for a later poll
+	batch := s.queueSpace(limit)
+	if batch == 0 {
+		s.logger.Debug("Worker queue full, skipping poll", zap.String("tier", tier))
+		return
+	}
+	limit = batch
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	tasks, err := s.taskRepo.FindDueTasks(ctx, minPriority, limit)
+	tasks, err := s.taskRepo.FindDueTasks(ctx, minPriority, limit, s.saturatedKeys())
 	if err != nil {
-		log.Printf("Failed to fetch due tasks (tier=%s): %v", tier, err)
+		s.logger.Error("Failed to fetch due tasks", zap.String("tier", tier), zap.Error(err))
 		return
 	}
 
 	if len(tasks) == 0 {
-		// Only poll for retries if no new pending tasks
-		s.pollRetryTasks(tier, limit)
 		return
 	}
 
-	log.Printf("Found %d due tasks (tier=%s)", len(tasks), tier)
+	s.logger.Debug("Found due tasks", zap.String("tier", tier), zap.Int("limit", limit), zap.Int("count", len(tasks)))
 
-	submitted := 0
+	submitted, rejected := 0, 0
 	for _, task := range tasks {
+		if !s.decrypt(task) {
+			continue
+		}
 		if s.workerPool.SubmitTask(task) {
 			submitted++
+			s.logger.Debug("Task submitted",
+				zap.String("task_id", task.ID),
+				logger.RequestID(task.RequestID),
+				zap.String("tier", tier))
 		} else {
-			log.Printf("Worker pool full, task will be retried next cycle: %s", task.ID)
+			// Other submissions can still fill the queue after it was sized; the tasks stay
+			// pending, as workers only claim tasks they take from the queue
+			rejected++
+		}
+	}
+	if rejected > 0 {
+		s.logger.Warn("Worker pool full, tasks will be retried next cycle",
+			zap.String("tier", tier),
+			zap.Int("rejected", rejected))
+	}
+
+	s.logger.Debug("Tasks submitted to workers",
+		zap.String("tier", tier),
+		zap.Int("limit", limit),
+		zap.Int("submitted", submitted),
+		zap.Int("found", len(tasks)))
+}
+
+// ScheduleTask hands a created or rescheduled task to the scheduler: tasks due now go to the
+// worker pool, tasks due within the delay queue's horizon wait in memory, and the rest are
+// left to polling. Only pending tasks are scheduled; waiting tasks run once released
+// Returns how the task will be dispatched, or an empty Dispatch for other statuses
+func (s *Scheduler) ScheduleTask(task *entity.Task) Dispatch {
+	dispatch := s.EstimateDispatch(task)
+	switch dispatch {
+	case DispatchImmediate:
+		if !s.SubmitTaskImmediately(task) {
+			return DispatchQueuedForPoll
+		}
+	case DispatchScheduled:
+		if s.delayQueue != nil {
+			s.delayQueue.Add(task.ID, task.ScheduledAt)
 		}
 	}
+	return dispatch
+}
 
-	log.Printf("Tasks submitted to workers (tier=%s): %d/%d", tier, submitted, len(tasks))
+// EstimateDispatch returns how ScheduleTask would dispatch the task, without scheduling it
+// Tasks due now are estimated as immediate, though a full worker pool leaves them to polling
+func (s *Scheduler) EstimateDispatch(task *entity.Task) Dispatch {
+	switch {
+	case task.Status == entity.TaskStatusWaiting:
+		return DispatchAfterDependency
+	case task.Status != entity.TaskStatusPending:
+		return ""
+	case task.ShouldExecuteNow(s.clock.Now()):
+		return DispatchImmediate
+	default:
+		return DispatchScheduled
+	}
+}
+
+// ForgetTask drops a deleted task from the delay queue
+func (s *Scheduler) ForgetTask(id string) {
+	if s.delayQueue != nil {
+		s.delayQueue.Remove(id)
+	}
 }
 
-func (s *Scheduler) pollRetryTasks(tier string, limit int) {
+// pollUpcomingTasks loads tasks due within the delay queue's horizon, so tasks created on
+// other replicas or before a restart are dispatched on time too
+func (s *Scheduler) pollUpcomingTasks(limit int) {
+	if s.delayQueue == nil || !s.isLeader() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	upcoming, err := s.taskRepo.FindUpcomingTasks(ctx, s.clock.Now().Add(s.delayQueue.Horizon()), limit)
+	if err != nil {
+		s.logger.Error("Failed to fetch upcoming tasks", zap.Error(err))
+		return
+	}
+	for _, task := range upcoming {
+		s.delayQueue.Add(task.ID, task.ScheduledAt)
+	}
+}
+
+// dispatchDelayed submits a task from the delay queue once the database confirms it is still
+// pending and due; deleted, started or rescheduled tasks are dropped or re-queued
+func (s *Scheduler) dispatchDelayed(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	task, err := s.taskRepo.FindByID(ctx, id)
+	if err != nil {
+		// Deleted tasks aren't found; anything else is retried by the next poll
+		return
+	}
+	if task.Status != entity.TaskStatusPending {
+		return
+	}
+	if task.ScheduledAt.After(s.clock.Now()) {
+		s.delayQueue.Add(task.ID, task.ScheduledAt)
+		return
+	}
+	if !s.decrypt(task) {
+		return
+	}
+
+	if !s.workerPool.SubmitTask(task) {
+		s.logger.Warn("Worker pool full, delayed task will be picked up by the next poll",
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID))
+	}
+}
+
+func (s *Scheduler) pollRetryTasks(limit int) {
+	if !s.isLeader() {
+		return
+	}
+
+	// Retries reset to pending but left unsubmitted would wait for a due-task poll, so only
+	// as many as the workers can take are fetched
+	batch := s.queueSpace(limit)
+	if batch == 0 {
+		s.logger.Debug("Worker queue full, skipping retry poll")
+		return
+	}
+	limit = batch
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Poll for failed tasks ready for retry
 	retryTasks, err := s.taskRepo.FindFailedTasks(ctx, limit)
 	if err != nil {
-		log.Printf("Failed to fetch retry tasks (tier=%s): %v", tier, err)
+		s.logger.Error("Failed to fetch retry tasks", zap.Error(err))
 		return
 	}
 
@@ -137,34 +685,122 @@ func (s *Scheduler) pollRetryTasks(tier string, limit int) {
 		return
 	}
 
-	log.Printf("Found %d retry tasks (tier=%s)", len(retryTasks), tier)
+	s.logger.Debug("Found retry tasks", zap.Int("limit", limit), zap.Int("count", len(retryTasks)))
 
-	submitted := 0
+	submitted, rejected := 0, 0
 	for _, task := range retryTasks {
-		// Reset task to pending before resubmitting
+		if !s.decrypt(task) {
+			continue
+		}
+
+		// Persist the reset to pending before submitting, so a task whose worker never
+		// picks it up (full queue, crash) is still found by the next due-task poll
+		// The reset only applies while the task is still failed, so one executed or
+		// deleted in the meantime is left alone
 		task.Status = entity.TaskStatusPending
+		reset, err := s.taskRepo.UpdateIfStatus(ctx, task, entity.TaskStatusFailed)
+		if err != nil {
+			s.logger.Error("Failed to reset retry task to pending",
+				zap.String("task_id", task.ID),
+				logger.RequestID(task.RequestID),
+				zap.Error(err))
+			continue
+		}
+		if !reset {
+			continue
+		}
 
 		if s.workerPool.SubmitTask(task) {
 			submitted++
+			s.logger.Debug("Retry task submitted",
+				zap.String("task_id", task.ID),
+				logger.RequestID(task.RequestID),
+				zap.Int("retry_count", task.RetryCount))
 		} else {
-			log.Printf("Worker pool full, retry task will be retried next cycle: %s", task.ID)
+			rejected++
 		}
 	}
+	if rejected > 0 {
+		s.logger.Warn("Worker pool full, retry tasks will be picked up by the next poll",
+			zap.Int("rejected", rejected))
+	}
 
-	log.Printf("Retry tasks submitted to workers (tier=%s): %d/%d", tier, submitted, len(retryTasks))
+	s.logger.Debug("Retry tasks submitted to workers",
+		zap.Int("limit", limit),
+		zap.Int("submitted", submitted),
+		zap.Int("found", len(retryTasks)))
+}
+
+// decrypt restores the task's plaintext payload for delivery
+// Tasks whose key is unavailable stay in the queue until it is configured
+func (s *Scheduler) decrypt(task *entity.Task) bool {
+	if err := decryptPayload(s.cipher, task); err != nil {
+		s.logger.Error("Skipping task with undecryptable payload",
+			zap.String("task_id", task.ID),
+			logger.RequestID(task.RequestID),
+			zap.Error(err))
+		return false
+	}
+	return true
 }
 
 func (s *Scheduler) cleanupExpiredTasks() {
+	if !s.isLeader() {
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	count, err := s.taskRepo.CleanupExpiredData(ctx)
+	// Counted before the cleanup deletes old dead letters, which would hide recent growth
+	s.checkDeadLetters(ctx)
+	s.runCleanup(ctx, "scheduled", func() (*repository.CleanupResult, error) {
+		return s.taskRepo.CleanupExpiredData(ctx, s.retention)
+	})
+	s.cleanupTaskEvents(ctx)
+}
+
+// runCleanup runs a cleanup once no other is running, so scheduled and on-demand cleanups don't
+// overlap, then records and logs its counts
+func (s *Scheduler) runCleanup(ctx context.Context, trigger string, cleanup func() (*repository.CleanupResult, error)) (*repository.CleanupResult, error) {
+	select {
+	case s.cleanupLock <- struct{}{}:
+		defer func() { <-s.cleanupLock }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	// Batches deleted before a failure stay deleted, so their counts are reported either way
+	sinkArchived := s.archivedTasks.Load()
+	result, err := cleanup()
+	sinkArchived = s.archivedTasks.Load() - sinkArchived
+	if result != nil {
+		s.deletedTasks.Add(result.Deleted)
+		if result.Archived > 0 || result.Deleted > 0 || sinkArchived > 0 {
+			s.logger.Info("Cleaned up expired tasks",
+				zap.String("trigger", trigger),
+				zap.Int64("archived", result.Archived),
+				zap.Int64("archived_to_sink", sinkArchived),
+				zap.Int64("deleted", result.Deleted))
+		}
+	}
 	if err != nil {
-		log.Printf("Failed to cleanup expired data: %v", err)
-		return
+		s.logger.Error("Failed to cleanup expired data", zap.String("trigger", trigger), zap.Error(err))
 	}
+	return result, err
+}
 
-	if count > 0 {
-		log.Printf("Cleaned up %d expired tasks", count)
+// cleanupTaskEvents deletes recorded task events older than the event retention
+func (s *Scheduler) cleanupTaskEvents(ctx context.Context) {
+	if s.taskEvents == nil || s.eventTTL <= 0 {
+		return
+	}
+	deleted, err := s.taskEvents.DeleteBefore(ctx, s.clock.Now().Add(-s.eventTTL))
+	if err != nil {
+		s.logger.Error("Failed to cleanup task events", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		s.logger.Info("Cleaned up task events", zap.Int64("deleted", deleted))
 	}
 }
@@ -0,0 +1,536 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/clock"
+	"github.com/usual2970/later/infrastructure/worker"
+	"github.com/usual2970/later/repository/memory"
+)
+
+// backlogRepository always has due pending tasks and one failed task due for retry
+type backlogRepository struct {
+	repository.TaskRepository
+
+	mu         sync.Mutex
+	retry      *entity.Task
+	retryDue   bool
+	retryPolls int
+	updates    []entity.Task
+}
+
+func (r *backlogRepository) FindDueTasks(ctx context.Context, minPriority int, limit int, skipGroups []string) ([]*entity.Task, error) {
+	tasks := make([]*entity.Task, limit)
+	for i := range tasks {
+		tasks[i] = &entity.Task{ID: fmt.Sprintf("pending-%d", i), Status: entity.TaskStatusPending}
+	}
+	return tasks, nil
+}
+
+func (r *backlogRepository) FindFailedTasks(ctx context.Context, limit int) ([]*entity.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retryPolls++
+	if !r.retryDue || r.retry.Status != entity.TaskStatusFailed {
+		return nil, nil
+	}
+	task := *r.retry
+	return []*entity.Task{&task}, nil
+}
+
+func (r *backlogRepository) UpdateIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if task.ID == r.retry.ID {
+		if !slices.Contains(from, r.retry.Status) {
+			return false, nil
+		}
+		r.retry.Status = task.Status
+	}
+	r.updates = append(r.updates, *task)
+	return true, nil
+}
+
+func (r *backlogRepository) CleanupExpiredData(ctx context.Context, policy repository.RetentionPolicy) (*repository.CleanupResult, error) {
+	return &repository.CleanupResult{}, nil
+}
+
+// recordingPool accepts every task and records the IDs and statuses it was given
+type recordingPool struct {
+	mu        sync.Mutex
+	submitted map[string]entity.TaskStatus
+}
+
+func (p *recordingPool) Start(workerCount int) {}
+
+func (p *recordingPool) SubmitTask(task *entity.Task) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.submitted[task.ID] = task.Status
+	return true
+}
+
+func (p *recordingPool) Stop(ctx context.Context) int { return 0 }
+
+func (p *recordingPool) status(id string) (entity.TaskStatus, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status, ok := p.submitted[id]
+	return status, ok
+}
+
+func TestSchedulerRetriesDispatchDespiteBacklog(t *testing.T) {
+	repo := &backlogRepository{retry: &entity.Task{ID: "retry", Status: entity.TaskStatusFailed}}
+	pool := &recordingPool{submitted: make(map[string]entity.TaskStatus)}
+
+	clk := clock.NewFake(time.Now())
+	retryInterval := 100 * time.Millisecond
+	scheduler := NewScheduler(repo, pool, SchedulerConfig{
+		HighPriorityInterval:   10 * time.Millisecond,
+		NormalPriorityInterval: 10 * time.Millisecond,
+		RetryInterval:          retryInterval,
+		CleanupInterval:        time.Hour,
+		Clock:                  clk,
+	})
+
+	go scheduler.Start()
+	defer scheduler.Stop()
+
+	// The retry becomes due after the initial poll, so it must be picked up by the retry ticker
+	require.Eventually(t, func() bool {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+		return repo.retryPolls == 1
+	}, time.Second, time.Millisecond, "the initial poll checks for retries")
+	repo.mu.Lock()
+	repo.retryDue = true
+	repo.mu.Unlock()
+
+	clk.Advance(retryInterval - time.Millisecond)
+	_, ok := pool.status("retry")
+	assert.False(t, ok, "nothing polls for retries before the retry interval")
+
+	clk.Advance(time.Millisecond)
+	require.Eventually(t, func() bool {
+		_, ok := pool.status("retry")
+		return ok
+	}, time.Second, time.Millisecond, "retries must dispatch within one retry interval")
+
+	status, _ := pool.status("retry")
+	assert.Equal(t, entity.TaskStatusPending, status)
+	_, ok = pool.status("pending-0")
+	assert.True(t, ok, "the pending backlog is still polled")
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	require.NotEmpty(t, repo.updates)
+	assert.Equal(t, entity.TaskStatusPending, repo.updates[0].Status, "the reset to pending is persisted before submission")
+}
+
+// TestSchedulerDispatchesStoredTasks tests which stored tasks each poll dispatches, against the
+// in-memory repository on a fake clock
+func TestSchedulerDispatchesStoredTasks(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	repo := memory.NewTaskRepositoryWithClock(clk)
+	pool := &recordingPool{submitted: make(map[string]entity.TaskStatus)}
+
+	now := clk.Now()
+	retryAt := now.Add(time.Minute)
+	for _, task := range []*entity.Task{
+		{ID: "due", Status: entity.TaskStatusPending, ScheduledAt: now.Add(-time.Second), Priority: 1},
+		{ID: "urgent", Status: entity.TaskStatusPending, ScheduledAt: now, Priority: 8},
+		{ID: "later", Status: entity.TaskStatusPending, ScheduledAt: now.Add(time.Hour)},
+		{ID: "waiting", Status: entity.TaskStatusWaiting, ScheduledAt: now},
+		{ID: "retry", Status: entity.TaskStatusPending, ScheduledAt: now.Add(-time.Hour)},
+	} {
+		require.NoError(t, repo.Create(ctx, task))
+	}
+	failed, err := repo.FindByID(ctx, "retry")
+	require.NoError(t, err)
+	failed.Status, failed.RetryCount, failed.NextRetryAt = entity.TaskStatusFailed, 1, &retryAt
+	require.NoError(t, repo.Update(ctx, failed))
+
+	scheduler := NewScheduler(repo, pool, SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		RetryInterval:          time.Minute,
+		CleanupInterval:        2 * time.Hour,
+		Logger:                 zap.NewNop(),
+		Clock:                  clk,
+	})
+	go scheduler.Start()
+	defer scheduler.Stop()
+
+	require.Eventually(t, func() bool {
+		_, due := pool.status("due")
+		_, urgent := pool.status("urgent")
+		return due && urgent
+	}, time.Second, time.Millisecond, "the initial poll dispatches due tasks")
+	for _, id := range []string{"later", "waiting", "retry"} {
+		_, ok := pool.status(id)
+		assert.False(t, ok, "%s isn't due", id)
+	}
+
+	clk.Advance(time.Minute)
+	require.Eventually(t, func() bool {
+		_, ok := pool.status("retry")
+		return ok
+	}, time.Second, time.Millisecond, "the retry is dispatched once its retry time passes")
+	stored, err := repo.FindByID(ctx, "retry")
+	require.NoError(t, err)
+	assert.Equal(t, entity.TaskStatusPending, stored.Status, "the reset to pending is persisted")
+}
+
+func TestSchedulerBatchSizes(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		cfg := SchedulerConfig{}.WithBatchDefaults(40)
+		assert.Equal(t, DefaultHighPriorityBatchSize, cfg.HighPriorityBatchSize)
+		assert.Equal(t, DefaultNormalPriorityBatchSize, cfg.NormalPriorityBatchSize)
+		assert.Equal(t, DefaultRetryBatchSize, cfg.RetryBatchSize)
+		assert.NoError(t, cfg.ValidateBatchSizes(40))
+	})
+
+	t.Run("Defaults are capped for small worker pools", func(t *testing.T) {
+		cfg := SchedulerConfig{MaxBatchFactor: 5, RetryBatchSize: 3}.WithBatchDefaults(2)
+		assert.Equal(t, 10, cfg.HighPriorityBatchSize)
+		assert.Equal(t, 10, cfg.NormalPriorityBatchSize)
+		assert.Equal(t, 3, cfg.RetryBatchSize, "configured sizes are kept")
+		assert.NoError(t, cfg.ValidateBatchSizes(2))
+	})
+
+	tests := []struct {
+		name    string
+		cfg     SchedulerConfig
+		wantErr bool
+	}{
+		{"Unset sizes are valid", SchedulerConfig{}, false},
+		{"Within the default factor", SchedulerConfig{NormalPriorityBatchSize: 400}, false},
+		{"Beyond the default factor", SchedulerConfig{NormalPriorityBatchSize: 401}, true},
+		{"Beyond a configured factor", SchedulerConfig{MaxBatchFactor: 2, RetryBatchSize: 81}, true},
+		{"Negative size", SchedulerConfig{HighPriorityBatchSize: -1}, true},
+		{"Negative factor", SchedulerConfig{MaxBatchFactor: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.ValidateBatchSizes(40)
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+		})
+	}
+}
+
+// fullPool refuses every task, as a pool with a full queue does
+type fullPool struct{ recordingPool }
+
+func (p *fullPool) SubmitTask(task *entity.Task) bool { return false }
+
+// boundedPool accepts every task and reports a queue of 10 with room for free more
+type boundedPool struct {
+	recordingPool
+	free int
+}
+
+func (p *boundedPool) QueueDepth() int    { return 10 - p.free }
+func (p *boundedPool) QueueCapacity() int { return 10 }
+
+func TestSchedulerSizesPollsToQueueSpace(t *testing.T) {
+	pool := &boundedPool{recordingPool: recordingPool{submitted: make(map[string]entity.TaskStatus)}, free: 3}
+	cfg := SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        time.Hour,
+	}
+	scheduler := NewScheduler(&backlogRepository{}, pool, cfg)
+
+	scheduler.pollDueTasks("normal", 0, 100)
+	assert.Len(t, pool.submitted, 3, "only as many tasks as the queue has room for")
+	assert.Equal(t, int64(1), scheduler.SaturatedPolls())
+
+	scheduler.pollDueTasks("high", 5, 2)
+	assert.Equal(t, int64(1), scheduler.SaturatedPolls(), "room for the whole batch")
+
+	repo := &pollCountingRepository{}
+	pool.free = 0
+	scheduler = NewScheduler(repo, pool, cfg)
+	scheduler.pollDueTasks("normal", 0, 100)
+	assert.Zero(t, repo.polls.Load(), "a full queue skips the poll")
+	assert.Equal(t, int64(1), scheduler.SaturatedPolls())
+}
+
+func TestSchedulerSkipsSaturatedConcurrencyKeys(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	repo := memory.NewTaskRepositoryWithClock(clk)
+	erp := "erp"
+	for i := 0; i < 6; i++ {
+		require.NoError(t, repo.Create(ctx, &entity.Task{
+			ID: fmt.Sprintf("erp-%d", i), Status: entity.TaskStatusPending, ConcurrencyKey: &erp,
+			ScheduledAt: clk.Now().Add(-time.Hour + time.Duration(i)*time.Second),
+		}))
+	}
+	require.NoError(t, repo.Create(ctx, &entity.Task{ID: "crm", Status: entity.TaskStatusPending, Tags: []string{"crm"}, ScheduledAt: clk.Now()}))
+
+	// The pool isn't started, so the tasks it accepts stay in its queue
+	limiter, err := worker.NewConcurrencyLimiter(map[string]int{"erp": 2}, 0)
+	require.NoError(t, err)
+	pool := worker.NewWorkerPool(1, nil, nil, nil, zap.NewNop(), worker.WithConcurrencyLimiter(limiter), worker.WithQueueBuffer(10))
+	scheduler := NewScheduler(repo, pool, SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        time.Hour,
+		Clock:                  clk,
+	})
+
+	scheduler.pollDueTasks("normal", -1, 2)
+	assert.Equal(t, 2, limiter.InFlight("erp"))
+	scheduler.pollDueTasks("normal", -1, 2)
+
+	queue := pool.(interface{ QueueDepth() int })
+	assert.Equal(t, 3, queue.QueueDepth(), "the other key's task is dispatched despite the older erp backlog")
+	assert.Zero(t, limiter.Waiting("erp"), "tasks of a saturated key are left in the database")
+}
+
+func TestScheduleTaskReportsDispatch(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		task   entity.Task
+		full   bool
+		expect Dispatch
+	}{
+		{"due now", entity.Task{ID: "due", Status: entity.TaskStatusPending, ScheduledAt: now}, false, DispatchImmediate},
+		{"due now with a full queue", entity.Task{ID: "due", Status: entity.TaskStatusPending, ScheduledAt: now}, true, DispatchQueuedForPoll},
+		{"due later", entity.Task{ID: "later", Status: entity.TaskStatusPending, ScheduledAt: now.Add(time.Hour)}, false, DispatchScheduled},
+		{"waiting on a dependency", entity.Task{ID: "child", Status: entity.TaskStatusWaiting, ScheduledAt: now}, false, DispatchAfterDependency},
+		{"completed", entity.Task{ID: "done", Status: entity.TaskStatusCompleted, ScheduledAt: now}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pool worker.WorkerPool = &recordingPool{submitted: make(map[string]entity.TaskStatus)}
+			if tt.full {
+				pool = &fullPool{}
+			}
+			scheduler := NewScheduler(&backlogRepository{}, pool, SchedulerConfig{
+				HighPriorityInterval:   time.Hour,
+				NormalPriorityInterval: time.Hour,
+				CleanupInterval:        time.Hour,
+			})
+
+			task := tt.task
+			assert.Equal(t, tt.expect, scheduler.ScheduleTask(&task))
+		})
+	}
+}
+
+// panickingRepository panics on every poll for due tasks
+type panickingRepository struct {
+	backlogRepository
+	polls atomic.Int32
+}
+
+func (r *panickingRepository) FindDueTasks(ctx context.Context, minPriority int, limit int, skipGroups []string) ([]*entity.Task, error) {
+	r.polls.Add(1)
+	panic("bad poll")
+}
+
+func TestSchedulerSurvivesPanickingPoll(t *testing.T) {
+	repo := &panickingRepository{backlogRepository: backlogRepository{retry: &entity.Task{ID: "retry"}}}
+	scheduler := NewScheduler(repo, &recordingPool{submitted: make(map[string]entity.TaskStatus)}, SchedulerConfig{
+		HighPriorityInterval:   10 * time.Millisecond,
+		NormalPriorityInterval: time.Hour,
+		RetryInterval:          time.Hour,
+		CleanupInterval:        time.Hour,
+	})
+	assert.True(t, scheduler.LastActivity().IsZero(), "no activity before Start")
+
+	go scheduler.Start()
+	defer scheduler.Stop()
+
+	require.Eventually(t, func() bool { return repo.polls.Load() >= 3 }, time.Second, 5*time.Millisecond,
+		"polling continues after a poll panics")
+	assert.WithinDuration(t, time.Now(), scheduler.LastActivity(), 100*time.Millisecond)
+}
+
+// idleRepository has nothing to poll
+type idleRepository struct {
+	repository.TaskRepository
+}
+
+func (idleRepository) FindDueTasks(ctx context.Context, minPriority int, limit int, skipGroups []string) ([]*entity.Task, error) {
+	return nil, nil
+}
+
+func (idleRepository) FindFailedTasks(ctx context.Context, limit int) ([]*entity.Task, error) {
+	return nil, nil
+}
+
+// TestSchedulerPollLogVolume tests that polling, idle or not, stays out of Info-level logs
+func TestSchedulerPollLogVolume(t *testing.T) {
+	for name, repo := range map[string]repository.TaskRepository{
+		"idle":    idleRepository{},
+		"backlog": &backlogRepository{retry: &entity.Task{ID: "retry"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.InfoLevel)
+			scheduler := NewScheduler(repo, &recordingPool{submitted: make(map[string]entity.TaskStatus)}, SchedulerConfig{
+				HighPriorityInterval:   time.Millisecond,
+				NormalPriorityInterval: time.Millisecond,
+				RetryInterval:          time.Millisecond,
+				CleanupInterval:        time.Hour,
+				Logger:                 zap.New(core),
+			})
+
+			done := make(chan struct{})
+			go func() {
+				scheduler.Start()
+				close(done)
+			}()
+			time.Sleep(50 * time.Millisecond)
+			scheduler.Stop()
+			<-done
+
+			var messages []string
+			for _, entry := range logs.All() {
+				messages = append(messages, entry.Message)
+			}
+			assert.Equal(t, []string{"Scheduler started with tiered polling", "Scheduler stopping"}, messages)
+		})
+	}
+}
+
+// pollCountingRepository counts polls for due tasks and has nothing to return
+type pollCountingRepository struct {
+	idleRepository
+	polls atomic.Int64
+}
+
+func (r *pollCountingRepository) FindDueTasks(ctx context.Context, minPriority int, limit int, skipGroups []string) ([]*entity.Task, error) {
+	r.polls.Add(1)
+	return nil, nil
+}
+
+// TestSchedulerInitialPollDelay tests that the first poll waits out the delay plus jitter, and
+// that the tickers keep the offset afterwards
+func TestSchedulerInitialPollDelay(t *testing.T) {
+	repo := &pollCountingRepository{}
+	clk := clock.NewFake(time.Now())
+	scheduler := NewScheduler(repo, &recordingPool{submitted: make(map[string]entity.TaskStatus)}, SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        2 * time.Hour,
+		InitialPollDelay:       5 * time.Second,
+		PollJitter:             2 * time.Second,
+		Logger:                 zap.NewNop(),
+		Clock:                  clk,
+	})
+	var jitterBound time.Duration
+	scheduler.jitter = func(bound time.Duration) time.Duration {
+		jitterBound = bound
+		return 1500 * time.Millisecond
+	}
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.Start()
+		close(done)
+	}()
+	defer func() {
+		scheduler.Stop()
+		<-done
+	}()
+
+	// Four tickers and the initial delay
+	clk.BlockUntil(5)
+	assert.Equal(t, 2*time.Second, jitterBound)
+
+	clk.Advance(6499 * time.Millisecond)
+	assert.Zero(t, repo.polls.Load(), "No poll before the delay")
+	assert.True(t, scheduler.LastActivity().IsZero())
+
+	clk.Advance(time.Millisecond)
+	require.Eventually(t, func() bool { return repo.polls.Load() == 2 }, time.Second, time.Millisecond,
+		"High and normal priority polls once the delay passes")
+
+	// The tickers restarted with the delay, so an hour after Start is too early for them
+	clk.Advance(time.Hour - 6500*time.Millisecond)
+	assert.Equal(t, int64(2), repo.polls.Load())
+	clk.Advance(6500 * time.Millisecond)
+	require.Eventually(t, func() bool { return repo.polls.Load() == 4 }, time.Second, time.Millisecond,
+		"High and normal priority polls an hour after the first poll")
+}
+
+// TestSchedulerStopDuringInitialPollDelay tests that a scheduler stopped before its first poll never polls
+func TestSchedulerStopDuringInitialPollDelay(t *testing.T) {
+	repo := &pollCountingRepository{}
+	scheduler := NewScheduler(repo, &recordingPool{submitted: make(map[string]entity.TaskStatus)}, SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        time.Hour,
+		InitialPollDelay:       time.Hour,
+		Logger:                 zap.NewNop(),
+	})
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.Start()
+		close(done)
+	}()
+	scheduler.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Scheduler didn't stop during the initial poll delay")
+	}
+	assert.Zero(t, repo.polls.Load())
+}
+
+func TestRandomJitter(t *testing.T) {
+	assert.Zero(t, randomJitter(0))
+	for range 100 {
+		j := randomJitter(time.Second)
+		assert.True(t, j >= 0 && j < time.Second, "jitter %s out of range", j)
+	}
+}
+
+func TestSchedulerCleansUpTaskEvents(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	events := memory.NewTaskEventRepository()
+	for _, age := range []time.Duration{48 * time.Hour, 25 * time.Hour, time.Hour} {
+		require.NoError(t, events.Append(ctx, &entity.TaskEvent{
+			TaskID: "task", Type: "task_updated", ToStatus: entity.TaskStatusPending, CreatedAt: clk.Now().Add(-age),
+		}))
+	}
+
+	scheduler := NewScheduler(memory.NewTaskRepositoryWithClock(clk), &recordingPool{}, SchedulerConfig{
+		HighPriorityInterval:   time.Hour,
+		NormalPriorityInterval: time.Hour,
+		CleanupInterval:        time.Hour,
+		TaskEvents:             events,
+		EventRetention:         24 * time.Hour,
+		Logger:                 zap.NewNop(),
+		Clock:                  clk,
+	})
+	scheduler.cleanupExpiredTasks()
+
+	kept, err := events.ListByTask(ctx, "task", 10)
+	require.NoError(t, err)
+	require.Len(t, kept, 1, "events older than the retention are deleted")
+	assert.Equal(t, clk.Now().Add(-time.Hour).UTC(), kept[0].CreatedAt.UTC())
+}
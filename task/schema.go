@@ -0,0 +1,460 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSchemaProblems caps the validation errors reported for one payload
+const maxSchemaProblems = 10
+
+// PayloadSchema validates task payloads against a JSON Schema
+// The validation keywords of draft 2020-12 that apply to plain JSON documents are supported:
+// type, enum, const, properties, required, additionalProperties, items, minItems, maxItems,
+// uniqueItems, minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, multipleOf, allOf, anyOf, oneOf and not. References such as $ref are
+// not, and schemas using them are rejected rather than silently accepting any payload
+type PayloadSchema struct {
+	never bool // The false schema, which nothing matches
+
+	types    []string
+	enum     []any
+	constant *any
+
+	properties           map[string]*PayloadSchema
+	required             []string
+	additionalProperties *PayloadSchema
+
+	items    *PayloadSchema
+	minItems *int
+	maxItems *int
+	unique   bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+
+	allOf []*PayloadSchema
+	anyOf []*PayloadSchema
+	oneOf []*PayloadSchema
+	not   *PayloadSchema
+}
+
+// annotationKeywords don't affect validation and are accepted in any schema
+var annotationKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "format": true, "deprecated": true,
+	"readOnly": true, "writeOnly": true,
+}
+
+// CompilePayloadSchema parses a JSON Schema document
+func CompilePayloadSchema(raw []byte) (*PayloadSchema, error) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return compileSchema(doc, "#")
+}
+
+// LoadPayloadSchemas compiles every <task name>.json file in dir, keyed by task name
+func LoadPayloadSchemas(dir string) (map[string]*PayloadSchema, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	schemas := make(map[string]*PayloadSchema, len(files))
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := CompilePayloadSchema(raw)
+		if err != nil {
+			return nil, fmt.Errorf("payload schema %s: %w", file, err)
+		}
+		schemas[strings.TrimSuffix(filepath.Base(file), ".json")] = schema
+	}
+	return schemas, nil
+}
+
+func compileSchema(node any, at string) (*PayloadSchema, error) {
+	switch node := node.(type) {
+	case bool:
+		return &PayloadSchema{never: !node}, nil
+	case map[string]any:
+		s := &PayloadSchema{}
+		keywords := make([]string, 0, len(node))
+		for keyword := range node {
+			keywords = append(keywords, keyword)
+		}
+		sort.Strings(keywords)
+		for _, keyword := range keywords {
+			if err := s.compileKeyword(keyword, node[keyword], at+"/"+keyword); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%s: a schema must be an object or a boolean", at)
+	}
+}
+
+func (s *PayloadSchema) compileKeyword(keyword string, value any, at string) error {
+	var err error
+	switch keyword {
+	case "type":
+		switch value := value.(type) {
+		case string:
+			s.types = []string{value}
+		case []any:
+			for _, t := range value {
+				name, ok := t.(string)
+				if !ok {
+					return fmt.Errorf("%s: must be a string or an array of strings", at)
+				}
+				s.types = append(s.types, name)
+			}
+		default:
+			return fmt.Errorf("%s: must be a string or an array of strings", at)
+		}
+		for _, t := range s.types {
+			switch t {
+			case "null", "boolean", "object", "array", "number", "integer", "string":
+			default:
+				return fmt.Errorf("%s: unknown type %q", at, t)
+			}
+		}
+	case "enum":
+		values, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: must be an array", at)
+		}
+		s.enum = values
+	case "const":
+		s.constant = &value
+	case "properties":
+		properties, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: must be an object", at)
+		}
+		s.properties = make(map[string]*PayloadSchema, len(properties))
+		for name, property := range properties {
+			if s.properties[name], err = compileSchema(property, at+"/"+name); err != nil {
+				return err
+			}
+		}
+	case "required":
+		names, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: must be an array of strings", at)
+		}
+		for _, name := range names {
+			str, ok := name.(string)
+			if !ok {
+				return fmt.Errorf("%s: must be an array of strings", at)
+			}
+			s.required = append(s.required, str)
+		}
+	case "additionalProperties":
+		s.additionalProperties, err = compileSchema(value, at)
+	case "items":
+		s.items, err = compileSchema(value, at)
+	case "minItems":
+		s.minItems, err = compileCount(value, at)
+	case "maxItems":
+		s.maxItems, err = compileCount(value, at)
+	case "uniqueItems":
+		unique, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%s: must be a boolean", at)
+		}
+		s.unique = unique
+	case "minLength":
+		s.minLength, err = compileCount(value, at)
+	case "maxLength":
+		s.maxLength, err = compileCount(value, at)
+	case "pattern":
+		pattern, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", at)
+		}
+		if s.pattern, err = regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%s: %w", at, err)
+		}
+	case "minimum":
+		s.minimum, err = compileNumber(value, at)
+	case "maximum":
+		s.maximum, err = compileNumber(value, at)
+	case "exclusiveMinimum":
+		s.exclusiveMinimum, err = compileNumber(value, at)
+	case "exclusiveMaximum":
+		s.exclusiveMaximum, err = compileNumber(value, at)
+	case "multipleOf":
+		if s.multipleOf, err = compileNumber(value, at); err == nil && *s.multipleOf <= 0 {
+			err = fmt.Errorf("%s: must be positive", at)
+		}
+	case "allOf":
+		s.allOf, err = compileSchemas(value, at)
+	case "anyOf":
+		s.anyOf, err = compileSchemas(value, at)
+	case "oneOf":
+		s.oneOf, err = compileSchemas(value, at)
+	case "not":
+		s.not, err = compileSchema(value, at)
+	default:
+		if !annotationKeywords[keyword] {
+			return fmt.Errorf("%s: unsupported keyword", at)
+		}
+	}
+	return err
+}
+
+func compileSchemas(value any, at string) ([]*PayloadSchema, error) {
+	nodes, ok := value.([]any)
+	if !ok || len(nodes) == 0 {
+		return nil, fmt.Errorf("%s: must be a non-empty array of schemas", at)
+	}
+	schemas := make([]*PayloadSchema, len(nodes))
+	for i, node := range nodes {
+		var err error
+		if schemas[i], err = compileSchema(node, fmt.Sprintf("%s/%d", at, i)); err != nil {
+			return nil, err
+		}
+	}
+	return schemas, nil
+}
+
+func compileNumber(value any, at string) (*float64, error) {
+	number, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a number", at)
+	}
+	return &number, nil
+}
+
+func compileCount(value any, at string) (*int, error) {
+	number, ok := value.(float64)
+	if !ok || number < 0 || number != math.Trunc(number) {
+		return nil, fmt.Errorf("%s: must be a non-negative integer", at)
+	}
+	count := int(number)
+	return &count, nil
+}
+
+// Validate checks a JSON payload against the schema, returning what doesn't match
+// An empty payload is validated as null; at most a handful of problems are reported
+func (s *PayloadSchema) Validate(payload []byte) []string {
+	var value any
+	if len(bytes.TrimSpace(payload)) > 0 {
+		if err := json.Unmarshal(payload, &value); err != nil {
+			return []string{"payload is not valid JSON"}
+		}
+	}
+	problems := s.validate(value, "$")
+	if len(problems) > maxSchemaProblems {
+		problems = append(problems[:maxSchemaProblems], fmt.Sprintf("and %d more", len(problems)-maxSchemaProblems))
+	}
+	return problems
+}
+
+func (s *PayloadSchema) validate(value any, at string) []string {
+	if s.never {
+		return []string{fmt.Sprintf("%s: no value is allowed", at)}
+	}
+
+	var problems []string
+	if len(s.types) > 0 && !s.matchesType(value) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", at, strings.Join(s.types, " or "), jsonType(value))}
+	}
+	if s.enum != nil && !containsValue(s.enum, value) {
+		problems = append(problems, fmt.Sprintf("%s: must be one of %s", at, compactJSON(s.enum)))
+	}
+	if s.constant != nil && !reflect.DeepEqual(*s.constant, value) {
+		problems = append(problems, fmt.Sprintf("%s: must be %s", at, compactJSON(*s.constant)))
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		problems = append(problems, s.validateObject(value, at)...)
+	case []any:
+		problems = append(problems, s.validateArray(value, at)...)
+	case string:
+		problems = append(problems, s.validateString(value, at)...)
+	case float64:
+		problems = append(problems, s.validateNumber(value, at)...)
+	}
+
+	for _, sub := range s.allOf {
+		problems = append(problems, sub.validate(value, at)...)
+	}
+	if s.anyOf != nil && matching(s.anyOf, value) == 0 {
+		problems = append(problems, fmt.Sprintf("%s: must match at least one of the anyOf schemas", at))
+	}
+	if s.oneOf != nil {
+		if n := matching(s.oneOf, value); n != 1 {
+			problems = append(problems, fmt.Sprintf("%s: must match exactly one of the oneOf schemas, matched %d", at, n))
+		}
+	}
+	if s.not != nil && len(s.not.validate(value, at)) == 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not match the not schema", at))
+	}
+	return problems
+}
+
+func (s *PayloadSchema) validateObject(object map[string]any, at string) []string {
+	var problems []string
+	for _, name := range s.required {
+		if _, ok := object[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: missing required property %q", at, name))
+		}
+	}
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if property, ok := s.properties[name]; ok {
+			problems = append(problems, property.validate(object[name], at+"."+name)...)
+		} else if s.additionalProperties != nil {
+			if s.additionalProperties.never {
+				problems = append(problems, fmt.Sprintf("%s: unexpected property %q", at, name))
+				continue
+			}
+			problems = append(problems, s.additionalProperties.validate(object[name], at+"."+name)...)
+		}
+	}
+	return problems
+}
+
+func (s *PayloadSchema) validateArray(array []any, at string) []string {
+	var problems []string
+	if s.minItems != nil && len(array) < *s.minItems {
+		problems = append(problems, fmt.Sprintf("%s: must have at least %d items", at, *s.minItems))
+	}
+	if s.maxItems != nil && len(array) > *s.maxItems {
+		problems = append(problems, fmt.Sprintf("%s: must have at most %d items", at, *s.maxItems))
+	}
+	if s.unique {
+		for i := range array {
+			if containsValue(array[:i], array[i]) {
+				problems = append(problems, fmt.Sprintf("%s: items must be unique, [%d] is repeated", at, i))
+				break
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range array {
+			problems = append(problems, s.items.validate(item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	}
+	return problems
+}
+
+func (s *PayloadSchema) validateString(str string, at string) []string {
+	var problems []string
+	length := utf8.RuneCountInString(str)
+	if s.minLength != nil && length < *s.minLength {
+		problems = append(problems, fmt.Sprintf("%s: must be at least %d characters", at, *s.minLength))
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		problems = append(problems, fmt.Sprintf("%s: must be at most %d characters", at, *s.maxLength))
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		problems = append(problems, fmt.Sprintf("%s: must match %s", at, s.pattern))
+	}
+	return problems
+}
+
+func (s *PayloadSchema) validateNumber(number float64, at string) []string {
+	var problems []string
+	if s.minimum != nil && number < *s.minimum {
+		problems = append(problems, fmt.Sprintf("%s: must be at least %v", at, *s.minimum))
+	}
+	if s.maximum != nil && number > *s.maximum {
+		problems = append(problems, fmt.Sprintf("%s: must be at most %v", at, *s.maximum))
+	}
+	if s.exclusiveMinimum != nil && number <= *s.exclusiveMinimum {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than %v", at, *s.exclusiveMinimum))
+	}
+	if s.exclusiveMaximum != nil && number >= *s.exclusiveMaximum {
+		problems = append(problems, fmt.Sprintf("%s: must be less than %v", at, *s.exclusiveMaximum))
+	}
+	if s.multipleOf != nil {
+		if q := number / *s.multipleOf; q != math.Trunc(q) {
+			problems = append(problems, fmt.Sprintf("%s: must be a multiple of %v", at, *s.multipleOf))
+		}
+	}
+	return problems
+}
+
+func (s *PayloadSchema) matchesType(value any) bool {
+	actual := jsonType(value)
+	for _, t := range s.types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a decoded value; numbers without a fraction are integers
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func matching(schemas []*PayloadSchema, value any) int {
+	n := 0
+	for _, schema := range schemas {
+		if len(schema.validate(value, "$")) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compactJSON(value any) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
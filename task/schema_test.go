@@ -0,0 +1,126 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+)
+
+const sendEmailSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["to", "template"],
+	"additionalProperties": false,
+	"properties": {
+		"to": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+		"cc": {"type": "array", "items": {"type": "string"}, "maxItems": 2, "uniqueItems": true},
+		"template": {"enum": ["welcome", "reset"]},
+		"priority": {"type": "integer", "minimum": 1, "maximum": 5},
+		"locale": {"type": ["string", "null"], "minLength": 2, "maxLength": 5}
+	}
+}`
+
+func TestPayloadSchemaValidate(t *testing.T) {
+	schema, err := CompilePayloadSchema([]byte(sendEmailSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		payload  string
+		problems []string
+	}{
+		{"Valid", `{"to":"a@example.com","template":"welcome","priority":3,"locale":null}`, nil},
+		{"Missing required", `{"to":"a@example.com"}`, []string{`$: missing required property "template"`}},
+		{"Wrong type", `{"to":42,"template":"reset"}`, []string{"$.to: expected string, got integer"}},
+		{"Not an integer", `{"to":"a@example.com","template":"reset","priority":2.5}`, []string{"$.priority: expected integer, got number"}},
+		{"Out of range", `{"to":"a@example.com","template":"reset","priority":9}`, []string{"$.priority: must be at most 5"}},
+		{"Not in enum", `{"to":"a@example.com","template":"bye"}`, []string{`$.template: must be one of ["welcome","reset"]`}},
+		{"Pattern", `{"to":"nobody","template":"reset"}`, []string{"$.to: must match ^[^@]+@[^@]+$"}},
+		{"String length", `{"to":"a@example.com","template":"reset","locale":"x"}`, []string{"$.locale: must be at least 2 characters"}},
+		{"Array items", `{"to":"a@example.com","template":"reset","cc":["b",1,"b"]}`, []string{
+			"$.cc: must have at most 2 items",
+			"$.cc: items must be unique, [2] is repeated",
+			"$.cc[1]: expected string, got integer",
+		}},
+		{"Unexpected property", `{"to":"a@example.com","template":"reset","bcc":"c"}`, []string{`$: unexpected property "bcc"`}},
+		{"Not an object", `["a@example.com"]`, []string{"$: expected object, got array"}},
+		{"Empty payload", ``, []string{"$: expected object, got null"}},
+		{"Invalid JSON", `{"to":`, []string{"payload is not valid JSON"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.problems, schema.Validate([]byte(tt.payload)))
+		})
+	}
+}
+
+func TestPayloadSchemaCombinators(t *testing.T) {
+	schema, err := CompilePayloadSchema([]byte(`{
+		"oneOf": [{"type": "integer", "multipleOf": 2}, {"type": "integer", "multipleOf": 3}],
+		"not": {"const": 0}
+	}`))
+	require.NoError(t, err)
+
+	assert.Empty(t, schema.Validate([]byte(`4`)))
+	assert.Empty(t, schema.Validate([]byte(`9`)))
+	assert.Equal(t, []string{"$: must match exactly one of the oneOf schemas, matched 2"}, schema.Validate([]byte(`6`)))
+	assert.Equal(t, []string{"$: must match exactly one of the oneOf schemas, matched 0"}, schema.Validate([]byte(`7`)))
+	assert.Contains(t, schema.Validate([]byte(`0`)), "$: must not match the not schema")
+}
+
+func TestCompilePayloadSchemaErrors(t *testing.T) {
+	for schema, want := range map[string]string{
+		`{"type": "object"`:           "invalid JSON",
+		`[]`:                          "#: a schema must be an object or a boolean",
+		`{"$ref": "#/$defs/address"}`: "#/$ref: unsupported keyword",
+		`{"type": "text"}`:            `#/type: unknown type "text"`,
+		`{"properties": {"n": {"minimum": "1"}}}`: "#/properties/n/minimum: must be a number",
+		`{"pattern": "("}`:                        "#/pattern: error parsing regexp",
+		`{"items": {"maxItems": -1}}`:             "#/items/maxItems: must be a non-negative integer",
+		`{"anyOf": []}`:                           "#/anyOf: must be a non-empty array of schemas",
+	} {
+		_, err := CompilePayloadSchema([]byte(schema))
+		if assert.Error(t, err, schema) {
+			assert.Contains(t, err.Error(), want)
+		}
+	}
+}
+
+func TestLoadPayloadSchemas(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "send-email.json"), []byte(sendEmailSchema), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a schema"), 0o600))
+
+	schemas, err := LoadPayloadSchemas(dir)
+	require.NoError(t, err)
+	assert.Len(t, schemas, 1)
+	assert.Contains(t, schemas, "send-email")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"$ref": "x"}`), 0o600))
+	_, err = LoadPayloadSchemas(dir)
+	assert.ErrorContains(t, err, "broken.json")
+}
+
+func TestCreateTaskValidatesPayloadSchema(t *testing.T) {
+	schema, err := CompilePayloadSchema([]byte(sendEmailSchema))
+	require.NoError(t, err)
+	svc := NewService(&fakeRepository{}, WithPayloadSchemas(map[string]*PayloadSchema{"send-email": schema}))
+	ctx := context.Background()
+
+	require.NoError(t, svc.CreateTask(ctx, &entity.Task{Name: "send-email", Payload: []byte(`{"to":"a@example.com","template":"welcome"}`)}))
+
+	err = svc.CreateTask(ctx, &entity.Task{Name: "send-email", Payload: []byte(`{"to":"a@example.com"}`)})
+	assert.True(t, errors.Is(err, domain.ErrBadParamInput))
+	assert.ErrorContains(t, err, `payload does not match the send-email schema: $: missing required property "template"`)
+
+	// Unknown names pass through unchanged
+	require.NoError(t, svc.CreateTask(ctx, &entity.Task{Name: "send-sms", Payload: []byte(`"anything"`)}))
+}
@@ -2,48 +2,337 @@ package task
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/usual2970/later/callback"
 	"github.com/usual2970/later/domain"
 	"github.com/usual2970/later/domain/entity"
 	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/infrastructure/logger"
+
+	"go.uber.org/zap"
 )
 
+// DefaultStatsWindow is the activity window used when none is requested
+const DefaultStatsWindow = "24h"
+
+// StatsWindows lists the supported activity windows
+var StatsWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// MaxTimeSeriesBuckets caps the number of buckets a time series query may return
+const MaxTimeSeriesBuckets = 500
+
+// MaxStatsGroups caps the number of groups a grouped stats query returns
+const MaxStatsGroups = 100
+
 // Stats represents statistics
 type Stats struct {
 	Total               int64                       `json:"total"`
 	ByStatus            map[entity.TaskStatus]int64 `json:"by_status"`
 	Last24h             Last24hStats                `json:"last_24h"`
-	CallbackSuccessRate float64                     `json:"callback_success_rate"`
+	Window              string                      `json:"window"`
+	Recent              WindowStats                 `json:"recent"`                // Activity within Window
+	CallbackSuccessRate float64                     `json:"callback_success_rate"` // Completed share of the tasks finished within Window
+}
+
+// WindowStats represents task activity within a time window
+type WindowStats struct {
+	Submitted              int64   `json:"submitted"`
+	Completed              int64   `json:"completed"`
+	Failed                 int64   `json:"failed"` // Awaiting retry
+	DeadLettered           int64   `json:"dead_lettered"`
+	Expired                int64   `json:"expired"`               // Picked up past their expiry, never delivered
+	AvgCallbackAttempts    float64 `json:"avg_callback_attempts"` // Of the tasks completed or dead-lettered
+	P50CompletionLatencyMs float64 `json:"p50_completion_latency_ms"`
+	P95CompletionLatencyMs float64 `json:"p95_completion_latency_ms"`
+	P50DispatchLatencyMs   float64 `json:"p50_dispatch_latency_ms"` // Of the tasks started, past their due time
+	P95DispatchLatencyMs   float64 `json:"p95_dispatch_latency_ms"`
+	P50CallbackDurationMs  float64 `json:"p50_callback_duration_ms"`
+	P95CallbackDurationMs  float64 `json:"p95_callback_duration_ms"`
 }
 
 // Last24hStats represents statistics for the last 24 hours
-type Last24hStats struct {
-	Submitted int64 `json:"submitted"`
-	Completed int64 `json:"completed"`
-	Failed    int64 `json:"failed"`
+type Last24hStats = WindowStats
+
+// TimeSeries represents task activity bucketed over a window
+type TimeSeries struct {
+	Window  string            `json:"window"`
+	Bucket  string            `json:"bucket"`
+	Buckets []TimeSeriesPoint `json:"buckets"`
+}
+
+// TimeSeriesPoint represents task activity within one bucket
+type TimeSeriesPoint struct {
+	Start                time.Time `json:"start"`
+	Created              int64     `json:"created"`
+	Completed            int64     `json:"completed"`
+	Failed               int64     `json:"failed"`
+	DeadLettered         int64     `json:"dead_lettered"`
+	AvgCallbackLatencyMs float64   `json:"avg_callback_latency_ms"`
+}
+
+// GroupedStats represents task counts per task name or tag over a window
+type GroupedStats struct {
+	Window    string       `json:"window"`
+	GroupBy   string       `json:"group_by"`  // "name" or "tag"
+	Groups    []GroupStats `json:"groups"`    // Largest first
+	Truncated bool         `json:"truncated"` // More than MaxStatsGroups groups had tasks
+}
+
+// GroupStats represents the tasks of one name or tag created within the window
+type GroupStats struct {
+	Key                 string                      `json:"key"`
+	Total               int64                       `json:"total"`
+	ByStatus            map[entity.TaskStatus]int64 `json:"by_status"`
+	AvgCallbackAttempts float64                     `json:"avg_callback_attempts"` // Of the tasks completed or dead-lettered
+	SuccessRate         float64                     `json:"success_rate"`          // Completed share of the tasks finished
 }
 
 // Service handles business logic for tasks
 type Service struct {
-	repo repository.TaskRepository
+	repo      repository.TaskRepository
+	urlPolicy *callback.URLPolicy // nil accepts any callback URL
+
+	maxPayloadSize     int
+	defaultTTL         time.Duration                  // Zero leaves tasks without an expiry unless they set one
+	compressionMinSize int                            // Zero stores payloads uncompressed
+	cipher             *PayloadCipher                 // nil stores payloads in plaintext
+	backlog            *backlogLimit                  // nil accepts tasks however many are pending
+	payloadSchemas     map[string]*PayloadSchema      // By task name; other tasks' payloads aren't validated
+	idFormat           string                         // Format of client-supplied IDs; empty is IDFormatUUID
+	events             repository.TaskEventRepository // nil leaves task timelines empty
+	retryPolicy        entity.RetryPolicy
+	hostPauses         *hostPauses // nil pauses no hosts
+	logger             *zap.Logger
+}
+
+// ServiceOption configures optional Service behaviour
+type ServiceOption func(*Service)
+
+// WithCallbackURLPolicy rejects tasks whose callback URL violates the policy
+func WithCallbackURLPolicy(policy *callback.URLPolicy) ServiceOption {
+	return func(s *Service) {
+		s.urlPolicy = policy
+	}
+}
+
+// WithMaxPayloadSize rejects tasks whose payload exceeds size bytes (default entity.MaxPayloadSize)
+func WithMaxPayloadSize(size int) ServiceOption {
+	return func(s *Service) {
+		s.maxPayloadSize = size
+	}
+}
+
+// WithDefaultTaskTTL expires tasks that haven't run ttl after their scheduled time,
+// unless they set their own expiry
+func WithDefaultTaskTTL(ttl time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.defaultTTL = ttl
+	}
+}
+
+// WithPayloadSchemas rejects tasks whose payload doesn't match the schema registered for
+// their name; tasks with other names are accepted unchecked
+func WithPayloadSchemas(schemas map[string]*PayloadSchema) ServiceOption {
+	return func(s *Service) {
+		s.payloadSchemas = schemas
+	}
+}
+
+// WithPayloadCompression gzip-compresses payloads of at least minSize bytes at rest
+// Payloads are decompressed when read, so callbacks and API responses are unaffected
+func WithPayloadCompression(minSize int) ServiceOption {
+	return func(s *Service) {
+		s.compressionMinSize = minSize
+	}
+}
+
+// WithPayloadCipher encrypts new payloads at rest and decrypts stored ones when read
+// Encrypted payloads are not compressed, since ciphertext doesn't compress
+func WithPayloadCipher(c *PayloadCipher) ServiceOption {
+	return func(s *Service) {
+		s.cipher = c
+	}
+}
+
+// WithLogger sets the logger for task lifecycle events (default: the global logger)
+func WithLogger(l *zap.Logger) ServiceOption {
+	return func(s *Service) {
+		s.logger = l
+	}
 }
 
 // NewService creates a new task service
-func NewService(repo repository.TaskRepository) *Service {
-	return &Service{repo: repo}
+func NewService(repo repository.TaskRepository, opts ...ServiceOption) *Service {
+	s := &Service{
+		repo:           repo,
+		maxPayloadSize: entity.MaxPayloadSize,
+		retryPolicy:    entity.DefaultRetryPolicy,
+		logger:         logger.Named("task"),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.backlog != nil {
+		s.backlog.repo = repo
+		s.backlog.logger = s.logger
+	}
+	if s.hostPauses != nil {
+		s.hostPauses.logger = s.logger
+	}
+	return s
 }
 
 // CreateTask creates a new task and saves it to the database
+// Tasks created with a tenant-scoped context are owned by that tenant, and tasks created while
+// serving an API request record its ID unless they already carry one
+// Zero CallbackTimeoutSecs and RetryBackoffSeconds are replaced with their defaults, and tasks
+// without an expiry get the default TTL if one is configured
+// Payloads of tasks whose name has a registered schema must match it
+// Returns domain.ErrQueueFull while the backlog limit is reached
 func (s *Service) CreateTask(ctx context.Context, task *entity.Task) error {
-	return s.repo.Create(ctx, task)
+	if err := s.prepareCreate(ctx, task); err != nil {
+		return err
+	}
+	restore, err := s.sealPayload(task)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	if err := s.repo.Create(ctx, task); err != nil {
+		return err
+	}
+	s.releaseAfterCreate(ctx, task)
+	return nil
+}
+
+// ValidateTask runs CreateTask's checks and fills in its defaults without storing the task, so
+// callers can see the task a create would make. A taken ID returns domain.ErrConflict, which
+// CreateTask only reports once the insert fails
+func (s *Service) ValidateTask(ctx context.Context, task *entity.Task) error {
+	if err := s.prepareCreate(ctx, task); err != nil {
+		return err
+	}
+	existing, err := s.repo.ExistingIDs(ctx, []string{task.ID})
+	if err != nil {
+		return fmt.Errorf("failed to check task ID: %w", err)
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("%w: task %s already exists", domain.ErrConflict, task.ID)
+	}
+	return nil
+}
+
+// prepareCreate validates a new task and fills in what CreateTask documents, short of storing it
+func (s *Service) prepareCreate(ctx context.Context, task *entity.Task) error {
+	if s.backlog != nil {
+		if err := s.backlog.admit(task); err != nil {
+			return err
+		}
+	}
+	if len(task.Payload) > s.maxPayloadSize {
+		return fmt.Errorf("%w: payload size %d exceeds the %d byte limit",
+			domain.ErrBadParamInput, len(task.Payload), s.maxPayloadSize)
+	}
+	if schema, ok := s.payloadSchemas[task.Name]; ok {
+		if problems := schema.Validate(task.Payload); len(problems) > 0 {
+			return fmt.Errorf("%w: payload does not match the %s schema: %s",
+				domain.ErrBadParamInput, task.Name, strings.Join(problems, "; "))
+		}
+	}
+	if task.CallbackTimeoutSecs == 0 {
+		task.CallbackTimeoutSecs = entity.DefaultCallbackTimeoutSecs
+	}
+	if !task.ValidCallbackTimeout() {
+		return fmt.Errorf("%w: callback timeout must be between %d and %d seconds",
+			domain.ErrBadParamInput, entity.MinCallbackTimeoutSecs, entity.MaxCallbackTimeoutSecs)
+	}
+	if task.RetryBackoffSeconds == 0 {
+		task.RetryBackoffSeconds = entity.DefaultRetryBackoffSecs
+	}
+	if !task.ValidRetryBackoff() {
+		return fmt.Errorf("%w: retry backoff must be between %d and %d seconds",
+			domain.ErrBadParamInput, entity.MinRetryBackoffSecs, entity.MaxRetryBackoffSecs)
+	}
+	if task.ExpiresAt == nil && s.defaultTTL > 0 {
+		expiresAt := task.ScheduledAt.Add(s.defaultTTL)
+		task.ExpiresAt = &expiresAt
+	}
+	if task.ExpiresAt != nil && !task.ExpiresAt.After(task.ScheduledAt) {
+		return fmt.Errorf("%w: expires_at must be after the scheduled time", domain.ErrBadParamInput)
+	}
+	if !task.ValidRetryableStatusCodes() {
+		return fmt.Errorf("%w: retryable status codes must be between 100 and 599", domain.ErrBadParamInput)
+	}
+	if task.ConcurrencyKey != nil && (*task.ConcurrencyKey == "" || len(*task.ConcurrencyKey) > entity.MaxConcurrencyKeyLength) {
+		return fmt.Errorf("%w: concurrency key must be 1 to %d characters",
+			domain.ErrBadParamInput, entity.MaxConcurrencyKeyLength)
+	}
+	if err := callback.ValidateBodyTemplate(task); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrBadParamInput, err)
+	}
+	if task.CallbackOAuth2 != nil {
+		if err := task.CallbackOAuth2.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrBadParamInput, err)
+		}
+	}
+	if s.urlPolicy != nil {
+		if err := s.urlPolicy.Check(ctx, task.CallbackURL); err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrBadParamInput, err)
+		}
+		if task.CallbackOAuth2 != nil {
+			if err := s.urlPolicy.Check(ctx, task.CallbackOAuth2.TokenURL); err != nil {
+				return fmt.Errorf("%w: oauth2 token_url: %v", domain.ErrBadParamInput, err)
+			}
+		}
+	}
+
+	if err := s.prepareDependency(ctx, task); err != nil {
+		return err
+	}
+
+	if tenantID, ok := domain.TenantFromContext(ctx); ok {
+		task.TenantID = tenantID
+	}
+	if requestID, ok := domain.RequestIDFromContext(ctx); ok && task.RequestID == nil {
+		task.RequestID = &requestID
+	}
+	return nil
+}
+
+// sealPayload encrypts or compresses the payload for storage as configured
+// Only the stored copy is encrypted; calling restore gives the caller back the plaintext
+func (s *Service) sealPayload(task *entity.Task) (restore func(), err error) {
+	if s.cipher != nil {
+		plaintext := task.Payload
+		if err := s.cipher.Encrypt(task); err != nil {
+			return nil, err
+		}
+		return func() {
+			task.Payload = plaintext
+			task.PayloadEncrypted = false
+		}, nil
+	}
+	if s.compressionMinSize > 0 && len(task.Payload) >= s.compressionMinSize {
+		task.PayloadEncoding = entity.PayloadEncodingGzip
+	}
+	return func() {}, nil
 }
 
 // GetTask retrieves a task by ID
 func (s *Service) GetTask(ctx context.Context, id string) (*entity.Task, error) {
 	task, err := s.repo.FindByID(ctx, id)
 	if err != nil {
-		return nil, domain.ErrNotFound
+		return nil, err
+	}
+	if err := decryptPayload(s.cipher, task); err != nil {
+		return nil, err
 	}
 	return task, nil
 }
@@ -54,16 +343,29 @@ func (s *Service) DeleteTask(ctx context.Context, id string, deletedBy string) e
 	// Get the task first
 	task, err := s.repo.FindByID(ctx, id)
 	if err != nil {
-		return domain.ErrNotFound
+		return err
 	}
 
 	// Validate task can be deleted
 	if !task.CanBeDeleted() {
-		return errors.New("task cannot be deleted: invalid status or already deleted")
+		return fmt.Errorf("%w: status %s", domain.ErrTaskCannotDelete, task.Status)
 	}
 
 	// Perform soft delete
-	return s.repo.SoftDelete(ctx, id, deletedBy)
+	if err := s.repo.SoftDelete(ctx, id, deletedBy); err != nil {
+		return err
+	}
+
+	// A cancelled task never completes, so tasks waiting on it follow their failure policy
+	now := time.Now()
+	task.DeletedAt = &now
+	if err := s.ResolveDependents(ctx, task); err != nil {
+		s.logger.Error("Failed to release tasks depending on cancelled task",
+			zap.String("task_id", id),
+			logger.RequestID(task.RequestID),
+			zap.Error(err))
+	}
+	return nil
 }
 
 // UpdateTask updates a task
@@ -71,49 +373,216 @@ func (s *Service) UpdateTask(ctx context.Context, task *entity.Task) error {
 	return s.repo.Update(ctx, task)
 }
 
+// UpdateTaskIfStatus updates a task only if its stored status is one of from, reporting
+// whether it did; workers use it to claim a task exactly once
+func (s *Service) UpdateTaskIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	return s.repo.UpdateIfStatus(ctx, task, from...)
+}
+
 // List retrieves tasks with filters and pagination
 func (s *Service) List(ctx context.Context, filter *repository.TaskFilter) ([]*entity.Task, int64, error) {
-	return s.repo.List(ctx, *filter)
+	tasks, total, err := s.repo.List(ctx, *filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, task := range tasks {
+		if err := decryptPayload(s.cipher, task); err != nil {
+			return nil, 0, fmt.Errorf("task %s: %w", task.ID, err)
+		}
+	}
+	return tasks, total, nil
+}
+
+// ListStream streams the tasks matching the filter to fn; see repository.TaskRepository.ListStream
+func (s *Service) ListStream(ctx context.Context, filter *repository.TaskFilter, fn func(*entity.Task) error) error {
+	return s.repo.ListStream(ctx, *filter, func(task *entity.Task) error {
+		if err := decryptPayload(s.cipher, task); err != nil {
+			return fmt.Errorf("task %s: %w", task.ID, err)
+		}
+		return fn(task)
+	})
 }
 
-// GetStats retrieves task statistics
+// GetStats retrieves task statistics with activity for the default window
 func (s *Service) GetStats(ctx context.Context) (*Stats, error) {
+	return s.GetStatsForWindow(ctx, DefaultStatsWindow)
+}
+
+// GetStatsForWindow retrieves task statistics with activity for the named window (see StatsWindows)
+func (s *Service) GetStatsForWindow(ctx context.Context, window string) (*Stats, error) {
+	duration, ok := StatsWindows[window]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported stats window %q", domain.ErrBadParamInput, window)
+	}
+
 	byStatus, err := s.repo.CountByStatus(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// Calculate total
-	total := byStatus[entity.TaskStatusPending] + byStatus[entity.TaskStatusProcessing] +
+	total := byStatus[entity.TaskStatusWaiting] + byStatus[entity.TaskStatusPending] + byStatus[entity.TaskStatusProcessing] +
 		byStatus[entity.TaskStatusCompleted] + byStatus[entity.TaskStatusFailed] +
-		byStatus[entity.TaskStatusDeadLettered]
+		byStatus[entity.TaskStatusDeadLettered] + byStatus[entity.TaskStatusExpired]
 
-	// Calculate last 24h stats
-	last24h := Last24hStats{
-		Submitted: 0, // TODO: Query from database
-		Completed: byStatus[entity.TaskStatusCompleted],
-		Failed:    byStatus[entity.TaskStatusFailed],
+	now := time.Now()
+	recent, err := s.windowStats(ctx, now.Add(-duration))
+	if err != nil {
+		return nil, err
+	}
+
+	last24h := recent
+	if duration != StatsWindows["24h"] {
+		last24h, err = s.windowStats(ctx, now.Add(-24*time.Hour))
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Calculate callback success rate
+	// Failed tasks may still succeed on retry, so only finished tasks count towards the rate
 	successRate := 0.0
-	totalCompletedAndFailed := byStatus[entity.TaskStatusCompleted] + byStatus[entity.TaskStatusFailed]
-	if totalCompletedAndFailed > 0 {
-		successRate = float64(byStatus[entity.TaskStatusCompleted]) / float64(totalCompletedAndFailed)
+	if finished := recent.Completed + recent.DeadLettered; finished > 0 {
+		successRate = float64(recent.Completed) / float64(finished)
 	}
 
 	return &Stats{
 		Total:               total,
 		ByStatus:            byStatus,
 		Last24h:             last24h,
+		Window:              window,
+		Recent:              recent,
 		CallbackSuccessRate: successRate,
 	}, nil
 }
 
+// windowStats summarizes task activity since the given time
+func (s *Service) windowStats(ctx context.Context, since time.Time) (WindowStats, error) {
+	summary, err := s.repo.StatsSummary(ctx, since)
+	if err != nil {
+		return WindowStats{}, err
+	}
+	return WindowStats{
+		Submitted:              summary.Created,
+		Completed:              summary.Completed,
+		Failed:                 summary.Failed,
+		DeadLettered:           summary.DeadLettered,
+		Expired:                summary.Expired,
+		AvgCallbackAttempts:    summary.AvgCallbackAttempts,
+		P50CompletionLatencyMs: summary.P50CompletionLatencyMs,
+		P95CompletionLatencyMs: summary.P95CompletionLatencyMs,
+		P50DispatchLatencyMs:   summary.P50DispatchLatencyMs,
+		P95DispatchLatencyMs:   summary.P95DispatchLatencyMs,
+		P50CallbackDurationMs:  summary.P50CallbackDurationMs,
+		P95CallbackDurationMs:  summary.P95CallbackDurationMs,
+	}, nil
+}
+
+// GetTimeSeries retrieves task activity over the named window (see StatsWindows) in buckets
+// of the given size, e.g. "5m" or "1h". Empty buckets are included so series are continuous.
+func (s *Service) GetTimeSeries(ctx context.Context, window string, bucket string) (*TimeSeries, error) {
+	duration, ok := StatsWindows[window]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported stats window %q", domain.ErrBadParamInput, window)
+	}
+
+	size, err := time.ParseDuration(bucket)
+	if err != nil || size < time.Minute || size%time.Second != 0 {
+		return nil, fmt.Errorf("%w: bucket must be a whole-second duration of at least 1m", domain.ErrBadParamInput)
+	}
+	if duration/size > MaxTimeSeriesBuckets {
+		return nil, fmt.Errorf("%w: window %s in %s buckets exceeds %d buckets",
+			domain.ErrBadParamInput, window, bucket, MaxTimeSeriesBuckets)
+	}
+
+	// Align to the epoch like the repository so bucket starts line up
+	now := time.Now().UTC()
+	sizeSeconds := int64(size / time.Second)
+	sinceSeconds := now.Add(-duration).Unix()
+	since := time.Unix(sinceSeconds-sinceSeconds%sizeSeconds, 0).UTC()
+
+	counts, err := s.repo.CountByTimeBucket(ctx, since, size)
+	if err != nil {
+		return nil, err
+	}
+
+	byStart := make(map[int64]*repository.TimeBucketCounts, len(counts))
+	for _, c := range counts {
+		byStart[c.Start.Unix()] = c
+	}
+
+	points := make([]TimeSeriesPoint, 0, int(duration/size)+1)
+	for start := since; !start.After(now); start = start.Add(size) {
+		point := TimeSeriesPoint{Start: start}
+		if c, ok := byStart[start.Unix()]; ok {
+			point.Created = c.Created
+			point.Completed = c.Completed
+			point.Failed = c.Failed
+			point.DeadLettered = c.DeadLettered
+			point.AvgCallbackLatencyMs = c.AvgCallbackLatencyMs
+		}
+		points = append(points, point)
+	}
+
+	return &TimeSeries{
+		Window:  window,
+		Bucket:  bucket,
+		Buckets: points,
+	}, nil
+}
+
+// GetStatsByName retrieves task counts per task name for the tasks created within the named
+// window (see StatsWindows)
+func (s *Service) GetStatsByName(ctx context.Context, window string) (*GroupedStats, error) {
+	return s.groupedStats(ctx, window, repository.StatsGroupName)
+}
+
+// GetStatsByTag retrieves task counts per tag for the tasks created within the named window
+// (see StatsWindows); a task counts towards each of its tags
+func (s *Service) GetStatsByTag(ctx context.Context, window string) (*GroupedStats, error) {
+	return s.groupedStats(ctx, window, repository.StatsGroupTag)
+}
+
+func (s *Service) groupedStats(ctx context.Context, window string, group repository.StatsGroup) (*GroupedStats, error) {
+	duration, ok := StatsWindows[window]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported stats window %q", domain.ErrBadParamInput, window)
+	}
+
+	// One group past the cap tells whether the result was truncated
+	counts, err := s.repo.StatsByGroup(ctx, group, time.Now().Add(-duration), MaxStatsGroups+1)
+	if err != nil {
+		return nil, err
+	}
+	truncated := len(counts) > MaxStatsGroups
+	if truncated {
+		counts = counts[:MaxStatsGroups]
+	}
+
+	groups := make([]GroupStats, len(counts))
+	for i, c := range counts {
+		groups[i] = GroupStats{
+			Key:                 c.Key,
+			Total:               c.Total,
+			ByStatus:            c.ByStatus,
+			AvgCallbackAttempts: c.AvgCallbackAttempts,
+		}
+		if finished := c.ByStatus[entity.TaskStatusCompleted] + c.ByStatus[entity.TaskStatusDeadLettered]; finished > 0 {
+			groups[i].SuccessRate = float64(c.ByStatus[entity.TaskStatusCompleted]) / float64(finished)
+		}
+	}
+
+	return &GroupedStats{
+		Window:    window,
+		GroupBy:   string(group),
+		Groups:    groups,
+		Truncated: truncated,
+	}, nil
+}
+
 // ProcessTask executes a task and delivers callback
 func (s *Service) ProcessTask(ctx context.Context, task *entity.Task) error {
 	// TODO: Implement callback delivery
 	// For now, just mark as completed
-	task.MarkAsCompleted()
+	task.MarkAsCompleted(time.Now())
 	return s.repo.Update(ctx, task)
 }
@@ -0,0 +1,574 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/callback"
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+	"github.com/usual2970/later/repository/memory"
+)
+
+// fakeRepository is an in-memory TaskRepository for service tests
+type fakeRepository struct {
+	repository.TaskRepository
+	tasks   []*entity.Task
+	batches int // CreateBatch calls
+}
+
+// Create rejects tasks named "unstorable", standing in for a database error
+func (r *fakeRepository) Create(ctx context.Context, task *entity.Task) error {
+	if strings.HasPrefix(task.Name, "unstorable") {
+		return errors.New("data too long for column 'name'")
+	}
+	stored := *task
+	r.tasks = append(r.tasks, &stored)
+	return nil
+}
+
+// CreateBatch fails without storing anything if any of the tasks fails, as the single
+// statement does
+func (r *fakeRepository) CreateBatch(ctx context.Context, tasks []*entity.Task) error {
+	r.batches++
+	for _, task := range tasks {
+		if strings.HasPrefix(task.Name, "unstorable") {
+			return errors.New("data too long for column 'name'")
+		}
+	}
+	for _, task := range tasks {
+		if err := r.Create(ctx, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) ExistingIDs(ctx context.Context, ids []string) ([]string, error) {
+	var existing []string
+	for _, task := range r.tasks {
+		if slices.Contains(ids, task.ID) {
+			existing = append(existing, task.ID)
+		}
+	}
+	return existing, nil
+}
+
+func (r *fakeRepository) FindByID(ctx context.Context, id string) (*entity.Task, error) {
+	for _, task := range r.live() {
+		if task.ID == id {
+			found := *task
+			return &found, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (r *fakeRepository) Update(ctx context.Context, task *entity.Task) error {
+	for i, stored := range r.tasks {
+		if stored.ID == task.ID {
+			updated := *task
+			r.tasks[i] = &updated
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (r *fakeRepository) UpdateIfStatus(ctx context.Context, task *entity.Task, from ...entity.TaskStatus) (bool, error) {
+	for i, stored := range r.tasks {
+		if stored.ID == task.ID && stored.DeletedAt == nil && slices.Contains(from, stored.Status) {
+			updated := *task
+			r.tasks[i] = &updated
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepository) SoftDelete(ctx context.Context, taskID string, deletedBy string) error {
+	for _, task := range r.live() {
+		if task.ID == taskID {
+			now := time.Now()
+			task.DeletedAt = &now
+			task.DeletedBy = &deletedBy
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+// bulkMatches returns the live tasks matching a bulk filter's IDs, statuses and tag, up to its limit
+func (r *fakeRepository) bulkMatches(filter repository.BulkFilter) []*entity.Task {
+	var matched []*entity.Task
+	for _, task := range r.live() {
+		if len(matched) == filter.Limit {
+			break
+		}
+		if len(filter.IDs) > 0 && !slices.Contains(filter.IDs, task.ID) {
+			continue
+		}
+		if !slices.Contains(filter.Statuses, task.Status) {
+			continue
+		}
+		if filter.Tag != "" && !slices.Contains(task.Tags, filter.Tag) {
+			continue
+		}
+		matched = append(matched, task)
+	}
+	return matched
+}
+
+func (r *fakeRepository) CountBulk(ctx context.Context, filter repository.BulkFilter) (int64, error) {
+	return int64(len(r.bulkMatches(filter))), nil
+}
+
+func (r *fakeRepository) BulkSoftDelete(ctx context.Context, filter repository.BulkFilter, deletedBy string) ([]string, error) {
+	var ids []string
+	for _, task := range r.bulkMatches(filter) {
+		now := time.Now()
+		task.DeletedAt = &now
+		task.DeletedBy = &deletedBy
+		ids = append(ids, task.ID)
+	}
+	return ids, nil
+}
+
+func (r *fakeRepository) BulkRetry(ctx context.Context, filter repository.BulkFilter) ([]string, error) {
+	var ids []string
+	for _, task := range r.bulkMatches(filter) {
+		task.Status = entity.TaskStatusPending
+		task.RetryCount = 0
+		ids = append(ids, task.ID)
+	}
+	return ids, nil
+}
+
+// List supports the status and dependency filters and the page size, in creation order
+func (r *fakeRepository) List(ctx context.Context, filter repository.TaskFilter) ([]*entity.Task, int64, error) {
+	var tasks []*entity.Task
+	for _, task := range r.live() {
+		if filter.Status != nil && task.Status != *filter.Status {
+			continue
+		}
+		if filter.DependsOn != nil && (task.DependsOn == nil || *task.DependsOn != *filter.DependsOn) {
+			continue
+		}
+		if len(filter.ParentIDs) > 0 && (task.DependsOn == nil || !slices.Contains(filter.ParentIDs, *task.DependsOn)) {
+			continue
+		}
+		found := *task
+		tasks = append(tasks, &found)
+	}
+	total := int64(len(tasks))
+	if filter.Limit > 0 && len(tasks) > filter.Limit {
+		tasks = tasks[:filter.Limit]
+	}
+	return tasks, total, nil
+}
+
+func (r *fakeRepository) live() []*entity.Task {
+	var tasks []*entity.Task
+	for _, task := range r.tasks {
+		if task.DeletedAt == nil {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+func (r *fakeRepository) CountByStatus(ctx context.Context) (map[entity.TaskStatus]int64, error) {
+	result := make(map[entity.TaskStatus]int64)
+	for _, task := range r.live() {
+		result[task.Status]++
+	}
+	return result, nil
+}
+
+func (r *fakeRepository) StatsSummary(ctx context.Context, since time.Time) (*repository.StatsSummary, error) {
+	var summary repository.StatsSummary
+	var attempts int64
+	var latencies []float64
+	for _, task := range r.live() {
+		if !task.CreatedAt.Before(since) {
+			summary.Created++
+		}
+		attempted := task.CreatedAt
+		if task.StartedAt != nil {
+			attempted = *task.StartedAt
+		}
+		switch {
+		case task.Status == entity.TaskStatusCompleted && task.CompletedAt != nil && !task.CompletedAt.Before(since):
+			summary.Completed++
+			attempts += int64(task.CallbackAttempts)
+			latencies = append(latencies, float64(task.CompletedAt.Sub(task.CreatedAt).Microseconds())/1000)
+		case task.Status == entity.TaskStatusFailed && !attempted.Before(since):
+			summary.Failed++
+		case task.Status == entity.TaskStatusDeadLettered && !attempted.Before(since):
+			summary.DeadLettered++
+			attempts += int64(task.CallbackAttempts)
+		}
+	}
+	if finished := summary.Completed + summary.DeadLettered; finished > 0 {
+		summary.AvgCallbackAttempts = float64(attempts) / float64(finished)
+	}
+	if n := len(latencies); n > 0 {
+		slices.Sort(latencies)
+		summary.P50CompletionLatencyMs = latencies[int(math.Ceil(0.50*float64(n)))-1]
+		summary.P95CompletionLatencyMs = latencies[int(math.Ceil(0.95*float64(n)))-1]
+	}
+	return &summary, nil
+}
+
+func (r *fakeRepository) CountByTimeBucket(ctx context.Context, since time.Time, bucket time.Duration) ([]*repository.TimeBucketCounts, error) {
+	buckets := map[int64]*repository.TimeBucketCounts{}
+	seconds := int64(bucket / time.Second)
+	for _, task := range r.live() {
+		if task.CreatedAt.Before(since) {
+			continue
+		}
+		start := task.CreatedAt.Unix() - task.CreatedAt.Unix()%seconds
+		if buckets[start] == nil {
+			buckets[start] = &repository.TimeBucketCounts{Start: time.Unix(start, 0).UTC()}
+		}
+		buckets[start].Created++
+	}
+
+	var result []*repository.TimeBucketCounts
+	for _, b := range buckets {
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+// seededTask returns a task created and last updated the given duration ago
+func seededTask(status entity.TaskStatus, age time.Duration) *entity.Task {
+	at := time.Now().Add(-age)
+	task := &entity.Task{Status: status, CreatedAt: at, StartedAt: &at}
+	if status == entity.TaskStatusCompleted {
+		task.CompletedAt = &at
+	}
+	return task
+}
+
+func TestGetStatsForWindow(t *testing.T) {
+	deleted := seededTask(entity.TaskStatusCompleted, time.Minute)
+	deletedAt := time.Now()
+	deleted.DeletedAt = &deletedAt
+
+	repo := &fakeRepository{tasks: []*entity.Task{
+		seededTask(entity.TaskStatusPending, 10*time.Minute),
+		seededTask(entity.TaskStatusCompleted, 30*time.Minute),
+		seededTask(entity.TaskStatusCompleted, 5*time.Hour),
+		seededTask(entity.TaskStatusFailed, 2*time.Hour),
+		seededTask(entity.TaskStatusDeadLettered, 3*24*time.Hour),
+		seededTask(entity.TaskStatusCompleted, 10*24*time.Hour),
+		deleted,
+	}}
+	svc := NewService(repo)
+
+	t.Run("Default window", func(t *testing.T) {
+		stats, err := svc.GetStats(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(6), stats.Total, "soft-deleted tasks are excluded")
+		assert.Equal(t, int64(3), stats.ByStatus[entity.TaskStatusCompleted])
+		assert.Equal(t, "24h", stats.Window)
+		assert.Equal(t, WindowStats{Submitted: 4, Completed: 2, Failed: 1}, stats.Last24h)
+		assert.Equal(t, stats.Last24h, stats.Recent)
+	})
+
+	t.Run("One hour", func(t *testing.T) {
+		stats, err := svc.GetStatsForWindow(context.Background(), "1h")
+		require.NoError(t, err)
+
+		assert.Equal(t, WindowStats{Submitted: 2, Completed: 1, Failed: 0}, stats.Recent)
+		assert.Equal(t, WindowStats{Submitted: 4, Completed: 2, Failed: 1}, stats.Last24h)
+	})
+
+	t.Run("Seven days", func(t *testing.T) {
+		stats, err := svc.GetStatsForWindow(context.Background(), "7d")
+		require.NoError(t, err)
+
+		assert.Equal(t, WindowStats{Submitted: 5, Completed: 2, Failed: 1, DeadLettered: 1}, stats.Recent)
+		assert.InDelta(t, 2.0/3, stats.CallbackSuccessRate, 1e-9, "the failed task may still succeed on retry")
+	})
+
+	t.Run("Unsupported window", func(t *testing.T) {
+		_, err := svc.GetStatsForWindow(context.Background(), "30m")
+		assert.True(t, errors.Is(err, domain.ErrBadParamInput))
+	})
+}
+
+func TestGetStatsSummary(t *testing.T) {
+	now := time.Now()
+	finished := func(status entity.TaskStatus, latency time.Duration, attempts int) *entity.Task {
+		created := now.Add(-time.Hour)
+		done := created.Add(latency)
+		task := &entity.Task{Status: status, CreatedAt: created, StartedAt: &created, CallbackAttempts: attempts}
+		if status == entity.TaskStatusCompleted {
+			task.CompletedAt = &done
+		}
+		return task
+	}
+
+	var tasks []*entity.Task
+	for i := 1; i <= 20; i++ {
+		tasks = append(tasks, finished(entity.TaskStatusCompleted, time.Duration(i)*time.Second, 1))
+	}
+	tasks = append(tasks,
+		finished(entity.TaskStatusDeadLettered, 0, 4),
+		finished(entity.TaskStatusDeadLettered, 0, 4),
+		finished(entity.TaskStatusFailed, 0, 2),
+	)
+	svc := NewService(&fakeRepository{tasks: tasks})
+
+	stats, err := svc.GetStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, WindowStats{
+		Submitted:              23,
+		Completed:              20,
+		Failed:                 1,
+		DeadLettered:           2,
+		AvgCallbackAttempts:    28.0 / 22,
+		P50CompletionLatencyMs: 10000,
+		P95CompletionLatencyMs: 19000,
+	}, stats.Recent)
+	assert.InDelta(t, 20.0/22, stats.CallbackSuccessRate, 1e-9)
+}
+
+func TestGetTimeSeries(t *testing.T) {
+	repo := &fakeRepository{tasks: []*entity.Task{
+		seededTask(entity.TaskStatusPending, 10*time.Minute),
+		seededTask(entity.TaskStatusPending, 20*time.Minute),
+		seededTask(entity.TaskStatusPending, 5*time.Hour),
+	}}
+	svc := NewService(repo)
+
+	t.Run("Fills empty buckets", func(t *testing.T) {
+		series, err := svc.GetTimeSeries(context.Background(), "24h", "1h")
+		require.NoError(t, err)
+
+		assert.Len(t, series.Buckets, 25, "24 full buckets plus the partial current one")
+		var total int64
+		for i, point := range series.Buckets {
+			total += point.Created
+			if i > 0 {
+				assert.Equal(t, time.Hour, point.Start.Sub(series.Buckets[i-1].Start))
+			}
+		}
+		assert.Equal(t, int64(3), total)
+	})
+
+	t.Run("Rejects invalid parameters", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			window string
+			bucket string
+		}{
+			{"Unsupported window", "30d", "1h"},
+			{"Unparseable bucket", "24h", "hourly"},
+			{"Bucket below minimum", "24h", "30s"},
+			{"Too many buckets", "7d", "1m"},
+		}
+		for _, tt := range tests {
+			_, err := svc.GetTimeSeries(context.Background(), tt.window, tt.bucket)
+			assert.True(t, errors.Is(err, domain.ErrBadParamInput), tt.name)
+		}
+	})
+}
+
+func TestGetGroupedStats(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewTaskRepository()
+	seed := func(name string, status entity.TaskStatus, tags ...string) {
+		task := entity.NewTask(name, nil, "https://example.com/cb", time.Now(), 0)
+		task.Status = status
+		task.Tags = tags
+		require.NoError(t, repo.Create(ctx, task))
+		require.NoError(t, repo.Update(ctx, task))
+	}
+	seed("send_email", entity.TaskStatusCompleted, "billing")
+	seed("send_email", entity.TaskStatusCompleted, "billing")
+	seed("send_email", entity.TaskStatusDeadLettered)
+	seed("send_email", entity.TaskStatusFailed, "billing")
+	svc := NewService(repo)
+
+	byName, err := svc.GetStatsByName(ctx, "1h")
+	require.NoError(t, err)
+	assert.Equal(t, "name", byName.GroupBy)
+	require.Len(t, byName.Groups, 1)
+	assert.Equal(t, int64(4), byName.Groups[0].Total)
+	assert.InDelta(t, 2.0/3, byName.Groups[0].SuccessRate, 1e-9, "the failed task may still succeed on retry")
+	assert.False(t, byName.Truncated)
+
+	byTag, err := svc.GetStatsByTag(ctx, "24h")
+	require.NoError(t, err)
+	require.Len(t, byTag.Groups, 1, "untagged tasks have no group")
+	assert.Equal(t, "billing", byTag.Groups[0].Key)
+	assert.Equal(t, 1.0, byTag.Groups[0].SuccessRate)
+
+	for i := 0; i < MaxStatsGroups; i++ {
+		seed(fmt.Sprintf("job_%03d", i), entity.TaskStatusPending)
+	}
+	byName, err = svc.GetStatsByName(ctx, "7d")
+	require.NoError(t, err)
+	assert.Len(t, byName.Groups, MaxStatsGroups)
+	assert.True(t, byName.Truncated)
+	assert.Equal(t, "send_email", byName.Groups[0].Key, "the largest groups are kept")
+
+	_, err = svc.GetStatsByTag(ctx, "30m")
+	assert.True(t, errors.Is(err, domain.ErrBadParamInput))
+}
+
+func TestCreateTaskCallbackTimeout(t *testing.T) {
+	svc := NewService(&fakeRepository{})
+
+	task := &entity.Task{ID: "default"}
+	require.NoError(t, svc.CreateTask(context.Background(), task))
+	assert.Equal(t, entity.DefaultCallbackTimeoutSecs, task.CallbackTimeoutSecs)
+
+	for _, secs := range []int{1, 301} {
+		err := svc.CreateTask(context.Background(), &entity.Task{CallbackTimeoutSecs: secs})
+		assert.True(t, errors.Is(err, domain.ErrBadParamInput), "timeout %d", secs)
+	}
+}
+
+func TestCreateTaskExpiry(t *testing.T) {
+	svc := NewService(&fakeRepository{}, WithDefaultTaskTTL(time.Hour))
+	scheduledAt := time.Now().Add(time.Minute)
+
+	task := &entity.Task{ScheduledAt: scheduledAt}
+	require.NoError(t, svc.CreateTask(context.Background(), task))
+	if assert.NotNil(t, task.ExpiresAt) {
+		assert.Equal(t, scheduledAt.Add(time.Hour), *task.ExpiresAt)
+	}
+
+	// A task's own expiry wins, but must leave it time to run
+	own := scheduledAt.Add(5 * time.Minute)
+	task = &entity.Task{ScheduledAt: scheduledAt, ExpiresAt: &own}
+	require.NoError(t, svc.CreateTask(context.Background(), task))
+	assert.Equal(t, own, *task.ExpiresAt)
+
+	err := svc.CreateTask(context.Background(), &entity.Task{ScheduledAt: scheduledAt, ExpiresAt: &scheduledAt})
+	assert.True(t, errors.Is(err, domain.ErrBadParamInput))
+
+	// Without a default TTL tasks never expire
+	task = &entity.Task{ScheduledAt: scheduledAt}
+	require.NoError(t, NewService(&fakeRepository{}).CreateTask(context.Background(), task))
+	assert.Nil(t, task.ExpiresAt)
+}
+
+func TestCreateTaskRecordsRequestID(t *testing.T) {
+	svc := NewService(&fakeRepository{})
+	ctx := domain.WithRequestID(context.Background(), "req-123")
+
+	task := &entity.Task{}
+	require.NoError(t, svc.CreateTask(ctx, task))
+	if assert.NotNil(t, task.RequestID) {
+		assert.Equal(t, "req-123", *task.RequestID)
+	}
+
+	// A task carrying its own request ID keeps it
+	own := "req-456"
+	task = &entity.Task{RequestID: &own}
+	require.NoError(t, svc.CreateTask(ctx, task))
+	assert.Equal(t, "req-456", *task.RequestID)
+
+	// Outside a request nothing is recorded
+	task = &entity.Task{}
+	require.NoError(t, svc.CreateTask(context.Background(), task))
+	assert.Nil(t, task.RequestID)
+}
+
+func TestCreateTaskCallbackURLPolicy(t *testing.T) {
+	policy, err := callback.NewURLPolicy(callback.URLPolicyConfig{})
+	require.NoError(t, err)
+
+	repo := &fakeRepository{}
+	svc := NewService(repo, WithCallbackURLPolicy(policy))
+
+	err = svc.CreateTask(context.Background(), &entity.Task{CallbackURL: "http://169.254.169.254/latest/meta-data"})
+	assert.True(t, errors.Is(err, domain.ErrBadParamInput))
+	assert.Empty(t, repo.tasks)
+
+	require.NoError(t, svc.CreateTask(context.Background(), &entity.Task{CallbackURL: "https://203.0.113.10/hook"}))
+	assert.Len(t, repo.tasks, 1)
+}
+
+func TestValidateTask(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewTaskRepository()
+	existing := entity.NewTask("send_email", nil, "https://example.com/cb", time.Now(), 0)
+	require.NoError(t, repo.Create(ctx, existing))
+	svc := NewService(repo, WithDefaultTaskTTL(time.Hour))
+
+	task := entity.NewTask("send_email", nil, "https://example.com/cb", time.Now(), 0)
+	task.RetryBackoffSeconds = 0
+	require.NoError(t, svc.ValidateTask(ctx, task))
+	assert.Equal(t, entity.DefaultRetryBackoffSecs, task.RetryBackoffSeconds)
+	assert.NotNil(t, task.ExpiresAt)
+	_, err := repo.FindByID(ctx, task.ID)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "a validated task isn't stored")
+
+	task = entity.NewTask("send_email", nil, "https://example.com/cb", time.Now(), 0)
+	task.ID = existing.ID
+	assert.True(t, errors.Is(svc.ValidateTask(ctx, task), domain.ErrConflict))
+
+	task = entity.NewTask("send_email", nil, "https://example.com/cb", time.Now(), 0)
+	task.CallbackTimeoutSecs = entity.MaxCallbackTimeoutSecs + 1
+	assert.True(t, errors.Is(svc.ValidateTask(ctx, task), domain.ErrBadParamInput))
+}
+
+func TestCreateTaskPayloadLimits(t *testing.T) {
+	repo := &fakeRepository{}
+	svc := NewService(repo, WithMaxPayloadSize(64), WithPayloadCompression(32))
+
+	err := svc.CreateTask(context.Background(), &entity.Task{Payload: []byte(`{"data":"` + strings.Repeat("x", 64) + `"}`)})
+	assert.True(t, errors.Is(err, domain.ErrBadParamInput))
+	assert.Empty(t, repo.tasks)
+
+	small := &entity.Task{Payload: []byte(`{"k":"v"}`)}
+	require.NoError(t, svc.CreateTask(context.Background(), small))
+	assert.Empty(t, small.PayloadEncoding)
+
+	large := &entity.Task{Payload: []byte(`{"data":"` + strings.Repeat("x", 32) + `"}`)}
+	require.NoError(t, svc.CreateTask(context.Background(), large))
+	assert.Equal(t, entity.PayloadEncodingGzip, large.PayloadEncoding)
+}
+
+func TestCreateTaskEncryptsPayload(t *testing.T) {
+	payloadCipher, err := NewPayloadCipher(bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+
+	repo := &fakeRepository{}
+	svc := NewService(repo, WithPayloadCipher(payloadCipher), WithPayloadCompression(1))
+
+	task := &entity.Task{ID: "secret", Payload: []byte(`{"ssn":"123-45-6789"}`)}
+	require.NoError(t, svc.CreateTask(context.Background(), task))
+	assert.JSONEq(t, `{"ssn":"123-45-6789"}`, string(task.Payload), "the caller keeps the plaintext")
+	assert.False(t, task.PayloadEncrypted)
+
+	require.Len(t, repo.tasks, 1)
+	assert.True(t, repo.tasks[0].PayloadEncrypted)
+	assert.Empty(t, repo.tasks[0].PayloadEncoding, "ciphertext is not compressed")
+	assert.NotContains(t, string(repo.tasks[0].Payload), "123-45-6789")
+
+	found, err := svc.GetTask(context.Background(), "secret")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ssn":"123-45-6789"}`, string(found.Payload))
+
+	_, err = NewService(repo).GetTask(context.Background(), "secret")
+	assert.True(t, errors.Is(err, ErrPayloadKeyUnavailable), "encrypted payloads need a key")
+}
@@ -0,0 +1,59 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+)
+
+// SetTags replaces a task's tags, in any status; an empty list removes them all
+// Duplicates are dropped. A task without a concurrency key is limited by its first tag, so
+// changing the first tag of a task yet to run moves it to another limit
+// Returns an error wrapping domain.ErrNotFound for a missing task, or domain.ErrBadParamInput
+// for invalid tags
+func (s *Service) SetTags(ctx context.Context, id string, tags []string) (*entity.Task, error) {
+	return s.updateTags(ctx, id, func([]string) []string {
+		return entity.EditTags(nil, tags, nil)
+	})
+}
+
+// EditTags adds and removes tags of a task, keeping the others in order; a tag in both lists
+// is removed. It returns the same errors as SetTags
+func (s *Service) EditTags(ctx context.Context, id string, add, remove []string) (*entity.Task, error) {
+	return s.updateTags(ctx, id, func(tags []string) []string {
+		return entity.EditTags(tags, add, remove)
+	})
+}
+
+// updateTags stores the tags edit derives from the task's current ones
+// Only tags the task didn't have yet are validated, so tags set at creation, which isn't
+// validated, can still be kept or removed. Concurrent edits of a task's tags may overwrite
+// each other
+func (s *Service) updateTags(ctx context.Context, id string, edit func(tags []string) []string) (*entity.Task, error) {
+	task, err := s.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := edit(task.Tags)
+	if len(tags) > entity.MaxTags && len(tags) > len(task.Tags) {
+		return nil, fmt.Errorf("%w: a task can have at most %d tags, got %d", domain.ErrBadParamInput, entity.MaxTags, len(tags))
+	}
+	for _, tag := range tags {
+		if slices.Contains(task.Tags, tag) {
+			continue
+		}
+		if err := entity.ValidateTag(tag); err != nil {
+			return nil, fmt.Errorf("%w: %v", domain.ErrBadParamInput, err)
+		}
+	}
+
+	if err := s.repo.UpdateTags(ctx, id, tags); err != nil {
+		return nil, fmt.Errorf("failed to update task tags: %w", err)
+	}
+	task.Tags = tags
+	return task, nil
+}
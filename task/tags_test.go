@@ -0,0 +1,58 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/repository/memory"
+)
+
+func TestEditTaskTags(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewTaskRepository()
+	svc := NewService(repo)
+
+	// Tags aren't validated at creation, so this one couldn't be added later
+	task := entity.NewTask("send_email", []byte(`{}`), "https://example.com/cb", time.Now(), 0)
+	task.Status = entity.TaskStatusFailed
+	task.Tags = []string{"email", "legacy tag"}
+	require.NoError(t, repo.Create(ctx, task))
+
+	edited, err := svc.EditTags(ctx, task.ID, []string{"investigating", "email"}, []string{"missing"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"email", "legacy tag", "investigating"}, edited.Tags)
+
+	edited, err = svc.EditTags(ctx, task.ID, nil, []string{"legacy tag"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"email", "investigating"}, edited.Tags)
+	stored, err := repo.FindByID(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, edited.Tags, stored.Tags)
+
+	edited, err = svc.SetTags(ctx, task.ID, []string{"resolved", "resolved", "team:billing"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"resolved", "team:billing"}, edited.Tags)
+
+	tooMany := make([]string, entity.MaxTags+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("t", i+1)
+	}
+	for _, tags := range [][]string{{"on hold"}, {""}, {strings.Repeat("a", entity.MaxTagLength+1)}, tooMany} {
+		_, err := svc.SetTags(ctx, task.ID, tags)
+		assert.True(t, errors.Is(err, domain.ErrBadParamInput), "%v: got %v", tags, err)
+	}
+	stored, err = repo.FindByID(ctx, task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"resolved", "team:billing"}, stored.Tags, "invalid tags aren't stored")
+
+	_, err = svc.SetTags(ctx, "missing", []string{"a"})
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "got %v", err)
+}
@@ -0,0 +1,39 @@
+package task
+
+import (
+	"context"
+
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/domain/repository"
+)
+
+// MaxTimelineEvents caps the events GetTaskTimeline returns for one task
+const MaxTimelineEvents = 1000
+
+// WithEventHistory reads task timelines from the events recorded in repo
+// Without it every timeline is empty
+func WithEventHistory(repo repository.TaskEventRepository) ServiceOption {
+	return func(s *Service) {
+		s.events = repo
+	}
+}
+
+// GetTaskTimeline returns the recorded events of a task, oldest first, up to MaxTimelineEvents
+// Like GetTask it returns domain.ErrNotFound for deleted tasks and other tenants' tasks
+// Events older than the scheduler's retention have been removed
+func (s *Service) GetTaskTimeline(ctx context.Context, id string) ([]*entity.TaskEvent, error) {
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	if s.events == nil {
+		return []*entity.TaskEvent{}, nil
+	}
+	events, err := s.events.ListByTask(ctx, id, MaxTimelineEvents)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []*entity.TaskEvent{}
+	}
+	return events, nil
+}
@@ -0,0 +1,46 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/usual2970/later/domain"
+	"github.com/usual2970/later/domain/entity"
+	"github.com/usual2970/later/repository/memory"
+)
+
+func TestGetTaskTimeline(t *testing.T) {
+	repo := memory.NewTaskRepository()
+	events := memory.NewTaskEventRepository()
+	svc := NewService(repo, WithEventHistory(events))
+	ctx := domain.WithTenant(context.Background(), "acme")
+
+	task := entity.NewTask("send_email", nil, "https://example.com/callback", time.Now(), 0)
+	require.NoError(t, svc.CreateTask(ctx, task))
+	for _, status := range []entity.TaskStatus{entity.TaskStatusPending, entity.TaskStatusProcessing, entity.TaskStatusCompleted} {
+		require.NoError(t, events.Append(ctx, &entity.TaskEvent{
+			TaskID: task.ID, TenantID: "acme", Type: "task_updated", ToStatus: status, CreatedAt: time.Now(),
+		}))
+	}
+
+	timeline, err := svc.GetTaskTimeline(ctx, task.ID)
+	require.NoError(t, err)
+	require.Len(t, timeline, 3)
+	assert.Equal(t, entity.TaskStatusPending, timeline[1].FromStatus)
+	assert.Equal(t, entity.TaskStatusProcessing, timeline[1].ToStatus)
+
+	// Other tenants can't see that the task exists
+	_, err = svc.GetTaskTimeline(domain.WithTenant(context.Background(), "globex"), task.ID)
+	assert.True(t, errors.Is(err, domain.ErrNotFound), "got %v", err)
+
+	// Without an event store the timeline is empty
+	timeline, err = NewService(repo).GetTaskTimeline(ctx, task.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, timeline)
+	assert.Empty(t, timeline)
+}